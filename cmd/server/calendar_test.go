@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"workout-tracker/internal/models"
+)
+
+// TestRecurringCalendarEvents creates a weekly series, changes one occurrence and all
+// future ones, deletes another and reads the occurrences back through the event list and
+// the monthly calendar
+func TestRecurringCalendarEvents(t *testing.T) {
+	h := newHarness(t) // Thursday 2026-10-15
+	alice := h.LoginAs("alice")
+
+	write := func(method, path string, req models.CalendarEventRequest, want int) models.WorkoutCalendarEvent {
+		t.Helper()
+		resp := alice.SendJSON(method, path, req)
+		if resp.StatusCode != want {
+			t.Fatalf("%s %s: status %d, want %d: %s", method, path, resp.StatusCode, want, resp.Body)
+		}
+		var event models.WorkoutCalendarEvent
+		resp.JSON(t, &event)
+		return event
+	}
+	occurrences := func(from, to string) string {
+		t.Helper()
+		resp := alice.Get("/api/v1/calendar/events?from=" + from + "&to=" + to)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("calendar events: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var events []models.WorkoutCalendarEvent
+		resp.JSON(t, &events)
+		var got []string
+		for _, event := range events {
+			got = append(got, event.StartDate.Format("01-02T15:04")+" "+event.Title)
+		}
+		return strings.Join(got, ", ")
+	}
+
+	series := write("POST", "/api/v1/calendar/events", models.CalendarEventRequest{
+		Title: "Mobility", StartDate: "2026-10-12T07:00:00Z", RRule: "RRULE:FREQ=WEEKLY;BYDAY=MO,TH",
+	}, http.StatusCreated)
+	if !series.IsRecurring || series.RecurrencePattern == nil || *series.RecurrencePattern != `{"rrule":"FREQ=WEEKLY;BYDAY=MO,TH"}` {
+		t.Errorf("created series = %+v", series)
+	}
+	if got, want := occurrences("2026-10-12", "2026-10-25"), "10-12T07:00 Mobility, 10-15T07:00 Mobility, 10-19T07:00 Mobility, 10-22T07:00 Mobility"; got != want {
+		t.Errorf("occurrences = %s, want %s", got, want)
+	}
+
+	// Moving Thursday's occurrence to Friday leaves the rest of the series alone
+	moved := write("PUT", fmt.Sprintf("/api/v1/calendar/events/%d/occurrences/2026-10-15", series.ID), models.CalendarEventRequest{
+		Title: "Walk", StartDate: "2026-10-16T09:00:00Z",
+	}, http.StatusCreated)
+	if moved.IsRecurring || moved.ID == series.ID {
+		t.Errorf("moved occurrence = %+v, want a single event", moved)
+	}
+	if got, want := occurrences("2026-10-12", "2026-10-25"), "10-12T07:00 Mobility, 10-16T09:00 Walk, 10-19T07:00 Mobility, 10-22T07:00 Mobility"; got != want {
+		t.Errorf("occurrences after moving one = %s, want %s", got, want)
+	}
+	resp := alice.SendJSON("PUT", fmt.Sprintf("/api/v1/calendar/events/%d/occurrences/2026-10-15", series.ID), models.CalendarEventRequest{Title: "Walk", StartDate: "2026-10-16"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("change a moved occurrence: status %d, want 404", resp.StatusCode)
+	}
+
+	// Moving the series to the evening from the 22nd on starts a new series that repeats
+	// like the old one
+	evening := write("PUT", fmt.Sprintf("/api/v1/calendar/events/%d/occurrences/2026-10-22?scope=future", series.ID), models.CalendarEventRequest{
+		Title: "Evening mobility", StartDate: "2026-10-22T18:00:00Z",
+	}, http.StatusCreated)
+	if !evening.IsRecurring || evening.RecurrencePattern == nil || *evening.RecurrencePattern != `{"rrule":"FREQ=WEEKLY;BYDAY=MO,TH"}` {
+		t.Errorf("new series = %+v, want the old rule", evening)
+	}
+	resp = alice.Get(fmt.Sprintf("/api/v1/calendar/events/%d", series.ID))
+	var ended models.WorkoutCalendarEvent
+	resp.JSON(t, &ended)
+	if ended.RecurrencePattern == nil || *ended.RecurrencePattern != `{"rrule":"FREQ=WEEKLY;BYDAY=MO,TH;UNTIL=20261022T065959Z","exdates":["2026-10-15"]}` {
+		t.Errorf("old series = %+v, want it ended before the 22nd", ended)
+	}
+
+	resp = alice.Do("DELETE", fmt.Sprintf("/api/v1/calendar/events/%d/occurrences/2026-10-26", evening.ID), nil, "")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete occurrence: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	want := "10-12T07:00 Mobility, 10-16T09:00 Walk, 10-19T07:00 Mobility, 10-22T18:00 Evening mobility, 10-29T18:00 Evening mobility"
+	if got := occurrences("2026-10-01", "2026-10-31"); got != want {
+		t.Errorf("occurrences = %s, want %s", got, want)
+	}
+
+	resp = alice.Get("/api/v1/calendar?month=2026-10")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("calendar: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	var calendar models.MonthlyCalendarView
+	resp.JSON(t, &calendar)
+	// The grid runs to Sunday 1 November
+	if len(calendar.Events) != 5 || calendar.Events[4].RecurrenceID == nil || calendar.Events[4].ID != evening.ID {
+		t.Errorf("calendar events = %+v, want the five occurrences", calendar.Events)
+	}
+
+	// Deleting all future occurrences from the first deletes the series
+	resp = alice.Do("DELETE", fmt.Sprintf("/api/v1/calendar/events/%d/occurrences/2026-10-22?scope=future", evening.ID), nil, "")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete future occurrences: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	if resp := alice.Get(fmt.Sprintf("/api/v1/calendar/events/%d", evening.ID)); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("series after deleting every occurrence: status %d, want 404", resp.StatusCode)
+	}
+}
+
+// TestRecurringCalendarEventsInTimeZone checks recurring events repeat in the user's time
+// zone: at the same clock time across the end of summer time, on the user's weekdays and
+// without the occurrences of the user's days they deleted, in the app and in the feed
+func TestRecurringCalendarEventsInTimeZone(t *testing.T) {
+	h := newHarness(t) // Thursday 2026-10-15
+	alice := h.LoginAs("alice")
+	setTimezone(t, alice, "Europe/Berlin")
+
+	create := func(req models.CalendarEventRequest) models.WorkoutCalendarEvent {
+		t.Helper()
+		resp := alice.SendJSON("POST", "/api/v1/calendar/events", req)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create calendar event: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var event models.WorkoutCalendarEvent
+		resp.JSON(t, &event)
+		return event
+	}
+	create(models.CalendarEventRequest{Title: "Evening", StartDate: "2026-10-19T18:00:00+02:00", RRule: "FREQ=WEEKLY"})
+	// 00:30 on Monday in Berlin is Sunday in UTC
+	early := create(models.CalendarEventRequest{Title: "Early", StartDate: "2026-10-19T00:30:00+02:00", RRule: "FREQ=WEEKLY;BYDAY=MO"})
+
+	// Deleting Monday the 26th leaves out that Monday in Berlin
+	resp := alice.Do("DELETE", fmt.Sprintf("/api/v1/calendar/events/%d/occurrences/2026-10-26", early.ID), nil, "")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete occurrence: status %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	resp = alice.Get("/api/v1/calendar/events?from=2026-10-19&to=2026-11-02")
+	var events []models.WorkoutCalendarEvent
+	resp.JSON(t, &events)
+	var got []string
+	for _, event := range events {
+		got = append(got, event.StartDate.UTC().Format("01-02T15:04")+" "+event.Title)
+	}
+	// Summer time ends on the 25th, when 18:00 in Berlin moves from 16:00 to 17:00 UTC
+	want := "10-18T22:30 Early, 10-19T16:00 Evening, 10-26T17:00 Evening, 11-01T23:30 Early, 11-02T17:00 Evening"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("occurrences = %s, want %s", strings.Join(got, ", "), want)
+	}
+
+	cal, err := h.Store.CalendarEvents().Get(early.ID, alice.UserID)
+	if err != nil || cal.RecurrencePattern == nil || *cal.RecurrencePattern != `{"rrule":"FREQ=WEEKLY;BYDAY=MO","exdates":["2026-10-26"]}` {
+		t.Errorf("series after deleting an occurrence = %+v, %v", cal, err)
+	}
+
+	// The feed places the series in Berlin, so calendar apps repeat them there too
+	resp = alice.Do("POST", "/api/v1/calendar/feed", nil, "")
+	var feed models.CalendarFeedResponse
+	resp.JSON(t, &feed)
+	resp = h.Client().Get("/calendar/" + feed.Token + ".ics")
+	for _, line := range []string{"DTSTART;TZID=Europe/Berlin:20261019T180000", "DTSTART;TZID=Europe/Berlin:20261019T003000", "EXDATE;TZID=Europe/Berlin:20261026T003000"} {
+		if !strings.Contains(resp.Body, line) {
+			t.Errorf("feed lacks %s:\n%s", line, resp.Body)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// TestDeloads logs six weeks of stalled lifts through the API until the detector
+// recommends a deload, accepts it and checks the targets of a workout scheduled in it
+func TestDeloads(t *testing.T) {
+	h := newHarness(t) // Thursday 2026-10-15
+	alice := h.LoginAs("alice")
+
+	logWorkout := func(date string) {
+		t.Helper()
+		sets := []models.CreateWorkoutSetRequest{{Reps: 5, Weight: 100}, {Reps: 5, Weight: 100}}
+		workout := models.CreateWorkoutRequest{Name: "Strength", Date: date, Duration: 60, Exercises: []models.CreateWorkoutExerciseRequest{
+			{Name: "Squat", Category: "Legs", Sets: sets},
+			{Name: "Bench Press", Category: "Chest", Sets: sets},
+		}}
+		if resp := alice.SendJSON("POST", "/api/v1/workouts", workout); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create workout: status %d: %s", resp.StatusCode, resp.Body)
+		}
+	}
+	deloads := func(query string) []models.DeloadRecommendation {
+		t.Helper()
+		resp := alice.Get("/api/v1/deloads?" + query)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("deloads: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var recs []models.DeloadRecommendation
+		resp.JSON(t, &recs)
+		return recs
+	}
+
+	// History logged late is not checked, however flat it is
+	for _, date := range []string{"2026-09-07", "2026-09-14", "2026-09-21", "2026-09-28", "2026-10-05", "2026-10-12"} {
+		logWorkout(date)
+	}
+	if recs := deloads(""); len(recs) != 0 {
+		t.Fatalf("deloads after logging history = %+v, want none", recs)
+	}
+
+	logWorkout("2026-10-15")
+	recs := deloads("")
+	if len(recs) != 1 {
+		t.Fatalf("deloads after a flat six weeks = %+v, want one", recs)
+	}
+	rec := recs[0]
+	if rec.RecommendedStartDate.Format("2006-01-02") != "2026-10-16" || rec.RecommendedEndDate.Format("2006-01-02") != "2026-10-22" ||
+		rec.Reason != models.DeloadReasonPlateau || rec.VolumeReductionPercent != 40 || rec.IntensityReductionPercent != 20 ||
+		rec.Status != models.DeloadStatusSuggested {
+		t.Errorf("recommendation = %+v, want a plateau deload from 16 to 22 October", rec)
+	}
+	var metrics models.DeloadTriggerMetrics
+	if err := json.Unmarshal([]byte(rec.TriggerMetrics), &metrics); err != nil {
+		t.Fatalf("trigger metrics %q: %v", rec.TriggerMetrics, err)
+	}
+	if metrics.StalledLifts != 2 || metrics.WeeksSinceDeload != 5 || len(metrics.Lifts) != 2 {
+		t.Errorf("trigger metrics = %+v, want two stalled lifts after five weeks", metrics)
+	}
+
+	// A second flat workout does not recommend another deload on top of the first
+	logWorkout("2026-10-15")
+	if recs := deloads(""); len(recs) != 1 {
+		t.Errorf("deloads after another workout = %+v, want still one", recs)
+	}
+
+	templateID, err := h.Store.Templates().Create(models.WorkoutTemplate{UserID: alice.UserID, Name: "Strength"})
+	if err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+	if _, err := h.Store.Templates().CreateExercise(models.TemplateExercise{TemplateID: templateID, Name: "Squat", TargetSets: 5, TargetReps: 5, TargetWeight: 100}); err != nil {
+		t.Fatalf("failed to create template exercise: %v", err)
+	}
+	scheduledID, err := h.Store.ScheduledWorkouts().Create(models.ScheduledWorkout{
+		UserID: alice.UserID, TemplateID: &templateID, Title: "Strength", ScheduledDate: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("failed to schedule workout: %v", err)
+	}
+
+	resp := alice.SendJSON("POST", fmt.Sprintf("/api/v1/deloads/%d/response", rec.ID), models.DeloadResponseRequest{Status: "accepted"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("respond: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	var answered models.DeloadRecommendation
+	resp.JSON(t, &answered)
+	if answered.Status != models.DeloadStatusAccepted {
+		t.Errorf("answered = %+v, want accepted", answered)
+	}
+
+	scheduled, err := h.Store.ScheduledWorkouts().Get(scheduledID, alice.UserID)
+	if err != nil {
+		t.Fatalf("failed to get scheduled workout: %v", err)
+	}
+	if len(scheduled.Exercises) != 1 || scheduled.Exercises[0].TargetSets != 3 || scheduled.Exercises[0].TargetWeight != 80 {
+		t.Errorf("scheduled exercises = %+v, want 3 sets at 80", scheduled.Exercises)
+	}
+
+	h.Clock.Set(time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC))
+	resp = alice.Get("/api/v1/planning/analytics")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("planning analytics: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	var analytics models.PlanningAnalytics
+	resp.JSON(t, &analytics)
+	if analytics.ActiveDeloads != 1 || analytics.DeloadCompliance != 100 {
+		t.Errorf("analytics = %+v, want one active deload and full compliance", analytics)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"workout-tracker/internal/handlers/handlerstest"
+	"workout-tracker/internal/models"
+)
+
+// TestEventStream watches changes made on a phone from a laptop's event stream, then
+// reconnects the laptop with Last-Event-ID to catch up on what it missed
+func TestEventStream(t *testing.T) {
+	h := newHarness(t)
+	phone := h.LoginAs("alice")
+	bob := h.LoginAs("bob")
+
+	laptop := h.Client()
+	if resp := laptop.PostForm("/login", url.Values{"username": {"alice"}, "password": {handlerstest.DefaultPassword}}); resp.StatusCode != http.StatusFound {
+		t.Fatalf("login on laptop: status %d", resp.StatusCode)
+	}
+
+	stream := laptop.Stream("/api/v1/events")
+	if stream.StatusCode != http.StatusOK || stream.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream: status %d, content type %q", stream.StatusCode, stream.Header.Get("Content-Type"))
+	}
+
+	bob.SendJSON("POST", "/api/v1/body-weights", models.CreateBodyWeightRequest{Weight: 90, Unit: "kg", Date: "2026-10-15"})
+
+	resp := phone.SendJSON("POST", "/api/v1/workouts", models.CreateWorkoutRequest{Name: "Push", Date: "2026-10-15", Exercises: []models.CreateWorkoutExerciseRequest{
+		{Name: "Bench Press", Category: "Chest", Sets: []models.CreateWorkoutSetRequest{{Reps: 5, Weight: 100}}},
+	}})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create workout: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	var workout models.Workout
+	resp.JSON(t, &workout)
+
+	// Bob's write is not on alice's stream, so her workout is the first event
+	event := stream.Next()
+	var payload struct {
+		Type string         `json:"type"`
+		Data models.Workout `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(event.Data), &payload); err != nil {
+		t.Fatalf("failed to decode event %q: %v", event.Data, err)
+	}
+	if event.Type != "workout.created" || payload.Type != "workout.created" || payload.Data.ID != workout.ID || len(payload.Data.Exercises) != 1 {
+		t.Fatalf("first event = %+v, want workout.created for workout %d", event, workout.ID)
+	}
+	seen := event.ID
+
+	set := workout.Exercises[0].Sets[0]
+	phone.SendJSON("PUT", "/api/v1/sets/"+strconv.Itoa(set.ID), models.UpdateSetRequest{SetNumber: 1, Reps: 6, Weight: 100})
+	if event := stream.Next(); event.Type != "set.updated" {
+		t.Fatalf("second event = %+v, want set.updated", event)
+	}
+	stream.Close()
+
+	// Changes made while the laptop is offline are replayed when it reconnects
+	phone.SendJSON("POST", "/api/v1/meals", models.CreateMealRequest{Name: "Oats", Calories: 350, Date: "2026-10-15", MealType: "breakfast"})
+	phone.Delete("/api/v1/workouts/" + strconv.Itoa(workout.ID))
+
+	laptop.Header.Set("Last-Event-ID", seen)
+	stream = laptop.Stream("/api/events")
+	for _, want := range []string{"set.updated", "meal.created", "workout.deleted"} {
+		if event := stream.Next(); event.Type != want {
+			t.Fatalf("replayed event = %+v, want %s", event, want)
+		}
+	}
+	stream.Close()
+
+	// An ID the server no longer knows asks the client to reload
+	laptop.Header.Set("Last-Event-ID", "1")
+	stream = laptop.Stream("/api/v1/events")
+	if event := stream.Next(); event.Type != "reset" {
+		t.Fatalf("event after an unknown ID = %+v, want reset", event)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"workout-tracker/internal/handlers/handlerstest"
+	"workout-tracker/internal/ical"
+	"workout-tracker/internal/models"
+)
+
+// TestCalendarFeed subscribes to a user's schedule through their feed URL, checks what the
+// events carry and that regenerating or deleting the feed retires the old URL
+func TestCalendarFeed(t *testing.T) {
+	h := newHarness(t) // Thursday 2026-10-15
+	alice := h.LoginAs("alice")
+	setTimezone(t, alice, "Europe/Berlin")
+
+	templateID, err := h.Store.Templates().Create(models.WorkoutTemplate{UserID: alice.UserID, Name: "Legs"})
+	if err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+	if _, err := h.Store.Templates().CreateExercise(models.TemplateExercise{TemplateID: templateID, Name: "Squat", TargetSets: 5, TargetReps: 5, TargetWeight: 100}); err != nil {
+		t.Fatalf("failed to create template exercise: %v", err)
+	}
+	for _, req := range []models.CreateScheduledWorkoutRequest{
+		{TemplateID: &templateID, Title: "Legs", ScheduledDate: "2026-10-20", ScheduledTime: "18:00", EstimatedDuration: 75},
+		{Title: "Run", ScheduledDate: "2026-10-16", Notes: "Easy pace"},
+	} {
+		if resp := alice.SendJSON("POST", "/api/v1/scheduled-workouts", req); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("schedule workout: status %d: %s", resp.StatusCode, resp.Body)
+		}
+	}
+	if resp := alice.SendJSON("POST", "/api/v1/calendar/events", models.CalendarEventRequest{
+		EventType: models.CalendarEventRestDay, Title: "Mobility", StartDate: "2026-10-12T07:00:00Z", RRule: "FREQ=WEEKLY", ExceptionDates: []string{"2026-10-19"},
+	}); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create calendar event: status %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	if resp := alice.Get("/api/v1/calendar/feed"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("feed before generating one: status %d, want 404", resp.StatusCode)
+	}
+	generate := func() string {
+		t.Helper()
+		resp := alice.Do("POST", "/api/v1/calendar/feed", nil, "")
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("generate feed: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var feed models.CalendarFeedResponse
+		resp.JSON(t, &feed)
+		u, err := url.Parse(feed.URL)
+		if err != nil || !strings.HasPrefix(feed.Token, feed.Prefix) || u.Path != "/calendar/"+feed.Token+".ics" {
+			t.Fatalf("feed = %+v, want a URL with its token", feed)
+		}
+		return u.Path
+	}
+	anonymous := h.Client()
+	fetch := func(path string) (*ical.Calendar, int) {
+		t.Helper()
+		resp := anonymous.Get(path)
+		if resp.StatusCode != http.StatusOK {
+			return nil, resp.StatusCode
+		}
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+			t.Errorf("Content-Type = %q, want text/calendar", ct)
+		}
+		cal, err := ical.Parse(strings.NewReader(resp.Body))
+		if err != nil {
+			t.Fatalf("feed is not a valid calendar: %v\n%s", err, resp.Body)
+		}
+		return &cal, resp.StatusCode
+	}
+
+	feedPath := generate()
+	cal, status := fetch(feedPath)
+	if cal == nil {
+		t.Fatalf("fetch feed: status %d", status)
+	}
+	byName := map[string]ical.Event{}
+	for _, e := range cal.Events {
+		byName[e.Summary] = e
+	}
+	if len(cal.Events) != 3 {
+		t.Fatalf("feed has %d events, want 3: %+v", len(cal.Events), cal.Events)
+	}
+
+	legs := byName["Legs"]
+	if legs.Start.Location().String() != "Europe/Berlin" || legs.Start.Format("2006-01-02 15:04") != "2026-10-20 18:00" ||
+		legs.End.Sub(legs.Start) != 75*time.Minute || legs.Status != "CONFIRMED" {
+		t.Errorf("Legs = %v to %v (%s), want 75 minutes from 18:00 in Berlin", legs.Start, legs.End, legs.Status)
+	}
+	for _, want := range []string{"Status: scheduled", "Estimated duration: 75 min", "- Squat: 5 x 5 @ 100 kg"} {
+		if !strings.Contains(legs.Description, want) {
+			t.Errorf("Legs description lacks %q:\n%s", want, legs.Description)
+		}
+	}
+
+	run := byName["Run"]
+	if !run.AllDay || run.Start.Format("2006-01-02") != "2026-10-16" || !strings.Contains(run.Description, "Easy pace") {
+		t.Errorf("Run = %+v, want all day on the 16th with its notes", run)
+	}
+
+	mobility := byName["Mobility"]
+	if mobility.RRule != "FREQ=WEEKLY" || len(mobility.ExDates) != 1 || !mobility.ExDates[0].Equal(time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)) ||
+		len(mobility.Categories) != 1 || mobility.Categories[0] != "Rest day" {
+		t.Errorf("Mobility = %+v, want the weekly series without the 19th", mobility)
+	}
+
+	resp := alice.Get("/api/v1/calendar/feed")
+	var feed models.CalendarFeed
+	resp.JSON(t, &feed)
+	if resp.StatusCode != http.StatusOK || feed.LastUsedAt == nil {
+		t.Errorf("feed after fetching = %+v, want its last use", feed)
+	}
+
+	// Regenerating retires the old URL, deleting retires the new one
+	newPath := generate()
+	if _, status := fetch(feedPath); status != http.StatusNotFound {
+		t.Errorf("old feed URL: status %d, want 404", status)
+	}
+	if cal, status := fetch(newPath); cal == nil {
+		t.Errorf("new feed URL: status %d, want 200", status)
+	}
+	if resp := alice.Delete("/api/v1/calendar/feed"); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete feed: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	if _, status := fetch(newPath); status != http.StatusNotFound {
+		t.Errorf("deleted feed URL: status %d, want 404", status)
+	}
+}
+
+// TestImportScheduledWorkouts imports an .ics file with single, all-day and recurring
+// events, and imports it again as a form upload without duplicating anything
+func TestImportScheduledWorkouts(t *testing.T) {
+	h := newHarness(t) // Thursday 2026-10-15
+	alice := h.LoginAs("alice")
+	setTimezone(t, alice, "Europe/Berlin")
+
+	file := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:swim@example",
+		"DTSTART:20261022T053000Z",
+		"DURATION:PT45M",
+		"SUMMARY:Swim",
+		"DESCRIPTION:Pool\\, lane 3",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:hike@example",
+		"DTSTART;VALUE=DATE:20261024",
+		"SUMMARY:Hike",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:intervals@example",
+		"DTSTART;TZID=Europe/Berlin:20261013T190000",
+		"DTEND;TZID=Europe/Berlin:20261013T200000",
+		"RRULE:FREQ=WEEKLY;COUNT=4",
+		"SUMMARY:Intervals",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:intervals@example",
+		"RECURRENCE-ID;TZID=Europe/Berlin:20261027T190000",
+		"DTSTART;TZID=Europe/Berlin:20261028T180000",
+		"DTEND;TZID=Europe/Berlin:20261028T183000",
+		"SUMMARY:Intervals (moved)",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:birthday@example",
+		"DTSTART;VALUE=DATE:20261101",
+		"RRULE:FREQ=YEARLY",
+		"SUMMARY:Birthday",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	resp := alice.Do("POST", "/api/v1/scheduled-workouts/import", strings.NewReader(file), "text/calendar")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("import: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	var result models.ICalImportResult
+	resp.JSON(t, &result)
+	if result.Imported != 5 || result.Duplicates != 0 || len(result.Skipped) != 1 || !strings.Contains(result.Skipped[0], "Birthday") {
+		t.Errorf("import = %+v, want five workouts and the yearly birthday skipped", result)
+	}
+
+	resp = alice.Get("/api/v1/scheduled-workouts?from=2026-10-01&to=2026-11-30")
+	var workouts []models.ScheduledWorkout
+	resp.JSON(t, &workouts)
+	var got []string
+	for _, sw := range workouts {
+		clock := "all day"
+		if sw.ScheduledTime != nil {
+			clock = *sw.ScheduledTime
+		}
+		got = append(got, fmt.Sprintf("%s %s %s %d", sw.ScheduledDate.Format("01-02"), clock, sw.Title, sw.EstimatedDuration))
+	}
+	// The first intervals session was before today and the third was moved
+	want := "10-20 19:00 Intervals 60, 10-22 07:30 Swim 45, 10-24 all day Hike 60, 10-28 18:00 Intervals (moved) 30, 11-03 19:00 Intervals 60"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("scheduled = %s, want %s", strings.Join(got, ", "), want)
+	}
+	if len(workouts) > 1 && workouts[1].Description != "Pool, lane 3" {
+		t.Errorf("Swim description = %q", workouts[1].Description)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "schedule.ics")
+	part.Write([]byte(file))
+	form.Close()
+	resp = alice.Do("POST", "/api/v1/scheduled-workouts/import", &body, form.FormDataContentType())
+	result = models.ICalImportResult{}
+	resp.JSON(t, &result)
+	if resp.StatusCode != http.StatusOK || result.Imported != 0 || result.Duplicates != 5 {
+		t.Errorf("second import: status %d, %+v; want everything a duplicate", resp.StatusCode, result)
+	}
+
+	resp = alice.Do("POST", "/api/v1/scheduled-workouts/import", strings.NewReader("not a calendar"), "text/calendar")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("import of a non-calendar: status %d, want 400", resp.StatusCode)
+	}
+}
+
+// setTimezone changes the client's user's time zone through the account settings form
+func setTimezone(t *testing.T, client *handlerstest.Client, zone string) {
+	t.Helper()
+	saveSettings(t, client, zone, true)
+}
+
+// saveSettings saves the client's user's time zone and whether they get notifications
+// through the account settings form
+func saveSettings(t *testing.T, client *handlerstest.Client, zone string, notifications bool) {
+	t.Helper()
+	resp := client.PostForm("/account/update-settings", url.Values{"theme": {"light"}, "timezone": {zone}, "weight_unit": {"kg"},
+		"distance_unit": {"km"}, "date_format": {"2006-01-02"}, "language": {"en"}, "e1rm_formula": {"epley"},
+		"notifications": {strconv.FormatBool(notifications)}})
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("update settings: status %d: %s", resp.StatusCode, resp.Body)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"workout-tracker/internal/database"
+	"workout-tracker/internal/storage"
+)
+
+const loadUsage = "usage: server load rebuild"
+
+// runLoad handles the load subcommand, which rebuilds every user's daily training load
+// from their logged workouts, such as after upgrading from a version that did not keep it
+func runLoad(args []string) error {
+	if len(args) != 1 || args[0] != "rebuild" {
+		return fmt.Errorf(loadUsage)
+	}
+
+	db, err := database.Initialize()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	users, err := storage.New(db).TrainingLoad().RebuildAll()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Rebuilt training load for %d users\n", users)
+	return nil
+}
+
+// rebuildMissingLoad fills in the training load of users whose workouts were logged
+// before it was kept, so upgrading needs no rebuild by hand. The server starts
+// regardless; the load subcommand can be run later.
+func rebuildMissingLoad(store storage.Store) {
+	users, err := store.TrainingLoad().RebuildMissing()
+	if err != nil {
+		log.Printf("Failed to rebuild missing training load: %v", err)
+		return
+	}
+	if users > 0 {
+		log.Printf("Rebuilt training load for %d users", users)
+	}
+}
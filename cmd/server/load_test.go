@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"workout-tracker/internal/models"
+)
+
+// TestTrainingLoad logs a run of training days through the API and reads back the daily
+// load, the weekly volume and the metrics recommendations are based on
+func TestTrainingLoad(t *testing.T) {
+	h := newHarness(t) // Thursday 2026-10-15
+	alice := h.LoginAs("alice")
+
+	resp := alice.SendJSON("POST", "/api/v1/predefined-exercises", models.CreatePredefinedExerciseRequest{
+		Name: "Back Squat", Category: "strength", MuscleGroups: "Quads, Glutes",
+	})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create predefined exercise: status %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	rpe := 8.0
+	for _, workout := range []models.CreateWorkoutRequest{
+		{Name: "Monday", Date: "2026-10-12", Duration: 60, Exercises: []models.CreateWorkoutExerciseRequest{
+			{Name: "Back Squat", Category: "Legs", Sets: []models.CreateWorkoutSetRequest{{Reps: 5, Weight: 100, RPE: &rpe}}},
+		}},
+		{Name: "Tuesday", Date: "2026-10-13", Duration: 45, Exercises: []models.CreateWorkoutExerciseRequest{
+			{Name: "Back Squat", Category: "Legs", Sets: []models.CreateWorkoutSetRequest{{Reps: 5, Weight: 100}}},
+		}},
+		{Name: "Wednesday", Date: "2026-10-14", Exercises: []models.CreateWorkoutExerciseRequest{
+			{Name: "Back Squat", Category: "Legs", Sets: []models.CreateWorkoutSetRequest{{Reps: 5, Weight: 110, RPE: &rpe}, {Reps: 5, Weight: 110, RPE: &rpe}}},
+		}},
+	} {
+		if resp := alice.SendJSON("POST", "/api/v1/workouts", workout); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create workout: status %d: %s", resp.StatusCode, resp.Body)
+		}
+	}
+
+	resp = alice.Get("/api/v1/analytics/load?from=2026-10-11")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("training load: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	var body models.TrainingLoadResponse
+	resp.JSON(t, &body)
+
+	// Sunday before the first workout, three sessions and today, which has none yet
+	want := []float64{0, 480, 315, 48, 0}
+	if len(body.Days) != len(want) {
+		t.Fatalf("days = %+v, want %d", body.Days, len(want))
+	}
+	for i, w := range want {
+		if body.Days[i].Load != w {
+			t.Errorf("%s load = %v, want %v", body.Days[i].Date.Format("2006-01-02"), body.Days[i].Load, w)
+		}
+	}
+	if today := body.Days[4]; today.Date.Format("2006-01-02") != "2026-10-15" || today.Fitness <= 0 || today.AcuteLoad <= 0 {
+		t.Errorf("today = %+v, want the load carried over from the week", today)
+	}
+
+	if len(body.Weekly) != 2 || body.Weekly[1].Workouts != 3 || body.Weekly[1].TotalVolume != 2100 || body.Weekly[1].AvgRPE != 8 {
+		t.Errorf("weekly = %+v, want three workouts of 2100 at RPE 8 this week", body.Weekly)
+	}
+
+	metrics := body.Metrics
+	if metrics.ConsecutiveDays != 3 || metrics.LastRestDay == nil || metrics.LastRestDay.Format("2006-01-02") != "2026-10-11" {
+		t.Errorf("streak = %d days after %v, want 3 after 2026-10-11", metrics.ConsecutiveDays, metrics.LastRestDay)
+	}
+	if len(metrics.RecentWorkouts) != 3 || len(metrics.WeeklyVolume) != 4 {
+		t.Errorf("metrics = %d recent workouts and %d weeks, want 3 and 4", len(metrics.RecentWorkouts), len(metrics.WeeklyVolume))
+	}
+	if metrics.MuscleGroupFatigue["Quads"] <= metrics.MuscleGroupFatigue["Glutes"] || metrics.MuscleGroupFatigue["Glutes"] <= 0 {
+		t.Errorf("muscle fatigue = %v, want quads above glutes", metrics.MuscleGroupFatigue)
+	}
+	if score := metrics.PerformanceMetrics.RecoveryScore; score < 0 || score >= 50 {
+		t.Errorf("recovery after three days in a row = %v, want below 50", score)
+	}
+}
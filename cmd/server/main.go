@@ -1,17 +1,44 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"workout-tracker/internal/database"
 	"workout-tracker/internal/handlers"
+	"workout-tracker/internal/storage"
 
 	"github.com/gorilla/mux"
 )
 
 func main() {
+	// Maintenance subcommands: server migrate status|up|down|to N, server orphans [--repair],
+	// server records rebuild, server load rebuild, server schedule skip-overdue
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "migrate":
+			err = runMigrate(os.Args[2:])
+		case "orphans":
+			err = runOrphans(os.Args[2:])
+		case "records":
+			err = runRecords(os.Args[2:])
+		case "load":
+			err = runLoad(os.Args[2:])
+		case "schedule":
+			err = runSchedule(os.Args[2:])
+		default:
+			log.Fatalf("unknown command %q", os.Args[1])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Initialize database
 	db, err := database.Initialize()
 	if err != nil {
@@ -19,10 +46,39 @@ func main() {
 	}
 	defer db.Close()
 
+	store := storage.New(db)
+
+	// Fill in what is derived from data logged before it was kept
+	rebuildMissingRecords(store)
+	rebuildMissingLoad(store)
+
 	// Initialize handlers
 	h := handlers.New(db)
 
+	// Send workout reminders in the background
+	dispatcher, err := newReminderDispatcher(store)
+	if err != nil {
+		log.Fatal("Failed to configure reminders:", err)
+	}
+	go dispatcher.Run(context.Background())
+
+	// Forget idempotency keys once they can no longer be replayed
+	go h.PurgeIdempotencyKeys(context.Background(), time.Hour)
+
 	// Setup routes
+	r := newRouter(h, http.Dir("web/static/"))
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	log.Printf("Starting server on port %s", port)
+	log.Fatal(http.ListenAndServe(":"+port, r))
+}
+
+// newRouter registers every web page, API route and the static file server
+func newRouter(h *handlers.Handler, static http.FileSystem) *mux.Router {
 	r := mux.NewRouter()
 	
 	// Authentication routes
@@ -30,6 +86,9 @@ func main() {
 	r.HandleFunc("/register", h.Register).Methods("GET", "POST")
 	r.HandleFunc("/logout", h.Logout).Methods("GET", "POST")
 	r.HandleFunc("/clear-session", h.ClearSession).Methods("GET")
+
+	// Calendar feed, authenticated by the secret token in its URL
+	r.HandleFunc("/calendar/{token}.ics", h.GetCalendarFeed).Methods("GET")
 	
 	// Account settings routes
 	r.HandleFunc("/account-settings", h.AuthMiddleware(h.AccountSettings)).Methods("GET")
@@ -38,6 +97,8 @@ func main() {
 	r.HandleFunc("/account/update-settings", h.AuthMiddleware(h.UpdateSettings)).Methods("POST")
 	r.HandleFunc("/account/change-password", h.AuthMiddleware(h.ChangePassword)).Methods("POST")
 	r.HandleFunc("/account/delete-account", h.AuthMiddleware(h.DeleteAccount)).Methods("POST")
+	r.HandleFunc("/account/tokens", h.AuthMiddleware(h.CreateAPIToken)).Methods("POST")
+	r.HandleFunc("/account/tokens/{id}/revoke", h.AuthMiddleware(h.RevokeAPIToken)).Methods("POST")
 
 	// Protected web routes
 	r.HandleFunc("/", h.AuthMiddleware(h.Home)).Methods("GET")
@@ -67,68 +128,127 @@ func main() {
 	r.HandleFunc("/programs/{id}", h.AuthMiddleware(h.ProgramDetails)).Methods("GET")
 	r.HandleFunc("/programs/{id}/edit", h.AuthMiddleware(h.ProgramEdit)).Methods("GET")
 	
-	// API routes
-	r.HandleFunc("/api/workouts", h.AuthMiddleware(h.APIGetWorkouts)).Methods("GET")
-	r.HandleFunc("/api/workouts", h.AuthMiddleware(h.APICreateWorkout)).Methods("POST")
-	r.HandleFunc("/api/workouts/{id}", h.AuthMiddleware(h.APIGetWorkout)).Methods("GET")
-	r.HandleFunc("/api/workouts/{id}", h.AuthMiddleware(h.UpdateWorkout)).Methods("PUT")
-	r.HandleFunc("/api/workouts/{id}", h.AuthMiddleware(h.DeleteWorkout)).Methods("DELETE")
+	// Versioned API: JSON error envelope and 401/403 statuses instead of login redirects
+	v1 := r.PathPrefix("/api/v1").Subrouter()
+	v1.NotFoundHandler = http.HandlerFunc(h.APINotFound)
+	v1.MethodNotAllowedHandler = http.HandlerFunc(h.APIMethodNotAllowed)
+	registerAPIRoutes(v1, h, h.APIv1Middleware)
+
+	// Unversioned API kept for the existing web pages
+	registerAPIRoutes(r.PathPrefix("/api").Subrouter(), h, h.AuthMiddleware)
+	
+	// Static files
+	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(static)))
+
+	return r
+}
+
+// registerAPIRoutes mounts every JSON API route on the given router using the given auth middleware.
+// Create endpoints honour the Idempotency-Key header.
+func registerAPIRoutes(api *mux.Router, h *handlers.Handler, auth func(http.HandlerFunc) http.HandlerFunc) {
+	// Workout API routes
+	api.HandleFunc("/workouts", auth(h.APIGetWorkouts)).Methods("GET")
+	api.HandleFunc("/workouts", auth(h.Idempotent(h.APICreateWorkout))).Methods("POST")
+	api.HandleFunc("/workouts/{id}", auth(h.APIGetWorkout)).Methods("GET")
+	api.HandleFunc("/workouts/{id}", auth(h.UpdateWorkout)).Methods("PUT")
+	api.HandleFunc("/workouts/{id}", auth(h.DeleteWorkout)).Methods("DELETE")
+	api.HandleFunc("/workouts/{id}/exercises/order", auth(h.ReorderExercises)).Methods("PUT")
+	
+	// Live workout session API routes
+	api.HandleFunc("/sessions", auth(h.Idempotent(h.StartSession))).Methods("POST")
+	api.HandleFunc("/sessions/active", auth(h.GetActiveSession)).Methods("GET")
+	api.HandleFunc("/sessions/{id}", auth(h.GetSession)).Methods("GET")
+	api.HandleFunc("/sessions/{id}", auth(h.UpdateSession)).Methods("PUT")
+	api.HandleFunc("/sessions/{id}", auth(h.DeleteSession)).Methods("DELETE")
+	api.HandleFunc("/sessions/{id}/exercises", auth(h.Idempotent(h.AddSessionExercise))).Methods("POST")
+	api.HandleFunc("/sessions/{id}/sets", auth(h.Idempotent(h.CompleteSessionSet))).Methods("POST")
+	api.HandleFunc("/sessions/{id}/finish", auth(h.Idempotent(h.FinishSession))).Methods("POST")
+	
+	// Server-sent events for changes made on other devices
+	api.HandleFunc("/events", auth(h.StreamEvents)).Methods("GET")
+	
+	// Personal access token API routes
+	api.HandleFunc("/tokens", auth(h.GetAPITokens)).Methods("GET")
+	api.HandleFunc("/tokens", auth(h.Idempotent(h.CreateAPIToken))).Methods("POST")
+	api.HandleFunc("/tokens/{id}", auth(h.RevokeAPIToken)).Methods("DELETE")
 	
 	// Exercise API routes
-	r.HandleFunc("/api/exercises", h.AuthMiddleware(h.CreateExercise)).Methods("POST")
-	r.HandleFunc("/api/exercises/{id}", h.AuthMiddleware(h.UpdateExercise)).Methods("PUT")
-	r.HandleFunc("/api/exercises/{id}", h.AuthMiddleware(h.DeleteExercise)).Methods("DELETE")
+	api.HandleFunc("/exercises", auth(h.Idempotent(h.CreateExercise))).Methods("POST")
+	api.HandleFunc("/exercises/{id}", auth(h.UpdateExercise)).Methods("PUT")
+	api.HandleFunc("/exercises/{id}", auth(h.DeleteExercise)).Methods("DELETE")
 	
 	// Set API routes
-	r.HandleFunc("/api/sets", h.AuthMiddleware(h.CreateSet)).Methods("POST")
-	r.HandleFunc("/api/sets/{id}", h.AuthMiddleware(h.UpdateSet)).Methods("PUT")
-	r.HandleFunc("/api/sets/{id}", h.AuthMiddleware(h.DeleteSet)).Methods("DELETE")
+	api.HandleFunc("/sets", auth(h.Idempotent(h.CreateSet))).Methods("POST")
+	api.HandleFunc("/sets/{id}", auth(h.UpdateSet)).Methods("PUT")
+	api.HandleFunc("/sets/{id}", auth(h.DeleteSet)).Methods("DELETE")
 	
 	// Predefined exercise API routes
-	r.HandleFunc("/api/predefined-exercises", h.AuthMiddleware(h.GetPredefinedExercises)).Methods("GET")
-	r.HandleFunc("/api/predefined-exercises", h.AuthMiddleware(h.CreatePredefinedExercise)).Methods("POST")
-	r.HandleFunc("/api/predefined-exercises/category/{category}", h.AuthMiddleware(h.GetPredefinedExercisesByCategory)).Methods("GET")
+	api.HandleFunc("/predefined-exercises", auth(h.GetPredefinedExercises)).Methods("GET")
+	api.HandleFunc("/predefined-exercises", auth(h.Idempotent(h.CreatePredefinedExercise))).Methods("POST")
+	api.HandleFunc("/predefined-exercises/category/{category}", auth(h.GetPredefinedExercisesByCategory)).Methods("GET")
+	api.HandleFunc("/muscles", auth(h.GetMuscles)).Methods("GET")
 	
 	// Nutrition and Body tracking API routes
-	r.HandleFunc("/api/meals", h.AuthMiddleware(h.CreateMeal)).Methods("POST")
-	r.HandleFunc("/api/body-weights", h.AuthMiddleware(h.CreateBodyWeight)).Methods("POST")
-	r.HandleFunc("/api/body-fats", h.AuthMiddleware(h.CreateBodyFat)).Methods("POST")
-	r.HandleFunc("/api/body-measurements", h.AuthMiddleware(h.CreateBodyMeasurement)).Methods("POST")
+	api.HandleFunc("/meals", auth(h.Idempotent(h.CreateMeal))).Methods("POST")
+	api.HandleFunc("/body-weights", auth(h.Idempotent(h.CreateBodyWeight))).Methods("POST")
+	api.HandleFunc("/body-fats", auth(h.Idempotent(h.CreateBodyFat))).Methods("POST")
+	api.HandleFunc("/body-measurements", auth(h.Idempotent(h.CreateBodyMeasurement))).Methods("POST")
 	
 	// Analytics API routes
-	r.HandleFunc("/api/analytics", h.AuthMiddleware(h.AnalyticsAPI)).Methods("GET")
-	r.HandleFunc("/api/weekly-summary", h.AuthMiddleware(h.WeeklySummaryAPI)).Methods("GET")
-	r.HandleFunc("/api/monthly-summary", h.AuthMiddleware(h.MonthlySummaryAPI)).Methods("GET")
+	api.HandleFunc("/analytics", auth(h.AnalyticsAPI)).Methods("GET")
+	api.HandleFunc("/weekly-summary", auth(h.WeeklySummaryAPI)).Methods("GET")
+	api.HandleFunc("/monthly-summary", auth(h.MonthlySummaryAPI)).Methods("GET")
+	api.HandleFunc("/analytics/muscle-volume", auth(h.GetMuscleVolume)).Methods("GET")
+	api.HandleFunc("/analytics/load", auth(h.GetTrainingLoad)).Methods("GET")
 	
 	// Exercise progress chart API routes
-	r.HandleFunc("/api/exercise-progress/{exercise}", h.AuthMiddleware(h.GetExerciseProgressChart)).Methods("GET")
-	r.HandleFunc("/api/exercise-list", h.AuthMiddleware(h.GetExerciseList)).Methods("GET")
+	api.HandleFunc("/exercise-progress/{exercise}", auth(h.GetExerciseProgressChart)).Methods("GET")
+	api.HandleFunc("/exercise-list", auth(h.GetExerciseList)).Methods("GET")
+	api.HandleFunc("/personal-records", auth(h.GetPersonalRecords)).Methods("GET")
 	
 	// Workout Template API routes
-	r.HandleFunc("/api/templates", h.AuthMiddleware(h.GetWorkoutTemplates)).Methods("GET")
-	r.HandleFunc("/api/templates", h.AuthMiddleware(h.CreateWorkoutTemplate)).Methods("POST")
-	r.HandleFunc("/api/templates/{id}", h.AuthMiddleware(h.GetWorkoutTemplate)).Methods("GET")
-	r.HandleFunc("/api/templates/{id}", h.AuthMiddleware(h.UpdateWorkoutTemplate)).Methods("PUT")
-	r.HandleFunc("/api/templates/{id}", h.AuthMiddleware(h.DeleteWorkoutTemplate)).Methods("DELETE")
-	r.HandleFunc("/api/templates/{id}/share", h.AuthMiddleware(h.ShareWorkoutTemplate)).Methods("POST")
-	r.HandleFunc("/api/templates/{template_id}/create-workout", h.AuthMiddleware(h.CreateWorkoutFromTemplate)).Methods("POST")
-	r.HandleFunc("/api/shared-templates", h.AuthMiddleware(h.GetSharedTemplates)).Methods("GET")
+	api.HandleFunc("/templates", auth(h.GetWorkoutTemplates)).Methods("GET")
+	api.HandleFunc("/templates", auth(h.Idempotent(h.CreateWorkoutTemplate))).Methods("POST")
+	api.HandleFunc("/templates/{id}", auth(h.GetWorkoutTemplate)).Methods("GET")
+	api.HandleFunc("/templates/{id}", auth(h.UpdateWorkoutTemplate)).Methods("PUT")
+	api.HandleFunc("/templates/{id}", auth(h.DeleteWorkoutTemplate)).Methods("DELETE")
+	api.HandleFunc("/templates/{id}/share", auth(h.ShareWorkoutTemplate)).Methods("POST")
+	api.HandleFunc("/templates/{template_id}/create-workout", auth(h.Idempotent(h.CreateWorkoutFromTemplate))).Methods("POST")
+	api.HandleFunc("/shared-templates", auth(h.GetSharedTemplates)).Methods("GET")
 	
 	// Workout Program API routes
-	r.HandleFunc("/api/programs", h.AuthMiddleware(h.GetWorkoutPrograms)).Methods("GET")
-	r.HandleFunc("/api/programs", h.AuthMiddleware(h.CreateWorkoutProgram)).Methods("POST")
-	r.HandleFunc("/api/programs/{id}", h.AuthMiddleware(h.GetWorkoutProgram)).Methods("GET")
-	r.HandleFunc("/api/programs/{id}", h.AuthMiddleware(h.UpdateWorkoutProgram)).Methods("PUT")
-	r.HandleFunc("/api/programs/{id}", h.AuthMiddleware(h.DeleteWorkoutProgram)).Methods("DELETE")
+	api.HandleFunc("/programs", auth(h.GetWorkoutPrograms)).Methods("GET")
+	api.HandleFunc("/programs", auth(h.Idempotent(h.CreateWorkoutProgram))).Methods("POST")
+	api.HandleFunc("/programs/{id}", auth(h.GetWorkoutProgram)).Methods("GET")
+	api.HandleFunc("/programs/{id}", auth(h.UpdateWorkoutProgram)).Methods("PUT")
+	api.HandleFunc("/programs/{id}", auth(h.DeleteWorkoutProgram)).Methods("DELETE")
 	
-	// Static files
-	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("web/static/"))))
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	log.Printf("Starting server on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	// Planning API routes
+	api.HandleFunc("/scheduled-workouts", auth(h.GetScheduledWorkouts)).Methods("GET")
+	api.HandleFunc("/scheduled-workouts", auth(h.Idempotent(h.CreateScheduledWorkout))).Methods("POST")
+	api.HandleFunc("/scheduled-workouts/import", auth(h.ImportScheduledWorkouts)).Methods("POST")
+	api.HandleFunc("/scheduled-workouts/{id}", auth(h.GetScheduledWorkout)).Methods("GET")
+	api.HandleFunc("/scheduled-workouts/{id}", auth(h.UpdateScheduledWorkout)).Methods("PUT")
+	api.HandleFunc("/scheduled-workouts/{id}", auth(h.DeleteScheduledWorkout)).Methods("DELETE")
+	api.HandleFunc("/scheduled-workouts/{id}/reminders", auth(h.GetReminders)).Methods("GET")
+	api.HandleFunc("/scheduled-workouts/{id}/reminders", auth(h.Idempotent(h.CreateReminder))).Methods("POST")
+	api.HandleFunc("/reminders/{id}", auth(h.DeleteReminder)).Methods("DELETE")
+	api.HandleFunc("/calendar", auth(h.GetCalendarMonth)).Methods("GET")
+	api.HandleFunc("/calendar/events", auth(h.GetCalendarEvents)).Methods("GET")
+	api.HandleFunc("/calendar/events", auth(h.Idempotent(h.CreateCalendarEvent))).Methods("POST")
+	api.HandleFunc("/calendar/events/{id}", auth(h.GetCalendarEvent)).Methods("GET")
+	api.HandleFunc("/calendar/events/{id}", auth(h.UpdateCalendarEvent)).Methods("PUT")
+	api.HandleFunc("/calendar/events/{id}", auth(h.DeleteCalendarEvent)).Methods("DELETE")
+	api.HandleFunc("/calendar/events/{id}/occurrences/{date}", auth(h.UpdateCalendarOccurrence)).Methods("PUT")
+	api.HandleFunc("/calendar/events/{id}/occurrences/{date}", auth(h.DeleteCalendarOccurrence)).Methods("DELETE")
+	api.HandleFunc("/calendar/feed", auth(h.GetCalendarFeedToken)).Methods("GET")
+	api.HandleFunc("/calendar/feed", auth(h.RegenerateCalendarFeed)).Methods("POST")
+	api.HandleFunc("/calendar/feed", auth(h.DeleteCalendarFeed)).Methods("DELETE")
+	api.HandleFunc("/rest-days", auth(h.GetRestDays)).Methods("GET")
+	api.HandleFunc("/rest-days/{id}", auth(h.GetRestDay)).Methods("GET")
+	api.HandleFunc("/rest-days/{id}/response", auth(h.RespondToRestDay)).Methods("POST")
+	api.HandleFunc("/deloads", auth(h.GetDeloads)).Methods("GET")
+	api.HandleFunc("/deloads/{id}", auth(h.GetDeload)).Methods("GET")
+	api.HandleFunc("/deloads/{id}/response", auth(h.RespondToDeload)).Methods("POST")
+	api.HandleFunc("/planning/analytics", auth(h.GetPlanningAnalytics)).Methods("GET")
 }
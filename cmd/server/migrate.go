@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"workout-tracker/internal/database"
+)
+
+const migrateUsage = "usage: server migrate status|up|down|to N"
+
+// runMigrate handles the migrate subcommand
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "status":
+		return printMigrationStatus(db)
+	case "up":
+		return db.MigrateUp()
+	case "down":
+		return db.MigrateDown()
+	case "to":
+		if len(args) != 2 {
+			return fmt.Errorf(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid migration version %q", args[1])
+		}
+		return db.MigrateTo(version)
+	}
+
+	return fmt.Errorf(migrateUsage)
+}
+
+// printMigrationStatus writes a table of migrations and their state to stdout
+func printMigrationStatus(db *database.DB) error {
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state := "pending"
+		appliedAt := ""
+		if s.Applied {
+			state = "applied"
+			if s.Modified {
+				state = "applied (modified)"
+			}
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"workout-tracker/internal/models"
+)
+
+// TestMuscleVolume adds library exercises through the API, logs workouts with them and
+// reads back the weekly hard sets and tonnage of each muscle
+func TestMuscleVolume(t *testing.T) {
+	h := newHarness(t) // Thursday 2026-10-15
+	alice := h.LoginAs("alice")
+
+	for _, req := range []models.CreatePredefinedExerciseRequest{
+		{Name: "Bench Press", Category: "strength", MuscleGroups: "Chest, Anterior Deltoids, Triceps"},
+		{Name: "Barbell Row", Category: "strength", MuscleGroups: "Lats, Rhomboids, Biceps"},
+	} {
+		resp := alice.SendJSON("POST", "/api/v1/predefined-exercises", req)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create predefined exercise: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var exercise models.PredefinedExercise
+		resp.JSON(t, &exercise)
+		if req.Name == "Barbell Row" && (len(exercise.PrimaryMuscles) != 1 || exercise.PrimaryMuscles[0] != "Lats" || len(exercise.SecondaryMuscles) != 2) {
+			t.Errorf("barbell row muscles = %v primary, %v secondary; want Lats, then Biceps and Upper Back", exercise.PrimaryMuscles, exercise.SecondaryMuscles)
+		}
+	}
+
+	rpe, rir := 5.0, 2
+	for _, workout := range []models.CreateWorkoutRequest{
+		{Name: "Last week", Date: "2026-10-06", Exercises: []models.CreateWorkoutExerciseRequest{
+			{Name: "Bench Press", Category: "Chest", Sets: []models.CreateWorkoutSetRequest{{Reps: 5, Weight: 90}}},
+		}},
+		{Name: "This week", Date: "2026-10-13", Exercises: []models.CreateWorkoutExerciseRequest{
+			{Name: "bench press", Category: "Chest", Sets: []models.CreateWorkoutSetRequest{
+				{Reps: 10, Weight: 60, SetType: models.SetTypeWarmup},
+				{Reps: 5, Weight: 100},
+				{Reps: 5, Weight: 100, RPE: &rpe},
+			}},
+			{Name: "Barbell Row", Category: "Back", Sets: []models.CreateWorkoutSetRequest{{Reps: 8, Weight: 80, RIR: &rir}}},
+			{Name: "Plank", Category: "Core", Sets: []models.CreateWorkoutSetRequest{{Duration: 60}}},
+		}},
+	} {
+		if resp := alice.SendJSON("POST", "/api/v1/workouts", workout); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create workout: status %d: %s", resp.StatusCode, resp.Body)
+		}
+	}
+
+	volume := func(query string) models.MuscleVolumeResponse {
+		t.Helper()
+		resp := alice.Get("/api/v1/analytics/muscle-volume?" + query)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("muscle volume: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var body models.MuscleVolumeResponse
+		resp.JSON(t, &body)
+		return body
+	}
+	muscle := func(week models.MuscleVolumeWeek, name string) models.MuscleVolume {
+		for _, m := range week.Muscles {
+			if m.Muscle == name {
+				return m
+			}
+		}
+		return models.MuscleVolume{}
+	}
+
+	body := volume("weeks=2")
+	if len(body.Weeks) != 2 || body.Weeks[0].WeekStart != "2026-10-05" || body.Weeks[1].WeekStart != "2026-10-12" {
+		t.Fatalf("weeks = %+v, want the weeks of 5 and 12 October", body.Weeks)
+	}
+	if got := muscle(body.Weeks[0], "Chest"); got.HardSets != 1 || got.Tonnage != 450 {
+		t.Errorf("last week's chest = %+v, want 1 hard set and 450 tonnage", got)
+	}
+
+	// The warmup is left out and the set at RPE 5 moves weight without counting as hard
+	thisWeek := body.Weeks[1]
+	want := map[string]models.MuscleVolume{
+		"Chest":       {HardSets: 1, Tonnage: 1000},
+		"Front Delts": {HardSets: 0.5, Tonnage: 500},
+		"Triceps":     {HardSets: 0.5, Tonnage: 500},
+		"Lats":        {HardSets: 1, Tonnage: 640},
+		"Upper Back":  {HardSets: 0.5, Tonnage: 320},
+		"Biceps":      {HardSets: 0.5, Tonnage: 320},
+	}
+	if len(thisWeek.Muscles) != len(want) {
+		t.Errorf("this week = %+v, want %d muscles", thisWeek.Muscles, len(want))
+	}
+	for name, w := range want {
+		if got := muscle(thisWeek, name); got.HardSets != w.HardSets || got.Tonnage != w.Tonnage {
+			t.Errorf("this week's %s = %+v, want %v hard sets and %v tonnage", name, got, w.HardSets, w.Tonnage)
+		}
+	}
+	if len(body.UnlinkedExercises) != 1 || body.UnlinkedExercises[0] != "Plank" {
+		t.Errorf("unlinked exercises = %v, want Plank", body.UnlinkedExercises)
+	}
+
+	body = volume("weeks=1&secondary_fraction=0.25")
+	if len(body.Weeks) != 1 || body.SecondaryFraction != 0.25 {
+		t.Fatalf("response = %+v, want this week at a quarter", body)
+	}
+	if got := muscle(body.Weeks[0], "Triceps"); got.HardSets != 0.25 || got.Tonnage != 250 {
+		t.Errorf("triceps at a quarter = %+v, want 0.25 hard sets and 250 tonnage", got)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"workout-tracker/internal/database"
+)
+
+const orphansUsage = "usage: server orphans [--repair]"
+
+// runOrphans handles the orphans subcommand, which reports rows whose parent or owner
+// is missing and, with --repair, deletes them or clears the dangling reference
+func runOrphans(args []string) error {
+	repair := false
+	for _, arg := range args {
+		if arg != "--repair" {
+			return fmt.Errorf(orphansUsage)
+		}
+		repair = true
+	}
+
+	db, err := database.Initialize()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var orphans []database.Orphan
+	if repair {
+		orphans, err = db.RepairOrphans()
+	} else {
+		orphans, err = db.ScanOrphans()
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(orphans) == 0 {
+		fmt.Println("No orphaned rows found")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tCOLUMN\tMISSING PARENT\tROWS\tACTION")
+	for _, o := range orphans {
+		action := "would delete"
+		if repair {
+			action = "deleted"
+		}
+		if o.OnDelete == "SET NULL" {
+			action = "would set to null"
+			if repair {
+				action = "set to null"
+			}
+		}
+		parent := o.Parent
+		if o.Unowned {
+			parent += " (none set)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", o.Table, o.Column, parent, len(o.RowIDs), action)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if !repair {
+		fmt.Println("Run with --repair to fix these rows")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"workout-tracker/internal/database"
+	"workout-tracker/internal/storage"
+)
+
+const recordsUsage = "usage: server records rebuild"
+
+// runRecords handles the records subcommand, which rebuilds every user's personal
+// records from their logged sets, such as after upgrading from a version that did
+// not keep them
+func runRecords(args []string) error {
+	if len(args) != 1 || args[0] != "rebuild" {
+		return fmt.Errorf(recordsUsage)
+	}
+
+	db, err := database.Initialize()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	users, err := storage.New(db).PersonalRecords().RebuildAll()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Rebuilt personal records for %d users\n", users)
+	return nil
+}
+
+// rebuildMissingRecords fills in the personal records of users whose sets were logged
+// before records were kept, so upgrading needs no rebuild by hand. The server starts
+// regardless; the records subcommand can be run later.
+func rebuildMissingRecords(store storage.Store) {
+	users, err := store.PersonalRecords().RebuildMissing()
+	if err != nil {
+		log.Printf("Failed to rebuild missing personal records: %v", err)
+		return
+	}
+	if users > 0 {
+		log.Printf("Rebuilt personal records for %d users", users)
+	}
+}
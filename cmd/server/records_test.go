@@ -0,0 +1,100 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"workout-tracker/internal/models"
+)
+
+// TestPersonalRecords logs sets through the API and follows the records as sets are
+// logged, corrected and deleted, and as the user switches e1RM formula
+func TestPersonalRecords(t *testing.T) {
+	h := newHarness(t)
+	alice := h.LoginAs("alice")
+
+	logWorkout := func(date string, sets ...models.CreateWorkoutSetRequest) models.Workout {
+		t.Helper()
+		resp := alice.SendJSON("POST", "/api/v1/workouts", models.CreateWorkoutRequest{Name: "Legs", Date: date, Exercises: []models.CreateWorkoutExerciseRequest{
+			{Name: "Squat", Category: "Legs", Sets: sets},
+		}})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create workout: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var workout models.Workout
+		resp.JSON(t, &workout)
+		return workout
+	}
+	records := func() models.ExerciseRecords {
+		t.Helper()
+		resp := alice.Get("/api/v1/personal-records?exercise=squat")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("personal records: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var body models.PersonalRecordsResponse
+		resp.JSON(t, &body)
+		if len(body.Exercises) != 1 {
+			t.Fatalf("personal records = %+v, want squat only", body)
+		}
+		return body.Exercises[0]
+	}
+	find := func(records []models.PersonalRecordEntry, recordType string, reps int) (models.PersonalRecordEntry, bool) {
+		for _, r := range records {
+			if r.RecordType == recordType && (recordType != models.RecordTypeWeight || r.Reps == reps) {
+				return r, true
+			}
+		}
+		return models.PersonalRecordEntry{}, false
+	}
+
+	logWorkout("2026-10-01", models.CreateWorkoutSetRequest{Reps: 5, Weight: 140}, models.CreateWorkoutSetRequest{Reps: 10, Weight: 100})
+	heavy := logWorkout("2026-10-08", models.CreateWorkoutSetRequest{Reps: 5, Weight: 150})
+
+	squat := records()
+	if r, _ := find(squat.Current, models.RecordTypeWeight, 5); r.Weight != 150 || r.WorkoutID != heavy.ID {
+		t.Errorf("current 5RM = %+v, want 150 from workout %d", r, heavy.ID)
+	}
+	if r, _ := find(squat.Current, models.RecordTypeWeight, 10); r.Weight != 100 {
+		t.Errorf("current 10RM = %+v, want 100", r)
+	}
+	if r, _ := find(squat.Current, models.RecordTypeSessionVolume, 0); r.Volume != 1700 {
+		t.Errorf("current session volume = %+v, want 1700", r)
+	}
+	// The first 5RM and e1RM were beaten in the second workout, the first set's volume
+	// by the second set
+	if len(squat.History) != len(squat.Current)+3 {
+		t.Errorf("history = %+v, want three superseded records", squat.History)
+	}
+
+	// Correcting a mistyped set takes its record away again
+	set := heavy.Exercises[0].Sets[0]
+	resp := alice.SendJSON("PUT", "/api/v1/sets/"+strconv.Itoa(set.ID), models.UpdateSetRequest{SetNumber: 1, Reps: 5, Weight: 130})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update set: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	squat = records()
+	if r, _ := find(squat.Current, models.RecordTypeWeight, 5); r.Weight != 140 {
+		t.Errorf("5RM after the correction = %+v, want 140", r)
+	}
+	if r, _ := find(squat.Current, models.RecordTypeE1RM, 0); math.Abs(r.OneRepMax-140*(1+5/30.0)) > 0.001 {
+		t.Errorf("Epley e1RM = %+v", r)
+	}
+
+	resp = alice.PostForm("/account/update-settings", url.Values{"theme": {"light"}, "e1rm_formula": {"brzycki"}})
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("update settings: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	if r, _ := find(records().Current, models.RecordTypeE1RM, 0); math.Abs(r.OneRepMax-140*36/32.0) > 0.001 {
+		t.Errorf("Brzycki e1RM = %+v, want %v", r, 140*36/32.0)
+	}
+
+	if resp := alice.Delete("/api/v1/sets/" + strconv.Itoa(set.ID)); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete set: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	if squat = records(); len(squat.History) != len(squat.Current)+1 || squat.History[len(squat.History)-1].WorkoutID == heavy.ID {
+		t.Errorf("records after deleting the second workout's only set = %+v, want only the first workout's", squat)
+	}
+}
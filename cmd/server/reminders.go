@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/notify"
+	"workout-tracker/internal/storage"
+)
+
+// newReminderDispatcher returns the dispatcher for the channels configured in the
+// environment. Email needs SMTP_ADDR and SMTP_FROM, with SMTP_USERNAME and SMTP_PASSWORD
+// if the server wants them. Reminders of channels left unconfigured stay pending.
+func newReminderDispatcher(store storage.Store) (*notify.Dispatcher, error) {
+	var opts []notify.DispatcherOption
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		email, err := notify.NewSMTP(notify.SMTPConfig{
+			Addr:     addr,
+			From:     os.Getenv("SMTP_FROM"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, notify.WithChannel(models.ReminderTypeEmail, email))
+	} else {
+		log.Printf("SMTP_ADDR is not set, email reminders will not be sent")
+	}
+	return notify.NewDispatcher(store, opts...), nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"workout-tracker/internal/handlers/handlerstest"
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/notify"
+	"workout-tracker/internal/notify/smtptest"
+	"workout-tracker/internal/storage"
+)
+
+// TestReminderDispatcher sets reminders through the API and sends them by email to a fake
+// SMTP server, retrying a rejected delivery, leaving SMS reminders to a channel that is
+// not configured and cancelling those of a user who turned notifications off
+func TestReminderDispatcher(t *testing.T) {
+	h := newHarness(t) // Thursday 2026-10-15 12:00 UTC
+	alice := h.LoginAs("alice")
+	setTimezone(t, alice, "Europe/Berlin")
+	bob := h.LoginAs("bob")
+	saveSettings(t, bob, "UTC", false)
+
+	server := smtptest.NewServer(t)
+	email, err := notify.NewSMTP(notify.SMTPConfig{Addr: server.Addr, From: "Workout Tracker <gym@example.com>"})
+	if err != nil {
+		t.Fatalf("NewSMTP: %v", err)
+	}
+	dispatcher := notify.NewDispatcher(h.Store, notify.WithChannel(models.ReminderTypeEmail, email),
+		notify.WithClock(h.Clock.Now), notify.WithRetry(3, time.Minute))
+	dispatch := func(want int) {
+		t.Helper()
+		sent, err := dispatcher.DispatchDue(context.Background())
+		if err != nil || sent != want {
+			t.Fatalf("DispatchDue = %d, %v; want %d sent", sent, err, want)
+		}
+	}
+
+	schedule := func(client *handlerstest.Client, title, date, clock string) int {
+		t.Helper()
+		resp := client.SendJSON("POST", "/api/v1/scheduled-workouts", models.CreateScheduledWorkoutRequest{Title: title, ScheduledDate: date, ScheduledTime: clock})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("schedule workout: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var sw models.ScheduledWorkout
+		resp.JSON(t, &sw)
+		return sw.ID
+	}
+	remind := func(client *handlerstest.Client, scheduledID int, reminderType string, minutes int) models.WorkoutReminder {
+		t.Helper()
+		resp := client.SendJSON("POST", fmt.Sprintf("/api/v1/scheduled-workouts/%d/reminders", scheduledID),
+			models.CreateReminderRequest{ReminderType: reminderType, Message: "Bring your belt.", MinutesBefore: minutes})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create reminder: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var reminder models.WorkoutReminder
+		resp.JSON(t, &reminder)
+		return reminder
+	}
+	reminder := func(client *handlerstest.Client, scheduledID, id int) models.WorkoutReminder {
+		t.Helper()
+		var reminders []models.WorkoutReminder
+		client.Get(fmt.Sprintf("/api/v1/scheduled-workouts/%d/reminders", scheduledID)).JSON(t, &reminders)
+		for _, r := range reminders {
+			if r.ID == id {
+				return r
+			}
+		}
+		t.Fatalf("reminder %d not listed: %+v", id, reminders)
+		return models.WorkoutReminder{}
+	}
+
+	// 18:00 in Berlin is 16:00 UTC, so the reminder is due at 15:00 UTC
+	legs := schedule(alice, "Legs", "2026-10-16", "18:00")
+	legsEmail := remind(alice, legs, models.ReminderTypeEmail, 60)
+	if want := time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC); !legsEmail.ScheduledFor.Equal(want) || legsEmail.Status != models.ReminderStatusPending {
+		t.Errorf("reminder = %+v, want pending for %v", legsEmail, want)
+	}
+	legsSMS := remind(alice, legs, models.ReminderTypeSMS, 60)
+	run := schedule(bob, "Run", "2026-10-16", "17:00")
+	runEmail := remind(bob, run, models.ReminderTypeEmail, 120)
+
+	dispatch(0)
+	if mail := server.Mail(); len(mail) != 0 {
+		t.Fatalf("sent %d emails before any reminder was due", len(mail))
+	}
+
+	// The server turns the first delivery away, so it is retried a minute later
+	h.Clock.Set(time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC))
+	server.FailNext(1)
+	dispatch(0)
+	failed := reminder(alice, legs, legsEmail.ID)
+	if failed.Status != models.ReminderStatusPending || failed.Attempts != 1 || failed.ErrorMessage == nil ||
+		!strings.Contains(*failed.ErrorMessage, "451") || failed.NextAttemptAt == nil || !failed.NextAttemptAt.Equal(h.Clock.Now().Add(time.Minute)) {
+		t.Errorf("reminder after a rejected delivery = %+v", failed)
+	}
+	dispatch(0)
+	h.Clock.Advance(time.Minute)
+	dispatch(1)
+
+	mail := server.Mail()
+	if len(mail) != 1 || mail[0].From != "gym@example.com" || len(mail[0].To) != 1 || mail[0].To[0] != "alice@example.com" {
+		t.Fatalf("mail = %+v, want one email to alice", mail)
+	}
+	msg, err := mail[0].Message()
+	if err != nil {
+		t.Fatalf("email does not parse: %v\n%s", err, mail[0].Data)
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if subject := msg.Header.Get("Subject"); subject != "Reminder: Legs today at 18:00" {
+		t.Errorf("Subject = %q", subject)
+	}
+	if date, err := msg.Header.Date(); err != nil || date.Format("15:04 -0700") != "17:01 +0200" {
+		t.Errorf("Date = %v, %v; want the time in Berlin", date, err)
+	}
+	if want := "Bring your belt.\r\n\r\nLegs is scheduled today at 18:00 (Europe/Berlin).\r\n"; string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+	if sent := reminder(alice, legs, legsEmail.ID); sent.Status != models.ReminderStatusSent || sent.SentAt == nil || sent.Attempts != 2 {
+		t.Errorf("sent reminder = %+v", sent)
+	}
+
+	// Bob turned notifications off; nothing sends SMS
+	if cancelled := reminder(bob, run, runEmail.ID); cancelled.Status != models.ReminderStatusCancelled || cancelled.ErrorMessage == nil ||
+		*cancelled.ErrorMessage != "notifications are turned off" {
+		t.Errorf("reminder of a user without notifications = %+v, want it cancelled", cancelled)
+	}
+	if sms := reminder(alice, legs, legsSMS.ID); sms.Status != models.ReminderStatusPending || sms.Attempts != 0 {
+		t.Errorf("SMS reminder = %+v, want it left pending", sms)
+	}
+
+	// A delivery rejected every time is given up after three attempts
+	arms := schedule(alice, "Arms", "2026-10-17", "")
+	armsEmail := remind(alice, arms, models.ReminderTypeEmail, 60)
+	h.Clock.Set(time.Date(2026, 10, 16, 21, 0, 0, 0, time.UTC)) // 23:00 in Berlin
+	server.FailNext(3)
+	dispatch(0)
+	h.Clock.Advance(time.Minute)
+	dispatch(0)
+	h.Clock.Advance(2 * time.Minute)
+	dispatch(0)
+	if gaveUp := reminder(alice, arms, armsEmail.ID); gaveUp.Status != models.ReminderStatusFailed || gaveUp.Attempts != 3 || gaveUp.NextAttemptAt != nil {
+		t.Errorf("reminder rejected three times = %+v, want it failed", gaveUp)
+	}
+
+	// Reminders cannot be set once the workout has started
+	resp := alice.SendJSON("POST", fmt.Sprintf("/api/v1/scheduled-workouts/%d/reminders", legs),
+		models.CreateReminderRequest{ReminderType: models.ReminderTypeEmail, Message: "Late", MinutesBefore: 10})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("reminder for a started workout: status %d, want 400", resp.StatusCode)
+	}
+}
+
+// TestRemindersFollowTheirWorkout moves a workout and then the user's time zone after a
+// reminder was set, and checks the reminder is sent before the workout's new start
+func TestRemindersFollowTheirWorkout(t *testing.T) {
+	h := newHarness(t) // Thursday 2026-10-15 12:00 UTC
+	alice := h.LoginAs("alice")
+	setTimezone(t, alice, "Europe/Berlin")
+
+	server := smtptest.NewServer(t)
+	email, err := notify.NewSMTP(notify.SMTPConfig{Addr: server.Addr, From: "gym@example.com"})
+	if err != nil {
+		t.Fatalf("NewSMTP: %v", err)
+	}
+	dispatcher := notify.NewDispatcher(h.Store, notify.WithChannel(models.ReminderTypeEmail, email), notify.WithClock(h.Clock.Now))
+
+	resp := alice.SendJSON("POST", "/api/v1/scheduled-workouts", models.CreateScheduledWorkoutRequest{Title: "Legs", ScheduledDate: "2026-10-20", ScheduledTime: "18:00"})
+	var sw models.ScheduledWorkout
+	resp.JSON(t, &sw)
+	resp = alice.SendJSON("POST", fmt.Sprintf("/api/v1/scheduled-workouts/%d/reminders", sw.ID),
+		models.CreateReminderRequest{ReminderType: models.ReminderTypeEmail, Message: "Legs day", MinutesBefore: 60})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create reminder: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	scheduledFor := func() time.Time {
+		t.Helper()
+		var reminders []models.WorkoutReminder
+		alice.Get(fmt.Sprintf("/api/v1/scheduled-workouts/%d/reminders", sw.ID)).JSON(t, &reminders)
+		if len(reminders) != 1 {
+			t.Fatalf("reminders = %+v, want one", reminders)
+		}
+		return reminders[0].ScheduledFor
+	}
+	// 18:00 in Berlin is 16:00 UTC
+	if got, want := scheduledFor(), time.Date(2026, 10, 20, 15, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("reminder due %v, want %v", got, want)
+	}
+
+	// Moving the workout to 07:00 the next morning moves the reminder with it
+	resp = alice.SendJSON("PUT", fmt.Sprintf("/api/v1/scheduled-workouts/%d", sw.ID), models.UpdateScheduledWorkoutRequest{Title: "Legs", ScheduledDate: "2026-10-21", ScheduledTime: "07:00"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("move workout: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	if got, want := scheduledFor(), time.Date(2026, 10, 21, 4, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("reminder after moving the workout due %v, want %v", got, want)
+	}
+
+	// So does moving to New York, where 07:00 is 11:00 UTC
+	setTimezone(t, alice, "America/New_York")
+	want := time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC)
+	if got := scheduledFor(); !got.Equal(want) {
+		t.Errorf("reminder after changing time zone due %v, want %v", got, want)
+	}
+
+	h.Clock.Set(want.Add(-time.Minute))
+	if sent, err := dispatcher.DispatchDue(context.Background()); err != nil || sent != 0 {
+		t.Errorf("DispatchDue before the reminder is due = %d, %v", sent, err)
+	}
+	h.Clock.Set(want)
+	if sent, err := dispatcher.DispatchDue(context.Background()); err != nil || sent != 1 {
+		t.Fatalf("DispatchDue = %d, %v; want the reminder sent", sent, err)
+	}
+	mail := server.Mail()
+	if len(mail) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(mail))
+	}
+	msg, err := mail[0].Message()
+	if err != nil {
+		t.Fatalf("email does not parse: %v", err)
+	}
+	if subject := msg.Header.Get("Subject"); subject != "Reminder: Legs today at 07:00" {
+		t.Errorf("Subject = %q", subject)
+	}
+}
+
+// failingStore fails to claim one reminder, as a store that loses its connection would
+type failingStore struct {
+	storage.Store
+	reminderID int
+}
+
+func (s failingStore) Reminders() storage.ReminderRepository {
+	return failingReminders{s.Store.Reminders(), s.reminderID}
+}
+
+type failingReminders struct {
+	storage.ReminderRepository
+	reminderID int
+}
+
+func (r failingReminders) Claim(id int, now, until time.Time) error {
+	if id == r.reminderID {
+		return errors.New("connection reset")
+	}
+	return r.ReminderRepository.Claim(id, now, until)
+}
+
+// TestReminderDispatcherStoreFailure sends the other due reminders when the store fails on
+// one, and reports the failure
+func TestReminderDispatcherStoreFailure(t *testing.T) {
+	h := newHarness(t) // Thursday 2026-10-15 12:00 UTC
+	alice := h.LoginAs("alice")
+
+	server := smtptest.NewServer(t)
+	email, err := notify.NewSMTP(notify.SMTPConfig{Addr: server.Addr, From: "gym@example.com"})
+	if err != nil {
+		t.Fatalf("NewSMTP: %v", err)
+	}
+
+	var reminders []models.WorkoutReminder
+	for _, title := range []string{"Legs", "Push"} {
+		resp := alice.SendJSON("POST", "/api/v1/scheduled-workouts", models.CreateScheduledWorkoutRequest{Title: title, ScheduledDate: "2026-10-16", ScheduledTime: "18:00"})
+		var sw models.ScheduledWorkout
+		resp.JSON(t, &sw)
+		resp = alice.SendJSON("POST", fmt.Sprintf("/api/v1/scheduled-workouts/%d/reminders", sw.ID),
+			models.CreateReminderRequest{ReminderType: models.ReminderTypeEmail, Message: title + " day", MinutesBefore: 60})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create reminder: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var reminder models.WorkoutReminder
+		resp.JSON(t, &reminder)
+		reminders = append(reminders, reminder)
+	}
+
+	store := failingStore{h.Store, reminders[0].ID}
+	dispatcher := notify.NewDispatcher(store, notify.WithChannel(models.ReminderTypeEmail, email), notify.WithClock(h.Clock.Now))
+	h.Clock.Set(reminders[1].ScheduledFor)
+	sent, err := dispatcher.DispatchDue(context.Background())
+	if sent != 1 || err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("DispatchDue = %d, %v; want the other reminder sent and the failure reported", sent, err)
+	}
+	if mail := server.Mail(); len(mail) != 1 {
+		t.Errorf("sent %d emails, want 1", len(mail))
+	}
+}
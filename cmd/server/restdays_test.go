@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// TestRestDays logs a run of training days through the API until the engine recommends a
+// rest day, answers it and reads back how well the user kept to their rest days
+func TestRestDays(t *testing.T) {
+	h := newHarness(t) // Thursday 2026-10-15
+	alice := h.LoginAs("alice")
+
+	logWorkout := func(date string) {
+		t.Helper()
+		workout := models.CreateWorkoutRequest{Name: "Run", Date: date, Duration: 40, Exercises: []models.CreateWorkoutExerciseRequest{
+			{Name: "Treadmill", Category: "Cardio", Sets: []models.CreateWorkoutSetRequest{{Duration: 2400}}},
+		}}
+		if resp := alice.SendJSON("POST", "/api/v1/workouts", workout); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create workout: status %d: %s", resp.StatusCode, resp.Body)
+		}
+	}
+	restDays := func(query string) []models.RestDayRecommendation {
+		t.Helper()
+		resp := alice.Get("/api/v1/rest-days?" + query)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("rest days: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var recs []models.RestDayRecommendation
+		resp.JSON(t, &recs)
+		return recs
+	}
+	compliance := func() float64 {
+		t.Helper()
+		resp := alice.Get("/api/v1/planning/analytics")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("planning analytics: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var analytics models.PlanningAnalytics
+		resp.JSON(t, &analytics)
+		return analytics.RestDayCompliance
+	}
+
+	// Old history is not worth a recommendation, and four days in a row are fine
+	logWorkout("2026-09-01")
+	for day := 11; day <= 14; day++ {
+		logWorkout(fmt.Sprintf("2026-10-%d", day))
+	}
+	if recs := restDays("from=2026-08-01"); len(recs) != 0 {
+		t.Fatalf("rest days after four days = %+v, want none", recs)
+	}
+
+	logWorkout("2026-10-15")
+	recs := restDays("")
+	if len(recs) != 1 {
+		t.Fatalf("rest days after five days = %+v, want one", recs)
+	}
+	rec := recs[0]
+	if rec.RecommendedDate.Format("2006-01-02") != "2026-10-16" || rec.Reason != models.RestDayReasonConsecutiveDays ||
+		rec.ConsecutiveDays != 5 || rec.IntensityScore != 7 || rec.MuscleGroupsWorked != "[]" || rec.Status != models.RestDayStatusSuggested {
+		t.Errorf("recommendation = %+v, want a rest on 16 October after five days in a row", rec)
+	}
+
+	resp := alice.SendJSON("POST", fmt.Sprintf("/api/v1/rest-days/%d/response", rec.ID), models.RestDayResponseRequest{Status: "accepted"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("respond: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	var answered models.RestDayRecommendation
+	resp.JSON(t, &answered)
+	if answered.Status != models.RestDayStatusAccepted {
+		t.Errorf("answered = %+v, want accepted", answered)
+	}
+	if recs := restDays("status=suggested"); len(recs) != 0 {
+		t.Errorf("open suggestions = %+v, want none", recs)
+	}
+
+	// Nothing logged on the rest day keeps compliance full
+	if got := compliance(); got != 0 {
+		t.Errorf("compliance before the rest day = %v, want 0", got)
+	}
+	h.Clock.Set(time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC))
+	if got := compliance(); got != 100 {
+		t.Errorf("compliance after resting = %v, want 100", got)
+	}
+
+	// Training on it after all breaks the rest day and makes the run longer still
+	logWorkout("2026-10-16")
+	if got := compliance(); got != 0 {
+		t.Errorf("compliance after training on the rest day = %v, want 0", got)
+	}
+	recs = restDays("")
+	if len(recs) != 2 || recs[0].Status != models.RestDayStatusAccepted || recs[1].RecommendedDate.Format("2006-01-02") != "2026-10-17" || recs[1].ConsecutiveDays != 6 {
+		t.Errorf("rest days = %+v, want the accepted one and another for 17 October", recs)
+	}
+}
@@ -0,0 +1,667 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"workout-tracker/internal/handlers"
+	"workout-tracker/internal/handlers/handlerstest"
+	"workout-tracker/internal/models"
+)
+
+// routeCase is one request against a route registered by newRouter. Route is the mux
+// path template; {id} is filled from the fixture named by ID, {template_id},
+// {category} and {exercise} from the seeded template, category and exercise, and {token}
+// from the seeded calendar feed.
+type routeCase struct {
+	Method string
+	Route  string
+	ID     string
+	Query  string
+	// Body is sent as a form for url.Values, as an iCalendar file for a string and as JSON
+	// for anything else
+	Body interface{}
+	Want int
+	// Location is the expected redirect target, when Want is a redirect
+	Location string
+	// Stream marks event streams, which are checked without waiting for the body to end
+	Stream bool
+}
+
+// fixtures are the IDs of the seeded rows; other_ names belong to a second user
+type fixtures map[string]int
+
+// webRoutes covers the HTML pages and form posts
+var webRoutes = []routeCase{
+	{Method: "GET", Route: "/login", Want: http.StatusOK},
+	{Method: "POST", Route: "/login", Body: url.Values{"username": {"alice"}, "password": {"wrong"}}, Want: http.StatusUnauthorized},
+	{Method: "GET", Route: "/register", Want: http.StatusOK},
+	{Method: "POST", Route: "/register", Body: url.Values{"username": {"carol"}, "email": {"carol@example.com"}, "password": {"password123"}}, Want: http.StatusFound, Location: "/login"},
+	{Method: "GET", Route: "/logout", Want: http.StatusFound, Location: "/login"},
+	{Method: "POST", Route: "/logout", Want: http.StatusFound, Location: "/login"},
+	{Method: "GET", Route: "/clear-session", Want: http.StatusOK},
+
+	{Method: "GET", Route: "/account-settings", Want: http.StatusOK},
+	{Method: "GET", Route: "/profile", Want: http.StatusOK},
+	{Method: "POST", Route: "/account/update-profile", Body: url.Values{"username": {"alice"}, "email": {"alice@example.org"}, "full_name": {"Alice"}}, Want: http.StatusSeeOther, Location: "/account-settings?updated=profile"},
+	{Method: "POST", Route: "/account/update-profile", Body: url.Values{"username": {"alice"}, "email": {"alice"}}, Want: http.StatusUnprocessableEntity},
+	{Method: "POST", Route: "/account/update-settings", Body: url.Values{"theme": {"dark"}, "timezone": {"UTC"}, "weight_unit": {"kg"}, "distance_unit": {"km"}, "date_format": {"2006-01-02"}, "language": {"en"}, "auto_logout": {"30"}, "e1rm_formula": {"brzycki"}}, Want: http.StatusSeeOther, Location: "/account-settings?updated=settings"},
+	{Method: "POST", Route: "/account/update-settings", Body: url.Values{"theme": {"dark"}, "e1rm_formula": {"oconner"}}, Want: http.StatusBadRequest},
+	{Method: "POST", Route: "/account/change-password", Body: url.Values{"current_password": {handlerstest.DefaultPassword}, "new_password": {"newpassword123"}, "confirm_password": {"newpassword123"}}, Want: http.StatusSeeOther, Location: "/account-settings?updated=password"},
+	{Method: "POST", Route: "/account/change-password", Body: url.Values{"current_password": {handlerstest.DefaultPassword}, "new_password": {"short"}, "confirm_password": {"short"}}, Want: http.StatusUnprocessableEntity},
+	{Method: "POST", Route: "/account/delete-account", Body: url.Values{"confirm_deletion": {"DELETE"}, "password": {handlerstest.DefaultPassword}}, Want: http.StatusSeeOther},
+	{Method: "POST", Route: "/account/tokens", Body: url.Values{"name": {"cli"}, "scope": {"read"}}, Want: http.StatusOK},
+	{Method: "POST", Route: "/account/tokens", Body: url.Values{"name": {"cli"}, "scope": {"admin"}}, Want: http.StatusUnprocessableEntity},
+	{Method: "POST", Route: "/account/tokens/{id}/revoke", ID: "token", Want: http.StatusSeeOther},
+
+	{Method: "GET", Route: "/", Want: http.StatusOK},
+	{Method: "GET", Route: "/workouts", Want: http.StatusOK},
+	{Method: "GET", Route: "/workouts/new", Want: http.StatusOK},
+	{Method: "POST", Route: "/workouts", Body: url.Values{"name": {"Leg day"}, "date": {"2026-10-01"}, "notes": {"heavy"}}, Want: http.StatusSeeOther},
+	{Method: "GET", Route: "/workouts/{id}", ID: "workout", Want: http.StatusOK},
+	{Method: "GET", Route: "/workouts/{id}", ID: "other_workout", Want: http.StatusNotFound},
+	{Method: "GET", Route: "/workouts/{id}/edit", ID: "workout", Want: http.StatusOK},
+	{Method: "POST", Route: "/workouts/{id}/edit", ID: "workout", Body: url.Values{"_method": {"PUT"}, "name": {"Renamed"}, "date": {"2026-10-02"}, "duration": {"45"}}, Want: http.StatusSeeOther},
+	{Method: "POST", Route: "/workouts/{id}/delete", ID: "workout", Body: url.Values{"_method": {"DELETE"}}, Want: http.StatusSeeOther, Location: "/workouts"},
+	{Method: "POST", Route: "/workouts/{id}/delete", ID: "other_workout", Body: url.Values{"_method": {"DELETE"}}, Want: http.StatusNotFound},
+	{Method: "GET", Route: "/analytics", Want: http.StatusOK},
+
+	{Method: "GET", Route: "/exercise-library", Want: http.StatusOK},
+	{Method: "GET", Route: "/progress-stats", Want: http.StatusOK},
+	{Method: "GET", Route: "/log-meals", Want: http.StatusOK},
+	{Method: "GET", Route: "/body-weight", Want: http.StatusOK},
+	{Method: "GET", Route: "/body-fat", Want: http.StatusOK},
+	{Method: "GET", Route: "/body-measurements", Want: http.StatusOK},
+
+	{Method: "GET", Route: "/templates", Want: http.StatusOK},
+	{Method: "GET", Route: "/templates/{id}", ID: "template", Want: http.StatusOK},
+	{Method: "GET", Route: "/templates/{id}", ID: "other_template", Want: http.StatusNotFound},
+	{Method: "GET", Route: "/templates/{id}/edit", ID: "template", Want: http.StatusOK},
+
+	{Method: "GET", Route: "/programs", Want: http.StatusOK},
+	{Method: "GET", Route: "/programs/{id}", ID: "program", Want: http.StatusOK},
+	{Method: "GET", Route: "/programs/{id}/edit", ID: "program", Want: http.StatusOK},
+
+	{Method: "GET", Route: "/static/{file}", Want: http.StatusOK},
+	{Method: "GET", Route: "/calendar/{token}.ics", Want: http.StatusOK},
+}
+
+// outOfRangeRPE is above the 1-10 scale
+var outOfRangeRPE = 11.0
+
+// restTime restarts a session's rest timer
+var restTime = 90
+
+// apiRoutes covers the JSON API; paths are relative to /api and /api/v1
+var apiRoutes = []routeCase{
+	{Method: "GET", Route: "/workouts", Want: http.StatusOK},
+	{Method: "GET", Route: "/workouts", Query: "limit=0", Want: http.StatusBadRequest},
+	{Method: "POST", Route: "/workouts", Body: models.CreateWorkoutRequest{Name: "Push", Date: "2026-10-01", Exercises: []models.CreateWorkoutExerciseRequest{{Name: "Bench Press", Category: "Chest", Sets: []models.CreateWorkoutSetRequest{{Reps: 5, Weight: 100}}}}}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/workouts", Body: "{", Want: http.StatusBadRequest},
+	{Method: "GET", Route: "/workouts/{id}", ID: "workout", Want: http.StatusOK},
+	{Method: "GET", Route: "/workouts/{id}", ID: "other_workout", Want: http.StatusNotFound},
+	{Method: "PUT", Route: "/workouts/{id}", ID: "workout", Body: models.UpdateWorkoutRequest{Name: "Pull", Date: "2026-10-03"}, Want: http.StatusOK},
+	{Method: "PUT", Route: "/workouts/{id}", ID: "other_workout", Body: models.UpdateWorkoutRequest{Name: "Pull", Date: "2026-10-03"}, Want: http.StatusNotFound},
+	{Method: "DELETE", Route: "/workouts/{id}", ID: "workout", Want: http.StatusNoContent},
+	{Method: "DELETE", Route: "/workouts/{id}", ID: "other_workout", Want: http.StatusNotFound},
+	{Method: "PUT", Route: "/workouts/{id}/exercises/order", ID: "workout", Body: models.ReorderExercisesRequest{Exercises: []models.ExerciseOrder{{GroupID: 1}}}, Want: http.StatusOK},
+	{Method: "PUT", Route: "/workouts/{id}/exercises/order", ID: "workout", Body: models.ReorderExercisesRequest{Exercises: []models.ExerciseOrder{{ID: -1}}}, Want: http.StatusUnprocessableEntity},
+	{Method: "PUT", Route: "/workouts/{id}/exercises/order", ID: "other_workout", Body: models.ReorderExercisesRequest{Exercises: []models.ExerciseOrder{{}}}, Want: http.StatusNotFound},
+
+	{Method: "POST", Route: "/sessions", Body: models.StartSessionRequest{}, Want: http.StatusConflict},
+	{Method: "POST", Route: "/sessions", Body: models.StartSessionRequest{TemplateID: -1}, Want: http.StatusUnprocessableEntity},
+	{Method: "GET", Route: "/sessions/active", Want: http.StatusOK},
+	{Method: "GET", Route: "/sessions/{id}", ID: "session", Want: http.StatusOK},
+	{Method: "GET", Route: "/sessions/{id}", ID: "other_session", Want: http.StatusNotFound},
+	{Method: "PUT", Route: "/sessions/{id}", ID: "session", Body: models.UpdateSessionRequest{RestTime: &restTime}, Want: http.StatusOK},
+	{Method: "PUT", Route: "/sessions/{id}", ID: "other_session", Body: models.UpdateSessionRequest{RestTime: &restTime}, Want: http.StatusNotFound},
+	{Method: "DELETE", Route: "/sessions/{id}", ID: "session", Want: http.StatusNoContent},
+	{Method: "DELETE", Route: "/sessions/{id}", ID: "other_session", Want: http.StatusNotFound},
+	{Method: "POST", Route: "/sessions/{id}/exercises", ID: "session", Body: models.AddSessionExerciseRequest{Name: "Dips", Category: "Chest", TargetSets: 3}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/sessions/{id}/exercises", ID: "session", Body: models.AddSessionExerciseRequest{Category: "Chest"}, Want: http.StatusUnprocessableEntity},
+	{Method: "POST", Route: "/sessions/{id}/exercises", ID: "other_session", Body: models.AddSessionExerciseRequest{Name: "Dips", Category: "Chest"}, Want: http.StatusNotFound},
+	{Method: "POST", Route: "/sessions/{id}/sets", ID: "session", Body: models.CompleteSessionSetRequest{Reps: 5, Weight: 80}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/sessions/{id}/sets", ID: "session", Body: models.CompleteSessionSetRequest{Reps: 5, RPE: &outOfRangeRPE}, Want: http.StatusUnprocessableEntity},
+	{Method: "POST", Route: "/sessions/{id}/sets", ID: "other_session", Body: models.CompleteSessionSetRequest{Reps: 5, Weight: 80}, Want: http.StatusNotFound},
+	{Method: "POST", Route: "/sessions/{id}/finish", ID: "session", Body: models.FinishSessionRequest{Notes: "felt strong"}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/sessions/{id}/finish", ID: "other_session", Body: models.FinishSessionRequest{}, Want: http.StatusNotFound},
+
+	{Method: "GET", Route: "/events", Stream: true, Want: http.StatusOK},
+	{Method: "GET", Route: "/events", Query: "last_event_id=latest", Want: http.StatusBadRequest},
+
+	{Method: "GET", Route: "/tokens", Want: http.StatusOK},
+	{Method: "POST", Route: "/tokens", Body: models.CreateAPITokenRequest{Name: "ci", Scope: "read_write"}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/tokens", Body: models.CreateAPITokenRequest{Name: "ci", Scope: "admin"}, Want: http.StatusUnprocessableEntity},
+	{Method: "DELETE", Route: "/tokens/{id}", ID: "token", Want: http.StatusNoContent},
+
+	{Method: "POST", Route: "/exercises", ID: "workout", Body: models.CreateExerciseRequest{Name: "Row", Category: "Back"}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/exercises", ID: "other_workout", Body: models.CreateExerciseRequest{Name: "Row", Category: "Back"}, Want: http.StatusNotFound},
+	{Method: "PUT", Route: "/exercises/{id}", ID: "exercise", Body: models.UpdateExerciseRequest{Name: "Incline Bench", Category: "Chest"}, Want: http.StatusOK},
+	{Method: "PUT", Route: "/exercises/{id}", ID: "other_exercise", Body: models.UpdateExerciseRequest{Name: "Incline Bench", Category: "Chest"}, Want: http.StatusNotFound},
+	{Method: "DELETE", Route: "/exercises/{id}", ID: "exercise", Want: http.StatusNoContent},
+	{Method: "DELETE", Route: "/exercises/{id}", ID: "other_exercise", Want: http.StatusNotFound},
+
+	{Method: "POST", Route: "/sets", ID: "exercise", Body: models.CreateSetRequest{SetNumber: 2, Reps: 8, Weight: 60}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/sets", ID: "exercise", Body: models.CreateSetRequest{SetNumber: 2, Reps: 8, SetType: "cooldown"}, Want: http.StatusUnprocessableEntity},
+	{Method: "POST", Route: "/sets", ID: "exercise", Body: models.CreateSetRequest{SetNumber: 2, Reps: 8, RPE: &outOfRangeRPE}, Want: http.StatusUnprocessableEntity},
+	{Method: "POST", Route: "/sets", ID: "other_exercise", Body: models.CreateSetRequest{SetNumber: 2, Reps: 8, Weight: 60}, Want: http.StatusNotFound},
+	{Method: "PUT", Route: "/sets/{id}", ID: "set", Body: models.UpdateSetRequest{SetNumber: 1, Reps: 6, Weight: 82.5}, Want: http.StatusOK},
+	{Method: "PUT", Route: "/sets/{id}", ID: "other_set", Body: models.UpdateSetRequest{SetNumber: 1, Reps: 6, Weight: 82.5}, Want: http.StatusNotFound},
+	{Method: "DELETE", Route: "/sets/{id}", ID: "set", Want: http.StatusNoContent},
+	{Method: "DELETE", Route: "/sets/{id}", ID: "other_set", Want: http.StatusNotFound},
+
+	{Method: "GET", Route: "/predefined-exercises", Want: http.StatusOK},
+	{Method: "POST", Route: "/predefined-exercises", Body: models.CreatePredefinedExerciseRequest{Name: "Zercher Squat", Category: "Legs"}, Want: http.StatusCreated},
+	{Method: "GET", Route: "/predefined-exercises/category/{category}", Want: http.StatusOK},
+	{Method: "GET", Route: "/muscles", Want: http.StatusOK},
+
+	{Method: "POST", Route: "/meals", Body: models.CreateMealRequest{Name: "Oats", Calories: 350, Date: "2026-10-01", MealType: "breakfast"}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/body-weights", Body: models.CreateBodyWeightRequest{Weight: 80, Unit: "kg", Date: "2026-10-01"}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/body-fats", Body: models.CreateBodyFatRequest{BodyFatPct: 15, Date: "2026-10-01"}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/body-measurements", Body: models.CreateBodyMeasurementRequest{Measurement: "waist", Value: 82, Unit: "cm", Date: "2026-10-01"}, Want: http.StatusCreated},
+
+	{Method: "GET", Route: "/analytics", Want: http.StatusOK},
+	{Method: "GET", Route: "/weekly-summary", Want: http.StatusOK},
+	{Method: "GET", Route: "/weekly-summary", Query: "year=2026&week=40", Want: http.StatusOK},
+	{Method: "GET", Route: "/monthly-summary", Want: http.StatusOK},
+	{Method: "GET", Route: "/monthly-summary", Query: "year=2026&month=10", Want: http.StatusOK},
+	{Method: "GET", Route: "/analytics/muscle-volume", Want: http.StatusOK},
+	{Method: "GET", Route: "/analytics/muscle-volume", Query: "weeks=12&secondary_fraction=0.25", Want: http.StatusOK},
+	{Method: "GET", Route: "/analytics/muscle-volume", Query: "weeks=0", Want: http.StatusBadRequest},
+	{Method: "GET", Route: "/analytics/muscle-volume", Query: "secondary_fraction=2", Want: http.StatusBadRequest},
+	{Method: "GET", Route: "/analytics/load", Want: http.StatusOK},
+	{Method: "GET", Route: "/analytics/load", Query: "from=2026-01-01&to=2026-10-15", Want: http.StatusOK},
+	{Method: "GET", Route: "/analytics/load", Query: "from=2026-10-16&to=2026-10-15", Want: http.StatusBadRequest},
+	{Method: "GET", Route: "/analytics/load", Query: "to=15-10-2026", Want: http.StatusBadRequest},
+
+	{Method: "GET", Route: "/exercise-progress/{exercise}", Want: http.StatusOK},
+	{Method: "GET", Route: "/exercise-list", Want: http.StatusOK},
+	{Method: "GET", Route: "/personal-records", Want: http.StatusOK},
+	{Method: "GET", Route: "/personal-records", Query: "exercise=bench%20press", Want: http.StatusOK},
+
+	{Method: "GET", Route: "/templates", Want: http.StatusOK},
+	{Method: "POST", Route: "/templates", Body: models.CreateTemplateRequest{Name: "Upper", Exercises: []models.Exercise{{Name: "Bench Press", Category: "Chest"}}}, Want: http.StatusCreated},
+	{Method: "GET", Route: "/templates/{id}", ID: "template", Want: http.StatusOK},
+	{Method: "GET", Route: "/templates/{id}", ID: "other_template", Want: http.StatusNotFound},
+	{Method: "PUT", Route: "/templates/{id}", ID: "template", Body: models.UpdateTemplateRequest{Name: "Upper B"}, Want: http.StatusOK},
+	{Method: "PUT", Route: "/templates/{id}", ID: "other_template", Body: models.UpdateTemplateRequest{Name: "Upper B"}, Want: http.StatusNotFound},
+	{Method: "DELETE", Route: "/templates/{id}", ID: "template", Want: http.StatusNoContent},
+	{Method: "DELETE", Route: "/templates/{id}", ID: "other_template", Want: http.StatusNotFound},
+	{Method: "POST", Route: "/templates/{id}/share", ID: "template", Body: models.ShareTemplateRequest{Permission: "view"}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/templates/{id}/share", ID: "other_template", Body: models.ShareTemplateRequest{Permission: "view"}, Want: http.StatusNotFound},
+	{Method: "POST", Route: "/templates/{template_id}/create-workout", Body: models.CreateWorkoutFromTemplateRequest{Name: "From template", Date: "2026-10-04"}, Want: http.StatusCreated},
+	{Method: "GET", Route: "/shared-templates", Want: http.StatusOK},
+
+	{Method: "GET", Route: "/programs", Want: http.StatusOK},
+	{Method: "POST", Route: "/programs", Body: models.CreateProgramRequest{Name: "Block 2"}, Want: http.StatusCreated},
+	{Method: "GET", Route: "/programs/{id}", ID: "program", Want: http.StatusOK},
+	{Method: "PUT", Route: "/programs/{id}", ID: "program", Body: models.CreateProgramRequest{Name: "Block 1b"}, Want: http.StatusOK},
+	{Method: "DELETE", Route: "/programs/{id}", ID: "program", Want: http.StatusNoContent},
+	{Method: "DELETE", Route: "/programs/{id}", ID: "other_program", Want: http.StatusForbidden},
+
+	{Method: "GET", Route: "/rest-days", Want: http.StatusOK},
+	{Method: "GET", Route: "/rest-days", Query: "from=2026-10-01&to=2026-10-31&status=suggested", Want: http.StatusOK},
+	{Method: "GET", Route: "/rest-days", Query: "status=maybe", Want: http.StatusBadRequest},
+	{Method: "GET", Route: "/rest-days", Query: "from=2026-10-31&to=2026-10-01", Want: http.StatusBadRequest},
+	{Method: "GET", Route: "/rest-days/{id}", ID: "rest_day", Want: http.StatusOK},
+	{Method: "GET", Route: "/rest-days/{id}", ID: "other_rest_day", Want: http.StatusNotFound},
+	{Method: "POST", Route: "/rest-days/{id}/response", ID: "rest_day", Body: models.RestDayResponseRequest{Status: "accepted", UserResponse: "sore"}, Want: http.StatusOK},
+	{Method: "POST", Route: "/rest-days/{id}/response", ID: "rest_day", Body: models.RestDayResponseRequest{Status: "maybe"}, Want: http.StatusUnprocessableEntity},
+	{Method: "POST", Route: "/rest-days/{id}/response", ID: "other_rest_day", Body: models.RestDayResponseRequest{Status: "ignored"}, Want: http.StatusNotFound},
+	{Method: "GET", Route: "/deloads", Want: http.StatusOK},
+	{Method: "GET", Route: "/deloads", Query: "from=2026-10-01&to=2026-10-31&status=suggested", Want: http.StatusOK},
+	{Method: "GET", Route: "/deloads", Query: "status=active", Want: http.StatusBadRequest},
+	{Method: "GET", Route: "/deloads", Query: "from=2026-10-31&to=2026-10-01", Want: http.StatusBadRequest},
+	{Method: "GET", Route: "/deloads/{id}", ID: "deload", Want: http.StatusOK},
+	{Method: "GET", Route: "/deloads/{id}", ID: "other_deload", Want: http.StatusNotFound},
+	{Method: "POST", Route: "/deloads/{id}/response", ID: "deload", Body: models.DeloadResponseRequest{Status: "accepted"}, Want: http.StatusOK},
+	{Method: "POST", Route: "/deloads/{id}/response", ID: "deload", Body: models.DeloadResponseRequest{Status: "overridden"}, Want: http.StatusUnprocessableEntity},
+	{Method: "POST", Route: "/deloads/{id}/response", ID: "other_deload", Body: models.DeloadResponseRequest{Status: "ignored"}, Want: http.StatusNotFound},
+	{Method: "GET", Route: "/scheduled-workouts", Want: http.StatusOK},
+	{Method: "GET", Route: "/scheduled-workouts", Query: "from=2026-10-01&to=2026-10-31&status=scheduled", Want: http.StatusOK},
+	{Method: "GET", Route: "/scheduled-workouts", Query: "status=maybe", Want: http.StatusBadRequest},
+	{Method: "POST", Route: "/scheduled-workouts", Body: models.CreateScheduledWorkoutRequest{Title: "Lower", ScheduledDate: "2026-10-20", ScheduledTime: "07:30"}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/scheduled-workouts", Body: models.CreateScheduledWorkoutRequest{Title: "Lower", ScheduledDate: "20 October"}, Want: http.StatusBadRequest},
+	{Method: "POST", Route: "/scheduled-workouts", Body: models.CreateScheduledWorkoutRequest{Title: "Lower", ScheduledDate: "2026-10-20", ScheduledTime: "7pm"}, Want: http.StatusBadRequest},
+	{Method: "POST", Route: "/scheduled-workouts", Body: models.CreateScheduledWorkoutRequest{ScheduledDate: "2026-10-20"}, Want: http.StatusUnprocessableEntity},
+	{Method: "POST", Route: "/scheduled-workouts/import", Body: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:run@example\r\nDTSTART:20261020T063000Z\r\nSUMMARY:Run\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n", Want: http.StatusOK},
+	{Method: "POST", Route: "/scheduled-workouts/import", Body: "BEGIN:VEVENT\r\nEND:VEVENT\r\n", Want: http.StatusBadRequest},
+	{Method: "GET", Route: "/scheduled-workouts/{id}", ID: "scheduled_workout", Want: http.StatusOK},
+	{Method: "GET", Route: "/scheduled-workouts/{id}", ID: "other_scheduled_workout", Want: http.StatusNotFound},
+	{Method: "PUT", Route: "/scheduled-workouts/{id}", ID: "scheduled_workout", Body: models.UpdateScheduledWorkoutRequest{Title: "Upper", ScheduledDate: "2026-10-18"}, Want: http.StatusOK},
+	{Method: "PUT", Route: "/scheduled-workouts/{id}", ID: "scheduled_workout", Body: models.UpdateScheduledWorkoutRequest{Title: "Upper", ScheduledDate: "2026-10-18", Status: "done"}, Want: http.StatusUnprocessableEntity},
+	{Method: "PUT", Route: "/scheduled-workouts/{id}", ID: "other_scheduled_workout", Body: models.UpdateScheduledWorkoutRequest{Title: "Upper", ScheduledDate: "2026-10-18"}, Want: http.StatusNotFound},
+	{Method: "DELETE", Route: "/scheduled-workouts/{id}", ID: "scheduled_workout", Want: http.StatusNoContent},
+	{Method: "DELETE", Route: "/scheduled-workouts/{id}", ID: "other_scheduled_workout", Want: http.StatusNotFound},
+	{Method: "GET", Route: "/scheduled-workouts/{id}/reminders", ID: "scheduled_workout", Want: http.StatusOK},
+	{Method: "GET", Route: "/scheduled-workouts/{id}/reminders", ID: "other_scheduled_workout", Want: http.StatusNotFound},
+	{Method: "POST", Route: "/scheduled-workouts/{id}/reminders", ID: "scheduled_workout", Body: models.CreateReminderRequest{ReminderType: "email", Message: "Warm up", MinutesBefore: 60}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/scheduled-workouts/{id}/reminders", ID: "scheduled_workout", Body: models.CreateReminderRequest{ReminderType: "pigeon", Message: "Warm up", MinutesBefore: 60}, Want: http.StatusUnprocessableEntity},
+	{Method: "POST", Route: "/scheduled-workouts/{id}/reminders", ID: "other_scheduled_workout", Body: models.CreateReminderRequest{ReminderType: "email", Message: "Warm up", MinutesBefore: 60}, Want: http.StatusNotFound},
+	{Method: "DELETE", Route: "/reminders/{id}", ID: "reminder", Want: http.StatusNoContent},
+	{Method: "DELETE", Route: "/reminders/{id}", ID: "other_reminder", Want: http.StatusNotFound},
+	{Method: "GET", Route: "/calendar", Want: http.StatusOK},
+	{Method: "GET", Route: "/calendar", Query: "month=2026-11", Want: http.StatusOK},
+	{Method: "GET", Route: "/calendar", Query: "month=November", Want: http.StatusBadRequest},
+	{Method: "GET", Route: "/calendar/feed", Want: http.StatusOK},
+	{Method: "POST", Route: "/calendar/feed", Want: http.StatusCreated},
+	{Method: "DELETE", Route: "/calendar/feed", Want: http.StatusNoContent},
+	{Method: "GET", Route: "/calendar/events", Want: http.StatusOK},
+	{Method: "GET", Route: "/calendar/events", Query: "from=2026-10-01&to=2026-10-31", Want: http.StatusOK},
+	{Method: "GET", Route: "/calendar/events", Query: "from=tomorrow", Want: http.StatusBadRequest},
+	{Method: "GET", Route: "/calendar/events", Query: "from=2026-01-01&to=2027-01-01", Want: http.StatusOK},
+	{Method: "GET", Route: "/calendar/events", Query: "from=2026-01-01&to=2027-01-02", Want: http.StatusBadRequest},
+	{Method: "POST", Route: "/calendar/events", Body: models.CalendarEventRequest{Title: "Yoga", StartDate: "2026-10-16T18:00:00Z", RRule: "FREQ=WEEKLY;BYDAY=FR;COUNT=4"}, Want: http.StatusCreated},
+	{Method: "POST", Route: "/calendar/events", Body: models.CalendarEventRequest{Title: "Yoga", StartDate: "2026-10-16", RRule: "FREQ=YEARLY"}, Want: http.StatusBadRequest},
+	{Method: "POST", Route: "/calendar/events", Body: models.CalendarEventRequest{Title: "Yoga", StartDate: "Friday"}, Want: http.StatusBadRequest},
+	{Method: "POST", Route: "/calendar/events", Body: models.CalendarEventRequest{Title: "Yoga", StartDate: "2026-10-16", EventType: "party"}, Want: http.StatusUnprocessableEntity},
+	{Method: "GET", Route: "/calendar/events/{id}", ID: "calendar_event", Want: http.StatusOK},
+	{Method: "GET", Route: "/calendar/events/{id}", ID: "other_calendar_event", Want: http.StatusNotFound},
+	{Method: "PUT", Route: "/calendar/events/{id}", ID: "calendar_event", Body: models.CalendarEventRequest{Title: "Mobility", StartDate: "2026-10-12T07:00:00Z", RRule: "FREQ=WEEKLY"}, Want: http.StatusOK},
+	{Method: "PUT", Route: "/calendar/events/{id}", ID: "other_calendar_event", Body: models.CalendarEventRequest{Title: "Mobility", StartDate: "2026-10-12"}, Want: http.StatusNotFound},
+	{Method: "PUT", Route: "/calendar/events/{id}/occurrences/{date}", ID: "calendar_event", Body: models.CalendarEventRequest{Title: "Stretch", StartDate: "2026-10-19T08:00:00Z"}, Want: http.StatusCreated},
+	{Method: "PUT", Route: "/calendar/events/{id}/occurrences/{date}", ID: "calendar_event", Query: "scope=future", Body: models.CalendarEventRequest{Title: "Stretch", StartDate: "2026-10-19T08:00:00Z"}, Want: http.StatusCreated},
+	{Method: "PUT", Route: "/calendar/events/{id}/occurrences/{date}", ID: "calendar_event", Body: models.CalendarEventRequest{Title: "Stretch", StartDate: "2026-10-19", RRule: "FREQ=DAILY"}, Want: http.StatusBadRequest},
+	{Method: "PUT", Route: "/calendar/events/{id}/occurrences/{date}", ID: "calendar_event", Query: "scope=all", Body: models.CalendarEventRequest{Title: "Stretch", StartDate: "2026-10-19"}, Want: http.StatusBadRequest},
+	{Method: "PUT", Route: "/calendar/events/{id}/occurrences/{date}", ID: "other_calendar_event", Body: models.CalendarEventRequest{Title: "Stretch", StartDate: "2026-10-19"}, Want: http.StatusNotFound},
+	{Method: "DELETE", Route: "/calendar/events/{id}/occurrences/{date}", ID: "calendar_event", Want: http.StatusNoContent},
+	{Method: "DELETE", Route: "/calendar/events/{id}/occurrences/{date}", ID: "calendar_event", Query: "scope=future", Want: http.StatusNoContent},
+	{Method: "DELETE", Route: "/calendar/events/{id}/occurrences/{date}", ID: "other_calendar_event", Want: http.StatusNotFound},
+	{Method: "DELETE", Route: "/calendar/events/{id}", ID: "calendar_event", Want: http.StatusNoContent},
+	{Method: "DELETE", Route: "/calendar/events/{id}", ID: "other_calendar_event", Want: http.StatusNotFound},
+	{Method: "GET", Route: "/planning/analytics", Want: http.StatusOK},
+}
+
+// publicRoutes are served without a session
+var publicRoutes = map[string]bool{
+	"/login":         true,
+	"/register":      true,
+	"/logout":        true,
+	"/clear-session": true,
+	"/static/{file}": true,
+	// The feed's token in the URL is its credential
+	"/calendar/{token}.ics": true,
+}
+
+// newHarness serves the application's router from a fresh harness
+func newHarness(t *testing.T) *handlerstest.Harness {
+	t.Helper()
+
+	h := handlerstest.New(t, func(h *handlers.Handler) http.Handler {
+		return newRouter(h, http.Dir(filepath.Join(handlerstest.RepoRoot(), "web", "static")))
+	})
+	h.Clock.Set(time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC))
+	return h
+}
+
+// seedFeedToken is the token of alice's seeded calendar feed
+const seedFeedToken = "wtcal_0123456789abcdef"
+
+// seed logs alice in and gives her and bob a workout tree, a template, a program, a
+// session in progress, a rest day and a deload suggestion, a scheduled workout with a
+// reminder and a weekly calendar series each, and alice a calendar feed
+func seed(t *testing.T, h *handlerstest.Harness) (*handlerstest.Client, fixtures) {
+	t.Helper()
+
+	alice := h.LoginAs("alice")
+	bob := h.CreateUser("bob")
+
+	f := fixtures{"other_user": bob}
+	rpe := 8.0
+	for prefix, userID := range map[string]int{"": alice.UserID, "other_": bob} {
+		date := h.Clock.Now().AddDate(0, 0, -1)
+		workout := models.Workout{Name: "Push", Date: date, Duration: 60, Exercises: []models.Exercise{{
+			Name:     "Bench Press",
+			Category: "Chest",
+			Sets: []models.Set{
+				{SetNumber: 1, Reps: 5, Weight: 80, RPE: &rpe, Notes: "smooth"},
+				{SetNumber: 2, Reps: 10, Weight: 40, SetType: models.SetTypeWarmup},
+			},
+		}}}
+		workoutID, err := h.Store.Workouts().Create(workout, userID)
+		if err != nil {
+			t.Fatalf("failed to create workout: %v", err)
+		}
+		created, err := h.Store.Workouts().Get(workoutID, userID)
+		if err != nil {
+			t.Fatalf("failed to load workout: %v", err)
+		}
+		f[prefix+"workout"] = workoutID
+		f[prefix+"exercise"] = created.Exercises[0].ID
+		f[prefix+"set"] = created.Exercises[0].Sets[0].ID
+
+		templateID, err := h.Store.Templates().Create(models.WorkoutTemplate{UserID: userID, Name: "Upper", CreatedAt: date, UpdatedAt: date})
+		if err != nil {
+			t.Fatalf("failed to create template: %v", err)
+		}
+		if _, err := h.Store.Templates().CreateExercise(models.TemplateExercise{TemplateID: templateID, Name: "Bench Press", Category: "Chest", TargetSets: 3, TargetReps: 5, TargetWeight: 80}); err != nil {
+			t.Fatalf("failed to create template exercise: %v", err)
+		}
+		f[prefix+"template"] = templateID
+
+		programID, err := h.Store.Programs().Create(models.WorkoutProgram{Name: "Block 1", CreatedBy: userID, IsPublic: true, CreatedAt: date, UpdatedAt: date})
+		if err != nil {
+			t.Fatalf("failed to create program: %v", err)
+		}
+		if _, err := h.Store.Programs().AddTemplate(models.ProgramTemplate{ProgramID: programID, TemplateID: templateID, DayOfWeek: 1, WeekNumber: 1}); err != nil {
+			t.Fatalf("failed to add program template: %v", err)
+		}
+		f[prefix+"program"] = programID
+
+		sessionID, err := h.Store.Sessions().Create(models.WorkoutSession{
+			UserID:     userID,
+			TemplateID: &templateID,
+			Name:       "Upper",
+			StartedAt:  h.Clock.Now().Add(-30 * time.Minute),
+			Exercises:  []models.SessionExercise{{Name: "Bench Press", Category: "Chest", TargetSets: 3, TargetReps: 5, TargetWeight: 80, RestTime: 120}},
+		})
+		if err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+		session, err := h.Store.Sessions().Get(sessionID, userID)
+		if err != nil {
+			t.Fatalf("failed to load session: %v", err)
+		}
+		f[prefix+"session"] = sessionID
+		f[prefix+"session_exercise"] = session.Exercises[0].ID
+
+		restDay, err := h.Store.RestDays().Suggest(models.RestDayRecommendation{
+			UserID:             userID,
+			RecommendedDate:    h.Clock.Now(),
+			Reason:             models.RestDayReasonConsecutiveDays,
+			ConsecutiveDays:    5,
+			MuscleGroupsWorked: "[]",
+		})
+		if err != nil {
+			t.Fatalf("failed to suggest rest day: %v", err)
+		}
+		f[prefix+"rest_day"] = restDay.ID
+
+		deloadID, err := h.Store.Deloads().Create(models.DeloadRecommendation{
+			UserID:                    userID,
+			RecommendedStartDate:      h.Clock.Now().AddDate(0, 0, 1),
+			RecommendedEndDate:        h.Clock.Now().AddDate(0, 0, 7),
+			Reason:                    models.DeloadReasonScheduled,
+			VolumeReductionPercent:    40,
+			IntensityReductionPercent: 20,
+			TriggerMetrics:            "{}",
+		})
+		if err != nil {
+			t.Fatalf("failed to create deload: %v", err)
+		}
+		f[prefix+"deload"] = deloadID
+
+		scheduledID, err := h.Store.ScheduledWorkouts().Create(models.ScheduledWorkout{
+			UserID:        userID,
+			TemplateID:    &templateID,
+			Title:         "Upper",
+			ScheduledDate: h.Clock.Now().AddDate(0, 0, 2),
+		})
+		if err != nil {
+			t.Fatalf("failed to schedule workout: %v", err)
+		}
+		f[prefix+"scheduled_workout"] = scheduledID
+
+		reminderID, err := h.Store.Reminders().Create(models.WorkoutReminder{
+			UserID:             userID,
+			ScheduledWorkoutID: scheduledID,
+			ReminderType:       models.ReminderTypeEmail,
+			Message:            "Pack a towel",
+			ScheduledFor:       h.Clock.Now().AddDate(0, 0, 1),
+		})
+		if err != nil {
+			t.Fatalf("failed to create reminder: %v", err)
+		}
+		f[prefix+"reminder"] = reminderID
+
+		pattern := `{"rrule":"FREQ=WEEKLY"}`
+		eventID, err := h.Store.CalendarEvents().Create(models.WorkoutCalendarEvent{
+			UserID:            userID,
+			EventType:         models.CalendarEventWorkout,
+			Title:             "Mobility",
+			StartDate:         time.Date(2026, 10, 12, 7, 0, 0, 0, time.UTC),
+			IsRecurring:       true,
+			RecurrencePattern: &pattern,
+		})
+		if err != nil {
+			t.Fatalf("failed to create calendar event: %v", err)
+		}
+		f[prefix+"calendar_event"] = eventID
+	}
+
+	sum := sha256.Sum256([]byte(seedFeedToken))
+	if _, err := h.Store.CalendarFeeds().Replace(models.CalendarFeed{UserID: alice.UserID, Prefix: seedFeedToken[:14], TokenHash: hex.EncodeToString(sum[:])}); err != nil {
+		t.Fatalf("failed to create calendar feed: %v", err)
+	}
+
+	resp := alice.SendJSON("POST", "/api/tokens", models.CreateAPITokenRequest{Name: "fixture", Scope: "read"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("failed to create token: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	var token models.CreateAPITokenResponse
+	resp.JSON(t, &token)
+	f["token"] = token.ID
+
+	return alice, f
+}
+
+// path fills in the route's variables from the fixtures
+func (c routeCase) path(f fixtures) string {
+	path := strings.NewReplacer(
+		"{id}", strconv.Itoa(f[c.ID]),
+		"{template_id}", strconv.Itoa(f["template"]),
+		"{category}", "Chest",
+		"{exercise}", url.PathEscape("Bench Press"),
+		"{file}", "css/style.css",
+		"{date}", "2026-10-19",
+		"{token}", seedFeedToken,
+	).Replace(c.Route)
+	if c.Query != "" {
+		path += "?" + c.Query
+	}
+	return path
+}
+
+// body fills in the workout and exercise a create request refers to, since those are only known once seeded
+func (c routeCase) body(f fixtures) interface{} {
+	switch body := c.Body.(type) {
+	case models.CreateExerciseRequest:
+		body.WorkoutID = f[c.ID]
+		return body
+	case models.CreateSetRequest:
+		body.ExerciseID = f[c.ID]
+		return body
+	case models.ReorderExercisesRequest:
+		// Entries without an ID stand for the seeded exercise of the case's workout
+		exercises := make([]models.ExerciseOrder, len(body.Exercises))
+		for i, entry := range body.Exercises {
+			if entry.ID == 0 {
+				entry.ID = f[strings.TrimSuffix(c.ID, "workout")+"exercise"]
+			}
+			exercises[i] = entry
+		}
+		body.Exercises = exercises
+		return body
+	case models.CompleteSessionSetRequest:
+		body.ExerciseID = f[c.ID+"_exercise"]
+		return body
+	case models.ShareTemplateRequest:
+		body.SharedWithID = f["other_user"]
+		return body
+	}
+	return c.Body
+}
+
+func (c routeCase) name(prefix string) string {
+	name := c.Method + " " + prefix + c.Route
+	if c.ID != "" {
+		name += " " + c.ID
+	}
+	if c.Query != "" {
+		name += "?" + c.Query
+	}
+	return name
+}
+
+// send performs the case's request as the given client
+func (c routeCase) send(client *handlerstest.Client, prefix string, f fixtures) *handlerstest.Response {
+	path := prefix + c.path(f)
+	if c.Stream {
+		stream := client.Stream(path)
+		defer stream.Close()
+		return &handlerstest.Response{StatusCode: stream.StatusCode, Header: stream.Header}
+	}
+	switch body := c.body(f).(type) {
+	case nil:
+		return client.Do(c.Method, path, nil, "")
+	case url.Values:
+		return client.Do(c.Method, path, strings.NewReader(body.Encode()), "application/x-www-form-urlencoded")
+	case string:
+		return client.Do(c.Method, path, strings.NewReader(body), "text/calendar")
+	default:
+		return client.SendJSON(c.Method, path, body)
+	}
+}
+
+func runRouteCases(t *testing.T, prefix string, cases []routeCase) {
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name(prefix), func(t *testing.T) {
+			h := newHarness(t)
+			alice, f := seed(t, h)
+
+			resp := tc.send(alice, prefix, f)
+			if resp.StatusCode != tc.Want {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tc.Want, resp.Body)
+			}
+			// A template error after the page started rendering keeps the 200 status
+			if strings.Contains(resp.Body, "Failed to render template") {
+				t.Fatalf("page failed to render: %s", resp.Body)
+			}
+			if tc.Location != "" {
+				if got := resp.Header.Get("Location"); got != tc.Location {
+					t.Errorf("Location = %q, want %q", got, tc.Location)
+				}
+			}
+		})
+	}
+}
+
+func TestWebRoutes(t *testing.T) {
+	runRouteCases(t, "", webRoutes)
+}
+
+func TestAPIRoutes(t *testing.T) {
+	for _, prefix := range []string{"/api", "/api/v1"} {
+		runRouteCases(t, prefix, apiRoutes)
+	}
+}
+
+// TestValidationErrorFormats submits the same invalid profile as a form and as JSON: the
+// browser is shown plain text and the JSON client the error envelope
+func TestValidationErrorFormats(t *testing.T) {
+	h := newHarness(t)
+	alice := h.LoginAs("alice")
+
+	form := alice.PostForm("/account/update-profile", url.Values{"username": {""}, "email": {"alice"}})
+	if form.StatusCode != http.StatusUnprocessableEntity || !strings.HasPrefix(form.Header.Get("Content-Type"), "text/plain") ||
+		form.Body != "username is required\nemail must be a valid email address\n" {
+		t.Errorf("form = %d %q %q, want a plain-text 422 listing both fields", form.StatusCode, form.Header.Get("Content-Type"), form.Body)
+	}
+
+	resp := alice.SendJSON("POST", "/account/update-profile", models.UpdateProfileRequest{Email: "alice"})
+	var envelope models.APIErrorResponse
+	resp.JSON(t, &envelope)
+	if resp.StatusCode != http.StatusUnprocessableEntity || envelope.Error.Code != "validation_failed" {
+		t.Errorf("JSON = %d %+v, want the validation_failed envelope", resp.StatusCode, envelope)
+	}
+}
+
+// TestRoutesRequireLogin sends every authenticated route without a session: web pages and
+// the unversioned API redirect to the login page, the versioned API answers 401
+func TestRoutesRequireLogin(t *testing.T) {
+	h := newHarness(t)
+	_, f := seed(t, h)
+	anonymous := h.Client()
+
+	check := func(prefix string, cases []routeCase, want int, location string) {
+		seen := map[string]bool{}
+		for _, tc := range cases {
+			key := tc.Method + " " + tc.Route
+			if publicRoutes[tc.Route] || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			t.Run(tc.name(prefix), func(t *testing.T) {
+				resp := tc.send(anonymous, prefix, f)
+				if resp.StatusCode != want {
+					t.Fatalf("status = %d, want %d: %s", resp.StatusCode, want, resp.Body)
+				}
+				if got := resp.Header.Get("Location"); got != location {
+					t.Errorf("Location = %q, want %q", got, location)
+				}
+			})
+		}
+	}
+
+	check("", webRoutes, http.StatusFound, "/login")
+	check("/api", apiRoutes, http.StatusFound, "/login")
+	check("/api/v1", apiRoutes, http.StatusUnauthorized, "")
+}
+
+// TestEveryRouteIsCovered fails when main.go registers a route without a case above
+func TestEveryRouteIsCovered(t *testing.T) {
+	h := newHarness(t)
+	r := newRouter(h.Handler, http.Dir("."))
+
+	covered := map[string]bool{}
+	for _, tc := range webRoutes {
+		covered[tc.Method+" "+tc.Route] = true
+	}
+	for _, tc := range apiRoutes {
+		covered[tc.Method+" /api"+tc.Route] = true
+		covered[tc.Method+" /api/v1"+tc.Route] = true
+	}
+
+	var missing []string
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Prefix routes such as /static/ answer every method; subrouter prefixes have no handler
+			if route.GetHandler() == nil {
+				return nil
+			}
+			methods = []string{"GET"}
+			tpl += "{file}"
+		}
+		for _, method := range methods {
+			if !covered[method+" "+tpl] {
+				missing = append(missing, method+" "+tpl)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk routes: %v", err)
+	}
+
+	sort.Strings(missing)
+	for _, route := range missing {
+		t.Errorf("route %s has no test case", route)
+	}
+}
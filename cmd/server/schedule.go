@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"workout-tracker/internal/database"
+	"workout-tracker/internal/storage"
+)
+
+const scheduleUsage = "usage: server schedule skip-overdue"
+
+// runSchedule handles the schedule subcommand, which marks every workout still scheduled
+// for a day before today, in its user's time zone, skipped. It is meant to run daily, such as from cron.
+func runSchedule(args []string) error {
+	if len(args) != 1 || args[0] != "skip-overdue" {
+		return fmt.Errorf(scheduleUsage)
+	}
+
+	db, err := database.Initialize()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	skipped, err := storage.New(db).ScheduledWorkouts().SkipOverdue(time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Marked %d overdue scheduled workouts skipped\n", skipped)
+	return nil
+}
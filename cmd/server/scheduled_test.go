@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"workout-tracker/internal/handlers/handlerstest"
+	"workout-tracker/internal/models"
+)
+
+// TestScheduledWorkouts schedules workouts through the API, logs one of them and reads the
+// schedule back through the calendar and the planning analytics
+func TestScheduledWorkouts(t *testing.T) {
+	h := newHarness(t) // Thursday 2026-10-15
+	alice := h.LoginAs("alice")
+
+	templateID, err := h.Store.Templates().Create(models.WorkoutTemplate{UserID: alice.UserID, Name: "Legs"})
+	if err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+	if _, err := h.Store.Templates().CreateExercise(models.TemplateExercise{TemplateID: templateID, Name: "Squat", TargetSets: 5, TargetReps: 5, TargetWeight: 100}); err != nil {
+		t.Fatalf("failed to create template exercise: %v", err)
+	}
+
+	schedule := func(req models.CreateScheduledWorkoutRequest) models.ScheduledWorkout {
+		t.Helper()
+		resp := alice.SendJSON("POST", "/api/v1/scheduled-workouts", req)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("schedule workout: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var sw models.ScheduledWorkout
+		resp.JSON(t, &sw)
+		return sw
+	}
+	missed := schedule(models.CreateScheduledWorkoutRequest{Title: "Run", ScheduledDate: "2026-10-13"})
+	today := schedule(models.CreateScheduledWorkoutRequest{TemplateID: &templateID, Title: "Legs", ScheduledDate: "2026-10-15", ScheduledTime: "18:00"})
+	upcoming := schedule(models.CreateScheduledWorkoutRequest{Title: "Run", ScheduledDate: "2026-10-20", EstimatedDuration: 30})
+
+	if today.Status != models.ScheduledStatusScheduled || today.EstimatedDuration != 60 || today.ScheduledTime == nil || *today.ScheduledTime != "18:00" ||
+		len(today.Exercises) != 1 || today.Exercises[0].TargetSets != 5 {
+		t.Errorf("scheduled = %+v, want an hour of the template's squats at 18:00", today)
+	}
+	if upcoming.EstimatedDuration != 30 || len(upcoming.Exercises) != 0 {
+		t.Errorf("scheduled without a template = %+v", upcoming)
+	}
+
+	other := h.CreateUser("bob")
+	otherTemplate, err := h.Store.Templates().Create(models.WorkoutTemplate{UserID: other, Name: "Bob's"})
+	if err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+	resp := alice.SendJSON("POST", "/api/v1/scheduled-workouts", models.CreateScheduledWorkoutRequest{TemplateID: &otherTemplate, Title: "Borrowed", ScheduledDate: "2026-10-16"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("schedule another user's template: status %d, want 404", resp.StatusCode)
+	}
+
+	// Logging a workout that day completes the scheduled one
+	resp = alice.SendJSON("POST", "/api/v1/workouts", models.CreateWorkoutRequest{Name: "Legs", Date: "2026-10-15", Duration: 50, Exercises: []models.CreateWorkoutExerciseRequest{
+		{Name: "Squat", Category: "Legs", Sets: []models.CreateWorkoutSetRequest{{Reps: 5, Weight: 100}}},
+	}})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create workout: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	var workout models.Workout
+	resp.JSON(t, &workout)
+
+	resp = alice.Get(fmt.Sprintf("/api/v1/scheduled-workouts/%d", today.ID))
+	var completed models.ScheduledWorkout
+	resp.JSON(t, &completed)
+	if completed.Status != models.ScheduledStatusCompleted || completed.WorkoutID == nil || *completed.WorkoutID != workout.ID {
+		t.Errorf("scheduled workout after logging = %+v, want completed by workout %d", completed, workout.ID)
+	}
+
+	resp = alice.Get("/api/v1/calendar?month=2026-10")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("calendar: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	var calendar models.MonthlyCalendarView
+	resp.JSON(t, &calendar)
+	// Monday 28 September to Sunday 1 November
+	if calendar.Year != 2026 || calendar.Month != 10 || len(calendar.Days) != 35 {
+		t.Fatalf("calendar = %d-%d with %d days, want October 2026 in 35 days", calendar.Year, calendar.Month, len(calendar.Days))
+	}
+	if first := calendar.Days[0]; first.Date.Format("2006-01-02") != "2026-09-28" || first.IsCurrentMonth {
+		t.Errorf("first day = %+v, want 28 September outside the month", first)
+	}
+	day := calendar.Days[17]
+	if day.Date.Format("2006-01-02") != "2026-10-15" || !day.IsToday || !day.IsCurrentMonth || day.WorkoutCount != 1 ||
+		len(day.Workouts) != 1 || day.Workouts[0].Status != models.ScheduledStatusCompleted {
+		t.Errorf("today = %+v, want the completed workout and one logged", day)
+	}
+	if day := calendar.Days[15]; len(day.Workouts) != 1 || day.Workouts[0].ID != missed.ID || day.WorkoutCount != 0 {
+		t.Errorf("13 October = %+v, want the missed run", day)
+	}
+
+	analytics := func() models.PlanningAnalytics {
+		t.Helper()
+		resp := alice.Get("/api/v1/planning/analytics")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("planning analytics: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var analytics models.PlanningAnalytics
+		resp.JSON(t, &analytics)
+		return analytics
+	}
+	if got := analytics(); got.TotalScheduled != 3 || got.CompletedWorkouts != 1 || got.OverdueWorkouts != 1 || got.UpcomingWorkouts != 1 || got.CompletionRate != 50 {
+		t.Errorf("analytics = %+v, want one each completed, overdue and upcoming", got)
+	}
+
+	// The daily job skips the missed run
+	if skipped, err := h.Store.ScheduledWorkouts().SkipOverdue(h.Clock.Now()); err != nil || skipped != 1 {
+		t.Fatalf("SkipOverdue = %d, %v; want 1", skipped, err)
+	}
+	if got := analytics(); got.SkippedWorkouts != 1 || got.OverdueWorkouts != 0 || got.CompletionRate != 50 {
+		t.Errorf("analytics after skipping = %+v, want the run skipped", got)
+	}
+
+	resp = alice.SendJSON("PUT", fmt.Sprintf("/api/v1/scheduled-workouts/%d", upcoming.ID), models.UpdateScheduledWorkoutRequest{
+		Title: "Long run", ScheduledDate: "2026-10-21", ScheduledTime: "06:30", EstimatedDuration: 90,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	var moved models.ScheduledWorkout
+	resp.JSON(t, &moved)
+	if moved.Title != "Long run" || moved.ScheduledDate.Format("2006-01-02") != "2026-10-21" || moved.Status != models.ScheduledStatusScheduled || moved.EstimatedDuration != 90 {
+		t.Errorf("moved = %+v, want a long run still scheduled on 21 October", moved)
+	}
+
+	if resp := alice.Delete(fmt.Sprintf("/api/v1/scheduled-workouts/%d", upcoming.ID)); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("delete: status %d", resp.StatusCode)
+	}
+	h.Clock.Set(time.Date(2026, 10, 22, 9, 0, 0, 0, time.UTC))
+	if got := analytics(); got.TotalScheduled != 2 || got.UpcomingWorkouts != 0 {
+		t.Errorf("analytics after deleting = %+v, want two scheduled and none upcoming", got)
+	}
+}
+
+// TestSkipOverdueInTimeZone runs the daily job while it is already Friday in UTC but still
+// Thursday evening for a user eight hours behind it
+func TestSkipOverdueInTimeZone(t *testing.T) {
+	h := newHarness(t)
+	alice := h.LoginAs("alice")
+	bob := h.LoginAs("bob")
+	setTimezone(t, alice, "Etc/GMT+8")
+
+	schedule := func(client *handlerstest.Client, date string) models.ScheduledWorkout {
+		t.Helper()
+		resp := client.SendJSON("POST", "/api/v1/scheduled-workouts", models.CreateScheduledWorkoutRequest{Title: "Run", ScheduledDate: date})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("schedule workout: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var sw models.ScheduledWorkout
+		resp.JSON(t, &sw)
+		return sw
+	}
+	alicesToday := schedule(alice, "2026-10-15")
+	alicesMissed := schedule(alice, "2026-10-14")
+	bobsYesterday := schedule(bob, "2026-10-15")
+
+	h.Clock.Set(time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC)) // 19:00 on Thursday for alice
+	if skipped, err := h.Store.ScheduledWorkouts().SkipOverdue(h.Clock.Now()); err != nil || skipped != 2 {
+		t.Fatalf("SkipOverdue = %d, %v; want alice's missed workout and bob's", skipped, err)
+	}
+	for _, want := range []struct {
+		sw     models.ScheduledWorkout
+		status string
+	}{
+		{alicesToday, models.ScheduledStatusScheduled},
+		{alicesMissed, models.ScheduledStatusSkipped},
+		{bobsYesterday, models.ScheduledStatusSkipped},
+	} {
+		if sw, err := h.Store.ScheduledWorkouts().Get(want.sw.ID, want.sw.UserID); err != nil || sw.Status != want.status {
+			t.Errorf("workout on %s of user %d = %q, %v; want %s", want.sw.ScheduledDate.Format("2006-01-02"), want.sw.UserID, sw.Status, err, want.status)
+		}
+	}
+}
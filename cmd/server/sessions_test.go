@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"workout-tracker/internal/handlers"
+	"workout-tracker/internal/handlers/handlerstest"
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/storage"
+)
+
+// TestSessionLifecycle starts a session from a template with a superset, logs sets from
+// two devices and finishes it into a workout with the measured duration
+func TestSessionLifecycle(t *testing.T) {
+	h := newHarness(t)
+	phone := h.LoginAs("alice")
+	started := h.Clock.Now()
+
+	templateID, err := h.Store.Templates().Create(models.WorkoutTemplate{UserID: phone.UserID, Name: "Arms"})
+	if err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+	for i, name := range []string{"Curl", "Pushdown", "Plank"} {
+		groupID := 1
+		if name == "Plank" {
+			groupID = 0
+		}
+		exercise := models.TemplateExercise{TemplateID: templateID, Name: name, Category: "Arms", OrderIndex: i, GroupID: groupID, TargetSets: 2, TargetReps: 10, RestTime: 60}
+		if _, err := h.Store.Templates().CreateExercise(exercise); err != nil {
+			t.Fatalf("failed to create template exercise: %v", err)
+		}
+	}
+
+	var session models.WorkoutSession
+	resp := phone.SendJSON("POST", "/api/v1/sessions", models.StartSessionRequest{TemplateID: templateID})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("start: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	resp.JSON(t, &session)
+	if session.Name != "Arms" || len(session.Exercises) != 3 || session.CurrentExerciseID != session.Exercises[0].ID {
+		t.Fatalf("started session = %+v, want the template's exercises starting with Curl", session)
+	}
+	curl, pushdown, plank := session.Exercises[0].ID, session.Exercises[1].ID, session.Exercises[2].ID
+	sessionPath := "/api/v1/sessions/" + strconv.Itoa(session.ID)
+
+	if resp := phone.SendJSON("POST", "/api/v1/sessions", models.StartSessionRequest{}); resp.StatusCode != http.StatusConflict {
+		t.Errorf("second start: status %d, want 409", resp.StatusCode)
+	}
+
+	completeSet := func(client *handlerstest.Client, exerciseID int) models.WorkoutSession {
+		t.Helper()
+		resp := client.SendJSON("POST", sessionPath+"/sets", models.CompleteSessionSetRequest{ExerciseID: exerciseID, Reps: 10, Weight: 20})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("complete set: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var s models.WorkoutSession
+		resp.JSON(t, &s)
+		return s
+	}
+
+	// Within the superset the session alternates between its exercises
+	session = completeSet(phone, curl)
+	if session.CurrentExerciseID != pushdown || session.RestRemaining != 60 {
+		t.Errorf("after a curl: current %d, rest %d; want pushdown %d and 60s rest", session.CurrentExerciseID, session.RestRemaining, pushdown)
+	}
+
+	// The rest timer keeps running on the server, so another device resumes it
+	h.Clock.Advance(25 * time.Second)
+	laptop := h.Client()
+	if resp := laptop.PostForm("/login", url.Values{"username": {"alice"}, "password": {handlerstest.DefaultPassword}}); resp.StatusCode != http.StatusFound {
+		t.Fatalf("login on second device: status %d", resp.StatusCode)
+	}
+	resp = laptop.Get("/api/v1/sessions/active")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("active session: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	resp.JSON(t, &session)
+	if session.CurrentExerciseID != pushdown || session.RestRemaining != 35 || len(session.Exercises[0].Sets) != 1 {
+		t.Errorf("resumed session: current %d, rest %d, curl sets %d; want pushdown, 35s and 1 set", session.CurrentExerciseID, session.RestRemaining, len(session.Exercises[0].Sets))
+	}
+
+	session = completeSet(laptop, pushdown)
+	if session.CurrentExerciseID != curl {
+		t.Errorf("after a pushdown: current %d, want curl %d", session.CurrentExerciseID, curl)
+	}
+	completeSet(laptop, curl)
+	session = completeSet(laptop, pushdown)
+	if session.CurrentExerciseID != plank {
+		t.Errorf("after the superset: current %d, want plank %d", session.CurrentExerciseID, plank)
+	}
+
+	h.Clock.Set(started.Add(47*time.Minute + 40*time.Second))
+	resp = phone.SendJSON("POST", sessionPath+"/finish", models.FinishSessionRequest{Notes: "pump"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("finish: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	var workout models.Workout
+	resp.JSON(t, &workout)
+	if workout.Duration != 48 || workout.Notes != "pump" || !workout.Date.Equal(started) {
+		t.Errorf("workout = %+v, want 48 minutes from the session start", workout)
+	}
+	if len(workout.Exercises) != 2 || workout.Exercises[0].Name != "Curl" || len(workout.Exercises[1].Sets) != 2 {
+		t.Fatalf("workout exercises = %+v, want curl and pushdown with two sets each", workout.Exercises)
+	}
+	if workout.Exercises[0].GroupID != 1 || workout.Exercises[1].GroupID != 1 {
+		t.Errorf("workout lost the superset: %+v", workout.Exercises)
+	}
+
+	resp = phone.Get(sessionPath)
+	resp.JSON(t, &session)
+	if session.WorkoutID == nil || *session.WorkoutID != workout.ID || session.FinishedAt == nil {
+		t.Errorf("finished session = %+v, want it to point at workout %d", session, workout.ID)
+	}
+	if resp := phone.SendJSON("POST", sessionPath+"/sets", models.CompleteSessionSetRequest{ExerciseID: plank, Duration: 60}); resp.StatusCode != http.StatusConflict {
+		t.Errorf("set after finish: status %d, want 409", resp.StatusCode)
+	}
+	if resp := phone.Get("/api/v1/sessions/active"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("active session after finish: status %d, want 404", resp.StatusCode)
+	}
+}
+
+// staleStore checks for an active session as a start racing another device's does,
+// before the other session is stored
+type staleStore struct{ storage.Store }
+
+func (s *staleStore) Sessions() storage.SessionRepository { return staleSessions{s.Store.Sessions()} }
+
+type staleSessions struct{ storage.SessionRepository }
+
+func (staleSessions) Active(int) (models.WorkoutSession, error) {
+	return models.WorkoutSession{}, storage.ErrNotFound
+}
+
+// TestSessionStartRace starts a session that passes the active session check while another
+// one is already stored: the unique index stops it with the same 409 as the check
+func TestSessionStartRace(t *testing.T) {
+	stale := &staleStore{}
+	h := handlerstest.New(t, func(h *handlers.Handler) http.Handler {
+		return newRouter(h, http.Dir(filepath.Join(handlerstest.RepoRoot(), "web", "static")))
+	}, handlers.WithStore(stale))
+	stale.Store = h.Store
+	alice := h.LoginAs("alice")
+
+	if _, err := h.Store.Sessions().Create(models.WorkoutSession{UserID: alice.UserID, Name: "Phone", StartedAt: h.Clock.Now()}); err != nil {
+		t.Fatalf("failed to start session: %v", err)
+	}
+	resp := alice.SendJSON("POST", "/api/v1/sessions", models.StartSessionRequest{Name: "Watch"})
+	var envelope models.APIErrorResponse
+	resp.JSON(t, &envelope)
+	if resp.StatusCode != http.StatusConflict || envelope.Error.Message != "A session is already in progress" {
+		t.Errorf("racing start = %d %+v, want 409 for the session in progress", resp.StatusCode, envelope)
+	}
+}
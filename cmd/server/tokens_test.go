@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"workout-tracker/internal/handlers/handlerstest"
+	"workout-tracker/internal/models"
+)
+
+// TestAPITokens creates personal access tokens from a session, uses them as bearer tokens
+// on both APIs and revokes one
+func TestAPITokens(t *testing.T) {
+	h := newHarness(t)
+	alice := h.LoginAs("alice")
+
+	create := func(scope string) (*handlerstest.Client, models.CreateAPITokenResponse) {
+		t.Helper()
+		resp := alice.SendJSON("POST", "/api/tokens", models.CreateAPITokenRequest{Name: "cli", Scope: scope})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create %s token: status %d: %s", scope, resp.StatusCode, resp.Body)
+		}
+		var token models.CreateAPITokenResponse
+		resp.JSON(t, &token)
+		client := h.Client()
+		client.Header.Set("Authorization", "Bearer "+token.Token)
+		return client, token
+	}
+	reader, readToken := create("read")
+	writer, _ := create("read_write")
+
+	tokens := func() map[int]models.APIToken {
+		t.Helper()
+		resp := alice.Get("/api/tokens")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("list tokens: status %d: %s", resp.StatusCode, resp.Body)
+		}
+		var list []models.APIToken
+		resp.JSON(t, &list)
+		byID := map[int]models.APIToken{}
+		for _, token := range list {
+			byID[token.ID] = token
+		}
+		return byID
+	}
+	if token := tokens()[readToken.ID]; token.LastUsedAt != nil || token.Scope != "read" {
+		t.Errorf("new token = %+v, want a read token never used", token)
+	}
+
+	// A token authenticates as its user on both APIs and records when it was used
+	h.Clock.Advance(time.Minute)
+	for _, path := range []string{"/api/workouts", "/api/v1/workouts"} {
+		if resp := reader.Get(path); resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s with a token: status %d: %s", path, resp.StatusCode, resp.Body)
+		}
+	}
+	if token := tokens()[readToken.ID]; token.LastUsedAt == nil || !token.LastUsedAt.Equal(h.Clock.Now()) {
+		t.Errorf("last used = %v, want %v", token.LastUsedAt, h.Clock.Now())
+	}
+
+	// Read-only tokens cannot write, on either API
+	workout := models.CreateWorkoutRequest{Name: "Push", Date: "2026-10-15"}
+	for _, path := range []string{"/api/workouts", "/api/v1/workouts"} {
+		if resp := reader.SendJSON("POST", path, workout); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("POST %s with a read token: status %d, want 403: %s", path, resp.StatusCode, resp.Body)
+		}
+	}
+	if resp := writer.SendJSON("POST", "/api/v1/workouts", workout); resp.StatusCode != http.StatusCreated {
+		t.Errorf("POST with a read_write token: status %d, want 201: %s", resp.StatusCode, resp.Body)
+	}
+
+	// Tokens cannot manage tokens, even with write access
+	for _, req := range []struct {
+		method, path string
+		body         interface{}
+	}{
+		{"GET", "/api/tokens", nil},
+		{"GET", "/api/v1/tokens", nil},
+		{"POST", "/api/v1/tokens", models.CreateAPITokenRequest{Name: "escalated", Scope: "read_write"}},
+		{"DELETE", fmt.Sprintf("/api/v1/tokens/%d", readToken.ID), nil},
+	} {
+		if resp := writer.SendJSON(req.method, req.path, req.body); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s %s with a token: status %d, want 403: %s", req.method, req.path, resp.StatusCode, resp.Body)
+		}
+	}
+	if resp := writer.PostForm("/account/tokens", url.Values{"name": {"escalated"}, "scope": {"read_write"}}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("POST /account/tokens with a token: status %d, want 403", resp.StatusCode)
+	}
+	if len(tokens()) != 2 {
+		t.Errorf("tokens = %+v, want only the two created from the session", tokens())
+	}
+
+	// A revoked token is refused with a challenge rather than a login redirect
+	if resp := alice.Delete(fmt.Sprintf("/api/tokens/%d", readToken.ID)); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("revoke: status %d: %s", resp.StatusCode, resp.Body)
+	}
+	for _, path := range []string{"/api/workouts", "/api/v1/workouts", "/workouts"} {
+		resp := reader.Get(path)
+		if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("GET %s with a revoked token: status %d, WWW-Authenticate %q; want a 401 challenge",
+				path, resp.StatusCode, resp.Header.Get("WWW-Authenticate"))
+		}
+	}
+	if token := tokens()[readToken.ID]; token.RevokedAt == nil {
+		t.Errorf("revoked token = %+v, want its revocation recorded", token)
+	}
+	if resp := writer.Get("/api/v1/workouts"); resp.StatusCode != http.StatusOK {
+		t.Errorf("GET with the other token after revoking: status %d", resp.StatusCode)
+	}
+}
@@ -5,6 +5,7 @@ go 1.24.5
 require (
 	github.com/gorilla/mux v1.8.1
 	github.com/gorilla/sessions v1.4.0
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.18
 	golang.org/x/crypto v0.40.0
 	golang.org/x/oauth2 v0.30.0
@@ -0,0 +1,175 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Queries throughout the application are written with SQLite-style ? placeholders.
+// DB and Tx shadow the database/sql query methods and rewrite the placeholders to
+// $1, $2... when talking to PostgreSQL, so the same SQL runs on both drivers.
+
+// Rebind rewrites ? placeholders for the connected driver, leaving quoted strings alone
+func (db *DB) Rebind(query string) string {
+	if db.Driver != DriverPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	inString := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inString = !inString
+			b.WriteRune(r)
+		case r == '?' && !inString:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Exec executes a query without returning rows
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.Rebind(query), args...)
+}
+
+// Query executes a query that returns rows
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(db.Rebind(query), args...)
+}
+
+// QueryRow executes a query that returns at most one row
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.Rebind(query), args...)
+}
+
+// Insert executes an INSERT and returns the ID of the new row
+func (db *DB) Insert(query string, args ...interface{}) (int, error) {
+	return insertReturningID(db.Driver, db, query, args)
+}
+
+// Tx is a transaction that rebinds placeholders like DB does
+type Tx struct {
+	*sql.Tx
+	db *DB
+}
+
+// Begin starts a transaction
+func (db *DB) Begin() (*Tx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: db}, nil
+}
+
+// Exec executes a query without returning rows inside the transaction
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(tx.db.Rebind(query), args...)
+}
+
+// Query executes a query that returns rows inside the transaction
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.db.Rebind(query), args...)
+}
+
+// QueryRow executes a query that returns at most one row inside the transaction
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(tx.db.Rebind(query), args...)
+}
+
+// Insert executes an INSERT inside the transaction and returns the ID of the new row
+func (tx *Tx) Insert(query string, args ...interface{}) (int, error) {
+	return insertReturningID(tx.db.Driver, tx, query, args)
+}
+
+// execQueryer is implemented by both DB and Tx
+type execQueryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// insertReturningID runs an INSERT and reports the new row's id. PostgreSQL drivers
+// don't support LastInsertId, so there the statement gets a RETURNING clause instead.
+func insertReturningID(driver string, q execQueryer, query string, args []interface{}) (int, error) {
+	if driver == DriverPostgres {
+		var id int
+		query = strings.TrimSuffix(strings.TrimSpace(query), ";") + " RETURNING id"
+		err := q.QueryRow(query, args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := q.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// IsUniqueViolation reports whether an error is a driver's report of a unique constraint
+// or unique index rejecting a row
+func IsUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505" // unique_violation
+	}
+	return false
+}
+
+// Timestamp scans a time column. SQLite loses the column type on expressions such as
+// MAX(date), so the driver hands those back as text instead of time.Time.
+type Timestamp struct {
+	time.Time
+}
+
+// Scan implements sql.Scanner
+func (t *Timestamp) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	case time.Time:
+		t.Time = v
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	}
+	return fmt.Errorf("cannot scan %T into Timestamp", value)
+}
+
+func (t *Timestamp) parse(s string) error {
+	s = strings.TrimSuffix(s, "Z")
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if parsed, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse %q as a timestamp", s)
+}
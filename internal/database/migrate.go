@@ -0,0 +1,494 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+// migrationDirs maps each driver to its migrations. Both directories carry the
+// same versions so that a version number means the same schema on either database.
+var migrationDirs = map[string]string{
+	DriverSQLite:   "migrations/sqlite",
+	DriverPostgres: "migrations/postgres",
+}
+
+// migrationFilePattern matches files such as 0002_api_tokens.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change loaded from the embedded migrations directory
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus reports whether a migration has been applied to the database
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Modified  bool
+}
+
+// appliedMigration is a row of the schema_migrations table
+type appliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// loadMigrations reads the embedded migrations for a driver ordered by version
+func loadMigrations(driver string) ([]Migration, error) {
+	dir, ok := migrationDirs[driver]
+	if !ok {
+		return nil, fmt.Errorf("no migrations for driver %s", driver)
+	}
+	return readMigrations(migrationFiles, dir)
+}
+
+// readMigrations reads the migrations in a directory ordered by version
+func readMigrations(files fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %v", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected file in migrations: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %v", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		m.Checksum = migrationChecksum(m.Up, m.Down)
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	// Versions must run 1, 2, 3... so that "to N" is unambiguous
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be contiguous: expected %d, found %d", i+1, m.Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// migrationChecksum fingerprints both directions of a migration
+func migrationChecksum(up, down string) string {
+	sum := sha256.Sum256([]byte(up + "\x00" + down))
+	return hex.EncodeToString(sum[:])
+}
+
+// ensureMigrationsTable creates the schema_migrations table if it doesn't exist
+func (db *DB) ensureMigrationsTable() error {
+	timestampType := "DATETIME"
+	if db.Driver == DriverPostgres {
+		timestampType = "TIMESTAMPTZ"
+	}
+
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at %s DEFAULT CURRENT_TIMESTAMP
+		)
+	`, timestampType)
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+	return nil
+}
+
+// tableExists reports whether a table exists in the database
+func (db *DB) tableExists(table string) (bool, error) {
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	if db.Driver == DriverPostgres {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+	}
+
+	var count int
+	err := db.QueryRow(query, table).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check table %s: %v", table, err)
+	}
+	return count > 0, nil
+}
+
+// appliedMigrations returns the recorded migrations keyed by version
+func (db *DB) appliedMigrations() (map[int]appliedMigration, error) {
+	applied := make(map[int]appliedMigration)
+
+	exists, err := db.tableExists("schema_migrations")
+	if err != nil || !exists {
+		return applied, err
+	}
+
+	rows, err := db.Query(`SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[a.Version] = a
+	}
+
+	return applied, rows.Err()
+}
+
+// currentVersion returns the highest applied version, or 0 for an empty database
+func currentVersion(applied map[int]appliedMigration) int {
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version
+}
+
+// prepareMigrations loads the migrations, baselines databases created before
+// versioned migrations existed and refuses to continue if an applied migration changed
+func (db *DB) prepareMigrations() ([]Migration, map[int]appliedMigration, error) {
+	migrations, err := loadMigrations(db.Driver)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := db.ensureMigrationsTable(); err != nil {
+		return nil, nil, err
+	}
+
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(applied) == 0 {
+		legacy, err := db.tableExists("users")
+		if err != nil {
+			return nil, nil, err
+		}
+		if legacy {
+			log.Println("Existing database without schema_migrations found, recording it as baseline version 1")
+			if db.Driver == DriverSQLite {
+				if err := db.upgradeLegacySchema(); err != nil {
+					return nil, nil, err
+				}
+			}
+			// The baseline only uses IF NOT EXISTS, so it fills in whatever is missing
+			if err := db.applyMigration(migrations[0], true); err != nil {
+				return nil, nil, err
+			}
+			if applied, err = db.appliedMigrations(); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if err := verifyMigrations(migrations, applied); err != nil {
+		return nil, nil, err
+	}
+
+	return migrations, applied, nil
+}
+
+// verifyMigrations checks every applied migration still matches its embedded file
+func verifyMigrations(migrations []Migration, applied map[int]appliedMigration) error {
+	for version, a := range applied {
+		if version < 1 || version > len(migrations) {
+			return fmt.Errorf("database is at migration %d_%s which this build does not know about", version, a.Name)
+		}
+		m := migrations[version-1]
+		if m.Checksum != a.Checksum {
+			return fmt.Errorf("migration %d_%s has been modified since it was applied (checksum %s, recorded %s)",
+				m.Version, m.Name, m.Checksum[:12], a.Checksum[:min(12, len(a.Checksum))])
+		}
+	}
+	return nil
+}
+
+// applyMigration runs one migration in a transaction and records the result
+func (db *DB) applyMigration(m Migration, up bool) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// SQLite table rebuilds must not fire cascades, and SQLite ignores this pragma inside a transaction
+	if db.Driver == DriverSQLite {
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return fmt.Errorf("failed to disable foreign keys: %v", err)
+		}
+		defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if up {
+		if _, err := tx.Exec(m.Up); err != nil {
+			return fmt.Errorf("failed to apply migration %d_%s: %v", m.Version, m.Name, err)
+		}
+		_, err = tx.Exec(db.Rebind(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`),
+			m.Version, m.Name, m.Checksum, time.Now())
+	} else {
+		if m.Down == "" {
+			return fmt.Errorf("migration %d_%s cannot be rolled back", m.Version, m.Name)
+		}
+		if _, err := tx.Exec(m.Down); err != nil {
+			return fmt.Errorf("failed to roll back migration %d_%s: %v", m.Version, m.Name, err)
+		}
+		_, err = tx.Exec(db.Rebind(`DELETE FROM schema_migrations WHERE version = ?`), m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %v", m.Version, m.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d_%s: %v", m.Version, m.Name, err)
+	}
+
+	if up {
+		log.Printf("Applied migration %d_%s", m.Version, m.Name)
+	} else {
+		log.Printf("Rolled back migration %d_%s", m.Version, m.Name)
+	}
+	return nil
+}
+
+// migrationLockKey identifies the PostgreSQL advisory lock held while migrating
+const migrationLockKey = 7261450
+
+// lockMigrations stops two servers starting at once from running the same migrations.
+// On PostgreSQL it holds a session advisory lock on a connection of its own until the
+// returned function is called; SQLite already serialises writers, so there it does nothing.
+func (db *DB) lockMigrations() (func(), error) {
+	if db.Driver != DriverPostgres {
+		return func() {}, nil
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to lock migrations: %v", err)
+	}
+	return func() {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+			log.Printf("Failed to unlock migrations: %v", err)
+		}
+		conn.Close()
+	}, nil
+}
+
+// MigrateUp applies every pending migration
+func (db *DB) MigrateUp() error {
+	migrations, err := loadMigrations(db.Driver)
+	if err != nil {
+		return err
+	}
+	return db.MigrateTo(len(migrations))
+}
+
+// MigrateDown rolls back the most recently applied migration
+func (db *DB) MigrateDown() error {
+	unlock, err := db.lockMigrations()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, applied, err := db.prepareMigrations()
+	if err != nil {
+		return err
+	}
+
+	current := currentVersion(applied)
+	if current == 0 {
+		return fmt.Errorf("no migrations to roll back")
+	}
+	return db.migrateTo(current - 1)
+}
+
+// MigrateTo applies or rolls back migrations until the database is at the target version
+func (db *DB) MigrateTo(target int) error {
+	unlock, err := db.lockMigrations()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return db.migrateTo(target)
+}
+
+// migrateTo is MigrateTo for callers already holding the migration lock
+func (db *DB) migrateTo(target int) error {
+	migrations, applied, err := db.prepareMigrations()
+	if err != nil {
+		return err
+	}
+
+	if target < 0 || target > len(migrations) {
+		return fmt.Errorf("unknown migration version %d (latest is %d)", target, len(migrations))
+	}
+
+	current := currentVersion(applied)
+	for v := current + 1; v <= target; v++ {
+		if err := db.applyMigration(migrations[v-1], true); err != nil {
+			return err
+		}
+	}
+	for v := current; v > target; v-- {
+		if err := db.applyMigration(migrations[v-1], false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MigrationStatus lists every known migration and whether it has been applied.
+// Unlike the other commands it never changes the database.
+func (db *DB) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(db.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			appliedAt := a.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = a.Checksum != m.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// legacyColumn is a column that older databases gained through startup column probes
+type legacyColumn struct {
+	table      string
+	column     string
+	definition string
+}
+
+// legacyColumns lists the columns added before versioned migrations existed
+var legacyColumns = []legacyColumn{
+	{"users", "full_name", "TEXT DEFAULT ''"},
+	{"users", "bio", "TEXT DEFAULT ''"},
+	{"users", "avatar", "TEXT DEFAULT ''"},
+	{"users", "is_active", "BOOLEAN DEFAULT 1"},
+	{"predefined_exercises", "video_url", "TEXT DEFAULT ''"},
+	{"predefined_exercises", "instructions", "TEXT DEFAULT ''"},
+	{"predefined_exercises", "tips", "TEXT DEFAULT ''"},
+	{"predefined_exercises", "muscle_groups", "TEXT DEFAULT ''"},
+	{"predefined_exercises", "equipment", "TEXT DEFAULT ''"},
+	{"predefined_exercises", "difficulty", "TEXT DEFAULT 'beginner'"},
+	{"predefined_exercises", "image_url", "TEXT DEFAULT ''"},
+	{"workouts", "user_id", "INTEGER DEFAULT 1"},
+}
+
+// upgradeLegacySchema adds the columns older databases may be missing so that
+// the baseline migration can be recorded against them
+func (db *DB) upgradeLegacySchema() error {
+	for _, c := range legacyColumns {
+		exists, err := db.tableExists(c.table)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			// The baseline migration creates the table with every column
+			continue
+		}
+
+		var columnExists int
+		err = db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column).Scan(&columnExists)
+		if err != nil {
+			return fmt.Errorf("failed to check %s.%s column existence: %v", c.table, c.column, err)
+		}
+		if columnExists > 0 {
+			continue
+		}
+
+		query := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.column, c.definition)
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to run migration: %s, error: %v", query, err)
+		}
+		log.Printf("Added %s column to %s table", c.column, c.table)
+	}
+
+	return nil
+}
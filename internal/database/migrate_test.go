@@ -0,0 +1,225 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// openTestDB opens an empty SQLite database in a temporary directory without migrating it
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DATABASE_PATH", filepath.Join(t.TempDir(), "test.db"))
+	db, err := Open()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// appliedVersion returns the version the database is at
+func appliedVersion(t *testing.T, db *DB) int {
+	t.Helper()
+
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		t.Fatalf("failed to read applied migrations: %v", err)
+	}
+	return currentVersion(applied)
+}
+
+// hasColumn reports whether a table has a column
+func hasColumn(t *testing.T, db *DB, table, column string) bool {
+	t.Helper()
+
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&n); err != nil {
+		t.Fatalf("failed to check %s.%s: %v", table, column, err)
+	}
+	return n > 0
+}
+
+func TestMigrateRoundTrip(t *testing.T) {
+	db := openTestDB(t)
+	migrations, err := loadMigrations(DriverSQLite)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	latest := len(migrations)
+
+	if err := db.MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if v := appliedVersion(t, db); v != latest {
+		t.Fatalf("version after MigrateUp = %d, want %d", v, latest)
+	}
+
+	if err := db.MigrateDown(); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if v := appliedVersion(t, db); v != latest-1 {
+		t.Errorf("version after MigrateDown = %d, want %d", v, latest-1)
+	}
+
+	// Every migration after the baseline rolls back and applies again
+	if err := db.MigrateTo(1); err != nil {
+		t.Fatalf("MigrateTo(1): %v", err)
+	}
+	if exists, _ := db.tableExists("api_tokens"); exists || appliedVersion(t, db) != 1 {
+		t.Errorf("at version %d api_tokens exists = %v, want version 1 without it", appliedVersion(t, db), exists)
+	}
+	if err := db.MigrateTo(2); err != nil {
+		t.Fatalf("MigrateTo(2): %v", err)
+	}
+	if exists, _ := db.tableExists("api_tokens"); !exists {
+		t.Errorf("api_tokens missing at version 2")
+	}
+	if err := db.MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp after rolling back: %v", err)
+	}
+
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	for _, s := range statuses {
+		if !s.Applied || s.Modified || s.AppliedAt == nil {
+			t.Errorf("status of %d_%s = %+v, want applied unmodified", s.Version, s.Name, s)
+		}
+	}
+
+	if err := db.MigrateTo(latest + 1); err == nil {
+		t.Errorf("MigrateTo(%d) succeeded, want an unknown version error", latest+1)
+	}
+	if err := db.MigrateTo(0); err == nil || !strings.Contains(err.Error(), "cannot be rolled back") {
+		t.Errorf("MigrateTo(0) = %v, want the baseline to refuse rolling back", err)
+	}
+}
+
+func TestMigrateRejectsModifiedMigration(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	// The recorded checksum no longer matches the file, as after editing a released migration
+	if _, err := db.Exec(`UPDATE schema_migrations SET checksum = 'edited' WHERE version = 2`); err != nil {
+		t.Fatalf("failed to change checksum: %v", err)
+	}
+	if err := db.MigrateUp(); err == nil || !strings.Contains(err.Error(), "has been modified") {
+		t.Errorf("MigrateUp = %v, want a modified migration error", err)
+	}
+	if err := db.MigrateDown(); err == nil {
+		t.Errorf("MigrateDown succeeded over a modified migration")
+	}
+
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if !statuses[1].Modified || statuses[0].Modified {
+		t.Errorf("statuses = %+v, want only migration 2 modified", statuses[:2])
+	}
+
+	// A database from a newer build is refused too
+	migrations, _ := loadMigrations(DriverSQLite)
+	if _, err := db.Exec(`UPDATE schema_migrations SET checksum = ? WHERE version = 2`, migrations[1].Checksum); err != nil {
+		t.Fatalf("failed to restore checksum: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, checksum) VALUES (999, 'future', 'x')`); err != nil {
+		t.Fatalf("failed to record migration: %v", err)
+	}
+	if err := db.MigrateUp(); err == nil || !strings.Contains(err.Error(), "does not know about") {
+		t.Errorf("MigrateUp = %v, want an unknown migration error", err)
+	}
+}
+
+func TestMigrateBaselinesLegacyDatabase(t *testing.T) {
+	db := openTestDB(t)
+
+	// A database from before versioned migrations, lacking columns added by startup probes
+	for _, query := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT UNIQUE NOT NULL, email TEXT UNIQUE NOT NULL,
+			password_hash TEXT NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE workouts (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, date DATETIME NOT NULL,
+			duration INTEGER DEFAULT 0, notes TEXT DEFAULT '', created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`INSERT INTO users (username, email, password_hash) VALUES ('alice', 'alice@example.com', 'x')`,
+		`INSERT INTO workouts (name, date) VALUES ('Push', '2026-10-12 18:00:00')`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("failed to create legacy schema: %v", err)
+		}
+	}
+
+	if err := db.MigrateTo(1); err != nil {
+		t.Fatalf("MigrateTo(1): %v", err)
+	}
+	applied, err := db.appliedMigrations()
+	if err != nil || len(applied) != 1 || applied[1].Name != "baseline" {
+		t.Fatalf("applied migrations = %+v, %v; want the baseline recorded", applied, err)
+	}
+
+	// The existing tables and rows are kept, gaining the missing columns
+	var username string
+	var userID int
+	if err := db.QueryRow(`SELECT username FROM users`).Scan(&username); err != nil || username != "alice" {
+		t.Errorf("legacy user = %q, %v", username, err)
+	}
+	if err := db.QueryRow(`SELECT user_id FROM workouts WHERE name = 'Push'`).Scan(&userID); err != nil || userID != 1 {
+		t.Errorf("legacy workout's user_id = %d, %v; want the default 1", userID, err)
+	}
+	for _, c := range legacyColumns {
+		if !hasColumn(t, db, c.table, c.column) {
+			t.Errorf("%s.%s was not added", c.table, c.column)
+		}
+	}
+	// Tables the legacy database lacked are created
+	if exists, _ := db.tableExists("exercises"); !exists {
+		t.Errorf("exercises table missing after the baseline")
+	}
+
+	if err := db.MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp from the baseline: %v", err)
+	}
+}
+
+func TestReadMigrations(t *testing.T) {
+	file := func(sql string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(sql)} }
+
+	files := fstest.MapFS{
+		"m/0001_first.up.sql":    file("CREATE TABLE a (id INTEGER);"),
+		"m/0002_second.up.sql":   file("CREATE TABLE b (id INTEGER);"),
+		"m/0002_second.down.sql": file("DROP TABLE b;"),
+	}
+	migrations, err := readMigrations(files, "m")
+	if err != nil || len(migrations) != 2 || migrations[1].Name != "second" || migrations[1].Down != "DROP TABLE b;" {
+		t.Fatalf("readMigrations = %+v, %v", migrations, err)
+	}
+
+	tests := map[string]fstest.MapFS{
+		"must be contiguous": {
+			"m/0001_first.up.sql": file("SELECT 1;"),
+			"m/0003_third.up.sql": file("SELECT 1;"),
+		},
+		"conflicting names": {
+			"m/0001_first.up.sql":   file("SELECT 1;"),
+			"m/0001_other.down.sql": file("SELECT 1;"),
+		},
+		"has no up file": {
+			"m/0001_first.down.sql": file("SELECT 1;"),
+		},
+		"unexpected file": {
+			"m/0001_first.up.sql": file("SELECT 1;"),
+			"m/README.md":         file("notes"),
+		},
+	}
+	for want, files := range tests {
+		if _, err := readMigrations(files, "m"); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("readMigrations = %v, want an error containing %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_workout_programs_created_by;
+ALTER TABLE workout_programs DROP CONSTRAINT IF EXISTS workout_programs_created_by_fkey;
+ALTER TABLE workout_programs ALTER COLUMN created_by SET DEFAULT 0;
+
+ALTER TABLE workouts DROP CONSTRAINT IF EXISTS workouts_user_id_fkey;
+ALTER TABLE workouts ALTER COLUMN user_id SET DEFAULT 1;
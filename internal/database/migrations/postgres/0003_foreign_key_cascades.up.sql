@@ -0,0 +1,11 @@
+-- Give workouts and workout_programs real foreign keys to their owners so that
+-- deleting a user cascades to everything they own.
+
+ALTER TABLE workouts ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE workouts ADD CONSTRAINT workouts_user_id_fkey
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE workout_programs ALTER COLUMN created_by DROP DEFAULT;
+ALTER TABLE workout_programs ADD CONSTRAINT workout_programs_created_by_fkey
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_workout_programs_created_by ON workout_programs(created_by);
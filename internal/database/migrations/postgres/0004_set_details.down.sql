@@ -0,0 +1,4 @@
+ALTER TABLE sets DROP COLUMN notes;
+ALTER TABLE sets DROP COLUMN set_type;
+ALTER TABLE sets DROP COLUMN rir;
+ALTER TABLE sets DROP COLUMN rpe;
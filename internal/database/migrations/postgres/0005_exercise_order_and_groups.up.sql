@@ -0,0 +1,13 @@
+-- Explicit exercise order within a workout or template, and superset/circuit groups.
+-- Exercises sharing a non-zero group_id are performed back to back.
+ALTER TABLE exercises ADD COLUMN order_index INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE exercises ADD COLUMN group_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE template_exercises ADD COLUMN group_id INTEGER NOT NULL DEFAULT 0;
+
+-- Existing workouts keep their insertion order
+UPDATE exercises SET order_index = (
+	SELECT COUNT(*) FROM exercises earlier
+	WHERE earlier.workout_id = exercises.workout_id AND earlier.id < exercises.id
+);
+
+CREATE INDEX IF NOT EXISTS idx_exercises_workout_order ON exercises(workout_id, order_index);
@@ -0,0 +1,2 @@
+-- The formula used for estimated one-rep maxes: epley, brzycki, lombardi, mayhew or wathan
+ALTER TABLE user_settings ADD COLUMN e1rm_formula TEXT NOT NULL DEFAULT 'epley';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_personal_records_user_exercise;
+ALTER TABLE personal_records DROP COLUMN is_current;
+ALTER TABLE personal_records DROP COLUMN record_type;
@@ -0,0 +1,6 @@
+-- Personal records are kept per record type and rebuilt from the working sets whenever
+-- they change. Each row is one time a record was set; is_current marks the standing one.
+-- Records for sets logged before this migration are built by "server records rebuild".
+ALTER TABLE personal_records ADD COLUMN record_type TEXT NOT NULL DEFAULT 'weight'; -- weight, e1rm, set_volume, session_volume
+ALTER TABLE personal_records ADD COLUMN is_current BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_personal_records_user_exercise ON personal_records(user_id, exercise_name, record_type, reps);
@@ -0,0 +1,18 @@
+-- Training load of each day from a user's first workout to their last, rebuilt from
+-- the changed day on whenever workouts or sets change. Days logged before this
+-- migration are built by "server load rebuild".
+CREATE TABLE IF NOT EXISTS training_load (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	date TIMESTAMPTZ NOT NULL,
+	load DOUBLE PRECISION NOT NULL DEFAULT 0, -- session RPE * minutes, summed over the day
+	sessions INTEGER NOT NULL DEFAULT 0,
+	acute_load DOUBLE PRECISION NOT NULL DEFAULT 0,
+	chronic_load DOUBLE PRECISION NOT NULL DEFAULT 0,
+	acwr DOUBLE PRECISION NOT NULL DEFAULT 0,
+	monotony DOUBLE PRECISION NOT NULL DEFAULT 0,
+	strain DOUBLE PRECISION NOT NULL DEFAULT 0,
+	fitness DOUBLE PRECISION NOT NULL DEFAULT 0,
+	fatigue DOUBLE PRECISION NOT NULL DEFAULT 0,
+	performance DOUBLE PRECISION NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, date)
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_rest_day_recommendations_user_date;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_deload_recommendations_start;
+DROP INDEX IF EXISTS idx_scheduled_workouts_deload;
+ALTER TABLE scheduled_workouts DROP COLUMN deload_id;
@@ -0,0 +1,5 @@
+-- Scheduled template workouts inside an accepted deload's window point at it, and their
+-- targets are scaled down by its reductions until the deload is ignored again
+ALTER TABLE scheduled_workouts ADD COLUMN deload_id INTEGER REFERENCES deload_recommendations(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_scheduled_workouts_deload ON scheduled_workouts(deload_id);
+CREATE INDEX IF NOT EXISTS idx_deload_recommendations_start ON deload_recommendations(user_id, recommended_start_date);
@@ -0,0 +1,16 @@
+-- Each user has at most one iCalendar feed token; regenerating it replaces the row so
+-- the old feed URL stops working. Only the token's hash is stored.
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER UNIQUE NOT NULL,
+	prefix TEXT NOT NULL,
+	token_hash TEXT UNIQUE NOT NULL,
+	last_used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Workouts imported from .ics files keep the UID of their event so importing the same
+-- file again adds nothing
+ALTER TABLE scheduled_workouts ADD COLUMN ical_uid TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_workouts_ical_uid ON scheduled_workouts(user_id, ical_uid);
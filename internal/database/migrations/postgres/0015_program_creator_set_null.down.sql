@@ -0,0 +1,3 @@
+ALTER TABLE workout_programs DROP CONSTRAINT IF EXISTS workout_programs_created_by_fkey;
+ALTER TABLE workout_programs ADD CONSTRAINT workout_programs_created_by_fkey
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE;
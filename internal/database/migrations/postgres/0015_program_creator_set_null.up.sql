@@ -0,0 +1,6 @@
+-- Keep a program when its creator's account is deleted. Public programs are used by
+-- other users, so the program loses its creator instead of being deleted with them.
+
+ALTER TABLE workout_programs DROP CONSTRAINT IF EXISTS workout_programs_created_by_fkey;
+ALTER TABLE workout_programs ADD CONSTRAINT workout_programs_created_by_fkey
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1 @@
+ALTER TABLE workout_reminders DROP COLUMN minutes_before;
@@ -0,0 +1,37 @@
+CREATE TABLE workouts_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	date DATETIME NOT NULL,
+	duration INTEGER DEFAULT 0,
+	notes TEXT DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	user_id INTEGER DEFAULT 1
+);
+
+INSERT INTO workouts_old (id, name, date, duration, notes, created_at, updated_at, user_id)
+SELECT id, name, date, duration, notes, created_at, updated_at, user_id FROM workouts;
+
+DROP TABLE workouts;
+ALTER TABLE workouts_old RENAME TO workouts;
+CREATE INDEX IF NOT EXISTS idx_workouts_user_id ON workouts(user_id);
+CREATE INDEX IF NOT EXISTS idx_workouts_date ON workouts(date);
+
+CREATE TABLE workout_programs_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	description TEXT DEFAULT '',
+	difficulty TEXT DEFAULT 'beginner',
+	duration_weeks INTEGER DEFAULT 8,
+	goal TEXT DEFAULT 'general',
+	is_public BOOLEAN DEFAULT 1,
+	created_by INTEGER DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO workout_programs_old (id, name, description, difficulty, duration_weeks, goal, is_public, created_by, created_at, updated_at)
+SELECT id, name, description, difficulty, duration_weeks, goal, is_public, created_by, created_at, updated_at FROM workout_programs;
+
+DROP TABLE workout_programs;
+ALTER TABLE workout_programs_old RENAME TO workout_programs;
@@ -0,0 +1,46 @@
+-- Give workouts and workout_programs real foreign keys to their owners so that
+-- deleting a user cascades to everything they own. Every other user_id, workout_id,
+-- exercise_id and template_id column already declares its relationship in the baseline.
+-- Migrations run with foreign keys off, so dropping the old tables cascades nowhere.
+
+CREATE TABLE workouts_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	date DATETIME NOT NULL,
+	duration INTEGER DEFAULT 0,
+	notes TEXT DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	user_id INTEGER,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO workouts_new (id, name, date, duration, notes, created_at, updated_at, user_id)
+SELECT id, name, date, duration, notes, created_at, updated_at, user_id FROM workouts;
+
+DROP TABLE workouts;
+ALTER TABLE workouts_new RENAME TO workouts;
+CREATE INDEX IF NOT EXISTS idx_workouts_user_id ON workouts(user_id);
+CREATE INDEX IF NOT EXISTS idx_workouts_date ON workouts(date);
+
+CREATE TABLE workout_programs_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	description TEXT DEFAULT '',
+	difficulty TEXT DEFAULT 'beginner',
+	duration_weeks INTEGER DEFAULT 8,
+	goal TEXT DEFAULT 'general',
+	is_public BOOLEAN DEFAULT 1,
+	created_by INTEGER,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO workout_programs_new (id, name, description, difficulty, duration_weeks, goal, is_public, created_by, created_at, updated_at)
+SELECT id, name, description, difficulty, duration_weeks, goal, is_public, created_by, created_at, updated_at FROM workout_programs;
+
+DROP TABLE workout_programs;
+ALTER TABLE workout_programs_new RENAME TO workout_programs;
+CREATE INDEX IF NOT EXISTS idx_workout_programs_created_by ON workout_programs(created_by);
+
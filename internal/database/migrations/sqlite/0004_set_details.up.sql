@@ -0,0 +1,5 @@
+-- Effort, set type and notes per set. Sets logged before this migration count as working sets.
+ALTER TABLE sets ADD COLUMN rpe REAL;
+ALTER TABLE sets ADD COLUMN rir INTEGER;
+ALTER TABLE sets ADD COLUMN set_type TEXT NOT NULL DEFAULT 'working'; -- warmup, working, drop, failure, amrap, backoff
+ALTER TABLE sets ADD COLUMN notes TEXT NOT NULL DEFAULT '';
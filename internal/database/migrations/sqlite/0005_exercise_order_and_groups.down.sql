@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_exercises_workout_order;
+ALTER TABLE template_exercises DROP COLUMN group_id;
+ALTER TABLE exercises DROP COLUMN group_id;
+ALTER TABLE exercises DROP COLUMN order_index;
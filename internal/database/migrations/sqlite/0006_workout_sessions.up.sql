@@ -0,0 +1,57 @@
+-- Workouts in progress. The server keeps the current exercise, the completed sets and the
+-- rest timer so a session started on one device can be resumed on another.
+CREATE TABLE IF NOT EXISTS workout_sessions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	template_id INTEGER,
+	name TEXT NOT NULL,
+	started_at DATETIME NOT NULL,
+	current_exercise_id INTEGER NOT NULL DEFAULT 0, -- 0 before the first exercise is added
+	rest_ends_at DATETIME,
+	finished_at DATETIME,
+	workout_id INTEGER, -- the workout the session was saved as once finished
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (template_id) REFERENCES workout_templates(id) ON DELETE SET NULL,
+	FOREIGN KEY (workout_id) REFERENCES workouts(id) ON DELETE SET NULL
+);
+
+-- A user has at most one session in progress
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workout_sessions_active ON workout_sessions(user_id) WHERE finished_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS session_exercises (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	category TEXT NOT NULL,
+	order_index INTEGER NOT NULL DEFAULT 0,
+	group_id INTEGER NOT NULL DEFAULT 0,
+	target_sets INTEGER NOT NULL DEFAULT 0,
+	target_reps INTEGER NOT NULL DEFAULT 0,
+	target_weight REAL NOT NULL DEFAULT 0,
+	rest_time INTEGER NOT NULL DEFAULT 0, -- in seconds
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (session_id) REFERENCES workout_sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_exercises_session_id ON session_exercises(session_id, order_index);
+
+CREATE TABLE IF NOT EXISTS session_sets (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_exercise_id INTEGER NOT NULL,
+	set_number INTEGER NOT NULL,
+	reps INTEGER NOT NULL DEFAULT 0,
+	weight REAL NOT NULL DEFAULT 0,
+	distance REAL NOT NULL DEFAULT 0,
+	duration INTEGER NOT NULL DEFAULT 0,
+	rest_time INTEGER NOT NULL DEFAULT 0,
+	rpe REAL,
+	rir INTEGER,
+	set_type TEXT NOT NULL DEFAULT 'working',
+	notes TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP, -- when the set was completed
+	FOREIGN KEY (session_exercise_id) REFERENCES session_exercises(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_sets_exercise_id ON session_sets(session_exercise_id);
@@ -0,0 +1 @@
+ALTER TABLE user_settings DROP COLUMN e1rm_formula;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_exercises_predefined_exercise;
+ALTER TABLE exercises DROP COLUMN predefined_exercise_id;
+DROP TABLE IF EXISTS predefined_exercise_muscles;
+DROP TABLE IF EXISTS muscle_aliases;
+DROP TABLE IF EXISTS muscles;
@@ -0,0 +1,73 @@
+-- Muscles trained by library exercises, and logged exercises linked to the library
+-- entry they were picked from, so volume can be counted per muscle.
+CREATE TABLE IF NOT EXISTS muscles (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS muscle_aliases (
+	alias TEXT PRIMARY KEY,
+	muscle_id INTEGER NOT NULL REFERENCES muscles(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS predefined_exercise_muscles (
+	predefined_exercise_id INTEGER NOT NULL REFERENCES predefined_exercises(id) ON DELETE CASCADE,
+	muscle_id INTEGER NOT NULL REFERENCES muscles(id) ON DELETE CASCADE,
+	role TEXT NOT NULL DEFAULT 'primary', -- primary, secondary
+	PRIMARY KEY (predefined_exercise_id, muscle_id)
+);
+
+ALTER TABLE exercises ADD COLUMN predefined_exercise_id INTEGER REFERENCES predefined_exercises(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_exercises_predefined_exercise ON exercises(predefined_exercise_id);
+
+INSERT INTO muscles (name) VALUES
+	('Chest'), ('Front Delts'), ('Side Delts'), ('Rear Delts'), ('Biceps'), ('Triceps'), ('Forearms'),
+	('Lats'), ('Upper Back'), ('Traps'), ('Lower Back'), ('Abs'), ('Obliques'),
+	('Glutes'), ('Quads'), ('Hamstrings'), ('Adductors'), ('Abductors'), ('Calves'), ('Hip Flexors'), ('Neck');
+
+-- Names found in free-text muscle lists, lower case, and the muscle each one means
+INSERT INTO muscle_aliases (alias, muscle_id) SELECT LOWER(name), id FROM muscles;
+WITH aliases(alias, muscle) AS (VALUES
+	('pecs', 'Chest'), ('pectorals', 'Chest'), ('pectoralis major', 'Chest'), ('upper chest', 'Chest'), ('lower chest', 'Chest'),
+	('anterior deltoids', 'Front Delts'), ('anterior deltoid', 'Front Delts'), ('front deltoids', 'Front Delts'), ('front delt', 'Front Delts'),
+	('lateral deltoids', 'Side Delts'), ('lateral deltoid', 'Side Delts'), ('medial deltoids', 'Side Delts'), ('side delt', 'Side Delts'),
+	('posterior deltoids', 'Rear Delts'), ('posterior deltoid', 'Rear Delts'), ('rear deltoids', 'Rear Delts'), ('rear delt', 'Rear Delts'),
+	('biceps brachii', 'Biceps'), ('brachialis', 'Biceps'),
+	('triceps brachii', 'Triceps'),
+	('forearm', 'Forearms'), ('brachioradialis', 'Forearms'), ('grip', 'Forearms'),
+	('latissimus dorsi', 'Lats'), ('lat', 'Lats'),
+	('rhomboids', 'Upper Back'), ('rhomboid', 'Upper Back'), ('teres major', 'Upper Back'), ('mid back', 'Upper Back'), ('middle back', 'Upper Back'),
+	('trapezius', 'Traps'), ('upper traps', 'Traps'),
+	('erector spinae', 'Lower Back'), ('erectors', 'Lower Back'), ('spinal erectors', 'Lower Back'),
+	('core', 'Abs'), ('abdominals', 'Abs'), ('rectus abdominis', 'Abs'), ('transverse abdominis', 'Abs'),
+	('oblique', 'Obliques'),
+	('gluteus maximus', 'Glutes'), ('gluteus medius', 'Glutes'), ('glute', 'Glutes'),
+	('quadriceps', 'Quads'), ('quad', 'Quads'),
+	('hamstring', 'Hamstrings'), ('hams', 'Hamstrings'),
+	('adductor', 'Adductors'), ('inner thighs', 'Adductors'),
+	('abductor', 'Abductors'), ('outer thighs', 'Abductors'),
+	('calf', 'Calves'), ('gastrocnemius', 'Calves'), ('soleus', 'Calves'),
+	('hip flexor', 'Hip Flexors'), ('iliopsoas', 'Hip Flexors')
+)
+INSERT INTO muscle_aliases (alias, muscle_id)
+SELECT aliases.alias, muscles.id FROM aliases JOIN muscles ON muscles.name = aliases.muscle;
+
+-- The first muscle of an existing free-text list is its primary muscle, the rest secondary;
+-- names without an alias are left out
+WITH RECURSIVE split(predefined_exercise_id, position, item, rest) AS (
+	SELECT id, 0, '', COALESCE(muscle_groups, '') || ',' FROM predefined_exercises
+	UNION ALL
+	SELECT predefined_exercise_id, position + 1, TRIM(SUBSTR(rest, 1, INSTR(rest, ',') - 1)), SUBSTR(rest, INSTR(rest, ',') + 1)
+	FROM split WHERE rest <> ''
+)
+INSERT OR IGNORE INTO predefined_exercise_muscles (predefined_exercise_id, muscle_id, role)
+SELECT split.predefined_exercise_id, muscle_aliases.muscle_id, CASE WHEN split.position = 1 THEN 'primary' ELSE 'secondary' END
+FROM split JOIN muscle_aliases ON muscle_aliases.alias = LOWER(split.item)
+WHERE split.position > 0
+ORDER BY split.predefined_exercise_id, split.position;
+
+-- Logged exercises are linked to the library entry of the same name
+UPDATE exercises SET predefined_exercise_id = (
+	SELECT MIN(p.id) FROM predefined_exercises p WHERE LOWER(p.name) = LOWER(exercises.name)
+);
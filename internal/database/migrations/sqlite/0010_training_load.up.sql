@@ -0,0 +1,18 @@
+-- Training load of each day from a user's first workout to their last, rebuilt from
+-- the changed day on whenever workouts or sets change. Days logged before this
+-- migration are built by "server load rebuild".
+CREATE TABLE IF NOT EXISTS training_load (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	date DATETIME NOT NULL,
+	load REAL NOT NULL DEFAULT 0, -- session RPE * minutes, summed over the day
+	sessions INTEGER NOT NULL DEFAULT 0,
+	acute_load REAL NOT NULL DEFAULT 0,
+	chronic_load REAL NOT NULL DEFAULT 0,
+	acwr REAL NOT NULL DEFAULT 0,
+	monotony REAL NOT NULL DEFAULT 0,
+	strain REAL NOT NULL DEFAULT 0,
+	fitness REAL NOT NULL DEFAULT 0,
+	fatigue REAL NOT NULL DEFAULT 0,
+	performance REAL NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, date)
+);
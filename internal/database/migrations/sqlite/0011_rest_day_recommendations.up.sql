@@ -0,0 +1,3 @@
+-- The rest day engine keeps one recommendation per user and day, refreshing it while
+-- it is still only suggested
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rest_day_recommendations_user_date ON rest_day_recommendations(user_id, recommended_date);
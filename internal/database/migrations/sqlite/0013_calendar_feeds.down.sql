@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_scheduled_workouts_ical_uid;
+ALTER TABLE scheduled_workouts DROP COLUMN ical_uid;
+DROP TABLE IF EXISTS calendar_feeds;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_workout_reminders_status;
+ALTER TABLE workout_reminders DROP COLUMN next_attempt_at;
+ALTER TABLE workout_reminders DROP COLUMN attempts;
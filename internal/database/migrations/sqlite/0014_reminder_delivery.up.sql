@@ -0,0 +1,6 @@
+-- The reminder dispatcher counts delivery attempts and waits until next_attempt_at
+-- before retrying a failed one. It also sets next_attempt_at while sending, so a
+-- reminder claimed by a server that stops is retried once the claim runs out.
+ALTER TABLE workout_reminders ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE workout_reminders ADD COLUMN next_attempt_at DATETIME;
+CREATE INDEX IF NOT EXISTS idx_workout_reminders_status ON workout_reminders(status, scheduled_for);
@@ -0,0 +1,20 @@
+CREATE TABLE workout_programs_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	description TEXT DEFAULT '',
+	difficulty TEXT DEFAULT 'beginner',
+	duration_weeks INTEGER DEFAULT 8,
+	goal TEXT DEFAULT 'general',
+	is_public BOOLEAN DEFAULT 1,
+	created_by INTEGER,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO workout_programs_old (id, name, description, difficulty, duration_weeks, goal, is_public, created_by, created_at, updated_at)
+SELECT id, name, description, difficulty, duration_weeks, goal, is_public, created_by, created_at, updated_at FROM workout_programs;
+
+DROP TABLE workout_programs;
+ALTER TABLE workout_programs_old RENAME TO workout_programs;
+CREATE INDEX IF NOT EXISTS idx_workout_programs_created_by ON workout_programs(created_by);
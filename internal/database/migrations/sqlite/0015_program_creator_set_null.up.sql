@@ -0,0 +1,24 @@
+-- Keep a program when its creator's account is deleted. Public programs are used by
+-- other users, so the program loses its creator instead of being deleted with them.
+-- Migrations run with foreign keys off, so dropping the old table cascades nowhere.
+
+CREATE TABLE workout_programs_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	description TEXT DEFAULT '',
+	difficulty TEXT DEFAULT 'beginner',
+	duration_weeks INTEGER DEFAULT 8,
+	goal TEXT DEFAULT 'general',
+	is_public BOOLEAN DEFAULT 1,
+	created_by INTEGER,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+INSERT INTO workout_programs_new (id, name, description, difficulty, duration_weeks, goal, is_public, created_by, created_at, updated_at)
+SELECT id, name, description, difficulty, duration_weeks, goal, is_public, created_by, created_at, updated_at FROM workout_programs;
+
+DROP TABLE workout_programs;
+ALTER TABLE workout_programs_new RENAME TO workout_programs;
+CREATE INDEX IF NOT EXISTS idx_workout_programs_created_by ON workout_programs(created_by);
@@ -0,0 +1,3 @@
+-- minutes_before keeps scheduled_for following the workout when it is moved or the
+-- user changes time zone; reminders stored before it have none and keep their time.
+ALTER TABLE workout_reminders ADD COLUMN minutes_before INTEGER;
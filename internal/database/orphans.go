@@ -0,0 +1,249 @@
+package database
+
+import (
+	"fmt"
+)
+
+// Orphan groups rows whose foreign key points at a parent row that no longer exists or,
+// when Unowned is set, rows whose owner column is NULL so that no user owns them
+type Orphan struct {
+	Table    string
+	Column   string
+	Parent   string
+	OnDelete string
+	Unowned  bool
+	// RowIDs are SQLite rowids, or the id column on PostgreSQL
+	RowIDs []int64
+}
+
+// foreignKey describes one foreign key constraint of a table
+type foreignKey struct {
+	column   string
+	onDelete string
+}
+
+// ownerColumn is a nullable column of a table referencing the users who own its rows
+type ownerColumn struct {
+	table    string
+	column   string
+	onDelete string
+}
+
+// ScanOrphans reports every row that violates a foreign key constraint, grouped by
+// table and column, followed by the rows without an owner. Databases written before
+// foreign keys were enforced can contain these.
+func (db *DB) ScanOrphans() ([]Orphan, error) {
+	var orphans []Orphan
+	// PostgreSQL has always enforced the constraints, so only its owners can be missing
+	if db.Driver == DriverSQLite {
+		dangling, err := db.scanDangling()
+		if err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, dangling...)
+	}
+
+	unowned, err := db.scanUnowned()
+	if err != nil {
+		return nil, err
+	}
+	return append(orphans, unowned...), nil
+}
+
+// scanDangling reports the SQLite rows whose foreign key points at a missing parent
+func (db *DB) scanDangling() ([]Orphan, error) {
+	rows, err := db.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check foreign keys: %v", err)
+	}
+	defer rows.Close()
+
+	type groupKey struct {
+		table string
+		fkID  int
+	}
+	groups := make(map[groupKey]*Orphan)
+	var order []groupKey
+
+	for rows.Next() {
+		var table, parent string
+		var rowID int64
+		var fkID int
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return nil, err
+		}
+
+		key := groupKey{table, fkID}
+		group, ok := groups[key]
+		if !ok {
+			group = &Orphan{Table: table, Parent: parent}
+			groups[key] = group
+			order = append(order, key)
+		}
+		group.RowIDs = append(group.RowIDs, rowID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	orphans := make([]Orphan, 0, len(order))
+	for _, key := range order {
+		fk, err := db.foreignKey(key.table, key.fkID)
+		if err != nil {
+			return nil, err
+		}
+		group := groups[key]
+		group.Column = fk.column
+		group.OnDelete = fk.onDelete
+		orphans = append(orphans, *group)
+	}
+
+	return orphans, nil
+}
+
+// scanUnowned reports the rows whose owner column is NULL, which no user can see or
+// delete. Columns whose key is ON DELETE SET NULL are left out: their rows outlive
+// their owner by design.
+func (db *DB) scanUnowned() ([]Orphan, error) {
+	columns, err := db.ownerColumns()
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []Orphan
+	for _, c := range columns {
+		if c.onDelete == "SET NULL" {
+			continue
+		}
+		query := fmt.Sprintf(`SELECT %s FROM "%s" WHERE "%s" IS NULL ORDER BY 1`, db.rowIDColumn(), c.table, c.column)
+		rows, err := db.Query(query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s.%s for missing owners: %v", c.table, c.column, err)
+		}
+		orphan := Orphan{Table: c.table, Column: c.column, Parent: "users", OnDelete: c.onDelete, Unowned: true}
+		for rows.Next() {
+			var rowID int64
+			if err := rows.Scan(&rowID); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			orphan.RowIDs = append(orphan.RowIDs, rowID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if len(orphan.RowIDs) > 0 {
+			orphans = append(orphans, orphan)
+		}
+	}
+	return orphans, nil
+}
+
+// ownerColumns lists the nullable columns with a foreign key to users, by table
+func (db *DB) ownerColumns() ([]ownerColumn, error) {
+	query := `
+		SELECT m.name, f."from", f.on_delete
+		FROM sqlite_master m
+		JOIN pragma_foreign_key_list(m.name) f
+		JOIN pragma_table_info(m.name) c ON c.name = f."from"
+		WHERE m.type = 'table' AND f."table" = 'users' AND c."notnull" = 0
+		ORDER BY m.name, f."from"
+	`
+	if db.Driver == DriverPostgres {
+		query = `
+			SELECT k.table_name, k.column_name, rc.delete_rule
+			FROM information_schema.referential_constraints rc
+			JOIN information_schema.key_column_usage k
+				ON k.constraint_schema = rc.constraint_schema AND k.constraint_name = rc.constraint_name
+			JOIN information_schema.constraint_column_usage p
+				ON p.constraint_schema = rc.constraint_schema AND p.constraint_name = rc.constraint_name
+			JOIN information_schema.columns c
+				ON c.table_schema = k.table_schema AND c.table_name = k.table_name AND c.column_name = k.column_name
+			WHERE k.table_schema = current_schema() AND p.table_name = 'users' AND c.is_nullable = 'YES'
+			ORDER BY k.table_name, k.column_name
+		`
+	}
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list owner columns: %v", err)
+	}
+	defer rows.Close()
+
+	var columns []ownerColumn
+	for rows.Next() {
+		var c ownerColumn
+		if err := rows.Scan(&c.table, &c.column, &c.onDelete); err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
+
+// rowIDColumn names the column identifying a row in any table: SQLite's rowid, or the
+// id column every PostgreSQL table has
+func (db *DB) rowIDColumn() string {
+	if db.Driver == DriverPostgres {
+		return "id"
+	}
+	return "rowid"
+}
+
+// foreignKey looks up a foreign key constraint by its id within a table
+func (db *DB) foreignKey(table string, id int) (foreignKey, error) {
+	var fk foreignKey
+	err := db.QueryRow(`SELECT "from", on_delete FROM pragma_foreign_key_list(?) WHERE id = ? LIMIT 1`, table, id).Scan(&fk.column, &fk.onDelete)
+	if err != nil {
+		return fk, fmt.Errorf("failed to read foreign key %d of %s: %v", id, table, err)
+	}
+	return fk, nil
+}
+
+// RepairOrphans resolves dangling rows the way their constraint would have:
+// rows with ON DELETE SET NULL lose the reference, all others are deleted, as are
+// rows without an owner. Deleting an orphan cascades to its own children. It returns
+// what was repaired.
+func (db *DB) RepairOrphans() ([]Orphan, error) {
+	var repaired []Orphan
+
+	// Each pass can expose new orphans below a deleted row when a child table lacks a cascade
+	for pass := 0; pass < 10; pass++ {
+		orphans, err := db.ScanOrphans()
+		if err != nil {
+			return repaired, err
+		}
+		if len(orphans) == 0 {
+			return repaired, nil
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return repaired, err
+		}
+
+		for _, orphan := range orphans {
+			query := fmt.Sprintf(`DELETE FROM "%s" WHERE %s = ?`, orphan.Table, db.rowIDColumn())
+			if orphan.OnDelete == "SET NULL" {
+				query = fmt.Sprintf(`UPDATE "%s" SET "%s" = NULL WHERE rowid = ?`, orphan.Table, orphan.Column)
+			}
+			query = db.Rebind(query)
+
+			for _, rowID := range orphan.RowIDs {
+				if _, err := tx.Exec(query, rowID); err != nil {
+					tx.Rollback()
+					return repaired, fmt.Errorf("failed to repair %s.%s row %d: %v", orphan.Table, orphan.Column, rowID, err)
+				}
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return repaired, fmt.Errorf("failed to commit orphan repair: %v", err)
+		}
+		repaired = append(repaired, orphans...)
+	}
+
+	return repaired, fmt.Errorf("orphaned rows remain after repeated repair passes")
+}
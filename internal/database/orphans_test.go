@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestScanAndRepairOrphans(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	// Write the rows a database from before foreign keys were enforced can hold
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("failed to get a connection: %v", err)
+	}
+	exec := func(query string, args ...interface{}) int64 {
+		t.Helper()
+		result, err := conn.ExecContext(ctx, query, args...)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		id, _ := result.LastInsertId()
+		return id
+	}
+	exec(`PRAGMA foreign_keys = OFF`)
+	alice := exec(`INSERT INTO users (username, email, password_hash) VALUES ('alice', 'alice@example.com', 'x')`)
+	kept := exec(`INSERT INTO workouts (name, date, user_id) VALUES ('Kept', '2026-10-12', ?)`, alice)
+	dangling := exec(`INSERT INTO workouts (name, date, user_id) VALUES ('Dangling', '2026-10-12', 999)`)
+	unowned := exec(`INSERT INTO workouts (name, date, user_id) VALUES ('Unowned', '2026-10-12', NULL)`)
+	exec(`INSERT INTO exercises (workout_id, name, category) VALUES (?, 'Squat', 'Legs')`, dangling)
+	exec(`INSERT INTO exercises (workout_id, name, category) VALUES (?, 'Squat', 'Legs')`, unowned)
+	exec(`INSERT INTO exercises (workout_id, name, category) VALUES (?, 'Squat', 'Legs')`, kept)
+	program := exec(`INSERT INTO workout_programs (name, created_by) VALUES ('Dangling creator', 999)`)
+	exec(`INSERT INTO workout_programs (name, created_by) VALUES ('No creator', NULL)`)
+	exec(`PRAGMA foreign_keys = ON`)
+	conn.Close()
+
+	report := func(orphans []Orphan) string {
+		var s string
+		for _, o := range orphans {
+			s += fmt.Sprintf("%s.%s->%s %s unowned=%v %v; ", o.Table, o.Column, o.Parent, o.OnDelete, o.Unowned, o.RowIDs)
+		}
+		return s
+	}
+	want := fmt.Sprintf("workouts.user_id->users CASCADE unowned=false [%d]; workout_programs.created_by->users SET NULL unowned=false [%d]; workouts.user_id->users CASCADE unowned=true [%d]; ",
+		dangling, program, unowned)
+	orphans, err := db.ScanOrphans()
+	if err != nil {
+		t.Fatalf("ScanOrphans: %v", err)
+	}
+	if got := report(orphans); got != want {
+		t.Errorf("ScanOrphans = %s\nwant %s", got, want)
+	}
+
+	repaired, err := db.RepairOrphans()
+	if err != nil {
+		t.Fatalf("RepairOrphans: %v", err)
+	}
+	if got := report(repaired); got != want {
+		t.Errorf("RepairOrphans = %s\nwant %s", got, want)
+	}
+
+	count := func(query string) int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow(query).Scan(&n); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		return n
+	}
+	// The orphaned workouts are deleted with their exercises; the program loses its creator
+	if n := count(`SELECT COUNT(*) FROM workouts`); n != 1 {
+		t.Errorf("%d workouts after the repair, want alice's", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM exercises`); n != 1 {
+		t.Errorf("%d exercises after the repair, want alice's", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM workout_programs WHERE created_by IS NULL`); n != 2 {
+		t.Errorf("%d programs without a creator, want 2", n)
+	}
+	if orphans, err := db.ScanOrphans(); err != nil || len(orphans) != 0 {
+		t.Errorf("ScanOrphans after the repair = %s, %v; want none", report(orphans), err)
+	}
+}
@@ -0,0 +1,108 @@
+// Package e1rm estimates one-rep maxes from sets of several reps.
+//
+// Every formula is fitted to low rep ranges and overshoots or undershoots badly past
+// about a dozen reps, so sets beyond MaxReps are not estimated at all. A set rated with
+// RPE or RIR counts the reps left in reserve, so 100 kg for 3 at RPE 8 is estimated like
+// 100 kg for 5 taken to failure.
+package e1rm
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Formula names an estimated 1RM formula
+type Formula string
+
+// Supported formulas
+const (
+	Epley    Formula = "epley"
+	Brzycki  Formula = "brzycki"
+	Lombardi Formula = "lombardi"
+	Mayhew   Formula = "mayhew"
+	Wathan   Formula = "wathan"
+)
+
+// Default is the formula used when a user has not chosen one
+const Default = Epley
+
+// MaxReps is the most reps, including reps in reserve, a set may have to be estimated
+const MaxReps = 12
+
+// Formulas returns every supported formula
+func Formulas() []Formula {
+	return []Formula{Epley, Brzycki, Lombardi, Mayhew, Wathan}
+}
+
+// ParseFormula returns the formula with the given name; an empty name is the default
+func ParseFormula(name string) (Formula, error) {
+	if name == "" {
+		return Default, nil
+	}
+	f := Formula(strings.ToLower(strings.TrimSpace(name)))
+	if !f.Valid() {
+		return "", fmt.Errorf("unknown e1RM formula %q", name)
+	}
+	return f, nil
+}
+
+// Valid reports whether f is a supported formula
+func (f Formula) Valid() bool {
+	for _, known := range Formulas() {
+		if f == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Estimate returns the estimated 1RM of a set of reps taken to failure. ok is false
+// when the set cannot be estimated: no weight, no reps or more than MaxReps.
+func (f Formula) Estimate(weight float64, reps int) (estimate float64, ok bool) {
+	return f.estimate(weight, float64(reps))
+}
+
+// EstimateSet returns the estimated 1RM of a set, counting the reps left in reserve.
+// rir is used when given, otherwise rpe as 10 - RPE reps in reserve; with neither the
+// set is taken to have been to failure.
+func (f Formula) EstimateSet(weight float64, reps int, rpe *float64, rir *int) (estimate float64, ok bool) {
+	if reps < 1 {
+		return 0, false
+	}
+	reserve := 0.0
+	switch {
+	case rir != nil:
+		reserve = float64(*rir)
+	case rpe != nil:
+		reserve = 10 - *rpe
+	}
+	if reserve < 0 {
+		reserve = 0
+	}
+	return f.estimate(weight, float64(reps)+reserve)
+}
+
+// estimate applies the formula to reps that may include a fractional reserve
+func (f Formula) estimate(weight, reps float64) (float64, bool) {
+	if weight <= 0 || reps < 1 || reps > MaxReps {
+		return 0, false
+	}
+	// A true single is the 1RM; the formulas only approximate it
+	if reps == 1 {
+		return weight, true
+	}
+
+	switch f {
+	case Brzycki:
+		return weight * 36 / (37 - reps), true
+	case Lombardi:
+		return weight * math.Pow(reps, 0.10), true
+	case Mayhew:
+		return 100 * weight / (52.2 + 41.9*math.Exp(-0.055*reps)), true
+	case Wathan:
+		return 100 * weight / (48.8 + 53.8*math.Exp(-0.075*reps)), true
+	default: // Epley, also used for an unknown formula
+		return weight * (1 + reps/30), true
+	}
+}
@@ -0,0 +1,95 @@
+package e1rm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateReferenceValues(t *testing.T) {
+	tests := []struct {
+		formula Formula
+		weight  float64
+		reps    int
+		want    float64
+	}{
+		{Epley, 100, 5, 116.667},
+		{Epley, 100, 10, 133.333},
+		{Brzycki, 100, 5, 112.5},
+		{Brzycki, 100, 10, 133.333},
+		{Lombardi, 100, 5, 117.462},
+		{Lombardi, 100, 10, 125.893},
+		{Mayhew, 100, 5, 119.011},
+		{Mayhew, 100, 10, 130.934},
+		{Wathan, 100, 5, 116.583},
+		{Wathan, 100, 10, 134.747},
+		{Epley, 225, 3, 247.5},
+		{Brzycki, 315, 2, 324},
+	}
+	for _, tt := range tests {
+		got, ok := tt.formula.Estimate(tt.weight, tt.reps)
+		if !ok || math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("%s(%v x %d) = %.3f, %v; want %.3f", tt.formula, tt.weight, tt.reps, got, ok, tt.want)
+		}
+	}
+}
+
+func TestEstimateLimits(t *testing.T) {
+	for _, f := range Formulas() {
+		if got, ok := f.Estimate(140, 1); !ok || got != 140 {
+			t.Errorf("%s single = %v, %v; want the weight itself", f, got, ok)
+		}
+		if _, ok := f.Estimate(100, MaxReps); !ok {
+			t.Errorf("%s did not estimate a set of %d", f, MaxReps)
+		}
+		for _, set := range [][2]float64{{100, MaxReps + 1}, {100, 0}, {0, 5}, {-20, 5}} {
+			if got, ok := f.Estimate(set[0], int(set[1])); ok {
+				t.Errorf("%s(%v x %v) = %v, want no estimate", f, set[0], set[1], got)
+			}
+		}
+	}
+}
+
+func TestEstimateSetCountsRepsInReserve(t *testing.T) {
+	rpe := func(v float64) *float64 { return &v }
+	rir := func(v int) *int { return &v }
+	toFailure, _ := Epley.Estimate(100, 5)
+
+	tests := []struct {
+		name string
+		reps int
+		rpe  *float64
+		rir  *int
+		want float64
+		ok   bool
+	}{
+		{"no rating is taken to failure", 5, nil, nil, toFailure, true},
+		{"RPE 8 leaves two reps", 3, rpe(8), nil, toFailure, true},
+		{"RIR 2 leaves two reps", 3, nil, rir(2), toFailure, true},
+		{"RIR wins over RPE", 3, rpe(6), rir(2), toFailure, true},
+		{"half RPE", 4, rpe(9.5), nil, 100 * (1 + 4.5/30), true},
+		{"RPE 10 single is the weight", 1, rpe(10), nil, 100, true},
+		{"RPE 8 single is estimated", 1, rpe(8), nil, 110, true},
+		{"reserve past the rep cap", 10, rpe(7), nil, 0, false},
+		{"no reps", 0, rpe(8), nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Epley.EstimateSet(100, tt.reps, tt.rpe, tt.rir)
+			if ok != tt.ok || math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("EstimateSet = %.3f, %v; want %.3f, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseFormula(t *testing.T) {
+	tests := map[string]Formula{"": Default, "epley": Epley, "Brzycki": Brzycki, " wathan ": Wathan}
+	for name, want := range tests {
+		if got, err := ParseFormula(name); err != nil || got != want {
+			t.Errorf("ParseFormula(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseFormula("oconner"); err == nil {
+		t.Error("ParseFormula accepted an unknown formula")
+	}
+}
@@ -0,0 +1,209 @@
+// Package events is an in-process publish/subscribe bus for changes to a user's
+// records. Handlers publish after every successful write; the server-sent event
+// stream subscribes per user and replays recent events to reconnecting clients.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types are "<resource>.<action>"
+const (
+	WorkoutCreated = "workout.created"
+	WorkoutUpdated = "workout.updated"
+	WorkoutDeleted = "workout.deleted"
+
+	ExerciseCreated    = "exercise.created"
+	ExerciseUpdated    = "exercise.updated"
+	ExerciseDeleted    = "exercise.deleted"
+	ExercisesReordered = "exercises.reordered"
+
+	SetCreated = "set.created"
+	SetUpdated = "set.updated"
+	SetDeleted = "set.deleted"
+
+	MealCreated            = "meal.created"
+	BodyWeightCreated      = "body_weight.created"
+	BodyFatCreated         = "body_fat.created"
+	BodyMeasurementCreated = "body_measurement.created"
+
+	SessionStarted  = "session.started"
+	SessionUpdated  = "session.updated"
+	SessionFinished = "session.finished"
+	SessionDeleted  = "session.deleted"
+
+	RestDaySuggested = "rest_day.suggested"
+	RestDayUpdated   = "rest_day.updated"
+
+	DeloadSuggested = "deload.suggested"
+	DeloadUpdated   = "deload.updated"
+
+	ScheduledWorkoutCreated = "scheduled_workout.created"
+	ScheduledWorkoutUpdated = "scheduled_workout.updated"
+	ScheduledWorkoutDeleted = "scheduled_workout.deleted"
+
+	CalendarEventCreated = "calendar_event.created"
+	CalendarEventUpdated = "calendar_event.updated"
+	CalendarEventDeleted = "calendar_event.deleted"
+
+	ReminderCreated = "reminder.created"
+	ReminderDeleted = "reminder.deleted"
+)
+
+// DefaultHistorySize is how many recent events a bus keeps for replay
+const DefaultHistorySize = 1024
+
+// subscriberBuffer is how many events a subscriber can fall behind before it is dropped
+const subscriberBuffer = 64
+
+// Event is a change to one of a user's records
+type Event struct {
+	ID     int64       `json:"id"`
+	UserID int         `json:"-"`
+	Type   string      `json:"type"`
+	Data   interface{} `json:"data"` // the record after the change, or its ID for deletes
+	Time   time.Time   `json:"time"`
+}
+
+// Deleted is the data of a delete event
+type Deleted struct {
+	ID int `json:"id"`
+}
+
+// Bus fans published events out to the subscribers of the event's user and keeps
+// the most recent ones so reconnecting subscribers can catch up
+type Bus struct {
+	mu          sync.Mutex
+	lastID      int64
+	history     []Event // ring buffer of the last historySize events
+	next        int     // position of the next write in history
+	historySize int
+	subscribers map[int]map[*Subscription]struct{}
+	now         func() time.Time
+}
+
+// NewBus returns a bus that keeps historySize events for replay and stamps events
+// with the given clock
+func NewBus(historySize int, now func() time.Time) *Bus {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return &Bus{
+		// IDs continue from the creation time, so an ID issued before a restart is
+		// never mistaken for one of this bus's events
+		lastID:      now().UnixMicro(),
+		historySize: historySize,
+		subscribers: make(map[int]map[*Subscription]struct{}),
+		now:         now,
+	}
+}
+
+// Subscription receives a user's events until it is closed. Events stops when the
+// subscriber falls too far behind; it should reconnect with the last ID it saw.
+type Subscription struct {
+	bus    *Bus
+	userID int
+	events chan Event
+	closed bool
+}
+
+// Events returns the channel events are delivered on
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops delivery and releases the subscription
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
+
+// Publish records an event for a user and delivers it to the user's subscribers
+func (b *Bus) Publish(userID int, eventType string, data interface{}) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := Event{ID: b.lastID, UserID: userID, Type: eventType, Data: data, Time: b.now()}
+
+	if len(b.history) < b.historySize {
+		b.history = append(b.history, event)
+	} else {
+		b.history[b.next] = event
+	}
+	b.next = (b.next + 1) % b.historySize
+
+	for sub := range b.subscribers[userID] {
+		select {
+		case sub.events <- event:
+		default:
+			// A subscriber that stopped reading would hold up everyone else
+			b.remove(sub)
+		}
+	}
+	return event
+}
+
+// Subscribe registers a subscriber for a user's events. With a non-zero lastEventID it
+// also returns the user's events published after that one. complete is false when some
+// of those events are no longer kept, or lastEventID was issued before a restart, in
+// which case the client should reload its data instead of relying on the replay.
+func (b *Bus) Subscribe(userID int, lastEventID int64) (sub *Subscription, replay []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{bus: b, userID: userID, events: make(chan Event, subscriberBuffer)}
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	b.subscribers[userID][sub] = struct{}{}
+
+	if lastEventID == 0 {
+		return sub, nil, true
+	}
+
+	complete = lastEventID <= b.lastID && lastEventID >= b.oldestID()-1
+	for i := 0; i < len(b.history); i++ {
+		event := b.history[(b.next+i)%len(b.history)]
+		if event.ID > lastEventID && event.UserID == userID {
+			replay = append(replay, event)
+		}
+	}
+	return sub, replay, complete
+}
+
+// LastID returns the ID of the most recent event
+func (b *Bus) LastID() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// oldestID returns the ID of the oldest event kept, or the next ID when none are
+func (b *Bus) oldestID() int64 {
+	if len(b.history) < b.historySize {
+		if len(b.history) == 0 {
+			return b.lastID + 1
+		}
+		return b.history[0].ID
+	}
+	return b.history[b.next].ID
+}
+
+// remove unregisters a subscriber and closes its channel; the caller holds b.mu
+func (b *Bus) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.events)
+	delete(b.subscribers[sub.userID], sub)
+	if len(b.subscribers[sub.userID]) == 0 {
+		delete(b.subscribers, sub.userID)
+	}
+}
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestBus(historySize int) *Bus {
+	return NewBus(historySize, func() time.Time { return time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC) })
+}
+
+func TestPublishReachesOnlyTheUsersSubscribers(t *testing.T) {
+	bus := newTestBus(8)
+	alice, _, _ := bus.Subscribe(1, 0)
+	defer alice.Close()
+	bob, _, _ := bus.Subscribe(2, 0)
+	defer bob.Close()
+
+	published := bus.Publish(1, WorkoutCreated, Deleted{ID: 7})
+
+	select {
+	case event := <-alice.Events():
+		if event.ID != published.ID || event.Type != WorkoutCreated {
+			t.Errorf("alice got %+v, want %+v", event, published)
+		}
+	default:
+		t.Fatal("alice got no event")
+	}
+	select {
+	case event := <-bob.Events():
+		t.Errorf("bob got alice's event %+v", event)
+	default:
+	}
+}
+
+func TestSubscribeReplaysMissedEvents(t *testing.T) {
+	bus := newTestBus(8)
+	first := bus.Publish(1, WorkoutCreated, nil)
+	bus.Publish(2, MealCreated, nil)
+	second := bus.Publish(1, SetCreated, nil)
+	third := bus.Publish(1, SetUpdated, nil)
+
+	sub, replay, complete := bus.Subscribe(1, first.ID)
+	defer sub.Close()
+
+	if !complete {
+		t.Error("replay of kept events is incomplete")
+	}
+	if len(replay) != 2 || replay[0].ID != second.ID || replay[1].ID != third.ID {
+		t.Errorf("replay = %+v, want events %d and %d", replay, second.ID, third.ID)
+	}
+}
+
+func TestSubscribeReportsLostEvents(t *testing.T) {
+	bus := newTestBus(2)
+	first := bus.Publish(1, WorkoutCreated, nil)
+	bus.Publish(1, SetCreated, nil)
+	bus.Publish(1, SetCreated, nil)
+	last := bus.Publish(1, SetCreated, nil)
+
+	tests := []struct {
+		name        string
+		lastEventID int64
+		complete    bool
+	}{
+		{"up to date", last.ID, true},
+		{"oldest kept event seen", last.ID - 2, true},
+		{"events dropped from history", first.ID, false},
+		{"ID from before a restart", first.ID - 1000, false},
+		{"ID from the future", last.ID + 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, _, complete := bus.Subscribe(1, tt.lastEventID)
+			defer sub.Close()
+			if complete != tt.complete {
+				t.Errorf("complete = %v, want %v", complete, tt.complete)
+			}
+		})
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	bus := newTestBus(8)
+	slow, _, _ := bus.Subscribe(1, 0)
+	defer slow.Close()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		bus.Publish(1, SetCreated, nil)
+	}
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("received %d events before the channel closed, want %d", received, subscriberBuffer)
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"workout-tracker/internal/database"
+	"workout-tracker/internal/models"
+)
+
+// newTestHandler returns a handler backed by a fresh SQLite database
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DATABASE_PATH", filepath.Join(t.TempDir(), "test.db"))
+	db, err := database.Initialize()
+	if err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return New(db, WithTemplates(os.DirFS("../../web/templates")))
+}
+
+// seedUser creates a user with the given username and returns its ID
+func seedUser(t *testing.T, h *Handler, username string) int {
+	t.Helper()
+
+	id, err := h.storage.Users().Create(models.User{Username: username, Email: username + "@example.com", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user %s: %v", username, err)
+	}
+	return id
+}
+
+// seedSession logs a workout with a single exercise and the given sets for a user
+func seedSession(t *testing.T, h *Handler, userID int, daysAgo int, exercise, category string, sets [][2]float64) {
+	t.Helper()
+
+	workoutID, err := h.storage.Workouts().Create(models.Workout{
+		Name:     "Session",
+		Date:     time.Now().AddDate(0, 0, -daysAgo),
+		Duration: 60,
+	}, userID)
+	if err != nil {
+		t.Fatalf("failed to create workout: %v", err)
+	}
+
+	exerciseID, err := h.storage.Exercises().Create(models.Exercise{WorkoutID: workoutID, Name: exercise, Category: category})
+	if err != nil {
+		t.Fatalf("failed to create exercise: %v", err)
+	}
+
+	for i, s := range sets {
+		_, err := h.storage.Exercises().CreateSet(models.Set{ExerciseID: exerciseID, SetNumber: i + 1, Reps: int(s[0]), Weight: s[1]})
+		if err != nil {
+			t.Fatalf("failed to create set: %v", err)
+		}
+	}
+}
+
+// analyticsSnapshot captures every per-user analytics result as JSON for comparison
+func analyticsSnapshot(t *testing.T, h *Handler, userID int) map[string]string {
+	t.Helper()
+
+	now := time.Now()
+	year, week := now.ISOWeek()
+	start := now.AddDate(0, 0, -29).Format("2006-01-02")
+	end := now.Format("2006-01-02")
+
+	results := map[string]func() (interface{}, error){
+		"analytics":      func() (interface{}, error) { return h.getAnalyticsData(userID, 30) },
+		"weekly":         func() (interface{}, error) { return h.getWeeklySummary(userID, year, week) },
+		"monthly":        func() (interface{}, error) { return h.getMonthlySummary(userID, now.Year(), int(now.Month())) },
+		"progress_chart": func() (interface{}, error) { return h.getExerciseProgressChart(userID, "Bench Press", start, end) },
+		"stats":          func() (interface{}, error) { return h.getWorkoutStats(userID) },
+		"top_exercises":  func() (interface{}, error) { return h.getTopExercisesForPeriod(userID, start, end, 5) },
+		"prs":            func() (interface{}, error) { return h.countPRsForPeriod(userID, start, end) },
+		"categories":     func() (interface{}, error) { return h.getCategoryBreakdownForPeriod(userID, start, end) },
+	}
+
+	snapshot := make(map[string]string)
+	for name, fn := range results {
+		value, err := fn()
+		if err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("failed to marshal %s: %v", name, err)
+		}
+		snapshot[name] = string(data)
+	}
+	return snapshot
+}
+
+func TestAnalyticsUnaffectedByOtherUsers(t *testing.T) {
+	h := newTestHandler(t)
+
+	alice := seedUser(t, h, "alice")
+	bob := seedUser(t, h, "bob")
+
+	seedSession(t, h, alice, 3, "Bench Press", "Chest", [][2]float64{{5, 80}, {5, 85}})
+	seedSession(t, h, alice, 2, "Squat", "Legs", [][2]float64{{5, 100}})
+
+	before := analyticsSnapshot(t, h, alice)
+
+	// Bob logs heavier sessions on the same exercises and days
+	seedSession(t, h, bob, 3, "Bench Press", "Chest", [][2]float64{{3, 140}, {3, 145}})
+	seedSession(t, h, bob, 2, "Deadlift", "Back", [][2]float64{{1, 220}})
+	seedSession(t, h, bob, 1, "Bench Press", "Chest", [][2]float64{{8, 120}})
+
+	after := analyticsSnapshot(t, h, alice)
+
+	if !reflect.DeepEqual(before, after) {
+		for name := range before {
+			if before[name] != after[name] {
+				t.Errorf("%s changed after another user logged a session:\nbefore: %s\nafter:  %s", name, before[name], after[name])
+			}
+		}
+	}
+}
+
+func TestAnalyticsOnlyCountsOwnWorkouts(t *testing.T) {
+	h := newTestHandler(t)
+
+	alice := seedUser(t, h, "alice")
+	bob := seedUser(t, h, "bob")
+
+	seedSession(t, h, alice, 3, "Bench Press", "Chest", [][2]float64{{5, 80}})
+	seedSession(t, h, bob, 3, "Deadlift", "Back", [][2]float64{{1, 220}})
+	seedSession(t, h, bob, 2, "Deadlift", "Back", [][2]float64{{1, 225}})
+
+	analytics, err := h.getAnalyticsData(alice, 30)
+	if err != nil {
+		t.Fatalf("getAnalyticsData failed: %v", err)
+	}
+	if analytics.TotalWorkouts != 1 {
+		t.Errorf("expected 1 workout for alice, got %d", analytics.TotalWorkouts)
+	}
+
+	analytics, err = h.getAnalyticsData(bob, 30)
+	if err != nil {
+		t.Fatalf("getAnalyticsData failed: %v", err)
+	}
+	if analytics.TotalWorkouts != 2 {
+		t.Errorf("expected 2 workouts for bob, got %d", analytics.TotalWorkouts)
+	}
+
+	analytics, err = h.getAnalyticsData(alice, 30)
+	if err != nil {
+		t.Fatalf("getAnalyticsData failed: %v", err)
+	}
+	for _, pr := range analytics.PersonalRecords {
+		if pr.ExerciseName == "Deadlift" {
+			t.Errorf("alice's personal records include bob's deadlift")
+		}
+	}
+	if analytics.MaxWeight != 80 {
+		t.Errorf("expected alice's max weight to be 80, got %v", analytics.MaxWeight)
+	}
+}
+
+func TestWarmupSetsExcludedFromAnalytics(t *testing.T) {
+	h := newTestHandler(t)
+
+	alice := seedUser(t, h, "alice")
+	seedSession(t, h, alice, 3, "Bench Press", "Chest", [][2]float64{{5, 80}, {5, 85}})
+
+	before := analyticsSnapshot(t, h, alice)
+
+	// A heavy warmup would otherwise set a PR and add volume
+	_, err := h.storage.Workouts().Create(models.Workout{
+		Name: "Warmup only",
+		Date: time.Now().AddDate(0, 0, -1),
+		Exercises: []models.Exercise{{
+			Name:     "Bench Press",
+			Category: "Chest",
+			Sets:     []models.Set{{SetNumber: 1, Reps: 3, Weight: 120, SetType: models.SetTypeWarmup}},
+		}},
+	}, alice)
+	if err != nil {
+		t.Fatalf("failed to create workout: %v", err)
+	}
+
+	after := analyticsSnapshot(t, h, alice)
+	for _, name := range []string{"progress_chart", "prs"} {
+		if before[name] != after[name] {
+			t.Errorf("%s changed after a warmup set:\nbefore: %s\nafter:  %s", name, before[name], after[name])
+		}
+	}
+
+	analytics, err := h.getAnalyticsData(alice, 30)
+	if err != nil {
+		t.Fatalf("getAnalyticsData failed: %v", err)
+	}
+	if analytics.MaxWeight != 85 {
+		t.Errorf("expected max weight 85 without the warmup, got %v", analytics.MaxWeight)
+	}
+	if analytics.TotalVolume != 5*80+5*85 {
+		t.Errorf("expected volume %v without the warmup, got %v", 5*80+5*85, analytics.TotalVolume)
+	}
+	for _, pr := range analytics.PersonalRecords {
+		if pr.Weight == 120 {
+			t.Errorf("warmup set counted as a personal record: %+v", pr)
+		}
+	}
+}
+
+func TestPersonalRecordsUseChosenFormula(t *testing.T) {
+	h := newTestHandler(t)
+
+	alice := seedUser(t, h, "alice")
+	// The 20-rep set would estimate highest but is past the rep cap
+	seedSession(t, h, alice, 3, "Bench Press", "Chest", [][2]float64{{3, 100}, {5, 100}, {20, 80}})
+
+	check := func(formula string, want float64) {
+		t.Helper()
+		if err := h.updateUserSettings(alice, models.UpdateSettingsRequest{Theme: "light", E1RMFormula: formula}); err != nil {
+			t.Fatalf("failed to update settings: %v", err)
+		}
+
+		records, err := h.getPersonalRecords(alice, "2000-01-01", "2100-01-01")
+		if err != nil {
+			t.Fatalf("getPersonalRecords failed: %v", err)
+		}
+		if len(records) != 1 || records[0].Weight != 100 || records[0].Reps != 5 || math.Abs(records[0].OneRepMax-want) > 0.001 {
+			t.Fatalf("%s records = %+v, want 100 x 5 with an estimated 1RM of %v", formula, records, want)
+		}
+
+		start := time.Now().AddDate(0, 0, -29).Format("2006-01-02")
+		end := time.Now().Format("2006-01-02")
+		chart, err := h.getExerciseProgressChart(alice, "Bench Press", start, end)
+		if err != nil {
+			t.Fatalf("getExerciseProgressChart failed: %v", err)
+		}
+		if len(chart.DataPoints) != 1 || math.Abs(chart.DataPoints[0].OneRepMax-want) > 0.001 {
+			t.Errorf("%s progress chart = %+v, want an estimated 1RM of %v", formula, chart.DataPoints, want)
+		}
+	}
+
+	// Reading the settings creates the defaults, which use Epley
+	h.getUserSettings(alice)
+	check("", 100*(1+5/30.0))
+	check("brzycki", 112.5)
+	// An empty formula keeps the chosen one
+	check("", 112.5)
+}
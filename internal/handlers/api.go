@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/validation"
+)
+
+// APIv1Middleware authenticates /api/v1 requests and guarantees that every error
+// leaves the server in the standard JSON error envelope
+func (h *Handler) APIv1Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authedRequest, status, message := h.authenticateRequest(r)
+		if status != 0 {
+			if status == http.StatusUnauthorized {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="workout-tracker"`)
+			}
+			writeAPIError(w, status, errorCodeForStatus(status), message, nil)
+			return
+		}
+
+		ew := &apiErrorWriter{ResponseWriter: w}
+		next.ServeHTTP(ew, authedRequest)
+		ew.finish()
+	})
+}
+
+// APINotFound returns a 404 envelope for unknown /api/v1 routes
+func (h *Handler) APINotFound(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusNotFound, "not_found", "No such API route", nil)
+}
+
+// APIMethodNotAllowed returns a 405 envelope for known /api/v1 routes called with the wrong method
+func (h *Handler) APIMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed", nil)
+}
+
+// writeAPIError writes an error in the {"error":{"code","message","details"}} envelope
+func writeAPIError(w http.ResponseWriter, status int, code, message string, details interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.APIErrorResponse{
+		Error: models.APIError{Code: code, Message: message, Details: details},
+	})
+}
+
+// errorCodeForStatus maps an HTTP status to the machine-readable error code clients switch on
+func errorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "bad_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusUnprocessableEntity:
+		return "validation_failed"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	}
+	if status >= 500 {
+		return "internal_error"
+	}
+	return "error"
+}
+
+// validateRequest checks a decoded request against its validate tags.
+// On failure it writes a 422 envelope listing every invalid field and returns false.
+func validateRequest(w http.ResponseWriter, req interface{}) bool {
+	errs := validation.Struct(req)
+	if len(errs) == 0 {
+		return true
+	}
+	writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", "Request validation failed", errs)
+	return false
+}
+
+// validateSubmission checks a request read from either a JSON body or a submitted form.
+// JSON requests get validateRequest's envelope; forms get a plain-text 422 with one line
+// per invalid field, like the other errors browsers are shown.
+func validateSubmission(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if r.Header.Get("Content-Type") == "application/json" {
+		return validateRequest(w, req)
+	}
+	errs := validation.Struct(req)
+	if len(errs) == 0 {
+		return true
+	}
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Message
+	}
+	http.Error(w, strings.Join(messages, "\n"), http.StatusUnprocessableEntity)
+	return false
+}
+
+// apiErrorWriter captures plain-text error responses written by shared handlers
+// and rewrites them into the JSON error envelope once the handler returns
+type apiErrorWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	intercepted bool
+	body        bytes.Buffer
+}
+
+// WriteHeader holds back non-JSON error statuses so they can be re-encoded
+func (ew *apiErrorWriter) WriteHeader(status int) {
+	if ew.wroteHeader {
+		return
+	}
+	ew.wroteHeader = true
+	ew.status = status
+
+	if status >= 400 && !strings.HasPrefix(ew.Header().Get("Content-Type"), "application/json") {
+		ew.intercepted = true
+		return
+	}
+	ew.ResponseWriter.WriteHeader(status)
+}
+
+// Write buffers intercepted error bodies and passes everything else through
+func (ew *apiErrorWriter) Write(b []byte) (int, error) {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	if ew.intercepted {
+		return ew.body.Write(b)
+	}
+	return ew.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes through so streamed responses reach the client as they are written
+func (ew *apiErrorWriter) Flush() {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	if ew.intercepted {
+		return
+	}
+	if f, ok := ew.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish emits the envelope for an intercepted error response
+func (ew *apiErrorWriter) finish() {
+	if !ew.intercepted {
+		return
+	}
+	ew.Header().Del("Content-Length")
+	writeAPIError(ew.ResponseWriter, ew.status, errorCodeForStatus(ew.status), strings.TrimSpace(ew.body.String()), nil)
+}
+
+// idempotencyKeyTTL is how long a stored response can be replayed
+const idempotencyKeyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header
+const maxIdempotencyKeyLength = 255
+
+// Idempotent makes a create endpoint safe to retry. When the request carries an
+// Idempotency-Key header the first response is stored per user, later requests with
+// the same key and body replay it, and the same key with a different body gets a 422.
+func (h *Handler) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeAPIError(w, http.StatusBadRequest, "bad_request", "Idempotency-Key must be at most 255 characters", nil)
+			return
+		}
+
+		userID, err := h.getCurrentUserID(r)
+		if err != nil {
+			http.Error(w, "User not authenticated", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := hashIdempotentRequest(r, body)
+
+		// An expired key is free again even if the purge has not removed it yet
+		if err := h.deleteExpiredIdempotencyKey(userID, key, h.now().Add(-idempotencyKeyTTL)); err != nil {
+			log.Printf("Failed to delete expired idempotency key: %v", err)
+		}
+
+		reserved, err := h.reserveIdempotencyKey(userID, key, requestHash)
+		if err != nil {
+			log.Printf("Failed to reserve idempotency key: %v", err)
+			http.Error(w, "Failed to process request", http.StatusInternalServerError)
+			return
+		}
+
+		if !reserved {
+			rec, err := h.getIdempotencyRecord(userID, key)
+			if err != nil {
+				log.Printf("Failed to load idempotency key: %v", err)
+				http.Error(w, "Failed to process request", http.StatusInternalServerError)
+				return
+			}
+			if rec.RequestHash != requestHash {
+				writeAPIError(w, http.StatusUnprocessableEntity, "idempotency_key_reused",
+					"Idempotency-Key was already used with a different request", nil)
+				return
+			}
+			if rec.StatusCode == 0 {
+				writeAPIError(w, http.StatusConflict, "conflict",
+					"A request with this Idempotency-Key is still being processed", nil)
+				return
+			}
+
+			// Replay the original response
+			if rec.ContentType != "" {
+				w.Header().Set("Content-Type", rec.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(rec.StatusCode)
+			w.Write(rec.ResponseBody)
+			return
+		}
+
+		iw := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(iw, r)
+
+		// Server errors are not stored so the client can retry with the same key
+		if iw.status >= 500 {
+			if err := h.releaseIdempotencyKey(userID, key); err != nil {
+				log.Printf("Failed to release idempotency key: %v", err)
+			}
+			return
+		}
+		if err := h.completeIdempotencyKey(userID, key, iw.status, w.Header().Get("Content-Type"), iw.body.Bytes()); err != nil {
+			log.Printf("Failed to store idempotent response: %v", err)
+		}
+	})
+}
+
+// PurgeIdempotencyKeys deletes the keys of every user older than idempotencyKeyTTL
+// every interval until the context is cancelled
+func (h *Handler) PurgeIdempotencyKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := h.deleteExpiredIdempotencyKeys(h.now().Add(-idempotencyKeyTTL)); err != nil {
+			log.Printf("Failed to delete expired idempotency keys: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// hashIdempotentRequest fingerprints the method, path and body of a request
+func hashIdempotentRequest(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// idempotencyRecorder passes a response through while keeping a copy of it
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+// WriteHeader records the status code
+func (iw *idempotencyRecorder) WriteHeader(status int) {
+	if iw.wroteHeader {
+		return
+	}
+	iw.wroteHeader = true
+	iw.status = status
+	iw.ResponseWriter.WriteHeader(status)
+}
+
+// Write copies the body before passing it on
+func (iw *idempotencyRecorder) Write(b []byte) (int, error) {
+	if !iw.wroteHeader {
+		iw.WriteHeader(http.StatusOK)
+	}
+	iw.body.Write(b)
+	return iw.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes through to the client
+func (iw *idempotencyRecorder) Flush() {
+	if !iw.wroteHeader {
+		iw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := iw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,540 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"workout-tracker/internal/events"
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/recurrence"
+	"workout-tracker/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// calendarListPast and calendarListAhead are the default period of the calendar event
+	// list around today
+	calendarListPast  = 30
+	calendarListAhead = 60
+	// maxCalendarListDays bounds the period of the list, as recurring events are expanded
+	// into an occurrence for each of its days
+	maxCalendarListDays = 366
+	// defaultEventColor is the display color of events created without one
+	defaultEventColor = "#3788d8"
+)
+
+// GetCalendarEvents lists the occurrences of the current user's calendar events from 30
+// days ago to 60 days ahead, or the period of at most 366 days the from and to query
+// parameters pick in YYYY-MM-DD format. Each occurrence of a recurring event carries its
+// recurrence_id.
+func (h *Handler) GetCalendarEvents(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	from, to, err := h.parseListPeriod(r, calendarListPast, calendarListAhead)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from) >= maxCalendarListDays*24*time.Hour {
+		http.Error(w, fmt.Sprintf("the period may span at most %d days", maxCalendarListDays), http.StatusBadRequest)
+		return
+	}
+
+	occurrences, err := h.calendarEvents(userID, from, to)
+	if err != nil {
+		log.Printf("Failed to get calendar events: %v", err)
+		http.Error(w, "Failed to load calendar events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(occurrences)
+}
+
+// CreateCalendarEvent adds an event, or a series of them when it has a recurrence rule,
+// to the current user's calendar
+func (h *Handler) CreateCalendarEvent(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CalendarEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	event := models.WorkoutCalendarEvent{UserID: userID}
+	if !h.applyCalendarEventRequest(w, &event, req) {
+		return
+	}
+
+	id, err := h.storage.CalendarEvents().Create(event)
+	if err != nil {
+		log.Printf("Failed to create calendar event: %v", err)
+		http.Error(w, "Failed to create calendar event", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeCalendarEvent(w, r, http.StatusCreated, events.CalendarEventCreated, id, userID)
+}
+
+// GetCalendarEvent returns a calendar event, or the series of a recurring one
+func (h *Handler) GetCalendarEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.loadCalendarEvent(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
+
+// UpdateCalendarEvent replaces a calendar event, or every occurrence of a recurring one
+func (h *Handler) UpdateCalendarEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.loadCalendarEvent(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CalendarEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	if !h.applyCalendarEventRequest(w, &event, req) {
+		return
+	}
+	if !h.saveCalendarEvent(w, event) {
+		return
+	}
+
+	h.writeCalendarEvent(w, r, http.StatusOK, events.CalendarEventUpdated, event.ID, event.UserID)
+}
+
+// DeleteCalendarEvent removes a calendar event, or every occurrence of a recurring one
+func (h *Handler) DeleteCalendarEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.loadCalendarEvent(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.CalendarEvents().Delete(event.ID, event.UserID); err != nil {
+		http.Error(w, "Calendar event not found", http.StatusNotFound)
+		return
+	}
+	h.publish(r, events.CalendarEventDeleted, events.Deleted{ID: event.ID})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateCalendarOccurrence changes the occurrence of a recurring event on the day in the
+// URL. With the scope query parameter "this", the default, the occurrence is left out of
+// the series and replaced by a single event. With "future" the series ends before it and
+// a new series replaces it and the occurrences after it; without a recurrence rule of its
+// own the new series repeats like the old one.
+func (h *Handler) UpdateCalendarOccurrence(w http.ResponseWriter, r *http.Request) {
+	series, rule, pattern, occurrence, scope, ok := h.loadCalendarOccurrence(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CalendarEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+	if scope == models.OccurrenceScopeThis && req.RRule != "" {
+		http.Error(w, "A single occurrence cannot have a recurrence rule", http.StatusBadRequest)
+		return
+	}
+
+	replacement := models.WorkoutCalendarEvent{UserID: series.UserID}
+	if !h.applyCalendarEventRequest(w, &replacement, req) {
+		return
+	}
+
+	if scope == models.OccurrenceScopeThis {
+		pattern.ExDates = append(pattern.ExDates, occurrence.Format("2006-01-02"))
+		setRecurrence(&series, rule, pattern)
+	} else {
+		before, after, count := rule.Split(series.StartDate.In(occurrence.Location()), occurrence)
+		if req.RRule == "" {
+			// The new series keeps the old one's rule and exceptions from the occurrence on
+			var later []string
+			for _, day := range pattern.ExDates {
+				if day >= occurrence.Format("2006-01-02") {
+					later = append(later, day)
+				}
+			}
+			setRecurrence(&replacement, after, models.RecurrencePattern{ExDates: later})
+		}
+		if count == 0 {
+			// Nothing comes before the first occurrence; the whole series is replaced
+			replacement.ID = series.ID
+			if !h.saveCalendarEvent(w, replacement) {
+				return
+			}
+			h.writeCalendarEvent(w, r, http.StatusOK, events.CalendarEventUpdated, series.ID, series.UserID)
+			return
+		}
+		setRecurrence(&series, before, pattern)
+	}
+
+	id, err := h.storage.CalendarEvents().Split(series, replacement)
+	if err != nil {
+		log.Printf("Failed to split calendar event: %v", err)
+		http.Error(w, "Failed to update calendar event", http.StatusInternalServerError)
+		return
+	}
+	if updated, err := h.storage.CalendarEvents().Get(series.ID, series.UserID); err == nil {
+		h.publish(r, events.CalendarEventUpdated, updated)
+	}
+
+	h.writeCalendarEvent(w, r, http.StatusCreated, events.CalendarEventCreated, id, series.UserID)
+}
+
+// DeleteCalendarOccurrence removes the occurrence of a recurring event on the day in the
+// URL or, with the scope query parameter "future", it and every occurrence after it
+func (h *Handler) DeleteCalendarOccurrence(w http.ResponseWriter, r *http.Request) {
+	series, rule, pattern, occurrence, scope, ok := h.loadCalendarOccurrence(w, r)
+	if !ok {
+		return
+	}
+
+	if scope == models.OccurrenceScopeThis {
+		pattern.ExDates = append(pattern.ExDates, occurrence.Format("2006-01-02"))
+		setRecurrence(&series, rule, pattern)
+	} else {
+		before, _, count := rule.Split(series.StartDate.In(occurrence.Location()), occurrence)
+		if count == 0 {
+			if err := h.storage.CalendarEvents().Delete(series.ID, series.UserID); err != nil {
+				http.Error(w, "Calendar event not found", http.StatusNotFound)
+				return
+			}
+			h.publish(r, events.CalendarEventDeleted, events.Deleted{ID: series.ID})
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		setRecurrence(&series, before, pattern)
+	}
+
+	if !h.saveCalendarEvent(w, series) {
+		return
+	}
+	if updated, err := h.storage.CalendarEvents().Get(series.ID, series.UserID); err == nil {
+		h.publish(r, events.CalendarEventUpdated, updated)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// calendarEvents returns the occurrences of a user's events from one date to another,
+// in the order they start. Recurring events repeat in the user's time zone.
+func (h *Handler) calendarEvents(userID int, from, to time.Time) ([]models.WorkoutCalendarEvent, error) {
+	stored, err := h.storage.CalendarEvents().List(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	zone, err := h.userZone(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := []models.WorkoutCalendarEvent{}
+	for _, event := range stored {
+		if !event.IsRecurring {
+			occurrences = append(occurrences, event)
+			continue
+		}
+		rule, pattern, err := parseRecurrence(event)
+		if err != nil {
+			log.Printf("Skipping calendar event %d: %v", event.ID, err)
+			continue
+		}
+		start := seriesStart(event, zone)
+		first, end := dayIn(from, start.Location()), dayIn(to, start.Location()).AddDate(0, 0, 1).Add(-time.Nanosecond)
+		for _, start := range rule.Between(start, first, end, exceptionDates(pattern)) {
+			occurrences = append(occurrences, occurrenceOf(event, start))
+		}
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool { return occurrences[i].StartDate.Before(occurrences[j].StartDate) })
+	return occurrences, nil
+}
+
+// occurrenceOf returns the occurrence of a recurring event starting at the given time,
+// in UTC like the times of single events
+func occurrenceOf(event models.WorkoutCalendarEvent, start time.Time) models.WorkoutCalendarEvent {
+	occurrence := event
+	start = start.UTC()
+	if event.EndDate != nil {
+		end := start.Add(event.EndDate.Sub(event.StartDate))
+		occurrence.EndDate = &end
+	}
+	occurrence.StartDate = start
+	occurrence.RecurrenceID = &start
+	return occurrence
+}
+
+// applyCalendarEventRequest fills in an event from a request, writing a 400 or 404 if
+// the request does not hold up
+func (h *Handler) applyCalendarEventRequest(w http.ResponseWriter, event *models.WorkoutCalendarEvent, req models.CalendarEventRequest) bool {
+	event.EventType, event.Title, event.Description, event.Color = req.EventType, req.Title, req.Description, req.Color
+	if event.EventType == "" {
+		event.EventType = models.CalendarEventWorkout
+	}
+	if event.Color == "" {
+		event.Color = defaultEventColor
+	}
+
+	start, allDay, err := parseEventTime(req.StartDate)
+	if err != nil {
+		http.Error(w, "start_date "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	event.StartDate, event.AllDay, event.EndDate = start, allDay, nil
+	if req.EndDate != "" {
+		end, _, err := parseEventTime(req.EndDate)
+		if err != nil {
+			http.Error(w, "end_date "+err.Error(), http.StatusBadRequest)
+			return false
+		}
+		if end.Before(start) {
+			http.Error(w, "end_date must not be before start_date", http.StatusBadRequest)
+			return false
+		}
+		event.EndDate = &end
+	}
+
+	event.ScheduledWorkoutID = nil
+	if req.ScheduledWorkoutID != nil {
+		if _, err := h.storage.ScheduledWorkouts().Get(*req.ScheduledWorkoutID, event.UserID); err != nil {
+			http.Error(w, "Scheduled workout not found", http.StatusNotFound)
+			return false
+		}
+		event.ScheduledWorkoutID = req.ScheduledWorkoutID
+	}
+
+	event.IsRecurring, event.RecurrencePattern = false, nil
+	if req.RRule == "" {
+		if len(req.ExceptionDates) > 0 {
+			http.Error(w, "exception_dates need a recurrence rule", http.StatusBadRequest)
+			return false
+		}
+		return true
+	}
+	rule, err := recurrence.Parse(req.RRule)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	for _, day := range req.ExceptionDates {
+		if _, err := time.Parse("2006-01-02", day); err != nil {
+			http.Error(w, "exception_dates must be dates in YYYY-MM-DD format", http.StatusBadRequest)
+			return false
+		}
+	}
+	setRecurrence(event, rule, models.RecurrencePattern{ExDates: req.ExceptionDates})
+	return true
+}
+
+// parseEventTime reads a date in YYYY-MM-DD format, which makes an all-day event, or a time in RFC 3339 format
+func parseEventTime(value string) (time.Time, bool, error) {
+	if day, err := time.Parse("2006-01-02", value); err == nil {
+		return day, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, false, fmt.Errorf("must be a date in YYYY-MM-DD or a time in RFC 3339 format")
+	}
+	return t.UTC(), false, nil
+}
+
+// setRecurrence stores a rule and its exceptions on an event, making it recurring
+func setRecurrence(event *models.WorkoutCalendarEvent, rule recurrence.Rule, pattern models.RecurrencePattern) {
+	pattern.RRule = rule.String()
+	sort.Strings(pattern.ExDates)
+	encoded, _ := json.Marshal(pattern)
+	value := string(encoded)
+	event.IsRecurring, event.RecurrencePattern = true, &value
+}
+
+// parseRecurrence reads the rule and exceptions of a recurring event
+func parseRecurrence(event models.WorkoutCalendarEvent) (recurrence.Rule, models.RecurrencePattern, error) {
+	var pattern models.RecurrencePattern
+	if event.RecurrencePattern == nil {
+		return recurrence.Rule{}, pattern, fmt.Errorf("recurring event has no recurrence pattern")
+	}
+	if err := json.Unmarshal([]byte(*event.RecurrencePattern), &pattern); err != nil {
+		return recurrence.Rule{}, pattern, fmt.Errorf("invalid recurrence pattern: %v", err)
+	}
+	rule, err := recurrence.Parse(pattern.RRule)
+	return rule, pattern, err
+}
+
+// seriesStart is the start of a recurring event in the zone its occurrences repeat in:
+// the user's for an event at a time, so it keeps its clock time and weekdays there, and
+// UTC for an all-day event, whose days are stored as dates
+func seriesStart(event models.WorkoutCalendarEvent, zone *time.Location) time.Time {
+	if event.AllDay {
+		return event.StartDate
+	}
+	return event.StartDate.In(zone)
+}
+
+// dayIn returns the start of a date's day in a zone
+func dayIn(date time.Time, zone *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, zone)
+}
+
+// userZone returns the user's time zone, UTC if they have not set a known one
+func (h *Handler) userZone(userID int) (*time.Location, error) {
+	settings, err := h.getUserSettings(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %v", err)
+	}
+	zone, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return zone, nil
+}
+
+// exceptionDates returns the excepted days of a pattern, ignoring any that are not dates
+func exceptionDates(pattern models.RecurrencePattern) []time.Time {
+	var days []time.Time
+	for _, value := range pattern.ExDates {
+		if day, err := time.Parse("2006-01-02", value); err == nil {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// loadCalendarEvent loads the current user's calendar event named in the URL, writing a 404 if there is none
+func (h *Handler) loadCalendarEvent(w http.ResponseWriter, r *http.Request) (models.WorkoutCalendarEvent, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid calendar event ID", http.StatusBadRequest)
+		return models.WorkoutCalendarEvent{}, false
+	}
+
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return models.WorkoutCalendarEvent{}, false
+	}
+
+	event, err := h.storage.CalendarEvents().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Calendar event not found", http.StatusNotFound)
+		return models.WorkoutCalendarEvent{}, false
+	}
+	return event, true
+}
+
+// loadCalendarOccurrence loads the recurring event named in the URL, its rule and the
+// start of its occurrence on the URL's day in the zone the series repeats in, and reads
+// the scope query parameter. It writes a 400 or 404 if any of them does not hold up.
+func (h *Handler) loadCalendarOccurrence(w http.ResponseWriter, r *http.Request) (series models.WorkoutCalendarEvent, rule recurrence.Rule,
+	pattern models.RecurrencePattern, occurrence time.Time, scope string, ok bool) {
+	series, ok = h.loadCalendarEvent(w, r)
+	if !ok {
+		return
+	}
+	ok = false
+
+	switch scope = r.URL.Query().Get("scope"); scope {
+	case "":
+		scope = models.OccurrenceScopeThis
+	case models.OccurrenceScopeThis, models.OccurrenceScopeFuture:
+	default:
+		http.Error(w, "scope must be one of: this, future", http.StatusBadRequest)
+		return
+	}
+
+	day, err := time.Parse("2006-01-02", mux.Vars(r)["date"])
+	if err != nil {
+		http.Error(w, "The occurrence must be a date in YYYY-MM-DD format", http.StatusBadRequest)
+		return
+	}
+	if !series.IsRecurring {
+		http.Error(w, "Calendar event is not recurring", http.StatusBadRequest)
+		return
+	}
+	rule, pattern, err = parseRecurrence(series)
+	if err != nil {
+		log.Printf("Failed to read recurrence of calendar event %d: %v", series.ID, err)
+		http.Error(w, "Failed to load calendar event", http.StatusInternalServerError)
+		return
+	}
+
+	zone, err := h.userZone(series.UserID)
+	if err != nil {
+		log.Printf("Failed to load time zone: %v", err)
+		http.Error(w, "Failed to load calendar event", http.StatusInternalServerError)
+		return
+	}
+	start := seriesStart(series, zone)
+	day = dayIn(day, start.Location())
+	occurrences := rule.Between(start, day, day.AddDate(0, 0, 1).Add(-time.Nanosecond), exceptionDates(pattern))
+	if len(occurrences) == 0 {
+		http.Error(w, "Occurrence not found", http.StatusNotFound)
+		return
+	}
+	return series, rule, pattern, occurrences[0], scope, true
+}
+
+// saveCalendarEvent updates a calendar event, writing an error if that fails
+func (h *Handler) saveCalendarEvent(w http.ResponseWriter, event models.WorkoutCalendarEvent) bool {
+	err := h.storage.CalendarEvents().Update(event)
+	if err == storage.ErrNotFound {
+		http.Error(w, "Calendar event not found", http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to update calendar event: %v", err)
+		http.Error(w, "Failed to update calendar event", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// writeCalendarEvent reloads a calendar event, publishes it and writes it with the given status
+func (h *Handler) writeCalendarEvent(w http.ResponseWriter, r *http.Request, status int, eventType string, id, userID int) {
+	event, err := h.storage.CalendarEvents().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Calendar event saved but failed to retrieve", http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, eventType, event)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(event)
+}
@@ -5,285 +5,14 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"sort"
 	"time"
 
+	"workout-tracker/internal/database"
+	"workout-tracker/internal/e1rm"
 	"workout-tracker/internal/models"
 )
 
-// getRecentWorkouts returns the most recent workouts for a user
-func (h *Handler) getRecentWorkouts(limit int) ([]models.Workout, error) {
-	// This function needs a user ID parameter - will be updated in next step
-	// For now, return empty slice to avoid breaking existing code
-	return []models.Workout{}, nil
-}
-
-// getRecentWorkoutsByUser returns the most recent workouts for a specific user
-func (h *Handler) getRecentWorkoutsByUser(userID, limit int) ([]models.Workout, error) {
-	query := `
-		SELECT id, user_id, name, date, duration, notes, created_at, updated_at
-		FROM workouts
-		WHERE user_id = ?
-		ORDER BY date DESC, created_at DESC
-		LIMIT ?
-	`
-	
-	rows, err := h.db.Query(query, userID, limit)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var workouts []models.Workout
-	for rows.Next() {
-		var w models.Workout
-		err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Date, &w.Duration, &w.Notes, &w.CreatedAt, &w.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		workouts = append(workouts, w)
-	}
-
-	return workouts, nil
-}
-
-// getAllWorkouts returns all workouts (deprecated - use getAllWorkoutsByUser)
-func (h *Handler) getAllWorkouts() ([]models.Workout, error) {
-	query := `
-		SELECT id, name, date, duration, notes, created_at, updated_at
-		FROM workouts
-		ORDER BY date DESC, created_at DESC
-	`
-	
-	rows, err := h.db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var workouts []models.Workout
-	for rows.Next() {
-		var w models.Workout
-		err := rows.Scan(&w.ID, &w.Name, &w.Date, &w.Duration, &w.Notes, &w.CreatedAt, &w.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		workouts = append(workouts, w)
-	}
-
-	return workouts, nil
-}
-
-// getAllWorkoutsByUser returns all workouts for a specific user
-func (h *Handler) getAllWorkoutsByUser(userID int) ([]models.Workout, error) {
-	query := `
-		SELECT id, user_id, name, date, duration, notes, created_at, updated_at
-		FROM workouts
-		WHERE user_id = ?
-		ORDER BY date DESC, created_at DESC
-	`
-	
-	rows, err := h.db.Query(query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var workouts []models.Workout
-	for rows.Next() {
-		var w models.Workout
-		err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Date, &w.Duration, &w.Notes, &w.CreatedAt, &w.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		workouts = append(workouts, w)
-	}
-
-	return workouts, nil
-}
-
-// getWorkoutByID returns a workout by ID with its exercises and sets (deprecated - use getWorkoutByIDWithUser)
-func (h *Handler) getWorkoutByID(id int) (models.Workout, error) {
-	var w models.Workout
-	
-	// Get workout
-	query := `
-		SELECT id, name, date, duration, notes, created_at, updated_at
-		FROM workouts
-		WHERE id = ?
-	`
-	
-	err := h.db.QueryRow(query, id).Scan(&w.ID, &w.Name, &w.Date, &w.Duration, &w.Notes, &w.CreatedAt, &w.UpdatedAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return w, fmt.Errorf("workout not found")
-		}
-		return w, err
-	}
-
-	// Get exercises for this workout
-	exercises, err := h.getExercisesByWorkoutID(id)
-	if err != nil {
-		return w, err
-	}
-	w.Exercises = exercises
-
-	return w, nil
-}
-
-// getWorkoutByIDWithUser returns a workout by ID with user validation
-func (h *Handler) getWorkoutByIDWithUser(workoutID, userID int) (models.Workout, error) {
-	var w models.Workout
-	
-	// Get workout with user validation
-	query := `
-		SELECT id, user_id, name, date, duration, notes, created_at, updated_at
-		FROM workouts
-		WHERE id = ? AND user_id = ?
-	`
-	
-	err := h.db.QueryRow(query, workoutID, userID).Scan(&w.ID, &w.UserID, &w.Name, &w.Date, &w.Duration, &w.Notes, &w.CreatedAt, &w.UpdatedAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return w, fmt.Errorf("workout not found or access denied")
-		}
-		return w, err
-	}
-
-	// Get exercises for this workout
-	exercises, err := h.getExercisesByWorkoutID(workoutID)
-	if err != nil {
-		return w, err
-	}
-	w.Exercises = exercises
-
-	return w, nil
-}
-
-// getExercisesByWorkoutID returns exercises for a workout
-func (h *Handler) getExercisesByWorkoutID(workoutID int) ([]models.Exercise, error) {
-	query := `
-		SELECT id, workout_id, name, category, created_at, updated_at
-		FROM exercises
-		WHERE workout_id = ?
-		ORDER BY created_at ASC
-	`
-	
-	rows, err := h.db.Query(query, workoutID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var exercises []models.Exercise
-	for rows.Next() {
-		var e models.Exercise
-		err := rows.Scan(&e.ID, &e.WorkoutID, &e.Name, &e.Category, &e.CreatedAt, &e.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		
-		// Get sets for this exercise
-		sets, err := h.getSetsByExerciseID(e.ID)
-		if err != nil {
-			return nil, err
-		}
-		e.Sets = sets
-		
-		exercises = append(exercises, e)
-	}
-
-	return exercises, nil
-}
-
-// getSetsByExerciseID returns sets for an exercise
-func (h *Handler) getSetsByExerciseID(exerciseID int) ([]models.Set, error) {
-	query := `
-		SELECT id, exercise_id, set_number, reps, weight, distance, duration, rest_time, created_at, updated_at
-		FROM sets
-		WHERE exercise_id = ?
-		ORDER BY set_number ASC
-	`
-	
-	rows, err := h.db.Query(query, exerciseID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var sets []models.Set
-	for rows.Next() {
-		var s models.Set
-		err := rows.Scan(&s.ID, &s.ExerciseID, &s.SetNumber, &s.Reps, &s.Weight, &s.Distance, &s.Duration, &s.RestTime, &s.CreatedAt, &s.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		sets = append(sets, s)
-	}
-
-	return sets, nil
-}
-
-// createWorkout creates a new workout and returns its ID (deprecated - use createWorkoutWithUser)
-func (h *Handler) createWorkout(workout models.Workout) (int, error) {
-	// This function is deprecated and should not include user_id for backward compatibility
-	query := `
-		INSERT INTO workouts (name, date, duration, notes, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`
-	
-	result, err := h.db.Exec(query, workout.Name, workout.Date, workout.Duration, workout.Notes, time.Now(), time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(id), nil
-}
-
-// createWorkoutForUser creates a new workout with user association and returns its ID
-func (h *Handler) createWorkoutForUser(workout models.Workout, userID int) (int, error) {
-	query := `
-		INSERT INTO workouts (user_id, name, date, duration, notes, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`
-	
-	result, err := h.db.Exec(query, userID, workout.Name, workout.Date, workout.Duration, workout.Notes, time.Now(), time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(id), nil
-}
-
-// createExercise creates a new exercise and returns its ID
-func (h *Handler) createExercise(exercise models.Exercise) (int, error) {
-	query := `
-		INSERT INTO exercises (workout_id, name, category, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)
-	`
-	
-	result, err := h.db.Exec(query, exercise.WorkoutID, exercise.Name, exercise.Category, time.Now(), time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(id), nil
-}
-
 // getAllPredefinedExercises returns all predefined exercises
 func (h *Handler) getAllPredefinedExercises() ([]models.PredefinedExercise, error) {
 	query := `
@@ -307,8 +36,11 @@ func (h *Handler) getAllPredefinedExercises() ([]models.PredefinedExercise, erro
 		}
 		exercises = append(exercises, e)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return exercises, nil
+	return exercises, h.attachMuscles(exercises)
 }
 
 // getPredefinedExercisesByCategory returns predefined exercises by category
@@ -335,114 +67,39 @@ func (h *Handler) getPredefinedExercisesByCategory(category string) ([]models.Pr
 		}
 		exercises = append(exercises, e)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return exercises, nil
+	return exercises, h.attachMuscles(exercises)
 }
 
-// createPredefinedExercise creates a new predefined exercise and returns its ID
+// createPredefinedExercise creates a new predefined exercise, links it to the muscles
+// it lists and returns its ID
 func (h *Handler) createPredefinedExercise(exercise models.PredefinedExercise) (int, error) {
 	query := `
 		INSERT INTO predefined_exercises (name, category, description, video_url, instructions, tips, muscle_groups, equipment, difficulty, image_url, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	
-	result, err := h.db.Exec(query, exercise.Name, exercise.Category, exercise.Description, exercise.VideoURL, exercise.Instructions, exercise.Tips, exercise.MuscleGroups, exercise.Equipment, exercise.Difficulty, exercise.ImageURL, time.Now(), time.Now())
+	id, err := h.db.Insert(query, exercise.Name, exercise.Category, exercise.Description, exercise.VideoURL, exercise.Instructions, exercise.Tips, exercise.MuscleGroups, exercise.Equipment, exercise.Difficulty, exercise.ImageURL, h.now(), h.now())
 	if err != nil {
 		return 0, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
+	if err := h.storage.Muscles().LinkExercise(id); err != nil {
+		return 0, fmt.Errorf("failed to link muscles: %v", err)
 	}
-
-	return int(id), nil
-}
-
-// createSet creates a new set and returns its ID
-func (h *Handler) createSet(set models.Set) (int, error) {
-	query := `
-		INSERT INTO sets (exercise_id, set_number, reps, weight, distance, duration, rest_time, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-	
-	result, err := h.db.Exec(query, set.ExerciseID, set.SetNumber, set.Reps, set.Weight, set.Distance, set.Duration, set.RestTime, time.Now(), time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(id), nil
-}
-
-// deleteWorkout deletes a workout and all associated exercises and sets
-func (h *Handler) deleteWorkout(id int) error {
-	query := `DELETE FROM workouts WHERE id = ?`
-	_, err := h.db.Exec(query, id)
-	return err
-}
-
-// updateWorkout updates an existing workout
-func (h *Handler) updateWorkout(workout models.Workout) error {
-	query := `
-		UPDATE workouts 
-		SET name = ?, date = ?, duration = ?, notes = ?, updated_at = ?
-		WHERE id = ?
-	`
-	
-	_, err := h.db.Exec(query, workout.Name, workout.Date, workout.Duration, workout.Notes, time.Now(), workout.ID)
-	return err
-}
-
-// deleteExercise deletes an exercise and all associated sets
-func (h *Handler) deleteExercise(id int) error {
-	query := `DELETE FROM exercises WHERE id = ?`
-	_, err := h.db.Exec(query, id)
-	return err
-}
-
-// updateExercise updates an existing exercise
-func (h *Handler) updateExercise(exercise models.Exercise) error {
-	query := `
-		UPDATE exercises 
-		SET name = ?, category = ?, updated_at = ?
-		WHERE id = ?
-	`
-	
-	_, err := h.db.Exec(query, exercise.Name, exercise.Category, time.Now(), exercise.ID)
-	return err
-}
-
-// updateSet updates an existing set
-func (h *Handler) updateSet(set models.Set) error {
-	query := `
-		UPDATE sets 
-		SET set_number = ?, reps = ?, weight = ?, distance = ?, duration = ?, rest_time = ?, updated_at = ?
-		WHERE id = ?
-	`
-	
-	_, err := h.db.Exec(query, set.SetNumber, set.Reps, set.Weight, set.Distance, set.Duration, set.RestTime, time.Now(), set.ID)
-	return err
-}
-
-// deleteSet deletes a specific set
-func (h *Handler) deleteSet(id int) error {
-	query := `DELETE FROM sets WHERE id = ?`
-	_, err := h.db.Exec(query, id)
-	return err
+	return id, nil
 }
 
-// getWorkoutStats returns basic statistics about workouts
-func (h *Handler) getWorkoutStats() (map[string]interface{}, error) {
+// getWorkoutStats returns basic statistics about a user's workouts
+func (h *Handler) getWorkoutStats(userID int) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
 	
 	// Total workouts
 	var totalWorkouts int
-	err := h.db.QueryRow("SELECT COUNT(*) FROM workouts").Scan(&totalWorkouts)
+	err := h.db.QueryRow("SELECT COUNT(*) FROM workouts WHERE user_id = ?", userID).Scan(&totalWorkouts)
 	if err != nil {
 		return nil, err
 	}
@@ -452,8 +109,8 @@ func (h *Handler) getWorkoutStats() (map[string]interface{}, error) {
 	var thisWeekWorkouts int
 	err = h.db.QueryRow(`
 		SELECT COUNT(*) FROM workouts 
-		WHERE date >= date('now', '-7 days')
-	`).Scan(&thisWeekWorkouts)
+		WHERE user_id = ? AND date >= ?
+	`, userID, h.now().AddDate(0, 0, -7).Format("2006-01-02")).Scan(&thisWeekWorkouts)
 	if err != nil {
 		return nil, err
 	}
@@ -463,8 +120,8 @@ func (h *Handler) getWorkoutStats() (map[string]interface{}, error) {
 	var avgDuration sql.NullFloat64
 	err = h.db.QueryRow(`
 		SELECT AVG(duration) FROM workouts 
-		WHERE duration > 0
-	`).Scan(&avgDuration)
+		WHERE user_id = ? AND duration > 0
+	`, userID).Scan(&avgDuration)
 	if err != nil {
 		return nil, err
 	}
@@ -482,114 +139,63 @@ func (h *Handler) getAnalyticsData(userID int, days int) (*models.AnalyticsData,
 	analytics := &models.AnalyticsData{}
 
 	// Calculate date range
-	startDate := time.Now().AddDate(0, 0, -days+1).Format("2006-01-02")
-	endDate := time.Now().Format("2006-01-02")
+	startDate := h.now().AddDate(0, 0, -days+1).Format("2006-01-02")
+	endDate := h.now().Format("2006-01-02")
 
 	// Generate date labels
 	dates := make([]string, 0, days)
 	for i := 0; i < days; i++ {
-		date := time.Now().AddDate(0, 0, -days+1+i)
+		date := h.now().AddDate(0, 0, -days+1+i)
 		dates = append(dates, date.Format("01/02"))
 	}
 	analytics.Dates = dates
 
-	// Get basic stats
-	var totalWorkouts int
-	h.db.QueryRow("SELECT COUNT(*) FROM workouts WHERE date >= ? AND date <= ?", startDate, endDate).Scan(&totalWorkouts)
-	analytics.TotalWorkouts = totalWorkouts
+	from, to := h.now().AddDate(0, 0, -days+1), h.now()
 
-	// Get workout frequency by day
-	workoutCounts := make([]int, days)
-	rows, err := h.db.Query(`
-		SELECT DATE(date) as workout_date, COUNT(*) as count 
-		FROM workouts 
-		WHERE date >= ? AND date <= ?
-		GROUP BY DATE(date)
-		ORDER BY workout_date
-	`, startDate, endDate)
-	if err == nil {
-		defer rows.Close()
-		workoutMap := make(map[string]int)
-		for rows.Next() {
-			var date string
-			var count int
-			rows.Scan(&date, &count)
-			workoutMap[date] = count
-		}
-		// Fill in the workout counts for each day
-		for i := 0; i < days; i++ {
-			date := time.Now().AddDate(0, 0, -days+1+i).Format("2006-01-02")
-			workoutCounts[i] = workoutMap[date]
-		}
+	// Get workout frequency and durations by day
+	workoutDays, err := h.storage.Analytics().WorkoutDays(userID, from, to)
+	if err != nil {
+		return nil, err
 	}
-	analytics.WorkoutCounts = workoutCounts
-
-	// Get workout durations by day
+	workoutsByDate := make(map[string]models.WorkoutDay)
+	for _, day := range workoutDays {
+		workoutsByDate[day.Date.Format("2006-01-02")] = day
+		analytics.TotalWorkouts += day.Workouts
+	}
+	workoutCounts := make([]int, days)
 	durations := make([]int, days)
-	rows, err = h.db.Query(`
-		SELECT DATE(date) as workout_date, AVG(duration) as avg_duration 
-		FROM workouts 
-		WHERE date >= ? AND date <= ? AND duration > 0
-		GROUP BY DATE(date)
-		ORDER BY workout_date
-	`, startDate, endDate)
-	if err == nil {
-		defer rows.Close()
-		durationMap := make(map[string]int)
-		for rows.Next() {
-			var date string
-			var avgDuration sql.NullFloat64
-			rows.Scan(&date, &avgDuration)
-			if avgDuration.Valid {
-				durationMap[date] = int(avgDuration.Float64)
-			}
-		}
-		// Fill in the durations for each day
-		for i := 0; i < days; i++ {
-			date := time.Now().AddDate(0, 0, -days+1+i).Format("2006-01-02")
-			durations[i] = durationMap[date]
-		}
+	for i := 0; i < days; i++ {
+		day := workoutsByDate[from.AddDate(0, 0, i).Format("2006-01-02")]
+		workoutCounts[i] = day.Workouts
+		durations[i] = int(day.AvgDuration)
 	}
+	analytics.WorkoutCounts = workoutCounts
 	analytics.Durations = durations
 
 	// Get daily calories (if user has meal data)
+	calorieDays, err := h.storage.Analytics().CalorieDays(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	caloriesByDate := make(map[string]int)
+	totalCaloriesSum := 0
+	for _, day := range calorieDays {
+		caloriesByDate[day.Date.Format("2006-01-02")] = day.Calories
+		totalCaloriesSum += day.Calories
+	}
 	dailyCalories := make([]int, days)
-	rows, err = h.db.Query(`
-		SELECT DATE(date) as meal_date, SUM(calories) as total_calories 
-		FROM meals 
-		WHERE user_id = ? AND date >= ? AND date <= ?
-		GROUP BY DATE(date)
-		ORDER BY meal_date
-	`, userID, startDate, endDate)
-	if err == nil {
-		defer rows.Close()
-		caloriesMap := make(map[string]int)
-		totalCaloriesSum := 0
-		daysWithData := 0
-		for rows.Next() {
-			var date string
-			var calories int
-			rows.Scan(&date, &calories)
-			caloriesMap[date] = calories
-			totalCaloriesSum += calories
-			daysWithData++
-		}
-		// Fill in the calories for each day
-		for i := 0; i < days; i++ {
-			date := time.Now().AddDate(0, 0, -days+1+i).Format("2006-01-02")
-			dailyCalories[i] = caloriesMap[date]
-		}
-		// Calculate average calories
-		if daysWithData > 0 {
-			analytics.AvgCalories = totalCaloriesSum / daysWithData
-		}
+	for i := 0; i < days; i++ {
+		dailyCalories[i] = caloriesByDate[from.AddDate(0, 0, i).Format("2006-01-02")]
+	}
+	if len(calorieDays) > 0 {
+		analytics.AvgCalories = totalCaloriesSum / len(calorieDays)
 	}
 	analytics.DailyCalories = dailyCalories
 
 	// Get weight progress data
 	weightDates := []string{}
 	weights := []float64{}
-	rows, err = h.db.Query(`
+	rows, err := h.db.Query(`
 		SELECT date, weight 
 		FROM body_weights 
 		WHERE user_id = ? AND date >= ? AND date <= ?
@@ -621,10 +227,10 @@ func (h *Handler) getAnalyticsData(userID int, days int) (*models.AnalyticsData,
 		SELECT e.category, COUNT(*) as count
 		FROM exercises e
 		JOIN workouts w ON e.workout_id = w.id
-		WHERE w.date >= ? AND w.date <= ?
+		WHERE w.user_id = ? AND w.date >= ? AND w.date <= ?
 		GROUP BY e.category
 		ORDER BY count DESC
-	`, startDate, endDate)
+	`, userID, startDate, endDate)
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
@@ -658,19 +264,18 @@ func (h *Handler) getAnalyticsData(userID int, days int) (*models.AnalyticsData,
 	}
 	analytics.Macros = macros
 
-	// Calculate workout streak
-	workoutStreak := 0
-	currentDate := time.Now()
-	for i := 0; i < 365; i++ { // Check up to a year back
-		checkDate := currentDate.AddDate(0, 0, -i).Format("2006-01-02")
-		var count int
-		err := h.db.QueryRow("SELECT COUNT(*) FROM workouts WHERE DATE(date) = ?", checkDate).Scan(&count)
-		if err != nil || count == 0 {
-			break
-		}
-		workoutStreak++
+	// Calculate workout streak, checking up to a year back
+	streakDays, err := h.storage.Analytics().WorkoutDays(userID, to.AddDate(0, 0, -364), to)
+	if err != nil {
+		return nil, err
+	}
+	workedOut := make(map[string]bool)
+	for _, day := range streakDays {
+		workedOut[day.Date.Format("2006-01-02")] = true
+	}
+	for workedOut[to.AddDate(0, 0, -analytics.WorkoutStreak).Format("2006-01-02")] {
+		analytics.WorkoutStreak++
 	}
-	analytics.WorkoutStreak = workoutStreak
 
 	// Enhanced progress tracking
 	err = h.calculateDetailedProgress(analytics, userID, startDate, endDate)
@@ -682,245 +287,17 @@ func (h *Handler) getAnalyticsData(userID int, days int) (*models.AnalyticsData,
 	return analytics, nil
 }
 
-// createUser creates a new user and returns its ID
-func (h *Handler) createUser(user models.User) (int, error) {
-	query := `
-		INSERT INTO users (username, email, password_hash, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)
-	`
-	
-	result, err := h.db.Exec(query, user.Username, user.Email, user.PasswordHash, time.Now(), time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(id), nil
-}
-
-// getUserByUsername returns a user by username
-func (h *Handler) getUserByUsername(username string) (models.User, error) {
-	var u models.User
-	
-	query := `
-		SELECT id, username, email, password_hash, created_at, updated_at
-		FROM users
-		WHERE username = ?
-	`
-	
-	err := h.db.QueryRow(query, username).Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt)
-	if err != nil {
-		return u, err
-	}
-
-	return u, nil
-}
-
-// createMeal creates a new meal entry and returns its ID
-func (h *Handler) createMeal(meal models.Meal) (int, error) {
-	query := `
-		INSERT INTO meals (user_id, name, calories, protein, carbs, fat, date, meal_type, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-	
-	result, err := h.db.Exec(query, meal.UserID, meal.Name, meal.Calories, meal.Protein, meal.Carbs, meal.Fat, meal.Date, meal.MealType, time.Now(), time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(id), nil
-}
-
-// getMealsByUserAndDate returns meals for a user on a specific date
-func (h *Handler) getMealsByUserAndDate(userID int, date time.Time) ([]models.Meal, error) {
-	query := `
-		SELECT id, user_id, name, calories, protein, carbs, fat, date, meal_type, created_at, updated_at
-		FROM meals
-		WHERE user_id = ? AND DATE(date) = DATE(?)
-		ORDER BY created_at ASC
-	`
-	
-	rows, err := h.db.Query(query, userID, date)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var meals []models.Meal
-	for rows.Next() {
-		var m models.Meal
-		err := rows.Scan(&m.ID, &m.UserID, &m.Name, &m.Calories, &m.Protein, &m.Carbs, &m.Fat, &m.Date, &m.MealType, &m.CreatedAt, &m.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		meals = append(meals, m)
-	}
-
-	return meals, nil
-}
-
-// createBodyWeight creates a new body weight entry and returns its ID
-func (h *Handler) createBodyWeight(bodyWeight models.BodyWeight) (int, error) {
-	query := `
-		INSERT INTO body_weights (user_id, weight, unit, date, notes, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`
-	
-	result, err := h.db.Exec(query, bodyWeight.UserID, bodyWeight.Weight, bodyWeight.Unit, bodyWeight.Date, bodyWeight.Notes, time.Now(), time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(id), nil
-}
-
-// getBodyWeightsByUser returns body weight entries for a user
-func (h *Handler) getBodyWeightsByUser(userID int) ([]models.BodyWeight, error) {
-	query := `
-		SELECT id, user_id, weight, unit, date, notes, created_at, updated_at
-		FROM body_weights
-		WHERE user_id = ?
-		ORDER BY date DESC
-	`
-	
-	rows, err := h.db.Query(query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var weights []models.BodyWeight
-	for rows.Next() {
-		var w models.BodyWeight
-		err := rows.Scan(&w.ID, &w.UserID, &w.Weight, &w.Unit, &w.Date, &w.Notes, &w.CreatedAt, &w.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		weights = append(weights, w)
-	}
-
-	return weights, nil
-}
-
-// createBodyFat creates a new body fat entry and returns its ID
-func (h *Handler) createBodyFat(bodyFat models.BodyFat) (int, error) {
-	query := `
-		INSERT INTO body_fats (user_id, body_fat_pct, date, measurement, notes, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`
-	
-	result, err := h.db.Exec(query, bodyFat.UserID, bodyFat.BodyFatPct, bodyFat.Date, bodyFat.Measurement, bodyFat.Notes, time.Now(), time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(id), nil
-}
-
-// getBodyFatsByUser returns body fat entries for a user
-func (h *Handler) getBodyFatsByUser(userID int) ([]models.BodyFat, error) {
-	query := `
-		SELECT id, user_id, body_fat_pct, date, measurement, notes, created_at, updated_at
-		FROM body_fats
-		WHERE user_id = ?
-		ORDER BY date DESC
-	`
-	
-	rows, err := h.db.Query(query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var bodyFats []models.BodyFat
-	for rows.Next() {
-		var bf models.BodyFat
-		err := rows.Scan(&bf.ID, &bf.UserID, &bf.BodyFatPct, &bf.Date, &bf.Measurement, &bf.Notes, &bf.CreatedAt, &bf.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		bodyFats = append(bodyFats, bf)
-	}
-
-	return bodyFats, nil
-}
-
-// createBodyMeasurement creates a new body measurement entry and returns its ID
-func (h *Handler) createBodyMeasurement(measurement models.BodyMeasurement) (int, error) {
-	query := `
-		INSERT INTO body_measurements (user_id, measurement, value, unit, date, notes, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`
-	
-	result, err := h.db.Exec(query, measurement.UserID, measurement.Measurement, measurement.Value, measurement.Unit, measurement.Date, measurement.Notes, time.Now(), time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(id), nil
-}
-
-// getBodyMeasurementsByUser returns body measurements for a user
-func (h *Handler) getBodyMeasurementsByUser(userID int) ([]models.BodyMeasurement, error) {
-	query := `
-		SELECT id, user_id, measurement, value, unit, date, notes, created_at, updated_at
-		FROM body_measurements
-		WHERE user_id = ?
-		ORDER BY measurement, date DESC
-	`
-	
-	rows, err := h.db.Query(query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var measurements []models.BodyMeasurement
-	for rows.Next() {
-		var m models.BodyMeasurement
-		err := rows.Scan(&m.ID, &m.UserID, &m.Measurement, &m.Value, &m.Unit, &m.Date, &m.Notes, &m.CreatedAt, &m.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		measurements = append(measurements, m)
-	}
-
-	return measurements, nil
-}
-
 // User Settings Database Methods
 func (h *Handler) getUserSettings(userID int) (models.UserSettings, error) {
-	query := `SELECT * FROM user_settings WHERE user_id = ?`
+	query := `SELECT id, user_id, theme, timezone, weight_unit, distance_unit, date_format, notifications,
+			privacy_mode, auto_logout, language, e1rm_formula, created_at, updated_at
+		FROM user_settings WHERE user_id = ?`
 	var settings models.UserSettings
 	err := h.db.QueryRow(query, userID).Scan(
 		&settings.ID, &settings.UserID, &settings.Theme, &settings.Timezone,
 		&settings.WeightUnit, &settings.DistanceUnit, &settings.DateFormat,
 		&settings.Notifications, &settings.PrivacyMode, &settings.AutoLogout,
-		&settings.Language, &settings.CreatedAt, &settings.UpdatedAt)
+		&settings.Language, &settings.E1RMFormula, &settings.CreatedAt, &settings.UpdatedAt)
 	if err != nil {
 		// If no settings exist, create default settings
 		defaultSettings := models.UserSettings{
@@ -934,8 +311,9 @@ func (h *Handler) getUserSettings(userID int) (models.UserSettings, error) {
 			PrivacyMode:   false,
 			AutoLogout:    0,
 			Language:      "en",
-			CreatedAt:     time.Now(),
-			UpdatedAt:     time.Now(),
+			E1RMFormula:   string(e1rm.Default),
+			CreatedAt:     h.now(),
+			UpdatedAt:     h.now(),
 		}
 		_, createErr := h.createUserSettings(defaultSettings)
 		if createErr != nil {
@@ -947,75 +325,34 @@ func (h *Handler) getUserSettings(userID int) (models.UserSettings, error) {
 }
 
 func (h *Handler) createUserSettings(settings models.UserSettings) (int, error) {
-	query := `INSERT INTO user_settings (user_id, theme, timezone, weight_unit, distance_unit, date_format, notifications, privacy_mode, auto_logout, language, created_at, updated_at) 
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	result, err := h.db.Exec(query, settings.UserID, settings.Theme, settings.Timezone, settings.WeightUnit, 
+	query := `INSERT INTO user_settings (user_id, theme, timezone, weight_unit, distance_unit, date_format, notifications, privacy_mode, auto_logout, language, e1rm_formula, created_at, updated_at) 
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	return h.db.Insert(query, settings.UserID, settings.Theme, settings.Timezone, settings.WeightUnit, 
 		settings.DistanceUnit, settings.DateFormat, settings.Notifications, settings.PrivacyMode, 
-		settings.AutoLogout, settings.Language, settings.CreatedAt, settings.UpdatedAt)
-	if err != nil {
-		return 0, err
-	}
-	id, err := result.LastInsertId()
-	return int(id), err
+		settings.AutoLogout, settings.Language, settings.E1RMFormula, settings.CreatedAt, settings.UpdatedAt)
 }
 
 func (h *Handler) updateUserSettings(userID int, settings models.UpdateSettingsRequest) error {
+	previous, err := h.getUserSettings(userID)
+	if err != nil {
+		return err
+	}
+
 	query := `UPDATE user_settings SET theme = ?, timezone = ?, weight_unit = ?, distance_unit = ?, date_format = ?, 
-			notifications = ?, privacy_mode = ?, auto_logout = ?, language = ?, updated_at = ? WHERE user_id = ?`
-	_, err := h.db.Exec(query, settings.Theme, settings.Timezone, settings.WeightUnit, settings.DistanceUnit,
+			notifications = ?, privacy_mode = ?, auto_logout = ?, language = ?, 
+			e1rm_formula = COALESCE(NULLIF(?, ''), e1rm_formula), updated_at = ? WHERE user_id = ?`
+	_, err = h.db.Exec(query, settings.Theme, settings.Timezone, settings.WeightUnit, settings.DistanceUnit,
 		settings.DateFormat, settings.Notifications, settings.PrivacyMode, settings.AutoLogout, 
-		settings.Language, time.Now(), userID)
-	return err
-}
-
-func (h *Handler) updateUserProfile(userID int, profile models.UpdateProfileRequest) error {
-	query := `UPDATE users SET username = ?, email = ?, full_name = ?, bio = ?, updated_at = ? WHERE id = ?`
-	_, err := h.db.Exec(query, profile.Username, profile.Email, profile.FullName, profile.Bio, time.Now(), userID)
-	return err
-}
-
-func (h *Handler) updateUserPassword(userID int, hashedPassword string) error {
-	query := `UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`
-	_, err := h.db.Exec(query, hashedPassword, time.Now(), userID)
-	return err
-}
-
-func (h *Handler) getUserByID(userID int) (models.User, error) {
-	query := `SELECT id, username, email, password_hash, COALESCE(full_name, '') as full_name, COALESCE(bio, '') as bio, COALESCE(avatar, '') as avatar, COALESCE(is_active, 1) as is_active, created_at, updated_at FROM users WHERE id = ?`
-	var user models.User
-	err := h.db.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.FullName, &user.Bio, &user.Avatar, &user.IsActive, &user.CreatedAt, &user.UpdatedAt)
-	return user, err
-}
-
-func (h *Handler) deleteUserAccount(userID int) error {
-	tx, err := h.db.Begin()
+		settings.Language, settings.E1RMFormula, h.now(), userID)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
-	
-	// Delete user data in the correct order
-	// Start with the most dependent tables first
-	queries := []string{
-		`DELETE FROM sets WHERE exercise_id IN (SELECT id FROM exercises WHERE workout_id IN (SELECT id FROM workouts WHERE user_id = ?))`,
-		`DELETE FROM exercises WHERE workout_id IN (SELECT id FROM workouts WHERE user_id = ?)`,
-		`DELETE FROM workouts WHERE user_id = ?`,
-		`DELETE FROM body_measurements WHERE user_id = ?`,
-		`DELETE FROM body_fats WHERE user_id = ?`,
-		`DELETE FROM body_weights WHERE user_id = ?`,
-		`DELETE FROM meals WHERE user_id = ?`,
-		`DELETE FROM user_settings WHERE user_id = ?`,
-		`DELETE FROM users WHERE id = ?`,
-	}
-	
-	for _, query := range queries {
-		_, err := tx.Exec(query, userID)
-		if err != nil {
-			return err
-		}
+
+	// Estimated 1RM records depend on the formula
+	if settings.E1RMFormula != "" && settings.E1RMFormula != previous.E1RMFormula {
+		return h.storage.PersonalRecords().Rebuild(userID)
 	}
-	
-	return tx.Commit()
+	return nil
 }
 
 // calculateDetailedProgress computes enhanced analytics data
@@ -1033,10 +370,10 @@ func (h *Handler) calculateDetailedProgress(analytics *models.AnalyticsData, use
 		FROM sets s
 		JOIN exercises e ON s.exercise_id = e.id
 		JOIN workouts w ON e.workout_id = w.id
-		WHERE w.date >= ? AND w.date <= ? AND s.weight > 0
+		WHERE w.user_id = ? AND w.date >= ? AND w.date <= ? AND s.weight > 0 AND s.set_type <> ?
 	`
 
-	err := h.db.QueryRow(query, startDate, endDate).Scan(&totalSets, &totalReps, &totalVolume, &maxWeight)
+	err := h.db.QueryRow(query, userID, startDate, endDate, models.SetTypeWarmup).Scan(&totalSets, &totalReps, &totalVolume, &maxWeight)
 	if err != nil {
 		return err
 	}
@@ -1098,108 +435,141 @@ func (h *Handler) calculateDetailedProgress(analytics *models.AnalyticsData, use
 	return nil
 }
 
-// getPersonalRecords returns personal records for major exercises
+// getPersonalRecords returns up to ten exercises with records set in the period, each with
+// the heaviest weight record and the best estimated 1RM record of the period
 func (h *Handler) getPersonalRecords(userID int, startDate, endDate string) ([]models.PersonalRecord, error) {
 	query := `
-		SELECT 
-			e.name,
-			MAX(s.weight) as max_weight,
-			s.reps,
-			(s.weight * s.reps) as volume,
-			(s.weight * (1 + s.reps/30.0)) as one_rep_max,
-			w.date
-		FROM sets s
-		JOIN exercises e ON s.exercise_id = e.id
-		JOIN workouts w ON e.workout_id = w.id
-		WHERE w.date >= ? AND w.date <= ? AND s.weight > 0
-		GROUP BY e.name
-		HAVING MAX(s.weight) = s.weight
-		ORDER BY max_weight DESC
-		LIMIT 10
+		SELECT exercise_name, record_type, weight, reps, volume, one_rep_max, date
+		FROM personal_records
+		WHERE user_id = ? AND date >= ? AND date <= ? AND record_type IN (?, ?)
+		ORDER BY date, id
 	`
 
-	rows, err := h.db.Query(query, startDate, endDate)
+	rows, err := h.db.Query(query, userID, startDate, endDate, models.RecordTypeWeight, models.RecordTypeE1RM)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var records []models.PersonalRecord
+	byExercise := make(map[string]*models.PersonalRecord)
+	var records []*models.PersonalRecord
 	for rows.Next() {
-		var record models.PersonalRecord
-		err := rows.Scan(&record.ExerciseName, &record.Weight, &record.Reps, &record.Volume, &record.OneRepMax, &record.Date)
+		var entry models.PersonalRecordEntry
+		err := rows.Scan(&entry.ExerciseName, &entry.RecordType, &entry.Weight, &entry.Reps, &entry.Volume, &entry.OneRepMax, &entry.Date)
 		if err != nil {
 			return nil, err
 		}
+
+		record, ok := byExercise[entry.ExerciseName]
+		if !ok {
+			record = &models.PersonalRecord{ExerciseName: entry.ExerciseName}
+			byExercise[entry.ExerciseName] = record
+			records = append(records, record)
+		}
+		if entry.RecordType == models.RecordTypeE1RM {
+			if entry.OneRepMax > record.OneRepMax {
+				record.OneRepMax = entry.OneRepMax
+			}
+			continue
+		}
+		// Between records of the same weight the one with more reps wins
+		if entry.Weight > record.Weight || (entry.Weight == record.Weight && entry.Reps > record.Reps) {
+			record.Weight = entry.Weight
+			record.Reps = entry.Reps
+			record.Volume = entry.Volume
+			record.Date = entry.Date
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Weight > records[j].Weight })
+	if len(records) > 10 {
+		records = records[:10]
+	}
+
+	var result []models.PersonalRecord
+	for _, record := range records {
 		// Mark as new if achieved in the last 7 days
-		if time.Since(record.Date).Hours() < 168 {
+		if h.now().Sub(record.Date).Hours() < 168 {
 			record.IsNew = true
 		}
-		records = append(records, record)
+		result = append(result, *record)
+	}
+	return result, nil
+}
+
+// e1rmFormula returns the estimated 1RM formula the user has chosen
+func (h *Handler) e1rmFormula(userID int) e1rm.Formula {
+	settings, err := h.getUserSettings(userID)
+	if err != nil {
+		log.Printf("Failed to get settings for user %d: %v", userID, err)
+		return e1rm.Default
+	}
+	formula, err := e1rm.ParseFormula(settings.E1RMFormula)
+	if err != nil {
+		return e1rm.Default
+	}
+	return formula
+}
+
+// bestEstimates returns the best estimated 1RM of each group of sets, keyed by key(set)
+func bestEstimates[K comparable](sets []models.WorkingSet, formula e1rm.Formula, key func(models.WorkingSet) K) map[K]float64 {
+	best := make(map[K]float64)
+	for _, set := range sets {
+		if estimate, ok := formula.EstimateSet(set.Weight, set.Reps, set.RPE, set.RIR); ok && estimate > best[key(set)] {
+			best[key(set)] = estimate
+		}
 	}
+	return best
+}
 
-	return records, nil
+// parsePeriod parses the first and last day of a period given as YYYY-MM-DD
+func parsePeriod(startDate, endDate string) (time.Time, time.Time, error) {
+	from, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return from, from, fmt.Errorf("invalid start date %q", startDate)
+	}
+	to, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return from, to, fmt.Errorf("invalid end date %q", endDate)
+	}
+	return from, to, nil
 }
 
 // getStrengthProgress returns strength progression data for top exercises
 func (h *Handler) getStrengthProgress(userID int, startDate, endDate string) ([]models.StrengthProgress, error) {
-	// Get top 5 exercises by frequency
-	topExercisesQuery := `
-		SELECT e.name, e.category, COUNT(*) as frequency
-		FROM exercises e
-		JOIN workouts w ON e.workout_id = w.id
-		WHERE w.date >= ? AND w.date <= ?
-		GROUP BY e.name, e.category
-		ORDER BY frequency DESC
-		LIMIT 5
-	`
+	from, to, err := parsePeriod(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := h.db.Query(topExercisesQuery, startDate, endDate)
+	// Get top 5 exercises by frequency
+	topExercises, err := h.storage.Analytics().TopExercises(userID, from, to, 5)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
+	formula := h.e1rmFormula(userID)
 	var progressData []models.StrengthProgress
-	for rows.Next() {
-		var exerciseName, category string
-		var frequency int
-		err := rows.Scan(&exerciseName, &category, &frequency)
-		if err != nil {
-			continue
-		}
+	for _, exercise := range topExercises {
+		exerciseName, category := exercise.ExerciseName, exercise.Category
 
 		// Get progression data points for this exercise
-		dataPointsQuery := `
-			SELECT 
-				w.date,
-				MAX(s.weight) as max_weight,
-				MAX(s.reps) as max_reps,
-				SUM(s.weight * s.reps) as volume,
-				MAX(s.weight * (1 + s.reps/30.0)) as one_rep_max
-			FROM sets s
-			JOIN exercises e ON s.exercise_id = e.id
-			JOIN workouts w ON e.workout_id = w.id
-			WHERE e.name = ? AND w.date >= ? AND w.date <= ? AND s.weight > 0
-			GROUP BY DATE(w.date)
-			ORDER BY w.date
-		`
-
-		dataRows, err := h.db.Query(dataPointsQuery, exerciseName, startDate, endDate)
+		dataPoints, err := h.storage.Analytics().StrengthDays(userID, exerciseName, from, to)
 		if err != nil {
-			continue
+			return nil, err
 		}
 
-		var dataPoints []models.StrengthDataPoint
-		for dataRows.Next() {
-			var point models.StrengthDataPoint
-			err := dataRows.Scan(&point.Date, &point.MaxWeight, &point.MaxReps, &point.Volume, &point.OneRepMax)
-			if err != nil {
-				continue
-			}
-			dataPoints = append(dataPoints, point)
+		sets, err := h.storage.Analytics().WorkingSets(userID, exerciseName, from, to)
+		if err != nil {
+			return nil, err
+		}
+		estimates := bestEstimates(sets, formula, func(set models.WorkingSet) string { return set.Date.Format("2006-01-02") })
+		for i := range dataPoints {
+			dataPoints[i].OneRepMax = estimates[dataPoints[i].Date.Format("2006-01-02")]
 		}
-		dataRows.Close()
 
 		// Calculate trend
 		trend := "stable"
@@ -1240,13 +610,13 @@ func (h *Handler) getWorkoutIntensity(userID int, startDate, endDate string) ([]
 			COALESCE(SUM(s.reps), 0) as total_reps
 		FROM workouts w
 		LEFT JOIN exercises e ON w.id = e.workout_id
-		LEFT JOIN sets s ON e.id = s.exercise_id
-		WHERE w.date >= ? AND w.date <= ?
+		LEFT JOIN sets s ON e.id = s.exercise_id AND s.set_type <> ?
+		WHERE w.user_id = ? AND w.date >= ? AND w.date <= ?
 		GROUP BY w.date
 		ORDER BY w.date
 	`
 
-	rows, err := h.db.Query(query, startDate, endDate)
+	rows, err := h.db.Query(query, models.SetTypeWarmup, userID, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
@@ -1281,13 +651,13 @@ func (h *Handler) getMostPopularExercises(userID int, startDate, endDate string)
 			MAX(w.date) as last_performed
 		FROM exercises e
 		JOIN workouts w ON e.workout_id = w.id
-		WHERE w.date >= ? AND w.date <= ?
+		WHERE w.user_id = ? AND w.date >= ? AND w.date <= ?
 		GROUP BY e.name, e.category
 		ORDER BY count DESC
 		LIMIT 10
 	`
 
-	rows, err := h.db.Query(query, startDate, endDate)
+	rows, err := h.db.Query(query, userID, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
@@ -1309,10 +679,12 @@ func (h *Handler) getMostPopularExercises(userID int, startDate, endDate string)
 			count        int
 			lastPerformed time.Time
 		}
-		err := rows.Scan(&ex.name, &ex.category, &ex.count, &ex.lastPerformed)
+		var lastPerformed database.Timestamp
+		err := rows.Scan(&ex.name, &ex.category, &ex.count, &lastPerformed)
 		if err != nil {
 			continue
 		}
+		ex.lastPerformed = lastPerformed.Time
 		totalExercises += ex.count
 		exercises = append(exercises, ex)
 	}
@@ -1356,11 +728,11 @@ func (h *Handler) getProgressTrends(analytics *models.AnalyticsData, userID int,
 		FROM sets s
 		JOIN exercises e ON s.exercise_id = e.id
 		JOIN workouts w ON e.workout_id = w.id
-		WHERE w.date >= ? AND w.date <= ?
+		WHERE w.user_id = ? AND w.date >= ? AND w.date <= ? AND s.set_type <> ?
 	`
 
-	h.db.QueryRow(volumeQuery, startDate, endDate).Scan(&currentVolume)
-	h.db.QueryRow(volumeQuery, prevStartDate, prevEndDate).Scan(&previousVolume)
+	h.db.QueryRow(volumeQuery, userID, startDate, endDate, models.SetTypeWarmup).Scan(&currentVolume)
+	h.db.QueryRow(volumeQuery, userID, prevStartDate, prevEndDate, models.SetTypeWarmup).Scan(&previousVolume)
 
 	if previousVolume > 0 {
 		trends.VolumeChangePercent = ((currentVolume - previousVolume) / previousVolume) * 100
@@ -1382,11 +754,11 @@ func (h *Handler) getProgressTrends(analytics *models.AnalyticsData, userID int,
 		FROM sets s
 		JOIN exercises e ON s.exercise_id = e.id
 		JOIN workouts w ON e.workout_id = w.id
-		WHERE w.date >= ? AND w.date <= ?
+		WHERE w.user_id = ? AND w.date >= ? AND w.date <= ? AND s.set_type <> ?
 	`
 
-	h.db.QueryRow(maxWeightQuery, startDate, endDate).Scan(&currentMaxWeight)
-	h.db.QueryRow(maxWeightQuery, prevStartDate, prevEndDate).Scan(&previousMaxWeight)
+	h.db.QueryRow(maxWeightQuery, userID, startDate, endDate, models.SetTypeWarmup).Scan(&currentMaxWeight)
+	h.db.QueryRow(maxWeightQuery, userID, prevStartDate, prevEndDate, models.SetTypeWarmup).Scan(&previousMaxWeight)
 
 	if previousMaxWeight > 0 {
 		trends.StrengthChangePercent = ((currentMaxWeight - previousMaxWeight) / previousMaxWeight) * 100
@@ -1403,10 +775,10 @@ func (h *Handler) getProgressTrends(analytics *models.AnalyticsData, userID int,
 
 	// Compare frequency trends
 	var currentWorkouts, previousWorkouts int
-	workoutQuery := `SELECT COUNT(*) FROM workouts WHERE date >= ? AND date <= ?`
+	workoutQuery := `SELECT COUNT(*) FROM workouts WHERE user_id = ? AND date >= ? AND date <= ?`
 
-	h.db.QueryRow(workoutQuery, startDate, endDate).Scan(&currentWorkouts)
-	h.db.QueryRow(workoutQuery, prevStartDate, prevEndDate).Scan(&previousWorkouts)
+	h.db.QueryRow(workoutQuery, userID, startDate, endDate).Scan(&currentWorkouts)
+	h.db.QueryRow(workoutQuery, userID, prevStartDate, prevEndDate).Scan(&previousWorkouts)
 
 	if previousWorkouts > 0 {
 		trends.FrequencyChangePercent = ((float64(currentWorkouts) - float64(previousWorkouts)) / float64(previousWorkouts)) * 100
@@ -1438,46 +810,25 @@ func (h *Handler) getExerciseProgressChart(userID int, exerciseName string, star
 		TimeRange:   fmt.Sprintf("%s to %s", startDate, endDate),
 	}
 
-	// Get basic workout stats for the exercise
-	query := `
-		SELECT 
-			w.id as workout_id,
-			w.date,
-			MAX(s.weight) as max_weight,
-			MAX(s.reps) as max_reps,
-			SUM(s.weight * s.reps) as total_volume,
-			COUNT(s.id) as sets_count
-		FROM sets s
-		JOIN exercises e ON s.exercise_id = e.id
-		JOIN workouts w ON e.workout_id = w.id
-		WHERE e.name = ? AND w.date >= ? AND w.date <= ?
-		GROUP BY w.id, w.date
-		ORDER BY w.date
-	`
+	from, to, err := parsePeriod(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := h.db.Query(query, exerciseName, startDate, endDate)
+	// The best estimated 1RM of each workout, using the user's formula
+	sets, err := h.storage.Analytics().WorkingSets(userID, exerciseName, from, to)
+	if err != nil {
+		return nil, err
+	}
+	estimates := bestEstimates(sets, h.e1rmFormula(userID), func(set models.WorkingSet) int { return set.WorkoutID })
+
+	// Get basic workout stats for the exercise
+	dataPoints, err := h.storage.Analytics().ExerciseProgress(userID, exerciseName, from, to)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var dataPoints []models.ExerciseProgressPoint
-	for rows.Next() {
-		var point models.ExerciseProgressPoint
-		var dateStr string
-		err := rows.Scan(&point.WorkoutID, &dateStr, &point.MaxWeight, &point.MaxReps, &point.TotalVolume, &point.SetsCount)
-		if err != nil {
-			return nil, err
-		}
-		// Parse date string
-		point.Date, err = time.Parse("2006-01-02", dateStr)
-		if err != nil {
-			// If parsing fails, use current time
-			point.Date = time.Now()
-		}
-		// Calculate one rep max
-		point.OneRepMax = point.MaxWeight * float64(1+point.MaxReps/30.0)
-		dataPoints = append(dataPoints, point)
+	for i := range dataPoints {
+		dataPoints[i].OneRepMax = estimates[dataPoints[i].WorkoutID]
 	}
 	chart.DataPoints = dataPoints
 
@@ -1605,9 +956,9 @@ func (h *Handler) getWeeklySummary(userID int, year int, week int) (*models.Week
 			COALESCE(SUM(duration), 0) as total_duration,
 			COALESCE(AVG(duration), 0) as avg_duration
 		FROM workouts 
-		WHERE date >= ? AND date <= ?
+		WHERE user_id = ? AND date >= ? AND date <= ?
 	`
-	err := h.db.QueryRow(query, startDate, endDate).Scan(&totalWorkouts, &totalDuration, &avgDuration)
+	err := h.db.QueryRow(query, userID, startDate, endDate).Scan(&totalWorkouts, &totalDuration, &avgDuration)
 	if err != nil {
 		return nil, err
 	}
@@ -1632,9 +983,9 @@ func (h *Handler) getWeeklySummary(userID int, year int, week int) (*models.Week
 		FROM workouts w
 		LEFT JOIN exercises e ON w.id = e.workout_id
 		LEFT JOIN sets s ON e.id = s.exercise_id
-		WHERE w.date >= ? AND w.date <= ? AND s.weight > 0
+		WHERE w.user_id = ? AND w.date >= ? AND w.date <= ? AND s.weight > 0 AND s.set_type <> ?
 	`
-	err = h.db.QueryRow(exerciseQuery, startDate, endDate).Scan(&uniqueExercises, &totalSets, &totalReps, &totalVolume, &maxWeight)
+	err = h.db.QueryRow(exerciseQuery, userID, startDate, endDate, models.SetTypeWarmup).Scan(&uniqueExercises, &totalSets, &totalReps, &totalVolume, &maxWeight)
 	if err != nil {
 		return nil, err
 	}
@@ -1711,9 +1062,9 @@ func (h *Handler) getMonthlySummary(userID int, year int, month int) (*models.Mo
 			COALESCE(SUM(duration), 0) as total_duration,
 			COALESCE(AVG(duration), 0) as avg_duration
 		FROM workouts 
-		WHERE date >= ? AND date <= ?
+		WHERE user_id = ? AND date >= ? AND date <= ?
 	`
-	err := h.db.QueryRow(query, startStr, endStr).Scan(&totalWorkouts, &totalDuration, &avgDuration)
+	err := h.db.QueryRow(query, userID, startStr, endStr).Scan(&totalWorkouts, &totalDuration, &avgDuration)
 	if err != nil {
 		return nil, err
 	}
@@ -1738,9 +1089,9 @@ func (h *Handler) getMonthlySummary(userID int, year int, month int) (*models.Mo
 		FROM workouts w
 		LEFT JOIN exercises e ON w.id = e.workout_id
 		LEFT JOIN sets s ON e.id = s.exercise_id
-		WHERE w.date >= ? AND w.date <= ? AND s.weight > 0
+		WHERE w.user_id = ? AND w.date >= ? AND w.date <= ? AND s.weight > 0 AND s.set_type <> ?
 	`
-	err = h.db.QueryRow(exerciseQuery, startStr, endStr).Scan(&uniqueExercises, &totalSets, &totalReps, &totalVolume, &maxWeight)
+	err = h.db.QueryRow(exerciseQuery, userID, startStr, endStr, models.SetTypeWarmup).Scan(&uniqueExercises, &totalSets, &totalReps, &totalVolume, &maxWeight)
 	if err != nil {
 		return nil, err
 	}
@@ -1880,13 +1231,13 @@ func (h *Handler) getTopExercisesForPeriod(userID int, startDate, endDate string
 		SELECT e.name, COUNT(*) as frequency
 		FROM exercises e
 		JOIN workouts w ON e.workout_id = w.id
-		WHERE w.date >= ? AND w.date <= ?
+		WHERE w.user_id = ? AND w.date >= ? AND w.date <= ?
 		GROUP BY e.name
 		ORDER BY frequency DESC
 		LIMIT ?
 	`
 	
-	rows, err := h.db.Query(query, startDate, endDate, limit)
+	rows, err := h.db.Query(query, userID, startDate, endDate, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -1907,24 +1258,15 @@ func (h *Handler) getTopExercisesForPeriod(userID int, startDate, endDate string
 }
 
 func (h *Handler) countPRsForPeriod(userID int, startDate, endDate string) (int, error) {
-	// Count exercises where max weight in this period > previous max
+	// Count exercises with a personal record set in this period
 	query := `
-		SELECT COUNT(DISTINCT e.name) as pr_count
-		FROM exercises e
-		JOIN workouts w ON e.workout_id = w.id
-		JOIN sets s ON e.id = s.exercise_id
-		WHERE w.date >= ? AND w.date <= ?
-		AND s.weight = (
-			SELECT MAX(s2.weight)
-			FROM sets s2
-			JOIN exercises e2 ON s2.exercise_id = e2.id
-			JOIN workouts w2 ON e2.workout_id = w2.id
-			WHERE e2.name = e.name
-		)
+		SELECT COUNT(DISTINCT exercise_name) as pr_count
+		FROM personal_records
+		WHERE user_id = ? AND date >= ? AND date <= ?
 	`
 	
 	var count int
-	err := h.db.QueryRow(query, startDate, endDate).Scan(&count)
+	err := h.db.QueryRow(query, userID, startDate, endDate).Scan(&count)
 	return count, err
 }
 
@@ -1954,11 +1296,11 @@ func (h *Handler) getCategoryBreakdownForPeriod(userID int, startDate, endDate s
 		SELECT e.category, COUNT(*) as count
 		FROM exercises e
 		JOIN workouts w ON e.workout_id = w.id
-		WHERE w.date >= ? AND w.date <= ?
+		WHERE w.user_id = ? AND w.date >= ? AND w.date <= ?
 		GROUP BY e.category
 	`
 	
-	rows, err := h.db.Query(query, startDate, endDate)
+	rows, err := h.db.Query(query, userID, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
@@ -2052,598 +1394,165 @@ func (h *Handler) generateRecommendations(summary *models.MonthlySummary) []stri
 	return recommendations
 }
 
-// ========== WORKOUT TEMPLATE DATABASE FUNCTIONS ==========
-
-// getWorkoutTemplatesByUserID returns all workout templates for a specific user
-func (h *Handler) getWorkoutTemplatesByUserID(userID int) ([]models.WorkoutTemplate, error) {
-	query := `
-		SELECT id, user_id, name, description, created_at, updated_at
-		FROM workout_templates
-		WHERE user_id = ?
-		ORDER BY updated_at DESC
-	`
-	
-	rows, err := h.db.Query(query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var templates []models.WorkoutTemplate
-	for rows.Next() {
-		var template models.WorkoutTemplate
-		err := rows.Scan(&template.ID, &template.UserID, &template.Name, &template.Description, &template.CreatedAt, &template.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		
-		// Get exercises for this template - convert TemplateExercises to Exercises
-		templateExercises, err := h.getTemplateExercisesByTemplateID(template.ID)
-		if err != nil {
-			log.Printf("Failed to get exercises for template %d: %v", template.ID, err)
-			// Continue without exercises rather than failing completely
-		} else {
-			// Convert TemplateExercise to Exercise for compatibility
-			var exercises []models.Exercise
-			for _, te := range templateExercises {
-				exercise := models.Exercise{
-					ID:       te.ID,
-					Name:     te.Name,
-					Category: te.Category,
-				}
-				exercises = append(exercises, exercise)
-			}
-			template.Exercises = exercises
-		}
-		
-		templates = append(templates, template)
-	}
-
-	return templates, nil
-}
-
-// getWorkoutTemplateByID returns a specific workout template by ID
-func (h *Handler) getWorkoutTemplateByID(templateID int, userID int) (models.WorkoutTemplate, error) {
-	var template models.WorkoutTemplate
-	
-	query := `
-		SELECT id, user_id, name, description, created_at, updated_at
-		FROM workout_templates
-		WHERE id = ? AND user_id = ?
-	`
-	
-	err := h.db.QueryRow(query, templateID, userID).Scan(&template.ID, &template.UserID, &template.Name, &template.Description, &template.CreatedAt, &template.UpdatedAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return template, fmt.Errorf("template not found")
-		}
-		return template, err
-	}
-
-	// Get exercises for this template - convert TemplateExercises to Exercises
-	templateExercises, err := h.getTemplateExercisesByTemplateID(templateID)
-	if err != nil {
-		return template, err
-	}
-	
-	// Convert TemplateExercise to Exercise for compatibility
-	var exercises []models.Exercise
-	for _, te := range templateExercises {
-		exercise := models.Exercise{
-			ID:       te.ID,
-			Name:     te.Name,
-			Category: te.Category,
-		}
-		exercises = append(exercises, exercise)
-	}
-	template.Exercises = exercises
-
-	return template, nil
-}
-
-// createWorkoutTemplate creates a new workout template and returns its ID
-func (h *Handler) createWorkoutTemplate(template models.WorkoutTemplate) (int, error) {
-	query := `
-		INSERT INTO workout_templates (user_id, name, description, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)
-	`
-	
-	result, err := h.db.Exec(query, template.UserID, template.Name, template.Description, time.Now(), time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(id), nil
-}
-
-// updateWorkoutTemplate updates an existing workout template
-func (h *Handler) updateWorkoutTemplate(template models.WorkoutTemplate) error {
-	query := `
-		UPDATE workout_templates 
-		SET name = ?, description = ?, updated_at = ?
-		WHERE id = ? AND user_id = ?
-	`
-	
-	_, err := h.db.Exec(query, template.Name, template.Description, time.Now(), template.ID, template.UserID)
-	return err
-}
-
-// deleteWorkoutTemplate deletes a workout template and all associated exercises
-func (h *Handler) deleteWorkoutTemplate(templateID int) error {
-	query := `DELETE FROM workout_templates WHERE id = ?`
-	_, err := h.db.Exec(query, templateID)
-	return err
-}
-
-// getTemplateExercisesByTemplateID returns exercises for a template
-func (h *Handler) getTemplateExercisesByTemplateID(templateID int) ([]models.TemplateExercise, error) {
-	query := `
-		SELECT id, template_id, name, category, order_index, target_sets, target_reps, target_weight, rest_time, notes, created_at, updated_at
-		FROM template_exercises
-		WHERE template_id = ?
-		ORDER BY order_index ASC
-	`
-	
-	rows, err := h.db.Query(query, templateID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var exercises []models.TemplateExercise
-	for rows.Next() {
-		var exercise models.TemplateExercise
-		err := rows.Scan(&exercise.ID, &exercise.TemplateID, &exercise.Name, &exercise.Category, &exercise.OrderIndex, &exercise.TargetSets, &exercise.TargetReps, &exercise.TargetWeight, &exercise.RestTime, &exercise.Notes, &exercise.CreatedAt, &exercise.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		exercises = append(exercises, exercise)
-	}
-
-	return exercises, nil
-}
+// ========== API TOKEN DATABASE FUNCTIONS ==========
 
-// createTemplateExercise creates a new template exercise and returns its ID
-func (h *Handler) createTemplateExercise(exercise models.TemplateExercise) (int, error) {
+// createAPIToken stores a hashed personal access token and returns its ID
+func (h *Handler) createAPIToken(token models.APIToken) (int, error) {
 	query := `
-		INSERT INTO template_exercises (template_id, name, category, order_index, target_sets, target_reps, target_weight, rest_time, notes, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO api_tokens (user_id, name, prefix, token_hash, scope, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`
-	
-	result, err := h.db.Exec(query, exercise.TemplateID, exercise.Name, exercise.Category, exercise.OrderIndex, exercise.TargetSets, exercise.TargetReps, exercise.TargetWeight, exercise.RestTime, exercise.Notes, time.Now(), time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(id), nil
-}
 
-// deleteTemplateExercisesByTemplateID deletes all exercises for a template
-func (h *Handler) deleteTemplateExercisesByTemplateID(templateID int) error {
-	query := `DELETE FROM template_exercises WHERE template_id = ?`
-	_, err := h.db.Exec(query, templateID)
-	return err
+	return h.db.Insert(query, token.UserID, token.Name, token.Prefix, token.TokenHash, token.Scope, token.CreatedAt)
 }
 
-// createTemplateSharing creates a new template sharing record and returns its ID
-func (h *Handler) createTemplateSharing(sharing models.TemplateSharing) (int, error) {
+// getAPITokensByUser returns all personal access tokens for a user, newest first
+func (h *Handler) getAPITokensByUser(userID int) ([]models.APIToken, error) {
 	query := `
-		INSERT INTO template_sharing (template_id, owner_id, shared_with_id, permission, created_at)
-		VALUES (?, ?, ?, ?, ?)
+		SELECT id, user_id, name, prefix, scope, last_used_at, revoked_at, created_at
+		FROM api_tokens
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
 	`
-	
-	result, err := h.db.Exec(query, sharing.TemplateID, sharing.OwnerID, sharing.SharedWithID, sharing.Permission, time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(id), nil
-}
 
-// getSharedTemplates returns templates shared with the specified user
-func (h *Handler) getSharedTemplates(userID int) ([]models.WorkoutTemplate, error) {
-	query := `
-		SELECT wt.id, wt.user_id, wt.name, wt.description, wt.created_at, wt.updated_at
-		FROM workout_templates wt
-		JOIN template_sharing ts ON wt.id = ts.template_id
-		WHERE ts.shared_with_id = ?
-		ORDER BY ts.created_at DESC
-	`
-	
 	rows, err := h.db.Query(query, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var templates []models.WorkoutTemplate
+	tokens := []models.APIToken{}
 	for rows.Next() {
-		var template models.WorkoutTemplate
-		err := rows.Scan(&template.ID, &template.UserID, &template.Name, &template.Description, &template.CreatedAt, &template.UpdatedAt)
+		var t models.APIToken
+		err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &t.Scope, &t.LastUsedAt, &t.RevokedAt, &t.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
-		
-		// Get exercises for this template - convert TemplateExercises to Exercises
-		templateExercises, err := h.getTemplateExercisesByTemplateID(template.ID)
-		if err != nil {
-			log.Printf("Failed to get exercises for shared template %d: %v", template.ID, err)
-			// Continue without exercises rather than failing completely
-		} else {
-			// Convert TemplateExercise to Exercise for compatibility
-			var exercises []models.Exercise
-			for _, te := range templateExercises {
-				exercise := models.Exercise{
-					ID:       te.ID,
-					Name:     te.Name,
-					Category: te.Category,
-				}
-				exercises = append(exercises, exercise)
-			}
-			template.Exercises = exercises
-		}
-		
-		templates = append(templates, template)
+		tokens = append(tokens, t)
 	}
 
-	return templates, nil
+	return tokens, rows.Err()
 }
 
-// ========== WORKOUT PROGRAM DATABASE FUNCTIONS ==========
+// getActiveAPITokenByHash returns the unrevoked token matching the given hash
+func (h *Handler) getActiveAPITokenByHash(tokenHash string) (models.APIToken, error) {
+	var t models.APIToken
 
-// getAllWorkoutPrograms returns all workout programs
-func (h *Handler) getAllWorkoutPrograms() ([]models.WorkoutProgram, error) {
 	query := `
-		SELECT id, name, description, difficulty, duration_weeks, goal, is_public, created_by, created_at, updated_at
-		FROM workout_programs
-		WHERE is_public = 1
-		ORDER BY updated_at DESC
+		SELECT id, user_id, name, prefix, scope, last_used_at, revoked_at, created_at
+		FROM api_tokens
+		WHERE token_hash = ? AND revoked_at IS NULL
 	`
-	
-	rows, err := h.db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var programs []models.WorkoutProgram
-	for rows.Next() {
-		var program models.WorkoutProgram
-		err := rows.Scan(&program.ID, &program.Name, &program.Description, &program.Difficulty, &program.DurationWeeks, &program.Goal, &program.IsPublic, &program.CreatedBy, &program.CreatedAt, &program.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		
-		// Get templates for this program
-		templates, err := h.getProgramTemplatesByProgramID(program.ID)
-		if err != nil {
-			log.Printf("Failed to get templates for program %d: %v", program.ID, err)
-			// Continue without templates rather than failing completely
-		}
-		program.Templates = templates
-		
-		programs = append(programs, program)
-	}
-
-	return programs, nil
-}
 
-// getWorkoutProgramByID returns a specific workout program by ID
-func (h *Handler) getWorkoutProgramByID(programID int) (models.WorkoutProgram, error) {
-	var program models.WorkoutProgram
-	
-	query := `
-		SELECT id, name, description, difficulty, duration_weeks, goal, is_public, created_by, created_at, updated_at
-		FROM workout_programs
-		WHERE id = ?
-	`
-	
-	err := h.db.QueryRow(query, programID).Scan(&program.ID, &program.Name, &program.Description, &program.Difficulty, &program.DurationWeeks, &program.Goal, &program.IsPublic, &program.CreatedBy, &program.CreatedAt, &program.UpdatedAt)
+	err := h.db.QueryRow(query, tokenHash).Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &t.Scope, &t.LastUsedAt, &t.RevokedAt, &t.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return program, fmt.Errorf("program not found")
+			return t, fmt.Errorf("token not found")
 		}
-		return program, err
-	}
-
-	// Get templates for this program
-	templates, err := h.getProgramTemplatesByProgramID(programID)
-	if err != nil {
-		return program, err
-	}
-	program.Templates = templates
-
-	return program, nil
-}
-
-// createWorkoutProgram creates a new workout program and returns its ID
-func (h *Handler) createWorkoutProgram(program models.WorkoutProgram) (int, error) {
-	query := `
-		INSERT INTO workout_programs (name, description, difficulty, duration_weeks, goal, is_public, created_by, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-	
-	result, err := h.db.Exec(query, program.Name, program.Description, program.Difficulty, program.DurationWeeks, program.Goal, program.IsPublic, program.CreatedBy, time.Now(), time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
+		return t, err
 	}
 
-	return int(id), nil
-}
-
-// updateWorkoutProgram updates an existing workout program
-func (h *Handler) updateWorkoutProgram(program models.WorkoutProgram) error {
-	query := `
-		UPDATE workout_programs 
-		SET name = ?, description = ?, difficulty = ?, duration_weeks = ?, goal = ?, is_public = ?, updated_at = ?
-		WHERE id = ?
-	`
-	
-	_, err := h.db.Exec(query, program.Name, program.Description, program.Difficulty, program.DurationWeeks, program.Goal, program.IsPublic, time.Now(), program.ID)
-	return err
+	return t, nil
 }
 
-// deleteWorkoutProgram deletes a workout program and all associated program templates
-func (h *Handler) deleteWorkoutProgram(programID int) error {
-	query := `DELETE FROM workout_programs WHERE id = ?`
-	_, err := h.db.Exec(query, programID)
+// touchAPIToken records when a token was last used
+func (h *Handler) touchAPIToken(id int) error {
+	_, err := h.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, h.now(), id)
 	return err
 }
 
-// getProgramTemplatesByProgramID returns program templates for a program
-func (h *Handler) getProgramTemplatesByProgramID(programID int) ([]models.ProgramTemplate, error) {
-	query := `
-		SELECT pt.id, pt.program_id, pt.template_id, pt.day_of_week, pt.week_number, pt.order_index, pt.created_at,
-		       wt.name as template_name, wt.description as template_description
-		FROM program_templates pt
-		JOIN workout_templates wt ON pt.template_id = wt.id
-		WHERE pt.program_id = ?
-		ORDER BY pt.week_number ASC, pt.day_of_week ASC, pt.order_index ASC
-	`
-	
-	rows, err := h.db.Query(query, programID)
+// revokeAPIToken revokes a token owned by the given user
+func (h *Handler) revokeAPIToken(id, userID int) error {
+	query := `UPDATE api_tokens SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`
+	result, err := h.db.Exec(query, h.now(), id, userID)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var templates []models.ProgramTemplate
-	for rows.Next() {
-		var template models.ProgramTemplate
-		var templateName, templateDescription string
-		err := rows.Scan(&template.ID, &template.ProgramID, &template.TemplateID, &template.DayOfWeek, &template.WeekNumber, &template.OrderIndex, &template.CreatedAt, &templateName, &templateDescription)
-		if err != nil {
-			return nil, err
-		}
-		
-		// Create a minimal WorkoutTemplate for reference
-		template.WorkoutTemplate = &models.WorkoutTemplate{
-			ID:          template.TemplateID,
-			Name:        templateName,
-			Description: templateDescription,
-		}
-		
-		templates = append(templates, template)
+		return fmt.Errorf("failed to revoke token: %v", err)
 	}
 
-	return templates, nil
-}
-
-// createProgramTemplate creates a new program template and returns its ID
-func (h *Handler) createProgramTemplate(template models.ProgramTemplate) (int, error) {
-	query := `
-		INSERT INTO program_templates (program_id, template_id, day_of_week, week_number, order_index, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`
-	
-	result, err := h.db.Exec(query, template.ProgramID, template.TemplateID, template.DayOfWeek, template.WeekNumber, template.OrderIndex, time.Now())
+	rows, err := result.RowsAffected()
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("failed to check revoked rows: %v", err)
 	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
+	if rows == 0 {
+		return fmt.Errorf("token not found")
 	}
 
-	return int(id), nil
-}
-
-// deleteProgramTemplatesByProgramID deletes all program templates for a program
-func (h *Handler) deleteProgramTemplatesByProgramID(programID int) error {
-	query := `DELETE FROM program_templates WHERE program_id = ?`
-	_, err := h.db.Exec(query, programID)
-	return err
+	return nil
 }
 
-// ========== TEMPLATE-BASED WORKOUT CREATION FUNCTIONS ==========
-
-// getWorkoutTemplateWithExercises returns a workout template with all its exercises
-func (h *Handler) getWorkoutTemplateWithExercises(templateID, userID int) (*models.WorkoutTemplateWithExercises, error) {
-	// First get the template
-	template, err := h.getWorkoutTemplateByID(templateID, userID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Get template exercises
-	exercises, err := h.getTemplateExercisesByTemplateID(templateID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Convert to WorkoutTemplateWithExercises
-	templateWithExercises := &models.WorkoutTemplateWithExercises{
-		ID:          template.ID,
-		UserID:      template.UserID,
-		Name:        template.Name,
-		Description: template.Description,
-		Exercises:   exercises,
-		CreatedAt:   template.CreatedAt,
-		UpdatedAt:   template.UpdatedAt,
-	}
-
-	return templateWithExercises, nil
-}
+// ========== IDEMPOTENCY DATABASE FUNCTIONS ==========
 
-// getSharedTemplateForUser returns a shared template that the user has access to
-func (h *Handler) getSharedTemplateForUser(templateID, userID int) (*models.WorkoutTemplateWithExercises, error) {
-	// Check if user has access to this shared template
+// reserveIdempotencyKey claims a key for a user before the request runs.
+// It returns false when the user has already used the key.
+func (h *Handler) reserveIdempotencyKey(userID int, key, requestHash string) (bool, error) {
 	query := `
-		SELECT ts.permission
-		FROM template_sharing ts
-		WHERE ts.template_id = ? AND ts.shared_with_id = ?
-	`
-	
-	var permission string
-	err := h.db.QueryRow(query, templateID, userID).Scan(&permission)
-	if err != nil {
-		return nil, fmt.Errorf("template not shared with user")
-	}
-
-	// Get the template with the owner's ID (we need to query differently)
-	templateQuery := `
-		SELECT wt.id, wt.user_id, wt.name, wt.description, wt.created_at, wt.updated_at
-		FROM workout_templates wt
-		JOIN template_sharing ts ON wt.id = ts.template_id
-		WHERE wt.id = ? AND ts.shared_with_id = ?
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, status_code, created_at)
+		VALUES (?, ?, ?, 0, ?)
+		ON CONFLICT(user_id, idempotency_key) DO NOTHING
 	`
-	
-	var template models.WorkoutTemplate
-	err = h.db.QueryRow(templateQuery, templateID, userID).Scan(
-		&template.ID, &template.UserID, &template.Name, &template.Description,
-		&template.CreatedAt, &template.UpdatedAt,
-	)
+	result, err := h.db.Exec(query, userID, key, requestHash, h.now())
 	if err != nil {
-		return nil, err
+		return false, fmt.Errorf("failed to reserve idempotency key: %v", err)
 	}
 
-	// Get template exercises
-	exercises, err := h.getTemplateExercisesByTemplateID(templateID)
+	rows, err := result.RowsAffected()
 	if err != nil {
-		return nil, err
-	}
-
-	// Convert to WorkoutTemplateWithExercises
-	templateWithExercises := &models.WorkoutTemplateWithExercises{
-		ID:          template.ID,
-		UserID:      template.UserID,
-		Name:        template.Name,
-		Description: template.Description,
-		Exercises:   exercises,
-		CreatedAt:   template.CreatedAt,
-		UpdatedAt:   template.UpdatedAt,
+		return false, fmt.Errorf("failed to check reserved rows: %v", err)
 	}
 
-	return templateWithExercises, nil
+	return rows == 1, nil
 }
 
-// createWorkoutWithUser creates a new workout and returns its ID (with user association)
-func (h *Handler) createWorkoutWithUser(workout models.Workout, userID int) (int, error) {
-	// Note: This assumes workouts table has user_id column
-	// If not, you'll need to add it to the database schema
+// getIdempotencyRecord returns the stored record for a user's key
+func (h *Handler) getIdempotencyRecord(userID int, key string) (models.IdempotencyRecord, error) {
+	var rec models.IdempotencyRecord
+	var contentType sql.NullString
+
 	query := `
-		INSERT INTO workouts (name, date, duration, notes, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		SELECT id, user_id, idempotency_key, request_hash, status_code, content_type, response_body, created_at
+		FROM idempotency_keys
+		WHERE user_id = ? AND idempotency_key = ?
 	`
 
-	result, err := h.db.Exec(query, workout.Name, workout.Date, workout.Duration, workout.Notes, workout.CreatedAt, workout.UpdatedAt)
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
+	err := h.db.QueryRow(query, userID, key).Scan(&rec.ID, &rec.UserID, &rec.Key, &rec.RequestHash, &rec.StatusCode, &contentType, &rec.ResponseBody, &rec.CreatedAt)
 	if err != nil {
-		return 0, err
+		if err == sql.ErrNoRows {
+			return rec, fmt.Errorf("idempotency key not found")
+		}
+		return rec, err
 	}
+	rec.ContentType = contentType.String
 
-	return int(id), nil
+	return rec, nil
 }
 
-// createExerciseForWorkout creates an exercise for a specific workout
-func (h *Handler) createExerciseForWorkout(exercise models.Exercise) (int, error) {
+// completeIdempotencyKey stores the response for a reserved key
+func (h *Handler) completeIdempotencyKey(userID int, key string, statusCode int, contentType string, body []byte) error {
 	query := `
-		INSERT INTO exercises (workout_id, name, category, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)
+		UPDATE idempotency_keys SET status_code = ?, content_type = ?, response_body = ?
+		WHERE user_id = ? AND idempotency_key = ?
 	`
-
-	result, err := h.db.Exec(query, exercise.WorkoutID, exercise.Name, exercise.Category, exercise.CreatedAt, exercise.UpdatedAt)
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
+	_, err := h.db.Exec(query, statusCode, contentType, body, userID, key)
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("failed to store idempotent response: %v", err)
 	}
-
-	return int(id), nil
+	return nil
 }
 
-// createTemplateUsage records template usage for analytics
-func (h *Handler) createTemplateUsage(usage models.TemplateUsage) (int, error) {
-	// First, check if template_usage table exists, if not create it
-	createTableQuery := `
-		CREATE TABLE IF NOT EXISTS template_usage (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			template_id INTEGER NOT NULL,
-			user_id INTEGER NOT NULL,
-			workout_id INTEGER NOT NULL,
-			used_at DATETIME NOT NULL,
-			created_at DATETIME NOT NULL,
-			FOREIGN KEY (template_id) REFERENCES workout_templates(id) ON DELETE CASCADE,
-			FOREIGN KEY (workout_id) REFERENCES workouts(id) ON DELETE CASCADE
-		)
-	`
-	
-	_, err := h.db.Exec(createTableQuery)
-	if err != nil {
-		log.Printf("Warning: Failed to create template_usage table: %v", err)
-		// Continue anyway, the table might already exist
-	}
-
-	insertQuery := `
-		INSERT INTO template_usage (template_id, user_id, workout_id, used_at, created_at)
-		VALUES (?, ?, ?, ?, ?)
-	`
-
-	result, err := h.db.Exec(insertQuery, usage.TemplateID, usage.UserID, usage.WorkoutID, usage.UsedAt, usage.CreatedAt)
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
+// releaseIdempotencyKey removes a reservation so the key can be retried
+func (h *Handler) releaseIdempotencyKey(userID int, key string) error {
+	_, err := h.db.Exec(`DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ?`, userID, key)
+	return err
+}
 
-	return int(id), nil
+// deleteExpiredIdempotencyKeys removes keys created before the given time
+func (h *Handler) deleteExpiredIdempotencyKeys(before time.Time) error {
+	_, err := h.db.Exec(`DELETE FROM idempotency_keys WHERE created_at < ?`, before)
+	return err
 }
 
-// getWorkoutByIDWithUser returns a workout by ID with user validation
-func (h *Handler) getWorkoutByIDWithUser(workoutID, userID int) (models.Workout, error) {
-	// Use the existing getWorkoutByID function but add user validation if needed
-	// For now, call the existing function since user association might not be implemented yet
-	// TODO: Add user_id column to workouts table and filter by userID
-	return h.getWorkoutByID(workoutID)
+// deleteExpiredIdempotencyKey removes a user's key if it was created before the given time
+func (h *Handler) deleteExpiredIdempotencyKey(userID int, key string, before time.Time) error {
+	_, err := h.db.Exec(`DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND created_at < ?`, userID, key, before)
+	return err
 }
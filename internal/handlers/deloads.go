@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workout-tracker/internal/events"
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/recommend"
+	"workout-tracker/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// deloadListPast and deloadListAhead are the default period of the deload list around today
+	deloadListPast  = 90
+	deloadListAhead = 30
+)
+
+// suggestDeload recommends a deload from the day after a day of training if the deload
+// detector calls for one. No deload is recommended while one is still in view: until
+// recommend.DeloadCooldownDays after the end of the last one.
+func (h *Handler) suggestDeload(r *http.Request, userID int, day time.Time) {
+	last, err := h.storage.Deloads().Latest(userID)
+	if err == nil && !last.RecommendedEndDate.Before(day.AddDate(0, 0, -recommend.DeloadCooldownDays)) {
+		return
+	}
+	if err != nil && err != storage.ErrNotFound {
+		log.Printf("Failed to get last deload: %v", err)
+		return
+	}
+
+	rec, ok, err := h.evaluateDeload(userID, day)
+	if err != nil {
+		log.Printf("Failed to evaluate deload: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	id, err := h.storage.Deloads().Create(rec)
+	if err != nil {
+		log.Printf("Failed to suggest deload: %v", err)
+		return
+	}
+	if stored, err := h.storage.Deloads().Get(id, userID); err == nil {
+		h.publish(r, events.DeloadSuggested, stored)
+	}
+}
+
+// evaluateDeload runs the deload detector on a user's key lifts up to a day and returns
+// the deload it recommends from the day after, if any
+func (h *Handler) evaluateDeload(userID int, day time.Time) (models.DeloadRecommendation, bool, error) {
+	start := day.AddDate(0, 0, 1-2*recommend.DeloadWindowDays)
+	working, err := h.storage.Analytics().WorkingSets(userID, "", start, day)
+	if err != nil {
+		return models.DeloadRecommendation{}, false, err
+	}
+	sets := make([]recommend.LiftSet, 0, len(working))
+	for _, s := range working {
+		sets = append(sets, recommend.LiftSet{Exercise: s.ExerciseName, Date: s.Date, Weight: s.Weight, Reps: s.Reps, RPE: s.RPE, RIR: s.RIR})
+	}
+
+	weeks := 0
+	since, err := h.storage.Deloads().TrainingSince(userID, day)
+	if err != nil && err != storage.ErrNotFound {
+		return models.DeloadRecommendation{}, false, fmt.Errorf("failed to load training since last deload: %v", err)
+	}
+	if err == nil && !since.After(day) {
+		weeks = int(day.Sub(since).Hours()/24) / 7
+	}
+
+	reason, metrics := recommend.Deload(sets, h.e1rmFormula(userID), day, weeks)
+	if reason == "" {
+		return models.DeloadRecommendation{}, false, nil
+	}
+
+	trigger, err := json.Marshal(metrics)
+	if err != nil {
+		return models.DeloadRecommendation{}, false, err
+	}
+	return models.DeloadRecommendation{
+		UserID:                    userID,
+		RecommendedStartDate:      day.AddDate(0, 0, 1),
+		RecommendedEndDate:        day.AddDate(0, 0, recommend.DeloadDays),
+		Reason:                    reason,
+		VolumeReductionPercent:    recommend.VolumeReductionPercent,
+		IntensityReductionPercent: recommend.IntensityReductionPercent,
+		TriggerMetrics:            string(trigger),
+	}, true, nil
+}
+
+// GetDeloads lists the current user's deload recommendations whose window overlaps the
+// last 90 days to a month ahead. The from and to query parameters pick another period in
+// YYYY-MM-DD format and status keeps only the recommendations with that status.
+func (h *Handler) GetDeloads(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	from, to, status, err := h.parseDeloadListParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recs, err := h.storage.Deloads().List(userID, from, to, status)
+	if err != nil {
+		log.Printf("Failed to get deloads: %v", err)
+		http.Error(w, "Failed to load deloads", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recs)
+}
+
+// parseDeloadListParams reads the from, to and status query parameters
+func (h *Handler) parseDeloadListParams(r *http.Request) (from, to time.Time, status string, err error) {
+	from, to, err = h.parseListPeriod(r, deloadListPast, deloadListAhead)
+	if err != nil {
+		return from, to, "", err
+	}
+
+	switch status = r.URL.Query().Get("status"); status {
+	case "", models.DeloadStatusSuggested, models.DeloadStatusAccepted, models.DeloadStatusIgnored:
+	default:
+		return from, to, "", fmt.Errorf("status must be one of: suggested, accepted, ignored")
+	}
+	return from, to, status, nil
+}
+
+// GetDeload returns one of the current user's deload recommendations
+func (h *Handler) GetDeload(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid deload ID", http.StatusBadRequest)
+		return
+	}
+
+	rec, err := h.storage.Deloads().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Deload not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// RespondToDeload records whether the user accepts or ignores a deload recommendation.
+// Accepting it scales down the targets of the template workouts scheduled in its window;
+// ignoring it, even after accepting, restores them.
+func (h *Handler) RespondToDeload(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid deload ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.DeloadResponseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	err = h.storage.Deloads().Respond(id, userID, req.Status, req.UserResponse)
+	if err == storage.ErrNotFound {
+		http.Error(w, "Deload not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to respond to deload: %v", err)
+		http.Error(w, "Failed to update deload", http.StatusInternalServerError)
+		return
+	}
+
+	rec, err := h.storage.Deloads().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Deload updated but failed to retrieve", http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, events.DeloadUpdated, rec)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
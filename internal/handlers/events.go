@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workout-tracker/internal/events"
+)
+
+// eventStreamHeartbeat is how often an idle event stream sends a comment, so proxies
+// and load balancers do not close the connection
+const eventStreamHeartbeat = 25 * time.Second
+
+// eventStreamRetry is the reconnection delay, in milliseconds, suggested to clients
+const eventStreamRetry = 3000
+
+// publish announces a successful write by the request's user on the event bus
+func (h *Handler) publish(r *http.Request, eventType string, data interface{}) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		return
+	}
+	h.events.Publish(userID, eventType, data)
+}
+
+// StreamEvents streams the current user's change events as server-sent events.
+// A client reconnecting with a Last-Event-ID header, or a last_event_id query parameter,
+// first receives the events it missed. When those are no longer available it receives
+// a reset event instead and should reload its data.
+func (h *Handler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	// EventSource sends the header when it reconnects; the query parameter lets a
+	// client that reloaded the page pick up from the last ID it stored
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	var lastEventID int64
+	if value != "" {
+		if lastEventID, err = strconv.ParseInt(value, 10, 64); err != nil || lastEventID < 0 {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, replay, complete := h.events.Subscribe(userID, lastEventID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventStreamRetry)
+
+	if !complete {
+		fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", h.events.LastID())
+	}
+	for _, event := range replay {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				// The bus dropped this subscriber for falling behind; the client
+				// reconnects with its Last-Event-ID and catches up from the replay
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes one event in the text/event-stream format
+func writeEvent(w http.ResponseWriter, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode event %d: %v", event.ID, err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
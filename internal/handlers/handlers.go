@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
@@ -13,33 +18,120 @@ import (
 	"context"
 
 	"workout-tracker/internal/database"
+	"workout-tracker/internal/e1rm"
+	"workout-tracker/internal/events"
 	"workout-tracker/internal/models"
+	"workout-tracker/internal/storage"
+	"workout-tracker/internal/validation"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Handler holds the database connection and templates
+// Handler holds the database connection, storage repositories, templates and the
+// bus that change events are published on
 type Handler struct {
 	db        *database.DB
-	templates *template.Template
-	store     *sessions.CookieStore
+	storage   storage.Store
+	templates pageTemplates
+	store     sessions.Store
+	events    *events.Bus
+	now       func() time.Time
 }
 
-// New creates a new handler instance
-func New(db *database.DB) *Handler {
+// New creates a new handler instance. Without options it stores data through
+// storage.New(db), parses the templates in web/templates, keeps sessions in
+// cookies signed with SESSION_SECRET, publishes events on a bus of its own and
+// uses the system clock.
+func New(db *database.DB, opts ...Option) *Handler {
+	cfg := config{
+		templates: os.DirFS("web/templates"),
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.store == nil {
+		cfg.store = storage.New(db)
+	}
+	if cfg.sessions == nil {
+		cfg.sessions = newCookieStore()
+	}
+	if cfg.events == nil {
+		cfg.events = events.NewBus(events.DefaultHistorySize, cfg.now)
+	}
+
 	// Create template with custom functions
 	funcMap := template.FuncMap{
 		"hasPrefix": strings.HasPrefix,
 		"contains": strings.Contains,
 		"eq":       func(a, b interface{}) bool { return a == b },
 		"ne":       func(a, b interface{}) bool { return a != b },
+		"div":      divide,
 	}
 
 	// Load templates with proper parsing for inheritance
-	templates := template.Must(template.New("").Funcs(funcMap).ParseGlob("web/templates/*.html"))
-	
+	templates, err := parsePageTemplates(cfg.templates, funcMap)
+	if err != nil {
+		panic(err)
+	}
+
+	return &Handler{
+		db:        db,
+		storage:   cfg.store,
+		templates: templates,
+		store:     cfg.sessions,
+		events:    cfg.events,
+		now:       cfg.now,
+	}
+}
+
+// pageTemplates holds a template set per page. Every page defines its own "content"
+// and "head" blocks for base.html, so parsing them into one set would leave each
+// block with whichever definition was parsed last.
+type pageTemplates map[string]*template.Template
+
+// parsePageTemplates parses each *.html file at the root of fsys together with base.html
+func parsePageTemplates(fsys fs.FS, funcMap template.FuncMap) (pageTemplates, error) {
+	base, err := template.New("").Funcs(funcMap).ParseFS(fsys, "base.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base template: %v", err)
+	}
+
+	pages, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %v", err)
+	}
+
+	templates := make(pageTemplates)
+	for _, page := range pages {
+		if page == "base.html" {
+			continue
+		}
+		set, err := base.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("failed to clone base template: %v", err)
+		}
+		if templates[page], err = set.ParseFS(fsys, page); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %v", page, err)
+		}
+	}
+	return templates, nil
+}
+
+// ExecuteTemplate renders the named page
+func (t pageTemplates) ExecuteTemplate(w io.Writer, name string, data interface{}) error {
+	set, ok := t[name]
+	if !ok {
+		return fmt.Errorf("template %s not found", name)
+	}
+	return set.ExecuteTemplate(w, name, data)
+}
+
+// newCookieStore returns the default session store, signed with SESSION_SECRET
+func newCookieStore() *sessions.CookieStore {
 	// Get session secret from environment variable
 	sessionSecret := os.Getenv("SESSION_SECRET")
 	if sessionSecret == "" {
@@ -57,11 +149,31 @@ func New(db *database.DB) *Handler {
 		SameSite: http.SameSiteStrictMode,
 	}
 
-	return &Handler{
-		db:        db,
-		templates: templates,
-		store:     store,
+	return store
+}
+
+// divide is the template function behind {{div a b}}; it returns 0 when b is 0
+func divide(a, b interface{}) float64 {
+	divisor := toFloat(b)
+	if divisor == 0 {
+		return 0
 	}
+	return toFloat(a) / divisor
+}
+
+// toFloat converts the numeric values templates pass around to float64
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	}
+	return 0
 }
 
 // Login handles user login
@@ -71,7 +183,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		username := r.FormValue("username")
 		password := r.FormValue("password")
 
-		user, err := h.getUserByUsername(username)
+		user, err := h.storage.Users().GetByUsername(username)
 		if err != nil || !checkPasswordHash(password, user.PasswordHash) {
 			http.Error(w, "Invalid username or password", http.StatusUnauthorized)
 			return
@@ -84,8 +196,8 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	// Set session values
 	session.Values["authenticated"] = true
 	session.Values["user_id"] = user.ID
-	session.Values["created_at"] = time.Now().Unix()
-	session.Values["login_time"] = time.Now().Format(time.RFC3339)
+	session.Values["created_at"] = h.now().Unix()
+	session.Values["login_time"] = h.now().Format(time.RFC3339)
 	
 	// Save session
 	err = session.Save(r, w)
@@ -132,11 +244,11 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 			Username:     username,
 			Email:        email,
 			PasswordHash: passwordHash,
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
+			CreatedAt:    h.now(),
+			UpdatedAt:    h.now(),
 		}
 
-		_, err = h.createUser(user)
+		_, err = h.storage.Users().Create(user)
 		if err != nil {
 			http.Error(w, "Failed to create user", http.StatusInternalServerError)
 			return
@@ -169,14 +281,14 @@ func (h *Handler) Home(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get recent workouts for the dashboard
-	workouts, err := h.getRecentWorkoutsByUser(userID, 5)
+	workouts, err := h.storage.Workouts().Recent(userID, 5)
 	if err != nil {
 		http.Error(w, "Failed to load workouts", http.StatusInternalServerError)
 		return
 	}
 
 	// Get workout statistics
-	stats, err := h.getWorkoutStats()
+	stats, err := h.getWorkoutStats(userID)
 	if err != nil {
 		http.Error(w, "Failed to load statistics", http.StatusInternalServerError)
 		return
@@ -212,7 +324,13 @@ func (h *Handler) GetWorkouts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	workouts, err := h.getAllWorkoutsByUser(userID)
+	params, err := parseWorkoutListParams(r, 50)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	workouts, nextCursor, err := h.storage.Workouts().List(userID, params)
 	if err != nil {
 		http.Error(w, "Failed to load workouts", http.StatusInternalServerError)
 		return
@@ -220,12 +338,16 @@ func (h *Handler) GetWorkouts(w http.ResponseWriter, r *http.Request) {
 
 	data := struct {
 		Workouts     []models.Workout
+		NextCursor   string
 		Title        string
 		UserSettings *models.UserSettings
+		CurrentPath  string
 	}{
 		Workouts:     workouts,
+		NextCursor:   nextCursor,
 		Title:        "All Workouts",
 		UserSettings: h.getUserSettingsForTemplate(r),
+		CurrentPath:  r.URL.Path,
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "workouts.html", data); err != nil {
@@ -269,15 +391,18 @@ func (h *Handler) CreateWorkout(w http.ResponseWriter, r *http.Request) {
 			Name:      name,
 			Date:      date,
 			Notes:     notes,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			CreatedAt: h.now(),
+			UpdatedAt: h.now(),
 		}
 
-		id, err := h.createWorkoutWithUser(workout, userID)
+		id, err := h.storage.Workouts().Create(workout, userID)
 		if err != nil {
 			http.Error(w, "Failed to create workout", http.StatusInternalServerError)
 			return
 		}
+		workout.ID = id
+		workout.UserID = userID
+		h.workoutLogged(r, workout)
 
 		// Redirect to the new workout
 		http.Redirect(w, r, "/workouts/"+strconv.Itoa(id), http.StatusSeeOther)
@@ -288,9 +413,11 @@ func (h *Handler) CreateWorkout(w http.ResponseWriter, r *http.Request) {
 	data := struct {
 		Title        string
 		UserSettings *models.UserSettings
+		CurrentPath  string
 	}{
 		Title:        "Create Workout",
 		UserSettings: h.getUserSettingsForTemplate(r),
+		CurrentPath:  r.URL.Path,
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "create_workout.html", data); err != nil {
@@ -317,7 +444,7 @@ func (h *Handler) GetWorkout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("GetWorkout - Fetching workout with ID: %d for user: %d", id, userID)
-	workout, err := h.getWorkoutByIDWithUser(id, userID)
+	workout, err := h.storage.Workouts().Get(id, userID)
 	if err != nil {
 		log.Printf("GetWorkout - Database error: %v", err)
 		http.Error(w, "Workout not found", http.StatusNotFound)
@@ -329,10 +456,12 @@ func (h *Handler) GetWorkout(w http.ResponseWriter, r *http.Request) {
 		Workout      models.Workout
 		Title        string
 		UserSettings *models.UserSettings
+		CurrentPath  string
 	}{
 		Workout:      workout,
 		Title:        "Workout: " + workout.Name,
 		UserSettings: h.getUserSettingsForTemplate(r),
+		CurrentPath:  r.URL.Path,
 	}
 
 	log.Printf("GetWorkout - About to render template with data: %+v", data)
@@ -364,6 +493,7 @@ func (h *Handler) UpdateWorkout(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method == "POST" || r.Method == "PUT" {
 		var name, dateStr, durationStr, notes string
+		var exercises []models.CreateWorkoutExerciseRequest
 		
 		if r.Method == "PUT" {
 			// Handle JSON for API requests
@@ -372,10 +502,14 @@ func (h *Handler) UpdateWorkout(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "Invalid JSON", http.StatusBadRequest)
 				return
 			}
+			if !validateRequest(w, req) {
+				return
+			}
 			name = req.Name
 			dateStr = req.Date
 			durationStr = strconv.Itoa(req.Duration)
 			notes = req.Notes
+			exercises = req.Exercises
 		} else {
 			// Handle form data for web requests
 			if err := r.ParseForm(); err != nil {
@@ -419,19 +553,32 @@ func (h *Handler) UpdateWorkout(w http.ResponseWriter, r *http.Request) {
 			Date:      date,
 			Duration:  duration,
 			Notes:     notes,
-			UpdatedAt: time.Now(),
+			UpdatedAt: h.now(),
+		}
+
+		if exercises != nil {
+			// A present exercises list replaces the stored tree in one transaction
+			workout.Exercises = exercisesFromRequest(exercises)
+			err = h.storage.Workouts().Replace(workout, userID)
+		} else {
+			err = h.storage.Workouts().Update(workout, userID)
+		}
+		if err != nil {
+			http.Error(w, "Workout not found", http.StatusNotFound)
+			return
 		}
 
-		err = h.updateWorkoutWithUser(workout, userID)
+		updated, err := h.storage.Workouts().Get(id, userID)
 		if err != nil {
-			http.Error(w, "Failed to update workout", http.StatusInternalServerError)
+			http.Error(w, "Workout updated but failed to retrieve", http.StatusInternalServerError)
 			return
 		}
+		h.publish(r, events.WorkoutUpdated, updated)
 
 		if r.Method == "PUT" {
-			// API request - return JSON
+			// API request - return the stored tree as JSON
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(workout)
+			json.NewEncoder(w).Encode(updated)
 		} else {
 			// Web form request - redirect
 			http.Redirect(w, r, "/workouts/"+strconv.Itoa(id), http.StatusSeeOther)
@@ -440,7 +587,7 @@ func (h *Handler) UpdateWorkout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// GET request - show edit form
-	workout, err := h.getWorkoutByID(id)
+	workout, err := h.storage.Workouts().Get(id, userID)
 	if err != nil {
 		http.Error(w, "Workout not found", http.StatusNotFound)
 		return
@@ -450,10 +597,12 @@ func (h *Handler) UpdateWorkout(w http.ResponseWriter, r *http.Request) {
 		Workout      models.Workout
 		Title        string
 		UserSettings *models.UserSettings
+		CurrentPath  string
 	}{
 		Workout:      workout,
 		Title:        "Edit Workout: " + workout.Name,
 		UserSettings: h.getUserSettingsForTemplate(r),
+		CurrentPath:  r.URL.Path,
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "edit_workout.html", data); err != nil {
@@ -490,13 +639,18 @@ func (h *Handler) DeleteWorkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.deleteWorkoutWithUser(id, userID)
+	err = h.storage.Workouts().Delete(id, userID)
 	if err != nil {
-		http.Error(w, "Failed to delete workout", http.StatusInternalServerError)
+		http.Error(w, "Workout not found", http.StatusNotFound)
 		return
 	}
+	h.publish(r, events.WorkoutDeleted, events.Deleted{ID: id})
 
-	http.Redirect(w, r, "/workouts", http.StatusSeeOther)
+	if r.Method == "DELETE" {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		http.Redirect(w, r, "/workouts", http.StatusSeeOther)
+	}
 }
 
 // CreateExercise handles exercise creation
@@ -512,21 +666,32 @@ func (h *Handler) CreateExercise(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
+	if !h.authorizeOwner(w, r, h.storage.Workouts().OwnerID, req.WorkoutID, "Workout not found") {
+		return
+	}
+
 	exercise := models.Exercise{
-		WorkoutID: req.WorkoutID,
-		Name:      req.Name,
-		Category:  req.Category,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		WorkoutID:            req.WorkoutID,
+		Name:                 req.Name,
+		Category:             req.Category,
+		PredefinedExerciseID: libraryLink(req.PredefinedExerciseID),
+		CreatedAt:            h.now(),
+		UpdatedAt:            h.now(),
 	}
 
-	id, err := h.createExercise(exercise)
+	id, err := h.storage.Exercises().Create(exercise)
 	if err != nil {
 		http.Error(w, "Failed to create exercise", http.StatusInternalServerError)
 		return
 	}
 
 	exercise.ID = id
+	h.publish(r, events.ExerciseCreated, exercise)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(exercise)
@@ -545,6 +710,14 @@ func (h *Handler) CreateSet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
+	if !h.authorizeOwner(w, r, h.storage.Exercises().OwnerID, req.ExerciseID, "Exercise not found") {
+		return
+	}
+
 	set := models.Set{
 		ExerciseID: req.ExerciseID,
 		SetNumber:  req.SetNumber,
@@ -553,17 +726,23 @@ func (h *Handler) CreateSet(w http.ResponseWriter, r *http.Request) {
 		Distance:   req.Distance,
 		Duration:   req.Duration,
 		RestTime:   req.RestTime,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		RPE:        req.RPE,
+		RIR:        req.RIR,
+		SetType:    models.SetTypeOrDefault(req.SetType),
+		Notes:      req.Notes,
+		CreatedAt:  h.now(),
+		UpdatedAt:  h.now(),
 	}
 
-	id, err := h.createSet(set)
+	id, err := h.storage.Exercises().CreateSet(set)
 	if err != nil {
 		http.Error(w, "Failed to create set", http.StatusInternalServerError)
 		return
 	}
 
 	set.ID = id
+	h.publish(r, events.SetCreated, set)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(set)
@@ -583,6 +762,10 @@ func (h *Handler) UpdateExercise(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizeOwner(w, r, h.storage.Exercises().OwnerID, id, "Exercise not found") {
+		return
+	}
+
 	var req models.UpdateExerciseRequest
 	if r.Header.Get("Content-Type") == "application/json" {
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -599,18 +782,24 @@ func (h *Handler) UpdateExercise(w http.ResponseWriter, r *http.Request) {
 		req.Category = r.FormValue("category")
 	}
 
+	if !validateSubmission(w, r, req) {
+		return
+	}
+
 	exercise := models.Exercise{
-		ID:        id,
-		Name:      req.Name,
-		Category:  req.Category,
-		UpdatedAt: time.Now(),
+		ID:                   id,
+		Name:                 req.Name,
+		Category:             req.Category,
+		PredefinedExerciseID: libraryLink(req.PredefinedExerciseID),
+		UpdatedAt:            h.now(),
 	}
 
-	err = h.updateExercise(exercise)
+	err = h.storage.Exercises().Update(exercise)
 	if err != nil {
 		http.Error(w, "Failed to update exercise", http.StatusInternalServerError)
 		return
 	}
+	h.publish(r, events.ExerciseUpdated, exercise)
 
 	if r.Header.Get("Content-Type") == "application/json" {
 		w.Header().Set("Content-Type", "application/json")
@@ -622,6 +811,90 @@ func (h *Handler) UpdateExercise(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ReorderExercises sets the order of a workout's exercises and groups them into supersets
+// and circuits. The request must list every exercise of the workout exactly once; exercises
+// sharing a non-zero group_id form a group and ungrouped exercises use 0.
+func (h *Handler) ReorderExercises(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid workout ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ReorderExercisesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	workout, err := h.storage.Workouts().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Workout not found", http.StatusNotFound)
+		return
+	}
+
+	if errs := validateExerciseOrder(workout.Exercises, req.Exercises); len(errs) > 0 {
+		writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", "Request validation failed", errs)
+		return
+	}
+
+	if err := h.storage.Exercises().Reorder(id, req.Exercises); err != nil {
+		log.Printf("Failed to reorder exercises: %v", err)
+		http.Error(w, "Failed to reorder exercises", http.StatusInternalServerError)
+		return
+	}
+
+	workout, err = h.storage.Workouts().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Failed to load workout", http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, events.ExercisesReordered, workout)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workout)
+}
+
+// validateExerciseOrder checks that an order lists each of the workout's exercises exactly once
+func validateExerciseOrder(exercises []models.Exercise, order []models.ExerciseOrder) []validation.FieldError {
+	remaining := make(map[int]bool, len(exercises))
+	for _, exercise := range exercises {
+		remaining[exercise.ID] = true
+	}
+
+	var errs []validation.FieldError
+	for i, entry := range order {
+		if !remaining[entry.ID] {
+			errs = append(errs, validation.FieldError{
+				Field:   fmt.Sprintf("exercises[%d].id", i),
+				Rule:    "member",
+				Message: "must be an exercise of this workout listed only once",
+			})
+			continue
+		}
+		delete(remaining, entry.ID)
+	}
+	if len(remaining) > 0 {
+		errs = append(errs, validation.FieldError{
+			Field:   "exercises",
+			Rule:    "complete",
+			Message: "must list every exercise of the workout",
+		})
+	}
+	return errs
+}
+
 // DeleteExercise handles exercise deletion
 func (h *Handler) DeleteExercise(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -636,13 +909,18 @@ func (h *Handler) DeleteExercise(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.deleteExercise(id)
+	if !h.authorizeOwner(w, r, h.storage.Exercises().OwnerID, id, "Exercise not found") {
+		return
+	}
+
+	err = h.storage.Exercises().Delete(id)
 	if err != nil {
 		http.Error(w, "Failed to delete exercise", http.StatusInternalServerError)
 		return
 	}
+	h.publish(r, events.ExerciseDeleted, events.Deleted{ID: id})
 
-	if r.Header.Get("Content-Type") == "application/json" {
+	if r.Method == "DELETE" || r.Header.Get("Content-Type") == "application/json" {
 		w.WriteHeader(http.StatusNoContent)
 	} else {
 		// Redirect back to workout page
@@ -665,12 +943,20 @@ func (h *Handler) UpdateSet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizeOwner(w, r, h.storage.Exercises().SetOwnerID, id, "Set not found") {
+		return
+	}
+
 	var req models.UpdateSetRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
 	set := models.Set{
 		ID:         id,
 		SetNumber:  req.SetNumber,
@@ -679,14 +965,19 @@ func (h *Handler) UpdateSet(w http.ResponseWriter, r *http.Request) {
 		Distance:   req.Distance,
 		Duration:   req.Duration,
 		RestTime:   req.RestTime,
-		UpdatedAt:  time.Now(),
+		RPE:        req.RPE,
+		RIR:        req.RIR,
+		SetType:    models.SetTypeOrDefault(req.SetType),
+		Notes:      req.Notes,
+		UpdatedAt:  h.now(),
 	}
 
-	err = h.updateSet(set)
+	err = h.storage.Exercises().UpdateSet(set)
 	if err != nil {
 		http.Error(w, "Failed to update set", http.StatusInternalServerError)
 		return
 	}
+	h.publish(r, events.SetUpdated, set)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(set)
@@ -706,11 +997,16 @@ func (h *Handler) DeleteSet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.deleteSet(id)
+	if !h.authorizeOwner(w, r, h.storage.Exercises().SetOwnerID, id, "Set not found") {
+		return
+	}
+
+	err = h.storage.Exercises().DeleteSet(id)
 	if err != nil {
 		http.Error(w, "Failed to delete set", http.StatusInternalServerError)
 		return
 	}
+	h.publish(r, events.SetDeleted, events.Deleted{ID: id})
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -746,31 +1042,72 @@ type contextKey string
 
 const UserIDKey contextKey = "user_id"
 
+// APITokenKey holds the personal access token used to authenticate a request, if any
+const APITokenKey contextKey = "api_token"
+
 // AuthMiddleware checks if user is authenticated and adds user ID to context
 func (h *Handler) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Debug: print all cookies received
-		log.Printf("Auth check - URL: %s, Cookies received: %v", r.URL.Path, r.Cookies())
-		session, err := h.store.Get(r, "session-name")
-		if err != nil {
-			log.Printf("Error getting session: %v", err)
-		}
-		auth, ok := session.Values["authenticated"]
-		userID, userIDOk := session.Values["user_id"].(int)
-		log.Printf("Auth check - URL: %s, Session exists: %v, Auth value: %v, Type: %T, UserID: %v, Session Values: %+v", r.URL.Path, ok, auth, auth, userID, session.Values)
-		if !ok || auth != true || !userIDOk {
+		authedRequest, status, message := h.authenticateRequest(r)
+		if status != 0 {
+			// Token clients get a status code; browsers are sent to the login page
+			if hasBearerToken(r) {
+				if status == http.StatusUnauthorized {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="workout-tracker"`)
+				}
+				http.Error(w, message, status)
+				return
+			}
 			log.Printf("Redirecting to login from %s", r.URL.Path)
 			http.Redirect(w, r, "/login", http.StatusFound)
 			return
 		}
-		// Add user ID to request context
-		ctx := context.WithValue(r.Context(), UserIDKey, userID)
-		r = r.WithContext(ctx)
-		log.Printf("Authentication successful for user %d, proceeding to %s", userID, r.URL.Path)
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, authedRequest)
 	})
 }
 
+// hasBearerToken reports whether a request carries an Authorization: Bearer header
+func hasBearerToken(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// authenticateRequest resolves the user behind a request from a bearer token or the session cookie.
+// On success it returns the request with the user ID in its context and a zero status;
+// otherwise it returns the HTTP status and message describing why access was refused.
+func (h *Handler) authenticateRequest(r *http.Request) (*http.Request, int, string) {
+	// Personal access tokens are checked before the session cookie
+	if hasBearerToken(r) {
+		token, err := h.authenticateAPIToken(strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")))
+		if err != nil {
+			log.Printf("API token rejected for %s: %v", r.URL.Path, err)
+			return r, http.StatusUnauthorized, "Invalid or revoked API token"
+		}
+		if token.Scope != "read_write" && !isReadOnlyMethod(r.Method) {
+			return r, http.StatusForbidden, "API token is read-only"
+		}
+		ctx := context.WithValue(r.Context(), UserIDKey, token.UserID)
+		ctx = context.WithValue(ctx, APITokenKey, token)
+		return r.WithContext(ctx), 0, ""
+	}
+
+	// Debug: print all cookies received
+	log.Printf("Auth check - URL: %s, Cookies received: %v", r.URL.Path, r.Cookies())
+	session, err := h.store.Get(r, "session-name")
+	if err != nil {
+		log.Printf("Error getting session: %v", err)
+	}
+	auth, ok := session.Values["authenticated"]
+	userID, userIDOk := session.Values["user_id"].(int)
+	log.Printf("Auth check - URL: %s, Session exists: %v, Auth value: %v, Type: %T, UserID: %v, Session Values: %+v", r.URL.Path, ok, auth, auth, userID, session.Values)
+	if !ok || auth != true || !userIDOk {
+		return r, http.StatusUnauthorized, "Authentication required"
+	}
+	// Add user ID to request context
+	ctx := context.WithValue(r.Context(), UserIDKey, userID)
+	log.Printf("Authentication successful for user %d, proceeding to %s", userID, r.URL.Path)
+	return r.WithContext(ctx), 0, ""
+}
+
 // Helper function to get user ID from context (preferred for authenticated requests)
 func (h *Handler) getUserIDFromContext(r *http.Request) (int, error) {
 	userID, ok := r.Context().Value(UserIDKey).(int)
@@ -801,11 +1138,33 @@ func (h *Handler) getCurrentUserID(r *http.Request) (int, error) {
 	return userID, nil
 }
 
+// authorizeOwner verifies that the current user owns the object with the given ID.
+// Missing and foreign objects both get a 404 so other users' IDs are not revealed.
+func (h *Handler) authorizeOwner(w http.ResponseWriter, r *http.Request, lookup func(int) (int, error), id int, notFound string) bool {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return false
+	}
+
+	ownerID, err := lookup(id)
+	if err == storage.ErrNotFound || (err == nil && ownerID != userID) {
+		http.Error(w, notFound, http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to resolve owner: %v", err)
+		http.Error(w, "Failed to verify ownership", http.StatusInternalServerError)
+		return false
+	}
+
+	return true
+}
+
 // Helper function to get user settings for templates
 func (h *Handler) getUserSettingsForTemplate(r *http.Request) *models.UserSettings {
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
 		return nil
 	}
 	
@@ -842,14 +1201,79 @@ func (h *Handler) APIHandler() http.Handler {
 
 // APIGetWorkouts returns workouts as JSON
 func (h *Handler) APIGetWorkouts(w http.ResponseWriter, r *http.Request) {
-	workouts, err := h.getAllWorkouts()
+	userID, err := h.getCurrentUserID(r)
 	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	params, err := parseWorkoutListParams(r, 20)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	workouts, nextCursor, err := h.storage.Workouts().List(userID, params)
+	if err != nil {
+		log.Printf("Failed to list workouts: %v", err)
+		http.Error(w, "Failed to load workouts", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storage.Workouts().AttachExercises(workouts); err != nil {
+		log.Printf("Failed to load exercises for workouts: %v", err)
 		http.Error(w, "Failed to load workouts", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(workouts)
+	json.NewEncoder(w).Encode(models.WorkoutListResponse{Workouts: workouts, NextCursor: nextCursor})
+}
+
+// parseWorkoutListParams reads and validates workout listing parameters from the query string
+func parseWorkoutListParams(r *http.Request, defaultLimit int) (models.WorkoutListParams, error) {
+	query := r.URL.Query()
+	params := models.WorkoutListParams{
+		Limit:    defaultLimit,
+		Cursor:   query.Get("cursor"),
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+		Exercise: strings.TrimSpace(query.Get("exercise")),
+		Category: strings.TrimSpace(query.Get("category")),
+		Query:    strings.TrimSpace(query.Get("q")),
+	}
+
+	if limitParam := query.Get("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > 100 {
+			return params, fmt.Errorf("limit must be between 1 and 100")
+		}
+		params.Limit = limit
+	}
+
+	for name, value := range map[string]string{"from": params.From, "to": params.To} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return params, fmt.Errorf("%s must be a date in YYYY-MM-DD format", name)
+		}
+	}
+
+	sort, err := storage.NormalizeWorkoutSort(query.Get("sort"))
+	if err != nil {
+		return params, fmt.Errorf("sort must be one of date, name or duration, optionally prefixed with -")
+	}
+	params.Sort = sort
+
+	if params.Cursor != "" {
+		cursor, err := storage.DecodeWorkoutCursor(params.Cursor)
+		if err != nil || cursor.Sort != params.Sort {
+			return params, fmt.Errorf("invalid cursor")
+		}
+	}
+
+	return params, nil
 }
 
 // APICreateWorkout creates a workout via API
@@ -860,6 +1284,10 @@ func (h *Handler) APICreateWorkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
 	// Parse date
 	date, err := time.Parse("2006-01-02", req.Date)
 	if err != nil {
@@ -867,25 +1295,76 @@ func (h *Handler) APICreateWorkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
 	workout := models.Workout{
 		Name:      req.Name,
 		Date:      date,
 		Duration:  req.Duration,
 		Notes:     req.Notes,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Exercises: exercisesFromRequest(req.Exercises),
+		CreatedAt: h.now(),
+		UpdatedAt: h.now(),
 	}
 
-	id, err := h.createWorkout(workout)
+	// Workout, exercises and sets are written in one transaction
+	id, err := h.storage.Workouts().Create(workout, userID)
 	if err != nil {
+		log.Printf("Failed to create workout: %v", err)
 		http.Error(w, "Failed to create workout", http.StatusInternalServerError)
 		return
 	}
 
-	workout.ID = id
+	// Return the stored tree so clients get every generated ID
+	created, err := h.storage.Workouts().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Workout created but failed to retrieve", http.StatusInternalServerError)
+		return
+	}
+	h.workoutLogged(r, created)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(workout)
+	json.NewEncoder(w).Encode(created)
+}
+
+// exercisesFromRequest converts nested exercise requests into models.
+// Sets without an explicit set number are numbered by their position.
+func exercisesFromRequest(reqs []models.CreateWorkoutExerciseRequest) []models.Exercise {
+	exercises := make([]models.Exercise, 0, len(reqs))
+	for index, exerciseReq := range reqs {
+		exercise := models.Exercise{
+			Name:                 exerciseReq.Name,
+			Category:             exerciseReq.Category,
+			OrderIndex:           index,
+			GroupID:              exerciseReq.GroupID,
+			PredefinedExerciseID: libraryLink(exerciseReq.PredefinedExerciseID),
+		}
+		for i, setReq := range exerciseReq.Sets {
+			setNumber := setReq.SetNumber
+			if setNumber == 0 {
+				setNumber = i + 1
+			}
+			exercise.Sets = append(exercise.Sets, models.Set{
+				SetNumber: setNumber,
+				Reps:      setReq.Reps,
+				Weight:    setReq.Weight,
+				Distance:  setReq.Distance,
+				Duration:  setReq.Duration,
+				RestTime:  setReq.RestTime,
+				RPE:       setReq.RPE,
+				RIR:       setReq.RIR,
+				SetType:   models.SetTypeOrDefault(setReq.SetType),
+				Notes:     setReq.Notes,
+			})
+		}
+		exercises = append(exercises, exercise)
+	}
+	return exercises
 }
 
 // APIGetWorkout returns a specific workout as JSON
@@ -897,7 +1376,13 @@ func (h *Handler) APIGetWorkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	workout, err := h.getWorkoutByID(id)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	workout, err := h.storage.Workouts().Get(id, userID)
 	if err != nil {
 		http.Error(w, "Workout not found", http.StatusNotFound)
 		return
@@ -942,6 +1427,10 @@ func (h *Handler) CreatePredefinedExercise(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
 	exercise := models.PredefinedExercise{
 		Name:         req.Name,
 		Category:     req.Category,
@@ -953,17 +1442,24 @@ func (h *Handler) CreatePredefinedExercise(w http.ResponseWriter, r *http.Reques
 		Equipment:    req.Equipment,
 		Difficulty:   req.Difficulty,
 		ImageURL:     req.ImageURL,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		CreatedAt:    h.now(),
+		UpdatedAt:    h.now(),
 	}
 
 	id, err := h.createPredefinedExercise(exercise)
 	if err != nil {
+		log.Printf("Failed to create predefined exercise: %v", err)
 		http.Error(w, "Failed to create predefined exercise", http.StatusInternalServerError)
 		return
 	}
 
 	exercise.ID = id
+	created := []models.PredefinedExercise{exercise}
+	if err := h.attachMuscles(created); err != nil {
+		http.Error(w, "Predefined exercise created but failed to retrieve", http.StatusInternalServerError)
+		return
+	}
+	exercise = created[0]
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(exercise)
@@ -976,10 +1472,9 @@ func (h *Handler) CreateMeal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -989,6 +1484,10 @@ func (h *Handler) CreateMeal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
 	// Parse date
 	date, err := time.Parse("2006-01-02", req.Date)
 	if err != nil {
@@ -1005,17 +1504,19 @@ func (h *Handler) CreateMeal(w http.ResponseWriter, r *http.Request) {
 		Fat:       req.Fat,
 		Date:      date,
 		MealType:  req.MealType,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: h.now(),
+		UpdatedAt: h.now(),
 	}
 
-	id, err := h.createMeal(meal)
+	id, err := h.storage.Meals().Create(meal)
 	if err != nil {
 		http.Error(w, "Failed to create meal", http.StatusInternalServerError)
 		return
 	}
 
 	meal.ID = id
+	h.publish(r, events.MealCreated, meal)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(meal)
@@ -1028,10 +1529,9 @@ func (h *Handler) CreateBodyWeight(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -1041,6 +1541,10 @@ func (h *Handler) CreateBodyWeight(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
 	// Parse date
 	date, err := time.Parse("2006-01-02", req.Date)
 	if err != nil {
@@ -1054,17 +1558,19 @@ func (h *Handler) CreateBodyWeight(w http.ResponseWriter, r *http.Request) {
 		Unit:      req.Unit,
 		Date:      date,
 		Notes:     req.Notes,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: h.now(),
+		UpdatedAt: h.now(),
 	}
 
-	id, err := h.createBodyWeight(bodyWeight)
+	id, err := h.storage.BodyMetrics().CreateWeight(bodyWeight)
 	if err != nil {
 		http.Error(w, "Failed to create body weight entry", http.StatusInternalServerError)
 		return
 	}
 
 	bodyWeight.ID = id
+	h.publish(r, events.BodyWeightCreated, bodyWeight)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(bodyWeight)
@@ -1077,10 +1583,9 @@ func (h *Handler) CreateBodyFat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -1090,6 +1595,10 @@ func (h *Handler) CreateBodyFat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
 	// Parse date
 	date, err := time.Parse("2006-01-02", req.Date)
 	if err != nil {
@@ -1103,17 +1612,19 @@ func (h *Handler) CreateBodyFat(w http.ResponseWriter, r *http.Request) {
 		Date:        date,
 		Measurement: req.Measurement,
 		Notes:       req.Notes,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   h.now(),
+		UpdatedAt:   h.now(),
 	}
 
-	id, err := h.createBodyFat(bodyFat)
+	id, err := h.storage.BodyMetrics().CreateBodyFat(bodyFat)
 	if err != nil {
 		http.Error(w, "Failed to create body fat entry", http.StatusInternalServerError)
 		return
 	}
 
 	bodyFat.ID = id
+	h.publish(r, events.BodyFatCreated, bodyFat)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(bodyFat)
@@ -1125,13 +1636,13 @@ type AnalyticsPage struct {
 	Analytics  models.AnalyticsData
 	ErrMsg     string
 	UserSettings *models.UserSettings
+	CurrentPath  string
 }
 
 func (h *Handler) Analytics(w http.ResponseWriter, r *http.Request) {
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -1147,6 +1658,7 @@ func (h *Handler) Analytics(w http.ResponseWriter, r *http.Request) {
 		Title:        "Analytics Dashboard",
 		Analytics:    *analyticsData,
 		UserSettings: h.getUserSettingsForTemplate(r),
+		CurrentPath:  r.URL.Path,
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "analytics.html", data); err != nil {
@@ -1158,10 +1670,9 @@ func (h *Handler) Analytics(w http.ResponseWriter, r *http.Request) {
 
 // AnalyticsAPI returns analytics data as JSON
 func (h *Handler) AnalyticsAPI(w http.ResponseWriter, r *http.Request) {
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -1185,10 +1696,9 @@ func (h *Handler) AnalyticsAPI(w http.ResponseWriter, r *http.Request) {
 
 // WeeklySummaryAPI returns weekly summary data as JSON
 func (h *Handler) WeeklySummaryAPI(w http.ResponseWriter, r *http.Request) {
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -1198,10 +1708,9 @@ func (h *Handler) WeeklySummaryAPI(w http.ResponseWriter, r *http.Request) {
 
 	// If no parameters provided, use current week
 	var year, week int
-	var err error
 
 	if yearParam == "" || weekParam == "" {
-		now := time.Now()
+		now := h.now()
 		year, week = now.ISOWeek()
 	} else {
 		year, err = strconv.Atoi(yearParam)
@@ -1230,10 +1739,9 @@ func (h *Handler) WeeklySummaryAPI(w http.ResponseWriter, r *http.Request) {
 
 // MonthlySummaryAPI returns monthly summary data as JSON
 func (h *Handler) MonthlySummaryAPI(w http.ResponseWriter, r *http.Request) {
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -1243,10 +1751,9 @@ func (h *Handler) MonthlySummaryAPI(w http.ResponseWriter, r *http.Request) {
 
 	// If no parameters provided, use current month
 	var year, month int
-	var err error
 
 	if yearParam == "" || monthParam == "" {
-		now := time.Now()
+		now := h.now()
 		year = now.Year()
 		month = int(now.Month())
 	} else {
@@ -1276,15 +1783,14 @@ func (h *Handler) MonthlySummaryAPI(w http.ResponseWriter, r *http.Request) {
 
 // Profile renders the user profile page
 func (h *Handler) Profile(w http.ResponseWriter, r *http.Request) {
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
 	// Get user information
-	user, err := h.getUserByID(userID)
+	user, err := h.storage.Users().GetByID(userID)
 	if err != nil {
 		log.Printf("Failed to get user: %v", err)
 		http.Error(w, "Failed to load user profile", http.StatusInternalServerError)
@@ -1292,14 +1798,14 @@ func (h *Handler) Profile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get recent workouts for the profile
-	workouts, err := h.getRecentWorkouts(5)
+	workouts, err := h.storage.Workouts().Recent(userID, 5)
 	if err != nil {
 		log.Printf("Failed to get recent workouts: %v", err)
 		// Don't error out, just continue without workouts
 	}
 
 	// Get workout statistics
-	stats, err := h.getWorkoutStats()
+	stats, err := h.getWorkoutStats(userID)
 	if err != nil {
 		log.Printf("Failed to get workout stats: %v", err)
 		// Provide default stats if there's an error
@@ -1316,12 +1822,14 @@ func (h *Handler) Profile(w http.ResponseWriter, r *http.Request) {
 		Stats        map[string]interface{}
 		Title        string
 		UserSettings *models.UserSettings
+		CurrentPath  string
 	}{
 		User:         &user,
 		Workouts:     workouts,
 		Stats:        stats,
 		Title:        "Profile",
 		UserSettings: h.getUserSettingsForTemplate(r),
+		CurrentPath:  r.URL.Path,
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "profile.html", data); err != nil {
@@ -1338,10 +1846,9 @@ func (h *Handler) CreateBodyMeasurement(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -1351,6 +1858,10 @@ func (h *Handler) CreateBodyMeasurement(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
 	// Parse date
 	date, err := time.Parse("2006-01-02", req.Date)
 	if err != nil {
@@ -1365,17 +1876,19 @@ func (h *Handler) CreateBodyMeasurement(w http.ResponseWriter, r *http.Request)
 		Unit:        req.Unit,
 		Date:        date,
 		Notes:       req.Notes,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   h.now(),
+		UpdatedAt:   h.now(),
 	}
 
-	id, err := h.createBodyMeasurement(bodyMeasurement)
+	id, err := h.storage.BodyMetrics().CreateMeasurement(bodyMeasurement)
 	if err != nil {
 		http.Error(w, "Failed to create body measurement entry", http.StatusInternalServerError)
 		return
 	}
 
 	bodyMeasurement.ID = id
+	h.publish(r, events.BodyMeasurementCreated, bodyMeasurement)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(bodyMeasurement)
@@ -1390,11 +1903,15 @@ func (h *Handler) ExerciseLibrary(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := struct {
-		Exercises []models.PredefinedExercise
-		Title     string
+		Exercises    []models.PredefinedExercise
+		Title        string
+		UserSettings *models.UserSettings
+		CurrentPath  string
 	}{
-		Exercises: exercises,
-		Title:     "Exercise Library",
+		Exercises:    exercises,
+		Title:        "Exercise Library",
+		UserSettings: h.getUserSettingsForTemplate(r),
+		CurrentPath:  r.URL.Path,
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "exercise_library.html", data); err != nil {
@@ -1405,22 +1922,21 @@ func (h *Handler) ExerciseLibrary(w http.ResponseWriter, r *http.Request) {
 
 // ProgressStats displays progress statistics
 func (h *Handler) ProgressStats(w http.ResponseWriter, r *http.Request) {
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
 	// Get workout stats
-	workoutStats, err := h.getWorkoutStats()
+	workoutStats, err := h.getWorkoutStats(userID)
 	if err != nil {
 		http.Error(w, "Failed to load workout statistics", http.StatusInternalServerError)
 		return
 	}
 
 	// Get body weight data
-	bodyWeights, err := h.getBodyWeightsByUser(userID)
+	bodyWeights, err := h.storage.BodyMetrics().WeightsByUser(userID)
 	if err != nil {
 		http.Error(w, "Failed to load body weight data", http.StatusInternalServerError)
 		return
@@ -1444,16 +1960,15 @@ func (h *Handler) ProgressStats(w http.ResponseWriter, r *http.Request) {
 
 // LogMeals displays the meal logging page
 func (h *Handler) LogMeals(w http.ResponseWriter, r *http.Request) {
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
 	// Get today's meals
-	today := time.Now()
-	meals, err := h.getMealsByUserAndDate(userID, today)
+	today := h.now()
+	meals, err := h.storage.Meals().ByUserAndDate(userID, today)
 	if err != nil {
 		http.Error(w, "Failed to load meals", http.StatusInternalServerError)
 		return
@@ -1477,14 +1992,13 @@ func (h *Handler) LogMeals(w http.ResponseWriter, r *http.Request) {
 
 // BodyWeight displays the body weight tracking page
 func (h *Handler) BodyWeight(w http.ResponseWriter, r *http.Request) {
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
-	bodyWeights, err := h.getBodyWeightsByUser(userID)
+	bodyWeights, err := h.storage.BodyMetrics().WeightsByUser(userID)
 	if err != nil {
 		http.Error(w, "Failed to load body weights", http.StatusInternalServerError)
 		return
@@ -1506,14 +2020,13 @@ func (h *Handler) BodyWeight(w http.ResponseWriter, r *http.Request) {
 
 // BodyFatPage displays the body fat tracking page
 func (h *Handler) BodyFatPage(w http.ResponseWriter, r *http.Request) {
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
-	bodyFats, err := h.getBodyFatsByUser(userID)
+	bodyFats, err := h.storage.BodyMetrics().BodyFatsByUser(userID)
 	if err != nil {
 		http.Error(w, "Failed to load body fat data", http.StatusInternalServerError)
 		return
@@ -1535,14 +2048,13 @@ func (h *Handler) BodyFatPage(w http.ResponseWriter, r *http.Request) {
 
 // BodyMeasurements displays the body measurements tracking page
 func (h *Handler) BodyMeasurements(w http.ResponseWriter, r *http.Request) {
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
-	bodyMeasurements, err := h.getBodyMeasurementsByUser(userID)
+	bodyMeasurements, err := h.storage.BodyMetrics().MeasurementsByUser(userID)
 	if err != nil {
 		http.Error(w, "Failed to load body measurements", http.StatusInternalServerError)
 		return
@@ -1590,15 +2102,14 @@ func (h *Handler) TemplateDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
 	// Get template with exercises
-	template, err := h.getWorkoutTemplateByID(templateID, userID)
+	template, err := h.storage.Templates().Get(templateID, userID)
 	if err != nil {
 		http.Error(w, "Template not found", http.StatusNotFound)
 		return
@@ -1632,15 +2143,14 @@ func (h *Handler) TemplateEdit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
 	// Get template with exercises
-	template, err := h.getWorkoutTemplateByID(templateID, userID)
+	template, err := h.storage.Templates().Get(templateID, userID)
 	if err != nil {
 		http.Error(w, "Template not found", http.StatusNotFound)
 		return
@@ -1694,15 +2204,13 @@ func (h *Handler) ProgramDetails(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Authentication check for protected content
-	session, _ := h.store.Get(r, "session-name")
-	_, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	if _, err := h.getCurrentUserID(r); err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
 	// Get program with templates
-	program, err := h.getWorkoutProgramByID(programID)
+	program, err := h.storage.Programs().Get(programID)
 	if err != nil {
 		http.Error(w, "Program not found", http.StatusNotFound)
 		return
@@ -1736,22 +2244,21 @@ func (h *Handler) ProgramEdit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
 	// Get program with templates
-	program, err := h.getWorkoutProgramByID(programID)
+	program, err := h.storage.Programs().Get(programID)
 	if err != nil {
 		http.Error(w, "Program not found", http.StatusNotFound)
 		return
 	}
 
 	// Get available templates for dropdown
-	availableTemplates, err := h.getWorkoutTemplatesByUserID(userID)
+	availableTemplates, err := h.storage.Templates().ListByUser(userID)
 	if err != nil {
 		log.Printf("Failed to load available templates: %v", err)
 		availableTemplates = []models.WorkoutTemplate{} // Continue with empty list
@@ -1763,14 +2270,14 @@ func (h *Handler) ProgramEdit(w http.ResponseWriter, r *http.Request) {
 	data := struct {
 		Program                *models.WorkoutProgram
 		AvailableTemplates     []models.WorkoutTemplate
-		AvailableTemplatesJSON string
+		AvailableTemplatesJSON template.JS
 		Title                  string
 		UserSettings           *models.UserSettings
 		CurrentPath            string
 	}{
 		Program:                &program,
 		AvailableTemplates:     availableTemplates,
-		AvailableTemplatesJSON: string(availableTemplatesJSON),
+		AvailableTemplatesJSON: template.JS(availableTemplatesJSON),
 		Title:                  "Edit " + program.Name,
 		UserSettings:           h.getUserSettingsForTemplate(r),
 		CurrentPath:            r.URL.Path,
@@ -1785,18 +2292,21 @@ func (h *Handler) ProgramEdit(w http.ResponseWriter, r *http.Request) {
 
 // AccountSettings displays the account settings page
 func (h *Handler) AccountSettings(w http.ResponseWriter, r *http.Request) {
-	session, _ := h.store.Get(r, "session-name")
-	log.Printf("ACCOUNT_SETTINGS - All session values: %+v", session.Values)
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		log.Printf("ACCOUNT_SETTINGS - User ID not found in session or wrong type")
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		log.Printf("ACCOUNT_SETTINGS - User ID not found: %v", err)
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 	log.Printf("ACCOUNT_SETTINGS - Retrieved user ID: %d", userID)
 
+	h.renderAccountSettings(w, r, userID, nil)
+}
+
+// renderAccountSettings renders the account settings page, optionally showing a newly created token
+func (h *Handler) renderAccountSettings(w http.ResponseWriter, r *http.Request, userID int, newToken *models.CreateAPITokenResponse) {
 	// Get user profile
-	user, err := h.getUserByID(userID)
+	user, err := h.storage.Users().GetByID(userID)
 	if err != nil {
 		http.Error(w, "Failed to load user profile", http.StatusInternalServerError)
 		return
@@ -1810,17 +2320,30 @@ func (h *Handler) AccountSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-data := struct {
-		User     models.User
-		Settings models.UserSettings
-		Title    string
-	}{
-		User:     user,
-		Settings: settings,
-		Title:    "Account Settings",
+	tokens, err := h.getAPITokensByUser(userID)
+	if err != nil {
+		log.Printf("Failed to get API tokens: %v", err)
+		http.Error(w, "Failed to load API tokens", http.StatusInternalServerError)
+		return
 	}
 
-	log.Printf("AccountSettings Data: %+v", data)
+	data := struct {
+		User         models.User
+		Settings     models.UserSettings
+		APITokens    []models.APIToken
+		NewToken     *models.CreateAPITokenResponse
+		Title        string
+		UserSettings *models.UserSettings
+		CurrentPath  string
+	}{
+		User:         user,
+		Settings:     settings,
+		APITokens:    tokens,
+		NewToken:     newToken,
+		Title:        "Account Settings",
+		UserSettings: &settings,
+		CurrentPath:  r.URL.Path,
+	}
 
 	if err := h.templates.ExecuteTemplate(w, "account_settings.html", data); err != nil {
 		log.Printf("Template execution error: %v", err)
@@ -1836,10 +2359,9 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -1860,7 +2382,11 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		req.Bio = r.FormValue("bio")
 	}
 
-	err := h.updateUserProfile(userID, req)
+	if !validateSubmission(w, r, req) {
+		return
+	}
+
+	err = h.storage.Users().UpdateProfile(userID, req)
 	if err != nil {
 		log.Printf("Failed to update profile: %v", err)
 		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
@@ -1882,10 +2408,9 @@ func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -1908,6 +2433,7 @@ func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		req.Notifications = r.FormValue("notifications") == "true"
 		req.PrivacyMode = r.FormValue("privacy_mode") == "true"
 		req.Language = r.FormValue("language")
+		req.E1RMFormula = r.FormValue("e1rm_formula")
 		
 		// Parse auto logout
 		autoLogoutStr := r.FormValue("auto_logout")
@@ -1921,12 +2447,22 @@ func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	err := h.updateUserSettings(userID, req)
+	if req.E1RMFormula != "" {
+		formula, err := e1rm.ParseFormula(req.E1RMFormula)
+		if err != nil {
+			http.Error(w, "Invalid e1RM formula", http.StatusBadRequest)
+			return
+		}
+		req.E1RMFormula = string(formula)
+	}
+
+	err = h.updateUserSettings(userID, req)
 	if err != nil {
 		log.Printf("Failed to update settings: %v", err)
 		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
 		return
 	}
+	h.rescheduleReminders(userID)
 
 	if r.Header.Get("Content-Type") == "application/json" {
 		w.Header().Set("Content-Type", "application/json")
@@ -1943,10 +2479,9 @@ func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -1966,6 +2501,10 @@ func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		req.ConfirmPassword = r.FormValue("confirm_password")
 	}
 
+	if !validateSubmission(w, r, req) {
+		return
+	}
+
 	// Validate passwords match
 	if req.NewPassword != req.ConfirmPassword {
 		http.Error(w, "New passwords do not match", http.StatusBadRequest)
@@ -1973,7 +2512,7 @@ func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get current user to verify password
-	user, err := h.getUserByID(userID)
+	user, err := h.storage.Users().GetByID(userID)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
@@ -1993,7 +2532,7 @@ func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update password
-	err = h.updateUserPassword(userID, newPasswordHash)
+	err = h.storage.Users().UpdatePassword(userID, newPasswordHash)
 	if err != nil {
 		log.Printf("Failed to update password: %v", err)
 		http.Error(w, "Failed to update password", http.StatusInternalServerError)
@@ -2015,10 +2554,9 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -2037,6 +2575,10 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		req.ConfirmDeletion = r.FormValue("confirm_deletion")
 	}
 
+	if !validateSubmission(w, r, req) {
+		return
+	}
+
 	// Validate confirmation
 	if req.ConfirmDeletion != "DELETE" {
 		http.Error(w, "Must type DELETE to confirm account deletion", http.StatusBadRequest)
@@ -2044,7 +2586,7 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get current user to verify password
-	user, err := h.getUserByID(userID)
+	user, err := h.storage.Users().GetByID(userID)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
@@ -2057,7 +2599,7 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete account
-	err = h.deleteUserAccount(userID)
+	err = h.storage.Users().Delete(userID)
 	if err != nil {
 		log.Printf("Failed to delete account: %v", err)
 		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
@@ -2065,6 +2607,7 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Clear session
+	session, _ := h.store.Get(r, "session-name")
 	session.Values["authenticated"] = false
 	delete(session.Values, "user_id")
 	session.Save(r, w)
@@ -2079,10 +2622,9 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 
 // GetExerciseProgressChart returns exercise-specific progress chart data as JSON
 func (h *Handler) GetExerciseProgressChart(w http.ResponseWriter, r *http.Request) {
-	session, _ := h.store.Get(r, "session-name")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -2103,7 +2645,7 @@ func (h *Handler) GetExerciseProgressChart(w http.ResponseWriter, r *http.Reques
 
 	// Default to last 90 days if no date range provided
 	if startDate == "" || endDate == "" {
-		now := time.Now()
+		now := h.now()
 		startDate = now.AddDate(0, 0, -90).Format("2006-01-02")
 		endDate = now.Format("2006-01-02")
 	}
@@ -2133,10 +2675,9 @@ func (h *Handler) GetExerciseProgressChart(w http.ResponseWriter, r *http.Reques
 // GetExerciseList returns a list of exercises performed by the user
 func (h *Handler) GetExerciseList(w http.ResponseWriter, r *http.Request) {
 	// Authentication check for protected content
-	session, _ := h.store.Get(r, "session-name")
-	_, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "User not found in session", http.StatusUnauthorized)
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
 		return
 	}
 
@@ -2146,26 +2687,23 @@ func (h *Handler) GetExerciseList(w http.ResponseWriter, r *http.Request) {
 
 	// Default to last 365 days if no date range provided
 	if startDate == "" || endDate == "" {
-		now := time.Now()
+		now := h.now()
 		startDate = now.AddDate(-1, 0, 0).Format("2006-01-02")
 		endDate = now.Format("2006-01-02")
 	}
 
 	// Query for unique exercise names within the date range
-	// Note: For now, we don't filter by user since the workouts table doesn't have user_id
-	// This should be updated when user-specific workouts are implemented
 	query := `
 		SELECT DISTINCT e.name, e.category, COUNT(*) as frequency,
 		       MAX(w.date) as last_performed
 		FROM exercises e
 		JOIN workouts w ON e.workout_id = w.id
-		WHERE w.date >= ? AND w.date <= ?
+		WHERE w.user_id = ? AND w.date >= ? AND w.date <= ?
 		GROUP BY e.name, e.category
 		ORDER BY frequency DESC, e.name
 	`
 
-	// TODO: Add user_id to workouts table and filter by userID
-	rows, err := h.db.Query(query, startDate, endDate)
+	rows, err := h.db.Query(query, userID, startDate, endDate)
 	if err != nil {
 		log.Printf("Failed to query exercise list: %v", err)
 		http.Error(w, "Failed to load exercise list", http.StatusInternalServerError)
@@ -2183,11 +2721,13 @@ func (h *Handler) GetExerciseList(w http.ResponseWriter, r *http.Request) {
 	var exercises []ExerciseInfo
 	for rows.Next() {
 		var ex ExerciseInfo
-		err := rows.Scan(&ex.Name, &ex.Category, &ex.Frequency, &ex.LastPerformed)
+		var lastPerformed database.Timestamp
+		err := rows.Scan(&ex.Name, &ex.Category, &ex.Frequency, &lastPerformed)
 		if err != nil {
 			log.Printf("Error scanning exercise row: %v", err)
 			continue
 		}
+		ex.LastPerformed = lastPerformed.Time
 		exercises = append(exercises, ex)
 	}
 
@@ -2211,7 +2751,7 @@ func (h *Handler) GetWorkoutTemplates(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	templates, err := h.getWorkoutTemplatesByUserID(userID)
+	templates, err := h.storage.Templates().ListByUser(userID)
 	if err != nil {
 		log.Printf("Failed to get workout templates: %v", err)
 		http.Error(w, "Failed to load workout templates", http.StatusInternalServerError)
@@ -2237,7 +2777,7 @@ func (h *Handler) GetWorkoutTemplate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	template, err := h.getWorkoutTemplateByID(templateID, userID)
+	template, err := h.storage.Templates().Get(templateID, userID)
 	if err != nil {
 		log.Printf("Failed to get workout template: %v", err)
 		http.Error(w, "Workout template not found", http.StatusNotFound)
@@ -2267,15 +2807,19 @@ func (h *Handler) CreateWorkoutTemplate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
 	template := models.WorkoutTemplate{
 		UserID:      userID,
 		Name:        req.Name,
 		Description: req.Description,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   h.now(),
+		UpdatedAt:   h.now(),
 	}
 
-	id, err := h.createWorkoutTemplate(template)
+	id, err := h.storage.Templates().Create(template)
 	if err != nil {
 		log.Printf("Failed to create workout template: %v", err)
 		http.Error(w, "Failed to create workout template", http.StatusInternalServerError)
@@ -2289,15 +2833,16 @@ func (h *Handler) CreateWorkoutTemplate(w http.ResponseWriter, r *http.Request)
 			Name:         exerciseReq.Name,
 			Category:     exerciseReq.Category,
 			OrderIndex:   index,
+			GroupID:      exerciseReq.GroupID,
 			TargetSets:   exerciseReq.TargetSets,
 			TargetReps:   exerciseReq.TargetReps,
 			TargetWeight: exerciseReq.TargetWeight,
 			RestTime:     exerciseReq.RestTime,
 			Notes:        exerciseReq.Notes,
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
+			CreatedAt:    h.now(),
+			UpdatedAt:    h.now(),
 		}
-		_, err := h.createTemplateExercise(exercise)
+		_, err := h.storage.Templates().CreateExercise(exercise)
 		if err != nil {
 			log.Printf("Failed to create template exercise: %v", err)
 			// Continue with other exercises even if one fails
@@ -2305,7 +2850,7 @@ func (h *Handler) CreateWorkoutTemplate(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Return the created template with exercises
-	createdTemplate, err := h.getWorkoutTemplateByID(id, userID)
+	createdTemplate, err := h.storage.Templates().Get(id, userID)
 	if err != nil {
 		log.Printf("Failed to get created template: %v", err)
 		http.Error(w, "Template created but failed to retrieve", http.StatusInternalServerError)
@@ -2343,8 +2888,12 @@ func (h *Handler) UpdateWorkoutTemplate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
 	// Verify template belongs to user
-	_, err = h.getWorkoutTemplateByID(templateID, userID)
+	_, err = h.storage.Templates().Get(templateID, userID)
 	if err != nil {
 		http.Error(w, "Template not found", http.StatusNotFound)
 		return
@@ -2355,10 +2904,10 @@ func (h *Handler) UpdateWorkoutTemplate(w http.ResponseWriter, r *http.Request)
 		UserID:      userID,
 		Name:        req.Name,
 		Description: req.Description,
-		UpdatedAt:   time.Now(),
+		UpdatedAt:   h.now(),
 	}
 
-	err = h.updateWorkoutTemplate(template)
+	err = h.storage.Templates().Update(template)
 	if err != nil {
 		log.Printf("Failed to update workout template: %v", err)
 		http.Error(w, "Failed to update workout template", http.StatusInternalServerError)
@@ -2368,7 +2917,7 @@ func (h *Handler) UpdateWorkoutTemplate(w http.ResponseWriter, r *http.Request)
 	// Update exercises if provided
 	if req.Exercises != nil {
 		// Delete existing exercises
-		err = h.deleteTemplateExercisesByTemplateID(templateID)
+		err = h.storage.Templates().DeleteExercises(templateID)
 		if err != nil {
 			log.Printf("Failed to delete existing template exercises: %v", err)
 		}
@@ -2380,15 +2929,16 @@ func (h *Handler) UpdateWorkoutTemplate(w http.ResponseWriter, r *http.Request)
 				Name:         exerciseReq.Name,
 				Category:     exerciseReq.Category,
 				OrderIndex:   i,
+				GroupID:      exerciseReq.GroupID,
 				TargetSets:   exerciseReq.TargetSets,
 				TargetReps:   exerciseReq.TargetReps,
 				TargetWeight: exerciseReq.TargetWeight,
 				RestTime:     exerciseReq.RestTime,
 				Notes:        exerciseReq.Notes,
-				CreatedAt:    time.Now(),
-				UpdatedAt:    time.Now(),
+				CreatedAt:    h.now(),
+				UpdatedAt:    h.now(),
 			}
-			_, err := h.createTemplateExercise(exercise)
+			_, err := h.storage.Templates().CreateExercise(exercise)
 			if err != nil {
 				log.Printf("Failed to create template exercise: %v", err)
 			}
@@ -2396,7 +2946,7 @@ func (h *Handler) UpdateWorkoutTemplate(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Return updated template
-	updatedTemplate, err := h.getWorkoutTemplateByID(templateID, userID)
+	updatedTemplate, err := h.storage.Templates().Get(templateID, userID)
 	if err != nil {
 		log.Printf("Failed to get updated template: %v", err)
 		http.Error(w, "Template updated but failed to retrieve", http.StatusInternalServerError)
@@ -2428,13 +2978,13 @@ func (h *Handler) DeleteWorkoutTemplate(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Verify template belongs to user
-	_, err = h.getWorkoutTemplateByID(templateID, userID)
+	_, err = h.storage.Templates().Get(templateID, userID)
 	if err != nil {
 		http.Error(w, "Template not found", http.StatusNotFound)
 		return
 	}
 
-	err = h.deleteWorkoutTemplate(templateID)
+	err = h.storage.Templates().Delete(templateID)
 	if err != nil {
 		log.Printf("Failed to delete workout template: %v", err)
 		http.Error(w, "Failed to delete workout template", http.StatusInternalServerError)
@@ -2448,7 +2998,7 @@ func (h *Handler) DeleteWorkoutTemplate(w http.ResponseWriter, r *http.Request)
 
 // GetWorkoutPrograms returns all workout programs
 func (h *Handler) GetWorkoutPrograms(w http.ResponseWriter, r *http.Request) {
-	programs, err := h.getAllWorkoutPrograms()
+	programs, err := h.storage.Programs().ListPublic()
 	if err != nil {
 		log.Printf("Failed to get workout programs: %v", err)
 		http.Error(w, "Failed to load workout programs", http.StatusInternalServerError)
@@ -2468,7 +3018,7 @@ func (h *Handler) GetWorkoutProgram(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	program, err := h.getWorkoutProgramByID(programID)
+	program, err := h.storage.Programs().Get(programID)
 	if err != nil {
 		log.Printf("Failed to get workout program: %v", err)
 		http.Error(w, "Workout program not found", http.StatusNotFound)
@@ -2498,6 +3048,10 @@ func (h *Handler) CreateWorkoutProgram(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
 	program := models.WorkoutProgram{
 		Name:          req.Name,
 		Description:   req.Description,
@@ -2506,11 +3060,11 @@ func (h *Handler) CreateWorkoutProgram(w http.ResponseWriter, r *http.Request) {
 		Goal:          req.Goal,
 		IsPublic:      req.IsPublic,
 		CreatedBy:     userID,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		CreatedAt:     h.now(),
+		UpdatedAt:     h.now(),
 	}
 
-	id, err := h.createWorkoutProgram(program)
+	id, err := h.storage.Programs().Create(program)
 	if err != nil {
 		log.Printf("Failed to create workout program: %v", err)
 		http.Error(w, "Failed to create workout program", http.StatusInternalServerError)
@@ -2525,9 +3079,9 @@ func (h *Handler) CreateWorkoutProgram(w http.ResponseWriter, r *http.Request) {
 			DayOfWeek:  templateReq.DayOfWeek,
 			WeekNumber: templateReq.WeekNumber,
 			OrderIndex: templateReq.OrderIndex,
-			CreatedAt:  time.Now(),
+			CreatedAt:  h.now(),
 		}
-		_, err := h.createProgramTemplate(programTemplate)
+		_, err := h.storage.Programs().AddTemplate(programTemplate)
 		if err != nil {
 			log.Printf("Failed to create program template: %v", err)
 			// Continue with other templates even if one fails
@@ -2535,7 +3089,7 @@ func (h *Handler) CreateWorkoutProgram(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Return the created program with templates
-	createdProgram, err := h.getWorkoutProgramByID(id)
+	createdProgram, err := h.storage.Programs().Get(id)
 	if err != nil {
 		log.Printf("Failed to get created program: %v", err)
 		http.Error(w, "Program created but failed to retrieve", http.StatusInternalServerError)
@@ -2573,8 +3127,12 @@ func (h *Handler) UpdateWorkoutProgram(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !validateRequest(w, req) {
+		return
+	}
+
 	// Verify program exists and user has permission to edit
-	existingProgram, err := h.getWorkoutProgramByID(programID)
+	existingProgram, err := h.storage.Programs().Get(programID)
 	if err != nil {
 		http.Error(w, "Program not found", http.StatusNotFound)
 		return
@@ -2595,10 +3153,10 @@ func (h *Handler) UpdateWorkoutProgram(w http.ResponseWriter, r *http.Request) {
 		Goal:          req.Goal,
 		IsPublic:      req.IsPublic,
 		CreatedBy:     existingProgram.CreatedBy,
-		UpdatedAt:     time.Now(),
+		UpdatedAt:     h.now(),
 	}
 
-	err = h.updateWorkoutProgram(program)
+	err = h.storage.Programs().Update(program)
 	if err != nil {
 		log.Printf("Failed to update workout program: %v", err)
 		http.Error(w, "Failed to update workout program", http.StatusInternalServerError)
@@ -2608,7 +3166,7 @@ func (h *Handler) UpdateWorkoutProgram(w http.ResponseWriter, r *http.Request) {
 	// Update program templates if provided
 	if req.Templates != nil {
 		// Delete existing program templates
-		err = h.deleteProgramTemplatesByProgramID(programID)
+		err = h.storage.Programs().DeleteTemplates(programID)
 		if err != nil {
 			log.Printf("Failed to delete existing program templates: %v", err)
 		}
@@ -2621,9 +3179,9 @@ func (h *Handler) UpdateWorkoutProgram(w http.ResponseWriter, r *http.Request) {
 				DayOfWeek:  templateReq.DayOfWeek,
 				WeekNumber: templateReq.WeekNumber,
 				OrderIndex: templateReq.OrderIndex,
-				CreatedAt:  time.Now(),
+				CreatedAt:  h.now(),
 			}
-			_, err := h.createProgramTemplate(programTemplate)
+			_, err := h.storage.Programs().AddTemplate(programTemplate)
 			if err != nil {
 				log.Printf("Failed to create program template: %v", err)
 			}
@@ -2631,7 +3189,7 @@ func (h *Handler) UpdateWorkoutProgram(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Return updated program
-	updatedProgram, err := h.getWorkoutProgramByID(programID)
+	updatedProgram, err := h.storage.Programs().Get(programID)
 	if err != nil {
 		log.Printf("Failed to get updated program: %v", err)
 		http.Error(w, "Program updated but failed to retrieve", http.StatusInternalServerError)
@@ -2663,7 +3221,7 @@ func (h *Handler) DeleteWorkoutProgram(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify program exists and user has permission to delete
-	existingProgram, err := h.getWorkoutProgramByID(programID)
+	existingProgram, err := h.storage.Programs().Get(programID)
 	if err != nil {
 		http.Error(w, "Program not found", http.StatusNotFound)
 		return
@@ -2675,7 +3233,7 @@ func (h *Handler) DeleteWorkoutProgram(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.deleteWorkoutProgram(programID)
+	err = h.storage.Programs().Delete(programID)
 	if err != nil {
 		log.Printf("Failed to delete workout program: %v", err)
 		http.Error(w, "Failed to delete workout program", http.StatusInternalServerError)
@@ -2712,7 +3270,7 @@ func (h *Handler) ShareWorkoutTemplate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify template belongs to user
-	_, err = h.getWorkoutTemplateByID(templateID, userID)
+	_, err = h.storage.Templates().Get(templateID, userID)
 	if err != nil {
 		http.Error(w, "Template not found", http.StatusNotFound)
 		return
@@ -2723,10 +3281,10 @@ func (h *Handler) ShareWorkoutTemplate(w http.ResponseWriter, r *http.Request) {
 		OwnerID:      userID,
 		SharedWithID: req.SharedWithID,
 		Permission:   req.Permission,
-		CreatedAt:    time.Now(),
+		CreatedAt:    h.now(),
 	}
 
-	_, err = h.createTemplateSharing(sharing)
+	_, err = h.storage.Templates().Share(sharing)
 	if err != nil {
 		log.Printf("Failed to share template: %v", err)
 		http.Error(w, "Failed to share template", http.StatusInternalServerError)
@@ -2746,7 +3304,7 @@ func (h *Handler) GetSharedTemplates(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	templates, err := h.getSharedTemplates(userID)
+	templates, err := h.storage.Templates().SharedWith(userID)
 	if err != nil {
 		log.Printf("Failed to get shared templates: %v", err)
 		http.Error(w, "Failed to load shared templates", http.StatusInternalServerError)
@@ -2787,7 +3345,7 @@ func (h *Handler) CreateWorkoutFromTemplate(w http.ResponseWriter, r *http.Reque
 	req.TemplateID = templateID
 
 	// Get the template (check if user owns it or has access to shared template)
-	template, err := h.getWorkoutTemplateByID(templateID, userID)
+	template, err := h.storage.Templates().Get(templateID, userID)
 	if err != nil {
 		http.Error(w, "Template not found or access denied", http.StatusNotFound)
 		return
@@ -2811,11 +3369,11 @@ func (h *Handler) CreateWorkoutFromTemplate(w http.ResponseWriter, r *http.Reque
 		Name:      workoutName,
 		Date:      workoutDate,
 		Notes:     req.Notes,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: h.now(),
+		UpdatedAt: h.now(),
 	}
 
-	workoutID, err := h.createWorkoutWithUser(workout, userID)
+	workoutID, err := h.storage.Workouts().Create(workout, userID)
 	if err != nil {
 		log.Printf("Failed to create workout from template: %v", err)
 		http.Error(w, "Failed to create workout", http.StatusInternalServerError)
@@ -2835,16 +3393,17 @@ func (h *Handler) CreateWorkoutFromTemplate(w http.ResponseWriter, r *http.Reque
 			continue
 		}
 
-		// Create exercise
+		// Create exercise, keeping the template's superset and circuit grouping
 		exercise := models.Exercise{
 			WorkoutID: workoutID,
 			Name:      templateExercise.Name,
 			Category:  templateExercise.Category,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			GroupID:   templateExercise.GroupID,
+			CreatedAt: h.now(),
+			UpdatedAt: h.now(),
 		}
 
-		exerciseID, err := h.createExerciseForWorkout(exercise)
+		exerciseID, err := h.storage.Exercises().Create(exercise)
 		if err != nil {
 			log.Printf("Failed to create exercise from template: %v", err)
 			continue
@@ -2878,11 +3437,11 @@ func (h *Handler) CreateWorkoutFromTemplate(w http.ResponseWriter, r *http.Reque
 				Reps:       targetReps,
 				Weight:     targetWeight,
 				RestTime:   restTime,
-				CreatedAt:  time.Now(),
-				UpdatedAt:  time.Now(),
+				CreatedAt:  h.now(),
+				UpdatedAt:  h.now(),
 			}
 
-			_, err := h.createSet(set)
+			_, err := h.storage.Exercises().CreateSet(set)
 			if err != nil {
 				log.Printf("Failed to create set from template: %v", err)
 			}
@@ -2898,20 +3457,21 @@ func (h *Handler) CreateWorkoutFromTemplate(w http.ResponseWriter, r *http.Reque
 		TemplateID: templateID,
 		UserID:     userID,
 		WorkoutID:  workoutID,
-		UsedAt:     time.Now(),
-		CreatedAt:  time.Now(),
+		UsedAt:     h.now(),
+		CreatedAt:  h.now(),
 	}
 
-	_, err = h.createTemplateUsage(usage)
+	_, err = h.storage.Templates().RecordUsage(usage)
 	if err != nil {
 		log.Printf("Failed to record template usage: %v", err)
 		// Don't fail the request for usage tracking failure
 	}
 
 	// Get the complete workout with exercises
-	completeWorkout, err := h.getWorkoutByIDWithUser(workoutID, userID)
+	completeWorkout, err := h.storage.Workouts().Get(workoutID, userID)
 	if err != nil {
 		log.Printf("Failed to get complete workout: %v", err)
+		h.workoutLogged(r, models.Workout{ID: workoutID, UserID: userID, Name: workoutName, Date: workoutDate})
 		// Return basic response if we can't get the complete workout
 		response := models.WorkoutFromTemplateResponse{
 			Workout:      models.Workout{ID: workoutID, Name: workoutName, Date: workoutDate},
@@ -2924,6 +3484,8 @@ func (h *Handler) CreateWorkoutFromTemplate(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	h.workoutLogged(r, completeWorkout)
+
 	// Return complete response
 	response := models.WorkoutFromTemplateResponse{
 		Workout:      completeWorkout,
@@ -2942,3 +3504,186 @@ func (h *Handler) CreateWorkoutFromTemplate(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
 }
+
+// ========== API TOKEN HANDLERS ==========
+
+// apiTokenPrefix marks personal access tokens so they are easy to recognise in scripts and logs
+const apiTokenPrefix = "wt_"
+
+// generateAPIToken returns a new random token and the SHA-256 hash stored for it
+func generateAPIToken() (string, string, error) {
+	return generateToken(apiTokenPrefix)
+}
+
+// generateToken returns a new random token with the given prefix and its SHA-256 hash
+func generateToken(prefix string) (string, string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	token := prefix + hex.EncodeToString(bytes)
+	return token, hashAPIToken(token), nil
+}
+
+// hashAPIToken hashes a token for storage and lookup
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// isReadOnlyMethod reports whether an HTTP method never modifies data
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// authenticateAPIToken resolves a bearer token to its stored record and records its use
+func (h *Handler) authenticateAPIToken(token string) (models.APIToken, error) {
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return models.APIToken{}, fmt.Errorf("malformed token")
+	}
+
+	apiToken, err := h.getActiveAPITokenByHash(hashAPIToken(token))
+	if err != nil {
+		return apiToken, err
+	}
+
+	if err := h.touchAPIToken(apiToken.ID); err != nil {
+		log.Printf("Failed to record API token use: %v", err)
+	}
+
+	return apiToken, nil
+}
+
+// requireSessionAuth rejects requests authenticated with an API token.
+// Tokens cannot be used to create or revoke other tokens.
+func (h *Handler) requireSessionAuth(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := r.Context().Value(APITokenKey).(models.APIToken); ok {
+		http.Error(w, "API tokens cannot manage tokens", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// GetAPITokens lists the current user's personal access tokens
+func (h *Handler) GetAPITokens(w http.ResponseWriter, r *http.Request) {
+	if !h.requireSessionAuth(w, r) {
+		return
+	}
+
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.getAPITokensByUser(userID)
+	if err != nil {
+		log.Printf("Failed to get API tokens: %v", err)
+		http.Error(w, "Failed to load API tokens", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// CreateAPIToken creates a personal access token and returns the plaintext once
+func (h *Handler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	if !h.requireSessionAuth(w, r) {
+		return
+	}
+
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateAPITokenRequest
+	isJSON := r.Header.Get("Content-Type") == "application/json"
+	if isJSON {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+			return
+		}
+		req.Name = r.FormValue("name")
+		req.Scope = r.FormValue("scope")
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Scope == "" {
+		req.Scope = "read"
+	}
+	if !validateSubmission(w, r, req) {
+		return
+	}
+
+	plaintext, tokenHash, err := generateAPIToken()
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	token := models.APIToken{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    plaintext[:len(apiTokenPrefix)+8],
+		TokenHash: tokenHash,
+		Scope:     req.Scope,
+		CreatedAt: h.now(),
+	}
+
+	id, err := h.createAPIToken(token)
+	if err != nil {
+		log.Printf("Failed to create API token: %v", err)
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+	token.ID = id
+
+	response := models.CreateAPITokenResponse{APIToken: token, Token: plaintext}
+	if isJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	} else {
+		// Render directly so the plaintext token is shown once and never stored in a URL
+		h.renderAccountSettings(w, r, userID, &response)
+	}
+}
+
+// RevokeAPIToken revokes one of the current user's personal access tokens
+func (h *Handler) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	if !h.requireSessionAuth(w, r) {
+		return
+	}
+
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.revokeAPIToken(id, userID); err != nil {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		http.Redirect(w, r, "/account-settings?updated=tokens", http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,350 @@
+// Package handlerstest runs handlers behind a real HTTP server for tests. Each
+// harness gets its own SQLite database in a temporary directory, the repository's
+// HTML templates, a pinned clock and helpers for logged-in clients.
+package handlerstest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"workout-tracker/internal/database"
+	"workout-tracker/internal/handlers"
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/storage"
+
+	"github.com/gorilla/sessions"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultPassword is the password CreateUser and LoginAs give new users
+const DefaultPassword = "password123"
+
+// Harness is a handler under test served by an httptest.Server
+type Harness struct {
+	t       testing.TB
+	DB      *database.DB
+	Store   storage.Store
+	Handler *handlers.Handler
+	Server  *httptest.Server
+	Clock   *Clock
+}
+
+// New starts a harness. routes mounts the handler's routes, typically the same router
+// the server uses; opts are applied after the harness's own options and can override them.
+func New(t testing.TB, routes func(h *handlers.Handler) http.Handler, opts ...handlers.Option) *Harness {
+	t.Helper()
+
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DATABASE_PATH", filepath.Join(t.TempDir(), "test.db"))
+	db, err := database.Initialize()
+	if err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	clock := NewClock(time.Now())
+	store := storage.New(db)
+	sessionStore := sessions.NewCookieStore([]byte("handlerstest-session-secret"))
+	sessionStore.Options = &sessions.Options{Path: "/", MaxAge: 3600, HttpOnly: true}
+
+	options := append([]handlers.Option{
+		handlers.WithStore(store),
+		handlers.WithTemplates(os.DirFS(filepath.Join(RepoRoot(), "web", "templates"))),
+		handlers.WithSessionStore(sessionStore),
+		handlers.WithClock(clock.Now),
+	}, opts...)
+	h := handlers.New(db, options...)
+
+	server := httptest.NewServer(routes(h))
+	t.Cleanup(server.Close)
+
+	return &Harness{t: t, DB: db, Store: store, Handler: h, Server: server, Clock: clock}
+}
+
+// RepoRoot returns the repository's root directory, wherever the test runs from
+func RepoRoot() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..")
+}
+
+// CreateUser stores a user with DefaultPassword and returns its ID
+func (h *Harness) CreateUser(username string) int {
+	h.t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(DefaultPassword), bcrypt.MinCost)
+	if err != nil {
+		h.t.Fatalf("failed to hash password: %v", err)
+	}
+	id, err := h.Store.Users().Create(models.User{Username: username, Email: username + "@example.com", PasswordHash: string(hash)})
+	if err != nil {
+		h.t.Fatalf("failed to create user %s: %v", username, err)
+	}
+	return id
+}
+
+// LoginAs creates a user and returns a client logged in as them through POST /login
+func (h *Harness) LoginAs(username string) *Client {
+	h.t.Helper()
+
+	userID := h.CreateUser(username)
+	c := h.Client()
+	resp := c.PostForm("/login", url.Values{"username": {username}, "password": {DefaultPassword}})
+	if resp.StatusCode != http.StatusFound {
+		h.t.Fatalf("login as %s: status %d: %s", username, resp.StatusCode, resp.Body)
+	}
+	c.UserID = userID
+	c.Username = username
+	return c
+}
+
+// Client returns a client without a session. It keeps cookies and does not follow redirects.
+func (h *Harness) Client() *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{
+		t:    h.t,
+		base: h.Server.URL,
+		http: &http.Client{
+			Jar: jar,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Header: http.Header{},
+	}
+}
+
+// Client sends requests to the harness server
+type Client struct {
+	t        testing.TB
+	base     string
+	http     *http.Client
+	UserID   int
+	Username string
+	// Header is added to every request the client sends
+	Header http.Header
+}
+
+// Response is a fully read HTTP response
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// JSON decodes the response body into v, failing the test if it is not valid JSON
+func (r *Response) JSON(t testing.TB, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal([]byte(r.Body), v); err != nil {
+		t.Fatalf("failed to decode response %q: %v", r.Body, err)
+	}
+}
+
+// Do sends a request with an optional body and content type
+func (c *Client) Do(method, path string, body io.Reader, contentType string) *Response {
+	c.t.Helper()
+
+	req, err := http.NewRequest(method, c.base+path, body)
+	if err != nil {
+		c.t.Fatalf("failed to build request %s %s: %v", method, path, err)
+	}
+	for key, values := range c.Header {
+		req.Header[key] = values
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatalf("failed to read response of %s %s: %v", method, path, err)
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: string(data)}
+}
+
+// Get sends a GET request
+func (c *Client) Get(path string) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodGet, path, nil, "")
+}
+
+// Delete sends a DELETE request
+func (c *Client) Delete(path string) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodDelete, path, nil, "")
+}
+
+// PostForm sends a form-encoded POST request
+func (c *Client) PostForm(path string, form url.Values) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodPost, path, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+}
+
+// SendJSON sends v encoded as JSON; a string or []byte is sent as is
+func (c *Client) SendJSON(method, path string, v interface{}) *Response {
+	c.t.Helper()
+
+	var data []byte
+	switch body := v.(type) {
+	case string:
+		data = []byte(body)
+	case []byte:
+		data = body
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			c.t.Fatalf("failed to encode request body: %v", err)
+		}
+	}
+	return c.Do(method, path, bytes.NewReader(data), "application/json")
+}
+
+// Event is one server-sent event read from a Stream
+type Event struct {
+	ID   string
+	Type string
+	Data string
+}
+
+// Stream is a response read as a text/event-stream while the server keeps it open
+type Stream struct {
+	t          testing.TB
+	StatusCode int
+	Header     http.Header
+	events     chan Event
+	cancel     context.CancelFunc
+}
+
+// streamTimeout is how long Next waits for an event
+const streamTimeout = 5 * time.Second
+
+// Stream sends a GET request and returns once the response headers arrive. Events are
+// read in the background until the server ends the response or the stream is closed.
+func (c *Client) Stream(path string) *Stream {
+	c.t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+path, nil)
+	if err != nil {
+		cancel()
+		c.t.Fatalf("failed to build request GET %s: %v", path, err)
+	}
+	for key, values := range c.Header {
+		req.Header[key] = values
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		cancel()
+		c.t.Fatalf("GET %s: %v", path, err)
+	}
+
+	s := &Stream{t: c.t, StatusCode: resp.StatusCode, Header: resp.Header, events: make(chan Event, 64), cancel: cancel}
+	c.t.Cleanup(s.Close)
+	go s.read(resp.Body)
+	return s
+}
+
+// read parses events from the body until it ends
+func (s *Stream) read(body io.ReadCloser) {
+	defer close(s.events)
+	defer body.Close()
+
+	var event Event
+	var data []string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if event.Type != "" || len(data) > 0 {
+				event.Data = strings.Join(data, "\n")
+				s.events <- event
+			}
+			event, data = Event{}, nil
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			event.ID = value
+		case "event":
+			event.Type = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
+
+// Next returns the next event, failing the test if none arrives in time or the stream ends
+func (s *Stream) Next() Event {
+	s.t.Helper()
+
+	select {
+	case event, ok := <-s.events:
+		if !ok {
+			s.t.Fatalf("event stream ended")
+		}
+		return event
+	case <-time.After(streamTimeout):
+		s.t.Fatalf("no event within %s", streamTimeout)
+	}
+	return Event{}
+}
+
+// Close disconnects from the server
+func (s *Stream) Close() {
+	s.cancel()
+}
+
+// Clock is a settable clock for handlers.WithClock
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a clock stopped at the given time
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the clock's current time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to the given time
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,465 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"workout-tracker/internal/events"
+	"workout-tracker/internal/ical"
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/recurrence"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// calendarFeedPrefix marks calendar feed tokens, which can only read the feed
+	calendarFeedPrefix = "wtcal_"
+	// calendarFeedPast and calendarFeedAhead are the days around today the feed covers
+	calendarFeedPast  = 90
+	calendarFeedAhead = 365
+	// icalImportAhead is how many days ahead the occurrences of an imported series are
+	// scheduled
+	icalImportAhead = 365
+	// maxICalImportSize is the largest .ics file accepted, in bytes
+	maxICalImportSize = 5 << 20
+)
+
+// eventTypeCategories name the calendar event types in feeds
+var eventTypeCategories = map[string]string{
+	models.CalendarEventWorkout: "Workout",
+	models.CalendarEventRestDay: "Rest day",
+	models.CalendarEventDeload:  "Deload",
+}
+
+// GetCalendarFeedToken describes the current user's calendar feed without its token
+func (h *Handler) GetCalendarFeedToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	feed, err := h.storage.CalendarFeeds().Get(userID)
+	if err != nil {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feed)
+}
+
+// RegenerateCalendarFeed gives the current user a new calendar feed URL and returns it
+// once. The URL it replaces stops working.
+func (h *Handler) RegenerateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if !h.requireSessionAuth(w, r) {
+		return
+	}
+
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	plaintext, tokenHash, err := generateToken(calendarFeedPrefix)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	feed := models.CalendarFeed{
+		UserID:    userID,
+		Prefix:    plaintext[:len(calendarFeedPrefix)+8],
+		TokenHash: tokenHash,
+		CreatedAt: h.now(),
+	}
+	id, err := h.storage.CalendarFeeds().Replace(feed)
+	if err != nil {
+		log.Printf("Failed to create calendar feed: %v", err)
+		http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+		return
+	}
+	feed.ID = id
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	response := models.CalendarFeedResponse{
+		CalendarFeed: feed,
+		Token:        plaintext,
+		URL:          fmt.Sprintf("%s://%s/calendar/%s.ics", scheme, r.Host, plaintext),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteCalendarFeed turns the current user's calendar feed off
+func (h *Handler) DeleteCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if !h.requireSessionAuth(w, r) {
+		return
+	}
+
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.storage.CalendarFeeds().Delete(userID); err != nil {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCalendarFeed serves the training schedule of the user whose feed token is in the URL
+// as an iCalendar file, for calendar applications to subscribe to. The token is the only
+// credential, so the route needs no session.
+func (h *Handler) GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	if !strings.HasPrefix(token, calendarFeedPrefix) {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+	feed, err := h.storage.CalendarFeeds().GetByHash(hashAPIToken(token))
+	if err != nil {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+	if err := h.storage.CalendarFeeds().Touch(feed.ID, h.now()); err != nil {
+		log.Printf("Failed to record calendar feed use: %v", err)
+	}
+
+	cal, err := h.calendarFeed(feed.UserID)
+	if err != nil {
+		log.Printf("Failed to build calendar feed: %v", err)
+		http.Error(w, "Failed to load calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="workouts.ics"`)
+	if err := ical.Write(w, cal); err != nil {
+		log.Printf("Failed to write calendar feed: %v", err)
+	}
+}
+
+// calendarFeed builds the calendar of a user's scheduled workouts and calendar events
+// from 90 days ago to a year ahead. Workouts scheduled at a time are placed in the user's
+// time zone; calendar events standing for a scheduled workout are left out in its favour.
+func (h *Handler) calendarFeed(userID int) (ical.Calendar, error) {
+	cal := ical.Calendar{Name: "Training schedule"}
+	settings, err := h.getUserSettings(userID)
+	if err != nil {
+		return cal, fmt.Errorf("failed to load settings: %v", err)
+	}
+	// Without a known zone, times are floating: the planned clock time wherever the user is
+	zone, err := time.LoadLocation(settings.Timezone)
+	if settings.Timezone == "" || err != nil {
+		zone = nil
+	}
+
+	today := calendarDate(h.now())
+	from, to := today.AddDate(0, 0, -calendarFeedPast), today.AddDate(0, 0, calendarFeedAhead)
+
+	workouts, err := h.storage.ScheduledWorkouts().ListWithTargets(userID, from, to, "")
+	if err != nil {
+		return cal, err
+	}
+	for _, sw := range workouts {
+		cal.Events = append(cal.Events, scheduledWorkoutEvent(sw, zone, settings.WeightUnit))
+	}
+
+	stored, err := h.storage.CalendarEvents().List(userID, from, to)
+	if err != nil {
+		return cal, err
+	}
+	for _, event := range stored {
+		if event.ScheduledWorkoutID != nil {
+			continue
+		}
+		e, err := calendarFeedEvent(event, zone)
+		if err != nil {
+			log.Printf("Leaving calendar event %d out of the feed: %v", event.ID, err)
+			continue
+		}
+		cal.Events = append(cal.Events, e)
+	}
+	return cal, nil
+}
+
+// scheduledWorkoutEvent describes a scheduled workout as a feed event: its duration, its
+// status and the targets of its template's exercises
+func scheduledWorkoutEvent(sw models.ScheduledWorkout, zone *time.Location, weightUnit string) ical.Event {
+	e := ical.Event{
+		UID:        fmt.Sprintf("scheduled-workout-%d@workout-tracker", sw.ID),
+		Summary:    sw.Title,
+		Status:     "CONFIRMED",
+		Categories: []string{"Workout"},
+		Stamp:      sw.UpdatedAt,
+	}
+	if sw.Status == models.ScheduledStatusSkipped || sw.Status == models.ScheduledStatusCancelled {
+		e.Status = "CANCELLED"
+	}
+
+	var clock time.Time
+	timed := false
+	if sw.ScheduledTime != nil {
+		if t, err := time.Parse("15:04", *sw.ScheduledTime); err == nil {
+			clock, timed = t, true
+		}
+	}
+	if !timed {
+		e.AllDay, e.Start, e.End = true, sw.ScheduledDate, sw.ScheduledDate.AddDate(0, 0, 1)
+	} else {
+		loc := zone
+		if loc == nil {
+			loc, e.Floating = time.UTC, true
+		}
+		date := sw.ScheduledDate
+		e.Start = time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+		e.End = e.Start.Add(time.Duration(sw.EstimatedDuration) * time.Minute)
+	}
+
+	var lines []string
+	if sw.Description != "" {
+		lines = append(lines, sw.Description, "")
+	}
+	lines = append(lines, "Status: "+sw.Status, fmt.Sprintf("Estimated duration: %d min", sw.EstimatedDuration))
+	if len(sw.Exercises) > 0 {
+		lines = append(lines, "", "Exercises:")
+		for _, ex := range sw.Exercises {
+			line := "- " + ex.Name
+			if ex.TargetSets > 0 && ex.TargetReps > 0 {
+				line += fmt.Sprintf(": %d x %d", ex.TargetSets, ex.TargetReps)
+			}
+			if ex.TargetWeight > 0 {
+				line += fmt.Sprintf(" @ %g %s", ex.TargetWeight, weightUnit)
+			}
+			lines = append(lines, line)
+		}
+	}
+	if sw.Notes != "" {
+		lines = append(lines, "", sw.Notes)
+	}
+	e.Description = strings.Join(lines, "\n")
+	return e
+}
+
+// calendarFeedEvent describes a calendar event as a feed event, a recurring one with its
+// rule and exceptions. Events at a time are placed in the user's zone, when known, so a
+// recurring one repeats at the same clock time there as it does in the app.
+func calendarFeedEvent(event models.WorkoutCalendarEvent, zone *time.Location) (ical.Event, error) {
+	e := ical.Event{
+		UID:         fmt.Sprintf("calendar-event-%d@workout-tracker", event.ID),
+		Summary:     event.Title,
+		Description: event.Description,
+		Start:       event.StartDate,
+		AllDay:      event.AllDay,
+		Stamp:       event.UpdatedAt,
+	}
+	if category, ok := eventTypeCategories[event.EventType]; ok {
+		e.Categories = []string{category}
+	}
+	switch {
+	case event.AllDay:
+		// The end date of an all-day event is its last day; iCalendar's is the day after
+		last := event.StartDate
+		if event.EndDate != nil {
+			last = *event.EndDate
+		}
+		e.Start, e.End = calendarDate(event.StartDate), calendarDate(last).AddDate(0, 0, 1)
+	case zone != nil:
+		e.Start = event.StartDate.In(zone)
+		if event.EndDate != nil {
+			e.End = event.EndDate.In(zone)
+		}
+	case event.EndDate != nil:
+		e.End = *event.EndDate
+	}
+
+	if event.IsRecurring {
+		rule, pattern, err := parseRecurrence(event)
+		if err != nil {
+			return e, err
+		}
+		e.RRule = rule.String()
+		hour, minute, second := e.Start.Clock()
+		for _, day := range exceptionDates(pattern) {
+			if event.AllDay {
+				e.ExDates = append(e.ExDates, day)
+			} else {
+				e.ExDates = append(e.ExDates, time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, e.Start.Location()))
+			}
+		}
+	}
+	return e, nil
+}
+
+// ImportScheduledWorkouts schedules the events of an .ics file, sent as the request body
+// or as the "file" field of a multipart form. Each occurrence of a recurring event from
+// today to a year ahead is scheduled separately. Times are placed in the user's time
+// zone, and events imported before, recognised by their UID, are not imported again.
+func (h *Handler) ImportScheduledWorkouts(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxICalImportSize)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "The form needs an .ics file in its file field", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	cal, err := ical.Parse(body)
+	if err != nil {
+		http.Error(w, "Invalid iCalendar file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.getUserSettings(userID)
+	if err != nil {
+		log.Printf("Failed to load settings: %v", err)
+		http.Error(w, "Failed to import workouts", http.StatusInternalServerError)
+		return
+	}
+	zone, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		zone = time.UTC
+	}
+
+	workouts, skipped := importedWorkouts(userID, cal.Events, zone, calendarDate(h.now()))
+	ids, err := h.storage.ScheduledWorkouts().Import(workouts)
+	if err != nil {
+		log.Printf("Failed to import scheduled workouts: %v", err)
+		http.Error(w, "Failed to import workouts", http.StatusInternalServerError)
+		return
+	}
+	for _, id := range ids {
+		if sw, err := h.storage.ScheduledWorkouts().Get(id, userID); err == nil {
+			h.publish(r, events.ScheduledWorkoutCreated, sw)
+		}
+	}
+
+	result := models.ICalImportResult{Imported: len(ids), Duplicates: len(workouts) - len(ids), Skipped: skipped}
+	if result.Skipped == nil {
+		result.Skipped = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// importedWorkouts turns the events of an .ics file into scheduled workouts, describing
+// the events it cannot import. An event replacing one occurrence of a series is imported
+// in place of that occurrence.
+func importedWorkouts(userID int, calEvents []ical.Event, zone *time.Location, today time.Time) ([]models.ScheduledWorkout, []string) {
+	// replaced holds the days of the occurrences each series has replacement events for
+	replaced := make(map[string][]time.Time)
+	for _, e := range calEvents {
+		if e.RecurrenceID != nil {
+			replaced[e.UID] = append(replaced[e.UID], *e.RecurrenceID)
+		}
+	}
+
+	var workouts []models.ScheduledWorkout
+	var skipped []string
+	for _, e := range calEvents {
+		name := e.Summary
+		if name == "" {
+			name = e.UID
+		}
+		if e.Start.IsZero() {
+			skipped = append(skipped, fmt.Sprintf("%s: the event has no start", name))
+			continue
+		}
+
+		switch {
+		case e.RecurrenceID != nil:
+			workouts = append(workouts, importedWorkout(userID, e, e.Start, occurrenceUID(e.UID, *e.RecurrenceID), zone))
+		case e.RRule != "":
+			rule, err := recurrence.Parse(e.RRule)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			// Occurrences are counted in the series' own zone so they keep its clock time
+			from := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, e.Start.Location())
+			to := from.AddDate(0, 0, icalImportAhead)
+			except := append(append([]time.Time{}, e.ExDates...), replaced[e.UID]...)
+			for i := range except {
+				except[i] = except[i].In(e.Start.Location())
+			}
+			for _, start := range rule.Between(e.Start, from, to, except) {
+				workouts = append(workouts, importedWorkout(userID, e, start, occurrenceUID(e.UID, start), zone))
+			}
+		default:
+			workouts = append(workouts, importedWorkout(userID, e, e.Start, e.UID, zone))
+		}
+	}
+	return workouts, skipped
+}
+
+// importedWorkout schedules an occurrence of an event starting at the given time
+func importedWorkout(userID int, e ical.Event, start time.Time, uid string, zone *time.Location) models.ScheduledWorkout {
+	title := strings.TrimSpace(e.Summary)
+	if title == "" {
+		title = "Workout"
+	}
+	if runes := []rune(title); len(runes) > 200 {
+		title = string(runes[:200])
+	}
+
+	sw := models.ScheduledWorkout{
+		UserID:            userID,
+		Title:             title,
+		Description:       e.Description,
+		EstimatedDuration: defaultScheduledDuration,
+		Status:            models.ScheduledStatusScheduled,
+	}
+	if e.Status == "CANCELLED" {
+		sw.Status = models.ScheduledStatusCancelled
+	}
+	if uid != "" {
+		sw.ICalUID = &uid
+	}
+
+	if !e.AllDay {
+		if !e.Floating {
+			start = start.In(zone)
+		}
+		clock := start.Format("15:04")
+		sw.ScheduledTime = &clock
+		if minutes := int(e.End.Sub(e.Start).Minutes()); minutes > 0 {
+			sw.EstimatedDuration = min(minutes, 1440)
+		}
+	}
+	sw.ScheduledDate = calendarDate(start)
+	return sw
+}
+
+// occurrenceUID names one occurrence of a series by the series' UID and its day
+func occurrenceUID(uid string, start time.Time) string {
+	if uid == "" {
+		return ""
+	}
+	return uid + "/" + start.Format("20060102")
+}
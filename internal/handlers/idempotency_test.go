@@ -0,0 +1,175 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"workout-tracker/internal/handlers"
+	"workout-tracker/internal/handlers/handlerstest"
+
+	"github.com/gorilla/mux"
+)
+
+// idempotencyHarness serves create endpoints behind Idempotent: /count answers with how
+// many times it ran, /flaky fails with a 500 the first time and /slow waits for release
+type idempotencyHarness struct {
+	*handlerstest.Harness
+	runs    atomic.Int32
+	flaky   atomic.Int32
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newIdempotencyHarness(t *testing.T) *idempotencyHarness {
+	ih := &idempotencyHarness{entered: make(chan struct{}), release: make(chan struct{})}
+	created := func(w http.ResponseWriter, n int32) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]int32{"run": n})
+	}
+	ih.Harness = handlerstest.New(t, func(h *handlers.Handler) http.Handler {
+		r := mux.NewRouter()
+		r.HandleFunc("/login", h.Login).Methods("GET", "POST")
+		r.HandleFunc("/count", h.AuthMiddleware(h.Idempotent(func(w http.ResponseWriter, r *http.Request) {
+			created(w, ih.runs.Add(1))
+		}))).Methods("POST")
+		r.HandleFunc("/flaky", h.AuthMiddleware(h.Idempotent(func(w http.ResponseWriter, r *http.Request) {
+			if ih.flaky.Add(1) == 1 {
+				http.Error(w, "Database unavailable", http.StatusInternalServerError)
+				return
+			}
+			created(w, ih.flaky.Load())
+		}))).Methods("POST")
+		r.HandleFunc("/slow", h.AuthMiddleware(h.Idempotent(func(w http.ResponseWriter, r *http.Request) {
+			close(ih.entered)
+			<-ih.release
+			created(w, 1)
+		}))).Methods("POST")
+		return r
+	})
+	return ih
+}
+
+// post sends a JSON body with an Idempotency-Key, or none if key is empty
+func post(c *handlerstest.Client, path, key, body string) *handlerstest.Response {
+	c.Header.Del("Idempotency-Key")
+	if key != "" {
+		c.Header.Set("Idempotency-Key", key)
+	}
+	return c.SendJSON("POST", path, body)
+}
+
+func TestIdempotentReplaysTheFirstResponse(t *testing.T) {
+	h := newIdempotencyHarness(t)
+	alice := h.LoginAs("alice")
+	bob := h.LoginAs("bob")
+
+	first := post(alice, "/count", "create-1", `{"name":"Push"}`)
+	if first.StatusCode != http.StatusCreated || first.Header.Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first request: status %d, replayed %q", first.StatusCode, first.Header.Get("Idempotent-Replayed"))
+	}
+	replay := post(alice, "/count", "create-1", `{"name":"Push"}`)
+	if replay.StatusCode != http.StatusCreated || replay.Body != first.Body || replay.Header.Get("Idempotent-Replayed") != "true" ||
+		replay.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("retry = %d %q %v, want the first response replayed", replay.StatusCode, replay.Body, replay.Header)
+	}
+	if n := h.runs.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want once", n)
+	}
+
+	// The same key with another body is a mistake, not a retry
+	reused := post(alice, "/count", "create-1", `{"name":"Pull"}`)
+	if reused.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("key reused with another body: status %d, want 422: %s", reused.StatusCode, reused.Body)
+	}
+
+	// Keys belong to one user, and requests without one are not deduplicated
+	if resp := post(bob, "/count", "create-1", `{"name":"Push"}`); resp.StatusCode != http.StatusCreated || resp.Header.Get("Idempotent-Replayed") != "" {
+		t.Errorf("another user's request with the same key: status %d, replayed %q", resp.StatusCode, resp.Header.Get("Idempotent-Replayed"))
+	}
+	post(alice, "/count", "", `{"name":"Push"}`)
+	post(alice, "/count", "", `{"name":"Push"}`)
+	if n := h.runs.Load(); n != 4 {
+		t.Errorf("handler ran %d times, want 4", n)
+	}
+
+	// Once expired, a key runs the handler again
+	h.Clock.Advance(25 * time.Hour)
+	if resp := post(alice, "/count", "create-1", `{"name":"Pull"}`); resp.StatusCode != http.StatusCreated || resp.Header.Get("Idempotent-Replayed") != "" {
+		t.Errorf("expired key: status %d, replayed %q", resp.StatusCode, resp.Header.Get("Idempotent-Replayed"))
+	}
+	if n := h.runs.Load(); n != 5 {
+		t.Errorf("handler ran %d times, want 5", n)
+	}
+}
+
+func TestIdempotentReleasesServerErrors(t *testing.T) {
+	h := newIdempotencyHarness(t)
+	alice := h.LoginAs("alice")
+
+	if resp := post(alice, "/flaky", "flaky-1", `{}`); resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("first request: status %d, want 500", resp.StatusCode)
+	}
+	retry := post(alice, "/flaky", "flaky-1", `{}`)
+	if retry.StatusCode != http.StatusCreated || retry.Header.Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after a 500: status %d, replayed %q; want it run again", retry.StatusCode, retry.Header.Get("Idempotent-Replayed"))
+	}
+	if replay := post(alice, "/flaky", "flaky-1", `{}`); replay.Body != retry.Body || replay.Header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry after success = %q, want %q replayed", replay.Body, retry.Body)
+	}
+}
+
+func TestIdempotentRejectsConcurrentRetries(t *testing.T) {
+	h := newIdempotencyHarness(t)
+	alice := h.LoginAs("alice")
+	alice.Header.Set("Idempotency-Key", "slow-1")
+
+	done := make(chan *handlerstest.Response)
+	go func() { done <- alice.SendJSON("POST", "/slow", `{}`) }()
+	<-h.entered
+
+	if resp := alice.SendJSON("POST", "/slow", `{}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("retry while the first is running: status %d, want 409: %s", resp.StatusCode, resp.Body)
+	}
+	close(h.release)
+	if resp := <-done; resp.StatusCode != http.StatusCreated {
+		t.Errorf("first request: status %d, want 201", resp.StatusCode)
+	}
+	if resp := alice.SendJSON("POST", "/slow", `{}`); resp.Header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry once finished: status %d, want the response replayed", resp.StatusCode)
+	}
+}
+
+func TestPurgeIdempotencyKeys(t *testing.T) {
+	h := newIdempotencyHarness(t)
+	alice := h.LoginAs("alice")
+	post(alice, "/count", "old", `{}`)
+	h.Clock.Advance(23 * time.Hour)
+	post(alice, "/count", "recent", `{}`)
+	h.Clock.Advance(2 * time.Hour)
+
+	// A cancelled context purges once and returns
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h.Handler.PurgeIdempotencyKeys(ctx, time.Hour)
+
+	var keys []string
+	rows, err := h.DB.Query(`SELECT idempotency_key FROM idempotency_keys ORDER BY id`)
+	if err != nil {
+		t.Fatalf("failed to list keys: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		rows.Scan(&key)
+		keys = append(keys, key)
+	}
+	if fmt.Sprint(keys) != "[recent]" {
+		t.Errorf("keys after the purge = %v, want only the recent one", keys)
+	}
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"workout-tracker/internal/load"
+	"workout-tracker/internal/models"
+)
+
+const (
+	// defaultLoadDays and maxLoadDays bound the period of the training load endpoint
+	defaultLoadDays = 90
+	maxLoadDays     = 731
+	// metricWeeks is how many weeks of volume the recommendation metrics look back on
+	metricWeeks = 4
+	// intensityTrendRPE is how far the latest week's average RPE must move from the weeks
+	// before it to count as a trend
+	intensityTrendRPE = 0.5
+)
+
+// Intensity trends of the recommendation metrics
+const (
+	intensityIncreasing = "increasing"
+	intensityDecreasing = "decreasing"
+	intensityStable     = "stable"
+)
+
+// GetTrainingLoad returns the current user's daily training load, weekly volume and the
+// metrics recommendations are based on. The from and to query parameters pick the period
+// in YYYY-MM-DD format; it defaults to the last 90 days and may span at most two years.
+func (h *Handler) GetTrainingLoad(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	from, to, err := h.parseLoadPeriod(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.trainingLoad(userID, from, to)
+	if err != nil {
+		log.Printf("Failed to get training load: %v", err)
+		http.Error(w, "Failed to load training load", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseLoadPeriod reads the from and to query parameters
+func (h *Handler) parseLoadPeriod(r *http.Request) (from, to time.Time, err error) {
+	query := r.URL.Query()
+	to = calendarDate(h.now())
+	if param := query.Get("to"); param != "" {
+		if to, err = time.Parse("2006-01-02", param); err != nil {
+			return from, to, fmt.Errorf("to must be a date in YYYY-MM-DD format")
+		}
+	}
+	from = to.AddDate(0, 0, 1-defaultLoadDays)
+	if param := query.Get("from"); param != "" {
+		if from, err = time.Parse("2006-01-02", param); err != nil {
+			return from, to, fmt.Errorf("from must be a date in YYYY-MM-DD format")
+		}
+	}
+
+	if from.After(to) {
+		return from, to, fmt.Errorf("from must not be after to")
+	}
+	if to.Sub(from) >= maxLoadDays*24*time.Hour {
+		return from, to, fmt.Errorf("the period may span at most %d days", maxLoadDays)
+	}
+	return from, to, nil
+}
+
+// trainingLoad builds the training load response of a user for a period
+func (h *Handler) trainingLoad(userID int, from, to time.Time) (models.TrainingLoadResponse, error) {
+	days, err := h.storage.TrainingLoad().Days(userID, from, to)
+	if err != nil {
+		return models.TrainingLoadResponse{}, err
+	}
+	start := weekStart(from)
+	sessions, err := h.storage.TrainingLoad().Sessions(userID, start)
+	if err != nil {
+		return models.TrainingLoadResponse{}, err
+	}
+	metrics, err := h.recommendationMetrics(userID, to)
+	if err != nil {
+		return models.TrainingLoadResponse{}, err
+	}
+
+	weeks := int(weekStart(to).Sub(start).Hours())/(24*7) + 1
+	return models.TrainingLoadResponse{
+		Days:    days,
+		Weekly:  weeklyVolume(sessions, start, weeks),
+		Metrics: metrics,
+	}, nil
+}
+
+// recommendationMetrics summarises a user's recent training as of a day: the last week's
+// workouts, the volume of the last few weeks, the current run of training days, how
+// fatigued each muscle is and the load model's view of the user's performance
+func (h *Handler) recommendationMetrics(userID int, asOf time.Time) (models.RecommendationMetrics, error) {
+	asOf = calendarDate(asOf)
+	metrics := models.RecommendationMetrics{
+		UserID:             userID,
+		RecentWorkouts:     []models.Workout{},
+		MuscleGroupFatigue: make(map[string]float64),
+	}
+
+	// A month of days covers the chronic load and any run of training days worth reporting
+	days, err := h.storage.TrainingLoad().Days(userID, asOf.AddDate(0, 0, 1-load.ChronicDays), asOf)
+	if err != nil {
+		return metrics, fmt.Errorf("failed to load training load: %v", err)
+	}
+	metrics.ConsecutiveDays, metrics.LastRestDay = trainingStreak(days)
+
+	recent, err := h.storage.Workouts().Recent(userID, 2*load.AcuteDays)
+	if err != nil {
+		return metrics, fmt.Errorf("failed to load recent workouts: %v", err)
+	}
+	weekAgo := asOf.AddDate(0, 0, 1-load.AcuteDays)
+	for _, workout := range recent {
+		if date := calendarDate(workout.Date); !date.Before(weekAgo) && !date.After(asOf) {
+			metrics.RecentWorkouts = append(metrics.RecentWorkouts, workout)
+		}
+	}
+
+	// The four weeks up to the day reach back further than the weeks starting on Monday
+	start := weekStart(asOf).AddDate(0, 0, -7*(metricWeeks-1))
+	sessions, err := h.storage.TrainingLoad().Sessions(userID, asOf.AddDate(0, 0, 1-load.ChronicDays))
+	if err != nil {
+		return metrics, fmt.Errorf("failed to load sessions: %v", err)
+	}
+	metrics.WeeklyVolume = weeklyVolume(sessions, start, metricWeeks)
+	metrics.IntensityTrend = intensityTrend(metrics.WeeklyVolume)
+
+	sets, err := h.storage.Muscles().WorkingSets(userID, asOf.AddDate(0, 0, -2*load.FatigueDays))
+	if err != nil {
+		return metrics, fmt.Errorf("failed to load working sets: %v", err)
+	}
+	for _, s := range sets {
+		age := calendarDate(s.Date).Sub(asOf).Hours() / -24
+		if s.Muscle == "" || age < 0 || !isHardSet(s) {
+			continue
+		}
+		weight := 1.0
+		if s.Role == models.MuscleRoleSecondary {
+			weight = defaultSecondaryFraction
+		}
+		metrics.MuscleGroupFatigue[s.Muscle] += weight * math.Exp(-age/load.FatigueDays)
+	}
+
+	records, err := h.storage.PersonalRecords().List(userID, "")
+	if err != nil {
+		return metrics, fmt.Errorf("failed to load personal records: %v", err)
+	}
+	metrics.PerformanceMetrics = performanceMetrics(days, sessions, records, metrics.IntensityTrend, asOf)
+
+	return metrics, nil
+}
+
+// trainingStreak returns how many days in a row, up to the last of the days, the user
+// trained and the rest day before them. A last day without training is not over yet, so
+// the run may end the day before.
+func trainingStreak(days []models.TrainingLoadDay) (int, *time.Time) {
+	i := len(days) - 1
+	if i >= 0 && days[i].Sessions == 0 {
+		i--
+	}
+	streak := 0
+	for ; i >= 0 && days[i].Sessions > 0; i-- {
+		streak++
+	}
+	if i < 0 {
+		return streak, nil
+	}
+	rest := days[i].Date
+	return streak, &rest
+}
+
+// weeklyVolume sums sessions into the given number of weeks from a Monday
+func weeklyVolume(sessions []models.TrainingSession, start time.Time, weeks int) []models.WeeklyVolumeData {
+	volume := make([]models.WeeklyVolumeData, weeks)
+	rated := make([]int, weeks)
+	for i := range volume {
+		volume[i].WeekStart = start.AddDate(0, 0, 7*i)
+	}
+	for _, s := range sessions {
+		day := calendarDate(s.Date)
+		week := int(day.Sub(start).Hours()) / (24 * 7)
+		if day.Before(start) || week >= weeks {
+			continue
+		}
+		volume[week].TotalVolume += s.Tonnage
+		volume[week].Workouts++
+		if s.RPE > 0 {
+			volume[week].AvgRPE += s.RPE
+			rated[week]++
+		}
+	}
+	for i := range volume {
+		if rated[i] > 0 {
+			volume[i].AvgRPE /= float64(rated[i])
+		}
+	}
+	return volume
+}
+
+// intensityTrend compares the average RPE of the last week with the weeks before it
+func intensityTrend(weeks []models.WeeklyVolumeData) string {
+	if len(weeks) < 2 || weeks[len(weeks)-1].AvgRPE == 0 {
+		return intensityStable
+	}
+	total, rated := 0.0, 0
+	for _, w := range weeks[:len(weeks)-1] {
+		if w.AvgRPE > 0 {
+			total += w.AvgRPE
+			rated++
+		}
+	}
+	if rated == 0 {
+		return intensityStable
+	}
+	switch change := weeks[len(weeks)-1].AvgRPE - total/float64(rated); {
+	case change > intensityTrendRPE:
+		return intensityIncreasing
+	case change < -intensityTrendRPE:
+		return intensityDecreasing
+	}
+	return intensityStable
+}
+
+// performanceMetrics reads signs of fatigue from the load of the last days, the volume
+// of recent sessions and the e1RM records
+func performanceMetrics(days []models.TrainingLoadDay, sessions []models.TrainingSession, records []models.PersonalRecordEntry,
+	trend string, asOf time.Time) models.PerformanceMetrics {
+	var metrics models.PerformanceMetrics
+	if len(days) == 0 {
+		return metrics
+	}
+	today := days[len(days)-1]
+	metrics.RecoveryScore = load.Recovery(today.Fitness, today.Performance)
+
+	lastWeek, before, trained := recentVolume(sessions, asOf)
+	if before > 0 && lastWeek < before {
+		metrics.VolumeDecline = 100 * (before - lastWeek) / before
+	}
+
+	// A plateau is a month of training without beating any e1RM record set before it
+	monthAgo := asOf.AddDate(0, 0, 1-load.ChronicDays)
+	newRecord, oldRecord := false, false
+	for _, r := range records {
+		if r.RecordType != models.RecordTypeE1RM {
+			continue
+		}
+		if calendarDate(r.Date).Before(monthAgo) {
+			oldRecord = true
+		} else if !calendarDate(r.Date).After(asOf) {
+			newRecord = true
+		}
+	}
+	metrics.StrengthPlateau = trained && oldRecord && !newRecord
+
+	for _, indicator := range []bool{
+		today.ACWR > load.HighACWR,
+		today.Monotony > load.HighMonotony,
+		today.Performance < 0,
+		metrics.StrengthPlateau,
+		metrics.VolumeDecline > 0 && trend == intensityIncreasing,
+	} {
+		if indicator {
+			metrics.FatigueIndicators++
+		}
+	}
+	return metrics
+}
+
+// recentVolume returns the tonnage of the week up to a day, the weekly average of the
+// three weeks before it and whether there were any sessions in those four weeks
+func recentVolume(sessions []models.TrainingSession, asOf time.Time) (lastWeek, before float64, trained bool) {
+	weekAgo := asOf.AddDate(0, 0, 1-load.AcuteDays)
+	monthAgo := asOf.AddDate(0, 0, 1-load.ChronicDays)
+	for _, s := range sessions {
+		switch day := calendarDate(s.Date); {
+		case day.After(asOf) || day.Before(monthAgo):
+		case day.Before(weekAgo):
+			before += s.Tonnage / 3
+			trained = true
+		default:
+			lastWeek += s.Tonnage
+			trained = true
+		}
+	}
+	return lastWeek, before, trained
+}
+
+// weekStart returns the Monday of the week of t
+func weekStart(t time.Time) time.Time {
+	day := calendarDate(t)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// calendarDate returns the calendar date of t as midnight UTC
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
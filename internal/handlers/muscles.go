@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+const (
+	// defaultMuscleVolumeWeeks and maxMuscleVolumeWeeks bound the weeks parameter
+	defaultMuscleVolumeWeeks = 4
+	maxMuscleVolumeWeeks     = 52
+	// defaultSecondaryFraction is how much a set counts for a muscle it trains secondarily
+	defaultSecondaryFraction = 0.5
+	// hardSetMaxReserve is the most reps in reserve a rated set may leave and still be hard
+	hardSetMaxReserve = 4
+)
+
+// GetMuscles returns the name of every muscle library exercises can train
+func (h *Handler) GetMuscles(w http.ResponseWriter, r *http.Request) {
+	muscles, err := h.storage.Muscles().List()
+	if err != nil {
+		http.Error(w, "Failed to load muscles", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(muscles)
+}
+
+// GetMuscleVolume returns the current user's hard sets and tonnage per muscle for each
+// of the last weeks, the current week included. Weeks start on Monday. The weeks query
+// parameter picks how many (default 4, at most 52) and secondary_fraction how much a set
+// counts for the muscles its exercise trains secondarily (default 0.5).
+func (h *Handler) GetMuscleVolume(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	weeks, fraction, err := parseMuscleVolumeParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := h.now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	daysSinceMonday := (int(today.Weekday()) + 6) % 7
+	start := today.AddDate(0, 0, -daysSinceMonday-7*(weeks-1))
+
+	sets, err := h.storage.Muscles().WorkingSets(userID, start)
+	if err != nil {
+		log.Printf("Failed to get muscle volume: %v", err)
+		http.Error(w, "Failed to load muscle volume", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(muscleVolume(sets, start, weeks, fraction))
+}
+
+// parseMuscleVolumeParams reads the weeks and secondary_fraction query parameters
+func parseMuscleVolumeParams(r *http.Request) (weeks int, fraction float64, err error) {
+	query := r.URL.Query()
+	weeks, fraction = defaultMuscleVolumeWeeks, defaultSecondaryFraction
+
+	if param := query.Get("weeks"); param != "" {
+		weeks, err = strconv.Atoi(param)
+		if err != nil || weeks < 1 || weeks > maxMuscleVolumeWeeks {
+			return 0, 0, fmt.Errorf("weeks must be between 1 and %d", maxMuscleVolumeWeeks)
+		}
+	}
+	if param := query.Get("secondary_fraction"); param != "" {
+		fraction, err = strconv.ParseFloat(param, 64)
+		if err != nil || fraction < 0 || fraction > 1 {
+			return 0, 0, fmt.Errorf("secondary_fraction must be between 0 and 1")
+		}
+	}
+	return weeks, fraction, nil
+}
+
+// muscleVolume sums working sets into weekly volume per muscle for the weeks from
+// start, counting sets of secondary muscles at the given fraction
+func muscleVolume(sets []models.MuscleSet, start time.Time, weeks int, fraction float64) models.MuscleVolumeResponse {
+	response := models.MuscleVolumeResponse{
+		SecondaryFraction: fraction,
+		Weeks:             make([]models.MuscleVolumeWeek, weeks),
+		UnlinkedExercises: []string{},
+	}
+	totals := make([]map[string]*models.MuscleVolume, weeks)
+	for i := range response.Weeks {
+		response.Weeks[i].WeekStart = start.AddDate(0, 0, 7*i).Format("2006-01-02")
+		totals[i] = make(map[string]*models.MuscleVolume)
+	}
+
+	unlinked := make(map[string]bool)
+	for _, s := range sets {
+		day := time.Date(s.Date.Year(), s.Date.Month(), s.Date.Day(), 0, 0, 0, 0, time.UTC)
+		week := int(day.Sub(start).Hours()) / (24 * 7)
+		if day.Before(start) || week >= weeks {
+			continue
+		}
+		if s.Muscle == "" {
+			if !unlinked[s.ExerciseName] {
+				unlinked[s.ExerciseName] = true
+				response.UnlinkedExercises = append(response.UnlinkedExercises, s.ExerciseName)
+			}
+			continue
+		}
+
+		weight := 1.0
+		if s.Role == models.MuscleRoleSecondary {
+			weight = fraction
+		}
+		volume, ok := totals[week][s.Muscle]
+		if !ok {
+			volume = &models.MuscleVolume{Muscle: s.Muscle}
+			totals[week][s.Muscle] = volume
+		}
+		if isHardSet(s) {
+			volume.HardSets += weight
+		}
+		volume.Tonnage += weight * s.Weight * float64(s.Reps)
+	}
+
+	for i, muscles := range totals {
+		response.Weeks[i].Muscles = make([]models.MuscleVolume, 0, len(muscles))
+		for _, volume := range muscles {
+			response.Weeks[i].Muscles = append(response.Weeks[i].Muscles, *volume)
+		}
+		sort.Slice(response.Weeks[i].Muscles, func(a, b int) bool {
+			x, y := response.Weeks[i].Muscles[a], response.Weeks[i].Muscles[b]
+			if x.HardSets != y.HardSets {
+				return x.HardSets > y.HardSets
+			}
+			return x.Muscle < y.Muscle
+		})
+	}
+	sort.Strings(response.UnlinkedExercises)
+
+	return response
+}
+
+// isHardSet reports whether a working set was done and taken close enough to failure to
+// count towards a muscle's weekly sets. Sets without an RPE or RIR rating count as hard.
+func isHardSet(s models.MuscleSet) bool {
+	if s.Reps < 1 && s.Duration < 1 {
+		return false
+	}
+	switch {
+	case s.RIR != nil:
+		return *s.RIR <= hardSetMaxReserve
+	case s.RPE != nil:
+		return 10-*s.RPE <= hardSetMaxReserve
+	}
+	return true
+}
+
+// attachMuscles fills in the primary and secondary muscles of library exercises
+func (h *Handler) attachMuscles(exercises []models.PredefinedExercise) error {
+	if len(exercises) == 0 {
+		return nil
+	}
+	muscles, err := h.storage.Muscles().ExerciseMuscles()
+	if err != nil {
+		return fmt.Errorf("failed to load exercise muscles: %v", err)
+	}
+
+	for i := range exercises {
+		e := &exercises[i]
+		e.PrimaryMuscles, e.SecondaryMuscles = []string{}, []string{}
+		for _, m := range muscles[e.ID] {
+			if m.Role == models.MuscleRolePrimary {
+				e.PrimaryMuscles = append(e.PrimaryMuscles, m.Muscle)
+			} else {
+				e.SecondaryMuscles = append(e.SecondaryMuscles, m.Muscle)
+			}
+		}
+	}
+	return nil
+}
+
+// libraryLink returns the library entry a request links an exercise to, nil to link
+// the entry of the same name
+func libraryLink(predefinedExerciseID int) *int {
+	if predefinedExerciseID == 0 {
+		return nil
+	}
+	return &predefinedExerciseID
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"io/fs"
+	"time"
+
+	"workout-tracker/internal/events"
+	"workout-tracker/internal/storage"
+
+	"github.com/gorilla/sessions"
+)
+
+// Option customises a Handler created by New
+type Option func(*config)
+
+// config collects the dependencies New wires into a Handler
+type config struct {
+	store     storage.Store
+	templates fs.FS
+	sessions  sessions.Store
+	events    *events.Bus
+	now       func() time.Time
+}
+
+// WithStore makes the handler persist data through the given store instead of
+// the one matching the database driver
+func WithStore(store storage.Store) Option {
+	return func(c *config) {
+		c.store = store
+	}
+}
+
+// WithTemplates loads the HTML templates from the *.html files at the root of fsys
+func WithTemplates(fsys fs.FS) Option {
+	return func(c *config) {
+		c.templates = fsys
+	}
+}
+
+// WithSessionStore replaces the cookie store that keeps logged-in sessions
+func WithSessionStore(store sessions.Store) Option {
+	return func(c *config) {
+		c.sessions = store
+	}
+}
+
+// WithClock replaces time.Now, so tests can pin the current time
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+// WithEventBus publishes change events on the given bus, so several handlers can share one
+func WithEventBus(bus *events.Bus) Option {
+	return func(c *config) {
+		c.events = bus
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"workout-tracker/internal/models"
+)
+
+// GetPersonalRecords returns the current user's personal records grouped by exercise,
+// each with its current records and the history that led to them. The optional
+// exercise query parameter limits the response to one exercise, ignoring case.
+func (h *Handler) GetPersonalRecords(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	exerciseName := strings.TrimSpace(r.URL.Query().Get("exercise"))
+	entries, err := h.storage.PersonalRecords().List(userID, exerciseName)
+	if err != nil {
+		log.Printf("Failed to get personal records: %v", err)
+		http.Error(w, "Failed to load personal records", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groupPersonalRecords(entries))
+}
+
+// groupPersonalRecords groups record entries, ordered by exercise and date, by exercise.
+// Current records are listed weight records first by rep count, then e1RM, set volume
+// and session volume.
+func groupPersonalRecords(entries []models.PersonalRecordEntry) models.PersonalRecordsResponse {
+	response := models.PersonalRecordsResponse{Exercises: []models.ExerciseRecords{}}
+	for _, entry := range entries {
+		last := len(response.Exercises) - 1
+		if last < 0 || response.Exercises[last].ExerciseName != entry.ExerciseName {
+			response.Exercises = append(response.Exercises, models.ExerciseRecords{
+				ExerciseName: entry.ExerciseName,
+				Current:      []models.PersonalRecordEntry{},
+			})
+			last++
+		}
+		exercise := &response.Exercises[last]
+		exercise.History = append(exercise.History, entry)
+		if entry.IsCurrent {
+			exercise.Current = append(exercise.Current, entry)
+		}
+	}
+
+	order := map[string]int{
+		models.RecordTypeWeight:        0,
+		models.RecordTypeE1RM:          1,
+		models.RecordTypeSetVolume:     2,
+		models.RecordTypeSessionVolume: 3,
+	}
+	for _, exercise := range response.Exercises {
+		sort.SliceStable(exercise.Current, func(i, j int) bool {
+			a, b := exercise.Current[i], exercise.Current[j]
+			if a.RecordType != b.RecordType {
+				return order[a.RecordType] < order[b.RecordType]
+			}
+			return a.Reps < b.Reps
+		})
+	}
+	return response
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workout-tracker/internal/events"
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateReminder sets a reminder for one of the user's scheduled workouts, minutes_before
+// its start in the user's time zone. A workout without a time starts at midnight. The
+// reminder follows the workout when it is moved or the user changes time zone. The
+// reminder dispatcher sends it by email, push or SMS once due, unless the user has turned
+// notifications off by then.
+func (h *Handler) CreateReminder(w http.ResponseWriter, r *http.Request) {
+	sw, ok := h.loadScheduledWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateReminderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	if sw.Status != models.ScheduledStatusScheduled {
+		http.Error(w, "Only workouts still scheduled can have reminders", http.StatusBadRequest)
+		return
+	}
+	settings, err := h.getUserSettings(sw.UserID)
+	if err != nil {
+		log.Printf("Failed to load settings: %v", err)
+		http.Error(w, "Failed to create reminder", http.StatusInternalServerError)
+		return
+	}
+	zone, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		zone = time.UTC
+	}
+	start := models.ScheduledStart(sw.ScheduledDate, sw.ScheduledTime, zone)
+	if !h.now().Before(start) {
+		http.Error(w, "The workout has already started", http.StatusBadRequest)
+		return
+	}
+
+	reminder := models.WorkoutReminder{
+		UserID:             sw.UserID,
+		ScheduledWorkoutID: sw.ID,
+		ReminderType:       req.ReminderType,
+		Message:            req.Message,
+		ScheduledFor:       start.Add(-time.Duration(req.MinutesBefore) * time.Minute),
+		MinutesBefore:      &req.MinutesBefore,
+	}
+	id, err := h.storage.Reminders().Create(reminder)
+	if err != nil {
+		log.Printf("Failed to create reminder: %v", err)
+		http.Error(w, "Failed to create reminder", http.StatusInternalServerError)
+		return
+	}
+	reminder, err = h.storage.Reminders().Get(id, sw.UserID)
+	if err != nil {
+		http.Error(w, "Reminder saved but failed to retrieve", http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, events.ReminderCreated, reminder)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reminder)
+}
+
+// GetReminders lists the reminders of one of the user's scheduled workouts, whether
+// pending, sent, failed or cancelled, earliest first
+func (h *Handler) GetReminders(w http.ResponseWriter, r *http.Request) {
+	sw, ok := h.loadScheduledWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	reminders, err := h.storage.Reminders().List(sw.ID, sw.UserID)
+	if err != nil {
+		log.Printf("Failed to list reminders: %v", err)
+		http.Error(w, "Failed to load reminders", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reminders)
+}
+
+// DeleteReminder removes one of the user's reminders, so it is not sent
+func (h *Handler) DeleteReminder(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid reminder ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.Reminders().Delete(id, userID); err != nil {
+		if err == storage.ErrNotFound {
+			http.Error(w, "Reminder not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to delete reminder: %v", err)
+		http.Error(w, "Failed to delete reminder", http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, events.ReminderDeleted, events.Deleted{ID: id})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// rescheduleReminders moves the user's pending reminders after one of their workouts was
+// moved or they changed time zone. The change that called for it is already saved, so a
+// failure is only logged.
+func (h *Handler) rescheduleReminders(userID int) {
+	settings, err := h.getUserSettings(userID)
+	if err != nil {
+		log.Printf("Failed to load settings to reschedule reminders: %v", err)
+		return
+	}
+	zone, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		zone = time.UTC
+	}
+	if err := h.storage.Reminders().Reschedule(userID, zone); err != nil {
+		log.Printf("Failed to reschedule reminders: %v", err)
+	}
+}
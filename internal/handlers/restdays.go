@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workout-tracker/internal/events"
+	"workout-tracker/internal/load"
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/recommend"
+	"workout-tracker/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// restDayListPast and restDayListAhead are the default period of the rest day list
+	// around today
+	restDayListPast  = 30
+	restDayListAhead = 7
+)
+
+// workoutLogged publishes a newly logged workout, completes the workout scheduled for its
+// day and, when the user's recent training calls for them, recommends a rest day after it
+// and a deload from the next day
+func (h *Handler) workoutLogged(r *http.Request, workout models.Workout) {
+	h.publish(r, events.WorkoutCreated, workout)
+
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		return
+	}
+
+	scheduled, err := h.storage.ScheduledWorkouts().Complete(userID, workout.ID, workout.Date)
+	if err == nil {
+		h.publish(r, events.ScheduledWorkoutUpdated, scheduled)
+	} else if err != storage.ErrNotFound {
+		log.Printf("Failed to complete scheduled workout: %v", err)
+	}
+
+	// A workout logged days later is history; the rest it called for is over
+	day := calendarDate(workout.Date)
+	if day.Before(calendarDate(h.now()).AddDate(0, 0, -1)) {
+		return
+	}
+
+	h.suggestRestDay(r, userID, day)
+	h.suggestDeload(r, userID, day)
+}
+
+// suggestRestDay recommends a rest day after a day of training if the rest day rules call for one
+func (h *Handler) suggestRestDay(r *http.Request, userID int, day time.Time) {
+	rec, ok, err := h.evaluateRestDay(userID, day)
+	if err != nil {
+		log.Printf("Failed to evaluate rest day: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	stored, err := h.storage.RestDays().Suggest(rec)
+	if err != nil {
+		log.Printf("Failed to suggest rest day: %v", err)
+		return
+	}
+	if stored.Status == models.RestDayStatusSuggested {
+		h.publish(r, events.RestDaySuggested, stored)
+	}
+}
+
+// evaluateRestDay runs the rest day rules on a user's training up to a day and returns
+// the rest day they recommend for the day after, if any
+func (h *Handler) evaluateRestDay(userID int, day time.Time) (models.RestDayRecommendation, bool, error) {
+	metrics, err := h.recommendationMetrics(userID, day)
+	if err != nil {
+		return models.RestDayRecommendation{}, false, err
+	}
+	sessions, err := h.storage.TrainingLoad().Sessions(userID, day.AddDate(0, 0, 1-load.ChronicDays))
+	if err != nil {
+		return models.RestDayRecommendation{}, false, err
+	}
+
+	volume, baseline, _ := recentVolume(sessions, day)
+	score, count := intensityScore(sessions, day)
+	reason, muscles := recommend.RestDay(recommend.Signals{
+		ConsecutiveDays: metrics.ConsecutiveDays,
+		VolumeLoad:      volume,
+		BaselineVolume:  baseline,
+		IntensityScore:  score,
+		Sessions:        count,
+		MuscleFatigue:   metrics.MuscleGroupFatigue,
+	})
+	if reason == "" {
+		return models.RestDayRecommendation{}, false, nil
+	}
+
+	worked, err := json.Marshal(muscles)
+	if err != nil {
+		return models.RestDayRecommendation{}, false, err
+	}
+	return models.RestDayRecommendation{
+		UserID:             userID,
+		RecommendedDate:    day.AddDate(0, 0, 1),
+		Reason:             reason,
+		IntensityScore:     score,
+		VolumeLoad:         volume,
+		ConsecutiveDays:    metrics.ConsecutiveDays,
+		MuscleGroupsWorked: string(worked),
+	}, true, nil
+}
+
+// intensityScore returns the mean session RPE of the last few days up to a day, unrated
+// sessions counting at load.DefaultRPE, and how many sessions there were
+func intensityScore(sessions []models.TrainingSession, day time.Time) (float64, int) {
+	since := day.AddDate(0, 0, 1-recommend.IntensityDays)
+	total, count := 0.0, 0
+	for _, s := range sessions {
+		if date := calendarDate(s.Date); date.Before(since) || date.After(day) {
+			continue
+		}
+		rpe := s.RPE
+		if rpe == 0 {
+			rpe = load.DefaultRPE
+		}
+		total += rpe
+		count++
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return total / float64(count), count
+}
+
+// GetRestDays lists the current user's rest day recommendations, by default from 30 days
+// ago to a week ahead. The from and to query parameters pick another period in YYYY-MM-DD
+// format and status keeps only the recommendations with that status.
+func (h *Handler) GetRestDays(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	from, to, status, err := h.parseRestDayListParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recs, err := h.storage.RestDays().List(userID, from, to, status)
+	if err != nil {
+		log.Printf("Failed to get rest days: %v", err)
+		http.Error(w, "Failed to load rest days", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recs)
+}
+
+// parseRestDayListParams reads the from, to and status query parameters
+func (h *Handler) parseRestDayListParams(r *http.Request) (from, to time.Time, status string, err error) {
+	from, to, err = h.parseListPeriod(r, restDayListPast, restDayListAhead)
+	if err != nil {
+		return from, to, "", err
+	}
+
+	switch status = r.URL.Query().Get("status"); status {
+	case "", models.RestDayStatusSuggested, models.RestDayStatusAccepted, models.RestDayStatusIgnored, models.RestDayStatusOverridden:
+	default:
+		return from, to, "", fmt.Errorf("status must be one of: suggested, accepted, ignored, overridden")
+	}
+	return from, to, status, nil
+}
+
+// parseListPeriod reads the from and to query parameters of a list, which default to the
+// given number of days before and after today
+func (h *Handler) parseListPeriod(r *http.Request, past, ahead int) (from, to time.Time, err error) {
+	query := r.URL.Query()
+	today := calendarDate(h.now())
+	from, to = today.AddDate(0, 0, -past), today.AddDate(0, 0, ahead)
+	if param := query.Get("from"); param != "" {
+		if from, err = time.Parse("2006-01-02", param); err != nil {
+			return from, to, fmt.Errorf("from must be a date in YYYY-MM-DD format")
+		}
+	}
+	if param := query.Get("to"); param != "" {
+		if to, err = time.Parse("2006-01-02", param); err != nil {
+			return from, to, fmt.Errorf("to must be a date in YYYY-MM-DD format")
+		}
+	}
+	if from.After(to) {
+		return from, to, fmt.Errorf("from must not be after to")
+	}
+	return from, to, nil
+}
+
+// GetRestDay returns one of the current user's rest day recommendations
+func (h *Handler) GetRestDay(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid rest day ID", http.StatusBadRequest)
+		return
+	}
+
+	rec, err := h.storage.RestDays().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Rest day not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// RespondToRestDay records whether the user accepts, ignores or overrides a rest day
+// recommendation. A later answer replaces an earlier one.
+func (h *Handler) RespondToRestDay(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid rest day ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.RestDayResponseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	err = h.storage.RestDays().Respond(id, userID, req.Status, req.UserResponse)
+	if err == storage.ErrNotFound {
+		http.Error(w, "Rest day not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to respond to rest day: %v", err)
+		http.Error(w, "Failed to update rest day", http.StatusInternalServerError)
+		return
+	}
+
+	rec, err := h.storage.RestDays().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Rest day updated but failed to retrieve", http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, events.RestDayUpdated, rec)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// GetPlanningAnalytics returns how closely the current user follows their plan. The
+// completion rate is the percentage of scheduled workouts whose day has come that the
+// user completed, leaving out cancelled ones. Rest day compliance is the percentage of
+// rest days recommended before today on which the user logged no workout, deload
+// compliance the percentage of answered deload recommendations the user accepted.
+func (h *Handler) GetPlanningAnalytics(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	analytics, err := h.storage.ScheduledWorkouts().Summary(userID, h.now())
+	if err != nil {
+		log.Printf("Failed to get scheduled workout summary: %v", err)
+		http.Error(w, "Failed to load planning analytics", http.StatusInternalServerError)
+		return
+	}
+	if due := analytics.CompletedWorkouts + analytics.SkippedWorkouts + analytics.OverdueWorkouts; due > 0 {
+		analytics.CompletionRate = 100 * float64(analytics.CompletedWorkouts) / float64(due)
+	}
+
+	recommended, rested, err := h.storage.RestDays().Compliance(userID, h.now())
+	if err != nil {
+		log.Printf("Failed to get rest day compliance: %v", err)
+		http.Error(w, "Failed to load planning analytics", http.StatusInternalServerError)
+		return
+	}
+	if recommended > 0 {
+		analytics.RestDayCompliance = 100 * float64(rested) / float64(recommended)
+	}
+
+	answered, accepted, active, err := h.storage.Deloads().Compliance(userID, h.now())
+	if err != nil {
+		log.Printf("Failed to get deload compliance: %v", err)
+		http.Error(w, "Failed to load planning analytics", http.StatusInternalServerError)
+		return
+	}
+	analytics.ActiveDeloads = active
+	if answered > 0 {
+		analytics.DeloadCompliance = 100 * float64(accepted) / float64(answered)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analytics)
+}
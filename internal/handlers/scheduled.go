@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workout-tracker/internal/events"
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// scheduledListPast and scheduledListAhead are the default period of the scheduled
+	// workout list around today
+	scheduledListPast  = 30
+	scheduledListAhead = 60
+	// defaultScheduledDuration is the estimated duration, in minutes, of a workout
+	// scheduled without one
+	defaultScheduledDuration = 60
+)
+
+// GetScheduledWorkouts lists the current user's scheduled workouts, by default from 30
+// days ago to 60 days ahead. The from and to query parameters pick another period in
+// YYYY-MM-DD format and status keeps only the workouts with that status.
+func (h *Handler) GetScheduledWorkouts(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	from, to, err := h.parseListPeriod(r, scheduledListPast, scheduledListAhead)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.ScheduledStatusScheduled, models.ScheduledStatusCompleted, models.ScheduledStatusSkipped, models.ScheduledStatusCancelled:
+	default:
+		http.Error(w, "status must be one of: scheduled, completed, skipped, cancelled", http.StatusBadRequest)
+		return
+	}
+
+	workouts, err := h.storage.ScheduledWorkouts().List(userID, from, to, status)
+	if err != nil {
+		log.Printf("Failed to get scheduled workouts: %v", err)
+		http.Error(w, "Failed to load scheduled workouts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workouts)
+}
+
+// CreateScheduledWorkout schedules a workout for a day, optionally planned from one of the
+// user's templates
+func (h *Handler) CreateScheduledWorkout(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateScheduledWorkoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	sw := models.ScheduledWorkout{
+		UserID:            userID,
+		Title:             req.Title,
+		Description:       req.Description,
+		EstimatedDuration: req.EstimatedDuration,
+		Status:            models.ScheduledStatusScheduled,
+		Notes:             req.Notes,
+	}
+	if err := parseSchedule(&sw, req.ScheduledDate, req.ScheduledTime); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.TemplateID != nil {
+		template, err := h.storage.Templates().Get(*req.TemplateID, userID)
+		if err != nil {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		sw.TemplateID = &template.ID
+	}
+
+	id, err := h.storage.ScheduledWorkouts().Create(sw)
+	if err != nil {
+		log.Printf("Failed to create scheduled workout: %v", err)
+		http.Error(w, "Failed to schedule workout", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeScheduledWorkout(w, r, http.StatusCreated, events.ScheduledWorkoutCreated, id, userID)
+}
+
+// GetScheduledWorkout returns a scheduled workout with the exercises of its template
+func (h *Handler) GetScheduledWorkout(w http.ResponseWriter, r *http.Request) {
+	sw, ok := h.loadScheduledWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sw)
+}
+
+// UpdateScheduledWorkout reschedules a workout or changes its details or status. Leaving
+// out the status keeps the current one.
+func (h *Handler) UpdateScheduledWorkout(w http.ResponseWriter, r *http.Request) {
+	sw, ok := h.loadScheduledWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateScheduledWorkoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	sw.Title, sw.Description, sw.EstimatedDuration, sw.Notes = req.Title, req.Description, req.EstimatedDuration, req.Notes
+	if req.Status != "" {
+		sw.Status = req.Status
+	}
+	if err := parseSchedule(&sw, req.ScheduledDate, req.ScheduledTime); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.ScheduledWorkouts().Update(sw); err != nil {
+		if err == storage.ErrNotFound {
+			http.Error(w, "Scheduled workout not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to update scheduled workout: %v", err)
+		http.Error(w, "Failed to update scheduled workout", http.StatusInternalServerError)
+		return
+	}
+	h.rescheduleReminders(sw.UserID)
+
+	h.writeScheduledWorkout(w, r, http.StatusOK, events.ScheduledWorkoutUpdated, sw.ID, sw.UserID)
+}
+
+// DeleteScheduledWorkout removes a workout from the user's schedule
+func (h *Handler) DeleteScheduledWorkout(w http.ResponseWriter, r *http.Request) {
+	sw, ok := h.loadScheduledWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.ScheduledWorkouts().Delete(sw.ID, sw.UserID); err != nil {
+		http.Error(w, "Scheduled workout not found", http.StatusNotFound)
+		return
+	}
+	h.publish(r, events.ScheduledWorkoutDeleted, events.Deleted{ID: sw.ID})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCalendarMonth returns the current user's calendar for a month, picked by the month
+// query parameter in YYYY-MM format and the current one by default. It covers the whole
+// weeks, Monday to Sunday, the month falls in. Each day holds the workouts scheduled on
+// it, the number of workouts logged, and the rest day and deload recommended for it
+// unless the user ignored or overrode them. The occurrences of calendar events in those
+// weeks are listed alongside.
+func (h *Handler) GetCalendarMonth(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	today := calendarDate(h.now())
+	month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if param := r.URL.Query().Get("month"); param != "" {
+		if month, err = time.Parse("2006-01", param); err != nil {
+			http.Error(w, "month must be in YYYY-MM format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	view, err := h.calendarMonth(userID, month, today)
+	if err != nil {
+		log.Printf("Failed to get calendar: %v", err)
+		http.Error(w, "Failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
+
+// calendarMonth builds the calendar of a user for the month starting on the given day
+func (h *Handler) calendarMonth(userID int, month, today time.Time) (models.MonthlyCalendarView, error) {
+	view := models.MonthlyCalendarView{
+		Year:  month.Year(),
+		Month: int(month.Month()),
+		Days:  []models.CalendarDay{},
+	}
+	start := weekStart(month)
+	end := weekStart(month.AddDate(0, 1, -1)).AddDate(0, 0, 6)
+
+	scheduled, err := h.storage.ScheduledWorkouts().List(userID, start, end, "")
+	if err != nil {
+		return view, err
+	}
+	logged, err := h.storage.TrainingLoad().Days(userID, start, end)
+	if err != nil {
+		return view, err
+	}
+	restDays, err := h.storage.RestDays().List(userID, start, end, "")
+	if err != nil {
+		return view, err
+	}
+	deloads, err := h.storage.Deloads().List(userID, start, end, "")
+	if err != nil {
+		return view, err
+	}
+	if view.Events, err = h.calendarEvents(userID, start, end); err != nil {
+		return view, err
+	}
+
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		cd := models.CalendarDay{
+			Date:           day,
+			IsCurrentMonth: day.Month() == month.Month(),
+			IsToday:        day.Equal(today),
+			Workouts:       []models.ScheduledWorkout{},
+		}
+		for _, sw := range scheduled {
+			if calendarDate(sw.ScheduledDate).Equal(day) {
+				cd.Workouts = append(cd.Workouts, sw)
+			}
+		}
+		for _, l := range logged {
+			if calendarDate(l.Date).Equal(day) {
+				cd.WorkoutCount = l.Sessions
+			}
+		}
+		for i, rd := range restDays {
+			if calendarDate(rd.RecommendedDate).Equal(day) && (rd.Status == models.RestDayStatusSuggested || rd.Status == models.RestDayStatusAccepted) {
+				cd.RestDay = &restDays[i]
+			}
+		}
+		for i, d := range deloads {
+			if !day.Before(d.RecommendedStartDate) && !day.After(d.RecommendedEndDate) && d.Status != models.DeloadStatusIgnored {
+				cd.Deload = &deloads[i]
+			}
+		}
+		view.Days = append(view.Days, cd)
+	}
+	return view, nil
+}
+
+// parseSchedule sets the day and optional time of a scheduled workout from their YYYY-MM-DD
+// and HH:MM forms, and gives it the default duration if it has none
+func parseSchedule(sw *models.ScheduledWorkout, date, clock string) error {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return fmt.Errorf("scheduled_date must be a date in YYYY-MM-DD format")
+	}
+	sw.ScheduledDate, sw.ScheduledTime = day, nil
+	if clock != "" {
+		if _, err := time.Parse("15:04", clock); err != nil {
+			return fmt.Errorf("scheduled_time must be a time in HH:MM format")
+		}
+		sw.ScheduledTime = &clock
+	}
+	if sw.EstimatedDuration == 0 {
+		sw.EstimatedDuration = defaultScheduledDuration
+	}
+	return nil
+}
+
+// loadScheduledWorkout loads the current user's scheduled workout named in the URL,
+// writing a 404 if there is none
+func (h *Handler) loadScheduledWorkout(w http.ResponseWriter, r *http.Request) (models.ScheduledWorkout, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid scheduled workout ID", http.StatusBadRequest)
+		return models.ScheduledWorkout{}, false
+	}
+
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return models.ScheduledWorkout{}, false
+	}
+
+	sw, err := h.storage.ScheduledWorkouts().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Scheduled workout not found", http.StatusNotFound)
+		return models.ScheduledWorkout{}, false
+	}
+	return sw, true
+}
+
+// writeScheduledWorkout reloads a scheduled workout, publishes it and writes it with the given status
+func (h *Handler) writeScheduledWorkout(w http.ResponseWriter, r *http.Request, status int, eventType string, id, userID int) {
+	sw, err := h.storage.ScheduledWorkouts().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Scheduled workout saved but failed to retrieve", http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, eventType, sw)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(sw)
+}
@@ -0,0 +1,462 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workout-tracker/internal/events"
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// StartSession starts a live workout session, empty or planned from a template.
+// A user can only have one session in progress at a time.
+func (h *Handler) StartSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.StartSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	if _, err := h.storage.Sessions().Active(userID); err == nil {
+		http.Error(w, "A session is already in progress", http.StatusConflict)
+		return
+	} else if err != storage.ErrNotFound {
+		log.Printf("Failed to check for an active session: %v", err)
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	session := models.WorkoutSession{
+		UserID:    userID,
+		Name:      req.Name,
+		StartedAt: h.now(),
+	}
+
+	if req.TemplateID != 0 {
+		template, err := h.storage.Templates().Get(req.TemplateID, userID)
+		if err != nil {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		templateExercises, err := h.storage.Templates().Exercises(template.ID)
+		if err != nil {
+			log.Printf("Failed to load template exercises: %v", err)
+			http.Error(w, "Failed to start session", http.StatusInternalServerError)
+			return
+		}
+
+		session.TemplateID = &template.ID
+		if session.Name == "" {
+			session.Name = template.Name
+		}
+		for _, te := range templateExercises {
+			session.Exercises = append(session.Exercises, models.SessionExercise{
+				Name:         te.Name,
+				Category:     te.Category,
+				GroupID:      te.GroupID,
+				TargetSets:   te.TargetSets,
+				TargetReps:   te.TargetReps,
+				TargetWeight: te.TargetWeight,
+				RestTime:     te.RestTime,
+			})
+		}
+	}
+	if session.Name == "" {
+		session.Name = "Workout"
+	}
+
+	id, err := h.storage.Sessions().Create(session)
+	if err == storage.ErrConflict {
+		// Another request started a session since the check above
+		http.Error(w, "A session is already in progress", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create session: %v", err)
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeSession(w, r, http.StatusCreated, events.SessionStarted, id, userID)
+}
+
+// GetActiveSession returns the session the user has in progress, so another device can resume it
+func (h *Handler) GetActiveSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := h.storage.Sessions().Active(userID)
+	if err != nil {
+		http.Error(w, "No session in progress", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.withRestRemaining(session))
+}
+
+// GetSession returns a session with its exercises, completed sets and rest timer
+func (h *Handler) GetSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadSession(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.withRestRemaining(session))
+}
+
+// UpdateSession moves a session to another exercise and restarts or stops its rest timer
+func (h *Handler) UpdateSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadUnfinishedSession(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	if req.CurrentExerciseID != 0 {
+		if _, ok := session.Exercise(req.CurrentExerciseID); !ok {
+			http.Error(w, "Session exercise not found", http.StatusNotFound)
+			return
+		}
+		session.CurrentExerciseID = req.CurrentExerciseID
+	}
+	if req.RestTime != nil {
+		session.RestEndsAt = h.restEndsAt(*req.RestTime)
+	}
+
+	if err := h.storage.Sessions().UpdateState(session); err != nil {
+		log.Printf("Failed to update session: %v", err)
+		http.Error(w, "Failed to update session", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeSession(w, r, http.StatusOK, events.SessionUpdated, session.ID, session.UserID)
+}
+
+// AddSessionExercise adds an exercise to the end of a running session
+func (h *Handler) AddSessionExercise(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadUnfinishedSession(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AddSessionExerciseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	exerciseID, err := h.storage.Sessions().AddExercise(models.SessionExercise{
+		SessionID:    session.ID,
+		Name:         req.Name,
+		Category:     req.Category,
+		GroupID:      req.GroupID,
+		TargetSets:   req.TargetSets,
+		TargetReps:   req.TargetReps,
+		TargetWeight: req.TargetWeight,
+		RestTime:     req.RestTime,
+	})
+	if err != nil {
+		log.Printf("Failed to add session exercise: %v", err)
+		http.Error(w, "Failed to add exercise", http.StatusInternalServerError)
+		return
+	}
+
+	if session.CurrentExerciseID == 0 {
+		session.CurrentExerciseID = exerciseID
+		if err := h.storage.Sessions().UpdateState(session); err != nil {
+			log.Printf("Failed to update session: %v", err)
+		}
+	}
+
+	h.writeSession(w, r, http.StatusCreated, events.SessionUpdated, session.ID, session.UserID)
+}
+
+// CompleteSessionSet records a completed set, starts the rest timer and moves the
+// session on to the next exercise once the set finishes the exercise's target sets
+func (h *Handler) CompleteSessionSet(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadUnfinishedSession(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CompleteSessionSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	exercise, ok := session.Exercise(req.ExerciseID)
+	if !ok {
+		http.Error(w, "Session exercise not found", http.StatusNotFound)
+		return
+	}
+
+	restTime := exercise.RestTime
+	if req.RestTime != nil {
+		restTime = *req.RestTime
+	}
+
+	// The store numbers the set after the exercise's last one
+	set := models.Set{
+		ExerciseID: exercise.ID,
+		Reps:       req.Reps,
+		Weight:     req.Weight,
+		Distance:   req.Distance,
+		Duration:   req.Duration,
+		RestTime:   restTime,
+		RPE:        req.RPE,
+		RIR:        req.RIR,
+		SetType:    models.SetTypeOrDefault(req.SetType),
+		Notes:      req.Notes,
+	}
+	if _, err := h.storage.Sessions().AddSet(set); err != nil {
+		log.Printf("Failed to record session set: %v", err)
+		http.Error(w, "Failed to record set", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range session.Exercises {
+		if session.Exercises[i].ID == exercise.ID {
+			session.Exercises[i].Sets = append(session.Exercises[i].Sets, set)
+		}
+	}
+	session.CurrentExerciseID = nextSessionExercise(session, exercise.ID)
+	session.RestEndsAt = h.restEndsAt(restTime)
+
+	if err := h.storage.Sessions().UpdateState(session); err != nil {
+		log.Printf("Failed to update session: %v", err)
+		http.Error(w, "Failed to update session", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeSession(w, r, http.StatusCreated, events.SessionUpdated, session.ID, session.UserID)
+}
+
+// FinishSession saves the session as a workout whose duration is the time since the
+// session started. Exercises without completed sets are left out of the workout.
+func (h *Handler) FinishSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadUnfinishedSession(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.FinishSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !validateRequest(w, req) {
+		return
+	}
+
+	finishedAt := h.now()
+	session.FinishedAt = &finishedAt
+
+	workout := models.Workout{
+		Name:     session.Name,
+		Date:     session.StartedAt,
+		Duration: int(finishedAt.Sub(session.StartedAt).Round(time.Minute) / time.Minute),
+		Notes:    req.Notes,
+	}
+	for _, exercise := range session.Exercises {
+		if len(exercise.Sets) == 0 {
+			continue
+		}
+		workout.Exercises = append(workout.Exercises, models.Exercise{
+			Name:     exercise.Name,
+			Category: exercise.Category,
+			GroupID:  exercise.GroupID,
+			Sets:     exercise.Sets,
+		})
+	}
+
+	workoutID, err := h.storage.Sessions().Finish(session, workout)
+	if err == storage.ErrNotFound {
+		http.Error(w, "Session already finished", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to finish session: %v", err)
+		http.Error(w, "Failed to finish session", http.StatusInternalServerError)
+		return
+	}
+
+	created, err := h.storage.Workouts().Get(workoutID, session.UserID)
+	if err != nil {
+		http.Error(w, "Failed to load workout", http.StatusInternalServerError)
+		return
+	}
+	session.WorkoutID = &workoutID
+	session.RestEndsAt = nil
+	h.publish(r, events.SessionFinished, session)
+	h.workoutLogged(r, created)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// DeleteSession discards a session without saving a workout
+func (h *Handler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadSession(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.Sessions().Delete(session.ID, session.UserID); err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	h.publish(r, events.SessionDeleted, events.Deleted{ID: session.ID})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadSession loads the current user's session named in the URL, writing a 404 if there is none
+func (h *Handler) loadSession(w http.ResponseWriter, r *http.Request) (models.WorkoutSession, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return models.WorkoutSession{}, false
+	}
+
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return models.WorkoutSession{}, false
+	}
+
+	session, err := h.storage.Sessions().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return models.WorkoutSession{}, false
+	}
+	return session, true
+}
+
+// loadUnfinishedSession is loadSession for changes, which finished sessions refuse with a 409
+func (h *Handler) loadUnfinishedSession(w http.ResponseWriter, r *http.Request) (models.WorkoutSession, bool) {
+	session, ok := h.loadSession(w, r)
+	if !ok {
+		return session, false
+	}
+	if session.FinishedAt != nil {
+		http.Error(w, "Session already finished", http.StatusConflict)
+		return session, false
+	}
+	return session, true
+}
+
+// writeSession reloads a session, publishes it as an event of the given type and
+// writes it with the given status
+func (h *Handler) writeSession(w http.ResponseWriter, r *http.Request, status int, eventType string, id, userID int) {
+	session, err := h.storage.Sessions().Get(id, userID)
+	if err != nil {
+		http.Error(w, "Failed to load session", http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, eventType, session)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(h.withRestRemaining(session))
+}
+
+// withRestRemaining fills in the seconds left on the session's rest timer
+func (h *Handler) withRestRemaining(session models.WorkoutSession) models.WorkoutSession {
+	session.RestRemaining = 0
+	if session.RestEndsAt != nil {
+		if remaining := session.RestEndsAt.Sub(h.now()); remaining > 0 {
+			session.RestRemaining = int((remaining + time.Second - 1) / time.Second)
+		}
+	}
+	return session
+}
+
+// restEndsAt returns when a rest of the given seconds started now ends, or nil for no rest
+func (h *Handler) restEndsAt(seconds int) *time.Time {
+	if seconds <= 0 {
+		return nil
+	}
+	endsAt := h.now().Add(time.Duration(seconds) * time.Second)
+	return &endsAt
+}
+
+// nextSessionExercise picks the exercise to do after a set of the given one. Within a
+// superset or circuit it moves on to the next member of the group with sets left to do.
+// Otherwise it stays on the exercise until its target sets are done, then moves to the
+// next exercise with sets left. Exercises without a target always have sets left.
+func nextSessionExercise(session models.WorkoutSession, exerciseID int) int {
+	exercises := session.Exercises
+	current := -1
+	for i, exercise := range exercises {
+		if exercise.ID == exerciseID {
+			current = i
+		}
+	}
+	if current < 0 {
+		return session.CurrentExerciseID
+	}
+
+	setsLeft := func(exercise models.SessionExercise) bool {
+		return exercise.TargetSets == 0 || len(exercise.Sets) < exercise.TargetSets
+	}
+
+	if group := exercises[current].GroupID; group != 0 {
+		for k := 1; k <= len(exercises); k++ {
+			candidate := exercises[(current+k)%len(exercises)]
+			if candidate.GroupID == group && setsLeft(candidate) {
+				return candidate.ID
+			}
+		}
+	} else if setsLeft(exercises[current]) {
+		return exerciseID
+	}
+
+	for k := 1; k < len(exercises); k++ {
+		if candidate := exercises[(current+k)%len(exercises)]; setsLeft(candidate) {
+			return candidate.ID
+		}
+	}
+	return exerciseID
+}
@@ -0,0 +1,432 @@
+// Package ical writes and reads the parts of RFC 5545 iCalendar files the training
+// calendar needs: VEVENT components with their times, text, status and recurrence.
+//
+// Times are written in one of four forms: dates for all-day events, UTC times, times in
+// a named zone with a TZID parameter, and floating times, which mean the same clock time
+// wherever the calendar is read. Zones are referred to by their IANA names without
+// VTIMEZONE components, which calendar applications resolve themselves.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+	// maxLineLength is the number of octets after which content lines are folded
+	maxLineLength = 75
+)
+
+// Event is a VEVENT
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	// Start and End bound the event; End is exclusive, so an all-day event on one day
+	// ends at midnight of the next
+	Start time.Time
+	End   time.Time
+	// AllDay events are written and read as dates
+	AllDay bool
+	// Floating times have no zone; they are read in UTC and written without one
+	Floating   bool
+	Status     string // TENTATIVE, CONFIRMED or CANCELLED
+	Categories []string
+	// RRule is the recurrence rule without the RRULE: prefix and ExDates the start times
+	// of the occurrences left out of it
+	RRule   string
+	ExDates []time.Time
+	// RecurrenceID is the original start of the occurrence of a series this event replaces
+	RecurrenceID *time.Time
+	// Stamp is when the event was last changed; Write uses the current time when unset
+	Stamp time.Time
+}
+
+// Calendar is a VCALENDAR holding events
+type Calendar struct {
+	// Name is shown by calendar applications subscribing to the calendar
+	Name   string
+	Events []Event
+}
+
+// Write writes the calendar in iCalendar format
+func Write(w io.Writer, cal Calendar) error {
+	b := &builder{w: bufio.NewWriter(w)}
+	b.line("BEGIN", nil, "VCALENDAR")
+	b.line("VERSION", nil, "2.0")
+	b.line("PRODID", nil, "-//workout-tracker//Training schedule//EN")
+	b.line("CALSCALE", nil, "GREGORIAN")
+	if cal.Name != "" {
+		b.line("X-WR-CALNAME", nil, escape(cal.Name))
+	}
+
+	now := time.Now()
+	for _, e := range cal.Events {
+		b.line("BEGIN", nil, "VEVENT")
+		b.line("UID", nil, escape(e.UID))
+		stamp := e.Stamp
+		if stamp.IsZero() {
+			stamp = now
+		}
+		b.line("DTSTAMP", nil, stamp.UTC().Format(dateTimeLayout)+"Z")
+		b.time("DTSTART", e.Start, e)
+		if !e.End.IsZero() {
+			b.time("DTEND", e.End, e)
+		}
+		if e.RecurrenceID != nil {
+			b.time("RECURRENCE-ID", *e.RecurrenceID, e)
+		}
+		b.line("SUMMARY", nil, escape(e.Summary))
+		if e.Description != "" {
+			b.line("DESCRIPTION", nil, escape(e.Description))
+		}
+		if e.Status != "" {
+			b.line("STATUS", nil, e.Status)
+		}
+		if len(e.Categories) > 0 {
+			categories := make([]string, len(e.Categories))
+			for i, category := range e.Categories {
+				categories[i] = escape(category)
+			}
+			b.line("CATEGORIES", nil, strings.Join(categories, ","))
+		}
+		if e.RRule != "" {
+			b.line("RRULE", nil, e.RRule)
+		}
+		for _, exdate := range e.ExDates {
+			b.time("EXDATE", exdate, e)
+		}
+		b.line("END", nil, "VEVENT")
+	}
+
+	b.line("END", nil, "VCALENDAR")
+	if b.err != nil {
+		return b.err
+	}
+	return b.w.Flush()
+}
+
+// builder writes folded content lines, keeping the first error
+type builder struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes a property with its parameters, folding it after 75 octets
+func (b *builder) line(name string, params []string, value string) {
+	if b.err != nil {
+		return
+	}
+	line := name
+	for _, param := range params {
+		line += ";" + param
+	}
+	line += ":" + value
+
+	for len(line) > maxLineLength {
+		// Fold before a character boundary so multi-byte characters stay whole
+		cut := maxLineLength
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		if _, b.err = b.w.WriteString(line[:cut] + "\r\n "); b.err != nil {
+			return
+		}
+		line = line[cut:]
+	}
+	_, b.err = b.w.WriteString(line + "\r\n")
+}
+
+// time writes a date or time property in the form the event's times take
+func (b *builder) time(name string, t time.Time, e Event) {
+	switch {
+	case e.AllDay:
+		b.line(name, []string{"VALUE=DATE"}, t.Format(dateLayout))
+	case e.Floating:
+		b.line(name, nil, t.Format(dateTimeLayout))
+	case t.Location() == time.UTC:
+		b.line(name, nil, t.Format(dateTimeLayout)+"Z")
+	default:
+		b.line(name, []string{"TZID=" + t.Location().String()}, t.Format(dateTimeLayout))
+	}
+}
+
+// escape escapes a TEXT value
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// unescape reverses escape
+func unescape(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			out.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			out.WriteByte('\n')
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+	return out.String()
+}
+
+// Parse reads the events of an iCalendar file. Components other than events, such as
+// time zones and alarms, and properties the Event type has no place for are ignored.
+// Times in zones Go does not know by name are read as floating times.
+func Parse(r io.Reader) (Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return Calendar{}, err
+	}
+
+	var cal Calendar
+	var event *Event
+	var hasEnd bool
+	var duration time.Duration
+	// nested counts the components open inside the current event, such as alarms
+	nested := 0
+	seenCalendar := false
+
+	for n, raw := range lines {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		name, params, value, err := splitLine(raw)
+		if err != nil {
+			return cal, fmt.Errorf("line %d: %v", n+1, err)
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCALENDAR"):
+			seenCalendar = true
+			continue
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT") && event == nil:
+			event, hasEnd, duration = &Event{}, false, 0
+			continue
+		case name == "BEGIN" && event != nil:
+			nested++
+			continue
+		case name == "END" && event != nil && nested > 0:
+			nested--
+			continue
+		case name == "END" && strings.EqualFold(value, "VEVENT") && event != nil:
+			if !hasEnd && !event.Start.IsZero() {
+				event.End = event.Start.Add(duration)
+				if event.AllDay && duration == 0 {
+					event.End = event.Start.AddDate(0, 0, 1)
+				}
+			}
+			cal.Events = append(cal.Events, *event)
+			event = nil
+			continue
+		case name == "X-WR-CALNAME" && event == nil:
+			cal.Name = unescape(value)
+			continue
+		}
+		if event == nil || nested > 0 {
+			continue
+		}
+
+		switch name {
+		case "UID":
+			event.UID = unescape(value)
+		case "SUMMARY":
+			event.Summary = unescape(value)
+		case "DESCRIPTION":
+			event.Description = unescape(value)
+		case "STATUS":
+			event.Status = strings.ToUpper(value)
+		case "CATEGORIES":
+			for _, category := range splitList(value) {
+				event.Categories = append(event.Categories, unescape(category))
+			}
+		case "RRULE":
+			event.RRule = value
+		case "DTSTART", "DTEND", "RECURRENCE-ID", "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				t, allDay, floating, err := parseTime(v, params)
+				if err != nil {
+					return cal, fmt.Errorf("line %d: invalid %s: %v", n+1, name, err)
+				}
+				switch name {
+				case "DTSTART":
+					event.Start, event.AllDay, event.Floating = t, allDay, floating
+				case "DTEND":
+					event.End, hasEnd = t, true
+				case "RECURRENCE-ID":
+					event.RecurrenceID = &t
+				case "EXDATE":
+					event.ExDates = append(event.ExDates, t)
+				}
+			}
+		case "DURATION":
+			d, err := parseDuration(value)
+			if err != nil {
+				return cal, fmt.Errorf("line %d: invalid DURATION: %v", n+1, err)
+			}
+			duration = d
+		}
+	}
+
+	if !seenCalendar {
+		return cal, fmt.Errorf("not an iCalendar file: no VCALENDAR")
+	}
+	if event != nil {
+		return cal, fmt.Errorf("VEVENT is never ended")
+	}
+	return cal, nil
+}
+
+// unfold reads the content lines of a file, joining folded lines back together
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %v", err)
+	}
+	return lines, nil
+}
+
+// splitLine splits a content line into its upper-cased name, its parameters keyed by
+// upper-cased name and its value
+func splitLine(line string) (string, map[string]string, string, error) {
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", fmt.Errorf("missing ':' in %q", line)
+	}
+
+	parts := splitParams(line[:colon])
+	params := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return "", nil, "", fmt.Errorf("invalid parameter %q", part)
+		}
+		params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], nil
+}
+
+// splitParams splits the name and parameters of a content line at semicolons outside
+// quoted parameter values
+func splitParams(s string) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ';' && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// splitList splits a TEXT list at commas that are not escaped
+func splitList(s string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseTime reads a DATE or DATE-TIME value, reporting whether it is a date and whether
+// it is floating
+func parseTime(value string, params map[string]string) (t time.Time, allDay, floating bool, err error) {
+	value = strings.TrimSpace(value)
+	if params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		t, err = time.Parse(dateLayout, value)
+		return t, true, false, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse(dateTimeLayout, strings.TrimSuffix(value, "Z"))
+		return t, false, false, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		if loc, err := time.LoadLocation(tzid); err == nil {
+			t, err = time.ParseInLocation(dateTimeLayout, value, loc)
+			return t, false, false, err
+		}
+	}
+	t, err = time.Parse(dateTimeLayout, value)
+	return t, false, true, err
+}
+
+// parseDuration reads a DURATION value such as PT1H30M, P1D or -P1W
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	sign := time.Duration(1)
+	if strings.HasPrefix(s, "-") {
+		sign, s = -1, s[1:]
+	}
+	s = strings.TrimPrefix(s, "+")
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("%q is not a duration", value)
+	}
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+	var total time.Duration
+	number := ""
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+		case c == 'T':
+			units = map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+		default:
+			unit, ok := units[c]
+			n, err := strconv.Atoi(number)
+			if !ok || err != nil {
+				return 0, fmt.Errorf("%q is not a duration", value)
+			}
+			total += time.Duration(n) * unit
+			number = ""
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("%q is not a duration", value)
+	}
+	return sign * total, nil
+}
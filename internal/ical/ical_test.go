@@ -0,0 +1,192 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAndParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	stamp := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	cal := Calendar{Name: "Training", Events: []Event{
+		{
+			UID: "scheduled-1@example", Summary: "Legs; heavy, then easy", Description: "Squat: 5 x 5\nLunge: 3 x 10 \\ each side",
+			Start: time.Date(2026, 10, 15, 18, 0, 0, 0, berlin), End: time.Date(2026, 10, 15, 19, 0, 0, 0, berlin),
+			Status: "CONFIRMED", Categories: []string{"Workout", "Strength, lower"}, Stamp: stamp,
+		},
+		{
+			UID: "event-2@example", Summary: "Rest", Start: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+			End: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), AllDay: true, Stamp: stamp,
+		},
+		{
+			UID: "event-3@example", Summary: "Mobility", Start: time.Date(2026, 10, 12, 7, 0, 0, 0, time.UTC),
+			End: time.Date(2026, 10, 12, 7, 30, 0, 0, time.UTC), RRule: "FREQ=WEEKLY;BYDAY=MO,TH",
+			ExDates: []time.Time{time.Date(2026, 10, 15, 7, 0, 0, 0, time.UTC)}, Stamp: stamp,
+		},
+		{
+			UID: "scheduled-4@example", Summary: strings.Repeat("Très long ", 12), Start: time.Date(2026, 10, 20, 6, 30, 0, 0, time.UTC),
+			End: time.Date(2026, 10, 20, 7, 30, 0, 0, time.UTC), Floating: true, Status: "CANCELLED", Stamp: stamp,
+		},
+	}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, cal); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:Training\r\n",
+		"DTSTART;TZID=Europe/Berlin:20261015T180000\r\n",
+		`SUMMARY:Legs\; heavy\, then easy` + "\r\n",
+		`DESCRIPTION:Squat: 5 x 5\nLunge: 3 x 10 \\ each side` + "\r\n",
+		`CATEGORIES:Workout,Strength\, lower` + "\r\n",
+		"DTSTART;VALUE=DATE:20261016\r\nDTEND;VALUE=DATE:20261017\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,TH\r\nEXDATE:20261015T070000Z\r\n",
+		"DTSTART:20261020T063000\r\n",
+		"DTSTAMP:20261001T080000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("line of %d octets is not folded: %q", len(line), line)
+		}
+	}
+
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if parsed.Name != "Training" || len(parsed.Events) != len(cal.Events) {
+		t.Fatalf("Parse = %q with %d events", parsed.Name, len(parsed.Events))
+	}
+	for i, got := range parsed.Events {
+		want := cal.Events[i]
+		if got.UID != want.UID || got.Summary != want.Summary || got.Description != want.Description || got.Status != want.Status ||
+			got.AllDay != want.AllDay || got.Floating != want.Floating || got.RRule != want.RRule ||
+			strings.Join(got.Categories, "|") != strings.Join(want.Categories, "|") {
+			t.Errorf("event %d = %+v, want %+v", i, got, want)
+		}
+		if !got.Start.Equal(want.Start) && !(want.Floating && got.Start.Format(dateTimeLayout) == want.Start.Format(dateTimeLayout)) {
+			t.Errorf("event %d starts %v, want %v", i, got.Start, want.Start)
+		}
+		if got.End.Sub(got.Start) != want.End.Sub(want.Start) {
+			t.Errorf("event %d lasts %v, want %v", i, got.End.Sub(got.Start), want.End.Sub(want.Start))
+		}
+		if len(got.ExDates) != len(want.ExDates) {
+			t.Errorf("event %d has exdates %v, want %v", i, got.ExDates, want.ExDates)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	// Written the way other calendar applications do: LF line ends, folded lines,
+	// time zones, alarms, durations and an edited occurrence
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"PRODID:-//Other//EN",
+		"BEGIN:VTIMEZONE",
+		"TZID:America/New_York",
+		"BEGIN:STANDARD",
+		"DTSTART:19701101T020000",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:run@example",
+		"DTSTART;TZID=\"America/New_York\":20261019T063000",
+		"DURATION:PT1H15M",
+		"SUMMARY:Long",
+		"  run",
+		"RRULE:FREQ=WEEKLY;COUNT=4",
+		"EXDATE;TZID=America/New_York:20261026T063000,20261102T063000",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"DESCRIPTION:Alarm text",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:run@example",
+		"RECURRENCE-ID;TZID=America/New_York:20261109T063000",
+		"DTSTART;TZID=Windows Standard Time:20261110T070000",
+		"SUMMARY:Moved run",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:rest@example",
+		"DTSTART;VALUE=DATE:20261018",
+		"SUMMARY:Rest",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\n")
+
+	cal, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(cal.Events) != 3 {
+		t.Fatalf("Parse = %d events, want 3", len(cal.Events))
+	}
+
+	run := cal.Events[0]
+	if run.Summary != "Long run" || run.Description != "" || run.RRule != "FREQ=WEEKLY;COUNT=4" || len(run.ExDates) != 2 {
+		t.Errorf("run = %+v", run)
+	}
+	if run.Start.UTC().Format(time.RFC3339) != "2026-10-19T10:30:00Z" || run.End.Sub(run.Start) != 75*time.Minute {
+		t.Errorf("run from %v to %v, want 10:30 UTC for 75 minutes", run.Start, run.End)
+	}
+
+	moved := cal.Events[1]
+	if moved.RecurrenceID == nil || moved.RecurrenceID.UTC().Format(time.RFC3339) != "2026-11-09T11:30:00Z" {
+		t.Errorf("moved occurrence replaces %v", moved.RecurrenceID)
+	}
+	if !moved.Floating || moved.Start.Format(dateTimeLayout) != "20261110T070000" {
+		t.Errorf("time in an unknown zone = %v (floating %v), want 07:00 floating", moved.Start, moved.Floating)
+	}
+
+	rest := cal.Events[2]
+	if !rest.AllDay || rest.End.Sub(rest.Start) != 24*time.Hour {
+		t.Errorf("rest = %+v, want a whole day", rest)
+	}
+
+	for _, bad := range []string{
+		"BEGIN:VEVENT\nEND:VEVENT",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\nEND:VCALENDAR",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDURATION:1H\nEND:VEVENT\nEND:VCALENDAR",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY\nEND:VEVENT\nEND:VCALENDAR",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Open\nEND:VCALENDAR",
+	} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"PT45M":     45 * time.Minute,
+		"PT1H30M":   90 * time.Minute,
+		"P1D":       24 * time.Hour,
+		"P1DT2H":    26 * time.Hour,
+		"P2W":       14 * 24 * time.Hour,
+		"-PT15M":    -15 * time.Minute,
+		"PT1H0M30S": time.Hour + 30*time.Second,
+	}
+	for value, want := range tests {
+		if got, err := parseDuration(value); err != nil || got != want {
+			t.Errorf("parseDuration(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "P", "1H", "PT1X", "PT5"} {
+		if _, err := parseDuration(bad); err == nil {
+			t.Errorf("parseDuration(%q) succeeded, want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package load models training load from logged workouts.
+//
+// A session's load is its session RPE times its duration in minutes (Foster's sRPE
+// method). Daily loads feed exponentially weighted acute (7 day) and chronic (28 day)
+// loads, whose ratio is the acute:chronic workload ratio, the monotony and strain of
+// the last seven days, and Banister's fitness-fatigue model.
+package load
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// DefaultRPE is the session RPE of a workout none of whose sets were rated
+	DefaultRPE = 7
+	// MinutesPerSet estimates the duration of a workout logged without one
+	MinutesPerSet = 3
+
+	// AcuteDays and ChronicDays are the spans of the acute and chronic loads
+	AcuteDays   = 7
+	ChronicDays = 28
+
+	// FitnessDays and FatigueDays are the decay time constants of the Banister model,
+	// FitnessGain and FatigueGain the weights of fitness and fatigue in performance
+	FitnessDays = 42
+	FatigueDays = 7
+	FitnessGain = 1
+	FatigueGain = 2
+
+	// HighACWR is the acute:chronic ratio above which load is rising faster than the
+	// athlete has prepared for
+	HighACWR = 1.5
+	// HighMonotony is the monotony above which training lacks easy days
+	HighMonotony = 2
+)
+
+// Set is a working set as far as load is concerned
+type Set struct {
+	RPE *float64
+	RIR *int
+}
+
+// SessionRPE returns the mean effort of a workout's rated sets, RIR counting as 10 - RIR,
+// or DefaultRPE when none was rated
+func SessionRPE(sets []Set) float64 {
+	total, rated := 0.0, 0
+	for _, s := range sets {
+		switch {
+		case s.RIR != nil:
+			total += math.Max(10-float64(*s.RIR), 0)
+		case s.RPE != nil:
+			total += *s.RPE
+		default:
+			continue
+		}
+		rated++
+	}
+	if rated == 0 {
+		return DefaultRPE
+	}
+	return total / float64(rated)
+}
+
+// SessionLoad returns the load of a workout lasting the given minutes, estimated from
+// its working sets when zero, at the given session RPE
+func SessionLoad(minutes float64, workingSets int, rpe float64) float64 {
+	if minutes <= 0 {
+		minutes = float64(workingSets * MinutesPerSet)
+	}
+	return minutes * rpe
+}
+
+// Day is the training load of one day and the metrics derived from it and the days before
+type Day struct {
+	Date        time.Time
+	Load        float64
+	Sessions    int
+	Acute       float64
+	Chronic     float64
+	ACWR        float64 // Acute / Chronic, 0 without a chronic load
+	Monotony    float64 // mean / standard deviation of the last seven daily loads, 0 when they are all equal
+	Strain      float64 // load of the last seven days times monotony
+	Fitness     float64
+	Fatigue     float64
+	Performance float64 // FitnessGain * Fitness - FatigueGain * Fatigue
+}
+
+// Model carries the state needed to compute the next day
+type Model struct {
+	next    time.Time
+	recent  [AcuteDays]float64 // daily loads of the last seven days, oldest first
+	acute   float64
+	chronic float64
+	fitness float64
+	fatigue float64
+}
+
+// Resume returns a model that continues after the given consecutive days, oldest first.
+// Only the last AcuteDays days are needed. With no days the model starts from rest on
+// the start date.
+func Resume(days []Day, start time.Time) *Model {
+	m := &Model{next: dateOf(start)}
+	if len(days) == 0 {
+		return m
+	}
+	if len(days) > AcuteDays {
+		days = days[len(days)-AcuteDays:]
+	}
+	for i, d := range days {
+		m.recent[AcuteDays-len(days)+i] = d.Load
+	}
+	last := days[len(days)-1]
+	m.next = dateOf(last.Date).AddDate(0, 0, 1)
+	m.acute, m.chronic = last.Acute, last.Chronic
+	m.fitness, m.fatigue = last.Fitness, last.Fatigue
+	return m
+}
+
+// Next returns the date of the day the model computes next
+func (m *Model) Next() time.Time {
+	return m.next
+}
+
+// Step adds the next day's load and sessions and returns its metrics
+func (m *Model) Step(load float64, sessions int) Day {
+	copy(m.recent[:], m.recent[1:])
+	m.recent[AcuteDays-1] = load
+
+	m.acute = ewma(m.acute, load, AcuteDays)
+	m.chronic = ewma(m.chronic, load, ChronicDays)
+	m.fitness = m.fitness*math.Exp(-1.0/FitnessDays) + load
+	m.fatigue = m.fatigue*math.Exp(-1.0/FatigueDays) + load
+
+	d := Day{
+		Date:        m.next,
+		Load:        load,
+		Sessions:    sessions,
+		Acute:       m.acute,
+		Chronic:     m.chronic,
+		Fitness:     m.fitness,
+		Fatigue:     m.fatigue,
+		Performance: FitnessGain*m.fitness - FatigueGain*m.fatigue,
+	}
+	if m.chronic > 0 {
+		d.ACWR = m.acute / m.chronic
+	}
+
+	sum, sumSquares := 0.0, 0.0
+	for _, l := range m.recent {
+		sum += l
+		sumSquares += l * l
+	}
+	mean := sum / AcuteDays
+	if sd := math.Sqrt(math.Max(sumSquares/AcuteDays-mean*mean, 0)); sd > 1e-9 {
+		d.Monotony = mean / sd
+		d.Strain = sum * d.Monotony
+	}
+
+	m.next = m.next.AddDate(0, 0, 1)
+	return d
+}
+
+// Recovery scores from 0 to 100 how far fatigue has cleared relative to fitness. Without
+// any fitness there is nothing to recover from and the score is 100.
+func Recovery(fitness, performance float64) float64 {
+	if fitness <= 0 {
+		return 100
+	}
+	return math.Max(0, math.Min(100, 50+50*performance/fitness))
+}
+
+// ewma adds a value to an exponentially weighted moving average spanning n days
+func ewma(average, value float64, n int) float64 {
+	lambda := 2 / (float64(n) + 1)
+	return lambda*value + (1-lambda)*average
+}
+
+// dateOf returns the UTC calendar date of t
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,111 @@
+package load
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSessionRPE(t *testing.T) {
+	rpe := func(v float64) *float64 { return &v }
+	rir := func(v int) *int { return &v }
+
+	tests := []struct {
+		name string
+		sets []Set
+		want float64
+	}{
+		{"unrated", []Set{{}, {}}, DefaultRPE},
+		{"RPE", []Set{{RPE: rpe(7)}, {RPE: rpe(9)}}, 8},
+		{"RIR counts as 10 - RIR", []Set{{RIR: rir(1)}, {RPE: rpe(8)}}, 8.5},
+		{"unrated sets are left out", []Set{{RPE: rpe(6)}, {}}, 6},
+	}
+	for _, tt := range tests {
+		if got := SessionRPE(tt.sets); got != tt.want {
+			t.Errorf("%s: SessionRPE = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSessionLoad(t *testing.T) {
+	if got := SessionLoad(60, 20, 8); got != 480 {
+		t.Errorf("60 minutes at RPE 8 = %v, want 480", got)
+	}
+	if got := SessionLoad(0, 10, 7); got != 10*MinutesPerSet*7 {
+		t.Errorf("10 sets without a duration = %v, want %v", got, 10*MinutesPerSet*7)
+	}
+}
+
+func TestModel(t *testing.T) {
+	start := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	m := Resume(nil, start)
+
+	// A week of equal loads is perfectly monotonous and has no spread to measure
+	var days []Day
+	for i := 0; i < 7; i++ {
+		days = append(days, m.Step(300, 1))
+	}
+	first := days[0]
+	if !first.Date.Equal(start) || first.Acute != 75 || math.Abs(first.Chronic-600.0/29) > 1e-9 {
+		t.Errorf("first day = %+v", first)
+	}
+	if math.Abs(first.ACWR-75/(600.0/29)) > 1e-9 {
+		t.Errorf("first ACWR = %v", first.ACWR)
+	}
+	if first.Fitness != 300 || first.Fatigue != 300 || first.Performance != -300 {
+		t.Errorf("first Banister day = %+v", first)
+	}
+	if days[6].Monotony != 0 || days[6].Strain != 0 {
+		t.Errorf("monotony of equal loads = %v, strain %v; want 0", days[6].Monotony, days[6].Strain)
+	}
+
+	// A rest day then makes the week's loads vary
+	rest := m.Step(0, 0)
+	mean := 1800.0 / 7
+	sd := math.Sqrt(6*300*300/7.0 - mean*mean)
+	if math.Abs(rest.Monotony-mean/sd) > 1e-9 || math.Abs(rest.Strain-1800*mean/sd) > 1e-6 {
+		t.Errorf("rest day monotony = %v, strain = %v; want %v, %v", rest.Monotony, rest.Strain, mean/sd, 1800*mean/sd)
+	}
+	if rest.Fatigue >= days[6].Fatigue || rest.Fitness >= days[6].Fitness || rest.Fatigue/days[6].Fatigue >= rest.Fitness/days[6].Fitness {
+		t.Errorf("fatigue should fall faster than fitness: %+v after %+v", rest, days[6])
+	}
+	if !rest.Date.Equal(start.AddDate(0, 0, 7)) {
+		t.Errorf("rest day date = %v", rest.Date)
+	}
+
+	// Resuming from stored days continues exactly where the model left off
+	all := append(days, rest)
+	resumed := Resume(all, start)
+	if want := start.AddDate(0, 0, 8); !resumed.Next().Equal(want) {
+		t.Errorf("resumed next = %v, want %v", resumed.Next(), want)
+	}
+	if a, b := m.Step(450, 1), resumed.Step(450, 1); a != b {
+		t.Errorf("resumed step = %+v, want %+v", b, a)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	if got := Recovery(0, 0); got != 100 {
+		t.Errorf("recovery without training = %v, want 100", got)
+	}
+	// The first day of training is all fatigue
+	m := Resume(nil, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+	day := m.Step(500, 1)
+	if got := Recovery(day.Fitness, day.Performance); got != 0 {
+		t.Errorf("recovery after one hard day = %v, want 0", got)
+	}
+	// Weeks of steady training build fitness that outlasts the fatigue
+	for i := 0; i < 120; i++ {
+		day = m.Step(300, 1)
+	}
+	steady := Recovery(day.Fitness, day.Performance)
+	if steady < 50 || steady > 100 {
+		t.Errorf("recovery after steady training = %v, want between 50 and 100", steady)
+	}
+	for i := 0; i < 3; i++ {
+		day = m.Step(0, 0)
+	}
+	if rested := Recovery(day.Fitness, day.Performance); rested <= steady {
+		t.Errorf("recovery after three rest days = %v, want more than %v", rested, steady)
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -40,7 +41,7 @@ type WorkoutProgram struct {
 	DurationWeeks int               `json:"duration_weeks" db:"duration_weeks"`
 	Goal          string            `json:"goal" db:"goal"`          // strength, muscle_gain, fat_loss, endurance
 	IsPublic      bool              `json:"is_public" db:"is_public"`
-	CreatedBy     int               `json:"created_by" db:"created_by"`
+	CreatedBy     int               `json:"created_by" db:"created_by"`  // 0 once the creator's account is deleted
 	Templates     []ProgramTemplate `json:"templates,omitempty"`
 	CreatedAt     time.Time         `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time         `json:"updated_at" db:"updated_at"`
@@ -99,6 +100,9 @@ type Exercise struct {
 	WorkoutID   int    `json:"workout_id" db:"workout_id"`
 	Name        string `json:"name" db:"name"`
 	Category    string `json:"category" db:"category"` // e.g., "strength", "cardio", "flexibility"
+	OrderIndex  int    `json:"order_index" db:"order_index"`
+	GroupID     int    `json:"group_id" db:"group_id"` // exercises sharing a non-zero group form a superset or circuit
+	PredefinedExerciseID *int `json:"predefined_exercise_id" db:"predefined_exercise_id"` // library entry the exercise trains the muscles of
 	Sets        []Set  `json:"sets,omitempty"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
@@ -114,23 +118,129 @@ type Set struct {
 	Distance    float64 `json:"distance" db:"distance"` // for cardio exercises
 	Duration    int     `json:"duration" db:"duration"` // in seconds
 	RestTime    int     `json:"rest_time" db:"rest_time"` // in seconds
+	RPE         *float64 `json:"rpe" db:"rpe"` // rate of perceived exertion, 1-10
+	RIR         *int    `json:"rir" db:"rir"` // reps in reserve
+	SetType     string  `json:"set_type" db:"set_type"` // warmup, working, drop, failure, amrap, backoff
+	Notes       string  `json:"notes" db:"notes"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// Set types. Warmup sets are left out of volume and personal records.
+const (
+	SetTypeWarmup  = "warmup"
+	SetTypeWorking = "working"
+	SetTypeDrop    = "drop"
+	SetTypeFailure = "failure"
+	SetTypeAMRAP   = "amrap"
+	SetTypeBackoff = "backoff"
+)
+
+// GroupLabel names the superset or circuit an exercise group forms within the workout:
+// "Superset A" for two exercises, "Circuit B" for three or more. Groups are lettered in
+// the order they first appear. Ungrouped exercises and lone group members get no label.
+func (w Workout) GroupLabel(groupID int) string {
+	if groupID == 0 {
+		return ""
+	}
+
+	var order []int
+	sizes := make(map[int]int)
+	for _, exercise := range w.Exercises {
+		if exercise.GroupID == 0 {
+			continue
+		}
+		if sizes[exercise.GroupID] == 0 {
+			order = append(order, exercise.GroupID)
+		}
+		sizes[exercise.GroupID]++
+	}
+	if sizes[groupID] < 2 {
+		return ""
+	}
+
+	kind := "Superset"
+	if sizes[groupID] > 2 {
+		kind = "Circuit"
+	}
+	letter := 0
+	for _, id := range order {
+		if sizes[id] < 2 {
+			continue
+		}
+		if id == groupID {
+			break
+		}
+		letter++
+	}
+	return fmt.Sprintf("%s %c", kind, 'A'+letter%26)
+}
+
+// SetTypeOrDefault returns setType, or SetTypeWorking for sets logged without a type
+func SetTypeOrDefault(setType string) string {
+	if setType == "" {
+		return SetTypeWorking
+	}
+	return setType
+}
+
+// WorkoutListParams describes filtering, sorting and paging for workout listings
+type WorkoutListParams struct {
+	Limit    int    // page size
+	Cursor   string // opaque cursor returned as next_cursor by the previous page
+	From     string // inclusive start date (YYYY-MM-DD)
+	To       string // inclusive end date (YYYY-MM-DD)
+	Exercise string // only workouts containing an exercise with this name
+	Category string // only workouts containing an exercise in this category
+	Query    string // search terms matched against name and notes
+	Sort     string // date, name or duration; prefix with - for descending
+}
+
+// WorkoutListResponse is one page of workouts plus the cursor for the next page
+type WorkoutListResponse struct {
+	Workouts   []Workout `json:"workouts"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
 // CreateWorkoutRequest represents the request payload for creating a workout
+// Exercises and their sets may be nested to log a whole session in one request.
 type CreateWorkoutRequest struct {
-	Name      string `json:"name" validate:"required"`
-	Date      string `json:"date" validate:"required"`
-	Duration  int    `json:"duration"`
-	Notes     string `json:"notes"`
+	Name      string                         `json:"name" validate:"required"`
+	Date      string                         `json:"date" validate:"required"`
+	Duration  int                            `json:"duration"`
+	Notes     string                         `json:"notes"`
+	Exercises []CreateWorkoutExerciseRequest `json:"exercises"`
+}
+
+// CreateWorkoutExerciseRequest represents an exercise nested in a workout request
+type CreateWorkoutExerciseRequest struct {
+	Name                 string                    `json:"name" validate:"required"`
+	Category             string                    `json:"category" validate:"required"`
+	GroupID              int                       `json:"group_id" validate:"min=0"`
+	PredefinedExerciseID int                       `json:"predefined_exercise_id" validate:"min=0"` // 0 links the library entry of the same name
+	Sets                 []CreateWorkoutSetRequest `json:"sets"`
+}
+
+// CreateWorkoutSetRequest represents a set nested in a workout request
+type CreateWorkoutSetRequest struct {
+	SetNumber int     `json:"set_number"` // defaults to the set's position when omitted
+	Reps      int     `json:"reps"`
+	Weight    float64 `json:"weight"`
+	Distance  float64 `json:"distance"`
+	Duration  int     `json:"duration"`
+	RestTime  int     `json:"rest_time"`
+	RPE       *float64 `json:"rpe" validate:"min=1,max=10"`
+	RIR       *int     `json:"rir" validate:"min=0,max=10"`
+	SetType   string   `json:"set_type" validate:"oneof=warmup working drop failure amrap backoff"`
+	Notes     string   `json:"notes" validate:"max=500"`
 }
 
 // CreateExerciseRequest represents the request payload for creating an exercise
 type CreateExerciseRequest struct {
-	WorkoutID int    `json:"workout_id" validate:"required"`
-	Name      string `json:"name" validate:"required"`
-	Category  string `json:"category" validate:"required"`
+	WorkoutID            int    `json:"workout_id" validate:"required"`
+	Name                 string `json:"name" validate:"required"`
+	Category             string `json:"category" validate:"required"`
+	PredefinedExerciseID int    `json:"predefined_exercise_id" validate:"min=0"` // 0 links the library entry of the same name
 }
 
 // CreateSetRequest represents the request payload for creating a set
@@ -142,20 +252,39 @@ type CreateSetRequest struct {
 	Distance   float64 `json:"distance"`
 	Duration   int     `json:"duration"`
 	RestTime   int     `json:"rest_time"`
+	RPE        *float64 `json:"rpe" validate:"min=1,max=10"`
+	RIR        *int     `json:"rir" validate:"min=0,max=10"`
+	SetType    string   `json:"set_type" validate:"oneof=warmup working drop failure amrap backoff"`
+	Notes      string   `json:"notes" validate:"max=500"`
 }
 
 // UpdateWorkoutRequest represents the request payload for updating a workout
+// When Exercises is present, even as an empty list, it replaces the stored exercises and sets.
 type UpdateWorkoutRequest struct {
-	Name      string `json:"name" validate:"required"`
-	Date      string `json:"date" validate:"required"`
-	Duration  int    `json:"duration"`
-	Notes     string `json:"notes"`
+	Name      string                         `json:"name" validate:"required"`
+	Date      string                         `json:"date" validate:"required"`
+	Duration  int                            `json:"duration"`
+	Notes     string                         `json:"notes"`
+	Exercises []CreateWorkoutExerciseRequest `json:"exercises"`
 }
 
 // UpdateExerciseRequest represents the request payload for updating an exercise
 type UpdateExerciseRequest struct {
-	Name      string `json:"name" validate:"required"`
-	Category  string `json:"category" validate:"required"`
+	Name                 string `json:"name" validate:"required"`
+	Category             string `json:"category" validate:"required"`
+	PredefinedExerciseID int    `json:"predefined_exercise_id" validate:"min=0"` // 0 links the library entry of the same name
+}
+
+// ReorderExercisesRequest lists every exercise of a workout in its new order
+type ReorderExercisesRequest struct {
+	Exercises []ExerciseOrder `json:"exercises" validate:"required"`
+}
+
+// ExerciseOrder places one exercise in a reorder request. Exercises sharing a
+// non-zero group ID become a superset or circuit; zero leaves the exercise on its own.
+type ExerciseOrder struct {
+	ID      int `json:"id" validate:"required"`
+	GroupID int `json:"group_id" validate:"min=0"`
 }
 
 // UpdateSetRequest represents the request payload for updating a set
@@ -166,6 +295,10 @@ type UpdateSetRequest struct {
 	Distance   float64 `json:"distance"`
 	Duration   int     `json:"duration"`
 	RestTime   int     `json:"rest_time"`
+	RPE        *float64 `json:"rpe" validate:"min=1,max=10"`
+	RIR        *int     `json:"rir" validate:"min=0,max=10"`
+	SetType    string   `json:"set_type" validate:"oneof=warmup working drop failure amrap backoff"`
+	Notes      string   `json:"notes" validate:"max=500"`
 }
 
 // PredefinedExercise represents a predefined exercise in the library
@@ -178,6 +311,8 @@ type PredefinedExercise struct {
 	Instructions    string    `json:"instructions" db:"instructions"`
 	Tips            string    `json:"tips" db:"tips"`
 	MuscleGroups    string    `json:"muscle_groups" db:"muscle_groups"`
+	PrimaryMuscles  []string  `json:"primary_muscles"` // muscle_groups normalised to the muscle table
+	SecondaryMuscles []string `json:"secondary_muscles"`
 	Equipment       string    `json:"equipment" db:"equipment"`
 	Difficulty      string    `json:"difficulty" db:"difficulty"` // beginner, intermediate, advanced
 	ImageURL        string    `json:"image_url" db:"image_url"`
@@ -314,6 +449,7 @@ type UserSettings struct {
 	PrivacyMode      bool      `json:"privacy_mode" db:"privacy_mode"`           // hide stats from others
 	AutoLogout       int       `json:"auto_logout" db:"auto_logout"`             // minutes, 0 = never
 	Language         string    `json:"language" db:"language"`                   // en, es, fr, etc.
+	E1RMFormula      string    `json:"e1rm_formula" db:"e1rm_formula"`           // epley, brzycki, lombardi, mayhew, wathan
 	CreatedAt        time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -344,6 +480,7 @@ type UpdateSettingsRequest struct {
 	PrivacyMode      bool   `json:"privacy_mode"`
 	AutoLogout       int    `json:"auto_logout"`
 	Language         string `json:"language"`
+	E1RMFormula      string `json:"e1rm_formula"` // unchanged when empty
 }
 
 // DeleteAccountRequest represents a request to delete account
@@ -352,6 +489,58 @@ type DeleteAccountRequest struct {
 	ConfirmDeletion string `json:"confirm_deletion" validate:"required"` // must be "DELETE"
 }
 
+// APIError describes a failed /api/v1 request
+type APIError struct {
+	Code    string      `json:"code"`    // machine-readable, e.g. not_found, validation_failed
+	Message string      `json:"message"` // human-readable summary
+	Details interface{} `json:"details"` // optional extra information such as per-field errors
+}
+
+// APIErrorResponse is the envelope every /api/v1 error is returned in
+type APIErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// APIToken represents a personal access token used by scripts and mobile clients
+// Only the SHA-256 hash of the token is stored; the plaintext is shown once on creation.
+type APIToken struct {
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"` // first characters of the token, for display
+	TokenHash  string     `json:"-" db:"token_hash"`
+	Scope      string     `json:"scope" db:"scope"` // read, read_write
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// CreateAPITokenRequest represents a request to create a personal access token
+type CreateAPITokenRequest struct {
+	Name  string `json:"name" validate:"required"`
+	Scope string `json:"scope" validate:"required,oneof=read read_write"`
+}
+
+// CreateAPITokenResponse carries the plaintext token, which is never retrievable again
+type CreateAPITokenResponse struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+// IdempotencyRecord stores the response to a create request sent with an Idempotency-Key
+// so that client retries replay it instead of creating duplicates.
+// A StatusCode of 0 means the original request is still in flight.
+type IdempotencyRecord struct {
+	ID           int       `json:"id" db:"id"`
+	UserID       int       `json:"user_id" db:"user_id"`
+	Key          string    `json:"key" db:"idempotency_key"`
+	RequestHash  string    `json:"request_hash" db:"request_hash"`
+	StatusCode   int       `json:"status_code" db:"status_code"`
+	ContentType  string    `json:"content_type" db:"content_type"`
+	ResponseBody []byte    `json:"-" db:"response_body"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
 // AnalyticsData represents comprehensive analytics data for the dashboard
 type AnalyticsData struct {
 	TotalWorkouts      int                     `json:"totalWorkouts"`
@@ -382,6 +571,30 @@ type AnalyticsData struct {
 	ProgressTrends     *ProgressTrends         `json:"progressTrends"`
 }
 
+// WorkoutDay sums up the workouts a user logged on one day
+type WorkoutDay struct {
+	Date        time.Time `json:"date"`
+	Workouts    int       `json:"workouts"`
+	AvgDuration float64   `json:"avgDuration"` // of the workouts with a duration
+}
+
+// CalorieDay sums up the meals a user logged on one day
+type CalorieDay struct {
+	Date     time.Time `json:"date"`
+	Calories int       `json:"calories"`
+}
+
+// WorkingSet is a weighted set, other than a warmup, from one of a user's workouts
+type WorkingSet struct {
+	ExerciseName string
+	WorkoutID    int
+	Date         time.Time
+	Weight       float64
+	Reps         int
+	RPE          *float64
+	RIR          *int
+}
+
 // ExerciseCategoryData represents exercise category distribution
 type ExerciseCategoryData struct {
 	Labels []string `json:"labels"`
@@ -406,6 +619,129 @@ type PersonalRecord struct {
 	IsNew        bool      `json:"isNew"` // If achieved in current period
 }
 
+// Personal record types. Weight records are kept for every rep count up to RecordMaxReps.
+const (
+	RecordTypeWeight        = "weight"         // heaviest weight for a rep count
+	RecordTypeE1RM          = "e1rm"           // best estimated 1RM
+	RecordTypeSetVolume     = "set_volume"     // most weight * reps in one set
+	RecordTypeSessionVolume = "session_volume" // most working volume of the exercise in one workout
+)
+
+// RecordMaxReps is the highest rep count a weight record is kept for
+const RecordMaxReps = 12
+
+// PersonalRecordEntry is one time a personal record was set. The latest entry of each
+// record type, and of each rep count for weight records, is the current record.
+type PersonalRecordEntry struct {
+	ID           int       `json:"id" db:"id"`
+	UserID       int       `json:"-" db:"user_id"`
+	ExerciseName string    `json:"exercise_name" db:"exercise_name"`
+	RecordType   string    `json:"record_type" db:"record_type"`
+	Weight       float64   `json:"weight" db:"weight"` // zero for session volume
+	Reps         int       `json:"reps" db:"reps"`     // total reps for session volume
+	Volume       float64   `json:"volume" db:"volume"`
+	OneRepMax    float64   `json:"one_rep_max" db:"one_rep_max"` // zero when the set cannot be estimated
+	Date         time.Time `json:"date" db:"date"`
+	WorkoutID    int       `json:"workout_id" db:"workout_id"`
+	SetID        *int      `json:"set_id,omitempty" db:"set_id"` // nil for session volume
+	IsCurrent    bool      `json:"is_current" db:"is_current"`
+}
+
+// ExerciseRecords lists an exercise's current personal records and every entry that led to them
+type ExerciseRecords struct {
+	ExerciseName string                `json:"exercise_name"`
+	Current      []PersonalRecordEntry `json:"current"`
+	History      []PersonalRecordEntry `json:"history"` // oldest first
+}
+
+// PersonalRecordsResponse is the response of the personal records endpoint
+type PersonalRecordsResponse struct {
+	Exercises []ExerciseRecords `json:"exercises"`
+}
+
+// Roles a muscle plays in a library exercise
+const (
+	MuscleRolePrimary   = "primary"
+	MuscleRoleSecondary = "secondary"
+)
+
+// ExerciseMuscle is a muscle trained by a library exercise
+type ExerciseMuscle struct {
+	Muscle string `json:"muscle"`
+	Role   string `json:"role"`
+}
+
+// MuscleSet is a working set of a logged exercise together with one muscle its library
+// entry trains. Sets of exercises not linked to a muscle have an empty Muscle.
+type MuscleSet struct {
+	ExerciseName string
+	Muscle       string
+	Role         string
+	Date         time.Time
+	Weight       float64
+	Reps         int
+	Duration     int
+	RPE          *float64
+	RIR          *int
+}
+
+// MuscleVolumeResponse is the weekly training volume of each muscle, oldest week first
+type MuscleVolumeResponse struct {
+	SecondaryFraction float64            `json:"secondary_fraction"`
+	Weeks             []MuscleVolumeWeek `json:"weeks"`
+	// UnlinkedExercises were logged in the period but train no known muscle
+	UnlinkedExercises []string `json:"unlinked_exercises"`
+}
+
+// MuscleVolumeWeek is the volume of each muscle trained in the week starting on Monday WeekStart
+type MuscleVolumeWeek struct {
+	WeekStart string         `json:"week_start"`
+	Muscles   []MuscleVolume `json:"muscles"`
+}
+
+// MuscleVolume is a muscle's volume in one week. Sets and tonnage of exercises that
+// train the muscle secondarily count at the response's secondary fraction.
+type MuscleVolume struct {
+	Muscle   string  `json:"muscle"`
+	HardSets float64 `json:"hard_sets"`
+	Tonnage  float64 `json:"tonnage"`
+}
+
+// TrainingLoadDay is the training load of one day and the metrics derived from it and the
+// days before. Load is session RPE times minutes, summed over the day's workouts.
+type TrainingLoadDay struct {
+	Date        time.Time `json:"date" db:"date"`
+	Load        float64   `json:"load" db:"load"`
+	Sessions    int       `json:"sessions" db:"sessions"`
+	AcuteLoad   float64   `json:"acute_load" db:"acute_load"`     // 7-day exponentially weighted average
+	ChronicLoad float64   `json:"chronic_load" db:"chronic_load"` // 28-day exponentially weighted average
+	ACWR        float64   `json:"acwr" db:"acwr"`                 // acute:chronic workload ratio
+	Monotony    float64   `json:"monotony" db:"monotony"`
+	Strain      float64   `json:"strain" db:"strain"`
+	Fitness     float64   `json:"fitness" db:"fitness"`
+	Fatigue     float64   `json:"fatigue" db:"fatigue"`
+	Performance float64   `json:"performance" db:"performance"` // Banister fitness minus weighted fatigue
+}
+
+// TrainingSession is a workout's contribution to training load and volume
+type TrainingSession struct {
+	WorkoutID   int
+	Date        time.Time
+	Duration    int // minutes, 0 when not logged
+	WorkingSets int
+	Tonnage     float64
+	RPE         float64 // mean of the rated working sets, 0 when none was rated
+	Load        float64
+}
+
+// TrainingLoadResponse is a user's daily training load over a period, the weekly volume
+// of the period and the metrics the recommendations are based on as of its last day
+type TrainingLoadResponse struct {
+	Days    []TrainingLoadDay     `json:"days"`
+	Weekly  []WeeklyVolumeData    `json:"weekly"`
+	Metrics RecommendationMetrics `json:"metrics"`
+}
+
 // StrengthProgress represents strength progression for specific exercises
 type StrengthProgress struct {
 	ExerciseName string                 `json:"exerciseName"`
@@ -723,6 +1059,7 @@ type TemplateExercise struct {
 	Name         string    `json:"name" db:"name"`
 	Category     string    `json:"category" db:"category"`
 	OrderIndex   int       `json:"order_index" db:"order_index"`
+	GroupID      int       `json:"group_id" db:"group_id"` // exercises sharing a non-zero group form a superset or circuit
 	TargetSets   int       `json:"target_sets" db:"target_sets"`
 	TargetReps   int       `json:"target_reps" db:"target_reps"`
 	TargetWeight float64   `json:"target_weight" db:"target_weight"`
@@ -1009,6 +1346,8 @@ type ScheduledWorkout struct {
 	EstimatedDuration int              `json:"estimated_duration" db:"estimated_duration"` // minutes
 	Status            string           `json:"status" db:"status"` // scheduled, completed, skipped, cancelled
 	WorkoutID         *int             `json:"workout_id" db:"workout_id"`
+	DeloadID          *int             `json:"deload_id" db:"deload_id"` // the accepted deload scaling down its targets
+	ICalUID           *string          `json:"ical_uid,omitempty" db:"ical_uid"` // UID of the .ics event it was imported from
 	ReminderSent      bool             `json:"reminder_sent" db:"reminder_sent"`
 	Notes             string           `json:"notes" db:"notes"`
 	CreatedAt         time.Time        `json:"created_at" db:"created_at"`
@@ -1017,7 +1356,28 @@ type ScheduledWorkout struct {
 	Template          *WorkoutTemplate `json:"template,omitempty"`
 	Workout           *Workout         `json:"workout,omitempty"`
 	Reminders         []WorkoutReminder `json:"reminders,omitempty"`
-}
+	Exercises         []TemplateExercise `json:"exercises,omitempty"` // the template's exercises with this workout's targets
+}
+
+// ScheduledStart returns when a workout scheduled for the given day and optional HH:MM
+// time starts in the given zone, at midnight when it has no time
+func ScheduledStart(date time.Time, clock *string, zone *time.Location) time.Time {
+	if clock != nil {
+		if t, err := time.Parse("15:04", *clock); err == nil {
+			return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, zone)
+		}
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, zone)
+}
+
+// Statuses of scheduled workouts. Logging a workout on the day completes the first one
+// still scheduled; those left scheduled after their day are skipped.
+const (
+	ScheduledStatusScheduled = "scheduled"
+	ScheduledStatusCompleted = "completed"
+	ScheduledStatusSkipped   = "skipped"
+	ScheduledStatusCancelled = "cancelled"
+)
 
 // WorkoutReminder represents a reminder for a scheduled workout
 type WorkoutReminder struct {
@@ -1030,9 +1390,40 @@ type WorkoutReminder struct {
 	Status             string    `json:"status" db:"status"` // pending, sent, failed, cancelled
 	SentAt             *time.Time `json:"sent_at" db:"sent_at"`
 	ErrorMessage       *string   `json:"error_message" db:"error_message"`
+	MinutesBefore      *int      `json:"minutes_before" db:"minutes_before"` // scheduled_for follows the workout's start when set
+	Attempts           int       `json:"attempts" db:"attempts"`
+	NextAttemptAt      *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"` // retry after a failed attempt
 	CreatedAt          time.Time `json:"created_at" db:"created_at"`
 }
 
+// Reminder channels and statuses. Pending reminders are sent once due; a failed attempt
+// is retried until the dispatcher gives up and marks the reminder failed.
+const (
+	ReminderTypeEmail = "email"
+	ReminderTypePush  = "push"
+	ReminderTypeSMS   = "sms"
+
+	ReminderStatusPending   = "pending"
+	ReminderStatusSent      = "sent"
+	ReminderStatusFailed    = "failed"
+	ReminderStatusCancelled = "cancelled"
+)
+
+// DueReminder is a reminder ready to be sent together with what the dispatcher needs to
+// address it and to word it in the user's time zone
+type DueReminder struct {
+	WorkoutReminder
+	Username      string
+	Email         string
+	Timezone      string
+	Notifications bool
+	// The scheduled workout's title, day, time of day if it has one and status
+	WorkoutTitle  string
+	WorkoutDate   time.Time
+	WorkoutTime   *string
+	WorkoutStatus string
+}
+
 // RestDayRecommendation represents an AI recommendation for rest days
 type RestDayRecommendation struct {
 	ID                 int       `json:"id" db:"id"`
@@ -1049,6 +1440,22 @@ type RestDayRecommendation struct {
 	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// Reasons the rest day engine recommends a rest day, most pressing first
+const (
+	RestDayReasonConsecutiveDays = "consecutive_days"     // too many training days in a row
+	RestDayReasonHighVolume      = "high_volume"          // the last week's volume far above the weeks before
+	RestDayReasonHighIntensity   = "high_intensity"       // hard sessions close together
+	RestDayReasonMuscleFatigue   = "muscle_group_fatigue" // muscles trained hard several times in a few days
+)
+
+// Statuses of rest day recommendations. A suggestion becomes one of the others once the user responds.
+const (
+	RestDayStatusSuggested  = "suggested"
+	RestDayStatusAccepted   = "accepted"
+	RestDayStatusIgnored    = "ignored"
+	RestDayStatusOverridden = "overridden"
+)
+
 // DeloadRecommendation represents an AI recommendation for deload weeks
 type DeloadRecommendation struct {
 	ID                        int        `json:"id" db:"id"`
@@ -1067,6 +1474,43 @@ type DeloadRecommendation struct {
 	UpdatedAt                 time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// Reasons the deload detector recommends a deload, most pressing first
+const (
+	DeloadReasonOverreaching        = "overreaching"         // lifts regressing while the same loads feel harder
+	DeloadReasonFatigueAccumulation = "fatigue_accumulation" // the same loads feel harder
+	DeloadReasonPlateau             = "plateau"              // e1RMs of the key lifts stalled
+	DeloadReasonScheduled           = "scheduled"            // many weeks of training since the last deload
+)
+
+// Statuses of deload recommendations
+const (
+	DeloadStatusSuggested = "suggested"
+	DeloadStatusAccepted  = "accepted"
+	DeloadStatusIgnored   = "ignored"
+)
+
+// DeloadTriggerMetrics are the measures behind a deload recommendation, stored as its
+// trigger_metrics
+type DeloadTriggerMetrics struct {
+	WeeksSinceDeload int                 `json:"weeks_since_deload"`
+	StalledLifts     int                 `json:"stalled_lifts"`
+	RegressedLifts   int                 `json:"regressed_lifts"`
+	RisingRPELifts   int                 `json:"rising_rpe_lifts"`
+	Lifts            []DeloadLiftMetrics `json:"lifts"`
+}
+
+// DeloadLiftMetrics compares a key lift's last three weeks with the three weeks before
+type DeloadLiftMetrics struct {
+	Exercise      string   `json:"exercise"`
+	PreviousE1RM  float64  `json:"previous_e1rm"`
+	RecentE1RM    float64  `json:"recent_e1rm"`
+	ChangePercent float64  `json:"change_percent"`
+	RPEChange     *float64 `json:"rpe_change"` // at the same weight and reps; nil without rated sets in both periods
+	Stalled       bool     `json:"stalled"`
+	Regressed     bool     `json:"regressed"`
+	RisingRPE     bool     `json:"rising_rpe"`
+}
+
 // WorkoutCalendarEvent represents an event in the workout calendar
 type WorkoutCalendarEvent struct {
 	ID                 int       `json:"id" db:"id"`
@@ -1082,45 +1526,109 @@ type WorkoutCalendarEvent struct {
 	AllDay             bool      `json:"all_day" db:"all_day"`
 	Color              string    `json:"color" db:"color"`
 	IsRecurring        bool      `json:"is_recurring" db:"is_recurring"`
-	RecurrencePattern  *string   `json:"recurrence_pattern" db:"recurrence_pattern"` // JSON object
+	RecurrencePattern  *string   `json:"recurrence_pattern" db:"recurrence_pattern"` // JSON RecurrencePattern
 	CreatedAt          time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
+	// RecurrenceID is the start of the occurrence of a recurring event that an expanded event stands for
+	RecurrenceID       *time.Time `json:"recurrence_id,omitempty"`
 }
 
+// RecurrencePattern is how a recurring calendar event repeats: an RFC 5545 RRULE such as
+// FREQ=WEEKLY;BYDAY=MO,TH and the days, as YYYY-MM-DD, whose occurrences are left out
+type RecurrencePattern struct {
+	RRule   string   `json:"rrule"`
+	ExDates []string `json:"exdates,omitempty"`
+}
+
+// Types of calendar events
+const (
+	CalendarEventWorkout = "workout"
+	CalendarEventRestDay = "rest_day"
+	CalendarEventDeload  = "deload"
+)
+
+// Scopes of an edit to an occurrence of a recurring calendar event
+const (
+	OccurrenceScopeThis   = "this"
+	OccurrenceScopeFuture = "future"
+)
+
 // Request models for scheduling operations
 type CreateScheduledWorkoutRequest struct {
 	TemplateID        *int   `json:"template_id"`
-	Title             string `json:"title" validate:"required"`
+	Title             string `json:"title" validate:"required,max=200"`
 	Description       string `json:"description"`
 	ScheduledDate     string `json:"scheduled_date" validate:"required"` // YYYY-MM-DD
 	ScheduledTime     string `json:"scheduled_time"` // HH:MM
-	EstimatedDuration int    `json:"estimated_duration"`
+	EstimatedDuration int    `json:"estimated_duration" validate:"min=0,max=1440"`
 	Notes             string `json:"notes"`
 }
 
 type UpdateScheduledWorkoutRequest struct {
-	Title             string `json:"title" validate:"required"`
+	Title             string `json:"title" validate:"required,max=200"`
 	Description       string `json:"description"`
 	ScheduledDate     string `json:"scheduled_date" validate:"required"`
 	ScheduledTime     string `json:"scheduled_time"`
-	EstimatedDuration int    `json:"estimated_duration"`
-	Status            string `json:"status"`
+	EstimatedDuration int    `json:"estimated_duration" validate:"min=0,max=1440"`
+	Status            string `json:"status" validate:"oneof=scheduled completed skipped cancelled"`
 	Notes             string `json:"notes"`
 }
 
+// CalendarEventRequest creates or replaces a calendar event, or a series of them when it
+// has a recurrence rule. Dates are YYYY-MM-DD for all-day events and RFC 3339 otherwise.
+type CalendarEventRequest struct {
+	EventType          string   `json:"event_type" validate:"oneof=workout rest_day deload"`
+	Title              string   `json:"title" validate:"required,max=200"`
+	Description        string   `json:"description" validate:"max=2000"`
+	StartDate          string   `json:"start_date" validate:"required"`
+	EndDate            string   `json:"end_date"`
+	Color              string   `json:"color" validate:"max=20"`
+	ScheduledWorkoutID *int     `json:"scheduled_workout_id"`
+	RRule              string   `json:"rrule" validate:"max=500"`
+	ExceptionDates     []string `json:"exception_dates"` // YYYY-MM-DD
+}
+
+// CalendarFeed is a user's secret iCalendar feed of their training schedule. Only the
+// SHA-256 hash of its token is stored; the feed URL is shown once when it is generated.
+type CalendarFeed struct {
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	Prefix     string     `json:"prefix" db:"prefix"` // first characters of the token, for display
+	TokenHash  string     `json:"-" db:"token_hash"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// CalendarFeedResponse carries the plaintext token and the feed URL, which are never
+// retrievable again
+type CalendarFeedResponse struct {
+	CalendarFeed
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// ICalImportResult reports what importing an .ics file into scheduled workouts did
+type ICalImportResult struct {
+	Imported int `json:"imported"`
+	// Duplicates were imported before and left alone
+	Duplicates int `json:"duplicates"`
+	// Skipped describes the events that could not be imported
+	Skipped []string `json:"skipped"`
+}
+
 type CreateReminderRequest struct {
-	ReminderType    string `json:"reminder_type" validate:"required"` // email, push, sms
+	ReminderType    string `json:"reminder_type" validate:"required,oneof=email push sms"`
 	Message         string `json:"message" validate:"required"`
-	MinutesBefore   int    `json:"minutes_before" validate:"required"` // Minutes before workout
+	MinutesBefore   int    `json:"minutes_before" validate:"required,min=1"` // Minutes before workout
 }
 
 type RestDayResponseRequest struct {
-	Status       string `json:"status" validate:"required"` // accepted, ignored, overridden
+	Status       string `json:"status" validate:"required,oneof=accepted ignored overridden"`
 	UserResponse string `json:"user_response"`
 }
 
 type DeloadResponseRequest struct {
-	Status       string `json:"status" validate:"required"` // accepted, ignored, overridden
+	Status       string `json:"status" validate:"required,oneof=accepted ignored"`
 	UserResponse string `json:"user_response"`
 }
 
@@ -1207,6 +1715,7 @@ type CreateTemplateExerciseRequest struct {
 	TargetWeight float64 `json:"target_weight"`
 	RestTime     int     `json:"rest_time"`
 	Notes        string  `json:"notes"`
+	GroupID      int     `json:"group_id" validate:"min=0"`
 }
 
 type UpdateWorkoutTemplateRequest struct {
@@ -1284,3 +1793,91 @@ type WorkoutFromTemplateResponse struct {
 	TemplateUsed WorkoutTemplate `json:"template_used"`
 	Message      string        `json:"message"`
 }
+
+// ========== LIVE WORKOUT SESSION MODELS ==========
+
+// WorkoutSession is a workout in progress. The server keeps its state so the session
+// can be resumed on another device; finishing it saves a regular Workout.
+type WorkoutSession struct {
+	ID                int               `json:"id" db:"id"`
+	UserID            int               `json:"user_id" db:"user_id"`
+	TemplateID        *int              `json:"template_id" db:"template_id"`
+	Name              string            `json:"name" db:"name"`
+	StartedAt         time.Time         `json:"started_at" db:"started_at"`
+	CurrentExerciseID int               `json:"current_exercise_id" db:"current_exercise_id"` // 0 before the first exercise is added
+	RestEndsAt        *time.Time        `json:"rest_ends_at" db:"rest_ends_at"`               // nil when no rest timer is running
+	RestRemaining     int               `json:"rest_remaining"`                               // seconds left on the rest timer when served
+	FinishedAt        *time.Time        `json:"finished_at" db:"finished_at"`
+	WorkoutID         *int              `json:"workout_id" db:"workout_id"` // the saved workout once finished
+	Exercises         []SessionExercise `json:"exercises"`
+	CreatedAt         time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// SessionExercise is an exercise planned or added during a session. Its Sets are the
+// sets completed so far; their ExerciseID refers to the session exercise.
+type SessionExercise struct {
+	ID           int     `json:"id" db:"id"`
+	SessionID    int     `json:"session_id" db:"session_id"`
+	Name         string  `json:"name" db:"name"`
+	Category     string  `json:"category" db:"category"`
+	OrderIndex   int     `json:"order_index" db:"order_index"`
+	GroupID      int     `json:"group_id" db:"group_id"`
+	TargetSets   int     `json:"target_sets" db:"target_sets"`
+	TargetReps   int     `json:"target_reps" db:"target_reps"`
+	TargetWeight float64 `json:"target_weight" db:"target_weight"`
+	RestTime     int     `json:"rest_time" db:"rest_time"` // in seconds; starts the rest timer after each set
+	Sets         []Set   `json:"completed_sets"`
+}
+
+// Exercise returns the session exercise by ID
+func (s WorkoutSession) Exercise(id int) (SessionExercise, bool) {
+	for _, exercise := range s.Exercises {
+		if exercise.ID == id {
+			return exercise, true
+		}
+	}
+	return SessionExercise{}, false
+}
+
+// StartSessionRequest starts a session from scratch or, with a template ID, from a template
+type StartSessionRequest struct {
+	Name       string `json:"name" validate:"max=100"` // defaults to the template's name
+	TemplateID int    `json:"template_id" validate:"min=0"`
+}
+
+// AddSessionExerciseRequest adds an exercise to a running session
+type AddSessionExerciseRequest struct {
+	Name         string  `json:"name" validate:"required,max=100"`
+	Category     string  `json:"category" validate:"required,max=50"`
+	GroupID      int     `json:"group_id" validate:"min=0"`
+	TargetSets   int     `json:"target_sets" validate:"min=0"`
+	TargetReps   int     `json:"target_reps" validate:"min=0"`
+	TargetWeight float64 `json:"target_weight" validate:"min=0"`
+	RestTime     int     `json:"rest_time" validate:"min=0,max=3600"`
+}
+
+// CompleteSessionSetRequest records a completed set and starts the rest timer
+type CompleteSessionSetRequest struct {
+	ExerciseID int      `json:"exercise_id" validate:"required"`
+	Reps       int      `json:"reps" validate:"min=0"`
+	Weight     float64  `json:"weight" validate:"min=0"`
+	Distance   float64  `json:"distance" validate:"min=0"`
+	Duration   int      `json:"duration" validate:"min=0"`
+	RPE        *float64 `json:"rpe" validate:"min=1,max=10"`
+	RIR        *int     `json:"rir" validate:"min=0,max=10"`
+	SetType    string   `json:"set_type" validate:"oneof=warmup working drop failure amrap backoff"`
+	Notes      string   `json:"notes" validate:"max=500"`
+	RestTime   *int     `json:"rest_time" validate:"min=0,max=3600"` // overrides the exercise's rest time
+}
+
+// UpdateSessionRequest moves a session to another exercise or restarts its rest timer
+type UpdateSessionRequest struct {
+	CurrentExerciseID int  `json:"current_exercise_id" validate:"min=0"` // 0 keeps the current exercise
+	RestTime          *int `json:"rest_time" validate:"min=0,max=3600"`  // seconds from now; 0 stops the timer
+}
+
+// FinishSessionRequest finishes a session and saves it as a workout
+type FinishSessionRequest struct {
+	Notes string `json:"notes" validate:"max=1000"`
+}
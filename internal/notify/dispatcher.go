@@ -0,0 +1,234 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/storage"
+)
+
+// Dispatcher defaults
+const (
+	DefaultPollInterval = 30 * time.Second
+	DefaultMaxAttempts  = 5
+	DefaultBackoff      = time.Minute
+
+	// maxBackoff caps the wait between two attempts
+	maxBackoff = time.Hour
+	// claimDuration is how long a reminder being sent is held back from other dispatchers
+	claimDuration = 5 * time.Minute
+	// batchSize is how many due reminders one poll sends at most
+	batchSize = 100
+)
+
+// Dispatcher sends due workout reminders through the notifier of their channel.
+// Reminders of channels without a notifier stay pending.
+type Dispatcher struct {
+	store        storage.Store
+	channels     map[string]Notifier
+	now          func() time.Time
+	pollInterval time.Duration
+	maxAttempts  int
+	backoff      time.Duration
+}
+
+// DispatcherOption customises a Dispatcher created by NewDispatcher
+type DispatcherOption func(*Dispatcher)
+
+// WithChannel sends reminders of the given type, such as models.ReminderTypeEmail,
+// through the notifier
+func WithChannel(reminderType string, notifier Notifier) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.channels[reminderType] = notifier
+	}
+}
+
+// WithClock replaces time.Now, so tests can pin the current time
+func WithClock(now func() time.Time) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.now = now
+	}
+}
+
+// WithPollInterval changes how often Run looks for due reminders
+func WithPollInterval(interval time.Duration) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.pollInterval = interval
+	}
+}
+
+// WithRetry changes how many times a reminder is attempted before it is marked failed,
+// and the wait after the first failure, which doubles after each further one
+func WithRetry(maxAttempts int, backoff time.Duration) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.maxAttempts = maxAttempts
+		d.backoff = backoff
+	}
+}
+
+// NewDispatcher returns a dispatcher reading reminders from the store
+func NewDispatcher(store storage.Store, opts ...DispatcherOption) *Dispatcher {
+	d := &Dispatcher{
+		store:        store,
+		channels:     map[string]Notifier{},
+		now:          time.Now,
+		pollInterval: DefaultPollInterval,
+		maxAttempts:  DefaultMaxAttempts,
+		backoff:      DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run sends due reminders every poll interval until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchDue(ctx); err != nil {
+			log.Printf("Failed to dispatch reminders: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue attempts every reminder due now once and returns how many it sent.
+// Failed attempts are recorded on the reminders; the error is for the store failing.
+// A reminder the store fails on does not hold up the others, and the error joins
+// every such failure.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	types := make([]string, 0, len(d.channels))
+	for t := range d.channels {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	now := d.now()
+	due, err := d.store.Reminders().Due(types, now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	var errs []error
+	for _, reminder := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		ok, err := d.dispatch(ctx, reminder, now)
+		if err != nil {
+			log.Printf("Failed to dispatch reminder %d: %v", reminder.ID, err)
+			errs = append(errs, fmt.Errorf("reminder %d: %v", reminder.ID, err))
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// dispatch claims a due reminder and sends it, cancels it if it should no longer go
+// out, or records the failed attempt. It reports whether the reminder was sent.
+func (d *Dispatcher) dispatch(ctx context.Context, reminder models.DueReminder, now time.Time) (bool, error) {
+	reminders := d.store.Reminders()
+	err := reminders.Claim(reminder.ID, now, now.Add(claimDuration))
+	if err == storage.ErrNotFound {
+		// Another dispatcher got to it first
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	loc, err := time.LoadLocation(reminder.Timezone)
+	if err != nil || reminder.Timezone == "" {
+		loc = time.UTC
+	}
+	switch {
+	case !reminder.Notifications:
+		return false, reminders.Cancel(reminder.ID, "notifications are turned off")
+	case reminder.WorkoutStatus != models.ScheduledStatusScheduled:
+		return false, reminders.Cancel(reminder.ID, "the workout is "+reminder.WorkoutStatus)
+	case !now.Before(workoutEnd(reminder, loc)):
+		return false, reminders.Cancel(reminder.ID, "the workout has already started")
+	}
+
+	err = d.channels[reminder.ReminderType].Notify(ctx, reminderMessage(reminder, now.In(loc)))
+	if err == nil {
+		return true, reminders.MarkSent(reminder.ID, now)
+	}
+
+	attempts := reminder.Attempts + 1
+	if attempts >= d.maxAttempts {
+		log.Printf("Giving up on reminder %d after %d attempts: %v", reminder.ID, attempts, err)
+		return false, reminders.MarkFailed(reminder.ID, err.Error(), nil)
+	}
+	retryAt := now.Add(d.retryDelay(attempts))
+	return false, reminders.MarkFailed(reminder.ID, err.Error(), &retryAt)
+}
+
+// retryDelay is how long to wait after the given number of failed attempts
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// workoutEnd is when reminding about the workout stops making sense: its start, or the
+// end of its day for a workout without a time
+func workoutEnd(reminder models.DueReminder, loc *time.Location) time.Time {
+	start := models.ScheduledStart(reminder.WorkoutDate, reminder.WorkoutTime, loc)
+	if reminder.WorkoutTime == nil {
+		return start.AddDate(0, 0, 1)
+	}
+	return start
+}
+
+// reminderMessage words a reminder for the user, with the workout's day and time as
+// they see them: "today", "tomorrow" or the date, in their time zone
+func reminderMessage(reminder models.DueReminder, now time.Time) Message {
+	start := models.ScheduledStart(reminder.WorkoutDate, reminder.WorkoutTime, now.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var when string
+	switch {
+	case start.Before(today.AddDate(0, 0, 1)):
+		when = "today"
+	case start.Before(today.AddDate(0, 0, 2)):
+		when = "tomorrow"
+	default:
+		when = "on " + start.Format("Monday 2 January")
+	}
+	if reminder.WorkoutTime != nil {
+		when += " at " + start.Format("15:04")
+	}
+
+	var body strings.Builder
+	if reminder.Message != "" {
+		body.WriteString(reminder.Message)
+		body.WriteString("\n\n")
+	}
+	fmt.Fprintf(&body, "%s is scheduled %s (%s).\n", reminder.WorkoutTitle, when, now.Location())
+	return Message{
+		To:      reminder.Email,
+		ToName:  reminder.Username,
+		Subject: fmt.Sprintf("Reminder: %s %s", reminder.WorkoutTitle, when),
+		Body:    body.String(),
+		Date:    now,
+	}
+}
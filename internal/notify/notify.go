@@ -0,0 +1,35 @@
+// Package notify delivers workout reminders. A Dispatcher polls the store for due
+// reminders and hands each to the Notifier registered for its channel, retrying failed
+// deliveries with backoff. Email goes out over SMTP; other channels plug in as Notifiers.
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// Message is one notification addressed to a user
+type Message struct {
+	// To is the channel's address for the user, such as an email address
+	To string
+	// ToName is the user's name, where the channel has a place for it
+	ToName  string
+	Subject string
+	Body    string
+	// Date is when the message is sent, in the user's time zone
+	Date time.Time
+}
+
+// Notifier sends messages over one channel. Notify returns an error when the message
+// could not be handed over; the dispatcher retries it later.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// NotifierFunc adapts a function to a Notifier
+type NotifierFunc func(ctx context.Context, msg Message) error
+
+// Notify calls f
+func (f NotifierFunc) Notify(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"mime"
+	"strings"
+	"testing"
+	"time"
+
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/notify/smtptest"
+)
+
+func TestSMTPNotifier(t *testing.T) {
+	server := smtptest.NewServer(t)
+	email, err := NewSMTP(SMTPConfig{Addr: server.Addr, From: "Workout Tracker <gym@example.com>"})
+	if err != nil {
+		t.Fatalf("NewSMTP: %v", err)
+	}
+	date := time.Date(2026, 10, 16, 17, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	msg := Message{To: "alice@example.com", ToName: "alice", Subject: "Reminder: Rücken today", Body: "Line one\n.\nLine three", Date: date}
+	if err := email.Notify(context.Background(), msg); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	mail := server.Mail()
+	if len(mail) != 1 || mail[0].From != "gym@example.com" || strings.Join(mail[0].To, ",") != "alice@example.com" {
+		t.Fatalf("mail = %+v", mail)
+	}
+	parsed, err := mail[0].Message()
+	if err != nil {
+		t.Fatalf("email does not parse: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject = %q, %v; want %q", subject, err, msg.Subject)
+	}
+	if to := parsed.Header.Get("To"); to != `"alice" <alice@example.com>` {
+		t.Errorf("To = %q", to)
+	}
+	if got, err := parsed.Header.Date(); err != nil || !got.Equal(date) || got.Format("-0700") != "+0200" {
+		t.Errorf("Date = %v, %v; want %v", got, err, date)
+	}
+	// The lone dot survives the SMTP transfer
+	if body, _ := io.ReadAll(parsed.Body); string(body) != "Line one\r\n.\r\nLine three\r\n" {
+		t.Errorf("body = %q", body)
+	}
+
+	server.FailNext(1)
+	if err := email.Notify(context.Background(), msg); err == nil || !strings.Contains(err.Error(), "451") {
+		t.Errorf("Notify to a server rejecting the message: err = %v, want the 451", err)
+	}
+	if err := email.Notify(context.Background(), Message{To: "not an address"}); err == nil {
+		t.Errorf("Notify to an invalid address succeeded")
+	}
+	if len(server.Mail()) != 1 {
+		t.Errorf("server accepted %d messages, want 1", len(server.Mail()))
+	}
+
+	for _, config := range []SMTPConfig{{Addr: "localhost", From: "gym@example.com"}, {Addr: server.Addr, From: ""}} {
+		if _, err := NewSMTP(config); err == nil {
+			t.Errorf("NewSMTP(%+v) succeeded, want an error", config)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	d := NewDispatcher(nil, WithRetry(10, time.Minute))
+	tests := map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 3: 4 * time.Minute, 7: time.Hour, 9: time.Hour}
+	for attempts, want := range tests {
+		if got := d.retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestReminderMessage(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	// Thursday 15 October, 23:30 in Tokyo
+	now := time.Date(2026, 10, 15, 14, 30, 0, 0, time.UTC).In(tokyo)
+	clock := "07:00"
+	tests := []struct {
+		date    time.Time
+		clock   *string
+		subject string
+	}{
+		{time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), &clock, "Reminder: Swim tomorrow at 07:00"},
+		{time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), nil, "Reminder: Swim today"},
+		{time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), &clock, "Reminder: Swim on Tuesday 20 October at 07:00"},
+	}
+	for _, tt := range tests {
+		reminder := models.DueReminder{Email: "alice@example.com", Username: "alice", WorkoutTitle: "Swim", WorkoutDate: tt.date, WorkoutTime: tt.clock}
+		msg := reminderMessage(reminder, now)
+		if msg.Subject != tt.subject || msg.To != "alice@example.com" || !msg.Date.Equal(now) {
+			t.Errorf("reminderMessage for %v = %+v, want subject %q", tt.date, msg, tt.subject)
+		}
+		if !strings.HasSuffix(msg.Body, "(Asia/Tokyo).\n") {
+			t.Errorf("body = %q, want the time zone", msg.Body)
+		}
+	}
+}
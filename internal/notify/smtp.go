@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// smtpTimeout bounds a whole delivery when the context has no earlier deadline
+const smtpTimeout = 30 * time.Second
+
+// SMTPConfig says which server email reminders are relayed through
+type SMTPConfig struct {
+	// Addr is the server's host:port
+	Addr string
+	// From is the sender address, optionally with a name: "Workout Tracker <gym@example.com>"
+	From string
+	// Username and Password authenticate with PLAIN when both are set. net/smtp only
+	// sends them over TLS or to localhost.
+	Username string
+	Password string
+}
+
+// SMTPNotifier sends email through an SMTP server, upgrading to TLS when the server
+// offers STARTTLS
+type SMTPNotifier struct {
+	config SMTPConfig
+	from   *mail.Address
+}
+
+// NewSMTP returns a notifier relaying through the configured server
+func NewSMTP(config SMTPConfig) (*SMTPNotifier, error) {
+	if _, _, err := net.SplitHostPort(config.Addr); err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %v", config.Addr, err)
+	}
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %v", config.From, err)
+	}
+	return &SMTPNotifier{config: config, from: from}, nil
+}
+
+// Notify sends the message as a plain text email to msg.To
+func (n *SMTPNotifier) Notify(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address %q: %v", msg.To, err)
+	}
+	to.Name = msg.ToName
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, smtpTimeout)
+		defer cancel()
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.config.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %v", err)
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	host, _, _ := net.SplitHostPort(n.config.Addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %v", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %v", err)
+		}
+	}
+	if n.config.Username != "" && n.config.Password != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, host)); err != nil {
+			return fmt.Errorf("failed to authenticate with SMTP server: %v", err)
+		}
+	}
+	if err := client.Mail(n.from.Address); err != nil {
+		return fmt.Errorf("failed to set sender: %v", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("failed to set recipient: %v", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %v", err)
+	}
+	if _, err := w.Write(emailMessage(n.from, to, msg)); err != nil {
+		return fmt.Errorf("failed to write message: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %v", err)
+	}
+	return client.Quit()
+}
+
+// emailMessage formats a message as a plain text email with CRLF line ends
+func emailMessage(from, to *mail.Address, msg Message) []byte {
+	date := msg.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	if !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return buf.Bytes()
+}
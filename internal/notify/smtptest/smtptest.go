@@ -0,0 +1,178 @@
+// Package smtptest runs a fake SMTP server on a local port that records the mail it
+// accepts, so tests can point an SMTP notifier at it and read what was sent
+package smtptest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Mail is one message the server accepted
+type Mail struct {
+	From string
+	To   []string
+	// Data is the message as sent, headers and body, without the terminating dot
+	Data string
+}
+
+// Message parses the mail's headers and body
+func (m Mail) Message() (*mail.Message, error) {
+	return mail.ReadMessage(strings.NewReader(m.Data))
+}
+
+// Server is a fake SMTP server. It speaks just enough of the protocol for net/smtp:
+// EHLO, HELO, MAIL, RCPT, DATA, RSET, NOOP and QUIT, with no TLS or authentication.
+type Server struct {
+	// Addr is the host:port the server listens on
+	Addr string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	mail     []Mail
+	failures int
+}
+
+// NewServer starts a server on a free local port and stops it when the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start fake SMTP server: %v", err)
+	}
+	s := &Server{Addr: listener.Addr().String(), listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Close stops the server and waits for its connections to end
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// Mail returns the messages accepted so far, oldest first
+func (s *Server) Mail() []Mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Mail(nil), s.mail...)
+}
+
+// FailNext makes the server reject the next n messages with a temporary error
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle runs one SMTP session
+func (s *Server) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	reply("220 smtptest ready")
+	var current Mail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-smtptest")
+			reply("250 8BITMIME")
+		case "HELO":
+			reply("250 smtptest")
+		case "MAIL":
+			current = Mail{From: address(arg)}
+			reply("250 OK")
+		case "RCPT":
+			current.To = append(current.To, address(arg))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := readData(r)
+			if err != nil {
+				return
+			}
+			current.Data = data
+			if s.accept(current) {
+				reply("250 OK")
+			} else {
+				reply("451 Try again later")
+			}
+			current = Mail{}
+		case "RSET":
+			current = Mail{}
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// accept records a message unless the server was told to fail it
+func (s *Server) accept(m Mail) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return false
+	}
+	s.mail = append(s.mail, m)
+	return true
+}
+
+// readData reads a message up to the line holding a single dot, undoing dot-stuffing
+func readData(r *bufio.Reader) (string, error) {
+	var data strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return data.String(), nil
+		}
+		data.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+// address takes the address out of "FROM:<a@example.com>" or "TO:<b@example.com>"
+func address(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(strings.TrimSpace(addr), " ")
+	return strings.Trim(addr, "<>")
+}
@@ -0,0 +1,211 @@
+package recommend
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"workout-tracker/internal/e1rm"
+	"workout-tracker/internal/models"
+)
+
+const (
+	// DeloadWindowDays is the length of each of the two periods the deload detector
+	// compares, the recent one ending on the day of the check
+	DeloadWindowDays = 21
+	// KeyLifts is how many of the exercises trained in both periods, most often trained
+	// first, the detector looks at
+	KeyLifts = 3
+	// StallPercent is the e1RM gain below which a lift has stalled, RegressionPercent the
+	// loss at which it has regressed
+	StallPercent      = 1.0
+	RegressionPercent = 2.5
+	// RisingRPE is how much harder, in RPE, the same weight and reps must feel
+	RisingRPE = 1.0
+	// DeloadEveryWeeks is how many weeks of training call for a deload regardless
+	DeloadEveryWeeks = 8
+	// DeloadDays is the length of a recommended deload and DeloadCooldownDays how long
+	// after one ends before another is recommended
+	DeloadDays         = 7
+	DeloadCooldownDays = 14
+	// VolumeReductionPercent and IntensityReductionPercent are how far a deload cuts
+	// the sets and the weights of scheduled workouts
+	VolumeReductionPercent    = 40
+	IntensityReductionPercent = 20
+)
+
+// LiftSet is a working set of a lift
+type LiftSet struct {
+	Exercise string
+	Date     time.Time
+	Weight   float64
+	Reps     int
+	RPE      *float64
+	RIR      *int
+}
+
+// Deload compares the key lifts' sets of the DeloadWindowDays up to a day with the
+// period before and returns the most pressing reason for a deload, or "" when none is
+// needed, with the metrics behind it. weeksSinceDeload counts the weeks of training
+// since the last deload, or since the first workout.
+func Deload(sets []LiftSet, formula e1rm.Formula, day time.Time, weeksSinceDeload int) (string, models.DeloadTriggerMetrics) {
+	metrics := models.DeloadTriggerMetrics{WeeksSinceDeload: weeksSinceDeload, Lifts: []models.DeloadLiftMetrics{}}
+	recentStart := dateOf(day).AddDate(0, 0, 1-DeloadWindowDays)
+	previousStart := recentStart.AddDate(0, 0, -DeloadWindowDays)
+
+	// Split each lift's sets between the periods, counting the days it was trained
+	type lift struct {
+		recent, previous []LiftSet
+		days             map[time.Time]bool
+	}
+	lifts := make(map[string]*lift)
+	for _, s := range sets {
+		date := dateOf(s.Date)
+		if date.Before(previousStart) || date.After(dateOf(day)) {
+			continue
+		}
+		l := lifts[s.Exercise]
+		if l == nil {
+			l = &lift{days: make(map[time.Time]bool)}
+			lifts[s.Exercise] = l
+		}
+		if date.Before(recentStart) {
+			l.previous = append(l.previous, s)
+		} else {
+			l.recent = append(l.recent, s)
+		}
+		l.days[date] = true
+	}
+
+	var names []string
+	for name, l := range lifts {
+		if len(l.recent) > 0 && len(l.previous) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if a, b := len(lifts[names[i]].days), len(lifts[names[j]].days); a != b {
+			return a > b
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > KeyLifts {
+		names = names[:KeyLifts]
+	}
+
+	rated := 0
+	for _, name := range names {
+		l := lifts[name]
+		m := models.DeloadLiftMetrics{
+			Exercise:     name,
+			PreviousE1RM: bestEstimate(l.previous, formula),
+			RecentE1RM:   bestEstimate(l.recent, formula),
+		}
+		if m.PreviousE1RM > 0 {
+			m.ChangePercent = 100 * (m.RecentE1RM - m.PreviousE1RM) / m.PreviousE1RM
+			m.Stalled = m.ChangePercent < StallPercent
+			m.Regressed = m.ChangePercent <= -RegressionPercent
+		}
+		if change, ok := rpeChange(l.previous, l.recent); ok {
+			m.RPEChange = &change
+			m.RisingRPE = change >= RisingRPE
+			rated++
+		}
+
+		if m.Stalled {
+			metrics.StalledLifts++
+		}
+		if m.Regressed {
+			metrics.RegressedLifts++
+		}
+		if m.RisingRPE {
+			metrics.RisingRPELifts++
+		}
+		metrics.Lifts = append(metrics.Lifts, m)
+	}
+
+	// Stalls and rising effort count when they show in at least half the lifts measured
+	plateau := len(names) >= 2 && 2*metrics.StalledLifts >= len(names)
+	fatigue := metrics.RisingRPELifts > 0 && 2*metrics.RisingRPELifts >= rated
+	switch {
+	case fatigue && metrics.RegressedLifts > 0:
+		return models.DeloadReasonOverreaching, metrics
+	case fatigue:
+		return models.DeloadReasonFatigueAccumulation, metrics
+	case plateau:
+		return models.DeloadReasonPlateau, metrics
+	case weeksSinceDeload >= DeloadEveryWeeks:
+		return models.DeloadReasonScheduled, metrics
+	}
+	return "", metrics
+}
+
+// DeloadTargets scales a scheduled exercise's target sets and weight down by a deload's
+// reductions. At least one set is kept and weights are rounded to the nearest half unit.
+func DeloadTargets(sets int, weight float64, volumePercent, intensityPercent int) (int, float64) {
+	if sets > 0 {
+		sets = int(math.Max(1, math.Round(float64(sets*(100-volumePercent))/100)))
+	}
+	weight = math.Round(weight*float64(100-intensityPercent)/100*2) / 2
+	return sets, weight
+}
+
+// bestEstimate returns the best estimated 1RM of the sets
+func bestEstimate(sets []LiftSet, formula e1rm.Formula) float64 {
+	best := 0.0
+	for _, s := range sets {
+		if estimate, ok := formula.EstimateSet(s.Weight, s.Reps, s.RPE, s.RIR); ok && estimate > best {
+			best = estimate
+		}
+	}
+	return best
+}
+
+// rpeChange returns how much harder, on average, the weights and reps done in both
+// periods felt recently. RIR counts as 10 - RIR. ok is false when no weight and reps
+// were rated in both.
+func rpeChange(previous, recent []LiftSet) (change float64, ok bool) {
+	type load struct {
+		weight float64
+		reps   int
+	}
+	mean := func(sets []LiftSet) map[load]float64 {
+		total := make(map[load]float64)
+		count := make(map[load]int)
+		for _, s := range sets {
+			var rpe float64
+			switch {
+			case s.RIR != nil:
+				rpe = math.Max(10-float64(*s.RIR), 0)
+			case s.RPE != nil:
+				rpe = *s.RPE
+			default:
+				continue
+			}
+			total[load{s.Weight, s.Reps}] += rpe
+			count[load{s.Weight, s.Reps}]++
+		}
+		for l := range total {
+			total[l] /= float64(count[l])
+		}
+		return total
+	}
+
+	before, after := mean(previous), mean(recent)
+	sum, n := 0.0, 0
+	for l, rpe := range after {
+		if earlier, ok := before[l]; ok {
+			sum += rpe - earlier
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// dateOf returns the UTC calendar date of t
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,76 @@
+// Package recommend decides from a user's recent training when they should rest or deload.
+//
+// The rest day rules look at the run of training days, the last week's volume against
+// the weeks before it, how hard the last few sessions were and how fatigued each muscle
+// is, and return the most pressing reason to rest. The deload detector compares the key
+// lifts' last three weeks with the three before: stalled or regressing e1RMs and the
+// same loads feeling harder call for a deload, as do many weeks without one.
+package recommend
+
+import (
+	"sort"
+
+	"workout-tracker/internal/models"
+)
+
+const (
+	// MaxConsecutiveDays is how many days in a row a user trains before a rest day is due
+	MaxConsecutiveDays = 5
+	// HighVolumeRatio is how far the last week's volume may exceed the average week
+	// before it
+	HighVolumeRatio = 1.5
+	// IntensityDays is how many days the intensity score looks back on
+	IntensityDays = 3
+	// HighIntensity is the intensity score at which IntenseSessions sessions within
+	// IntensityDays call for rest
+	HighIntensity   = 8.5
+	IntenseSessions = 2
+	// FatiguedMuscleSets is the fatigue, in hard sets decayed by age, at which a muscle
+	// needs rest
+	FatiguedMuscleSets = 12
+)
+
+// Signals are the measures of a user's recent training the rest day rules read
+type Signals struct {
+	ConsecutiveDays int
+	VolumeLoad      float64 // tonnage of the last seven days
+	BaselineVolume  float64 // average weekly tonnage of the three weeks before them
+	IntensityScore  float64 // mean session RPE of the last IntensityDays days, 0-10
+	Sessions        int     // sessions in the last IntensityDays days
+	MuscleFatigue   map[string]float64
+}
+
+// RestDay returns the most pressing reason for a rest day, or "" when none is needed,
+// and the muscles fatigued enough to need rest, most fatigued first
+func RestDay(s Signals) (reason string, muscles []string) {
+	muscles = FatiguedMuscles(s.MuscleFatigue)
+	switch {
+	case s.ConsecutiveDays >= MaxConsecutiveDays:
+		reason = models.RestDayReasonConsecutiveDays
+	case s.BaselineVolume > 0 && s.VolumeLoad > HighVolumeRatio*s.BaselineVolume:
+		reason = models.RestDayReasonHighVolume
+	case s.Sessions >= IntenseSessions && s.IntensityScore >= HighIntensity:
+		reason = models.RestDayReasonHighIntensity
+	case len(muscles) > 0:
+		reason = models.RestDayReasonMuscleFatigue
+	}
+	return reason, muscles
+}
+
+// FatiguedMuscles returns the muscles whose fatigue reaches FatiguedMuscleSets, most
+// fatigued first
+func FatiguedMuscles(fatigue map[string]float64) []string {
+	muscles := []string{}
+	for muscle, sets := range fatigue {
+		if sets >= FatiguedMuscleSets {
+			muscles = append(muscles, muscle)
+		}
+	}
+	sort.Slice(muscles, func(i, j int) bool {
+		if fatigue[muscles[i]] != fatigue[muscles[j]] {
+			return fatigue[muscles[i]] > fatigue[muscles[j]]
+		}
+		return muscles[i] < muscles[j]
+	})
+	return muscles
+}
@@ -0,0 +1,137 @@
+package recommend
+
+import (
+	"testing"
+	"time"
+
+	"workout-tracker/internal/e1rm"
+	"workout-tracker/internal/models"
+)
+
+func TestRestDay(t *testing.T) {
+	fatigued := map[string]float64{"Chest": 14, "Triceps": 12, "Quads": 3}
+
+	tests := []struct {
+		name    string
+		signals Signals
+		want    string
+	}{
+		{"rested", Signals{ConsecutiveDays: 2, VolumeLoad: 5000, BaselineVolume: 4000, IntensityScore: 7, Sessions: 2}, ""},
+		{"a long run of training days", Signals{ConsecutiveDays: 5, VolumeLoad: 9000, BaselineVolume: 4000, MuscleFatigue: fatigued}, models.RestDayReasonConsecutiveDays},
+		{"a volume spike", Signals{ConsecutiveDays: 3, VolumeLoad: 6001, BaselineVolume: 4000}, models.RestDayReasonHighVolume},
+		{"no baseline to spike from", Signals{ConsecutiveDays: 3, VolumeLoad: 6001}, ""},
+		{"hard sessions close together", Signals{IntensityScore: 8.5, Sessions: 2, MuscleFatigue: fatigued}, models.RestDayReasonHighIntensity},
+		{"one hard session", Signals{IntensityScore: 9.5, Sessions: 1}, ""},
+		{"fatigued muscles", Signals{ConsecutiveDays: 2, MuscleFatigue: fatigued}, models.RestDayReasonMuscleFatigue},
+	}
+	for _, tt := range tests {
+		if got, _ := RestDay(tt.signals); got != tt.want {
+			t.Errorf("%s: reason = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFatiguedMuscles(t *testing.T) {
+	got := FatiguedMuscles(map[string]float64{"Quads": 3, "Triceps": 12, "Chest": 14, "Front Delts": 12})
+	want := []string{"Chest", "Front Delts", "Triceps"}
+	if len(got) != len(want) {
+		t.Fatalf("fatigued muscles = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("fatigued muscles = %v, want %v", got, want)
+			break
+		}
+	}
+	if got := FatiguedMuscles(nil); got == nil || len(got) != 0 {
+		t.Errorf("no fatigue = %#v, want an empty list", got)
+	}
+}
+
+func TestDeload(t *testing.T) {
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	rpe := func(v float64) *float64 { return &v }
+
+	// session logs a lift a number of days before the day of the check
+	session := func(exercise string, daysAgo int, weight float64, reps int, effort *float64) LiftSet {
+		return LiftSet{Exercise: exercise, Date: day.AddDate(0, 0, -daysAgo), Weight: weight, Reps: reps, RPE: effort}
+	}
+	progressing := []LiftSet{
+		session("Squat", 35, 140, 5, nil), session("Squat", 7, 150, 5, nil),
+		session("Bench Press", 35, 100, 5, nil), session("Bench Press", 7, 105, 5, nil),
+	}
+	stalled := []LiftSet{
+		session("Squat", 35, 140, 5, nil), session("Squat", 28, 140, 5, nil), session("Squat", 7, 140, 5, nil),
+		session("Bench Press", 35, 100, 5, nil), session("Bench Press", 7, 100, 5, nil),
+		session("Deadlift", 30, 180, 5, nil), session("Deadlift", 5, 190, 5, nil),
+	}
+	harder := []LiftSet{
+		session("Squat", 35, 140, 5, rpe(7)), session("Squat", 7, 140, 5, rpe(8.5)), session("Squat", 3, 150, 5, rpe(9.5)),
+		session("Bench Press", 35, 100, 5, rpe(7)), session("Bench Press", 7, 105, 5, rpe(8)),
+	}
+	regressing := []LiftSet{
+		session("Squat", 35, 140, 5, rpe(7)), session("Squat", 7, 140, 5, rpe(9)),
+		session("Bench Press", 35, 100, 5, rpe(8)), session("Bench Press", 7, 95, 5, rpe(8)),
+	}
+
+	tests := []struct {
+		name  string
+		sets  []LiftSet
+		weeks int
+		want  string
+	}{
+		{"progressing", progressing, 4, ""},
+		{"many weeks without a deload", progressing, DeloadEveryWeeks, models.DeloadReasonScheduled},
+		{"stalled lifts", stalled, 4, models.DeloadReasonPlateau},
+		{"the same loads feel harder", harder, 4, models.DeloadReasonFatigueAccumulation},
+		{"regressing while loads feel harder", regressing, 4, models.DeloadReasonOverreaching},
+		{"sets outside the periods", []LiftSet{session("Squat", 60, 200, 5, nil), session("Squat", -1, 100, 5, nil)}, 4, ""},
+	}
+	for _, tt := range tests {
+		if got, _ := Deload(tt.sets, e1rm.Epley, day, tt.weeks); got != tt.want {
+			t.Errorf("%s: reason = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	_, metrics := Deload(stalled, e1rm.Epley, day, 4)
+	if len(metrics.Lifts) != 3 || metrics.Lifts[0].Exercise != "Squat" || metrics.StalledLifts != 2 || metrics.RegressedLifts != 0 {
+		t.Fatalf("stalled metrics = %+v, want squat first and two of three lifts stalled", metrics)
+	}
+	if deadlift := metrics.Lifts[2]; deadlift.Exercise != "Deadlift" || deadlift.Stalled || deadlift.RPEChange != nil {
+		t.Errorf("deadlift = %+v, want progressing and unrated", deadlift)
+	}
+
+	// Reps in reserve count towards the estimates, so the harder squat regressed too
+	_, metrics = Deload(regressing, e1rm.Epley, day, 4)
+	if len(metrics.Lifts) != 2 || metrics.RegressedLifts != 2 || metrics.RisingRPELifts != 1 {
+		t.Fatalf("regressing metrics = %+v, want both lifts regressed and one rising", metrics)
+	}
+	if squat := metrics.Lifts[1]; squat.Exercise != "Squat" || squat.RPEChange == nil || *squat.RPEChange != 2 || !squat.RisingRPE {
+		t.Errorf("squat = %+v, want two RPE harder", squat)
+	}
+	if bench := metrics.Lifts[0]; bench.RPEChange != nil || bench.RisingRPE {
+		t.Errorf("bench press = %+v, want no RPE change at different weights", bench)
+	}
+}
+
+func TestDeloadTargets(t *testing.T) {
+	tests := []struct {
+		sets              int
+		weight            float64
+		volume, intensity int
+		wantSets          int
+		wantWeight        float64
+	}{
+		{5, 100, 40, 20, 3, 80},
+		{1, 62.5, 40, 20, 1, 50},
+		{3, 0, 40, 20, 2, 0},
+		{0, 101, 0, 10, 0, 91},
+	}
+	for _, tt := range tests {
+		sets, weight := DeloadTargets(tt.sets, tt.weight, tt.volume, tt.intensity)
+		if sets != tt.wantSets || weight != tt.wantWeight {
+			t.Errorf("DeloadTargets(%d, %v, %d, %d) = %d, %v; want %d, %v", tt.sets, tt.weight, tt.volume, tt.intensity,
+				sets, weight, tt.wantSets, tt.wantWeight)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+// Package recurrence interprets the subset of RFC 5545 recurrence rules calendar events
+// repeat by: daily, weekly and monthly frequencies with INTERVAL, BYDAY, COUNT and UNTIL.
+//
+// A rule repeats a series from its first occurrence, the start. Weeks begin on Monday.
+// Monthly rules without BYDAY repeat on the start's day of the month and skip months too
+// short to have it; with BYDAY they repeat on the listed weekdays, which a monthly rule may
+// prefix with an ordinal such as 1MO for the first Monday or -1FR for the last Friday.
+// COUNT counts occurrences from the start before any are excepted.
+//
+// Occurrences are worked out in the zone of the start: they keep its clock time across
+// daylight saving changes, and weekdays and excepted days are those of that zone.
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is how often a rule repeats
+type Frequency string
+
+// Supported frequencies
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// untilLayout is the UTC date-time form of UNTIL; dateLayout the date form, which
+// includes the whole day
+const (
+	untilLayout = "20060102T150405Z"
+	dateLayout  = "20060102"
+)
+
+// weekdays maps the BYDAY weekday codes to weekdays
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Weekday is a BYDAY entry: a weekday and, in monthly rules, which one of the month it is,
+// counting from the end when negative and every one when zero
+type Weekday struct {
+	Ordinal int
+	Day     time.Weekday
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []Weekday
+	// Count limits the series to that many occurrences and Until to those starting no
+	// later than it; at most one of them is set
+	Count int
+	Until time.Time
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10". An RRULE:
+// prefix is allowed.
+func Parse(s string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return rule, fmt.Errorf("recurrence rule is empty")
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return rule, fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+		key, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+		switch key {
+		case "FREQ":
+			switch f := Frequency(value); f {
+			case Daily, Weekly, Monthly:
+				rule.Freq = f
+			default:
+				return rule, fmt.Errorf("unsupported frequency %q: must be one of DAILY, WEEKLY, MONTHLY", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("INTERVAL must be a positive number")
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("COUNT must be a positive number")
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return rule, err
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, err := parseWeekday(code)
+				if err != nil {
+					return rule, err
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "WKST":
+			if value != "MO" {
+				return rule, fmt.Errorf("unsupported WKST %q: weeks start on MO", value)
+			}
+		default:
+			return rule, fmt.Errorf("unsupported recurrence rule part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, fmt.Errorf("recurrence rule needs a FREQ")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return rule, fmt.Errorf("recurrence rule may not have both COUNT and UNTIL")
+	}
+	for _, day := range rule.ByDay {
+		if day.Ordinal != 0 && rule.Freq != Monthly {
+			return rule, fmt.Errorf("BYDAY ordinals are only supported in MONTHLY rules")
+		}
+	}
+	return rule, nil
+}
+
+// parseUntil reads UNTIL in UTC date-time or date form
+func parseUntil(value string) (time.Time, error) {
+	if until, err := time.Parse(untilLayout, value); err == nil {
+		return until, nil
+	}
+	if day, err := time.Parse(dateLayout, value); err == nil {
+		return day.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL must be a date as YYYYMMDD or a UTC time as YYYYMMDDTHHMMSSZ")
+}
+
+// parseWeekday reads a BYDAY entry such as MO, 2TU or -1FR
+func parseWeekday(code string) (Weekday, error) {
+	code = strings.TrimSpace(code)
+	if len(code) < 2 {
+		return Weekday{}, fmt.Errorf("invalid BYDAY entry %q", code)
+	}
+	day, ok := weekdays[code[len(code)-2:]]
+	if !ok {
+		return Weekday{}, fmt.Errorf("invalid BYDAY entry %q", code)
+	}
+	ordinal := 0
+	if prefix := code[:len(code)-2]; prefix != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(prefix, "+"))
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return Weekday{}, fmt.Errorf("invalid BYDAY entry %q", code)
+		}
+		ordinal = n
+	}
+	return Weekday{Ordinal: ordinal, Day: day}, nil
+}
+
+// String formats the rule the way Parse reads it, without the RRULE: prefix
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			codes[i] = strings.ToUpper(day.Day.String()[:2])
+			if day.Ordinal != 0 {
+				codes[i] = strconv.Itoa(day.Ordinal) + codes[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between returns the start times of the occurrences of a series starting at start that
+// fall from one time to another, both included, leaving out those on the excepted days
+func (r Rule) Between(start, from, to time.Time, except []time.Time) []time.Time {
+	excepted := make(map[string]bool, len(except))
+	for _, day := range except {
+		excepted[day.Format("2006-01-02")] = true
+	}
+
+	var occurrences []time.Time
+	r.each(start, to, func(t time.Time) {
+		if !t.Before(from) && !excepted[t.Format("2006-01-02")] {
+			occurrences = append(occurrences, t)
+		}
+	})
+	return occurrences
+}
+
+// Split divides a series starting at start into the occurrences before the one at at and
+// those from it on, returning the rule of each part and how many occurrences come before.
+// The rule from at on is meant for a series starting there.
+func (r Rule) Split(start, at time.Time) (before, after Rule, count int) {
+	r.each(start, at.Add(-time.Nanosecond), func(time.Time) { count++ })
+
+	before, after = r, r
+	if r.Count > 0 {
+		before.Count, after.Count = count, r.Count-count
+	} else {
+		before.Until = at.Add(-time.Second)
+	}
+	return before, after, count
+}
+
+// each calls fn with the start time of every occurrence, in order, up to the given time
+func (r Rule) each(start, to time.Time, fn func(time.Time)) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	hour, minute, second := start.Clock()
+	emitted := 0
+
+	// emit passes on the occurrence on a day and reports whether the series goes on. It
+	// keeps the start's clock time in its zone, across daylight saving changes.
+	emit := func(day time.Time) bool {
+		t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, start.Nanosecond(), start.Location())
+		if t.Before(start) {
+			return true
+		}
+		if t.After(to) || (!r.Until.IsZero() && t.After(r.Until)) {
+			return false
+		}
+		fn(t)
+		emitted++
+		return r.Count == 0 || emitted < r.Count
+	}
+
+	switch r.Freq {
+	case Daily:
+		for day := dateOf(start); ; day = day.AddDate(0, 0, interval) {
+			if len(r.ByDay) > 0 && !r.onWeekday(day) {
+				if day.After(to) {
+					return
+				}
+				continue
+			}
+			if !emit(day) {
+				return
+			}
+		}
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []Weekday{{Day: start.Weekday()}}
+		}
+		offsets := make([]int, len(days))
+		for i, day := range days {
+			offsets[i] = (int(day.Day) + 6) % 7
+		}
+		sort.Ints(offsets)
+		for week := mondayOf(start); ; week = week.AddDate(0, 0, 7*interval) {
+			if week.After(to) {
+				return
+			}
+			for i, offset := range offsets {
+				if i > 0 && offset == offsets[i-1] {
+					continue
+				}
+				if !emit(week.AddDate(0, 0, offset)) {
+					return
+				}
+			}
+		}
+	case Monthly:
+		for month := firstOfMonth(start); ; month = month.AddDate(0, interval, 0) {
+			if month.After(to) {
+				return
+			}
+			for _, day := range r.monthDays(month, start) {
+				if !emit(day) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// onWeekday reports whether a day is one of the rule's BYDAY weekdays
+func (r Rule) onWeekday(day time.Time) bool {
+	for _, d := range r.ByDay {
+		if d.Day == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDays returns the days of a month a monthly rule occurs on, in order
+func (r Rule) monthDays(month, start time.Time) []time.Time {
+	length := month.AddDate(0, 1, -1).Day()
+	if len(r.ByDay) == 0 {
+		if start.Day() > length {
+			return nil
+		}
+		return []time.Time{month.AddDate(0, 0, start.Day()-1)}
+	}
+
+	seen := make(map[int]bool)
+	for _, d := range r.ByDay {
+		first := 1 + (int(d.Day)-int(month.Weekday())+7)%7
+		var matches []int
+		for day := first; day <= length; day += 7 {
+			matches = append(matches, day)
+		}
+		switch {
+		case d.Ordinal == 0:
+			for _, day := range matches {
+				seen[day] = true
+			}
+		case d.Ordinal > 0 && d.Ordinal <= len(matches):
+			seen[matches[d.Ordinal-1]] = true
+		case d.Ordinal < 0 && -d.Ordinal <= len(matches):
+			seen[matches[len(matches)+d.Ordinal]] = true
+		}
+	}
+	var days []int
+	for day := range seen {
+		days = append(days, day)
+	}
+	sort.Ints(days)
+
+	result := make([]time.Time, len(days))
+	for i, day := range days {
+		result[i] = month.AddDate(0, 0, day-1)
+	}
+	return result
+}
+
+// dateOf returns midnight of t's day in its location
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// mondayOf returns midnight of the Monday of t's week
+func mondayOf(t time.Time) time.Time {
+	day := dateOf(t)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// firstOfMonth returns midnight of the first day of t's month
+func firstOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,148 @@
+package recurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+		err  string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY", ""},
+		{"RRULE:freq=weekly;interval=2;byday=MO,TH;count=10", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10", ""},
+		{"FREQ=MONTHLY;BYDAY=1MO,-1FR;UNTIL=20261231", "FREQ=MONTHLY;BYDAY=1MO,-1FR;UNTIL=20261231T235959Z", ""},
+		{"FREQ=WEEKLY;UNTIL=20261101T090000Z;WKST=MO", "FREQ=WEEKLY;UNTIL=20261101T090000Z", ""},
+		{"", "", "empty"},
+		{"INTERVAL=2", "", "needs a FREQ"},
+		{"FREQ=YEARLY", "", "unsupported frequency"},
+		{"FREQ=DAILY;INTERVAL=0", "", "INTERVAL"},
+		{"FREQ=DAILY;COUNT=3;UNTIL=20261231", "", "both COUNT and UNTIL"},
+		{"FREQ=WEEKLY;BYDAY=1MO", "", "ordinals"},
+		{"FREQ=MONTHLY;BYDAY=XX", "", "BYDAY"},
+		{"FREQ=DAILY;BYMONTH=1", "", "unsupported recurrence rule part"},
+		{"FREQ=DAILY;UNTIL=tomorrow", "", "UNTIL"},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Parse(%q) error = %v, want one about %q", tt.rule, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.rule, err)
+			continue
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	// Monday 5 October 2026, 18:00
+	start := time.Date(2026, 10, 5, 18, 0, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time { return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name     string
+		rule     string
+		from, to time.Time
+		except   []time.Time
+		want     []string
+	}{
+		{"daily", "FREQ=DAILY", day(10, 1), day(10, 8), nil, []string{"10-05", "10-06", "10-07"}},
+		{"every other day on weekdays", "FREQ=DAILY;INTERVAL=2;BYDAY=MO,TU,WE,TH,FR", day(10, 1), day(10, 15), nil, []string{"10-05", "10-07", "10-09", "10-13"}},
+		{"weekly on the start's weekday", "FREQ=WEEKLY;COUNT=3", day(9, 1), day(12, 31), nil, []string{"10-05", "10-12", "10-19"}},
+		{"every other week on two days", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TH,MO", day(10, 1), day(10, 31), nil, []string{"10-05", "10-08", "10-19", "10-22"}},
+		{"days of the first week before the start are left out", "FREQ=WEEKLY;BYDAY=SU,SA,MO;COUNT=4", day(10, 1), day(10, 31), nil, []string{"10-05", "10-10", "10-11", "10-12"}},
+		{"until includes its day", "FREQ=WEEKLY;UNTIL=20261019", day(10, 1), day(12, 31), nil, []string{"10-05", "10-12", "10-19"}},
+		{"window in the middle of the series", "FREQ=WEEKLY", day(10, 12), day(10, 26), nil, []string{"10-12", "10-19"}},
+		{"excepted days count towards COUNT", "FREQ=DAILY;COUNT=4", day(10, 1), day(10, 31), []time.Time{day(10, 6)}, []string{"10-05", "10-07", "10-08"}},
+		{"monthly on the start's day", "FREQ=MONTHLY;COUNT=3", day(10, 1), day(12, 31), nil, []string{"10-05", "11-05", "12-05"}},
+		{"first Monday and last Friday", "FREQ=MONTHLY;BYDAY=1MO,-1FR", day(10, 1), day(11, 30), nil, []string{"10-05", "10-30", "11-02", "11-27"}},
+		{"every Wednesday of the month", "FREQ=MONTHLY;BYDAY=WE;COUNT=5", day(10, 1), day(12, 31), nil, []string{"10-07", "10-14", "10-21", "10-28", "11-04"}},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tt.name, err)
+		}
+		var got []string
+		for _, occurrence := range rule.Between(start, tt.from, tt.to, tt.except) {
+			if occurrence.Hour() != 18 {
+				t.Errorf("%s: occurrence %v is not at the start's time", tt.name, occurrence)
+			}
+			got = append(got, occurrence.Format("01-02"))
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: Between = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// A month without the start's day is skipped
+	rule, _ := Parse("FREQ=MONTHLY;COUNT=3")
+	jan31 := time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)
+	got := rule.Between(jan31, jan31, jan31.AddDate(1, 0, 0), nil)
+	if len(got) != 3 || got[1].Format("01-02") != "03-31" || got[2].Format("01-02") != "05-31" {
+		t.Errorf("monthly from 31 January = %v, want 31 January, March and May", got)
+	}
+}
+
+func TestBetweenInZone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, berlin) }
+
+	// Summer time ends on Sunday 25 October; the evening stays at 18:00
+	weekly, _ := Parse("FREQ=WEEKLY")
+	var got []string
+	for _, occurrence := range weekly.Between(time.Date(2026, 10, 12, 18, 0, 0, 0, berlin), day(1), day(31), nil) {
+		got = append(got, occurrence.Format("01-02T15:04")+" "+occurrence.UTC().Format("15:04"))
+	}
+	if want := "10-12T18:00 16:00 10-19T18:00 16:00 10-26T18:00 17:00"; strings.Join(got, " ") != want {
+		t.Errorf("weekly across the change = %v, want %s", got, want)
+	}
+
+	// 00:30 on a Monday in Berlin is Sunday in UTC; the weekdays and the excepted day are Berlin's
+	mondays, _ := Parse("FREQ=WEEKLY;BYDAY=MO")
+	got = nil
+	except := []time.Time{time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC)}
+	for _, occurrence := range mondays.Between(time.Date(2026, 10, 19, 0, 30, 0, 0, berlin), day(1), day(31).AddDate(0, 0, 7), except) {
+		got = append(got, occurrence.Format("Mon 01-02T15:04"))
+	}
+	if want := "Mon 10-19T00:30 Mon 11-02T00:30"; strings.Join(got, " ") != want {
+		t.Errorf("Mondays = %v, want %s", got, want)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	start := time.Date(2026, 10, 5, 18, 0, 0, 0, time.UTC)
+	at := time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC)
+	far := start.AddDate(1, 0, 0)
+
+	counted, _ := Parse("FREQ=WEEKLY;COUNT=5")
+	before, after, n := counted.Split(start, at)
+	if n != 2 || before.Count != 2 || after.Count != 3 {
+		t.Errorf("Split of 5 weekly = %v before, %+v and %+v", n, before, after)
+	}
+	if got := after.Between(at, at, far, nil); len(got) != 3 || !got[0].Equal(at) {
+		t.Errorf("series after the split = %v, want three from %v", got, at)
+	}
+
+	open, _ := Parse("FREQ=WEEKLY")
+	before, after, n = open.Split(start, at)
+	if n != 2 || after.Count != 0 || !after.Until.IsZero() {
+		t.Errorf("Split of an open series = %v before, %+v", n, after)
+	}
+	if got := before.Between(start, start, far, nil); len(got) != 2 {
+		t.Errorf("series before the split = %v, want two", got)
+	}
+}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"workout-tracker/internal/database"
+	"workout-tracker/internal/models"
+)
+
+// analyticsRepo is the SQL implementation of AnalyticsRepository
+type analyticsRepo struct {
+	*sqlStore
+}
+
+// between restricts a timestamp column to the calendar days from one date to another,
+// both included. Its two placeholders take the period's first and last day.
+func (r *analyticsRepo) between(column string) string {
+	day := r.dialect.dateOf(column)
+	return day + " >= " + r.dialect.dateOf("?") + " AND " + day + " <= " + r.dialect.dateOf("?")
+}
+
+// WorkoutDays returns each day of the period with a workout logged, with how many were
+// logged and the average duration of those with one
+func (r *analyticsRepo) WorkoutDays(userID int, from, to time.Time) ([]models.WorkoutDay, error) {
+	day := r.dialect.dateOf("date")
+	query := `
+		SELECT ` + day + `, COUNT(*), AVG(CASE WHEN duration > 0 THEN duration END)
+		FROM workouts
+		WHERE user_id = ? AND ` + r.between("date") + `
+		GROUP BY ` + day + `
+		ORDER BY ` + day
+
+	rows, err := r.db.Query(query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count workouts by day: %v", err)
+	}
+	defer rows.Close()
+
+	days := []models.WorkoutDay{}
+	for rows.Next() {
+		var d models.WorkoutDay
+		var date database.Timestamp
+		var avgDuration sql.NullFloat64
+		if err := rows.Scan(&date, &d.Workouts, &avgDuration); err != nil {
+			return nil, fmt.Errorf("failed to scan workout day: %v", err)
+		}
+		d.Date, d.AvgDuration = date.Time, avgDuration.Float64
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// CalorieDays returns each day of the period with a meal logged and the calories eaten
+func (r *analyticsRepo) CalorieDays(userID int, from, to time.Time) ([]models.CalorieDay, error) {
+	day := r.dialect.dateOf("date")
+	query := `
+		SELECT ` + day + `, SUM(calories)
+		FROM meals
+		WHERE user_id = ? AND ` + r.between("date") + `
+		GROUP BY ` + day + `
+		ORDER BY ` + day
+
+	rows, err := r.db.Query(query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum calories by day: %v", err)
+	}
+	defer rows.Close()
+
+	days := []models.CalorieDay{}
+	for rows.Next() {
+		var d models.CalorieDay
+		var date database.Timestamp
+		if err := rows.Scan(&date, &d.Calories); err != nil {
+			return nil, fmt.Errorf("failed to scan calorie day: %v", err)
+		}
+		d.Date = date.Time
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// TopExercises returns up to limit of the exercises logged most often in the period,
+// the most frequent first
+func (r *analyticsRepo) TopExercises(userID int, from, to time.Time, limit int) ([]models.ExerciseFrequency, error) {
+	query := `
+		SELECT e.name, e.category, COUNT(*) AS frequency
+		FROM exercises e
+		JOIN workouts w ON e.workout_id = w.id
+		WHERE w.user_id = ? AND ` + r.between("w.date") + `
+		GROUP BY e.name, e.category
+		ORDER BY frequency DESC, e.name
+		LIMIT ?`
+
+	rows, err := r.db.Query(query, userID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list top exercises: %v", err)
+	}
+	defer rows.Close()
+
+	exercises := []models.ExerciseFrequency{}
+	for rows.Next() {
+		var e models.ExerciseFrequency
+		if err := rows.Scan(&e.ExerciseName, &e.Category, &e.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan exercise frequency: %v", err)
+		}
+		exercises = append(exercises, e)
+	}
+	return exercises, rows.Err()
+}
+
+// StrengthDays returns the heaviest weight, most reps and volume of the exercise's
+// weighted working sets on each day of the period
+func (r *analyticsRepo) StrengthDays(userID int, exercise string, from, to time.Time) ([]models.StrengthDataPoint, error) {
+	day := r.dialect.dateOf("w.date")
+	query := `
+		SELECT ` + day + `, MAX(s.weight), MAX(s.reps), SUM(s.weight * s.reps)
+		FROM sets s
+		JOIN exercises e ON s.exercise_id = e.id
+		JOIN workouts w ON e.workout_id = w.id
+		WHERE w.user_id = ? AND e.name = ? AND ` + r.between("w.date") + ` AND s.weight > 0 AND s.set_type <> ?
+		GROUP BY ` + day + `
+		ORDER BY ` + day
+
+	rows, err := r.db.Query(query, userID, exercise, from, to, models.SetTypeWarmup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load strength progress: %v", err)
+	}
+	defer rows.Close()
+
+	points := []models.StrengthDataPoint{}
+	for rows.Next() {
+		var p models.StrengthDataPoint
+		var date database.Timestamp
+		if err := rows.Scan(&date, &p.MaxWeight, &p.MaxReps, &p.Volume); err != nil {
+			return nil, fmt.Errorf("failed to scan strength progress: %v", err)
+		}
+		p.Date = date.Time
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// ExerciseProgress returns the heaviest weight, most reps, volume and number of the
+// exercise's working sets in each workout of the period, the earliest first
+func (r *analyticsRepo) ExerciseProgress(userID int, exercise string, from, to time.Time) ([]models.ExerciseProgressPoint, error) {
+	query := `
+		SELECT w.id, ` + r.dialect.dateOf("w.date") + `, MAX(s.weight), MAX(s.reps), SUM(s.weight * s.reps), COUNT(s.id)
+		FROM sets s
+		JOIN exercises e ON s.exercise_id = e.id
+		JOIN workouts w ON e.workout_id = w.id
+		WHERE w.user_id = ? AND e.name = ? AND ` + r.between("w.date") + ` AND s.set_type <> ?
+		GROUP BY w.id, w.date
+		ORDER BY w.date, w.id`
+
+	rows, err := r.db.Query(query, userID, exercise, from, to, models.SetTypeWarmup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exercise progress: %v", err)
+	}
+	defer rows.Close()
+
+	points := []models.ExerciseProgressPoint{}
+	for rows.Next() {
+		var p models.ExerciseProgressPoint
+		var date database.Timestamp
+		if err := rows.Scan(&p.WorkoutID, &date, &p.MaxWeight, &p.MaxReps, &p.TotalVolume, &p.SetsCount); err != nil {
+			return nil, fmt.Errorf("failed to scan exercise progress: %v", err)
+		}
+		p.Date = date.Time
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// WorkingSets returns the weighted sets other than warmups of one exercise or, with an
+// empty name, of every exercise logged in the period, in the order they were logged
+func (r *analyticsRepo) WorkingSets(userID int, exercise string, from, to time.Time) ([]models.WorkingSet, error) {
+	query := `
+		SELECT e.name, w.id, w.date, s.weight, s.reps, s.rpe, s.rir
+		FROM sets s
+		JOIN exercises e ON s.exercise_id = e.id
+		JOIN workouts w ON e.workout_id = w.id
+		WHERE w.user_id = ? AND ` + r.between("w.date") + ` AND s.weight > 0 AND s.set_type <> ?
+		  AND (? = '' OR e.name = ?)
+		ORDER BY w.date, s.id`
+
+	rows, err := r.db.Query(query, userID, from, to, models.SetTypeWarmup, exercise, exercise)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load working sets: %v", err)
+	}
+	defer rows.Close()
+
+	sets := []models.WorkingSet{}
+	for rows.Next() {
+		var set models.WorkingSet
+		var date database.Timestamp
+		if err := rows.Scan(&set.ExerciseName, &set.WorkoutID, &date, &set.Weight, &set.Reps, &set.RPE, &set.RIR); err != nil {
+			return nil, fmt.Errorf("failed to scan working set: %v", err)
+		}
+		set.Date = date.Time
+		sets = append(sets, set)
+	}
+	return sets, rows.Err()
+}
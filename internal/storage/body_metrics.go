@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// bodyMetricsRepo is the SQL implementation of BodyMetricsRepository
+type bodyMetricsRepo struct {
+	*sqlStore
+}
+
+// CreateWeight creates a new body weight entry and returns its ID
+func (r *bodyMetricsRepo) CreateWeight(bodyWeight models.BodyWeight) (int, error) {
+	query := `
+		INSERT INTO body_weights (user_id, weight, unit, date, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, bodyWeight.UserID, bodyWeight.Weight, bodyWeight.Unit, bodyWeight.Date, bodyWeight.Notes, time.Now(), time.Now())
+}
+
+// WeightsByUser returns body weight entries for a user, newest first
+func (r *bodyMetricsRepo) WeightsByUser(userID int) ([]models.BodyWeight, error) {
+	query := `
+		SELECT id, user_id, weight, unit, date, notes, created_at, updated_at
+		FROM body_weights
+		WHERE user_id = ?
+		ORDER BY date DESC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var weights []models.BodyWeight
+	for rows.Next() {
+		var w models.BodyWeight
+		err := rows.Scan(&w.ID, &w.UserID, &w.Weight, &w.Unit, &w.Date, &w.Notes, &w.CreatedAt, &w.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		weights = append(weights, w)
+	}
+
+	return weights, rows.Err()
+}
+
+// CreateBodyFat creates a new body fat entry and returns its ID
+func (r *bodyMetricsRepo) CreateBodyFat(bodyFat models.BodyFat) (int, error) {
+	query := `
+		INSERT INTO body_fats (user_id, body_fat_pct, date, measurement, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, bodyFat.UserID, bodyFat.BodyFatPct, bodyFat.Date, bodyFat.Measurement, bodyFat.Notes, time.Now(), time.Now())
+}
+
+// BodyFatsByUser returns body fat entries for a user, newest first
+func (r *bodyMetricsRepo) BodyFatsByUser(userID int) ([]models.BodyFat, error) {
+	query := `
+		SELECT id, user_id, body_fat_pct, date, measurement, notes, created_at, updated_at
+		FROM body_fats
+		WHERE user_id = ?
+		ORDER BY date DESC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bodyFats []models.BodyFat
+	for rows.Next() {
+		var bf models.BodyFat
+		err := rows.Scan(&bf.ID, &bf.UserID, &bf.BodyFatPct, &bf.Date, &bf.Measurement, &bf.Notes, &bf.CreatedAt, &bf.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		bodyFats = append(bodyFats, bf)
+	}
+
+	return bodyFats, rows.Err()
+}
+
+// CreateMeasurement creates a new body measurement entry and returns its ID
+func (r *bodyMetricsRepo) CreateMeasurement(measurement models.BodyMeasurement) (int, error) {
+	query := `
+		INSERT INTO body_measurements (user_id, measurement, value, unit, date, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, measurement.UserID, measurement.Measurement, measurement.Value, measurement.Unit, measurement.Date, measurement.Notes, time.Now(), time.Now())
+}
+
+// MeasurementsByUser returns body measurements for a user grouped by measurement, newest first
+func (r *bodyMetricsRepo) MeasurementsByUser(userID int) ([]models.BodyMeasurement, error) {
+	query := `
+		SELECT id, user_id, measurement, value, unit, date, notes, created_at, updated_at
+		FROM body_measurements
+		WHERE user_id = ?
+		ORDER BY measurement, date DESC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var measurements []models.BodyMeasurement
+	for rows.Next() {
+		var m models.BodyMeasurement
+		err := rows.Scan(&m.ID, &m.UserID, &m.Measurement, &m.Value, &m.Unit, &m.Date, &m.Notes, &m.CreatedAt, &m.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, m)
+	}
+
+	return measurements, rows.Err()
+}
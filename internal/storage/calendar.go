@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"workout-tracker/internal/database"
+	"workout-tracker/internal/models"
+)
+
+// calendarEventRepo is the SQL implementation of CalendarEventRepository
+type calendarEventRepo struct {
+	*sqlStore
+}
+
+// calendarEventColumns are the columns of workout_calendar_events in the order scanCalendarEvent reads them
+const calendarEventColumns = `id, user_id, scheduled_workout_id, rest_day_id, deload_id, event_type, title,
+	COALESCE(description, ''), start_date, end_date, COALESCE(all_day, FALSE), COALESCE(color, ''),
+	COALESCE(is_recurring, FALSE), recurrence_pattern, created_at, updated_at`
+
+// Create stores a calendar event and returns its ID
+func (r *calendarEventRepo) Create(event models.WorkoutCalendarEvent) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	id, err := insertCalendarEvent(tx, event)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit calendar event: %v", err)
+	}
+	return id, nil
+}
+
+// Get returns a calendar event owned by the given user
+func (r *calendarEventRepo) Get(id, userID int) (models.WorkoutCalendarEvent, error) {
+	query := `SELECT ` + calendarEventColumns + ` FROM workout_calendar_events WHERE id = ? AND user_id = ?`
+	return scanCalendarEvent(r.db.QueryRow(query, id, userID))
+}
+
+// List returns the user's events that may occur from one date to another, earliest first:
+// single events overlapping the period and every series starting before its end
+func (r *calendarEventRepo) List(userID int, from, to time.Time) ([]models.WorkoutCalendarEvent, error) {
+	query := `SELECT ` + calendarEventColumns + ` FROM workout_calendar_events
+		WHERE user_id = ? AND start_date < ? AND (is_recurring OR COALESCE(end_date, start_date) >= ?)
+		ORDER BY start_date, id`
+	rows, err := r.db.Query(query, userID, calendarDate(to).AddDate(0, 0, 1), calendarDate(from))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendar events: %v", err)
+	}
+	defer rows.Close()
+
+	events := []models.WorkoutCalendarEvent{}
+	for rows.Next() {
+		event, err := scanCalendarEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// Update replaces a calendar event's fields
+func (r *calendarEventRepo) Update(event models.WorkoutCalendarEvent) error {
+	return updateCalendarEvent(r.db, event)
+}
+
+func (r *calendarEventRepo) Delete(id, userID int) error {
+	result, err := r.db.Exec(`DELETE FROM workout_calendar_events WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete calendar event: %v", err)
+	}
+	return requireRows(result)
+}
+
+// Split saves a series changed to leave out some of its occurrences together with the
+// event or series replacing them, in one transaction, and returns the replacement's ID
+func (r *calendarEventRepo) Split(series, replacement models.WorkoutCalendarEvent) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := updateCalendarEvent(tx, series); err != nil {
+		return 0, err
+	}
+	id, err := insertCalendarEvent(tx, replacement)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit calendar event: %v", err)
+	}
+	return id, nil
+}
+
+// insertCalendarEvent inserts a calendar event
+func insertCalendarEvent(tx *database.Tx, event models.WorkoutCalendarEvent) (int, error) {
+	query := `
+		INSERT INTO workout_calendar_events (user_id, scheduled_workout_id, rest_day_id, deload_id, event_type, title,
+			description, start_date, end_date, all_day, color, is_recurring, recurrence_pattern, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	id, err := tx.Insert(query, event.UserID, event.ScheduledWorkoutID, event.RestDayID, event.DeloadID, event.EventType, event.Title,
+		event.Description, event.StartDate, event.EndDate, event.AllDay, event.Color, event.IsRecurring, event.RecurrencePattern,
+		time.Now(), time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create calendar event: %v", err)
+	}
+	return id, nil
+}
+
+// updateCalendarEvent replaces a calendar event's fields, returning ErrNotFound if the
+// event does not belong to its user
+func updateCalendarEvent(q querier, event models.WorkoutCalendarEvent) error {
+	query := `
+		UPDATE workout_calendar_events
+		SET scheduled_workout_id = ?, event_type = ?, title = ?, description = ?, start_date = ?, end_date = ?, all_day = ?,
+			color = ?, is_recurring = ?, recurrence_pattern = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`
+	result, err := q.Exec(query, event.ScheduledWorkoutID, event.EventType, event.Title, event.Description, event.StartDate,
+		event.EndDate, event.AllDay, event.Color, event.IsRecurring, event.RecurrencePattern, time.Now(), event.ID, event.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to update calendar event: %v", err)
+	}
+	return requireRows(result)
+}
+
+// scanCalendarEvent reads a row selecting calendarEventColumns
+func scanCalendarEvent(row rowScanner) (models.WorkoutCalendarEvent, error) {
+	var event models.WorkoutCalendarEvent
+	err := row.Scan(&event.ID, &event.UserID, &event.ScheduledWorkoutID, &event.RestDayID, &event.DeloadID, &event.EventType,
+		&event.Title, &event.Description, &event.StartDate, &event.EndDate, &event.AllDay, &event.Color, &event.IsRecurring,
+		&event.RecurrencePattern, &event.CreatedAt, &event.UpdatedAt)
+	if err != nil {
+		return event, notFound(err)
+	}
+	event.StartDate = event.StartDate.UTC()
+	if event.EndDate != nil {
+		end := event.EndDate.UTC()
+		event.EndDate = &end
+	}
+	return event, nil
+}
@@ -0,0 +1,2146 @@
+package storage
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// runConformance runs the shared behaviour checks against a store implementation.
+// newStore must return a store over an empty, fully migrated database.
+func runConformance(t *testing.T, newStore func(t *testing.T) Store) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s Store)
+	}{
+		{"Users", testUsers},
+		{"WorkoutTree", testWorkoutTree},
+		{"WorkoutOwnership", testWorkoutOwnership},
+		{"WorkoutListing", testWorkoutListing},
+		{"Exercises", testExercises},
+		{"ExerciseOrder", testExerciseOrder},
+		{"Templates", testTemplates},
+		{"Programs", testPrograms},
+		{"BodyMetrics", testBodyMetrics},
+		{"Meals", testMeals},
+		{"Analytics", testAnalytics},
+		{"Sessions", testSessions},
+		{"PersonalRecords", testPersonalRecords},
+		{"Muscles", testMuscles},
+		{"TrainingLoad", testTrainingLoad},
+		{"RestDays", testRestDays},
+		{"Deloads", testDeloads},
+		{"ScheduledWorkouts", testScheduledWorkouts},
+		{"CalendarEvents", testCalendarEvents},
+		{"CalendarFeeds", testCalendarFeeds},
+		{"ScheduledWorkoutImport", testScheduledWorkoutImport},
+		{"Reminders", testReminders},
+		{"UserDeleteCascades", testUserDeleteCascades},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+// createUser creates a user with the given username and returns its ID
+func createUser(t *testing.T, s Store, username string) int {
+	t.Helper()
+
+	id, err := s.Users().Create(models.User{Username: username, Email: username + "@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("failed to create user %s: %v", username, err)
+	}
+	return id
+}
+
+// createWorkout creates a workout with one exercise and two sets and returns its ID
+func createWorkout(t *testing.T, s Store, userID int, name string, date time.Time, duration int) int {
+	t.Helper()
+
+	id, err := s.Workouts().Create(models.Workout{
+		Name:     name,
+		Date:     date,
+		Duration: duration,
+		Notes:    name + " notes",
+		Exercises: []models.Exercise{{
+			Name:     "Bench Press",
+			Category: "Strength",
+			Sets: []models.Set{
+				{SetNumber: 1, Reps: 5, Weight: 100},
+				{SetNumber: 2, Reps: 5, Weight: 105},
+			},
+		}},
+	}, userID)
+	if err != nil {
+		t.Fatalf("failed to create workout %s: %v", name, err)
+	}
+	return id
+}
+
+func testUsers(t *testing.T, s Store) {
+	id := createUser(t, s, "alice")
+
+	user, err := s.Users().GetByUsername("alice")
+	if err != nil {
+		t.Fatalf("GetByUsername: %v", err)
+	}
+	if user.ID != id || user.Email != "alice@example.com" || user.PasswordHash != "hash" {
+		t.Errorf("GetByUsername = %+v", user)
+	}
+
+	if err := s.Users().UpdateProfile(id, models.UpdateProfileRequest{Username: "alice", Email: "a@example.com", FullName: "Alice", Bio: "Lifter"}); err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	if err := s.Users().UpdatePassword(id, "new-hash"); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+
+	user, err = s.Users().GetByID(id)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if user.Email != "a@example.com" || user.FullName != "Alice" || user.Bio != "Lifter" || user.PasswordHash != "new-hash" || !user.IsActive {
+		t.Errorf("GetByID after update = %+v", user)
+	}
+
+	if _, err := s.Users().GetByID(id + 100); err != ErrNotFound {
+		t.Errorf("GetByID of missing user: err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Users().GetByUsername("nobody"); err != ErrNotFound {
+		t.Errorf("GetByUsername of missing user: err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Users().Create(models.User{Username: "alice", Email: "other@example.com", PasswordHash: "x"}); err == nil {
+		t.Error("creating a duplicate username succeeded")
+	}
+}
+
+func testWorkoutTree(t *testing.T, s Store) {
+	userID := createUser(t, s, "alice")
+	date := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	id := createWorkout(t, s, userID, "Push", date, 60)
+
+	workout, err := s.Workouts().Get(id, userID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if workout.Name != "Push" || workout.UserID != userID || workout.Duration != 60 || !workout.Date.Equal(date) {
+		t.Errorf("Get = %+v", workout)
+	}
+	if len(workout.Exercises) != 1 || len(workout.Exercises[0].Sets) != 2 {
+		t.Fatalf("Get returned exercises %+v, want 1 exercise with 2 sets", workout.Exercises)
+	}
+	if set := workout.Exercises[0].Sets[1]; set.SetNumber != 2 || set.Weight != 105 {
+		t.Errorf("second set = %+v", set)
+	}
+
+	workout.Name = "Push Day"
+	workout.Exercises = []models.Exercise{
+		{Name: "Squat", Category: "Strength", Sets: []models.Set{{SetNumber: 1, Reps: 3, Weight: 140, SetType: models.SetTypeWarmup, Notes: "opener"}}},
+		{Name: "Plank", Category: "Core"},
+	}
+	if err := s.Workouts().Replace(workout, userID); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+
+	workout, err = s.Workouts().Get(id, userID)
+	if err != nil {
+		t.Fatalf("Get after Replace: %v", err)
+	}
+	if workout.Name != "Push Day" || len(workout.Exercises) != 2 || workout.Exercises[0].Name != "Squat" || len(workout.Exercises[0].Sets) != 1 || len(workout.Exercises[1].Sets) != 0 {
+		t.Fatalf("Get after Replace = %+v", workout)
+	}
+	if set := workout.Exercises[0].Sets[0]; set.SetType != models.SetTypeWarmup || set.Notes != "opener" {
+		t.Errorf("replaced set = %+v, want a warmup with notes", set)
+	}
+
+	workout.Notes = "only the notes"
+	if err := s.Workouts().Update(workout, userID); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	workout, _ = s.Workouts().Get(id, userID)
+	if workout.Notes != "only the notes" || len(workout.Exercises) != 2 {
+		t.Errorf("Update changed exercises or lost notes: %+v", workout)
+	}
+
+	if err := s.Workouts().Delete(id, userID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Workouts().Get(id, userID); err != ErrNotFound {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Workouts().Delete(id, userID); err != ErrNotFound {
+		t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testWorkoutOwnership(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	id := createWorkout(t, s, alice, "Push", time.Now(), 60)
+
+	owner, err := s.Workouts().OwnerID(id)
+	if err != nil || owner != alice {
+		t.Errorf("OwnerID = %d, %v; want %d", owner, err, alice)
+	}
+	if _, err := s.Workouts().OwnerID(id + 100); err != ErrNotFound {
+		t.Errorf("OwnerID of missing workout: err = %v, want ErrNotFound", err)
+	}
+
+	if _, err := s.Workouts().Get(id, bob); err != ErrNotFound {
+		t.Errorf("Get by another user: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Workouts().Update(models.Workout{ID: id, Name: "Stolen", Date: time.Now()}, bob); err != ErrNotFound {
+		t.Errorf("Update by another user: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Workouts().Replace(models.Workout{ID: id, Name: "Stolen", Date: time.Now()}, bob); err != ErrNotFound {
+		t.Errorf("Replace by another user: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Workouts().Delete(id, bob); err != ErrNotFound {
+		t.Errorf("Delete by another user: err = %v, want ErrNotFound", err)
+	}
+
+	workout, err := s.Workouts().Get(id, alice)
+	if err != nil || workout.Name != "Push" || len(workout.Exercises) != 1 {
+		t.Errorf("workout changed by another user: %+v, %v", workout, err)
+	}
+}
+
+func testWorkoutListing(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+
+	base := time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC)
+	for i, name := range []string{"Legs A", "Push A", "Pull A", "Legs B", "Push B"} {
+		createWorkout(t, s, alice, name, base.AddDate(0, 0, i), 30+i*10)
+	}
+	createWorkout(t, s, bob, "Legs Bob", base, 45)
+
+	recent, err := s.Workouts().Recent(alice, 2)
+	if err != nil {
+		t.Fatalf("Recent: %v", err)
+	}
+	if len(recent) != 2 || recent[0].Name != "Push B" || recent[1].Name != "Legs B" {
+		t.Errorf("Recent = %v", workoutNames(recent))
+	}
+
+	// Page through every workout newest first
+	var names []string
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		workouts, next, err := s.Workouts().List(alice, models.WorkoutListParams{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("List page %d: %v", page, err)
+		}
+		names = append(names, workoutNames(workouts)...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	want := []string{"Push B", "Legs B", "Pull A", "Push A", "Legs A"}
+	if !equalStrings(names, want) {
+		t.Errorf("paged List = %v, want %v", names, want)
+	}
+
+	cases := []struct {
+		name   string
+		params models.WorkoutListParams
+		want   []string
+	}{
+		{"date range", models.WorkoutListParams{From: "2024-01-02", To: "2024-01-03", Sort: "date"}, []string{"Push A", "Pull A"}},
+		{"search ignores case", models.WorkoutListParams{Query: "legs", Sort: "name"}, []string{"Legs A", "Legs B"}},
+		{"search needs every term", models.WorkoutListParams{Query: "push b"}, []string{"Push B"}},
+		{"search is literal", models.WorkoutListParams{Query: "%"}, nil},
+		{"exercise ignores case", models.WorkoutListParams{Exercise: "bench press", Sort: "-duration"}, []string{"Push B", "Legs B", "Pull A", "Push A", "Legs A"}},
+		{"category", models.WorkoutListParams{Category: "Cardio"}, nil},
+		{"duration ascending", models.WorkoutListParams{Sort: "duration"}, []string{"Legs A", "Push A", "Pull A", "Legs B", "Push B"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.params.Limit = 10
+			workouts, next, err := s.Workouts().List(alice, tc.params)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if got := workoutNames(workouts); !equalStrings(got, tc.want) || next != "" {
+				t.Errorf("List = %v (next %q), want %v", got, next, tc.want)
+			}
+		})
+	}
+
+	// A cursor only continues the sort order it was created for
+	_, next, err := s.Workouts().List(alice, models.WorkoutListParams{Limit: 1, Sort: "name"})
+	if err != nil {
+		t.Fatalf("List by name: %v", err)
+	}
+	if _, _, err := s.Workouts().List(alice, models.WorkoutListParams{Limit: 1, Sort: "-date", Cursor: next}); err == nil {
+		t.Error("List accepted a cursor from another sort order")
+	}
+
+	workouts, _, err := s.Workouts().List(alice, models.WorkoutListParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if err := s.Workouts().AttachExercises(workouts); err != nil {
+		t.Fatalf("AttachExercises: %v", err)
+	}
+	for _, w := range workouts {
+		if len(w.Exercises) != 1 || len(w.Exercises[0].Sets) != 2 {
+			t.Errorf("workout %s has exercises %+v after AttachExercises", w.Name, w.Exercises)
+		}
+	}
+}
+
+func testExercises(t *testing.T, s Store) {
+	userID := createUser(t, s, "alice")
+	workoutID := createWorkout(t, s, userID, "Push", time.Now(), 60)
+
+	exerciseID, err := s.Exercises().Create(models.Exercise{WorkoutID: workoutID, Name: "Dips", Category: "Strength"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	setID, err := s.Exercises().CreateSet(models.Set{ExerciseID: exerciseID, SetNumber: 1, Reps: 12})
+	if err != nil {
+		t.Fatalf("CreateSet: %v", err)
+	}
+
+	if owner, err := s.Exercises().OwnerID(exerciseID); err != nil || owner != userID {
+		t.Errorf("OwnerID = %d, %v; want %d", owner, err, userID)
+	}
+	if owner, err := s.Exercises().SetOwnerID(setID); err != nil || owner != userID {
+		t.Errorf("SetOwnerID = %d, %v; want %d", owner, err, userID)
+	}
+	if _, err := s.Exercises().SetOwnerID(setID + 100); err != ErrNotFound {
+		t.Errorf("SetOwnerID of missing set: err = %v, want ErrNotFound", err)
+	}
+
+	if err := s.Exercises().Update(models.Exercise{ID: exerciseID, Name: "Weighted Dips", Category: "Strength"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	exercises, err := s.Exercises().ByWorkoutIDs([]int{workoutID})
+	if err != nil {
+		t.Fatalf("ByWorkoutIDs: %v", err)
+	}
+	if set := exercises[workoutID][1].Sets[0]; set.SetType != models.SetTypeWorking || set.RPE != nil || set.RIR != nil {
+		t.Errorf("set created without type or effort = %+v, want a working set with no RPE or RIR", set)
+	}
+
+	rpe, rir := 8.5, 0
+	updated := models.Set{ID: setID, SetNumber: 1, Reps: 8, Weight: 20, RPE: &rpe, RIR: &rir, SetType: models.SetTypeAMRAP, Notes: "last rep slow"}
+	if err := s.Exercises().UpdateSet(updated); err != nil {
+		t.Fatalf("UpdateSet: %v", err)
+	}
+
+	exercises, err = s.Exercises().ByWorkoutIDs([]int{workoutID})
+	if err != nil {
+		t.Fatalf("ByWorkoutIDs: %v", err)
+	}
+	got := exercises[workoutID]
+	if len(got) != 2 || got[1].Name != "Weighted Dips" || len(got[1].Sets) != 1 || got[1].Sets[0].Weight != 20 {
+		t.Fatalf("ByWorkoutIDs = %+v", got)
+	}
+	if set := got[1].Sets[0]; set.RPE == nil || *set.RPE != rpe || set.RIR == nil || *set.RIR != rir || set.SetType != models.SetTypeAMRAP || set.Notes != "last rep slow" {
+		t.Errorf("updated set = %+v, want RPE 8.5, RIR 0, amrap and notes", set)
+	}
+
+	if err := s.Exercises().DeleteSet(setID); err != nil {
+		t.Fatalf("DeleteSet: %v", err)
+	}
+	if err := s.Exercises().Delete(exerciseID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Exercises().Delete(exerciseID); err != ErrNotFound {
+		t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+	}
+
+	exercises, _ = s.Exercises().ByWorkoutIDs([]int{workoutID})
+	if len(exercises[workoutID]) != 1 {
+		t.Errorf("ByWorkoutIDs after Delete = %+v", exercises[workoutID])
+	}
+}
+
+func testExerciseOrder(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	workoutID := createWorkout(t, s, alice, "Push", time.Now(), 60)
+	otherWorkoutID := createWorkout(t, s, bob, "Pull", time.Now(), 60)
+
+	for _, name := range []string{"Dips", "Flyes"} {
+		if _, err := s.Exercises().Create(models.Exercise{WorkoutID: workoutID, Name: name, Category: "Strength"}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	exercises, err := s.Exercises().ByWorkoutIDs([]int{workoutID, otherWorkoutID})
+	if err != nil {
+		t.Fatalf("ByWorkoutIDs: %v", err)
+	}
+	got := exercises[workoutID]
+	if names := exerciseNames(got); !equalStrings(names, []string{"Bench Press", "Dips", "Flyes"}) {
+		t.Fatalf("exercises before Reorder = %v, want creation order", names)
+	}
+	for i, exercise := range got {
+		if exercise.OrderIndex != i || exercise.GroupID != 0 {
+			t.Errorf("exercise %s: order %d group %d, want order %d and no group", exercise.Name, exercise.OrderIndex, exercise.GroupID, i)
+		}
+	}
+
+	// Flyes and Dips become a superset ahead of the bench press
+	order := []models.ExerciseOrder{{ID: got[2].ID, GroupID: 1}, {ID: got[1].ID, GroupID: 1}, {ID: got[0].ID}}
+	if err := s.Exercises().Reorder(workoutID, order); err != nil {
+		t.Fatalf("Reorder: %v", err)
+	}
+	workout, err := s.Workouts().Get(workoutID, alice)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if names := exerciseNames(workout.Exercises); !equalStrings(names, []string{"Flyes", "Dips", "Bench Press"}) {
+		t.Errorf("exercises after Reorder = %v", names)
+	}
+	if groups := []int{workout.Exercises[0].GroupID, workout.Exercises[1].GroupID, workout.Exercises[2].GroupID}; groups[0] != 1 || groups[1] != 1 || groups[2] != 0 {
+		t.Errorf("groups after Reorder = %v, want [1 1 0]", groups)
+	}
+	if label := workout.GroupLabel(1); label != "Superset A" {
+		t.Errorf("GroupLabel(1) = %q, want Superset A", label)
+	}
+
+	otherExerciseID := exercises[otherWorkoutID][0].ID
+	if err := s.Exercises().Reorder(workoutID, []models.ExerciseOrder{{ID: otherExerciseID}}); err != ErrNotFound {
+		t.Errorf("Reorder with another workout's exercise: err = %v, want ErrNotFound", err)
+	}
+	if workout, _ := s.Workouts().Get(workoutID, alice); workout.Exercises[0].Name != "Flyes" {
+		t.Errorf("failed Reorder changed the order: %v", exerciseNames(workout.Exercises))
+	}
+}
+
+func testTemplates(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+
+	id, err := s.Templates().Create(models.WorkoutTemplate{UserID: alice, Name: "Full Body", Description: "Three lifts"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for i, name := range []string{"Squat", "Bench Press", "Deadlift"} {
+		_, err := s.Templates().CreateExercise(models.TemplateExercise{TemplateID: id, Name: name, Category: "Strength", OrderIndex: i, GroupID: i / 2, TargetSets: 3, TargetReps: 5})
+		if err != nil {
+			t.Fatalf("CreateExercise: %v", err)
+		}
+	}
+
+	template, err := s.Templates().Get(id, alice)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if template.Name != "Full Body" || len(template.Exercises) != 3 || template.Exercises[2].Name != "Deadlift" {
+		t.Errorf("Get = %+v", template)
+	}
+	if template.Exercises[2].GroupID != 1 || template.Exercises[2].OrderIndex != 2 {
+		t.Errorf("Get lost the order or group of %+v", template.Exercises[2])
+	}
+	if _, err := s.Templates().Get(id, bob); err != ErrNotFound {
+		t.Errorf("Get by another user: err = %v, want ErrNotFound", err)
+	}
+
+	bobs, err := s.Templates().Create(models.WorkoutTemplate{UserID: bob, Name: "Cardio"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := s.Templates().CreateExercise(models.TemplateExercise{TemplateID: bobs, Name: "Rowing", Category: "Cardio"}); err != nil {
+		t.Fatalf("CreateExercise: %v", err)
+	}
+	byTemplate, err := s.Templates().ExercisesByTemplateIDs([]int{id, bobs, bobs + 100})
+	if err != nil {
+		t.Fatalf("ExercisesByTemplateIDs: %v", err)
+	}
+	if len(byTemplate) != 2 || len(byTemplate[id]) != 3 || byTemplate[id][1].Name != "Bench Press" || len(byTemplate[bobs]) != 1 || byTemplate[bobs][0].Name != "Rowing" {
+		t.Errorf("ExercisesByTemplateIDs = %+v", byTemplate)
+	}
+
+	template.Name = "Full Body A"
+	if err := s.Templates().Update(template); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := s.Templates().DeleteExercises(id); err != nil {
+		t.Fatalf("DeleteExercises: %v", err)
+	}
+
+	templates, err := s.Templates().ListByUser(alice)
+	if err != nil {
+		t.Fatalf("ListByUser: %v", err)
+	}
+	if len(templates) != 1 || templates[0].Name != "Full Body A" || len(templates[0].Exercises) != 0 {
+		t.Errorf("ListByUser = %+v", templates)
+	}
+
+	if _, err := s.Templates().Share(models.TemplateSharing{TemplateID: id, OwnerID: alice, SharedWithID: bob, Permission: "view"}); err != nil {
+		t.Fatalf("Share: %v", err)
+	}
+	shared, err := s.Templates().SharedWith(bob)
+	if err != nil {
+		t.Fatalf("SharedWith: %v", err)
+	}
+	if len(shared) != 1 || shared[0].ID != id {
+		t.Errorf("SharedWith = %+v", shared)
+	}
+
+	workoutID := createWorkout(t, s, bob, "From template", time.Now(), 30)
+	if _, err := s.Templates().RecordUsage(models.TemplateUsage{TemplateID: id, UserID: bob, WorkoutID: workoutID, UsedAt: time.Now(), CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("RecordUsage: %v", err)
+	}
+
+	if err := s.Templates().Delete(id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if shared, _ := s.Templates().SharedWith(bob); len(shared) != 0 {
+		t.Errorf("SharedWith after Delete = %+v", shared)
+	}
+}
+
+func testPrograms(t *testing.T, s Store) {
+	userID := createUser(t, s, "coach")
+	templateID, err := s.Templates().Create(models.WorkoutTemplate{UserID: userID, Name: "Day A"})
+	if err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+
+	publicID, err := s.Programs().Create(models.WorkoutProgram{Name: "5x5", Difficulty: "beginner", DurationWeeks: 12, Goal: "strength", IsPublic: true, CreatedBy: userID})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := s.Programs().Create(models.WorkoutProgram{Name: "Private", IsPublic: false, CreatedBy: userID}); err != nil {
+		t.Fatalf("Create private: %v", err)
+	}
+
+	for _, week := range []int{2, 1} {
+		_, err := s.Programs().AddTemplate(models.ProgramTemplate{ProgramID: publicID, TemplateID: templateID, DayOfWeek: 1, WeekNumber: week})
+		if err != nil {
+			t.Fatalf("AddTemplate: %v", err)
+		}
+	}
+
+	programs, err := s.Programs().ListPublic()
+	if err != nil {
+		t.Fatalf("ListPublic: %v", err)
+	}
+	if len(programs) != 1 || programs[0].ID != publicID || !programs[0].IsPublic || len(programs[0].Templates) != 2 {
+		t.Fatalf("ListPublic = %+v", programs)
+	}
+	if pt := programs[0].Templates[0]; pt.WeekNumber != 1 || pt.WorkoutTemplate == nil || pt.WorkoutTemplate.Name != "Day A" {
+		t.Errorf("first program template = %+v", pt)
+	}
+
+	program, err := s.Programs().Get(publicID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	program.IsPublic = false
+	if err := s.Programs().Update(program); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if programs, _ := s.Programs().ListPublic(); len(programs) != 0 {
+		t.Errorf("ListPublic after unpublishing = %+v", programs)
+	}
+
+	if err := s.Programs().DeleteTemplates(publicID); err != nil {
+		t.Fatalf("DeleteTemplates: %v", err)
+	}
+	if templates, _ := s.Programs().Templates(publicID); len(templates) != 0 {
+		t.Errorf("Templates after DeleteTemplates = %+v", templates)
+	}
+	if err := s.Programs().Delete(publicID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Programs().Get(publicID); err != ErrNotFound {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testBodyMetrics(t *testing.T, s Store) {
+	userID := createUser(t, s, "alice")
+	day := time.Date(2024, 5, 1, 7, 0, 0, 0, time.UTC)
+
+	for i, weight := range []float64{80.5, 80.1} {
+		if _, err := s.BodyMetrics().CreateWeight(models.BodyWeight{UserID: userID, Weight: weight, Unit: "kg", Date: day.AddDate(0, 0, i)}); err != nil {
+			t.Fatalf("CreateWeight: %v", err)
+		}
+	}
+	if _, err := s.BodyMetrics().CreateBodyFat(models.BodyFat{UserID: userID, BodyFatPct: 15.5, Date: day, Measurement: "calipers"}); err != nil {
+		t.Fatalf("CreateBodyFat: %v", err)
+	}
+	for _, name := range []string{"waist", "chest"} {
+		if _, err := s.BodyMetrics().CreateMeasurement(models.BodyMeasurement{UserID: userID, Measurement: name, Value: 80, Unit: "cm", Date: day}); err != nil {
+			t.Fatalf("CreateMeasurement: %v", err)
+		}
+	}
+
+	weights, err := s.BodyMetrics().WeightsByUser(userID)
+	if err != nil {
+		t.Fatalf("WeightsByUser: %v", err)
+	}
+	if len(weights) != 2 || weights[0].Weight != 80.1 || !weights[1].Date.Equal(day) {
+		t.Errorf("WeightsByUser = %+v", weights)
+	}
+
+	bodyFats, err := s.BodyMetrics().BodyFatsByUser(userID)
+	if err != nil {
+		t.Fatalf("BodyFatsByUser: %v", err)
+	}
+	if len(bodyFats) != 1 || bodyFats[0].BodyFatPct != 15.5 || bodyFats[0].Measurement != "calipers" {
+		t.Errorf("BodyFatsByUser = %+v", bodyFats)
+	}
+
+	measurements, err := s.BodyMetrics().MeasurementsByUser(userID)
+	if err != nil {
+		t.Fatalf("MeasurementsByUser: %v", err)
+	}
+	if len(measurements) != 2 || measurements[0].Measurement != "chest" {
+		t.Errorf("MeasurementsByUser = %+v", measurements)
+	}
+
+	other := createUser(t, s, "bob")
+	if weights, _ := s.BodyMetrics().WeightsByUser(other); len(weights) != 0 {
+		t.Errorf("WeightsByUser of another user = %+v", weights)
+	}
+}
+
+func testMeals(t *testing.T, s Store) {
+	userID := createUser(t, s, "alice")
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	meals := []models.Meal{
+		{UserID: userID, Name: "Oats", Calories: 400, Protein: 15, Date: day.Add(8 * time.Hour), MealType: "breakfast"},
+		{UserID: userID, Name: "Rice", Calories: 700, Protein: 40, Date: day.Add(13 * time.Hour), MealType: "lunch"},
+		{UserID: userID, Name: "Pizza", Calories: 900, Date: day.AddDate(0, 0, 1).Add(19 * time.Hour), MealType: "dinner"},
+	}
+	for _, meal := range meals {
+		if _, err := s.Meals().Create(meal); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	got, err := s.Meals().ByUserAndDate(userID, day.Add(12*time.Hour))
+	if err != nil {
+		t.Fatalf("ByUserAndDate: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "Oats" || got[1].Protein != 40 {
+		t.Errorf("ByUserAndDate = %+v", got)
+	}
+}
+
+func testAnalytics(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	first := createWorkout(t, s, alice, "Morning", day.Add(18*time.Hour), 60)
+	second := createWorkout(t, s, alice, "Evening", day.Add(20*time.Hour), 0)
+	third, err := s.Workouts().Create(models.Workout{
+		Name:     "Legs",
+		Date:     day.AddDate(0, 0, 2).Add(9 * time.Hour),
+		Duration: 45,
+		Exercises: []models.Exercise{
+			{Name: "Squat", Category: "Strength", Sets: []models.Set{
+				{SetNumber: 1, Reps: 5, Weight: 60, SetType: models.SetTypeWarmup},
+				{SetNumber: 2, Reps: 3, Weight: 140},
+			}},
+			{Name: "Bench Press", Category: "Strength", Sets: []models.Set{{SetNumber: 1, Reps: 3, Weight: 110}}},
+		},
+	}, alice)
+	if err != nil {
+		t.Fatalf("failed to create workout: %v", err)
+	}
+	// Outside the period or someone else's
+	createWorkout(t, s, alice, "Before", day.Add(-time.Hour), 30)
+	createWorkout(t, s, alice, "After", day.AddDate(0, 0, 3), 30)
+	createWorkout(t, s, bob, "Bob's", day.Add(12*time.Hour), 30)
+
+	for _, meal := range []models.Meal{
+		{UserID: alice, Name: "Oats", Calories: 400, Date: day.Add(8 * time.Hour), MealType: "breakfast"},
+		{UserID: alice, Name: "Pizza", Calories: 700, Date: day.Add(19 * time.Hour), MealType: "dinner"},
+		{UserID: alice, Name: "Rice", Calories: 500, Date: day.AddDate(0, 0, 2).Add(13 * time.Hour), MealType: "lunch"},
+		{UserID: alice, Name: "Late", Calories: 900, Date: day.AddDate(0, 0, 5), MealType: "dinner"},
+		{UserID: bob, Name: "Bob's", Calories: 300, Date: day.Add(8 * time.Hour), MealType: "breakfast"},
+	} {
+		if _, err := s.Meals().Create(meal); err != nil {
+			t.Fatalf("failed to create meal: %v", err)
+		}
+	}
+
+	// Periods cover whole calendar days, whatever the times of their ends
+	from, to := day.Add(15*time.Hour), day.AddDate(0, 0, 2)
+	analytics := s.Analytics()
+
+	workoutDays, err := analytics.WorkoutDays(alice, from, to)
+	if err != nil {
+		t.Fatalf("WorkoutDays: %v", err)
+	}
+	if len(workoutDays) != 2 ||
+		!workoutDays[0].Date.Equal(day) || workoutDays[0].Workouts != 2 || workoutDays[0].AvgDuration != 60 ||
+		!workoutDays[1].Date.Equal(day.AddDate(0, 0, 2)) || workoutDays[1].Workouts != 1 || workoutDays[1].AvgDuration != 45 {
+		t.Errorf("WorkoutDays = %+v", workoutDays)
+	}
+
+	calorieDays, err := analytics.CalorieDays(alice, from, to)
+	if err != nil {
+		t.Fatalf("CalorieDays: %v", err)
+	}
+	if len(calorieDays) != 2 || !calorieDays[0].Date.Equal(day) || calorieDays[0].Calories != 1100 ||
+		!calorieDays[1].Date.Equal(day.AddDate(0, 0, 2)) || calorieDays[1].Calories != 500 {
+		t.Errorf("CalorieDays = %+v", calorieDays)
+	}
+
+	top, err := analytics.TopExercises(alice, from, to, 5)
+	if err != nil {
+		t.Fatalf("TopExercises: %v", err)
+	}
+	if len(top) != 2 || top[0].ExerciseName != "Bench Press" || top[0].Count != 3 || top[1].ExerciseName != "Squat" || top[1].Category != "Strength" {
+		t.Errorf("TopExercises = %+v", top)
+	}
+	if top, err := analytics.TopExercises(alice, from, to, 1); err != nil || len(top) != 1 {
+		t.Errorf("TopExercises with a limit of 1 = %+v, %v", top, err)
+	}
+
+	strength, err := analytics.StrengthDays(alice, "Bench Press", from, to)
+	if err != nil {
+		t.Fatalf("StrengthDays: %v", err)
+	}
+	if len(strength) != 2 ||
+		!strength[0].Date.Equal(day) || strength[0].MaxWeight != 105 || strength[0].MaxReps != 5 || strength[0].Volume != 2050 ||
+		!strength[1].Date.Equal(day.AddDate(0, 0, 2)) || strength[1].MaxWeight != 110 || strength[1].Volume != 330 {
+		t.Errorf("StrengthDays = %+v", strength)
+	}
+	// Warmups count towards none of the figures
+	if squat, err := analytics.StrengthDays(alice, "Squat", from, to); err != nil || len(squat) != 1 || squat[0].MaxReps != 3 || squat[0].Volume != 420 {
+		t.Errorf("StrengthDays of squats = %+v, %v", squat, err)
+	}
+
+	progress, err := analytics.ExerciseProgress(alice, "Bench Press", from, to)
+	if err != nil {
+		t.Fatalf("ExerciseProgress: %v", err)
+	}
+	if len(progress) != 3 || progress[0].WorkoutID != first || progress[1].WorkoutID != second || progress[2].WorkoutID != third ||
+		!progress[0].Date.Equal(day) || progress[0].SetsCount != 2 || progress[0].TotalVolume != 1025 || progress[2].MaxWeight != 110 {
+		t.Errorf("ExerciseProgress = %+v", progress)
+	}
+	if squat, err := analytics.ExerciseProgress(alice, "Squat", from, to); err != nil || len(squat) != 1 || squat[0].SetsCount != 1 || squat[0].MaxWeight != 140 {
+		t.Errorf("ExerciseProgress of squats = %+v, %v", squat, err)
+	}
+
+	sets, err := analytics.WorkingSets(alice, "", from, to)
+	if err != nil {
+		t.Fatalf("WorkingSets: %v", err)
+	}
+	if len(sets) != 6 || sets[0].WorkoutID != first || sets[0].Weight != 100 || !sets[0].Date.Equal(day.Add(18*time.Hour)) || sets[5].WorkoutID != third {
+		t.Errorf("WorkingSets = %+v", sets)
+	}
+	if squat, err := analytics.WorkingSets(alice, "Squat", from, to); err != nil || len(squat) != 1 || squat[0].Weight != 140 || squat[0].ExerciseName != "Squat" {
+		t.Errorf("WorkingSets of squats = %+v, %v", squat, err)
+	}
+
+	// Nothing logged gives empty lists rather than nil
+	if days, err := analytics.WorkoutDays(bob, day.AddDate(0, 0, 1), day.AddDate(0, 0, 1)); err != nil || days == nil || len(days) != 0 {
+		t.Errorf("WorkoutDays of an empty period = %#v, %v", days, err)
+	}
+}
+
+func testSessions(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	started := time.Date(2026, 10, 15, 18, 0, 0, 0, time.UTC)
+
+	id, err := s.Sessions().Create(models.WorkoutSession{UserID: alice, Name: "Legs", StartedAt: started, Exercises: []models.SessionExercise{
+		{Name: "Squat", Category: "Legs", TargetSets: 3, TargetReps: 5, TargetWeight: 120, RestTime: 180},
+		{Name: "Lunge", Category: "Legs", GroupID: 1},
+	}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := s.Sessions().Create(models.WorkoutSession{UserID: alice, Name: "Again", StartedAt: started}); err != ErrConflict {
+		t.Errorf("Create of a second unfinished session: err = %v, want ErrConflict", err)
+	}
+
+	session, err := s.Sessions().Active(alice)
+	if err != nil {
+		t.Fatalf("Active: %v", err)
+	}
+	if session.ID != id || !session.StartedAt.Equal(started) || len(session.Exercises) != 2 || session.CurrentExerciseID != session.Exercises[0].ID {
+		t.Fatalf("Active = %+v", session)
+	}
+	if squat := session.Exercises[0]; squat.Name != "Squat" || squat.TargetWeight != 120 || squat.RestTime != 180 {
+		t.Errorf("planned exercise = %+v", squat)
+	}
+	if _, err := s.Sessions().Get(id, bob); err != ErrNotFound {
+		t.Errorf("Get by another user: err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Sessions().Active(bob); err != ErrNotFound {
+		t.Errorf("Active without a session: err = %v, want ErrNotFound", err)
+	}
+
+	squatID := session.Exercises[0].ID
+	rpe := 9.0
+	// Sets are numbered by the store, whatever number they come with
+	for i := 1; i <= 2; i++ {
+		if _, err := s.Sessions().AddSet(models.Set{ExerciseID: squatID, SetNumber: 1, Reps: 5, Weight: 120, RestTime: 180, RPE: &rpe}); err != nil {
+			t.Fatalf("AddSet: %v", err)
+		}
+	}
+	calfRaiseID, err := s.Sessions().AddExercise(models.SessionExercise{SessionID: id, Name: "Calf Raise", Category: "Legs"})
+	if err != nil {
+		t.Fatalf("AddExercise: %v", err)
+	}
+
+	restEndsAt := started.Add(10 * time.Minute)
+	session.CurrentExerciseID = calfRaiseID
+	session.RestEndsAt = &restEndsAt
+	if err := s.Sessions().UpdateState(session); err != nil {
+		t.Fatalf("UpdateState: %v", err)
+	}
+
+	session, err = s.Sessions().Get(id, alice)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if session.CurrentExerciseID != calfRaiseID || session.RestEndsAt == nil || !session.RestEndsAt.Equal(restEndsAt) {
+		t.Errorf("state after UpdateState = %d, %v", session.CurrentExerciseID, session.RestEndsAt)
+	}
+	if len(session.Exercises) != 3 || session.Exercises[2].ID != calfRaiseID || len(session.Exercises[0].Sets) != 2 {
+		t.Fatalf("exercises after AddExercise = %+v", session.Exercises)
+	}
+	if set := session.Exercises[0].Sets[1]; set.SetNumber != 2 || set.RPE == nil || *set.RPE != rpe || set.SetType != models.SetTypeWorking {
+		t.Errorf("completed set = %+v", set)
+	}
+
+	finishedAt := started.Add(time.Hour)
+	session.FinishedAt = &finishedAt
+	workout := models.Workout{Name: session.Name, Date: started, Duration: 60, Exercises: []models.Exercise{
+		{Name: "Squat", Category: "Legs", Sets: session.Exercises[0].Sets},
+	}}
+	workoutID, err := s.Sessions().Finish(session, workout)
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if _, err := s.Sessions().Finish(session, workout); err != ErrNotFound {
+		t.Errorf("second Finish: err = %v, want ErrNotFound", err)
+	}
+
+	saved, err := s.Workouts().Get(workoutID, alice)
+	if err != nil {
+		t.Fatalf("Get finished workout: %v", err)
+	}
+	if saved.Duration != 60 || len(saved.Exercises) != 1 || len(saved.Exercises[0].Sets) != 2 {
+		t.Errorf("finished workout = %+v", saved)
+	}
+	session, err = s.Sessions().Get(id, alice)
+	if err != nil {
+		t.Fatalf("Get finished session: %v", err)
+	}
+	if session.WorkoutID == nil || *session.WorkoutID != workoutID || session.FinishedAt == nil || session.RestEndsAt != nil {
+		t.Errorf("finished session = %+v", session)
+	}
+	if err := s.Sessions().UpdateState(session); err != ErrNotFound {
+		t.Errorf("UpdateState of a finished session: err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Sessions().Active(alice); err != ErrNotFound {
+		t.Errorf("Active after Finish: err = %v, want ErrNotFound", err)
+	}
+
+	if err := s.Sessions().Delete(id, bob); err != ErrNotFound {
+		t.Errorf("Delete by another user: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Sessions().Delete(id, alice); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Workouts().Get(workoutID, alice); err != nil {
+		t.Errorf("deleting the session removed its workout: %v", err)
+	}
+}
+
+func testPersonalRecords(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	day := time.Date(2026, 10, 12, 18, 0, 0, 0, time.UTC)
+
+	first, err := s.Workouts().Create(models.Workout{Name: "Push", Date: day, Exercises: []models.Exercise{{
+		Name: "Bench Press", Category: "Chest", Sets: []models.Set{
+			{SetNumber: 1, Reps: 5, Weight: 140, SetType: models.SetTypeWarmup},
+			{SetNumber: 2, Reps: 5, Weight: 100},
+			{SetNumber: 3, Reps: 5, Weight: 100},
+			{SetNumber: 4, Reps: 3, Weight: 110},
+			{SetNumber: 5, Reps: 15, Weight: 60},
+		},
+	}}}, alice)
+	if err != nil {
+		t.Fatalf("failed to create workout: %v", err)
+	}
+	firstWorkout, _ := s.Workouts().Get(first, alice)
+	firstSets := firstWorkout.Exercises[0].Sets
+
+	// current returns alice's standing records of an exercise keyed by type and rep count
+	current := func(exerciseName string) map[string]models.PersonalRecordEntry {
+		t.Helper()
+		entries, err := s.PersonalRecords().List(alice, exerciseName)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		records := make(map[string]models.PersonalRecordEntry)
+		for _, e := range entries {
+			if e.IsCurrent {
+				records[e.RecordType+"/"+strconv.Itoa(e.Reps)] = e
+			}
+		}
+		return records
+	}
+
+	records := current("Bench Press")
+	want := map[string]float64{"weight/5": 100, "weight/3": 110, "e1rm/3": 121, "set_volume/15": 900, "session_volume/28": 2230}
+	if len(records) != len(want) {
+		t.Fatalf("records = %+v, want %v", records, want)
+	}
+	for key, value := range want {
+		record, ok := records[key]
+		got := record.Weight
+		switch record.RecordType {
+		case models.RecordTypeE1RM:
+			got = record.OneRepMax
+		case models.RecordTypeSetVolume, models.RecordTypeSessionVolume:
+			got = record.Volume
+		}
+		if !ok || math.Abs(got-value) > 0.001 {
+			t.Errorf("%s = %+v, want %v", key, record, value)
+		}
+	}
+	// The first of two equal sets holds the record
+	if record := records["weight/5"]; record.SetID == nil || *record.SetID != firstSets[1].ID || record.WorkoutID != first {
+		t.Errorf("5-rep record = %+v, want set %d", record, firstSets[1].ID)
+	}
+	if record := records["session_volume/28"]; record.SetID != nil || !record.Date.Equal(day) {
+		t.Errorf("session volume record = %+v", record)
+	}
+
+	second, err := s.Workouts().Create(models.Workout{Name: "Push", Date: day.AddDate(0, 0, 3), Exercises: []models.Exercise{{
+		Name: "Bench Press", Category: "Chest", Sets: []models.Set{{SetNumber: 1, Reps: 5, Weight: 105}},
+	}}}, alice)
+	if err != nil {
+		t.Fatalf("failed to create workout: %v", err)
+	}
+	secondWorkout, _ := s.Workouts().Get(second, alice)
+	secondSet := secondWorkout.Exercises[0].Sets[0]
+
+	records = current("bench press")
+	if records["weight/5"].Weight != 105 || math.Abs(records["e1rm/5"].OneRepMax-122.5) > 0.001 || records["session_volume/28"].Volume != 2230 {
+		t.Errorf("records after a heavier set = %+v", records)
+	}
+	history, _ := s.PersonalRecords().List(alice, "Bench Press")
+	if len(history) != 9 {
+		t.Errorf("history has %d entries, want 9: %+v", len(history), history)
+	}
+
+	secondSet.Weight = 90
+	if err := s.Exercises().UpdateSet(secondSet); err != nil {
+		t.Fatalf("UpdateSet: %v", err)
+	}
+	if records = current("Bench Press"); records["weight/5"].Weight != 100 || math.Abs(records["e1rm/3"].OneRepMax-121) > 0.001 {
+		t.Errorf("records after lowering the set = %+v", records)
+	}
+
+	if err := s.Exercises().DeleteSet(firstSets[3].ID); err != nil {
+		t.Fatalf("DeleteSet: %v", err)
+	}
+	if records = current("Bench Press"); records["weight/3"].Weight != 0 || records["session_volume/25"].Volume != 1900 {
+		t.Errorf("records after deleting the triple = %+v", records)
+	}
+
+	firstWorkout.Exercises[0].Name = "Incline Press"
+	if err := s.Exercises().Update(firstWorkout.Exercises[0]); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if records = current("Bench Press"); len(records) != 4 || records["weight/5"].Weight != 90 || records["weight/5"].WorkoutID != second {
+		t.Errorf("bench press records after renaming the first exercise = %+v", records)
+	}
+	if records = current("Incline Press"); records["weight/5"].Weight != 100 {
+		t.Errorf("incline press records = %+v", records)
+	}
+
+	if err := s.Workouts().Delete(second, alice); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if records = current("Bench Press"); len(records) != 0 {
+		t.Errorf("records of a deleted workout survived: %+v", records)
+	}
+	if entries, _ := s.PersonalRecords().List(bob, ""); len(entries) != 0 {
+		t.Errorf("bob sees alice's records: %+v", entries)
+	}
+
+	if err := s.PersonalRecords().Rebuild(alice); err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if records = current(""); len(records) != 4 || records["weight/5"].ExerciseName != "Incline Press" {
+		t.Errorf("records after Rebuild = %+v", records)
+	}
+
+	// Records missing after an upgrade are rebuilt for the users with sets, once
+	if _, err := s.(*sqlStore).db.Exec(`DELETE FROM personal_records WHERE user_id = ?`, alice); err != nil {
+		t.Fatalf("failed to delete records: %v", err)
+	}
+	if users, err := s.PersonalRecords().RebuildMissing(); err != nil || users != 1 {
+		t.Errorf("RebuildMissing = %d, %v; want alice's records rebuilt", users, err)
+	}
+	if records = current(""); len(records) != 4 {
+		t.Errorf("records after RebuildMissing = %+v", records)
+	}
+	if users, err := s.PersonalRecords().RebuildMissing(); err != nil || users != 0 {
+		t.Errorf("RebuildMissing with every record kept = %d, %v; want none", users, err)
+	}
+}
+
+// createPredefinedExercise adds a library exercise, which the store has no method for,
+// links its muscles and returns its ID
+func createPredefinedExercise(t *testing.T, s Store, name, muscleGroups string) int {
+	t.Helper()
+
+	query := `INSERT INTO predefined_exercises (name, category, muscle_groups) VALUES (?, ?, ?)`
+	id, err := s.(*sqlStore).db.Insert(query, name, "strength", muscleGroups)
+	if err != nil {
+		t.Fatalf("failed to create predefined exercise %s: %v", name, err)
+	}
+	if err := s.Muscles().LinkExercise(id); err != nil {
+		t.Fatalf("LinkExercise: %v", err)
+	}
+	return id
+}
+
+func testMuscles(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	day := time.Date(2026, 10, 12, 18, 0, 0, 0, time.UTC)
+
+	workoutID, err := s.Workouts().Create(models.Workout{Name: "Push", Date: day, Exercises: []models.Exercise{
+		{Name: "bench press", Category: "Chest", Sets: []models.Set{
+			{SetNumber: 1, Reps: 10, Weight: 60, SetType: models.SetTypeWarmup},
+			{SetNumber: 2, Reps: 5, Weight: 100},
+		}},
+		{Name: "Curl", Category: "Arms", Sets: []models.Set{{SetNumber: 1, Reps: 12, Weight: 15}}},
+	}}, alice)
+	if err != nil {
+		t.Fatalf("failed to create workout: %v", err)
+	}
+	createWorkout(t, s, bob, "Bob's push", day, 60)
+
+	if muscles, err := s.Muscles().List(); err != nil || len(muscles) == 0 || muscles[0] != "Abductors" {
+		t.Errorf("List = %v, %v; want every muscle alphabetically", muscles, err)
+	}
+
+	// Adding the library entry links the exercises already logged under its name
+	bench := createPredefinedExercise(t, s, "Bench Press", "Chest, Anterior Deltoids, triceps, Chest, Grip Strength")
+	muscles, err := s.Muscles().ExerciseMuscles()
+	if err != nil {
+		t.Fatalf("ExerciseMuscles: %v", err)
+	}
+	want := []models.ExerciseMuscle{
+		{Muscle: "Chest", Role: models.MuscleRolePrimary},
+		{Muscle: "Front Delts", Role: models.MuscleRoleSecondary},
+		{Muscle: "Triceps", Role: models.MuscleRoleSecondary},
+	}
+	if len(muscles[bench]) != len(want) {
+		t.Fatalf("muscles of bench press = %+v, want %+v", muscles[bench], want)
+	}
+	for i := range want {
+		if muscles[bench][i] != want[i] {
+			t.Errorf("muscle %d = %+v, want %+v", i, muscles[bench][i], want[i])
+		}
+	}
+
+	workout, err := s.Workouts().Get(workoutID, alice)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if id := workout.Exercises[0].PredefinedExerciseID; id == nil || *id != bench {
+		t.Errorf("logged bench press linked to %v, want %d", id, bench)
+	}
+	if id := workout.Exercises[1].PredefinedExerciseID; id != nil {
+		t.Errorf("curl linked to %d, want no library entry", *id)
+	}
+
+	// A logged exercise can be linked to a library entry of another name
+	exerciseID, err := s.Exercises().Create(models.Exercise{WorkoutID: workoutID, Name: "Close-Grip Bench", Category: "Chest", PredefinedExerciseID: &bench})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := s.Exercises().CreateSet(models.Set{ExerciseID: exerciseID, SetNumber: 1, Reps: 8, Weight: 80}); err != nil {
+		t.Fatalf("CreateSet: %v", err)
+	}
+
+	sets, err := s.Muscles().WorkingSets(alice, day.AddDate(0, 0, -day.Day()+1))
+	if err != nil {
+		t.Fatalf("WorkingSets: %v", err)
+	}
+	got := make(map[string]int)
+	for _, set := range sets {
+		got[set.ExerciseName+"/"+set.Muscle+"/"+set.Role]++
+	}
+	wantSets := map[string]int{
+		"bench press/Chest/primary": 1, "bench press/Front Delts/secondary": 1, "bench press/Triceps/secondary": 1,
+		"Close-Grip Bench/Chest/primary": 1, "Close-Grip Bench/Front Delts/secondary": 1, "Close-Grip Bench/Triceps/secondary": 1,
+		"Curl//": 1,
+	}
+	if len(sets) != 7 || len(got) != len(wantSets) {
+		t.Fatalf("working sets = %+v, want %v", sets, wantSets)
+	}
+	for key, n := range wantSets {
+		if got[key] != n {
+			t.Errorf("working sets of %s = %d, want %d", key, got[key], n)
+		}
+	}
+	if sets, _ := s.Muscles().WorkingSets(alice, day.AddDate(0, 0, 1)); len(sets) != 0 {
+		t.Errorf("working sets after the start date = %+v, want none", sets)
+	}
+}
+
+func testTrainingLoad(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	monday := time.Date(2026, 10, 5, 18, 0, 0, 0, time.UTC)
+	rpe := 8.0
+
+	// An hour at the default RPE, then two sets without a duration, one rated
+	long := createWorkout(t, s, alice, "Long", monday, 60)
+	short, err := s.Workouts().Create(models.Workout{Name: "Short", Date: monday.AddDate(0, 0, 2), Exercises: []models.Exercise{{
+		Name: "Squat", Category: "Legs", Sets: []models.Set{
+			{SetNumber: 1, Reps: 10, Weight: 60, SetType: models.SetTypeWarmup},
+			{SetNumber: 2, Reps: 5, Weight: 140, RPE: &rpe},
+			{SetNumber: 3, Reps: 5, Weight: 140},
+		},
+	}}}, alice)
+	if err != nil {
+		t.Fatalf("failed to create workout: %v", err)
+	}
+	createWorkout(t, s, bob, "Bob", monday, 90)
+
+	days := func(from, to time.Time) []models.TrainingLoadDay {
+		t.Helper()
+		days, err := s.TrainingLoad().Days(alice, from, to)
+		if err != nil {
+			t.Fatalf("Days: %v", err)
+		}
+		return days
+	}
+	loads := func(days []models.TrainingLoadDay) []float64 {
+		var loads []float64
+		for _, d := range days {
+			loads = append(loads, d.Load)
+		}
+		return loads
+	}
+	equalLoads := func(a, b []float64) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if math.Abs(a[i]-b[i]) > 1e-9 {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Days before the first workout are empty and days after the last are rest days
+	from, to := time.Date(2026, 10, 4, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	week := days(from, to)
+	if want := []float64{0, 420, 0, 48, 0, 0, 0}; !equalLoads(loads(week), want) {
+		t.Fatalf("loads = %v, want %v", loads(week), want)
+	}
+	for i, d := range week {
+		if !d.Date.Equal(from.AddDate(0, 0, i)) {
+			t.Errorf("day %d date = %v, want %v", i, d.Date, from.AddDate(0, 0, i))
+		}
+	}
+	if week[0].Fitness != 0 || week[1].Fitness != 420 || week[1].Sessions != 1 || week[2].Sessions != 0 {
+		t.Errorf("first days = %+v", week[:3])
+	}
+	if week[6].Fatigue >= week[4].Fatigue || week[6].Fatigue <= 0 {
+		t.Errorf("fatigue should decay over rest days: %v then %v", week[4].Fatigue, week[6].Fatigue)
+	}
+
+	sessions, err := s.TrainingLoad().Sessions(alice, from)
+	if err != nil {
+		t.Fatalf("Sessions: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].RPE != 0 || sessions[1].WorkingSets != 2 || sessions[1].Tonnage != 1400 || sessions[1].RPE != 8 {
+		t.Errorf("sessions = %+v", sessions)
+	}
+
+	// Rating a set of the first workout changes its load and every day after it
+	longWorkout, _ := s.Workouts().Get(long, alice)
+	set := longWorkout.Exercises[0].Sets[0]
+	hard := 9.0
+	set.RPE = &hard
+	if err := s.Exercises().UpdateSet(set); err != nil {
+		t.Fatalf("UpdateSet: %v", err)
+	}
+	rated := days(from, to)
+	if want := []float64{0, 540, 0, 48, 0, 0, 0}; !equalLoads(loads(rated), want) {
+		t.Errorf("loads after rating a set = %v, want %v", loads(rated), want)
+	}
+	if rated[3].Fitness <= week[3].Fitness {
+		t.Errorf("fitness after a harder session = %v, want more than %v", rated[3].Fitness, week[3].Fitness)
+	}
+
+	// Moving a workout earlier recomputes from its new date
+	shortWorkout, _ := s.Workouts().Get(short, alice)
+	shortWorkout.Date = from.AddDate(0, 0, -1)
+	if err := s.Workouts().Update(shortWorkout, alice); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	moved := days(from.AddDate(0, 0, -1), to)
+	if want := []float64{48, 0, 540, 0, 0, 0, 0, 0}; !equalLoads(loads(moved), want) {
+		t.Errorf("loads after moving a workout = %v, want %v", loads(moved), want)
+	}
+
+	// Rebuilding from scratch gives the same days
+	if err := s.TrainingLoad().Rebuild(alice); err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	rebuilt := days(from.AddDate(0, 0, -1), to)
+	for i := range moved {
+		if math.Abs(moved[i].Fitness-rebuilt[i].Fitness) > 1e-9 || math.Abs(moved[i].ACWR-rebuilt[i].ACWR) > 1e-9 {
+			t.Errorf("rebuilt day %d = %+v, want %+v", i, rebuilt[i], moved[i])
+		}
+	}
+
+	// Load missing after an upgrade is rebuilt for the users with workouts, once
+	if _, err := s.(*sqlStore).db.Exec(`DELETE FROM training_load WHERE user_id = ?`, alice); err != nil {
+		t.Fatalf("failed to delete training load: %v", err)
+	}
+	if users, err := s.TrainingLoad().RebuildMissing(); err != nil || users != 1 {
+		t.Errorf("RebuildMissing = %d, %v; want alice's load rebuilt", users, err)
+	}
+	if got := loads(days(from.AddDate(0, 0, -1), to)); !equalLoads(got, loads(moved)) {
+		t.Errorf("loads after RebuildMissing = %v, want %v", got, loads(moved))
+	}
+	if users, err := s.TrainingLoad().RebuildMissing(); err != nil || users != 0 {
+		t.Errorf("RebuildMissing with all load kept = %d, %v; want none", users, err)
+	}
+
+	// Deleting the last workout leaves only rest days
+	if err := s.Workouts().Delete(long, alice); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got := loads(days(from, to)); !equalLoads(got, make([]float64, 7)) {
+		t.Errorf("loads after deleting a workout = %v, want rest days", got)
+	}
+
+	bobDays, err := s.TrainingLoad().Days(bob, monday, monday)
+	if err != nil || len(bobDays) != 1 || bobDays[0].Load != 630 {
+		t.Errorf("bob's day = %+v, %v; want a load of 630", bobDays, err)
+	}
+}
+
+func testRestDays(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	day := time.Date(2026, 10, 6, 0, 0, 0, 0, time.UTC)
+
+	first, err := s.RestDays().Suggest(models.RestDayRecommendation{
+		UserID: alice, RecommendedDate: day.Add(7 * time.Hour), Reason: models.RestDayReasonConsecutiveDays,
+		IntensityScore: 7.5, VolumeLoad: 12000, ConsecutiveDays: 5, MuscleGroupsWorked: `["Chest"]`,
+	})
+	if err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	if first.ID == 0 || !first.RecommendedDate.Equal(day) || first.Status != models.RestDayStatusSuggested || first.MuscleGroupsWorked != `["Chest"]` {
+		t.Errorf("first suggestion = %+v", first)
+	}
+
+	// A second suggestion for the same day refreshes the first
+	refreshed, err := s.RestDays().Suggest(models.RestDayRecommendation{
+		UserID: alice, RecommendedDate: day, Reason: models.RestDayReasonHighVolume, VolumeLoad: 15000, MuscleGroupsWorked: "[]",
+	})
+	if err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	if refreshed.ID != first.ID || refreshed.Reason != models.RestDayReasonHighVolume || refreshed.VolumeLoad != 15000 {
+		t.Errorf("refreshed suggestion = %+v, want suggestion %d with the new metrics", refreshed, first.ID)
+	}
+
+	if err := s.RestDays().Respond(first.ID, bob, models.RestDayStatusIgnored, ""); err != ErrNotFound {
+		t.Errorf("Respond as another user: err = %v, want ErrNotFound", err)
+	}
+	if err := s.RestDays().Respond(first.ID, alice, models.RestDayStatusAccepted, "legs are sore"); err != nil {
+		t.Fatalf("Respond: %v", err)
+	}
+	answered, err := s.RestDays().Get(first.ID, alice)
+	if err != nil || answered.Status != models.RestDayStatusAccepted || answered.UserResponse == nil || *answered.UserResponse != "legs are sore" {
+		t.Errorf("answered = %+v, %v", answered, err)
+	}
+	if _, err := s.RestDays().Get(first.ID, bob); err != ErrNotFound {
+		t.Errorf("Get as another user: err = %v, want ErrNotFound", err)
+	}
+
+	// Once answered, the day's recommendation stands
+	again, err := s.RestDays().Suggest(models.RestDayRecommendation{UserID: alice, RecommendedDate: day, Reason: models.RestDayReasonHighIntensity})
+	if err != nil || again.ID != first.ID || again.Status != models.RestDayStatusAccepted || again.Reason != models.RestDayReasonHighVolume {
+		t.Errorf("suggestion after an answer = %+v, %v; want the answered one", again, err)
+	}
+
+	second, err := s.RestDays().Suggest(models.RestDayRecommendation{UserID: alice, RecommendedDate: day.AddDate(0, 0, 2), Reason: models.RestDayReasonMuscleFatigue})
+	if err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	if _, err := s.RestDays().Suggest(models.RestDayRecommendation{UserID: bob, RecommendedDate: day, Reason: models.RestDayReasonMuscleFatigue}); err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+
+	list := func(from, to time.Time, status string) []int {
+		t.Helper()
+		recs, err := s.RestDays().List(alice, from, to, status)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		ids := []int{}
+		for _, rec := range recs {
+			ids = append(ids, rec.ID)
+		}
+		return ids
+	}
+	if got := list(day.AddDate(0, 0, -7), day.AddDate(0, 0, 7), ""); len(got) != 2 || got[0] != first.ID || got[1] != second.ID {
+		t.Errorf("List = %v, want %d and %d", got, first.ID, second.ID)
+	}
+	if got := list(day.AddDate(0, 0, 1), day.AddDate(0, 0, 2), ""); len(got) != 1 || got[0] != second.ID {
+		t.Errorf("List from the day after = %v, want %d", got, second.ID)
+	}
+	if got := list(day.AddDate(0, 0, -7), day.AddDate(0, 0, 7), models.RestDayStatusSuggested); len(got) != 1 || got[0] != second.ID {
+		t.Errorf("List of suggestions = %v, want %d", got, second.ID)
+	}
+
+	// Alice rested on the first day but trained on the second; the third is still ahead
+	createWorkout(t, s, alice, "Anyway", day.AddDate(0, 0, 2).Add(18*time.Hour), 60)
+	if _, err := s.RestDays().Suggest(models.RestDayRecommendation{UserID: alice, RecommendedDate: day.AddDate(0, 0, 4), Reason: models.RestDayReasonMuscleFatigue}); err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	recommended, rested, err := s.RestDays().Compliance(alice, day.AddDate(0, 0, 4))
+	if err != nil || recommended != 2 || rested != 1 {
+		t.Errorf("Compliance = %d recommended, %d rested, %v; want 2 and 1", recommended, rested, err)
+	}
+	if recommended, rested, err := s.RestDays().Compliance(bob, day); err != nil || recommended != 0 || rested != 0 {
+		t.Errorf("Compliance before any recommendation = %d, %d, %v; want none", recommended, rested, err)
+	}
+}
+
+func testDeloads(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	day := time.Date(2026, 10, 6, 0, 0, 0, 0, time.UTC)
+
+	if _, err := s.Deloads().Latest(alice); err != ErrNotFound {
+		t.Errorf("Latest before any deload: err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Deloads().TrainingSince(alice, day); err != ErrNotFound {
+		t.Errorf("TrainingSince before any workout: err = %v, want ErrNotFound", err)
+	}
+	createWorkout(t, s, alice, "First", day.AddDate(0, 0, -60).Add(18*time.Hour), 60)
+	if since, err := s.Deloads().TrainingSince(alice, day); err != nil || !since.Equal(day.AddDate(0, 0, -60)) {
+		t.Errorf("TrainingSince without a deload = %v, %v; want the first workout's day", since, err)
+	}
+
+	templateID, err := s.Templates().Create(models.WorkoutTemplate{UserID: alice, Name: "Strength"})
+	if err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+	if _, err := s.Templates().CreateExercise(models.TemplateExercise{TemplateID: templateID, Name: "Squat", TargetSets: 5, TargetReps: 5, TargetWeight: 100}); err != nil {
+		t.Fatalf("failed to create template exercise: %v", err)
+	}
+	schedule := func(date time.Time, template *int) int {
+		t.Helper()
+		id, err := s.ScheduledWorkouts().Create(models.ScheduledWorkout{UserID: alice, TemplateID: template, Title: "Strength", ScheduledDate: date})
+		if err != nil {
+			t.Fatalf("failed to schedule workout: %v", err)
+		}
+		return id
+	}
+	inside := schedule(day.AddDate(0, 0, 3).Add(17*time.Hour), &templateID)
+	after := schedule(day.AddDate(0, 0, 9), &templateID)
+	untemplated := schedule(day.AddDate(0, 0, 4), nil)
+
+	id, err := s.Deloads().Create(models.DeloadRecommendation{
+		UserID: alice, RecommendedStartDate: day.AddDate(0, 0, 1), RecommendedEndDate: day.AddDate(0, 0, 7),
+		Reason: models.DeloadReasonPlateau, VolumeReductionPercent: 40, IntensityReductionPercent: 20, TriggerMetrics: `{"stalled_lifts":2}`,
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	rec, err := s.Deloads().Get(id, alice)
+	if err != nil || rec.Status != models.DeloadStatusSuggested || !rec.RecommendedStartDate.Equal(day.AddDate(0, 0, 1)) || rec.TriggerMetrics != `{"stalled_lifts":2}` {
+		t.Errorf("Get = %+v, %v", rec, err)
+	}
+	if _, err := s.Deloads().Get(id, bob); err != ErrNotFound {
+		t.Errorf("Get as another user: err = %v, want ErrNotFound", err)
+	}
+	if latest, err := s.Deloads().Latest(alice); err != nil || latest.ID != id {
+		t.Errorf("Latest = %+v, %v; want %d", latest, err, id)
+	}
+
+	targets := func(scheduledID int) (int, float64, *int) {
+		t.Helper()
+		sw, err := s.ScheduledWorkouts().Get(scheduledID, alice)
+		if err != nil {
+			t.Fatalf("failed to get scheduled workout: %v", err)
+		}
+		if len(sw.Exercises) != 1 {
+			t.Fatalf("scheduled workout exercises = %+v, want the template's one", sw.Exercises)
+		}
+		return sw.Exercises[0].TargetSets, sw.Exercises[0].TargetWeight, sw.DeloadID
+	}
+	if sets, weight, deload := targets(inside); sets != 5 || weight != 100 || deload != nil {
+		t.Errorf("targets before accepting = %d x %v, deload %v; want 5 x 100", sets, weight, deload)
+	}
+
+	if err := s.Deloads().Respond(id, bob, models.DeloadStatusAccepted, ""); err != ErrNotFound {
+		t.Errorf("Respond as another user: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Deloads().Respond(id, alice, models.DeloadStatusAccepted, "feeling beat up"); err != nil {
+		t.Fatalf("Respond: %v", err)
+	}
+	if sets, weight, deload := targets(inside); sets != 3 || weight != 80 || deload == nil || *deload != id {
+		t.Errorf("targets inside the accepted deload = %d x %v, deload %v; want 3 x 80", sets, weight, deload)
+	}
+	if sets, weight, _ := targets(after); sets != 5 || weight != 100 {
+		t.Errorf("targets after the deload = %d x %v, want 5 x 100", sets, weight)
+	}
+	if sw, err := s.ScheduledWorkouts().Get(untemplated, alice); err != nil || sw.DeloadID != nil || len(sw.Exercises) != 0 {
+		t.Errorf("scheduled workout without a template = %+v, %v", sw, err)
+	}
+	if _, err := s.ScheduledWorkouts().Get(inside, bob); err != ErrNotFound {
+		t.Errorf("Get scheduled workout as another user: err = %v, want ErrNotFound", err)
+	}
+
+	// Workouts scheduled into an accepted deload later are scaled down too
+	later := schedule(day.AddDate(0, 0, 7).Add(9*time.Hour), &templateID)
+	if sets, weight, _ := targets(later); sets != 3 || weight != 80 {
+		t.Errorf("targets of a workout scheduled into the deload = %d x %v, want 3 x 80", sets, weight)
+	}
+
+	// Listing with targets scales each workout of the template on its own
+	listed, err := s.ScheduledWorkouts().ListWithTargets(alice, day, day.AddDate(0, 0, 9), "")
+	if err != nil {
+		t.Fatalf("ListWithTargets: %v", err)
+	}
+	want := map[int]float64{inside: 80, later: 80, after: 100}
+	if len(listed) != 4 {
+		t.Fatalf("ListWithTargets = %+v, want the four scheduled workouts", listed)
+	}
+	for _, sw := range listed {
+		if sw.ID == untemplated {
+			if len(sw.Exercises) != 0 {
+				t.Errorf("listed workout without a template has exercises %+v", sw.Exercises)
+			}
+			continue
+		}
+		if len(sw.Exercises) != 1 || sw.Exercises[0].TargetWeight != want[sw.ID] {
+			t.Errorf("listed workout %d exercises = %+v, want a target of %v", sw.ID, sw.Exercises, want[sw.ID])
+		}
+	}
+
+	// The stretch of training restarts after an accepted deload ends
+	if since, err := s.Deloads().TrainingSince(alice, day.AddDate(0, 0, 20)); err != nil || !since.Equal(day.AddDate(0, 0, 8)) {
+		t.Errorf("TrainingSince after the deload = %v, %v; want the day after it", since, err)
+	}
+	answered, accepted, active, err := s.Deloads().Compliance(alice, day.AddDate(0, 0, 3))
+	if err != nil || answered != 1 || accepted != 1 || active != 1 {
+		t.Errorf("Compliance during the deload = %d, %d, %d, %v; want 1, 1, 1", answered, accepted, active, err)
+	}
+
+	// Ignoring the deload after all restores the targets
+	if err := s.Deloads().Respond(id, alice, models.DeloadStatusIgnored, ""); err != nil {
+		t.Fatalf("Respond: %v", err)
+	}
+	if sets, weight, deload := targets(inside); sets != 5 || weight != 100 || deload != nil {
+		t.Errorf("targets after ignoring = %d x %v, deload %v; want 5 x 100", sets, weight, deload)
+	}
+	if sets, weight, _ := targets(later); sets != 5 || weight != 100 {
+		t.Errorf("targets of the later workout after ignoring = %d x %v, want 5 x 100", sets, weight)
+	}
+	answered, accepted, active, err = s.Deloads().Compliance(alice, day.AddDate(0, 0, 3))
+	if err != nil || answered != 1 || accepted != 0 || active != 0 {
+		t.Errorf("Compliance after ignoring = %d, %d, %d, %v; want 1, 0, 0", answered, accepted, active, err)
+	}
+
+	second, err := s.Deloads().Create(models.DeloadRecommendation{
+		UserID: alice, RecommendedStartDate: day.AddDate(0, 0, 30), RecommendedEndDate: day.AddDate(0, 0, 36), Reason: models.DeloadReasonScheduled,
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	list := func(from, to time.Time, status string) []int {
+		t.Helper()
+		recs, err := s.Deloads().List(alice, from, to, status)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		ids := []int{}
+		for _, rec := range recs {
+			ids = append(ids, rec.ID)
+		}
+		return ids
+	}
+	if got := list(day, day.AddDate(0, 0, 60), ""); len(got) != 2 || got[0] != id || got[1] != second {
+		t.Errorf("List = %v, want %d and %d", got, id, second)
+	}
+	if got := list(day.AddDate(0, 0, 7), day.AddDate(0, 0, 7), ""); len(got) != 1 || got[0] != id {
+		t.Errorf("List of the deload's last day = %v, want %d", got, id)
+	}
+	if got := list(day, day.AddDate(0, 0, 60), models.DeloadStatusSuggested); len(got) != 1 || got[0] != second {
+		t.Errorf("List of suggestions = %v, want %d", got, second)
+	}
+	if latest, err := s.Deloads().Latest(alice); err != nil || latest.ID != second {
+		t.Errorf("Latest = %+v, %v; want %d", latest, err, second)
+	}
+	if recs, err := s.Deloads().List(bob, day, day.AddDate(0, 0, 60), ""); err != nil || len(recs) != 0 {
+		t.Errorf("another user's List = %+v, %v; want none", recs, err)
+	}
+}
+
+func testScheduledWorkouts(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	day := time.Date(2026, 10, 6, 0, 0, 0, 0, time.UTC)
+
+	schedule := func(userID int, title string, date time.Time, clock string) int {
+		t.Helper()
+		sw := models.ScheduledWorkout{UserID: userID, Title: title, ScheduledDate: date, EstimatedDuration: 45}
+		if clock != "" {
+			sw.ScheduledTime = &clock
+		}
+		id, err := s.ScheduledWorkouts().Create(sw)
+		if err != nil {
+			t.Fatalf("failed to schedule workout: %v", err)
+		}
+		return id
+	}
+	evening := schedule(alice, "Evening", day, "18:00")
+	morning := schedule(alice, "Morning", day, "07:00")
+	later := schedule(alice, "Later", day.AddDate(0, 0, 3), "")
+	bobs := schedule(bob, "Bob's", day, "")
+
+	list := func(from, to time.Time, status string) []int {
+		t.Helper()
+		workouts, err := s.ScheduledWorkouts().List(alice, from, to, status)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		ids := []int{}
+		for _, sw := range workouts {
+			ids = append(ids, sw.ID)
+		}
+		return ids
+	}
+	if got := list(day, day.AddDate(0, 0, 7), ""); len(got) != 3 || got[0] != morning || got[1] != evening || got[2] != later {
+		t.Errorf("List = %v, want %d, %d and %d", got, morning, evening, later)
+	}
+	if got := list(day, day, ""); len(got) != 2 {
+		t.Errorf("List of one day = %v, want its two workouts", got)
+	}
+
+	sw, err := s.ScheduledWorkouts().Get(morning, alice)
+	if err != nil || sw.Status != models.ScheduledStatusScheduled || sw.Title != "Morning" || sw.ScheduledTime == nil || *sw.ScheduledTime != "07:00" ||
+		sw.EstimatedDuration != 45 || !sw.ScheduledDate.Equal(day) {
+		t.Errorf("Get = %+v, %v", sw, err)
+	}
+
+	// Logging a workout completes the earliest one still scheduled that day
+	workoutID := createWorkout(t, s, alice, "Push", day.Add(8*time.Hour), 60)
+	completed, err := s.ScheduledWorkouts().Complete(alice, workoutID, day.Add(8*time.Hour))
+	if err != nil || completed.ID != morning || completed.Status != models.ScheduledStatusCompleted || completed.WorkoutID == nil || *completed.WorkoutID != workoutID {
+		t.Errorf("Complete = %+v, %v; want %d completed by workout %d", completed, err, morning, workoutID)
+	}
+	if got := list(day, day, models.ScheduledStatusCompleted); len(got) != 1 || got[0] != morning {
+		t.Errorf("List of completed = %v, want %d", got, morning)
+	}
+	if _, err := s.ScheduledWorkouts().Complete(alice, workoutID, day.AddDate(0, 0, 1)); err != ErrNotFound {
+		t.Errorf("Complete on a day without a scheduled workout: err = %v, want ErrNotFound", err)
+	}
+
+	// Moving the logged workout to another day completes the workout scheduled there
+	// instead, whether only its fields change or its exercises are replaced too
+	status := func(id int) (string, *int) {
+		t.Helper()
+		sw, err := s.ScheduledWorkouts().Get(id, alice)
+		if err != nil {
+			t.Fatalf("failed to get scheduled workout: %v", err)
+		}
+		return sw.Status, sw.WorkoutID
+	}
+	workout, err := s.Workouts().Get(workoutID, alice)
+	if err != nil {
+		t.Fatalf("failed to get workout: %v", err)
+	}
+	workout.Date = day.Add(10 * time.Hour)
+	if err := s.Workouts().Update(workout, alice); err != nil {
+		t.Fatalf("failed to update workout: %v", err)
+	}
+	if st, linked := status(morning); st != models.ScheduledStatusCompleted || linked == nil || *linked != workoutID {
+		t.Errorf("scheduled workout after moving its workout within the day = %s, %v; want it still completed", st, linked)
+	}
+	workout.Date = day.AddDate(0, 0, 3).Add(9 * time.Hour)
+	if err := s.Workouts().Update(workout, alice); err != nil {
+		t.Fatalf("failed to update workout: %v", err)
+	}
+	if st, linked := status(morning); st != models.ScheduledStatusScheduled || linked != nil {
+		t.Errorf("scheduled workout after moving its workout away = %s, %v; want it scheduled again", st, linked)
+	}
+	if st, linked := status(later); st != models.ScheduledStatusCompleted || linked == nil || *linked != workoutID {
+		t.Errorf("scheduled workout on the new day = %s, %v; want it completed by workout %d", st, linked, workoutID)
+	}
+	workout.Date = day.Add(8 * time.Hour)
+	if err := s.Workouts().Replace(workout, alice); err != nil {
+		t.Fatalf("failed to replace workout: %v", err)
+	}
+	if st, _ := status(later); st != models.ScheduledStatusScheduled {
+		t.Errorf("scheduled workout after replacing its workout on another day = %s, want scheduled again", st)
+	}
+	if st, linked := status(morning); st != models.ScheduledStatusCompleted || linked == nil || *linked != workoutID {
+		t.Errorf("scheduled workout after moving its workout back = %s, %v; want it completed again", st, linked)
+	}
+
+	// Deleting the logged workout schedules its workout again
+	if err := s.Workouts().Delete(workoutID, alice); err != nil {
+		t.Fatalf("failed to delete workout: %v", err)
+	}
+	if sw, err := s.ScheduledWorkouts().Get(morning, alice); err != nil || sw.Status != models.ScheduledStatusScheduled || sw.WorkoutID != nil {
+		t.Errorf("scheduled workout after deleting its workout = %+v, %v", sw, err)
+	}
+
+	sw.Title, sw.ScheduledDate, sw.ScheduledTime, sw.Status = "Moved", day.AddDate(0, 0, 1), nil, models.ScheduledStatusCancelled
+	if err := s.ScheduledWorkouts().Update(sw); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if moved, err := s.ScheduledWorkouts().Get(morning, alice); err != nil || moved.Title != "Moved" || !moved.ScheduledDate.Equal(day.AddDate(0, 0, 1)) ||
+		moved.ScheduledTime != nil || moved.Status != models.ScheduledStatusCancelled {
+		t.Errorf("updated = %+v, %v", moved, err)
+	}
+	other := sw
+	other.UserID = bob
+	if err := s.ScheduledWorkouts().Update(other); err != ErrNotFound {
+		t.Errorf("Update as another user: err = %v, want ErrNotFound", err)
+	}
+
+	summary, err := s.ScheduledWorkouts().Summary(alice, day.AddDate(0, 0, 2))
+	if err != nil || summary.TotalScheduled != 3 || summary.CompletedWorkouts != 0 || summary.OverdueWorkouts != 1 || summary.UpcomingWorkouts != 1 {
+		t.Errorf("Summary = %+v, %v; want 3 in all, 1 overdue and 1 upcoming", summary, err)
+	}
+
+	// Only workouts still scheduled before the day are skipped, for every user
+	skipped, err := s.ScheduledWorkouts().SkipOverdue(day.AddDate(0, 0, 2))
+	if err != nil || skipped != 2 {
+		t.Errorf("SkipOverdue = %d, %v; want alice's evening workout and bob's", skipped, err)
+	}
+	if got := list(day, day.AddDate(0, 0, 7), models.ScheduledStatusSkipped); len(got) != 1 || got[0] != evening {
+		t.Errorf("List of skipped = %v, want %d", got, evening)
+	}
+	if sw, _ := s.ScheduledWorkouts().Get(bobs, bob); sw.Status != models.ScheduledStatusSkipped {
+		t.Errorf("bob's overdue workout = %+v, want skipped", sw)
+	}
+	if sw, _ := s.ScheduledWorkouts().Get(later, alice); sw.Status != models.ScheduledStatusScheduled {
+		t.Errorf("upcoming workout = %+v, want still scheduled", sw)
+	}
+
+	if err := s.ScheduledWorkouts().Delete(later, bob); err != ErrNotFound {
+		t.Errorf("Delete as another user: err = %v, want ErrNotFound", err)
+	}
+	if err := s.ScheduledWorkouts().Delete(later, alice); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.ScheduledWorkouts().Get(later, alice); err != ErrNotFound {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testCalendarEvents(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	day := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+
+	create := func(event models.WorkoutCalendarEvent) int {
+		t.Helper()
+		if event.EventType == "" {
+			event.EventType = models.CalendarEventWorkout
+		}
+		id, err := s.CalendarEvents().Create(event)
+		if err != nil {
+			t.Fatalf("failed to create calendar event: %v", err)
+		}
+		return id
+	}
+	weekly := `{"rrule":"FREQ=WEEKLY"}`
+	end := day.AddDate(0, 0, 2)
+	series := create(models.WorkoutCalendarEvent{UserID: alice, Title: "Mobility", StartDate: day.Add(7 * time.Hour), IsRecurring: true, RecurrencePattern: &weekly})
+	span := create(models.WorkoutCalendarEvent{UserID: alice, Title: "Camp", StartDate: day, EndDate: &end, AllDay: true})
+	later := create(models.WorkoutCalendarEvent{UserID: alice, Title: "Race", StartDate: day.AddDate(0, 0, 20)})
+	create(models.WorkoutCalendarEvent{UserID: bob, Title: "Bob's", StartDate: day})
+
+	list := func(from, to time.Time) []int {
+		t.Helper()
+		events, err := s.CalendarEvents().List(alice, from, to)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		ids := []int{}
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		return ids
+	}
+	if got := list(day, day.AddDate(0, 0, 30)); len(got) != 3 || got[0] != span || got[1] != series || got[2] != later {
+		t.Errorf("List = %v, want %d, %d and %d", got, span, series, later)
+	}
+	// A single event is listed while it lasts, a series whenever it has started
+	if got := list(day.AddDate(0, 0, 2), day.AddDate(0, 0, 9)); len(got) != 2 || got[0] != span || got[1] != series {
+		t.Errorf("List from the end of the span = %v, want %d and %d", got, span, series)
+	}
+	if got := list(day.AddDate(0, 0, 3), day.AddDate(0, 0, 9)); len(got) != 1 || got[0] != series {
+		t.Errorf("List after the span = %v, want %d", got, series)
+	}
+	if got := list(day.AddDate(0, 0, -7), day.AddDate(0, 0, -1)); len(got) != 0 {
+		t.Errorf("List before everything = %v, want none", got)
+	}
+
+	event, err := s.CalendarEvents().Get(series, alice)
+	if err != nil || event.Title != "Mobility" || !event.StartDate.Equal(day.Add(7*time.Hour)) || !event.IsRecurring ||
+		event.RecurrencePattern == nil || *event.RecurrencePattern != weekly || event.EndDate != nil {
+		t.Errorf("Get = %+v, %v", event, err)
+	}
+	if _, err := s.CalendarEvents().Get(series, bob); err != ErrNotFound {
+		t.Errorf("Get as another user: err = %v, want ErrNotFound", err)
+	}
+
+	// Splitting ends the series and stores the event replacing its later occurrences
+	until := `{"rrule":"FREQ=WEEKLY;UNTIL=20261011T235959Z"}`
+	event.RecurrencePattern = &until
+	replacement := event
+	replacement.Title, replacement.StartDate = "Stretch", day.AddDate(0, 0, 7).Add(8*time.Hour)
+	replacementID, err := s.CalendarEvents().Split(event, replacement)
+	if err != nil {
+		t.Fatalf("Split: %v", err)
+	}
+	if got, err := s.CalendarEvents().Get(series, alice); err != nil || *got.RecurrencePattern != until {
+		t.Errorf("series after Split = %+v, %v", got, err)
+	}
+	if got, err := s.CalendarEvents().Get(replacementID, alice); err != nil || got.Title != "Stretch" || !got.StartDate.Equal(replacement.StartDate) ||
+		*got.RecurrencePattern != until {
+		t.Errorf("replacement after Split = %+v, %v", got, err)
+	}
+	bobs := event
+	bobs.UserID = bob
+	if _, err := s.CalendarEvents().Split(bobs, replacement); err != ErrNotFound {
+		t.Errorf("Split as another user: err = %v, want ErrNotFound", err)
+	}
+
+	event.Title, event.IsRecurring, event.RecurrencePattern = "Once", false, nil
+	if err := s.CalendarEvents().Update(event); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, err := s.CalendarEvents().Get(series, alice); err != nil || got.Title != "Once" || got.IsRecurring || got.RecurrencePattern != nil {
+		t.Errorf("updated = %+v, %v", got, err)
+	}
+	if err := s.CalendarEvents().Update(bobs); err != ErrNotFound {
+		t.Errorf("Update as another user: err = %v, want ErrNotFound", err)
+	}
+
+	if err := s.CalendarEvents().Delete(later, bob); err != ErrNotFound {
+		t.Errorf("Delete as another user: err = %v, want ErrNotFound", err)
+	}
+	if err := s.CalendarEvents().Delete(later, alice); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.CalendarEvents().Get(later, alice); err != ErrNotFound {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testCalendarFeeds(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+
+	if _, err := s.CalendarFeeds().Get(alice); err != ErrNotFound {
+		t.Errorf("Get before any feed: err = %v, want ErrNotFound", err)
+	}
+	first, err := s.CalendarFeeds().Replace(models.CalendarFeed{UserID: alice, Prefix: "wtcal_1", TokenHash: "hash-1", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	if _, err := s.CalendarFeeds().Replace(models.CalendarFeed{UserID: bob, Prefix: "wtcal_b", TokenHash: "hash-b", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Replace for bob: %v", err)
+	}
+	feed, err := s.CalendarFeeds().GetByHash("hash-1")
+	if err != nil || feed.ID != first || feed.UserID != alice || feed.Prefix != "wtcal_1" || feed.LastUsedAt != nil {
+		t.Errorf("GetByHash = %+v, %v", feed, err)
+	}
+
+	used := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	if err := s.CalendarFeeds().Touch(first, used); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	if feed, err := s.CalendarFeeds().Get(alice); err != nil || feed.LastUsedAt == nil || !feed.LastUsedAt.Equal(used) {
+		t.Errorf("Get after Touch = %+v, %v", feed, err)
+	}
+
+	// Replacing the feed retires the old token
+	second, err := s.CalendarFeeds().Replace(models.CalendarFeed{UserID: alice, Prefix: "wtcal_2", TokenHash: "hash-2", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("second Replace: %v", err)
+	}
+	if _, err := s.CalendarFeeds().GetByHash("hash-1"); err != ErrNotFound {
+		t.Errorf("GetByHash of the replaced token: err = %v, want ErrNotFound", err)
+	}
+	if feed, err := s.CalendarFeeds().Get(alice); err != nil || feed.ID != second || feed.TokenHash != "hash-2" || feed.LastUsedAt != nil {
+		t.Errorf("Get after Replace = %+v, %v", feed, err)
+	}
+
+	if err := s.CalendarFeeds().Delete(alice); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.CalendarFeeds().Delete(alice); err != ErrNotFound {
+		t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.CalendarFeeds().GetByHash("hash-b"); err != nil {
+		t.Errorf("other user's feed was removed: %v", err)
+	}
+}
+
+func testScheduledWorkoutImport(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	day := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	imported := func(userID int, uid, title string) models.ScheduledWorkout {
+		sw := models.ScheduledWorkout{UserID: userID, Title: title, ScheduledDate: day, EstimatedDuration: 45}
+		if uid != "" {
+			sw.ICalUID = &uid
+		}
+		return sw
+	}
+	ids, err := s.ScheduledWorkouts().Import([]models.ScheduledWorkout{
+		imported(alice, "run@example", "Run"),
+		imported(alice, "swim@example", "Swim"),
+		imported(alice, "run@example", "Run again"),
+		imported(alice, "", "No UID"),
+	})
+	if err != nil || len(ids) != 3 {
+		t.Fatalf("Import = %v, %v; want three workouts", ids, err)
+	}
+	sw, err := s.ScheduledWorkouts().Get(ids[0], alice)
+	if err != nil || sw.Title != "Run" || sw.ICalUID == nil || *sw.ICalUID != "run@example" || sw.Status != models.ScheduledStatusScheduled {
+		t.Errorf("imported = %+v, %v", sw, err)
+	}
+
+	// Importing again adds only what is new; UIDs are the user's own
+	ids, err = s.ScheduledWorkouts().Import([]models.ScheduledWorkout{
+		imported(alice, "run@example", "Run"),
+		imported(alice, "", "No UID"),
+		imported(bob, "run@example", "Bob's run"),
+	})
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("second Import = %v, %v; want the workout without a UID and bob's", ids, err)
+	}
+	if workouts, _ := s.ScheduledWorkouts().List(alice, day, day, ""); len(workouts) != 4 {
+		t.Errorf("alice has %d scheduled workouts, want 4", len(workouts))
+	}
+}
+
+func testReminders(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	clock := "18:00"
+	legs, err := s.ScheduledWorkouts().Create(models.ScheduledWorkout{UserID: alice, Title: "Legs", ScheduledDate: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), ScheduledTime: &clock})
+	if err != nil {
+		t.Fatalf("failed to schedule workout: %v", err)
+	}
+	run, err := s.ScheduledWorkouts().Create(models.ScheduledWorkout{UserID: bob, Title: "Run", ScheduledDate: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("failed to schedule workout: %v", err)
+	}
+
+	create := func(userID, scheduledID int, reminderType string, at time.Time) int {
+		t.Helper()
+		id, err := s.Reminders().Create(models.WorkoutReminder{UserID: userID, ScheduledWorkoutID: scheduledID, ReminderType: reminderType, Message: "Go", ScheduledFor: at})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return id
+	}
+	early := create(alice, legs, models.ReminderTypeEmail, now.Add(-time.Hour))
+	sms := create(alice, legs, models.ReminderTypeSMS, now.Add(-30*time.Minute))
+	later := create(alice, legs, models.ReminderTypeEmail, now.Add(time.Hour))
+	bobs := create(bob, run, models.ReminderTypeEmail, now.Add(-2*time.Hour))
+
+	reminders, err := s.Reminders().List(legs, alice)
+	if err != nil || len(reminders) != 3 || reminders[0].ID != early || reminders[2].ID != later {
+		t.Fatalf("List = %+v, %v; want alice's three reminders, earliest first", reminders, err)
+	}
+	if r := reminders[0]; r.Status != models.ReminderStatusPending || r.Attempts != 0 || !r.ScheduledFor.Equal(now.Add(-time.Hour)) {
+		t.Errorf("created reminder = %+v", r)
+	}
+	if reminders, _ := s.Reminders().List(legs, bob); len(reminders) != 0 {
+		t.Errorf("List of another user's workout = %+v", reminders)
+	}
+
+	dueIDs := func() []int {
+		t.Helper()
+		due, err := s.Reminders().Due([]string{models.ReminderTypeEmail}, now, 10)
+		if err != nil {
+			t.Fatalf("Due: %v", err)
+		}
+		ids := []int{}
+		for _, d := range due {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+	due, err := s.Reminders().Due([]string{models.ReminderTypeEmail}, now, 10)
+	if err != nil || len(due) != 2 || due[0].ID != bobs || due[1].ID != early {
+		t.Fatalf("Due = %+v, %v; want bob's and alice's email reminders that are due", due, err)
+	}
+	if d := due[1]; d.Username != "alice" || d.Email != "alice@example.com" || d.Timezone != "UTC" || !d.Notifications ||
+		d.WorkoutTitle != "Legs" || d.WorkoutTime == nil || *d.WorkoutTime != "18:00" || d.WorkoutStatus != models.ScheduledStatusScheduled {
+		t.Errorf("due reminder = %+v", d)
+	}
+	if due, _ := s.Reminders().Due(nil, now, 10); len(due) != 0 {
+		t.Errorf("Due without channels = %+v", due)
+	}
+	if due, _ := s.Reminders().Due([]string{models.ReminderTypeEmail, models.ReminderTypeSMS}, now, 10); len(due) != 3 {
+		t.Errorf("Due for email and SMS = %d reminders, want 3", len(due))
+	}
+
+	// A claimed reminder is held back until its claim runs out, and only claimed once
+	if err := s.Reminders().Claim(early, now, now.Add(5*time.Minute)); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if err := s.Reminders().Claim(early, now, now.Add(5*time.Minute)); err != ErrNotFound {
+		t.Errorf("second Claim: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Reminders().Claim(later, now, now.Add(5*time.Minute)); err != ErrNotFound {
+		t.Errorf("Claim before the reminder is due: err = %v, want ErrNotFound", err)
+	}
+	if got := dueIDs(); len(got) != 1 || got[0] != bobs {
+		t.Errorf("Due while claimed = %v, want only bob's", got)
+	}
+
+	// A failed attempt waits for its retry
+	retry := now.Add(2 * time.Minute)
+	if err := s.Reminders().MarkFailed(early, "connection refused", &retry); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	rem, err := s.Reminders().Get(early, alice)
+	if err != nil || rem.Status != models.ReminderStatusPending || rem.Attempts != 1 || rem.ErrorMessage == nil ||
+		*rem.ErrorMessage != "connection refused" || rem.NextAttemptAt == nil || !rem.NextAttemptAt.Equal(retry) {
+		t.Errorf("reminder after a failed attempt = %+v, %v", rem, err)
+	}
+	if due, _ := s.Reminders().Due([]string{models.ReminderTypeEmail}, retry, 10); len(due) != 2 || due[1].ID != early || due[1].Attempts != 1 {
+		t.Errorf("Due at the retry = %+v, want the failed reminder back", due)
+	}
+	if err := s.Reminders().Claim(early, retry, retry.Add(5*time.Minute)); err != nil {
+		t.Fatalf("Claim at the retry: %v", err)
+	}
+	if err := s.Reminders().MarkSent(early, retry); err != nil {
+		t.Fatalf("MarkSent: %v", err)
+	}
+	rem, err = s.Reminders().Get(early, alice)
+	if err != nil || rem.Status != models.ReminderStatusSent || rem.SentAt == nil || !rem.SentAt.Equal(retry) || rem.Attempts != 2 || rem.ErrorMessage != nil {
+		t.Errorf("sent reminder = %+v, %v", rem, err)
+	}
+	if sw, err := s.ScheduledWorkouts().Get(legs, alice); err != nil || !sw.ReminderSent {
+		t.Errorf("scheduled workout after its reminder = %+v, %v; want it reminded", sw, err)
+	}
+
+	if err := s.Reminders().MarkFailed(sms, "no SMS gateway", nil); err != nil {
+		t.Fatalf("MarkFailed for good: %v", err)
+	}
+	if err := s.Reminders().Cancel(bobs, "notifications are turned off"); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if rem, _ := s.Reminders().Get(sms, alice); rem.Status != models.ReminderStatusFailed || rem.NextAttemptAt != nil {
+		t.Errorf("failed reminder = %+v", rem)
+	}
+	if rem, _ := s.Reminders().Get(bobs, bob); rem.Status != models.ReminderStatusCancelled || rem.ErrorMessage == nil {
+		t.Errorf("cancelled reminder = %+v", rem)
+	}
+	if got := dueIDs(); len(got) != 0 {
+		t.Errorf("Due after every reminder was handled = %v", got)
+	}
+
+	if err := s.Reminders().Delete(later, bob); err != ErrNotFound {
+		t.Errorf("Delete of another user's reminder: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Reminders().Delete(later, alice); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Reminders().Get(later, alice); err != ErrNotFound {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+
+	// Rescheduling moves pending reminders with minutes before to the workout's start in
+	// the given zone, and leaves the others alone
+	tokyo := time.FixedZone("JST", 9*60*60)
+	minutes := 90
+	following, err := s.Reminders().Create(models.WorkoutReminder{UserID: alice, ScheduledWorkoutID: legs, ReminderType: models.ReminderTypeEmail,
+		Message: "Go", ScheduledFor: now.Add(3 * time.Hour), MinutesBefore: &minutes})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	fixed := create(alice, legs, models.ReminderTypeEmail, now.Add(4*time.Hour))
+	if err := s.Reminders().Reschedule(alice, tokyo); err != nil {
+		t.Fatalf("Reschedule: %v", err)
+	}
+	// 18:00 on the 16th in Tokyo is 09:00 UTC
+	if rem, _ := s.Reminders().Get(following, alice); !rem.ScheduledFor.Equal(time.Date(2026, 10, 16, 7, 30, 0, 0, time.UTC)) ||
+		rem.MinutesBefore == nil || *rem.MinutesBefore != 90 {
+		t.Errorf("rescheduled reminder = %+v, want it 90 minutes before 18:00 in Tokyo", rem)
+	}
+	if rem, _ := s.Reminders().Get(fixed, alice); !rem.ScheduledFor.Equal(now.Add(4 * time.Hour)) {
+		t.Errorf("reminder without minutes before moved to %v", rem.ScheduledFor)
+	}
+	if rem, _ := s.Reminders().Get(early, alice); rem.ScheduledFor.Equal(time.Date(2026, 10, 16, 7, 30, 0, 0, time.UTC)) {
+		t.Errorf("sent reminder was rescheduled")
+	}
+
+	// Deleting the scheduled workout deletes its reminders
+	if err := s.ScheduledWorkouts().Delete(legs, alice); err != nil {
+		t.Fatalf("failed to delete scheduled workout: %v", err)
+	}
+	if _, err := s.Reminders().Get(sms, alice); err != ErrNotFound {
+		t.Errorf("reminder of a deleted workout survived: err = %v", err)
+	}
+}
+
+func testUserDeleteCascades(t *testing.T, s Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+
+	aliceWorkout := createWorkout(t, s, alice, "Push", time.Now(), 60)
+	bobWorkout := createWorkout(t, s, bob, "Pull", time.Now(), 60)
+	if _, err := s.Templates().Create(models.WorkoutTemplate{UserID: alice, Name: "Template"}); err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+	programID, err := s.Programs().Create(models.WorkoutProgram{Name: "5x5", IsPublic: true, CreatedBy: alice})
+	if err != nil {
+		t.Fatalf("failed to create program: %v", err)
+	}
+	if _, err := s.BodyMetrics().CreateWeight(models.BodyWeight{UserID: alice, Weight: 80, Unit: "kg", Date: time.Now()}); err != nil {
+		t.Fatalf("failed to create body weight: %v", err)
+	}
+	sessionID, err := s.Sessions().Create(models.WorkoutSession{UserID: alice, Name: "Session", StartedAt: time.Now(), Exercises: []models.SessionExercise{{Name: "Squat", Category: "Legs"}}})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	if _, err := s.RestDays().Suggest(models.RestDayRecommendation{UserID: alice, RecommendedDate: time.Now(), Reason: models.RestDayReasonHighVolume}); err != nil {
+		t.Fatalf("failed to suggest rest day: %v", err)
+	}
+	if _, err := s.Deloads().Create(models.DeloadRecommendation{UserID: alice, RecommendedStartDate: time.Now(), RecommendedEndDate: time.Now(), Reason: models.DeloadReasonScheduled}); err != nil {
+		t.Fatalf("failed to create deload: %v", err)
+	}
+	scheduledID, err := s.ScheduledWorkouts().Create(models.ScheduledWorkout{UserID: alice, Title: "Legs", ScheduledDate: time.Now()})
+	if err != nil {
+		t.Fatalf("failed to schedule workout: %v", err)
+	}
+	if _, err := s.CalendarFeeds().Replace(models.CalendarFeed{UserID: alice, Prefix: "wtcal_a", TokenHash: "alice-hash", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("failed to create calendar feed: %v", err)
+	}
+	reminderID, err := s.Reminders().Create(models.WorkoutReminder{UserID: alice, ScheduledWorkoutID: scheduledID, ReminderType: models.ReminderTypeEmail, Message: "Legs", ScheduledFor: time.Now()})
+	if err != nil {
+		t.Fatalf("failed to create reminder: %v", err)
+	}
+	eventID, err := s.CalendarEvents().Create(models.WorkoutCalendarEvent{UserID: alice, EventType: models.CalendarEventWorkout, Title: "Legs", StartDate: time.Now()})
+	if err != nil {
+		t.Fatalf("failed to create calendar event: %v", err)
+	}
+
+	if err := s.Users().Delete(alice); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Users().Delete(alice); err != ErrNotFound {
+		t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+	}
+
+	if _, err := s.Workouts().OwnerID(aliceWorkout); err != ErrNotFound {
+		t.Errorf("deleted user's workout survived: err = %v", err)
+	}
+	if templates, _ := s.Templates().ListByUser(alice); len(templates) != 0 {
+		t.Errorf("deleted user's templates survived: %+v", templates)
+	}
+	if weights, _ := s.BodyMetrics().WeightsByUser(alice); len(weights) != 0 {
+		t.Errorf("deleted user's body weights survived: %+v", weights)
+	}
+	if _, err := s.Sessions().Get(sessionID, alice); err != ErrNotFound {
+		t.Errorf("deleted user's session survived: err = %v", err)
+	}
+	if records, _ := s.PersonalRecords().List(alice, ""); len(records) != 0 {
+		t.Errorf("deleted user's personal records survived: %+v", records)
+	}
+	if recs, _ := s.RestDays().List(alice, time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 1), ""); len(recs) != 0 {
+		t.Errorf("deleted user's rest days survived: %+v", recs)
+	}
+	if _, err := s.Deloads().Latest(alice); err != ErrNotFound {
+		t.Errorf("deleted user's deloads survived: err = %v", err)
+	}
+	if _, err := s.ScheduledWorkouts().Get(scheduledID, alice); err != ErrNotFound {
+		t.Errorf("deleted user's scheduled workout survived: err = %v", err)
+	}
+	if _, err := s.CalendarEvents().Get(eventID, alice); err != ErrNotFound {
+		t.Errorf("deleted user's calendar event survived: err = %v", err)
+	}
+	if _, err := s.CalendarFeeds().GetByHash("alice-hash"); err != ErrNotFound {
+		t.Errorf("deleted user's calendar feed survived: err = %v", err)
+	}
+	if _, err := s.Reminders().Get(reminderID, alice); err != ErrNotFound {
+		t.Errorf("deleted user's reminder survived: err = %v", err)
+	}
+	if days, _ := s.TrainingLoad().Days(alice, time.Now(), time.Now()); len(days) != 1 || days[0].Fitness != 0 {
+		t.Errorf("deleted user's training load survived: %+v", days)
+	}
+	if _, err := s.Workouts().Get(bobWorkout, bob); err != nil {
+		t.Errorf("other user's workout was removed: %v", err)
+	}
+	// Others may follow the deleted user's public program, so it stays without a creator
+	if program, err := s.Programs().Get(programID); err != nil || program.CreatedBy != 0 {
+		t.Errorf("deleted user's program = %+v, %v; want it kept without a creator", program, err)
+	}
+}
+
+// workoutNames returns the names of the workouts in order
+func workoutNames(workouts []models.Workout) []string {
+	var names []string
+	for _, w := range workouts {
+		names = append(names, w.Name)
+	}
+	return names
+}
+
+// exerciseNames returns the names of the exercises in order
+func exerciseNames(exercises []models.Exercise) []string {
+	var names []string
+	for _, e := range exercises {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+// equalStrings reports whether two string slices hold the same values in order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
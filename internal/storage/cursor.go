@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// workoutSortColumns maps listing sort keys to the column they order by
+var workoutSortColumns = map[string]string{
+	"date":     "w.date",
+	"name":     "w.name",
+	"duration": "w.duration",
+}
+
+// WorkoutCursor is the decoded form of a workout listing cursor
+type WorkoutCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// EncodeWorkoutCursor builds the opaque cursor pointing just past a workout
+func EncodeWorkoutCursor(c WorkoutCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeWorkoutCursor parses a cursor produced by EncodeWorkoutCursor
+func DecodeWorkoutCursor(cursor string) (WorkoutCursor, error) {
+	var c WorkoutCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	return c, nil
+}
+
+// NormalizeWorkoutSort validates a sort parameter and returns it in canonical form, newest first by default
+func NormalizeWorkoutSort(sort string) (string, error) {
+	if sort == "" {
+		return "-date", nil
+	}
+	if _, ok := workoutSortColumns[strings.TrimPrefix(sort, "-")]; !ok {
+		return "", fmt.Errorf("invalid sort: %s", sort)
+	}
+	return sort, nil
+}
+
+// escapeLike escapes LIKE wildcards so search terms match literally
+func escapeLike(term string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(term)
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// deloadRepo is the SQL implementation of DeloadRepository
+type deloadRepo struct {
+	*sqlStore
+}
+
+// deloadColumns are the columns of deload_recommendations in the order scanDeload reads them
+const deloadColumns = `id, user_id, recommended_start_date, recommended_end_date, reason, volume_reduction_percent,
+	intensity_reduction_percent, COALESCE(trigger_metrics, ''), status, user_response, started_at, completed_at,
+	created_at, updated_at`
+
+// acceptedDeload matches the deload recommendations a user has accepted
+const acceptedDeload = `status = '` + models.DeloadStatusAccepted + `'`
+
+// Create stores a deload suggestion and returns its ID
+func (r *deloadRepo) Create(rec models.DeloadRecommendation) (int, error) {
+	query := `
+		INSERT INTO deload_recommendations (user_id, recommended_start_date, recommended_end_date, reason,
+			volume_reduction_percent, intensity_reduction_percent, trigger_metrics, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	id, err := r.db.Insert(query, rec.UserID, calendarDate(rec.RecommendedStartDate), calendarDate(rec.RecommendedEndDate),
+		rec.Reason, rec.VolumeReductionPercent, rec.IntensityReductionPercent, rec.TriggerMetrics, models.DeloadStatusSuggested,
+		time.Now(), time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create deload recommendation: %v", err)
+	}
+	return id, nil
+}
+
+// Get returns a recommendation owned by the given user
+func (r *deloadRepo) Get(id, userID int) (models.DeloadRecommendation, error) {
+	query := `SELECT ` + deloadColumns + ` FROM deload_recommendations WHERE id = ? AND user_id = ?`
+	return scanDeload(r.db.QueryRow(query, id, userID))
+}
+
+// Latest returns the user's recommendation starting last
+func (r *deloadRepo) Latest(userID int) (models.DeloadRecommendation, error) {
+	query := `SELECT ` + deloadColumns + ` FROM deload_recommendations WHERE user_id = ?
+		ORDER BY recommended_start_date DESC, id DESC LIMIT 1`
+	return scanDeload(r.db.QueryRow(query, userID))
+}
+
+// List returns the user's recommendations whose window overlaps the period, earliest
+// first, only those with the given status unless it is empty
+func (r *deloadRepo) List(userID int, from, to time.Time, status string) ([]models.DeloadRecommendation, error) {
+	query := `SELECT ` + deloadColumns + ` FROM deload_recommendations
+		WHERE user_id = ? AND recommended_end_date >= ? AND recommended_start_date <= ?`
+	args := []interface{}{userID, calendarDate(from), calendarDate(to)}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY recommended_start_date, id`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deload recommendations: %v", err)
+	}
+	defer rows.Close()
+
+	recs := []models.DeloadRecommendation{}
+	for rows.Next() {
+		rec, err := scanDeload(rows)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
+
+// Respond records the user's answer to a recommendation in one transaction with its
+// effect on the scheduled template workouts in its window: accepting links them to it,
+// which scales their targets down, and any other answer unlinks them again
+func (r *deloadRepo) Respond(id, userID int, status, response string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	rec, err := scanDeload(tx.QueryRow(`SELECT `+deloadColumns+` FROM deload_recommendations WHERE id = ? AND user_id = ?`, id, userID))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE deload_recommendations SET status = ?, user_response = ?, updated_at = ? WHERE id = ?`,
+		status, response, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to respond to deload recommendation: %v", err)
+	}
+
+	if status == models.DeloadStatusAccepted {
+		query := `
+			UPDATE scheduled_workouts SET deload_id = ?, updated_at = ?
+			WHERE user_id = ? AND template_id IS NOT NULL AND status = 'scheduled'
+			  AND scheduled_date >= ? AND scheduled_date < ?
+		`
+		_, err = tx.Exec(query, id, time.Now(), userID, rec.RecommendedStartDate, rec.RecommendedEndDate.AddDate(0, 0, 1))
+	} else {
+		_, err = tx.Exec(`UPDATE scheduled_workouts SET deload_id = NULL, updated_at = ? WHERE deload_id = ?`, time.Now(), id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply deload to scheduled workouts: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// TrainingSince returns the day the user's current stretch of training began: the day
+// after their last accepted deload ending before the given day or, without one, the day
+// of their first workout. It returns ErrNotFound if the user has no workouts.
+func (r *deloadRepo) TrainingSince(userID int, day time.Time) (time.Time, error) {
+	var date time.Time
+	query := `SELECT recommended_end_date FROM deload_recommendations WHERE user_id = ? AND ` + acceptedDeload + `
+		AND recommended_end_date < ? ORDER BY recommended_end_date DESC LIMIT 1`
+	err := r.db.QueryRow(query, userID, calendarDate(day)).Scan(&date)
+	if err == nil {
+		return calendarDate(date).AddDate(0, 0, 1), nil
+	}
+	if err = notFound(err); err != ErrNotFound {
+		return date, fmt.Errorf("failed to load last deload: %v", err)
+	}
+
+	err = r.db.QueryRow(`SELECT date FROM workouts WHERE user_id = ? ORDER BY date LIMIT 1`, userID).Scan(&date)
+	return calendarDate(date), notFound(err)
+}
+
+// Compliance counts the user's answered recommendations, the accepted ones among them
+// and the accepted ones whose window includes the given day
+func (r *deloadRepo) Compliance(userID int, day time.Time) (answered, accepted, active int, err error) {
+	day = calendarDate(day)
+	query := `
+		SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN ` + acceptedDeload + ` THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN ` + acceptedDeload + ` AND recommended_start_date <= ? AND recommended_end_date >= ? THEN 1 ELSE 0 END), 0)
+		FROM deload_recommendations
+		WHERE user_id = ? AND status <> ?
+	`
+	err = r.db.QueryRow(query, day, day, userID, models.DeloadStatusSuggested).Scan(&answered, &accepted, &active)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to count deload compliance: %v", err)
+	}
+	return answered, accepted, active, nil
+}
+
+// scanDeload reads a row selecting deloadColumns
+func scanDeload(row rowScanner) (models.DeloadRecommendation, error) {
+	var rec models.DeloadRecommendation
+	err := row.Scan(&rec.ID, &rec.UserID, &rec.RecommendedStartDate, &rec.RecommendedEndDate, &rec.Reason,
+		&rec.VolumeReductionPercent, &rec.IntensityReductionPercent, &rec.TriggerMetrics, &rec.Status, &rec.UserResponse,
+		&rec.StartedAt, &rec.CompletedAt, &rec.CreatedAt, &rec.UpdatedAt)
+	if err != nil {
+		return rec, notFound(err)
+	}
+	rec.RecommendedStartDate = rec.RecommendedStartDate.UTC()
+	rec.RecommendedEndDate = rec.RecommendedEndDate.UTC()
+	return rec, nil
+}
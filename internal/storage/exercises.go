@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// exerciseRepo is the SQL implementation of ExerciseRepository
+type exerciseRepo struct {
+	*sqlStore
+}
+
+// Create adds an exercise to the end of its workout and returns its ID
+func (r *exerciseRepo) Create(exercise models.Exercise) (int, error) {
+	query := `
+		INSERT INTO exercises (workout_id, name, category, order_index, group_id, predefined_exercise_id, created_at, updated_at)
+		VALUES (?, ?, ?, (SELECT COALESCE(MAX(order_index) + 1, 0) FROM exercises WHERE workout_id = ?), ?, ` + linkPredefinedExercise + `, ?, ?)
+	`
+
+	return r.db.Insert(query, exercise.WorkoutID, exercise.Name, exercise.Category, exercise.WorkoutID, exercise.GroupID,
+		exercise.PredefinedExerciseID, exercise.Name, time.Now(), time.Now())
+}
+
+// Update updates an existing exercise. Renaming it moves its sets to the records of
+// the new name, so the records of both names are rebuilt.
+func (r *exerciseRepo) Update(exercise models.Exercise) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, name, _, err := exerciseRecordKey(tx, exercise.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE exercises
+		SET name = ?, category = ?, predefined_exercise_id = ` + linkPredefinedExercise + `, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := tx.Exec(query, exercise.Name, exercise.Category, exercise.PredefinedExerciseID, exercise.Name, time.Now(), exercise.ID)
+	if err != nil {
+		return err
+	}
+	if err := requireRows(result); err != nil {
+		return err
+	}
+
+	if err := rebuildRecords(tx, userID, name, exercise.Name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete deletes an exercise and all associated sets
+func (r *exerciseRepo) Delete(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, name, date, err := exerciseRecordKey(tx, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM exercises WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if err := requireRows(result); err != nil {
+		return err
+	}
+
+	if err := rebuildRecords(tx, userID, name); err != nil {
+		return err
+	}
+	if err := rebuildTrainingLoad(tx, userID, date); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ByWorkoutIDs loads the exercises and sets for many workouts in a single query
+func (r *exerciseRepo) ByWorkoutIDs(workoutIDs []int) (map[int][]models.Exercise, error) {
+	result := make(map[int][]models.Exercise)
+	if len(workoutIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(workoutIDs)), ",")
+	args := make([]interface{}, len(workoutIDs))
+	for i, id := range workoutIDs {
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`
+		SELECT e.id, e.workout_id, e.name, e.category, e.order_index, e.group_id, e.predefined_exercise_id, e.created_at, e.updated_at,
+		       s.id, s.set_number, s.reps, s.weight, s.distance, s.duration, s.rest_time,
+		       s.rpe, s.rir, s.set_type, s.notes, s.created_at, s.updated_at
+		FROM exercises e
+		LEFT JOIN sets s ON s.exercise_id = e.id
+		WHERE e.workout_id IN (%s)
+		ORDER BY e.workout_id, e.order_index ASC, e.id ASC, s.set_number ASC, s.id ASC
+	`, placeholders)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Position of each exercise within its workout's slice
+	positions := make(map[int]int)
+	for rows.Next() {
+		var e models.Exercise
+		var predefinedExerciseID sql.NullInt64
+		var setID, setNumber, reps, duration, restTime sql.NullInt64
+		var weight, distance, rpe sql.NullFloat64
+		var rir sql.NullInt64
+		var setType, notes sql.NullString
+		var setCreatedAt, setUpdatedAt sql.NullTime
+		err := rows.Scan(&e.ID, &e.WorkoutID, &e.Name, &e.Category, &e.OrderIndex, &e.GroupID, &predefinedExerciseID, &e.CreatedAt, &e.UpdatedAt,
+			&setID, &setNumber, &reps, &weight, &distance, &duration, &restTime,
+			&rpe, &rir, &setType, &notes, &setCreatedAt, &setUpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if predefinedExerciseID.Valid {
+			id := int(predefinedExerciseID.Int64)
+			e.PredefinedExerciseID = &id
+		}
+
+		pos, seen := positions[e.ID]
+		if !seen {
+			pos = len(result[e.WorkoutID])
+			positions[e.ID] = pos
+			result[e.WorkoutID] = append(result[e.WorkoutID], e)
+		}
+
+		// Exercises without sets come back with NULL set columns
+		if !setID.Valid {
+			continue
+		}
+		exercise := &result[e.WorkoutID][pos]
+		set := models.Set{
+			ID:         int(setID.Int64),
+			ExerciseID: e.ID,
+			SetNumber:  int(setNumber.Int64),
+			Reps:       int(reps.Int64),
+			Weight:     weight.Float64,
+			Distance:   distance.Float64,
+			Duration:   int(duration.Int64),
+			RestTime:   int(restTime.Int64),
+			SetType:    setType.String,
+			Notes:      notes.String,
+			CreatedAt:  setCreatedAt.Time,
+			UpdatedAt:  setUpdatedAt.Time,
+		}
+		if rpe.Valid {
+			set.RPE = &rpe.Float64
+		}
+		if rir.Valid {
+			value := int(rir.Int64)
+			set.RIR = &value
+		}
+		exercise.Sets = append(exercise.Sets, set)
+	}
+
+	return result, rows.Err()
+}
+
+// OwnerID resolves an exercise through its workout to the owning user
+func (r *exerciseRepo) OwnerID(id int) (int, error) {
+	var userID int
+	query := `
+		SELECT w.user_id
+		FROM exercises e
+		JOIN workouts w ON e.workout_id = w.id
+		WHERE e.id = ?
+	`
+	err := r.db.QueryRow(query, id).Scan(&userID)
+	return userID, notFound(err)
+}
+
+// Reorder gives a workout's exercises the order and groups listed, in one transaction
+func (r *exerciseRepo) Reorder(workoutID int, order []models.ExerciseOrder) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE exercises
+		SET order_index = ?, group_id = ?, updated_at = ?
+		WHERE id = ? AND workout_id = ?
+	`
+	for index, exercise := range order {
+		result, err := tx.Exec(query, index, exercise.GroupID, time.Now(), exercise.ID, workoutID)
+		if err != nil {
+			return fmt.Errorf("failed to reorder exercise %d: %v", exercise.ID, err)
+		}
+		if err := requireRows(result); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CreateSet creates a new set, updates the exercise's personal records and returns the set's ID
+func (r *exerciseRepo) CreateSet(set models.Set) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	userID, name, date, err := exerciseRecordKey(tx, set.ExerciseID)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO sets (exercise_id, set_number, reps, weight, distance, duration, rest_time, rpe, rir, set_type, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	id, err := tx.Insert(query, set.ExerciseID, set.SetNumber, set.Reps, set.Weight, set.Distance, set.Duration, set.RestTime,
+		set.RPE, set.RIR, models.SetTypeOrDefault(set.SetType), set.Notes, time.Now(), time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	if err := rebuildRecords(tx, userID, name); err != nil {
+		return 0, err
+	}
+	if err := rebuildTrainingLoad(tx, userID, date); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// UpdateSet updates an existing set and the exercise's personal records
+func (r *exerciseRepo) UpdateSet(set models.Set) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, name, date, err := setRecordKey(tx, set.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE sets
+		SET set_number = ?, reps = ?, weight = ?, distance = ?, duration = ?, rest_time = ?,
+		    rpe = ?, rir = ?, set_type = ?, notes = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := tx.Exec(query, set.SetNumber, set.Reps, set.Weight, set.Distance, set.Duration, set.RestTime,
+		set.RPE, set.RIR, models.SetTypeOrDefault(set.SetType), set.Notes, time.Now(), set.ID)
+	if err != nil {
+		return err
+	}
+	if err := requireRows(result); err != nil {
+		return err
+	}
+
+	if err := rebuildRecords(tx, userID, name); err != nil {
+		return err
+	}
+	if err := rebuildTrainingLoad(tx, userID, date); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteSet deletes a specific set and updates the exercise's personal records
+func (r *exerciseRepo) DeleteSet(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, name, date, err := setRecordKey(tx, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM sets WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if err := requireRows(result); err != nil {
+		return err
+	}
+
+	if err := rebuildRecords(tx, userID, name); err != nil {
+		return err
+	}
+	if err := rebuildTrainingLoad(tx, userID, date); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetOwnerID resolves a set through its exercise and workout to the owning user
+func (r *exerciseRepo) SetOwnerID(id int) (int, error) {
+	var userID int
+	query := `
+		SELECT w.user_id
+		FROM sets s
+		JOIN exercises e ON s.exercise_id = e.id
+		JOIN workouts w ON e.workout_id = w.id
+		WHERE s.id = ?
+	`
+	err := r.db.QueryRow(query, id).Scan(&userID)
+	return userID, notFound(err)
+}
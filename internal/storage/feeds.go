@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// calendarFeedRepo is the SQL implementation of CalendarFeedRepository
+type calendarFeedRepo struct {
+	*sqlStore
+}
+
+// calendarFeedColumns are the columns of calendar_feeds in the order scanCalendarFeed reads them
+const calendarFeedColumns = `id, user_id, prefix, token_hash, last_used_at, created_at`
+
+// Get returns the user's feed
+func (r *calendarFeedRepo) Get(userID int) (models.CalendarFeed, error) {
+	query := `SELECT ` + calendarFeedColumns + ` FROM calendar_feeds WHERE user_id = ?`
+	return scanCalendarFeed(r.db.QueryRow(query, userID))
+}
+
+// GetByHash returns the feed whose token has the given hash
+func (r *calendarFeedRepo) GetByHash(tokenHash string) (models.CalendarFeed, error) {
+	query := `SELECT ` + calendarFeedColumns + ` FROM calendar_feeds WHERE token_hash = ?`
+	return scanCalendarFeed(r.db.QueryRow(query, tokenHash))
+}
+
+// Replace stores a user's feed in place of the one they had in one transaction, so the
+// old token stops working as the new one starts, and returns the new feed's ID
+func (r *calendarFeedRepo) Replace(feed models.CalendarFeed) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM calendar_feeds WHERE user_id = ?`, feed.UserID); err != nil {
+		return 0, fmt.Errorf("failed to delete calendar feed: %v", err)
+	}
+	id, err := tx.Insert(`INSERT INTO calendar_feeds (user_id, prefix, token_hash, created_at) VALUES (?, ?, ?, ?)`,
+		feed.UserID, feed.Prefix, feed.TokenHash, feed.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create calendar feed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit calendar feed: %v", err)
+	}
+	return id, nil
+}
+
+// Touch records when a feed was last fetched
+func (r *calendarFeedRepo) Touch(id int, at time.Time) error {
+	if _, err := r.db.Exec(`UPDATE calendar_feeds SET last_used_at = ? WHERE id = ?`, at, id); err != nil {
+		return fmt.Errorf("failed to record calendar feed use: %v", err)
+	}
+	return nil
+}
+
+// Delete removes the user's feed, so its token stops working
+func (r *calendarFeedRepo) Delete(userID int) error {
+	result, err := r.db.Exec(`DELETE FROM calendar_feeds WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete calendar feed: %v", err)
+	}
+	return requireRows(result)
+}
+
+// scanCalendarFeed reads a row selecting calendarFeedColumns
+func scanCalendarFeed(row rowScanner) (models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := row.Scan(&feed.ID, &feed.UserID, &feed.Prefix, &feed.TokenHash, &feed.LastUsedAt, &feed.CreatedAt)
+	if err != nil {
+		return feed, notFound(err)
+	}
+	return feed, nil
+}
@@ -0,0 +1,305 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"workout-tracker/internal/load"
+	"workout-tracker/internal/models"
+)
+
+// trainingLoadRepo is the SQL implementation of TrainingLoadRepository
+type trainingLoadRepo struct {
+	*sqlStore
+}
+
+// trainingLoadColumns are the columns of training_load in the order scanLoadDays reads them
+const trainingLoadColumns = `date, load, sessions, acute_load, chronic_load, acwr, monotony, strain, fitness, fatigue, performance`
+
+// Days returns the user's training load for every day from one date to another. Days
+// after the last workout are continued as rest days.
+func (r *trainingLoadRepo) Days(userID int, from, to time.Time) ([]models.TrainingLoadDay, error) {
+	from, to = calendarDate(from), calendarDate(to)
+	history, err := loadHistory(r.db, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + trainingLoadColumns + ` FROM training_load WHERE user_id = ? AND date >= ? AND date <= ? ORDER BY date`
+	stored, err := scanLoadDays(r.db, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []models.TrainingLoadDay
+	if len(stored) > 0 {
+		// Before the first workout there was no load at all
+		for d := from; d.Before(calendarDate(stored[0].Date)); d = d.AddDate(0, 0, 1) {
+			days = append(days, models.TrainingLoadDay{Date: d})
+		}
+		days = append(days, stored...)
+		history = append(history, stored...)
+	}
+
+	m := load.Resume(toModelDays(history), from)
+	for !m.Next().After(to) {
+		day := m.Step(0, 0)
+		if !day.Date.Before(from) {
+			days = append(days, fromModelDay(day))
+		}
+	}
+	return days, nil
+}
+
+// Sessions returns the load and volume of the user's workouts from the start date on, oldest first
+func (r *trainingLoadRepo) Sessions(userID int, start time.Time) ([]models.TrainingSession, error) {
+	return trainingSessions(r.db, userID, start)
+}
+
+// Rebuild recomputes every day of the user's training load
+func (r *trainingLoadRepo) Rebuild(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := rebuildTrainingLoad(tx, userID, time.Time{}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RebuildAll recomputes the training load of every user and returns how many users it covered
+func (r *trainingLoadRepo) RebuildAll() (int, error) {
+	userIDs, err := allUserIDs(r.db)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range userIDs {
+		if err := r.Rebuild(id); err != nil {
+			return 0, fmt.Errorf("failed to rebuild training load of user %d: %v", id, err)
+		}
+	}
+	return len(userIDs), nil
+}
+
+// RebuildMissing recomputes the training load of users with workouts but no training
+// load, such as after upgrading from a version that did not keep it, and returns how
+// many users it covered
+func (r *trainingLoadRepo) RebuildMissing() (int, error) {
+	query := `
+		SELECT DISTINCT w.user_id
+		FROM workouts w
+		WHERE NOT EXISTS (SELECT 1 FROM training_load t WHERE t.user_id = w.user_id)
+		ORDER BY w.user_id
+	`
+	userIDs, err := queryIDs(r.db, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find users without training load: %v", err)
+	}
+	for _, id := range userIDs {
+		if err := r.Rebuild(id); err != nil {
+			return 0, fmt.Errorf("failed to rebuild training load of user %d: %v", id, err)
+		}
+	}
+	return len(userIDs), nil
+}
+
+// rebuildTrainingLoad recomputes the user's training load from the given date on. Days
+// are kept from the first workout to the last, so the load of every earlier day stands.
+func rebuildTrainingLoad(q querier, userID int, since time.Time) error {
+	start := calendarDate(since)
+	history, err := loadHistory(q, userID, start)
+	if err != nil {
+		return err
+	}
+	if len(history) > 0 {
+		// Days between the last stored day and the change were never stored
+		if next := calendarDate(history[len(history)-1].Date).AddDate(0, 0, 1); next.Before(start) {
+			start = next
+		}
+	}
+
+	if _, err := q.Exec(`DELETE FROM training_load WHERE user_id = ? AND date >= ?`, userID, start); err != nil {
+		return fmt.Errorf("failed to clear training load: %v", err)
+	}
+
+	sessions, err := trainingSessions(q, userID, start)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		return nil
+	}
+	if len(history) == 0 {
+		start = calendarDate(sessions[0].Date)
+	}
+
+	type daily struct {
+		load     float64
+		sessions int
+	}
+	days := make(map[time.Time]daily)
+	for _, s := range sessions {
+		d := days[calendarDate(s.Date)]
+		d.load += s.Load
+		d.sessions++
+		days[calendarDate(s.Date)] = d
+	}
+
+	insert := `
+		INSERT INTO training_load (user_id, ` + trainingLoadColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	last := calendarDate(sessions[len(sessions)-1].Date)
+	m := load.Resume(toModelDays(history), start)
+	for !m.Next().After(last) {
+		today := days[m.Next()]
+		d := m.Step(today.load, today.sessions)
+		_, err := q.Exec(insert, userID, d.Date, d.Load, d.Sessions, d.Acute, d.Chronic, d.ACWR, d.Monotony, d.Strain,
+			d.Fitness, d.Fatigue, d.Performance)
+		if err != nil {
+			return fmt.Errorf("failed to save training load: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// loadHistory returns the stored days just before a date that the load model resumes from, oldest first
+func loadHistory(q querier, userID int, before time.Time) ([]models.TrainingLoadDay, error) {
+	query := fmt.Sprintf(`SELECT %s FROM training_load WHERE user_id = ? AND date < ? ORDER BY date DESC LIMIT %d`,
+		trainingLoadColumns, load.AcuteDays)
+	days, err := scanLoadDays(q, query, userID, before)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(days)-1; i < j; i, j = i+1, j-1 {
+		days[i], days[j] = days[j], days[i]
+	}
+	return days, nil
+}
+
+// scanLoadDays runs a query selecting trainingLoadColumns
+func scanLoadDays(q querier, query string, args ...interface{}) ([]models.TrainingLoadDay, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load training load: %v", err)
+	}
+	defer rows.Close()
+
+	var days []models.TrainingLoadDay
+	for rows.Next() {
+		var d models.TrainingLoadDay
+		err := rows.Scan(&d.Date, &d.Load, &d.Sessions, &d.AcuteLoad, &d.ChronicLoad, &d.ACWR, &d.Monotony, &d.Strain,
+			&d.Fitness, &d.Fatigue, &d.Performance)
+		if err != nil {
+			return nil, err
+		}
+		d.Date = d.Date.UTC()
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// trainingSessions returns the load and volume of a user's workouts from the start date on, oldest first
+func trainingSessions(q querier, userID int, start time.Time) ([]models.TrainingSession, error) {
+	query := `
+		SELECT w.id, w.date, COALESCE(w.duration, 0), s.id, COALESCE(s.weight, 0), COALESCE(s.reps, 0), s.rpe, s.rir
+		FROM workouts w
+		LEFT JOIN exercises e ON e.workout_id = w.id
+		LEFT JOIN sets s ON s.exercise_id = e.id AND s.set_type <> ?
+		WHERE w.user_id = ? AND w.date >= ?
+		ORDER BY w.date, w.id
+	`
+
+	rows, err := q.Query(query, models.SetTypeWarmup, userID, start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workouts: %v", err)
+	}
+	defer rows.Close()
+
+	var sessions []models.TrainingSession
+	var sets []load.Set
+	finish := func() {
+		if len(sessions) == 0 {
+			return
+		}
+		s := &sessions[len(sessions)-1]
+		rpe := load.SessionRPE(sets)
+		s.Load = load.SessionLoad(float64(s.Duration), s.WorkingSets, rpe)
+		for _, set := range sets {
+			if set.RPE != nil || set.RIR != nil {
+				s.RPE = rpe
+				break
+			}
+		}
+		sets = sets[:0]
+	}
+
+	for rows.Next() {
+		var workoutID, duration, reps int
+		var date time.Time
+		var setID *int
+		var weight float64
+		var set load.Set
+		if err := rows.Scan(&workoutID, &date, &duration, &setID, &weight, &reps, &set.RPE, &set.RIR); err != nil {
+			return nil, err
+		}
+		if len(sessions) == 0 || sessions[len(sessions)-1].WorkoutID != workoutID {
+			finish()
+			sessions = append(sessions, models.TrainingSession{WorkoutID: workoutID, Date: date, Duration: duration})
+		}
+		if setID == nil {
+			continue
+		}
+		s := &sessions[len(sessions)-1]
+		s.WorkingSets++
+		s.Tonnage += weight * float64(reps)
+		sets = append(sets, set)
+	}
+	finish()
+
+	return sessions, rows.Err()
+}
+
+// workoutDate returns the date of a workout owned by the given user
+func workoutDate(q querier, workoutID, userID int) (time.Time, error) {
+	var date time.Time
+	err := q.QueryRow(`SELECT date FROM workouts WHERE id = ? AND user_id = ?`, workoutID, userID).Scan(&date)
+	return date, notFound(err)
+}
+
+// earlier returns the earlier of two times
+func earlier(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+// toModelDays converts stored days for the load model
+func toModelDays(days []models.TrainingLoadDay) []load.Day {
+	converted := make([]load.Day, len(days))
+	for i, d := range days {
+		converted[i] = load.Day{
+			Date: d.Date, Load: d.Load, Sessions: d.Sessions, Acute: d.AcuteLoad, Chronic: d.ChronicLoad, ACWR: d.ACWR,
+			Monotony: d.Monotony, Strain: d.Strain, Fitness: d.Fitness, Fatigue: d.Fatigue, Performance: d.Performance,
+		}
+	}
+	return converted
+}
+
+// fromModelDay converts a day computed by the load model
+func fromModelDay(d load.Day) models.TrainingLoadDay {
+	return models.TrainingLoadDay{
+		Date: d.Date, Load: d.Load, Sessions: d.Sessions, AcuteLoad: d.Acute, ChronicLoad: d.Chronic, ACWR: d.ACWR,
+		Monotony: d.Monotony, Strain: d.Strain, Fitness: d.Fitness, Fatigue: d.Fatigue, Performance: d.Performance,
+	}
+}
+
+// calendarDate returns the calendar date of t as midnight UTC
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// mealRepo is the SQL implementation of MealRepository
+type mealRepo struct {
+	*sqlStore
+}
+
+// Create creates a new meal entry and returns its ID
+func (r *mealRepo) Create(meal models.Meal) (int, error) {
+	query := `
+		INSERT INTO meals (user_id, name, calories, protein, carbs, fat, date, meal_type, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, meal.UserID, meal.Name, meal.Calories, meal.Protein, meal.Carbs, meal.Fat, meal.Date, meal.MealType, time.Now(), time.Now())
+}
+
+// ByUserAndDate returns the meals a user logged on the day of the given date
+func (r *mealRepo) ByUserAndDate(userID int, date time.Time) ([]models.Meal, error) {
+	query := `
+		SELECT id, user_id, name, calories, protein, carbs, fat, date, meal_type, created_at, updated_at
+		FROM meals
+		WHERE user_id = ? AND ` + r.dialect.dateOf("date") + ` = ` + r.dialect.dateOf("?") + `
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(query, userID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var meals []models.Meal
+	for rows.Next() {
+		var m models.Meal
+		err := rows.Scan(&m.ID, &m.UserID, &m.Name, &m.Calories, &m.Protein, &m.Carbs, &m.Fat, &m.Date, &m.MealType, &m.CreatedAt, &m.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		meals = append(meals, m)
+	}
+
+	return meals, rows.Err()
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// muscleRepo is the SQL implementation of MuscleRepository
+type muscleRepo struct {
+	*sqlStore
+}
+
+// linkPredefinedExercise is the library entry a logged exercise is linked to: the one
+// given by ID when it exists, otherwise the one of the same name. It takes the ID,
+// which may be NULL, and the exercise's name.
+const linkPredefinedExercise = `COALESCE(
+	(SELECT id FROM predefined_exercises WHERE id = ?),
+	(SELECT MIN(id) FROM predefined_exercises WHERE LOWER(name) = LOWER(?)))`
+
+// List returns the name of every muscle, alphabetically
+func (r *muscleRepo) List() ([]string, error) {
+	return queryNames(r.db, `SELECT name FROM muscles ORDER BY name`)
+}
+
+// ExerciseMuscles returns the muscles of every library exercise, primary muscles first
+func (r *muscleRepo) ExerciseMuscles() (map[int][]models.ExerciseMuscle, error) {
+	query := `
+		SELECT pem.predefined_exercise_id, m.name, pem.role
+		FROM predefined_exercise_muscles pem
+		JOIN muscles m ON pem.muscle_id = m.id
+		ORDER BY pem.predefined_exercise_id, pem.role, m.name
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	muscles := make(map[int][]models.ExerciseMuscle)
+	for rows.Next() {
+		var id int
+		var m models.ExerciseMuscle
+		if err := rows.Scan(&id, &m.Muscle, &m.Role); err != nil {
+			return nil, err
+		}
+		muscles[id] = append(muscles[id], m)
+	}
+	return muscles, rows.Err()
+}
+
+// LinkExercise sets the muscles of a library exercise from its free-text muscle list
+// and links the logged exercises of the same name that are not linked yet
+func (r *muscleRepo) LinkExercise(predefinedExerciseID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var name string
+	var muscleGroups sql.NullString
+	err = tx.QueryRow(`SELECT name, muscle_groups FROM predefined_exercises WHERE id = ?`, predefinedExerciseID).Scan(&name, &muscleGroups)
+	if err != nil {
+		return notFound(err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM predefined_exercise_muscles WHERE predefined_exercise_id = ?`, predefinedExerciseID); err != nil {
+		return fmt.Errorf("failed to clear exercise muscles: %v", err)
+	}
+	linked := make(map[int]bool)
+	for i, item := range strings.Split(muscleGroups.String, ",") {
+		var muscleID int
+		err := tx.QueryRow(`SELECT muscle_id FROM muscle_aliases WHERE alias = ?`, strings.ToLower(strings.TrimSpace(item))).Scan(&muscleID)
+		if err == sql.ErrNoRows || linked[muscleID] {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up muscle %q: %v", item, err)
+		}
+		linked[muscleID] = true
+
+		role := models.MuscleRoleSecondary
+		if i == 0 {
+			role = models.MuscleRolePrimary
+		}
+		_, err = tx.Exec(`INSERT INTO predefined_exercise_muscles (predefined_exercise_id, muscle_id, role) VALUES (?, ?, ?)`,
+			predefinedExerciseID, muscleID, role)
+		if err != nil {
+			return fmt.Errorf("failed to save exercise muscle: %v", err)
+		}
+	}
+
+	query := `
+		UPDATE exercises
+		SET predefined_exercise_id = ?
+		WHERE predefined_exercise_id IS NULL AND LOWER(name) = LOWER(?)
+	`
+	if _, err := tx.Exec(query, predefinedExerciseID, name); err != nil {
+		return fmt.Errorf("failed to link logged exercises: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// WorkingSets returns the user's working sets from the start date on, once for each
+// muscle their exercise trains
+func (r *muscleRepo) WorkingSets(userID int, start time.Time) ([]models.MuscleSet, error) {
+	query := `
+		SELECT e.name, COALESCE(m.name, ''), COALESCE(pem.role, ''), w.date, s.weight, s.reps, s.duration, s.rpe, s.rir
+		FROM sets s
+		JOIN exercises e ON s.exercise_id = e.id
+		JOIN workouts w ON e.workout_id = w.id
+		LEFT JOIN predefined_exercise_muscles pem ON pem.predefined_exercise_id = e.predefined_exercise_id
+		LEFT JOIN muscles m ON pem.muscle_id = m.id
+		WHERE w.user_id = ? AND w.date >= ? AND s.set_type <> ?
+		ORDER BY w.date, w.id, e.order_index, s.id
+	`
+
+	rows, err := r.db.Query(query, userID, start.Format("2006-01-02"), models.SetTypeWarmup)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sets []models.MuscleSet
+	for rows.Next() {
+		var s models.MuscleSet
+		err := rows.Scan(&s.ExerciseName, &s.Muscle, &s.Role, &s.Date, &s.Weight, &s.Reps, &s.Duration, &s.RPE, &s.RIR)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, s)
+	}
+	return sets, rows.Err()
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"workout-tracker/internal/database"
+)
+
+// postgresDialect is the SQL flavour of PostgreSQL
+type postgresDialect struct{}
+
+func (postgresDialect) equalFold(column string) string {
+	return "LOWER(" + column + ") = LOWER(?)"
+}
+
+func (postgresDialect) containsFold(column string) string {
+	return column + ` ILIKE ? ESCAPE '\'`
+}
+
+func (postgresDialect) dateOf(expr string) string {
+	return "CAST(" + expr + " AS DATE)"
+}
+
+// NewPostgres returns a store backed by a PostgreSQL database, which lets several
+// server replicas share one database
+func NewPostgres(db *database.DB) Store {
+	return &sqlStore{db: db, dialect: postgresDialect{}}
+}
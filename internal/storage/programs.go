@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// programRepo is the SQL implementation of ProgramRepository
+type programRepo struct {
+	*sqlStore
+}
+
+// ListPublic returns all public workout programs with their templates
+func (r *programRepo) ListPublic() ([]models.WorkoutProgram, error) {
+	query := `
+		SELECT id, name, description, difficulty, duration_weeks, goal, is_public, COALESCE(created_by, 0), created_at, updated_at
+		FROM workout_programs
+		WHERE is_public = ?
+		ORDER BY updated_at DESC
+	`
+
+	rows, err := r.db.Query(query, true)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var programs []models.WorkoutProgram
+	for rows.Next() {
+		var program models.WorkoutProgram
+		err := rows.Scan(&program.ID, &program.Name, &program.Description, &program.Difficulty, &program.DurationWeeks, &program.Goal, &program.IsPublic, &program.CreatedBy, &program.CreatedAt, &program.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		programs = append(programs, program)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range programs {
+		templates, err := r.Templates(programs[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		programs[i].Templates = templates
+	}
+
+	return programs, nil
+}
+
+// Get returns a workout program with its templates
+func (r *programRepo) Get(id int) (models.WorkoutProgram, error) {
+	var program models.WorkoutProgram
+
+	query := `
+		SELECT id, name, description, difficulty, duration_weeks, goal, is_public, COALESCE(created_by, 0), created_at, updated_at
+		FROM workout_programs
+		WHERE id = ?
+	`
+
+	err := r.db.QueryRow(query, id).Scan(&program.ID, &program.Name, &program.Description, &program.Difficulty, &program.DurationWeeks, &program.Goal, &program.IsPublic, &program.CreatedBy, &program.CreatedAt, &program.UpdatedAt)
+	if err != nil {
+		return program, notFound(err)
+	}
+
+	templates, err := r.Templates(id)
+	if err != nil {
+		return program, err
+	}
+	program.Templates = templates
+
+	return program, nil
+}
+
+// Create creates a new workout program and returns its ID
+func (r *programRepo) Create(program models.WorkoutProgram) (int, error) {
+	query := `
+		INSERT INTO workout_programs (name, description, difficulty, duration_weeks, goal, is_public, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, program.Name, program.Description, program.Difficulty, program.DurationWeeks, program.Goal, program.IsPublic, program.CreatedBy, time.Now(), time.Now())
+}
+
+// Update updates an existing workout program
+func (r *programRepo) Update(program models.WorkoutProgram) error {
+	query := `
+		UPDATE workout_programs
+		SET name = ?, description = ?, difficulty = ?, duration_weeks = ?, goal = ?, is_public = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.Exec(query, program.Name, program.Description, program.Difficulty, program.DurationWeeks, program.Goal, program.IsPublic, time.Now(), program.ID)
+	if err != nil {
+		return err
+	}
+
+	return requireRows(result)
+}
+
+// Delete deletes a workout program and all associated program templates
+func (r *programRepo) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM workout_programs WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return requireRows(result)
+}
+
+// Templates returns the templates scheduled in a program, in schedule order
+func (r *programRepo) Templates(programID int) ([]models.ProgramTemplate, error) {
+	query := `
+		SELECT pt.id, pt.program_id, pt.template_id, pt.day_of_week, pt.week_number, pt.order_index, pt.created_at,
+		       wt.name, wt.description
+		FROM program_templates pt
+		JOIN workout_templates wt ON pt.template_id = wt.id
+		WHERE pt.program_id = ?
+		ORDER BY pt.week_number ASC, pt.day_of_week ASC, pt.order_index ASC
+	`
+
+	rows, err := r.db.Query(query, programID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []models.ProgramTemplate
+	for rows.Next() {
+		var template models.ProgramTemplate
+		var templateName, templateDescription string
+		err := rows.Scan(&template.ID, &template.ProgramID, &template.TemplateID, &template.DayOfWeek, &template.WeekNumber, &template.OrderIndex, &template.CreatedAt, &templateName, &templateDescription)
+		if err != nil {
+			return nil, err
+		}
+
+		// Create a minimal WorkoutTemplate for reference
+		template.WorkoutTemplate = &models.WorkoutTemplate{
+			ID:          template.TemplateID,
+			Name:        templateName,
+			Description: templateDescription,
+		}
+
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+// AddTemplate schedules a template in a program and returns the program template ID
+func (r *programRepo) AddTemplate(template models.ProgramTemplate) (int, error) {
+	query := `
+		INSERT INTO program_templates (program_id, template_id, day_of_week, week_number, order_index, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, template.ProgramID, template.TemplateID, template.DayOfWeek, template.WeekNumber, template.OrderIndex, time.Now())
+}
+
+// DeleteTemplates removes every template from a program
+func (r *programRepo) DeleteTemplates(programID int) error {
+	_, err := r.db.Exec(`DELETE FROM program_templates WHERE program_id = ?`, programID)
+	return err
+}
@@ -0,0 +1,360 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"workout-tracker/internal/e1rm"
+	"workout-tracker/internal/models"
+)
+
+// recordRepo is the SQL implementation of PersonalRecordRepository
+type recordRepo struct {
+	*sqlStore
+}
+
+// querier runs statements on the database or inside a transaction
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// List returns the user's record entries, oldest first, of one exercise or of all
+func (r *recordRepo) List(userID int, exerciseName string) ([]models.PersonalRecordEntry, error) {
+	query := `
+		SELECT id, user_id, exercise_name, record_type, weight, reps, volume, one_rep_max, date,
+		       COALESCE(workout_id, 0), set_id, is_current
+		FROM personal_records
+		WHERE user_id = ? AND (? = '' OR ` + r.dialect.equalFold("exercise_name") + `)
+		ORDER BY exercise_name, date, id
+	`
+
+	rows, err := r.db.Query(query, userID, exerciseName, exerciseName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.PersonalRecordEntry
+	for rows.Next() {
+		var e models.PersonalRecordEntry
+		var setID sql.NullInt64
+		err := rows.Scan(&e.ID, &e.UserID, &e.ExerciseName, &e.RecordType, &e.Weight, &e.Reps, &e.Volume, &e.OneRepMax, &e.Date,
+			&e.WorkoutID, &setID, &e.IsCurrent)
+		if err != nil {
+			return nil, err
+		}
+		if setID.Valid {
+			id := int(setID.Int64)
+			e.SetID = &id
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// Rebuild recomputes the records of every exercise the user has logged
+func (r *recordRepo) Rebuild(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT DISTINCT e.name
+		FROM exercises e
+		JOIN workouts w ON e.workout_id = w.id
+		WHERE w.user_id = ?
+		UNION
+		SELECT exercise_name FROM personal_records WHERE user_id = ?
+	`
+	names, err := queryNames(tx, query, userID, userID)
+	if err != nil {
+		return err
+	}
+	if err := rebuildRecords(tx, userID, names...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RebuildAll recomputes the records of every user and returns how many users it covered
+func (r *recordRepo) RebuildAll() (int, error) {
+	userIDs, err := allUserIDs(r.db)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range userIDs {
+		if err := r.Rebuild(id); err != nil {
+			return 0, fmt.Errorf("failed to rebuild records of user %d: %v", id, err)
+		}
+	}
+	return len(userIDs), nil
+}
+
+// RebuildMissing recomputes the records of users with working sets but no record
+// entries, such as after upgrading from a version that did not keep them, and returns
+// how many users it covered
+func (r *recordRepo) RebuildMissing() (int, error) {
+	query := `
+		SELECT DISTINCT w.user_id
+		FROM sets s
+		JOIN exercises e ON s.exercise_id = e.id
+		JOIN workouts w ON e.workout_id = w.id
+		WHERE s.weight > 0 AND s.reps > 0 AND s.set_type <> ?
+			AND NOT EXISTS (SELECT 1 FROM personal_records pr WHERE pr.user_id = w.user_id)
+		ORDER BY w.user_id
+	`
+	userIDs, err := queryIDs(r.db, query, models.SetTypeWarmup)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find users without records: %v", err)
+	}
+	for _, id := range userIDs {
+		if err := r.Rebuild(id); err != nil {
+			return 0, fmt.Errorf("failed to rebuild records of user %d: %v", id, err)
+		}
+	}
+	return len(userIDs), nil
+}
+
+// recordSet is a working set considered for personal records
+type recordSet struct {
+	SetID     int
+	WorkoutID int
+	Date      time.Time
+	Weight    float64
+	Reps      int
+	RPE       *float64
+	RIR       *int
+}
+
+// rebuildRecords replaces the record entries of the named exercises of a user with ones
+// detected from the user's current working sets
+func rebuildRecords(q querier, userID int, names ...string) error {
+	formula := e1rm.Default
+	var name string
+	err := q.QueryRow(`SELECT e1rm_formula FROM user_settings WHERE user_id = ?`, userID).Scan(&name)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to load e1RM formula: %v", err)
+	}
+	if parsed, err := e1rm.ParseFormula(name); err == nil {
+		formula = parsed
+	}
+
+	insert := `
+		INSERT INTO personal_records (user_id, exercise_name, record_type, weight, reps, volume, one_rep_max, date,
+		                              workout_id, set_id, is_new, is_current, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	seen := make(map[string]bool)
+	for _, exerciseName := range names {
+		if seen[exerciseName] {
+			continue
+		}
+		seen[exerciseName] = true
+
+		sets, err := loadRecordSets(q, userID, exerciseName)
+		if err != nil {
+			return err
+		}
+		if _, err := q.Exec(`DELETE FROM personal_records WHERE user_id = ? AND exercise_name = ?`, userID, exerciseName); err != nil {
+			return fmt.Errorf("failed to clear personal records: %v", err)
+		}
+		for _, e := range detectRecords(sets, formula) {
+			_, err := q.Exec(insert, userID, exerciseName, e.RecordType, e.Weight, e.Reps, e.Volume, e.OneRepMax, e.Date,
+				e.WorkoutID, e.SetID, false, e.IsCurrent, time.Now(), time.Now())
+			if err != nil {
+				return fmt.Errorf("failed to save personal record: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadRecordSets returns the user's working sets of an exercise in the order they were done
+func loadRecordSets(q querier, userID int, exerciseName string) ([]recordSet, error) {
+	query := `
+		SELECT s.id, w.id, w.date, s.weight, s.reps, s.rpe, s.rir
+		FROM sets s
+		JOIN exercises e ON s.exercise_id = e.id
+		JOIN workouts w ON e.workout_id = w.id
+		WHERE w.user_id = ? AND e.name = ? AND s.weight > 0 AND s.reps > 0 AND s.set_type <> ?
+		ORDER BY w.date, w.id, s.id
+	`
+
+	rows, err := q.Query(query, userID, exerciseName, models.SetTypeWarmup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sets: %v", err)
+	}
+	defer rows.Close()
+
+	var sets []recordSet
+	for rows.Next() {
+		var s recordSet
+		if err := rows.Scan(&s.SetID, &s.WorkoutID, &s.Date, &s.Weight, &s.Reps, &s.RPE, &s.RIR); err != nil {
+			return nil, err
+		}
+		sets = append(sets, s)
+	}
+
+	return sets, rows.Err()
+}
+
+// detectRecords walks an exercise's working sets in order and returns an entry each time
+// a record is beaten. A record has to be beaten, not matched, so the first set to reach
+// a value keeps it.
+func detectRecords(sets []recordSet, formula e1rm.Formula) []models.PersonalRecordEntry {
+	var entries []models.PersonalRecordEntry
+	// Index in entries of the standing record, keyed by type and rep count
+	type recordKey struct {
+		recordType string
+		reps       int
+	}
+	current := make(map[recordKey]int)
+	best := func(key recordKey) float64 {
+		i, ok := current[key]
+		if !ok {
+			return 0
+		}
+		e := entries[i]
+		switch key.recordType {
+		case models.RecordTypeWeight:
+			return e.Weight
+		case models.RecordTypeE1RM:
+			return e.OneRepMax
+		}
+		return e.Volume
+	}
+	record := func(key recordKey, e models.PersonalRecordEntry) {
+		e.RecordType = key.recordType
+		if i, ok := current[key]; ok {
+			entries[i].IsCurrent = false
+		}
+		e.IsCurrent = true
+		current[key] = len(entries)
+		entries = append(entries, e)
+	}
+
+	var session models.PersonalRecordEntry
+	endSession := func() {
+		if session.WorkoutID != 0 && session.Volume > best(recordKey{models.RecordTypeSessionVolume, 0}) {
+			record(recordKey{models.RecordTypeSessionVolume, 0}, session)
+		}
+	}
+
+	for _, s := range sets {
+		if s.WorkoutID != session.WorkoutID {
+			endSession()
+			session = models.PersonalRecordEntry{WorkoutID: s.WorkoutID, Date: s.Date}
+		}
+		volume := s.Weight * float64(s.Reps)
+		session.Volume += volume
+		session.Reps += s.Reps
+
+		setID := s.SetID
+		estimate, _ := formula.EstimateSet(s.Weight, s.Reps, s.RPE, s.RIR)
+		entry := models.PersonalRecordEntry{
+			Weight:    s.Weight,
+			Reps:      s.Reps,
+			Volume:    volume,
+			OneRepMax: estimate,
+			Date:      s.Date,
+			WorkoutID: s.WorkoutID,
+			SetID:     &setID,
+		}
+
+		if s.Reps <= models.RecordMaxReps && s.Weight > best(recordKey{models.RecordTypeWeight, s.Reps}) {
+			record(recordKey{models.RecordTypeWeight, s.Reps}, entry)
+		}
+		if estimate > best(recordKey{models.RecordTypeE1RM, 0}) {
+			record(recordKey{models.RecordTypeE1RM, 0}, entry)
+		}
+		if volume > best(recordKey{models.RecordTypeSetVolume, 0}) {
+			record(recordKey{models.RecordTypeSetVolume, 0}, entry)
+		}
+	}
+	endSession()
+
+	return entries
+}
+
+// queryNames returns the single string column of a query's rows
+func queryNames(q querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// allUserIDs returns the ID of every user
+func allUserIDs(q querier) ([]int, error) {
+	return queryIDs(q, `SELECT id FROM users ORDER BY id`)
+}
+
+// queryIDs returns the IDs a query selects
+func queryIDs(q querier, query string, args ...interface{}) ([]int, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// workoutExerciseNames returns the names of a workout's exercises
+func workoutExerciseNames(q querier, workoutID int) ([]string, error) {
+	return queryNames(q, `SELECT DISTINCT name FROM exercises WHERE workout_id = ?`, workoutID)
+}
+
+// exerciseRecordKey returns the owner and name of an exercise, whose records change with
+// it, and the date of its workout, whose training load does
+func exerciseRecordKey(q querier, exerciseID int) (userID int, name string, date time.Time, err error) {
+	query := `
+		SELECT w.user_id, e.name, w.date
+		FROM exercises e
+		JOIN workouts w ON e.workout_id = w.id
+		WHERE e.id = ?
+	`
+	err = q.QueryRow(query, exerciseID).Scan(&userID, &name, &date)
+	return userID, name, date, notFound(err)
+}
+
+// setRecordKey returns the owner and exercise name of a set, whose records change with
+// it, and the date of its workout, whose training load does
+func setRecordKey(q querier, setID int) (userID int, name string, date time.Time, err error) {
+	query := `
+		SELECT w.user_id, e.name, w.date
+		FROM sets s
+		JOIN exercises e ON s.exercise_id = e.id
+		JOIN workouts w ON e.workout_id = w.id
+		WHERE s.id = ?
+	`
+	err = q.QueryRow(query, setID).Scan(&userID, &name, &date)
+	return userID, name, date, notFound(err)
+}
@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// reminderRepo is the SQL implementation of ReminderRepository
+type reminderRepo struct {
+	*sqlStore
+}
+
+// reminderColumns are the columns of workout_reminders in the order scanReminder reads them
+const reminderColumns = `r.id, r.user_id, r.scheduled_workout_id, r.reminder_type, r.message, r.scheduled_for,
+	COALESCE(r.status, 'pending'), r.sent_at, r.error_message, r.minutes_before, r.attempts, r.next_attempt_at, r.created_at`
+
+// Create stores a pending reminder and returns its ID
+func (r *reminderRepo) Create(reminder models.WorkoutReminder) (int, error) {
+	query := `
+		INSERT INTO workout_reminders (user_id, scheduled_workout_id, reminder_type, message, scheduled_for, minutes_before, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	id, err := r.db.Insert(query, reminder.UserID, reminder.ScheduledWorkoutID, reminder.ReminderType, reminder.Message,
+		reminder.ScheduledFor.UTC(), reminder.MinutesBefore, models.ReminderStatusPending, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create reminder: %v", err)
+	}
+	return id, nil
+}
+
+func (r *reminderRepo) Get(id, userID int) (models.WorkoutReminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM workout_reminders r WHERE r.id = ? AND r.user_id = ?`
+	return scanReminder(r.db.QueryRow(query, id, userID))
+}
+
+// List returns the reminders of one of the user's scheduled workouts, earliest first
+func (r *reminderRepo) List(scheduledWorkoutID, userID int) ([]models.WorkoutReminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM workout_reminders r
+		WHERE r.scheduled_workout_id = ? AND r.user_id = ? ORDER BY r.scheduled_for, r.id`
+	rows, err := r.db.Query(query, scheduledWorkoutID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reminders: %v", err)
+	}
+	defer rows.Close()
+
+	reminders := []models.WorkoutReminder{}
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %v", err)
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, rows.Err()
+}
+
+func (r *reminderRepo) Delete(id, userID int) error {
+	result, err := r.db.Exec(`DELETE FROM workout_reminders WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete reminder: %v", err)
+	}
+	return requireRows(result)
+}
+
+// Reschedule moves the user's pending reminders to their minutes before the start of
+// their workout in the given zone, after the workout was moved or the user changed time
+// zone. Reminders without minutes before keep their time.
+func (r *reminderRepo) Reschedule(userID int, zone *time.Location) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT r.id, r.minutes_before, sw.scheduled_date, sw.scheduled_time
+		FROM workout_reminders r
+		JOIN scheduled_workouts sw ON sw.id = r.scheduled_workout_id
+		WHERE r.user_id = ? AND r.status = ? AND r.minutes_before IS NOT NULL
+	`
+	rows, err := tx.Query(query, userID, models.ReminderStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to list pending reminders: %v", err)
+	}
+	due := map[int]time.Time{}
+	for rows.Next() {
+		var id, minutes int
+		var date time.Time
+		var clock *string
+		if err := rows.Scan(&id, &minutes, &date, &clock); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan pending reminder: %v", err)
+		}
+		due[id] = models.ScheduledStart(date, clock, zone).Add(-time.Duration(minutes) * time.Minute)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list pending reminders: %v", err)
+	}
+
+	for id, at := range due {
+		if _, err := tx.Exec(`UPDATE workout_reminders SET scheduled_for = ? WHERE id = ?`, at.UTC(), id); err != nil {
+			return fmt.Errorf("failed to reschedule reminder: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rescheduled reminders: %v", err)
+	}
+	return nil
+}
+
+// Due returns up to limit pending reminders of the given types whose time, or whose
+// retry time after a failed attempt, has come, the longest waiting first
+func (r *reminderRepo) Due(types []string, now time.Time, limit int) ([]models.DueReminder, error) {
+	if len(types) == 0 {
+		return []models.DueReminder{}, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(types)), ",")
+	query := `SELECT ` + reminderColumns + `, u.username, u.email, COALESCE(s.timezone, 'UTC'), COALESCE(s.notifications, TRUE),
+			sw.title, sw.scheduled_date, sw.scheduled_time, sw.status
+		FROM workout_reminders r
+		JOIN users u ON u.id = r.user_id
+		JOIN scheduled_workouts sw ON sw.id = r.scheduled_workout_id
+		LEFT JOIN user_settings s ON s.user_id = r.user_id
+		WHERE r.status = ? AND r.reminder_type IN (` + placeholders + `) AND COALESCE(r.next_attempt_at, r.scheduled_for) <= ?
+		ORDER BY r.scheduled_for, r.id
+		LIMIT ?`
+	args := []interface{}{models.ReminderStatusPending}
+	for _, t := range types {
+		args = append(args, t)
+	}
+	args = append(args, now.UTC(), limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due reminders: %v", err)
+	}
+	defer rows.Close()
+
+	due := []models.DueReminder{}
+	for rows.Next() {
+		var d models.DueReminder
+		rem := &d.WorkoutReminder
+		err := rows.Scan(&rem.ID, &rem.UserID, &rem.ScheduledWorkoutID, &rem.ReminderType, &rem.Message, &rem.ScheduledFor,
+			&rem.Status, &rem.SentAt, &rem.ErrorMessage, &rem.MinutesBefore, &rem.Attempts, &rem.NextAttemptAt, &rem.CreatedAt,
+			&d.Username, &d.Email, &d.Timezone, &d.Notifications, &d.WorkoutTitle, &d.WorkoutDate, &d.WorkoutTime, &d.WorkoutStatus)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan due reminder: %v", err)
+		}
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// Claim takes a due reminder for one delivery attempt, counting the attempt and holding
+// the reminder back from other dispatchers until the given time. It returns ErrNotFound
+// if the reminder is no longer due, such as when another dispatcher claimed it first.
+func (r *reminderRepo) Claim(id int, now, until time.Time) error {
+	query := `
+		UPDATE workout_reminders SET attempts = attempts + 1, next_attempt_at = ?
+		WHERE id = ? AND status = ? AND COALESCE(next_attempt_at, scheduled_for) <= ?
+	`
+	result, err := r.db.Exec(query, until.UTC(), id, models.ReminderStatusPending, now.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to claim reminder: %v", err)
+	}
+	return requireRows(result)
+}
+
+// MarkSent records that a reminder went out, and that its scheduled workout has had a reminder
+func (r *reminderRepo) MarkSent(id int, at time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE workout_reminders SET status = ?, sent_at = ?, next_attempt_at = NULL, error_message = NULL WHERE id = ?`,
+		models.ReminderStatusSent, at.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark reminder sent: %v", err)
+	}
+	if err := requireRows(result); err != nil {
+		return err
+	}
+	query := `UPDATE scheduled_workouts SET reminder_sent = TRUE
+		WHERE id = (SELECT scheduled_workout_id FROM workout_reminders WHERE id = ?)`
+	if _, err := tx.Exec(query, id); err != nil {
+		return fmt.Errorf("failed to mark scheduled workout reminded: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sent reminder: %v", err)
+	}
+	return nil
+}
+
+// MarkFailed records why a delivery attempt failed. The reminder stays pending until
+// retryAt, or is marked failed for good when retryAt is nil.
+func (r *reminderRepo) MarkFailed(id int, message string, retryAt *time.Time) error {
+	status := models.ReminderStatusFailed
+	if retryAt != nil {
+		status = models.ReminderStatusPending
+		utc := retryAt.UTC()
+		retryAt = &utc
+	}
+	result, err := r.db.Exec(`UPDATE workout_reminders SET status = ?, error_message = ?, next_attempt_at = ? WHERE id = ?`,
+		status, message, retryAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark reminder failed: %v", err)
+	}
+	return requireRows(result)
+}
+
+// Cancel marks a reminder that should not be sent cancelled, recording why
+func (r *reminderRepo) Cancel(id int, reason string) error {
+	result, err := r.db.Exec(`UPDATE workout_reminders SET status = ?, error_message = ?, next_attempt_at = NULL WHERE id = ?`,
+		models.ReminderStatusCancelled, reason, id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel reminder: %v", err)
+	}
+	return requireRows(result)
+}
+
+// scanReminder reads a row selecting reminderColumns
+func scanReminder(row rowScanner) (models.WorkoutReminder, error) {
+	var rem models.WorkoutReminder
+	err := row.Scan(&rem.ID, &rem.UserID, &rem.ScheduledWorkoutID, &rem.ReminderType, &rem.Message, &rem.ScheduledFor,
+		&rem.Status, &rem.SentAt, &rem.ErrorMessage, &rem.MinutesBefore, &rem.Attempts, &rem.NextAttemptAt, &rem.CreatedAt)
+	if err != nil {
+		return rem, notFound(err)
+	}
+	return rem, nil
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// restDayRepo is the SQL implementation of RestDayRepository
+type restDayRepo struct {
+	*sqlStore
+}
+
+// restDayColumns are the columns of rest_day_recommendations in the order scanRestDay reads them
+const restDayColumns = `id, user_id, recommended_date, reason, intensity_score, volume_load, consecutive_days,
+	muscle_groups_worked, status, user_response, created_at, updated_at`
+
+// Suggest stores a rest day recommendation, or refreshes the suggestion already made for
+// the same day, and returns it as stored
+func (r *restDayRepo) Suggest(rec models.RestDayRecommendation) (models.RestDayRecommendation, error) {
+	rec.RecommendedDate = calendarDate(rec.RecommendedDate)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return rec, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + restDayColumns + ` FROM rest_day_recommendations WHERE user_id = ? AND recommended_date = ?`
+	existing, err := scanRestDay(tx.QueryRow(query, rec.UserID, rec.RecommendedDate))
+	switch {
+	case err == ErrNotFound:
+		query := `
+			INSERT INTO rest_day_recommendations (user_id, recommended_date, reason, intensity_score, volume_load,
+				consecutive_days, muscle_groups_worked, status, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`
+		rec.ID, err = tx.Insert(query, rec.UserID, rec.RecommendedDate, rec.Reason, rec.IntensityScore, rec.VolumeLoad,
+			rec.ConsecutiveDays, rec.MuscleGroupsWorked, models.RestDayStatusSuggested, time.Now(), time.Now())
+		if err != nil {
+			return rec, fmt.Errorf("failed to create rest day recommendation: %v", err)
+		}
+	case err != nil:
+		return rec, fmt.Errorf("failed to load rest day recommendation: %v", err)
+	case existing.Status != models.RestDayStatusSuggested:
+		// The user has answered this one already
+		return existing, nil
+	default:
+		rec.ID = existing.ID
+		query := `
+			UPDATE rest_day_recommendations
+			SET reason = ?, intensity_score = ?, volume_load = ?, consecutive_days = ?, muscle_groups_worked = ?, updated_at = ?
+			WHERE id = ?
+		`
+		_, err := tx.Exec(query, rec.Reason, rec.IntensityScore, rec.VolumeLoad, rec.ConsecutiveDays, rec.MuscleGroupsWorked,
+			time.Now(), rec.ID)
+		if err != nil {
+			return rec, fmt.Errorf("failed to update rest day recommendation: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return rec, fmt.Errorf("failed to commit rest day recommendation: %v", err)
+	}
+	return r.Get(rec.ID, rec.UserID)
+}
+
+// Get returns a recommendation owned by the given user
+func (r *restDayRepo) Get(id, userID int) (models.RestDayRecommendation, error) {
+	query := `SELECT ` + restDayColumns + ` FROM rest_day_recommendations WHERE id = ? AND user_id = ?`
+	return scanRestDay(r.db.QueryRow(query, id, userID))
+}
+
+// List returns the user's recommendations from one date to another, oldest first,
+// only those with the given status unless it is empty
+func (r *restDayRepo) List(userID int, from, to time.Time, status string) ([]models.RestDayRecommendation, error) {
+	query := `SELECT ` + restDayColumns + ` FROM rest_day_recommendations
+		WHERE user_id = ? AND recommended_date >= ? AND recommended_date <= ?`
+	args := []interface{}{userID, calendarDate(from), calendarDate(to)}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY recommended_date`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rest day recommendations: %v", err)
+	}
+	defer rows.Close()
+
+	recs := []models.RestDayRecommendation{}
+	for rows.Next() {
+		rec, err := scanRestDay(rows)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
+
+// Respond records the user's answer to a recommendation owned by them
+func (r *restDayRepo) Respond(id, userID int, status, response string) error {
+	result, err := r.db.Exec(`UPDATE rest_day_recommendations SET status = ?, user_response = ?, updated_at = ? WHERE id = ? AND user_id = ?`,
+		status, response, time.Now(), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to respond to rest day recommendation: %v", err)
+	}
+	return requireRows(result)
+}
+
+// Compliance counts the user's recommendations for days before the given date and the
+// days among them with no workout logged
+func (r *restDayRepo) Compliance(userID int, before time.Time) (recommended, rested int, err error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*), COALESCE(SUM(CASE WHEN EXISTS (
+			SELECT 1 FROM workouts w WHERE w.user_id = r.user_id AND %s = %s
+		) THEN 0 ELSE 1 END), 0)
+		FROM rest_day_recommendations r
+		WHERE r.user_id = ? AND r.recommended_date < ?
+	`, r.dialect.dateOf("w.date"), r.dialect.dateOf("r.recommended_date"))
+
+	err = r.db.QueryRow(query, userID, calendarDate(before)).Scan(&recommended, &rested)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count rest day compliance: %v", err)
+	}
+	return recommended, rested, nil
+}
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRestDay reads a row selecting restDayColumns
+func scanRestDay(row rowScanner) (models.RestDayRecommendation, error) {
+	var rec models.RestDayRecommendation
+	var muscles sql.NullString
+	err := row.Scan(&rec.ID, &rec.UserID, &rec.RecommendedDate, &rec.Reason, &rec.IntensityScore, &rec.VolumeLoad,
+		&rec.ConsecutiveDays, &muscles, &rec.Status, &rec.UserResponse, &rec.CreatedAt, &rec.UpdatedAt)
+	if err != nil {
+		return rec, notFound(err)
+	}
+	rec.RecommendedDate = rec.RecommendedDate.UTC()
+	rec.MuscleGroupsWorked = muscles.String
+	return rec, nil
+}
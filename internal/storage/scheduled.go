@@ -0,0 +1,405 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"workout-tracker/internal/database"
+	"workout-tracker/internal/models"
+	"workout-tracker/internal/recommend"
+)
+
+// scheduledWorkoutRepo is the SQL implementation of ScheduledWorkoutRepository
+type scheduledWorkoutRepo struct {
+	*sqlStore
+}
+
+// scheduledWorkoutColumns are the columns of scheduled_workouts in the order scanScheduledWorkout reads them
+const scheduledWorkoutColumns = `id, user_id, template_id, title, COALESCE(description, ''), scheduled_date, scheduled_time,
+	COALESCE(estimated_duration, 0), status, workout_id, deload_id, ical_uid, COALESCE(reminder_sent, FALSE), COALESCE(notes, ''),
+	created_at, updated_at`
+
+// Create stores a scheduled workout and returns its ID. A template workout scheduled
+// inside an accepted deload's window is linked to the deload.
+func (r *scheduledWorkoutRepo) Create(sw models.ScheduledWorkout) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	id, err := insertScheduledWorkout(tx, sw)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit scheduled workout: %v", err)
+	}
+	return id, nil
+}
+
+// Import stores the workouts of an .ics file in one transaction, leaving out those whose
+// iCalendar UID the user already has, and returns the IDs of the ones it stored
+func (r *scheduledWorkoutRepo) Import(workouts []models.ScheduledWorkout) ([]int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	ids := []int{}
+	for _, sw := range workouts {
+		if sw.ICalUID != nil {
+			var exists int
+			err := tx.QueryRow(`SELECT COUNT(*) FROM scheduled_workouts WHERE user_id = ? AND ical_uid = ?`, sw.UserID, *sw.ICalUID).Scan(&exists)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check imported workouts: %v", err)
+			}
+			if exists > 0 {
+				continue
+			}
+		}
+		id, err := insertScheduledWorkout(tx, sw)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit imported workouts: %v", err)
+	}
+	return ids, nil
+}
+
+// Get returns a scheduled workout owned by the given user with its template's exercises,
+// their targets scaled down while a deload applies
+func (r *scheduledWorkoutRepo) Get(id, userID int) (models.ScheduledWorkout, error) {
+	query := `SELECT ` + scheduledWorkoutColumns + ` FROM scheduled_workouts WHERE id = ? AND user_id = ?`
+	sw, err := scanScheduledWorkout(r.db.QueryRow(query, id, userID))
+	if err != nil {
+		return sw, err
+	}
+	if err := r.attachTargets([]*models.ScheduledWorkout{&sw}); err != nil {
+		return sw, err
+	}
+	return sw, nil
+}
+
+// List returns the user's workouts scheduled from one date to another, in the order they
+// are planned, only those with the given status unless it is empty. Their exercises are
+// not filled in.
+func (r *scheduledWorkoutRepo) List(userID int, from, to time.Time, status string) ([]models.ScheduledWorkout, error) {
+	query := `SELECT ` + scheduledWorkoutColumns + ` FROM scheduled_workouts
+		WHERE user_id = ? AND scheduled_date >= ? AND scheduled_date < ?`
+	args := []interface{}{userID, calendarDate(from), calendarDate(to).AddDate(0, 0, 1)}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY scheduled_date, COALESCE(scheduled_time, ''), id`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scheduled workouts: %v", err)
+	}
+	defer rows.Close()
+
+	workouts := []models.ScheduledWorkout{}
+	for rows.Next() {
+		sw, err := scanScheduledWorkout(rows)
+		if err != nil {
+			return nil, err
+		}
+		workouts = append(workouts, sw)
+	}
+	return workouts, rows.Err()
+}
+
+// ListWithTargets is List with the template exercises and targets Get fills in, loaded
+// for every workout at once
+func (r *scheduledWorkoutRepo) ListWithTargets(userID int, from, to time.Time, status string) ([]models.ScheduledWorkout, error) {
+	workouts, err := r.List(userID, from, to, status)
+	if err != nil {
+		return nil, err
+	}
+	pointers := make([]*models.ScheduledWorkout, len(workouts))
+	for i := range workouts {
+		pointers[i] = &workouts[i]
+	}
+	if err := r.attachTargets(pointers); err != nil {
+		return nil, err
+	}
+	return workouts, nil
+}
+
+// Update changes a scheduled workout's own fields. A workout moved into or out of an
+// accepted deload's window is linked to it or unlinked.
+func (r *scheduledWorkoutRepo) Update(sw models.ScheduledWorkout) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE scheduled_workouts
+		SET title = ?, description = ?, scheduled_date = ?, scheduled_time = ?, estimated_duration = ?, status = ?, notes = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`
+	result, err := tx.Exec(query, sw.Title, sw.Description, sw.ScheduledDate, sw.ScheduledTime, sw.EstimatedDuration, sw.Status,
+		sw.Notes, time.Now(), sw.ID, sw.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to update scheduled workout: %v", err)
+	}
+	if err := requireRows(result); err != nil {
+		return err
+	}
+
+	if err := linkDeload(tx, sw.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *scheduledWorkoutRepo) Delete(id, userID int) error {
+	result, err := r.db.Exec(`DELETE FROM scheduled_workouts WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete scheduled workout: %v", err)
+	}
+	return requireRows(result)
+}
+
+// Complete links a logged workout to the first workout the user still has scheduled on
+// its day and marks that one completed. It returns ErrNotFound if nothing is scheduled.
+func (r *scheduledWorkoutRepo) Complete(userID, workoutID int, date time.Time) (models.ScheduledWorkout, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.ScheduledWorkout{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	sw, err := completeScheduled(tx, userID, workoutID, date)
+	if err != nil {
+		return sw, err
+	}
+	return sw, tx.Commit()
+}
+
+// completeScheduled is Complete within a transaction
+func completeScheduled(tx *database.Tx, userID, workoutID int, date time.Time) (models.ScheduledWorkout, error) {
+	day := calendarDate(date)
+	query := `SELECT ` + scheduledWorkoutColumns + ` FROM scheduled_workouts
+		WHERE user_id = ? AND status = ? AND workout_id IS NULL AND scheduled_date >= ? AND scheduled_date < ?
+		ORDER BY COALESCE(scheduled_time, ''), id LIMIT 1`
+	sw, err := scanScheduledWorkout(tx.QueryRow(query, userID, models.ScheduledStatusScheduled, day, day.AddDate(0, 0, 1)))
+	if err != nil {
+		return sw, err
+	}
+
+	sw.Status, sw.WorkoutID, sw.UpdatedAt = models.ScheduledStatusCompleted, &workoutID, time.Now()
+	_, err = tx.Exec(`UPDATE scheduled_workouts SET status = ?, workout_id = ?, updated_at = ? WHERE id = ?`,
+		sw.Status, workoutID, sw.UpdatedAt, sw.ID)
+	if err != nil {
+		return sw, fmt.Errorf("failed to complete scheduled workout: %v", err)
+	}
+	return sw, nil
+}
+
+// uncompleteScheduled schedules again the workout a logged workout completed
+func uncompleteScheduled(tx *database.Tx, workoutID int) error {
+	_, err := tx.Exec(`UPDATE scheduled_workouts SET status = ?, workout_id = NULL, updated_at = ? WHERE workout_id = ?`,
+		models.ScheduledStatusScheduled, time.Now(), workoutID)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule completed workout: %v", err)
+	}
+	return nil
+}
+
+// relinkScheduled follows a logged workout moved to another day: the workout it completed
+// is scheduled again and the first one scheduled on its new day is completed instead
+func relinkScheduled(tx *database.Tx, userID, workoutID int, from, to time.Time) error {
+	if calendarDate(from).Equal(calendarDate(to)) {
+		return nil
+	}
+	if err := uncompleteScheduled(tx, workoutID); err != nil {
+		return err
+	}
+	if _, err := completeScheduled(tx, userID, workoutID, to); err != nil && err != ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// SkipOverdue marks every user's workouts still scheduled before the user's own today,
+// in the time zone of their settings, skipped and returns how many it marked
+func (r *scheduledWorkoutRepo) SkipOverdue(now time.Time) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	zones, err := queryNames(tx, `SELECT DISTINCT COALESCE(s.timezone, 'UTC') FROM scheduled_workouts sw
+		LEFT JOIN user_settings s ON s.user_id = sw.user_id
+		WHERE sw.status = ?`, models.ScheduledStatusScheduled)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list time zones: %v", err)
+	}
+
+	// Users sharing a time zone share a today, so each zone takes one update
+	query := `UPDATE scheduled_workouts SET status = ?, updated_at = ?
+		WHERE status = ? AND scheduled_date < ? AND user_id IN (
+			SELECT u.id FROM users u LEFT JOIN user_settings s ON s.user_id = u.id WHERE COALESCE(s.timezone, 'UTC') = ?)`
+	var skipped int64
+	for _, zone := range zones {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			loc = time.UTC
+		}
+		result, err := tx.Exec(query, models.ScheduledStatusSkipped, time.Now(), models.ScheduledStatusScheduled,
+			calendarDate(now.In(loc)), zone)
+		if err != nil {
+			return 0, fmt.Errorf("failed to skip overdue workouts: %v", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		skipped += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit skipped workouts: %v", err)
+	}
+	return int(skipped), nil
+}
+
+// Summary counts the user's scheduled workouts by status, splitting those still
+// scheduled into overdue ones, before the given day, and upcoming ones
+func (r *scheduledWorkoutRepo) Summary(userID int, today time.Time) (models.PlanningAnalytics, error) {
+	var summary models.PlanningAnalytics
+	today = calendarDate(today)
+	query := `
+		SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = ? AND scheduled_date >= ? THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = ? AND scheduled_date < ? THEN 1 ELSE 0 END), 0)
+		FROM scheduled_workouts
+		WHERE user_id = ?
+	`
+	err := r.db.QueryRow(query, models.ScheduledStatusCompleted, models.ScheduledStatusSkipped,
+		models.ScheduledStatusScheduled, today, models.ScheduledStatusScheduled, today, userID).Scan(
+		&summary.TotalScheduled, &summary.CompletedWorkouts, &summary.SkippedWorkouts, &summary.UpcomingWorkouts, &summary.OverdueWorkouts)
+	if err != nil {
+		return summary, fmt.Errorf("failed to count scheduled workouts: %v", err)
+	}
+	return summary, nil
+}
+
+// attachTargets fills in the exercises of scheduled template workouts, scaled down by
+// the deload each is linked to. The exercises of every template are loaded at once.
+func (r *scheduledWorkoutRepo) attachTargets(workouts []*models.ScheduledWorkout) error {
+	var templateIDs []int
+	for _, sw := range workouts {
+		if sw.TemplateID != nil {
+			templateIDs = append(templateIDs, *sw.TemplateID)
+		}
+	}
+	if len(templateIDs) == 0 {
+		return nil
+	}
+	templates := &templateRepo{r.sqlStore}
+	byTemplate, err := templates.ExercisesByTemplateIDs(templateIDs)
+	if err != nil {
+		return fmt.Errorf("failed to load template exercises: %v", err)
+	}
+
+	// Reductions of the deloads seen so far, by deload ID
+	reductions := map[int][2]int{}
+	for _, sw := range workouts {
+		if sw.TemplateID == nil {
+			continue
+		}
+		// Workouts of the same template each scale a copy of its exercises
+		exercises := append([]models.TemplateExercise(nil), byTemplate[*sw.TemplateID]...)
+
+		if sw.DeloadID != nil {
+			reduction, ok := reductions[*sw.DeloadID]
+			if !ok {
+				query := `SELECT volume_reduction_percent, intensity_reduction_percent FROM deload_recommendations WHERE id = ?`
+				if err := r.db.QueryRow(query, *sw.DeloadID).Scan(&reduction[0], &reduction[1]); err != nil {
+					return fmt.Errorf("failed to load deload: %v", err)
+				}
+				reductions[*sw.DeloadID] = reduction
+			}
+			for i := range exercises {
+				exercises[i].TargetSets, exercises[i].TargetWeight = recommend.DeloadTargets(exercises[i].TargetSets,
+					exercises[i].TargetWeight, reduction[0], reduction[1])
+			}
+		}
+		sw.Exercises = exercises
+	}
+	return nil
+}
+
+// insertScheduledWorkout stores a scheduled workout, scheduled unless it has a status,
+// and links it to the accepted deload whose window it falls in
+func insertScheduledWorkout(tx *database.Tx, sw models.ScheduledWorkout) (int, error) {
+	status := sw.Status
+	if status == "" {
+		status = models.ScheduledStatusScheduled
+	}
+	query := `
+		INSERT INTO scheduled_workouts (user_id, template_id, title, description, scheduled_date, scheduled_time,
+			estimated_duration, status, workout_id, ical_uid, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	id, err := tx.Insert(query, sw.UserID, sw.TemplateID, sw.Title, sw.Description, sw.ScheduledDate, sw.ScheduledTime,
+		sw.EstimatedDuration, status, sw.WorkoutID, sw.ICalUID, sw.Notes, time.Now(), time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create scheduled workout: %v", err)
+	}
+
+	if err := linkDeload(tx, id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// linkDeload links a scheduled template workout still to be done to the accepted deload
+// whose window it falls in, or unlinks it when there is none
+func linkDeload(q querier, scheduledWorkoutID int) error {
+	query := `
+		UPDATE scheduled_workouts SET deload_id = (
+			SELECT d.id FROM deload_recommendations d
+			WHERE d.user_id = scheduled_workouts.user_id AND d.` + acceptedDeload + `
+			  AND d.recommended_start_date <= scheduled_workouts.scheduled_date
+			  AND d.recommended_end_date >= ?
+			ORDER BY d.recommended_start_date DESC LIMIT 1
+		)
+		WHERE id = ? AND template_id IS NOT NULL AND status = '` + models.ScheduledStatusScheduled + `'
+	`
+	var date time.Time
+	if err := q.QueryRow(`SELECT scheduled_date FROM scheduled_workouts WHERE id = ?`, scheduledWorkoutID).Scan(&date); err != nil {
+		return fmt.Errorf("failed to load scheduled workout: %v", err)
+	}
+	if _, err := q.Exec(query, calendarDate(date), scheduledWorkoutID); err != nil {
+		return fmt.Errorf("failed to link scheduled workout to deload: %v", err)
+	}
+	return nil
+}
+
+// scanScheduledWorkout reads a row selecting scheduledWorkoutColumns
+func scanScheduledWorkout(row rowScanner) (models.ScheduledWorkout, error) {
+	var sw models.ScheduledWorkout
+	err := row.Scan(&sw.ID, &sw.UserID, &sw.TemplateID, &sw.Title, &sw.Description, &sw.ScheduledDate, &sw.ScheduledTime,
+		&sw.EstimatedDuration, &sw.Status, &sw.WorkoutID, &sw.DeloadID, &sw.ICalUID, &sw.ReminderSent, &sw.Notes, &sw.CreatedAt, &sw.UpdatedAt)
+	if err != nil {
+		return sw, notFound(err)
+	}
+	sw.ScheduledDate = sw.ScheduledDate.UTC()
+	return sw, nil
+}
@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"workout-tracker/internal/database"
+	"workout-tracker/internal/models"
+)
+
+// sessionRepo is the SQL implementation of SessionRepository
+type sessionRepo struct {
+	*sqlStore
+}
+
+// Create stores a session with its planned exercises in one transaction and returns its ID.
+// The first planned exercise becomes the current one.
+func (r *sessionRepo) Create(session models.WorkoutSession) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO workout_sessions (user_id, template_id, name, started_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	id, err := tx.Insert(query, session.UserID, session.TemplateID, session.Name, session.StartedAt, time.Now(), time.Now())
+	if database.IsUniqueViolation(err) {
+		// The unique index on unfinished sessions lost a race with another start
+		return 0, ErrConflict
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to create session: %v", err)
+	}
+
+	exerciseQuery := `
+		INSERT INTO session_exercises (session_id, name, category, order_index, group_id, target_sets, target_reps, target_weight, rest_time, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	currentExerciseID := 0
+	for index, exercise := range session.Exercises {
+		exerciseID, err := tx.Insert(exerciseQuery, id, exercise.Name, exercise.Category, index, exercise.GroupID,
+			exercise.TargetSets, exercise.TargetReps, exercise.TargetWeight, exercise.RestTime, time.Now())
+		if err != nil {
+			return 0, fmt.Errorf("failed to create session exercise: %v", err)
+		}
+		if index == 0 {
+			currentExerciseID = exerciseID
+		}
+	}
+
+	if currentExerciseID != 0 {
+		if _, err := tx.Exec(`UPDATE workout_sessions SET current_exercise_id = ? WHERE id = ?`, currentExerciseID, id); err != nil {
+			return 0, fmt.Errorf("failed to set current exercise: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit session: %v", err)
+	}
+
+	return id, nil
+}
+
+// Get returns a session owned by the given user with its exercises and completed sets
+func (r *sessionRepo) Get(id, userID int) (models.WorkoutSession, error) {
+	return r.get(`WHERE id = ? AND user_id = ?`, id, userID)
+}
+
+// Active returns the user's unfinished session
+func (r *sessionRepo) Active(userID int) (models.WorkoutSession, error) {
+	return r.get(`WHERE user_id = ? AND finished_at IS NULL`, userID)
+}
+
+// get loads the single session matching the where clause
+func (r *sessionRepo) get(where string, args ...interface{}) (models.WorkoutSession, error) {
+	var s models.WorkoutSession
+
+	query := `
+		SELECT id, user_id, template_id, name, started_at, current_exercise_id, rest_ends_at, finished_at, workout_id, created_at, updated_at
+		FROM workout_sessions
+	` + where
+
+	err := r.db.QueryRow(query, args...).Scan(&s.ID, &s.UserID, &s.TemplateID, &s.Name, &s.StartedAt, &s.CurrentExerciseID,
+		&s.RestEndsAt, &s.FinishedAt, &s.WorkoutID, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return s, notFound(err)
+	}
+
+	exercises, err := r.exercises(s.ID)
+	if err != nil {
+		return s, err
+	}
+	s.Exercises = exercises
+
+	return s, nil
+}
+
+// exercises loads the exercises of a session in order, each with its completed sets
+func (r *sessionRepo) exercises(sessionID int) ([]models.SessionExercise, error) {
+	query := `
+		SELECT e.id, e.session_id, e.name, e.category, e.order_index, e.group_id, e.target_sets, e.target_reps, e.target_weight, e.rest_time,
+		       s.id, s.set_number, s.reps, s.weight, s.distance, s.duration, s.rest_time, s.rpe, s.rir, s.set_type, s.notes, s.created_at
+		FROM session_exercises e
+		LEFT JOIN session_sets s ON s.session_exercise_id = e.id
+		WHERE e.session_id = ?
+		ORDER BY e.order_index ASC, e.id ASC, s.set_number ASC, s.id ASC
+	`
+
+	rows, err := r.db.Query(query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exercises []models.SessionExercise
+	for rows.Next() {
+		var e models.SessionExercise
+		var setID, setNumber, reps, duration, restTime sql.NullInt64
+		var weight, distance, rpe sql.NullFloat64
+		var rir sql.NullInt64
+		var setType, notes sql.NullString
+		var completedAt sql.NullTime
+		err := rows.Scan(&e.ID, &e.SessionID, &e.Name, &e.Category, &e.OrderIndex, &e.GroupID, &e.TargetSets, &e.TargetReps, &e.TargetWeight, &e.RestTime,
+			&setID, &setNumber, &reps, &weight, &distance, &duration, &restTime, &rpe, &rir, &setType, &notes, &completedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(exercises) == 0 || exercises[len(exercises)-1].ID != e.ID {
+			exercises = append(exercises, e)
+		}
+
+		// Exercises without completed sets come back with NULL set columns
+		if !setID.Valid {
+			continue
+		}
+		exercise := &exercises[len(exercises)-1]
+		set := models.Set{
+			ID:         int(setID.Int64),
+			ExerciseID: e.ID,
+			SetNumber:  int(setNumber.Int64),
+			Reps:       int(reps.Int64),
+			Weight:     weight.Float64,
+			Distance:   distance.Float64,
+			Duration:   int(duration.Int64),
+			RestTime:   int(restTime.Int64),
+			SetType:    setType.String,
+			Notes:      notes.String,
+			CreatedAt:  completedAt.Time,
+			UpdatedAt:  completedAt.Time,
+		}
+		if rpe.Valid {
+			set.RPE = &rpe.Float64
+		}
+		if rir.Valid {
+			value := int(rir.Int64)
+			set.RIR = &value
+		}
+		exercise.Sets = append(exercise.Sets, set)
+	}
+
+	return exercises, rows.Err()
+}
+
+// AddExercise appends an exercise to a session and returns its ID
+func (r *sessionRepo) AddExercise(exercise models.SessionExercise) (int, error) {
+	query := `
+		INSERT INTO session_exercises (session_id, name, category, order_index, group_id, target_sets, target_reps, target_weight, rest_time, created_at)
+		VALUES (?, ?, ?, (SELECT COALESCE(MAX(order_index) + 1, 0) FROM session_exercises WHERE session_id = ?), ?, ?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, exercise.SessionID, exercise.Name, exercise.Category, exercise.SessionID, exercise.GroupID,
+		exercise.TargetSets, exercise.TargetReps, exercise.TargetWeight, exercise.RestTime, time.Now())
+}
+
+// AddSet records a completed set of a session exercise, numbered after its last one, and
+// returns its ID
+func (r *sessionRepo) AddSet(set models.Set) (int, error) {
+	// Numbering the set in the insert keeps two sets completed at once from sharing a number
+	query := `
+		INSERT INTO session_sets (session_exercise_id, set_number, reps, weight, distance, duration, rest_time, rpe, rir, set_type, notes, created_at)
+		VALUES (?, (SELECT COALESCE(MAX(set_number), 0) + 1 FROM session_sets WHERE session_exercise_id = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, set.ExerciseID, set.ExerciseID, set.Reps, set.Weight, set.Distance, set.Duration, set.RestTime,
+		set.RPE, set.RIR, models.SetTypeOrDefault(set.SetType), set.Notes, time.Now())
+}
+
+// UpdateState saves the current exercise and rest timer of an unfinished session
+func (r *sessionRepo) UpdateState(session models.WorkoutSession) error {
+	query := `
+		UPDATE workout_sessions
+		SET current_exercise_id = ?, rest_ends_at = ?, updated_at = ?
+		WHERE id = ? AND user_id = ? AND finished_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, session.CurrentExerciseID, session.RestEndsAt, time.Now(), session.ID, session.UserID)
+	if err != nil {
+		return err
+	}
+
+	return requireRows(result)
+}
+
+// Finish saves the workout and marks the session finished in one transaction, returning the workout's ID
+func (r *sessionRepo) Finish(session models.WorkoutSession, workout models.Workout) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	workoutID, err := insertWorkout(tx, workout, session.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		UPDATE workout_sessions
+		SET finished_at = ?, workout_id = ?, rest_ends_at = NULL, updated_at = ?
+		WHERE id = ? AND user_id = ? AND finished_at IS NULL
+	`
+	result, err := tx.Exec(query, session.FinishedAt, workoutID, time.Now(), session.ID, session.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to finish session: %v", err)
+	}
+	if err := requireRows(result); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit session: %v", err)
+	}
+
+	return workoutID, nil
+}
+
+// Delete discards a session with its exercises and sets
+func (r *sessionRepo) Delete(id, userID int) error {
+	result, err := r.db.Exec(`DELETE FROM workout_sessions WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return requireRows(result)
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"database/sql"
+
+	"workout-tracker/internal/database"
+)
+
+// dialect covers the SQL that differs between SQLite and PostgreSQL.
+// Placeholders and inserted IDs are already handled by database.DB.
+type dialect interface {
+	// equalFold compares a column with a placeholder ignoring case
+	equalFold(column string) string
+	// containsFold matches a column against an escaped LIKE pattern ignoring case
+	containsFold(column string) string
+	// dateOf truncates a timestamp expression to its calendar date
+	dateOf(expr string) string
+}
+
+// sqlStore implements Store on top of database/sql. The SQLite and PostgreSQL
+// stores share it and differ only in their dialect.
+type sqlStore struct {
+	db      *database.DB
+	dialect dialect
+}
+
+func (s *sqlStore) Users() UserRepository                         { return &userRepo{s} }
+func (s *sqlStore) Workouts() WorkoutRepository                   { return &workoutRepo{s} }
+func (s *sqlStore) Exercises() ExerciseRepository                 { return &exerciseRepo{s} }
+func (s *sqlStore) Templates() TemplateRepository                 { return &templateRepo{s} }
+func (s *sqlStore) Programs() ProgramRepository                   { return &programRepo{s} }
+func (s *sqlStore) BodyMetrics() BodyMetricsRepository            { return &bodyMetricsRepo{s} }
+func (s *sqlStore) Meals() MealRepository                         { return &mealRepo{s} }
+func (s *sqlStore) Sessions() SessionRepository                   { return &sessionRepo{s} }
+func (s *sqlStore) PersonalRecords() PersonalRecordRepository     { return &recordRepo{s} }
+func (s *sqlStore) Muscles() MuscleRepository                     { return &muscleRepo{s} }
+func (s *sqlStore) TrainingLoad() TrainingLoadRepository          { return &trainingLoadRepo{s} }
+func (s *sqlStore) RestDays() RestDayRepository                   { return &restDayRepo{s} }
+func (s *sqlStore) Deloads() DeloadRepository                     { return &deloadRepo{s} }
+func (s *sqlStore) ScheduledWorkouts() ScheduledWorkoutRepository { return &scheduledWorkoutRepo{s} }
+func (s *sqlStore) CalendarEvents() CalendarEventRepository       { return &calendarEventRepo{s} }
+func (s *sqlStore) CalendarFeeds() CalendarFeedRepository         { return &calendarFeedRepo{s} }
+func (s *sqlStore) Reminders() ReminderRepository                 { return &reminderRepo{s} }
+func (s *sqlStore) Analytics() AnalyticsRepository                { return &analyticsRepo{s} }
+
+// notFound maps sql.ErrNoRows to ErrNotFound
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+// requireRows returns ErrNotFound when a write touched no rows
+func requireRows(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"workout-tracker/internal/database"
+)
+
+// sqliteDialect is the SQL flavour of the bundled SQLite database
+type sqliteDialect struct{}
+
+func (sqliteDialect) equalFold(column string) string {
+	return column + " = ? COLLATE NOCASE"
+}
+
+// LIKE already ignores ASCII case in SQLite
+func (sqliteDialect) containsFold(column string) string {
+	return column + ` LIKE ? ESCAPE '\'`
+}
+
+func (sqliteDialect) dateOf(expr string) string {
+	return "DATE(" + expr + ")"
+}
+
+// NewSQLite returns a store backed by a SQLite database
+func NewSQLite(db *database.DB) Store {
+	return &sqlStore{db: db, dialect: sqliteDialect{}}
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"workout-tracker/internal/database"
+)
+
+func TestSQLiteConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) Store {
+		t.Setenv("DATABASE_URL", "")
+		t.Setenv("DATABASE_PATH", filepath.Join(t.TempDir(), "test.db"))
+		db, err := database.Initialize()
+		if err != nil {
+			t.Fatalf("failed to initialize database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		if db.Driver != database.DriverSQLite {
+			t.Fatalf("driver = %s, want %s", db.Driver, database.DriverSQLite)
+		}
+		return New(db)
+	})
+}
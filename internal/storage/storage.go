@@ -0,0 +1,364 @@
+// Package storage defines the repositories the handlers use to persist workouts,
+// exercises, templates, programs, body metrics, meals, users, live workout sessions,
+// personal records, muscles, training load, rest day and deload recommendations,
+// scheduled workouts and their reminders, calendar events and calendar feeds, together
+// with SQLite and PostgreSQL implementations of them.
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"workout-tracker/internal/database"
+	"workout-tracker/internal/models"
+)
+
+// ErrNotFound is returned when a record does not exist or belongs to another user
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned when a record clashes with one that already exists, such as a
+// second session started while another is in progress
+var ErrConflict = errors.New("conflict")
+
+// Store gives access to every repository
+type Store interface {
+	Users() UserRepository
+	Workouts() WorkoutRepository
+	Exercises() ExerciseRepository
+	Templates() TemplateRepository
+	Programs() ProgramRepository
+	BodyMetrics() BodyMetricsRepository
+	Meals() MealRepository
+	Sessions() SessionRepository
+	PersonalRecords() PersonalRecordRepository
+	Muscles() MuscleRepository
+	TrainingLoad() TrainingLoadRepository
+	RestDays() RestDayRepository
+	Deloads() DeloadRepository
+	ScheduledWorkouts() ScheduledWorkoutRepository
+	CalendarEvents() CalendarEventRepository
+	CalendarFeeds() CalendarFeedRepository
+	Reminders() ReminderRepository
+	Analytics() AnalyticsRepository
+}
+
+// UserRepository stores user accounts
+type UserRepository interface {
+	Create(user models.User) (int, error)
+	GetByID(id int) (models.User, error)
+	GetByUsername(username string) (models.User, error)
+	UpdateProfile(id int, profile models.UpdateProfileRequest) error
+	UpdatePassword(id int, passwordHash string) error
+	// Delete removes a user; everything the user owns is removed with it
+	Delete(id int) error
+}
+
+// WorkoutRepository stores workouts. Every method is scoped to the owning user.
+type WorkoutRepository interface {
+	// Create stores a workout together with its exercises and sets in one transaction
+	Create(workout models.Workout, userID int) (int, error)
+	// Get returns a workout with its exercises and sets
+	Get(id, userID int) (models.Workout, error)
+	Recent(userID, limit int) ([]models.Workout, error)
+	// List returns one page of workouts and the cursor for the next page
+	List(userID int, params models.WorkoutListParams) ([]models.Workout, string, error)
+	// Update changes the workout's own fields and leaves its exercises alone. Moved to
+	// another day, it completes the workout scheduled there instead of the one it completed.
+	Update(workout models.Workout, userID int) error
+	// Replace updates the workout as Update does and replaces all of its exercises and sets
+	// in one transaction
+	Replace(workout models.Workout, userID int) error
+	Delete(id, userID int) error
+	// AttachExercises fills in the exercises and sets of the given workouts
+	AttachExercises(workouts []models.Workout) error
+	OwnerID(id int) (int, error)
+}
+
+// ExerciseRepository stores the exercises logged in workouts and their sets
+type ExerciseRepository interface {
+	Create(exercise models.Exercise) (int, error)
+	Update(exercise models.Exercise) error
+	Delete(id int) error
+	// ByWorkoutIDs loads the exercises and sets of many workouts at once, keyed by workout ID
+	ByWorkoutIDs(workoutIDs []int) (map[int][]models.Exercise, error)
+	OwnerID(id int) (int, error)
+	// Reorder gives a workout's exercises the order and groups listed, in one transaction.
+	// It returns ErrNotFound if an exercise does not belong to the workout.
+	Reorder(workoutID int, order []models.ExerciseOrder) error
+
+	CreateSet(set models.Set) (int, error)
+	UpdateSet(set models.Set) error
+	DeleteSet(id int) error
+	SetOwnerID(id int) (int, error)
+}
+
+// TemplateRepository stores workout templates, their exercises and sharing
+type TemplateRepository interface {
+	ListByUser(userID int) ([]models.WorkoutTemplate, error)
+	Get(id, userID int) (models.WorkoutTemplate, error)
+	Create(template models.WorkoutTemplate) (int, error)
+	Update(template models.WorkoutTemplate) error
+	Delete(id int) error
+
+	Exercises(templateID int) ([]models.TemplateExercise, error)
+	// ExercisesByTemplateIDs loads the exercises of many templates at once, keyed by template ID
+	ExercisesByTemplateIDs(templateIDs []int) (map[int][]models.TemplateExercise, error)
+	CreateExercise(exercise models.TemplateExercise) (int, error)
+	DeleteExercises(templateID int) error
+
+	Share(sharing models.TemplateSharing) (int, error)
+	SharedWith(userID int) ([]models.WorkoutTemplate, error)
+	RecordUsage(usage models.TemplateUsage) (int, error)
+}
+
+// ProgramRepository stores workout programs and the templates scheduled in them
+type ProgramRepository interface {
+	ListPublic() ([]models.WorkoutProgram, error)
+	Get(id int) (models.WorkoutProgram, error)
+	Create(program models.WorkoutProgram) (int, error)
+	Update(program models.WorkoutProgram) error
+	Delete(id int) error
+
+	Templates(programID int) ([]models.ProgramTemplate, error)
+	AddTemplate(template models.ProgramTemplate) (int, error)
+	DeleteTemplates(programID int) error
+}
+
+// BodyMetricsRepository stores body weight, body fat and body measurements
+type BodyMetricsRepository interface {
+	CreateWeight(weight models.BodyWeight) (int, error)
+	WeightsByUser(userID int) ([]models.BodyWeight, error)
+	CreateBodyFat(bodyFat models.BodyFat) (int, error)
+	BodyFatsByUser(userID int) ([]models.BodyFat, error)
+	CreateMeasurement(measurement models.BodyMeasurement) (int, error)
+	MeasurementsByUser(userID int) ([]models.BodyMeasurement, error)
+}
+
+// MealRepository stores logged meals
+type MealRepository interface {
+	Create(meal models.Meal) (int, error)
+	ByUserAndDate(userID int, date time.Time) ([]models.Meal, error)
+}
+
+// SessionRepository stores live workout sessions. Every method is scoped to the owning user.
+type SessionRepository interface {
+	// Create stores a session with its planned exercises in one transaction. It returns
+	// ErrConflict if the user already has an unfinished session.
+	Create(session models.WorkoutSession) (int, error)
+	// Get returns a session with its exercises and completed sets
+	Get(id, userID int) (models.WorkoutSession, error)
+	// Active returns the user's unfinished session, or ErrNotFound if there is none
+	Active(userID int) (models.WorkoutSession, error)
+	AddExercise(exercise models.SessionExercise) (int, error)
+	// AddSet records a completed set numbered after the exercise's last one and returns its ID
+	AddSet(set models.Set) (int, error)
+	// UpdateState saves the current exercise and rest timer of an unfinished session
+	UpdateState(session models.WorkoutSession) error
+	// Finish saves the workout and marks the session finished in one transaction.
+	// It returns ErrNotFound if the session is already finished.
+	Finish(session models.WorkoutSession, workout models.Workout) (int, error)
+	Delete(id, userID int) error
+}
+
+// PersonalRecordRepository reads the personal records kept for each user's exercises.
+// The workout and exercise repositories rebuild an exercise's records whenever its
+// working sets change; Rebuild is needed only when something else they depend on does.
+type PersonalRecordRepository interface {
+	// List returns the user's record entries, oldest first, of the named exercise
+	// ignoring case or, with an empty name, of every exercise
+	List(userID int, exerciseName string) ([]models.PersonalRecordEntry, error)
+	// Rebuild recomputes the records of every exercise of a user, such as after the
+	// user picks another e1RM formula
+	Rebuild(userID int) error
+	// RebuildAll recomputes the records of every user and returns how many users it covered
+	RebuildAll() (int, error)
+	// RebuildMissing recomputes the records of users who have working sets but no
+	// records, such as after upgrading, and returns how many users it covered
+	RebuildMissing() (int, error)
+}
+
+// MuscleRepository stores the muscles library exercises train. Logged exercises are
+// linked to the library entry of the same name when they are written, unless given one.
+type MuscleRepository interface {
+	// List returns the name of every muscle, alphabetically
+	List() ([]string, error)
+	// ExerciseMuscles returns the muscles of every library exercise keyed by its ID,
+	// primary muscles first
+	ExerciseMuscles() (map[int][]models.ExerciseMuscle, error)
+	// LinkExercise sets a library exercise's muscles from its free-text muscle list, the
+	// first named muscle primary and the rest secondary, and links the unlinked logged
+	// exercises of the same name to it. Names that match no muscle are ignored.
+	LinkExercise(predefinedExerciseID int) error
+	// WorkingSets returns the user's non-warmup sets from the start date on, once for
+	// each muscle their exercise trains
+	WorkingSets(userID int, start time.Time) ([]models.MuscleSet, error)
+}
+
+// TrainingLoadRepository reads the training load kept for each day of a user's training.
+// The workout and exercise repositories rebuild it from the changed day on whenever
+// workouts or their working sets change.
+type TrainingLoadRepository interface {
+	// Days returns the user's training load for every day from one date to another,
+	// oldest first. Days after the last workout are continued as rest days.
+	Days(userID int, from, to time.Time) ([]models.TrainingLoadDay, error)
+	// Sessions returns the load and volume of the user's workouts from the start date
+	// on, oldest first
+	Sessions(userID int, start time.Time) ([]models.TrainingSession, error)
+	// Rebuild recomputes every day of a user's training load
+	Rebuild(userID int) error
+	// RebuildAll recomputes the training load of every user and returns how many users it covered
+	RebuildAll() (int, error)
+	// RebuildMissing recomputes the training load of users who have workouts but no
+	// training load, such as after upgrading, and returns how many users it covered
+	RebuildMissing() (int, error)
+}
+
+// RestDayRepository stores the rest days recommended to each user, at most one a day
+type RestDayRepository interface {
+	// Suggest stores a rest day suggestion, or refreshes the one already made for the same
+	// user and day, and returns it as stored. A recommendation the user has responded to
+	// is returned unchanged.
+	Suggest(rec models.RestDayRecommendation) (models.RestDayRecommendation, error)
+	Get(id, userID int) (models.RestDayRecommendation, error)
+	// List returns the user's recommendations from one date to another, oldest first,
+	// only those with the given status unless it is empty
+	List(userID int, from, to time.Time, status string) ([]models.RestDayRecommendation, error)
+	// Respond records the user's answer to a recommendation: accepted, ignored or overridden
+	Respond(id, userID int, status, response string) error
+	// Compliance counts the user's recommendations for days before the given date and
+	// the days among them on which no workout was logged
+	Compliance(userID int, before time.Time) (recommended, rested int, err error)
+}
+
+// DeloadRepository stores the deloads recommended to each user
+type DeloadRepository interface {
+	Create(rec models.DeloadRecommendation) (int, error)
+	Get(id, userID int) (models.DeloadRecommendation, error)
+	// Latest returns the user's recommendation starting last, or ErrNotFound if there is none
+	Latest(userID int) (models.DeloadRecommendation, error)
+	// List returns the user's recommendations whose window overlaps the period, earliest
+	// first, only those with the given status unless it is empty
+	List(userID int, from, to time.Time, status string) ([]models.DeloadRecommendation, error)
+	// Respond records the user's answer to a recommendation: accepting it scales down the
+	// targets of the scheduled template workouts in its window, ignoring it restores them
+	Respond(id, userID int, status, response string) error
+	// TrainingSince returns the day after the user's last accepted deload before the given
+	// day or, without one, the day of the first workout; ErrNotFound if there are no workouts
+	TrainingSince(userID int, day time.Time) (time.Time, error)
+	// Compliance counts the user's answered recommendations, the accepted ones among them
+	// and the accepted ones whose window includes the given day
+	Compliance(userID int, day time.Time) (answered, accepted, active int, err error)
+}
+
+// ScheduledWorkoutRepository stores the workouts users plan for coming days. Every method
+// is scoped to the owning user.
+type ScheduledWorkoutRepository interface {
+	// Create stores a scheduled workout, linked to the accepted deload whose window it falls in
+	Create(sw models.ScheduledWorkout) (int, error)
+	// Import stores the workouts of an .ics file in one transaction, leaving out those whose
+	// iCalendar UID the user already has, and returns the IDs of those it stored
+	Import(workouts []models.ScheduledWorkout) ([]int, error)
+	// Get returns a scheduled workout with its template's exercises, their targets scaled
+	// down while a deload applies
+	Get(id, userID int) (models.ScheduledWorkout, error)
+	// List returns the user's workouts scheduled from one date to another, in the order
+	// they are planned, only those with the given status unless it is empty
+	List(userID int, from, to time.Time, status string) ([]models.ScheduledWorkout, error)
+	// ListWithTargets is List with the template exercises and targets Get fills in
+	ListWithTargets(userID int, from, to time.Time, status string) ([]models.ScheduledWorkout, error)
+	// Update changes a scheduled workout's own fields, relinking it to the deload it falls in
+	Update(sw models.ScheduledWorkout) error
+	Delete(id, userID int) error
+	// Complete links a logged workout to the first workout still scheduled on its day and
+	// marks that one completed, or returns ErrNotFound if there is none. Deleting the
+	// logged workout schedules it again.
+	Complete(userID, workoutID int, date time.Time) (models.ScheduledWorkout, error)
+	// SkipOverdue marks every user's workouts still scheduled before the day it is for
+	// the user at the given time, in their settings' time zone, skipped and returns how
+	// many it marked
+	SkipOverdue(now time.Time) (int, error)
+	// Summary counts the user's scheduled workouts by status, splitting those still
+	// scheduled into overdue ones, before the given day, and upcoming ones
+	Summary(userID int, today time.Time) (models.PlanningAnalytics, error)
+}
+
+// CalendarEventRepository stores calendar events and recurring series of them. Every
+// method is scoped to the owning user; series are stored once and expanded by the caller.
+type CalendarEventRepository interface {
+	Create(event models.WorkoutCalendarEvent) (int, error)
+	Get(id, userID int) (models.WorkoutCalendarEvent, error)
+	// List returns the user's events that may occur from one date to another, earliest
+	// first: single events overlapping the period and every series starting before its end
+	List(userID int, from, to time.Time) ([]models.WorkoutCalendarEvent, error)
+	Update(event models.WorkoutCalendarEvent) error
+	Delete(id, userID int) error
+	// Split saves a series changed to leave out some of its occurrences together with the
+	// event or series replacing them, in one transaction, and returns the replacement's ID
+	Split(series, replacement models.WorkoutCalendarEvent) (int, error)
+}
+
+// CalendarFeedRepository stores the tokens of users' iCalendar feeds, one per user
+type CalendarFeedRepository interface {
+	Get(userID int) (models.CalendarFeed, error)
+	// GetByHash returns the feed whose token has the given hash
+	GetByHash(tokenHash string) (models.CalendarFeed, error)
+	// Replace stores a user's feed in place of the one they had, if any, and returns its ID
+	Replace(feed models.CalendarFeed) (int, error)
+	// Touch records when a feed was last fetched
+	Touch(id int, at time.Time) error
+	Delete(userID int) error
+}
+
+// ReminderRepository stores the reminders users set for their scheduled workouts. The
+// user-facing methods are scoped to the owning user; the dispatcher's work across users.
+type ReminderRepository interface {
+	Create(reminder models.WorkoutReminder) (int, error)
+	Get(id, userID int) (models.WorkoutReminder, error)
+	// List returns the reminders of one of the user's scheduled workouts, earliest first
+	List(scheduledWorkoutID, userID int) ([]models.WorkoutReminder, error)
+	Delete(id, userID int) error
+	// Reschedule moves the user's pending reminders to their minutes before the start of
+	// their workout in the given zone
+	Reschedule(userID int, zone *time.Location) error
+	// Due returns up to limit pending reminders of the given types that are ready to be
+	// sent, or retried, at the given time
+	Due(types []string, now time.Time, limit int) ([]models.DueReminder, error)
+	// Claim takes a due reminder for one delivery attempt and holds it back from other
+	// dispatchers until the given time, or returns ErrNotFound if it is no longer due
+	Claim(id int, now, until time.Time) error
+	// MarkSent records that a reminder went out
+	MarkSent(id int, at time.Time) error
+	// MarkFailed records a failed attempt, keeping the reminder pending until retryAt
+	// or marking it failed for good when retryAt is nil
+	MarkFailed(id int, message string, retryAt *time.Time) error
+	// Cancel marks a reminder that should not be sent cancelled, recording why
+	Cancel(id int, reason string) error
+}
+
+// AnalyticsRepository aggregates a user's workouts and meals for the analytics pages.
+// Periods run from the calendar day of from to that of to, both included.
+type AnalyticsRepository interface {
+	// WorkoutDays returns each day with a workout logged, with how many were logged and
+	// the average duration of those with one
+	WorkoutDays(userID int, from, to time.Time) ([]models.WorkoutDay, error)
+	// CalorieDays returns each day with a meal logged and the calories eaten
+	CalorieDays(userID int, from, to time.Time) ([]models.CalorieDay, error)
+	// TopExercises returns up to limit of the exercises logged most often, most frequent first
+	TopExercises(userID int, from, to time.Time, limit int) ([]models.ExerciseFrequency, error)
+	// StrengthDays returns the heaviest weight, most reps and volume of an exercise's
+	// weighted working sets on each day
+	StrengthDays(userID int, exercise string, from, to time.Time) ([]models.StrengthDataPoint, error)
+	// ExerciseProgress returns the same for each workout, with its number of working sets
+	ExerciseProgress(userID int, exercise string, from, to time.Time) ([]models.ExerciseProgressPoint, error)
+	// WorkingSets returns the weighted sets other than warmups of one exercise or, with
+	// an empty name, of every exercise, in the order they were logged
+	WorkingSets(userID int, exercise string, from, to time.Time) ([]models.WorkingSet, error)
+}
+
+// New returns the store implementation for the database's driver
+func New(db *database.DB) Store {
+	if db.Driver == database.DriverPostgres {
+		return NewPostgres(db)
+	}
+	return NewSQLite(db)
+}
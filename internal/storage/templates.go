@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// templateRepo is the SQL implementation of TemplateRepository
+type templateRepo struct {
+	*sqlStore
+}
+
+// ListByUser returns a user's workout templates, most recently updated first
+func (r *templateRepo) ListByUser(userID int) ([]models.WorkoutTemplate, error) {
+	query := `
+		SELECT id, user_id, name, description, created_at, updated_at
+		FROM workout_templates
+		WHERE user_id = ?
+		ORDER BY updated_at DESC
+	`
+
+	return r.queryTemplates(query, userID)
+}
+
+// SharedWith returns templates other users have shared with the given user
+func (r *templateRepo) SharedWith(userID int) ([]models.WorkoutTemplate, error) {
+	query := `
+		SELECT wt.id, wt.user_id, wt.name, wt.description, wt.created_at, wt.updated_at
+		FROM workout_templates wt
+		JOIN template_sharing ts ON wt.id = ts.template_id
+		WHERE ts.shared_with_id = ?
+		ORDER BY ts.created_at DESC
+	`
+
+	return r.queryTemplates(query, userID)
+}
+
+// queryTemplates runs a template query and fills in each template's exercises
+func (r *templateRepo) queryTemplates(query string, args ...interface{}) ([]models.WorkoutTemplate, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []models.WorkoutTemplate
+	for rows.Next() {
+		var template models.WorkoutTemplate
+		err := rows.Scan(&template.ID, &template.UserID, &template.Name, &template.Description, &template.CreatedAt, &template.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range templates {
+		if err := r.attachExercises(&templates[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return templates, nil
+}
+
+// Get returns a template owned by the given user with its exercises
+func (r *templateRepo) Get(id, userID int) (models.WorkoutTemplate, error) {
+	var template models.WorkoutTemplate
+
+	query := `
+		SELECT id, user_id, name, description, created_at, updated_at
+		FROM workout_templates
+		WHERE id = ? AND user_id = ?
+	`
+
+	err := r.db.QueryRow(query, id, userID).Scan(&template.ID, &template.UserID, &template.Name, &template.Description, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return template, notFound(err)
+	}
+
+	if err := r.attachExercises(&template); err != nil {
+		return template, err
+	}
+
+	return template, nil
+}
+
+// attachExercises loads a template's exercises, converted to Exercise for compatibility
+func (r *templateRepo) attachExercises(template *models.WorkoutTemplate) error {
+	templateExercises, err := r.Exercises(template.ID)
+	if err != nil {
+		return err
+	}
+
+	var exercises []models.Exercise
+	for _, te := range templateExercises {
+		exercises = append(exercises, models.Exercise{
+			ID:         te.ID,
+			Name:       te.Name,
+			Category:   te.Category,
+			OrderIndex: te.OrderIndex,
+			GroupID:    te.GroupID,
+		})
+	}
+	template.Exercises = exercises
+
+	return nil
+}
+
+// Create creates a new workout template and returns its ID
+func (r *templateRepo) Create(template models.WorkoutTemplate) (int, error) {
+	query := `
+		INSERT INTO workout_templates (user_id, name, description, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, template.UserID, template.Name, template.Description, time.Now(), time.Now())
+}
+
+// Update updates a template owned by template.UserID
+func (r *templateRepo) Update(template models.WorkoutTemplate) error {
+	query := `
+		UPDATE workout_templates
+		SET name = ?, description = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`
+
+	result, err := r.db.Exec(query, template.Name, template.Description, time.Now(), template.ID, template.UserID)
+	if err != nil {
+		return err
+	}
+
+	return requireRows(result)
+}
+
+// Delete deletes a workout template and all associated exercises
+func (r *templateRepo) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM workout_templates WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return requireRows(result)
+}
+
+// Exercises returns the exercises of a template in order
+func (r *templateRepo) Exercises(templateID int) ([]models.TemplateExercise, error) {
+	exercises, err := r.ExercisesByTemplateIDs([]int{templateID})
+	if err != nil {
+		return nil, err
+	}
+	return exercises[templateID], nil
+}
+
+// ExercisesByTemplateIDs loads the exercises of many templates at once, keyed by template ID
+func (r *templateRepo) ExercisesByTemplateIDs(templateIDs []int) (map[int][]models.TemplateExercise, error) {
+	result := make(map[int][]models.TemplateExercise)
+	if len(templateIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(templateIDs)), ",")
+	args := make([]interface{}, len(templateIDs))
+	for i, id := range templateIDs {
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, template_id, name, category, order_index, group_id, target_sets, target_reps, target_weight, rest_time, notes, created_at, updated_at
+		FROM template_exercises
+		WHERE template_id IN (%s)
+		ORDER BY template_id, order_index ASC, id ASC
+	`, placeholders)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var exercise models.TemplateExercise
+		err := rows.Scan(&exercise.ID, &exercise.TemplateID, &exercise.Name, &exercise.Category, &exercise.OrderIndex, &exercise.GroupID, &exercise.TargetSets, &exercise.TargetReps, &exercise.TargetWeight, &exercise.RestTime, &exercise.Notes, &exercise.CreatedAt, &exercise.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		result[exercise.TemplateID] = append(result[exercise.TemplateID], exercise)
+	}
+
+	return result, rows.Err()
+}
+
+// CreateExercise creates a new template exercise and returns its ID
+func (r *templateRepo) CreateExercise(exercise models.TemplateExercise) (int, error) {
+	query := `
+		INSERT INTO template_exercises (template_id, name, category, order_index, group_id, target_sets, target_reps, target_weight, rest_time, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, exercise.TemplateID, exercise.Name, exercise.Category, exercise.OrderIndex, exercise.GroupID, exercise.TargetSets, exercise.TargetReps, exercise.TargetWeight, exercise.RestTime, exercise.Notes, time.Now(), time.Now())
+}
+
+// DeleteExercises deletes all exercises of a template
+func (r *templateRepo) DeleteExercises(templateID int) error {
+	_, err := r.db.Exec(`DELETE FROM template_exercises WHERE template_id = ?`, templateID)
+	return err
+}
+
+// Share records that a template was shared with another user and returns the sharing ID
+func (r *templateRepo) Share(sharing models.TemplateSharing) (int, error) {
+	query := `
+		INSERT INTO template_sharing (template_id, owner_id, shared_with_id, permission, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, sharing.TemplateID, sharing.OwnerID, sharing.SharedWithID, sharing.Permission, time.Now())
+}
+
+// RecordUsage records that a workout was created from a template
+func (r *templateRepo) RecordUsage(usage models.TemplateUsage) (int, error) {
+	query := `
+		INSERT INTO template_usage (template_id, user_id, workout_id, used_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, usage.TemplateID, usage.UserID, usage.WorkoutID, usage.UsedAt, usage.CreatedAt)
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"time"
+
+	"workout-tracker/internal/models"
+)
+
+// userRepo is the SQL implementation of UserRepository
+type userRepo struct {
+	*sqlStore
+}
+
+// Create creates a new user and returns its ID
+func (r *userRepo) Create(user models.User) (int, error) {
+	query := `
+		INSERT INTO users (username, email, password_hash, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	return r.db.Insert(query, user.Username, user.Email, user.PasswordHash, time.Now(), time.Now())
+}
+
+// GetByID returns a user with their profile fields
+func (r *userRepo) GetByID(id int) (models.User, error) {
+	query := `
+		SELECT id, username, email, password_hash, COALESCE(full_name, ''), COALESCE(bio, ''), COALESCE(avatar, ''),
+		       COALESCE(is_active, TRUE), created_at, updated_at
+		FROM users
+		WHERE id = ?
+	`
+
+	var u models.User
+	err := r.db.QueryRow(query, id).Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.FullName, &u.Bio, &u.Avatar, &u.IsActive, &u.CreatedAt, &u.UpdatedAt)
+	return u, notFound(err)
+}
+
+// GetByUsername returns a user by username
+func (r *userRepo) GetByUsername(username string) (models.User, error) {
+	query := `
+		SELECT id, username, email, password_hash, created_at, updated_at
+		FROM users
+		WHERE username = ?
+	`
+
+	var u models.User
+	err := r.db.QueryRow(query, username).Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt)
+	return u, notFound(err)
+}
+
+// UpdateProfile updates a user's username, email, full name and bio
+func (r *userRepo) UpdateProfile(id int, profile models.UpdateProfileRequest) error {
+	query := `UPDATE users SET username = ?, email = ?, full_name = ?, bio = ?, updated_at = ? WHERE id = ?`
+	result, err := r.db.Exec(query, profile.Username, profile.Email, profile.FullName, profile.Bio, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return requireRows(result)
+}
+
+// UpdatePassword replaces a user's password hash
+func (r *userRepo) UpdatePassword(id int, passwordHash string) error {
+	result, err := r.db.Exec(`UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`, passwordHash, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return requireRows(result)
+}
+
+// Delete deletes a user. Foreign keys cascade the delete to every workout,
+// template, program, token and other row the user owns.
+func (r *userRepo) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return requireRows(result)
+}
@@ -0,0 +1,414 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"workout-tracker/internal/database"
+	"workout-tracker/internal/models"
+)
+
+// workoutRepo is the SQL implementation of WorkoutRepository
+type workoutRepo struct {
+	*sqlStore
+}
+
+// Create creates a workout with its exercises and sets in one transaction and returns its ID
+func (r *workoutRepo) Create(workout models.Workout, userID int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertWorkout(tx, workout, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit workout: %v", err)
+	}
+
+	return id, nil
+}
+
+// Get returns a workout owned by the given user with its exercises and sets
+func (r *workoutRepo) Get(id, userID int) (models.Workout, error) {
+	var w models.Workout
+
+	query := `
+		SELECT id, user_id, name, date, duration, notes, created_at, updated_at
+		FROM workouts
+		WHERE id = ? AND user_id = ?
+	`
+
+	err := r.db.QueryRow(query, id, userID).Scan(&w.ID, &w.UserID, &w.Name, &w.Date, &w.Duration, &w.Notes, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return w, notFound(err)
+	}
+
+	exercises, err := r.Exercises().ByWorkoutIDs([]int{id})
+	if err != nil {
+		return w, err
+	}
+	w.Exercises = exercises[id]
+
+	return w, nil
+}
+
+// Recent returns the most recent workouts of a user without their exercises
+func (r *workoutRepo) Recent(userID, limit int) ([]models.Workout, error) {
+	query := `
+		SELECT id, user_id, name, date, duration, notes, created_at, updated_at
+		FROM workouts
+		WHERE user_id = ?
+		ORDER BY date DESC, created_at DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workouts []models.Workout
+	for rows.Next() {
+		var w models.Workout
+		err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Date, &w.Duration, &w.Notes, &w.CreatedAt, &w.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		workouts = append(workouts, w)
+	}
+
+	return workouts, rows.Err()
+}
+
+// List returns one page of a user's workouts and the cursor for the next page
+func (r *workoutRepo) List(userID int, params models.WorkoutListParams) ([]models.Workout, string, error) {
+	sortName, err := NormalizeWorkoutSort(params.Sort)
+	if err != nil {
+		return nil, "", err
+	}
+	descending := strings.HasPrefix(sortName, "-")
+	column := workoutSortColumns[strings.TrimPrefix(sortName, "-")]
+
+	conditions := []string{"w.user_id = ?"}
+	args := []interface{}{userID}
+
+	if params.From != "" {
+		conditions = append(conditions, r.dialect.dateOf("w.date")+" >= ?")
+		args = append(args, params.From)
+	}
+	if params.To != "" {
+		conditions = append(conditions, r.dialect.dateOf("w.date")+" <= ?")
+		args = append(args, params.To)
+	}
+	if params.Exercise != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM exercises e WHERE e.workout_id = w.id AND "+r.dialect.equalFold("e.name")+")")
+		args = append(args, params.Exercise)
+	}
+	if params.Category != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM exercises e WHERE e.workout_id = w.id AND "+r.dialect.equalFold("e.category")+")")
+		args = append(args, params.Category)
+	}
+	// Every search term has to appear in either the name or the notes
+	for _, term := range strings.Fields(params.Query) {
+		pattern := "%" + escapeLike(term) + "%"
+		conditions = append(conditions, "("+r.dialect.containsFold("w.name")+" OR "+r.dialect.containsFold("w.notes")+")")
+		args = append(args, pattern, pattern)
+	}
+
+	comparison, direction := ">", "ASC"
+	if descending {
+		comparison, direction = "<", "DESC"
+	}
+	if params.Cursor != "" {
+		cursor, err := DecodeWorkoutCursor(params.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if cursor.Sort != sortName {
+			return nil, "", fmt.Errorf("cursor does not match sort order")
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s %s ? OR (%s = ? AND w.id %s ?))", column, comparison, column, comparison))
+		args = append(args, cursor.Value, cursor.Value, cursor.ID)
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	query := fmt.Sprintf(`
+		SELECT w.id, w.user_id, w.name, w.date, w.duration, w.notes, w.created_at, w.updated_at,
+		       CAST(%s AS TEXT)
+		FROM workouts w
+		WHERE %s
+		ORDER BY %s %s, w.id %s
+		LIMIT ?
+	`, column, strings.Join(conditions, " AND "), column, direction, direction)
+	args = append(args, params.Limit+1)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	workouts := []models.Workout{}
+	var sortValues []string
+	for rows.Next() {
+		var w models.Workout
+		var sortValue string
+		err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.Date, &w.Duration, &w.Notes, &w.CreatedAt, &w.UpdatedAt, &sortValue)
+		if err != nil {
+			return nil, "", err
+		}
+		workouts = append(workouts, w)
+		sortValues = append(sortValues, sortValue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(workouts) > params.Limit {
+		workouts = workouts[:params.Limit]
+		last := workouts[len(workouts)-1]
+		nextCursor = EncodeWorkoutCursor(WorkoutCursor{Sort: sortName, Value: sortValues[params.Limit-1], ID: last.ID})
+	}
+
+	return workouts, nextCursor, nil
+}
+
+// Update updates a workout owned by the given user. Its date decides the order records
+// were set in and the day its load falls on, so the records of its exercises and the
+// training load from the earlier of its old and new dates are rebuilt too. A workout moved
+// to another day completes the workout scheduled there instead of the one it completed.
+func (r *workoutRepo) Update(workout models.Workout, userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	since, err := workoutDate(tx, workout.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE workouts
+		SET name = ?, date = ?, duration = ?, notes = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`
+
+	result, err := tx.Exec(query, workout.Name, workout.Date, workout.Duration, workout.Notes, time.Now(), workout.ID, userID)
+	if err != nil {
+		return err
+	}
+	if err := requireRows(result); err != nil {
+		return err
+	}
+
+	if err := relinkScheduled(tx, userID, workout.ID, since, workout.Date); err != nil {
+		return err
+	}
+
+	names, err := workoutExerciseNames(tx, workout.ID)
+	if err != nil {
+		return err
+	}
+	if err := rebuildRecords(tx, userID, names...); err != nil {
+		return err
+	}
+	if err := rebuildTrainingLoad(tx, userID, earlier(since, workout.Date)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Replace updates a workout owned by the given user and replaces all of its
+// exercises and sets in one transaction, relinking scheduled workouts as Update does
+func (r *workoutRepo) Replace(workout models.Workout, userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	since, err := workoutDate(tx, workout.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE workouts SET name = ?, date = ?, duration = ?, notes = ?, updated_at = ? WHERE id = ? AND user_id = ?`
+	result, err := tx.Exec(query, workout.Name, workout.Date, workout.Duration, workout.Notes, time.Now(), workout.ID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update workout: %v", err)
+	}
+	if err := requireRows(result); err != nil {
+		return err
+	}
+	if err := relinkScheduled(tx, userID, workout.ID, since, workout.Date); err != nil {
+		return err
+	}
+
+	names, err := workoutExerciseNames(tx, workout.ID)
+	if err != nil {
+		return err
+	}
+	for _, exercise := range workout.Exercises {
+		names = append(names, exercise.Name)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM sets WHERE exercise_id IN (SELECT id FROM exercises WHERE workout_id = ?)`, workout.ID); err != nil {
+		return fmt.Errorf("failed to delete sets: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM exercises WHERE workout_id = ?`, workout.ID); err != nil {
+		return fmt.Errorf("failed to delete exercises: %v", err)
+	}
+
+	if err := insertExercises(tx, workout.ID, workout.Exercises); err != nil {
+		return err
+	}
+
+	if err := rebuildRecords(tx, userID, names...); err != nil {
+		return err
+	}
+	if err := rebuildTrainingLoad(tx, userID, earlier(since, workout.Date)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit workout: %v", err)
+	}
+
+	return nil
+}
+
+// Delete deletes a workout owned by the given user together with its exercises and sets
+func (r *workoutRepo) Delete(id, userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	since, err := workoutDate(tx, id, userID)
+	if err != nil {
+		return err
+	}
+	names, err := workoutExerciseNames(tx, id)
+	if err != nil {
+		return err
+	}
+
+	// The workout it completed is to be done again
+	if err := uncompleteScheduled(tx, id); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM workouts WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if err := requireRows(result); err != nil {
+		return err
+	}
+
+	if err := rebuildRecords(tx, userID, names...); err != nil {
+		return err
+	}
+	if err := rebuildTrainingLoad(tx, userID, since); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AttachExercises fills in the exercises and sets of the given workouts with one batched query
+func (r *workoutRepo) AttachExercises(workouts []models.Workout) error {
+	ids := make([]int, len(workouts))
+	for i, w := range workouts {
+		ids[i] = w.ID
+	}
+
+	exercises, err := r.Exercises().ByWorkoutIDs(ids)
+	if err != nil {
+		return err
+	}
+	for i := range workouts {
+		workouts[i].Exercises = exercises[workouts[i].ID]
+	}
+
+	return nil
+}
+
+// OwnerID returns the ID of the user who owns a workout
+func (r *workoutRepo) OwnerID(id int) (int, error) {
+	var userID int
+	err := r.db.QueryRow(`SELECT user_id FROM workouts WHERE id = ?`, id).Scan(&userID)
+	return userID, notFound(err)
+}
+
+// insertWorkout inserts a workout with its exercises and sets inside a transaction, updates
+// the user's personal records and training load and returns the workout's ID
+func insertWorkout(tx *database.Tx, workout models.Workout, userID int) (int, error) {
+	query := `
+		INSERT INTO workouts (user_id, name, date, duration, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	id, err := tx.Insert(query, userID, workout.Name, workout.Date, workout.Duration, workout.Notes, time.Now(), time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create workout: %v", err)
+	}
+
+	if err := insertExercises(tx, id, workout.Exercises); err != nil {
+		return 0, err
+	}
+
+	names := make([]string, len(workout.Exercises))
+	for i, exercise := range workout.Exercises {
+		names[i] = exercise.Name
+	}
+	if err := rebuildRecords(tx, userID, names...); err != nil {
+		return 0, err
+	}
+	if err := rebuildTrainingLoad(tx, userID, workout.Date); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// insertExercises inserts exercises, in the order given, and their sets for a workout inside a transaction
+func insertExercises(tx *database.Tx, workoutID int, exercises []models.Exercise) error {
+	exerciseQuery := `
+		INSERT INTO exercises (workout_id, name, category, order_index, group_id, predefined_exercise_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ` + linkPredefinedExercise + `, ?, ?)
+	`
+	setQuery := `
+		INSERT INTO sets (exercise_id, set_number, reps, weight, distance, duration, rest_time, rpe, rir, set_type, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	for index, exercise := range exercises {
+		exerciseID, err := tx.Insert(exerciseQuery, workoutID, exercise.Name, exercise.Category, index, exercise.GroupID,
+			exercise.PredefinedExerciseID, exercise.Name, time.Now(), time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to create exercise: %v", err)
+		}
+
+		for _, set := range exercise.Sets {
+			_, err := tx.Exec(setQuery, exerciseID, set.SetNumber, set.Reps, set.Weight, set.Distance, set.Duration, set.RestTime,
+				set.RPE, set.RIR, models.SetTypeOrDefault(set.SetType), set.Notes, time.Now(), time.Now())
+			if err != nil {
+				return fmt.Errorf("failed to create set: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
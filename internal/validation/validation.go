@@ -0,0 +1,185 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes a single field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Struct validates a struct using its `validate:` tags and returns every failing field.
+// Supported rules are required, min=N, max=N, email and oneof=a b c. Nested structs and
+// slices of structs are validated too, with field paths such as exercises[0].name.
+func Struct(v interface{}) []FieldError {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs []FieldError
+	validateStruct(val, "", &errs)
+	return errs
+}
+
+// validateStruct checks every exported field of a struct value
+func validateStruct(val reflect.Value, prefix string, errs *[]FieldError) {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		fv := val.Field(i)
+		if tag := field.Tag.Get("validate"); tag != "" {
+			for _, rule := range strings.Split(tag, ",") {
+				if msg := checkRule(fv, rule); msg != "" {
+					ruleName := strings.SplitN(rule, "=", 2)[0]
+					*errs = append(*errs, FieldError{Field: path, Rule: ruleName, Message: path + " " + msg})
+					// Report only the first failing rule per field
+					break
+				}
+			}
+		}
+
+		validateNested(fv, path, errs)
+	}
+}
+
+// validateNested descends into struct, pointer and slice fields
+func validateNested(fv reflect.Value, path string, errs *[]FieldError) {
+	switch fv.Kind() {
+	case reflect.Ptr:
+		if !fv.IsNil() {
+			validateNested(fv.Elem(), path, errs)
+		}
+	case reflect.Struct:
+		// time.Time and similar types carry no tags worth checking
+		if fv.Type().PkgPath() == "time" {
+			return
+		}
+		validateStruct(fv, path, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			validateNested(fv.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+// checkRule applies one rule to a value and returns a message when it fails
+func checkRule(fv reflect.Value, rule string) string {
+	parts := strings.SplitN(strings.TrimSpace(rule), "=", 2)
+	name := parts[0]
+	param := ""
+	if len(parts) == 2 {
+		param = parts[1]
+	}
+
+	switch name {
+	case "required":
+		if fv.IsZero() {
+			return "is required"
+		}
+	case "min", "max":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return ""
+		}
+		size, ok := measure(fv)
+		if !ok {
+			return ""
+		}
+		if name == "min" && size < limit {
+			return fmt.Sprintf("must be at least %s%s", param, unitFor(fv))
+		}
+		if name == "max" && size > limit {
+			return fmt.Sprintf("must be at most %s%s", param, unitFor(fv))
+		}
+	case "email":
+		if fv.Kind() == reflect.String && fv.String() != "" {
+			if _, err := mail.ParseAddress(fv.String()); err != nil {
+				return "must be a valid email address"
+			}
+		}
+	case "oneof":
+		if fv.Kind() == reflect.String && fv.String() != "" {
+			options := strings.Fields(param)
+			for _, option := range options {
+				if fv.String() == option {
+					return ""
+				}
+			}
+			return "must be one of: " + strings.Join(options, ", ")
+		}
+	}
+
+	return ""
+}
+
+// measure returns the length of strings and collections or the value of numbers.
+// Optional fields are measured through their pointer; nil pointers are not checked.
+func measure(fv reflect.Value) (float64, bool) {
+	switch fv.Kind() {
+	case reflect.Ptr:
+		if fv.IsNil() {
+			return 0, false
+		}
+		return measure(fv.Elem())
+	case reflect.String:
+		return float64(len([]rune(fv.String()))), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(fv.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), true
+	}
+	return 0, false
+}
+
+// unitFor describes what min and max count for a value
+func unitFor(fv reflect.Value) string {
+	switch fv.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	}
+	return ""
+}
+
+// fieldName returns the JSON name of a struct field
+func fieldName(field reflect.StructField) string {
+	if tag := field.Tag.Get("json"); tag != "" {
+		name := strings.Split(tag, ",")[0]
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,105 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type set struct {
+	Reps int      `json:"reps" validate:"min=1,max=100"`
+	RPE  *float64 `json:"rpe" validate:"min=1,max=10"`
+}
+
+type exercise struct {
+	Name string `json:"name" validate:"required,max=10"`
+	Sets []set  `json:"sets" validate:"max=3"`
+}
+
+type workout struct {
+	Name      string     `json:"name" validate:"required,min=2"`
+	Email     string     `json:"email" validate:"email"`
+	Status    string     `json:"status" validate:"oneof=planned done"`
+	Duration  int        `validate:"max=600"`
+	Exercises []exercise `json:"exercises"`
+	Cooldown  *exercise  `json:"cooldown"`
+	Date      time.Time  `json:"date"`
+	// Fields hidden from JSON and unexported fields are never checked
+	Internal string `json:"-" validate:"required"`
+	secret   string `validate:"required"`
+}
+
+func TestStruct(t *testing.T) {
+	rpe := func(v float64) *float64 { return &v }
+	valid := func() workout {
+		return workout{
+			Name:      "Legs",
+			Email:     "alice@example.com",
+			Status:    "planned",
+			Exercises: []exercise{{Name: "Squat", Sets: []set{{Reps: 5, RPE: rpe(8)}, {Reps: 5}}}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(w *workout)
+		want   []FieldError
+	}{
+		{"valid", func(w *workout) {}, nil},
+		{"required, reporting the first failing rule only", func(w *workout) { w.Name = "" },
+			[]FieldError{{"name", "required", "name is required"}}},
+		{"min characters", func(w *workout) { w.Name = "L" },
+			[]FieldError{{"name", "min", "name must be at least 2 characters"}}},
+		{"min counts runes", func(w *workout) { w.Name = "Öl" }, nil},
+		{"max number", func(w *workout) { w.Duration = 601 },
+			[]FieldError{{"Duration", "max", "Duration must be at most 600"}}},
+		{"email", func(w *workout) { w.Email = "alice" },
+			[]FieldError{{"email", "email", "email must be a valid email address"}}},
+		{"empty email is left to required", func(w *workout) { w.Email = "" }, nil},
+		{"oneof", func(w *workout) { w.Status = "skipped" },
+			[]FieldError{{"status", "oneof", "status must be one of: planned, done"}}},
+		{"empty oneof is left to required", func(w *workout) { w.Status = "" }, nil},
+		{"nested slice path", func(w *workout) { w.Exercises[0].Sets[1].Reps = 0 },
+			[]FieldError{{"exercises[0].sets[1].reps", "min", "exercises[0].sets[1].reps must be at least 1"}}},
+		{"max items", func(w *workout) { w.Exercises[0].Sets = make([]set, 4) },
+			[]FieldError{
+				{"exercises[0].sets", "max", "exercises[0].sets must be at most 3 items"},
+				{"exercises[0].sets[0].reps", "min", "exercises[0].sets[0].reps must be at least 1"},
+				{"exercises[0].sets[1].reps", "min", "exercises[0].sets[1].reps must be at least 1"},
+				{"exercises[0].sets[2].reps", "min", "exercises[0].sets[2].reps must be at least 1"},
+				{"exercises[0].sets[3].reps", "min", "exercises[0].sets[3].reps must be at least 1"},
+			}},
+		{"pointer checked through", func(w *workout) { w.Exercises[0].Sets[0].RPE = rpe(11) },
+			[]FieldError{{"exercises[0].sets[0].rpe", "max", "exercises[0].sets[0].rpe must be at most 10"}}},
+		{"nil pointer skipped", func(w *workout) { w.Exercises[0].Sets[0].RPE = nil }, nil},
+		{"nested pointer path", func(w *workout) { w.Cooldown = &exercise{Name: "Walk for a while"} },
+			[]FieldError{{"cooldown.name", "max", "cooldown.name must be at most 10 characters"}}},
+		{"every field reported", func(w *workout) { w.Name, w.Status = "", "skipped" },
+			[]FieldError{
+				{"name", "required", "name is required"},
+				{"status", "oneof", "status must be one of: planned, done"},
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := valid()
+			tc.modify(&w)
+			if got := Struct(w); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Struct = %+v, want %+v", got, tc.want)
+			}
+			// A pointer to the struct is validated the same way
+			if got := Struct(&w); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Struct(&w) = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStructIgnoresNonStructs(t *testing.T) {
+	var nilWorkout *workout
+	for _, v := range []interface{}{nil, nilWorkout, "name", 42} {
+		if got := Struct(v); got != nil {
+			t.Errorf("Struct(%#v) = %+v, want nil", v, got)
+		}
+	}
+}
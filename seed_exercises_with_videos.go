@@ -8,6 +8,7 @@ import (
 
 	"workout-tracker/internal/database"
 	"workout-tracker/internal/models"
+	"workout-tracker/internal/storage"
 )
 
 func main() {
@@ -17,6 +18,7 @@ func main() {
 		log.Fatal("Failed to initialize database:", err)
 	}
 	defer db.Close()
+	store := storage.New(db)
 
 	// Sample exercises with form videos and instructions
 	exercises := []models.PredefinedExercise{
@@ -113,9 +115,11 @@ func main() {
 			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`
 		
-		_, err := db.Exec(query, exercise.Name, exercise.Category, exercise.Description, exercise.VideoURL, exercise.Instructions, exercise.Tips, exercise.MuscleGroups, exercise.Equipment, exercise.Difficulty, exercise.ImageURL, exercise.CreatedAt, exercise.UpdatedAt)
+		id, err := db.Insert(query, exercise.Name, exercise.Category, exercise.Description, exercise.VideoURL, exercise.Instructions, exercise.Tips, exercise.MuscleGroups, exercise.Equipment, exercise.Difficulty, exercise.ImageURL, exercise.CreatedAt, exercise.UpdatedAt)
 		if err != nil {
 			log.Printf("Failed to insert exercise %s: %v", exercise.Name, err)
+		} else if err := store.Muscles().LinkExercise(id); err != nil {
+			log.Printf("Failed to link muscles of exercise %s: %v", exercise.Name, err)
 		} else {
 			fmt.Printf("Successfully inserted exercise: %s\n", exercise.Name)
 		}
@@ -4,12 +4,19 @@
 
 API_BASE="http://localhost:8080/api"
 
+# Personal access token created under Account Settings -> API Tokens
+if [ -z "$API_TOKEN" ]; then
+    echo "Set API_TOKEN to a read & write personal access token first"
+    exit 1
+fi
+AUTH_HEADER="Authorization: Bearer $API_TOKEN"
+
 echo "Testing Workout Tracker API..."
 echo "================================"
 
 # Test 1: Get all workouts (should be empty initially)
 echo "1. Testing GET /api/workouts"
-curl -s "$API_BASE/workouts" | jq '.' 2>/dev/null || curl -s "$API_BASE/workouts"
+curl -s -H "$AUTH_HEADER" "$API_BASE/workouts" | jq '.' 2>/dev/null || curl -s -H "$AUTH_HEADER" "$API_BASE/workouts"
 echo -e "\n"
 
 # Test 2: Create a new workout
@@ -21,7 +28,7 @@ WORKOUT_JSON='{
     "notes": "This is a test workout"
 }'
 
-RESPONSE=$(curl -s -X POST "$API_BASE/workouts" \
+RESPONSE=$(curl -s -X POST -H "$AUTH_HEADER" "$API_BASE/workouts" \
     -H "Content-Type: application/json" \
     -d "$WORKOUT_JSON")
 
@@ -34,13 +41,13 @@ if command -v jq &> /dev/null; then
     
     if [ "$WORKOUT_ID" != "null" ] && [ "$WORKOUT_ID" != "" ]; then
         echo "3. Testing GET /api/workouts/$WORKOUT_ID"
-        curl -s "$API_BASE/workouts/$WORKOUT_ID" | jq '.' 2>/dev/null || curl -s "$API_BASE/workouts/$WORKOUT_ID"
+        curl -s -H "$AUTH_HEADER" "$API_BASE/workouts/$WORKOUT_ID" | jq '.' 2>/dev/null || curl -s -H "$AUTH_HEADER" "$API_BASE/workouts/$WORKOUT_ID"
         echo -e "\n"
     fi
 fi
 
 echo "4. Testing GET /api/workouts (should now have one workout)"
-curl -s "$API_BASE/workouts" | jq '.' 2>/dev/null || curl -s "$API_BASE/workouts"
+curl -s -H "$AUTH_HEADER" "$API_BASE/workouts" | jq '.' 2>/dev/null || curl -s -H "$AUTH_HEADER" "$API_BASE/workouts"
 echo -e "\n"
 
 echo "API testing complete!"